@@ -123,6 +123,22 @@ go_t3c_preprocess_dir="$ccpath"/t3c-preprocess
 	cp "$TC_DIR"/"$ccdir"/t3c-preprocess/t3c-preprocess.1 .
 ) || { echo "Could not copy go program at $(pwd): $!"; exit 1; }
 
+# copy t3c-explain binary
+go_t3c_explain_dir="$ccpath"/t3c-explain
+( mkdir -p "$go_t3c_explain_dir" && \
+	cd "$go_t3c_explain_dir" && \
+	cp "$TC_DIR"/"$ccdir"/t3c-explain/t3c-explain .
+	cp "$TC_DIR"/"$ccdir"/t3c-explain/t3c-explain.1 .
+) || { echo "Could not copy go program at $(pwd): $!"; exit 1; }
+
+# copy t3c-rollback binary
+go_t3c_rollback_dir="$ccpath"/t3c-rollback
+( mkdir -p "$go_t3c_rollback_dir" && \
+	cd "$go_t3c_rollback_dir" && \
+	cp "$TC_DIR"/"$ccdir"/t3c-rollback/t3c-rollback .
+	cp "$TC_DIR"/"$ccdir"/t3c-rollback/t3c-rollback.1 .
+) || { echo "Could not copy go program at $(pwd): $!"; exit 1; }
+
 # copy t3c-tail binary
 go_t3c_tail_dir="$ccpath"/t3c-tail
 ( mkdir -p "$go_t3c_tail_dir" && \
@@ -173,6 +189,14 @@ t3c_diff_src=src/github.com/apache/trafficcontrol/"$ccdir"/t3c-diff
 cp -p "$t3c_diff_src"/t3c-diff ${RPM_BUILD_ROOT}/"$installdir"
 gzip -c -9 "$src"/t3c-diff/t3c-diff.1 > ${RPM_BUILD_ROOT}/"$mandir"/"$man1dir"/t3c-diff.1.gz
 
+t3c_explain_src=src/github.com/apache/trafficcontrol/"$ccdir"/t3c-explain
+cp -p "$t3c_explain_src"/t3c-explain ${RPM_BUILD_ROOT}/"$installdir"
+gzip -c -9 "$src"/t3c-explain/t3c-explain.1 > ${RPM_BUILD_ROOT}/"$mandir"/"$man1dir"/t3c-explain.1.gz
+
+t3c_rollback_src=src/github.com/apache/trafficcontrol/"$ccdir"/t3c-rollback
+cp -p "$t3c_rollback_src"/t3c-rollback ${RPM_BUILD_ROOT}/"$installdir"
+gzip -c -9 "$src"/t3c-rollback/t3c-rollback.1 > ${RPM_BUILD_ROOT}/"$mandir"/"$man1dir"/t3c-rollback.1.gz
+
 t3c_tail_src=src/github.com/apache/trafficcontrol/"$ccdir"/t3c-tail
 cp -p "$t3c_tail_src"/t3c-tail ${RPM_BUILD_ROOT}/"$installdir"
 gzip -c -9 "$src"/t3c-tail/t3c-tail.1 > ${RPM_BUILD_ROOT}/"$mandir"/"$man1dir"/t3c-tail.1.gz
@@ -233,9 +257,11 @@ fi
 /usr/bin/t3c-check-refs
 /usr/bin/t3c-check-reload
 /usr/bin/t3c-diff
+/usr/bin/t3c-explain
 /usr/bin/t3c-generate
 /usr/bin/t3c-preprocess
 /usr/bin/t3c-request
+/usr/bin/t3c-rollback
 /usr/bin/t3c-tail
 /usr/bin/t3c-update
 /usr/share/man/man1/t3c.1.gz
@@ -244,9 +270,11 @@ fi
 /usr/share/man/man1/t3c-check-refs.1.gz
 /usr/share/man/man1/t3c-check-reload.1.gz
 /usr/share/man/man1/t3c-diff.1.gz
+/usr/share/man/man1/t3c-explain.1.gz
 /usr/share/man/man1/t3c-generate.1.gz
 /usr/share/man/man1/t3c-preprocess.1.gz
 /usr/share/man/man1/t3c-request.1.gz
+/usr/share/man/man1/t3c-rollback.1.gz
 /usr/share/man/man1/t3c-tail.1.gz
 /usr/share/man/man1/t3c-update.1.gz
 
@@ -111,6 +111,35 @@ type Cfg struct {
 	ServiceAction          t3cutil.ApplyServiceActionFlag
 	NoConfirmServiceAction bool
 
+	// NoRollback is whether to leave changed config in place when reloading or
+	// restarting ATS, or the health check, fails after applying it.
+	NoRollback bool
+	// BackupDir is the directory in which the config files replaced by a run
+	// are backed up, so they may be restored by a rollback.
+	BackupDir string
+	// Provenance is whether to write the provenance of generated config lines
+	// to ProvenanceDir, for t3c-explain.
+	Provenance bool
+	// ProvenanceDir is the directory in which config line provenance sidecar
+	// files are written.
+	ProvenanceDir string
+	// FromBundle is the path of a bundle written by 't3c-request --bundle' to
+	// get all data from, rather than Traffic Ops. If set, Traffic Ops is not
+	// contacted, and is not updated after the config is applied.
+	FromBundle string
+	// RunReportFile is the path to write the JSON run report to. If empty, no
+	// report is written.
+	RunReportFile string
+	// MetricsFile is the path to write the run report metrics to, for the
+	// node_exporter textfile collector. If empty, no metrics are written.
+	MetricsFile string
+	// HealthCheckURL is a URL which must return a 2xx response after config is
+	// applied, or the config is rolled back. If empty, no health check is made.
+	HealthCheckURL string
+	// ReportConfigFiles is what to report to Traffic Ops about the config
+	// files applied, for previewing how they would change.
+	ReportConfigFiles t3cutil.ReportConfigFilesFlag
+
 	ReportOnly        bool
 	Files             t3cutil.ApplyFilesFlag
 	InstallPackages   bool
@@ -264,6 +293,33 @@ func GetCfg(appVersion string, gitRevision string) (Cfg, error) {
 	const noConfirmServiceActionFlagName = "no-confirm-service-action"
 	noConfirmServiceAction := getopt.BoolLong(noConfirmServiceActionFlagName, 0, "Whether to skip waiting and confirming the service action succeeded (reload or restart) via t3c-tail. Default is false.")
 
+	const noRollbackFlagName = "no-rollback"
+	noRollbackPtr := getopt.BoolLong(noRollbackFlagName, 0, "Whether to leave the new config in place if the service action or health check fails, rather than rolling back to the previous config. Default is false.")
+
+	const backupDirFlagName = "backup-dir"
+	backupDirPtr := getopt.StringLong(backupDirFlagName, 0, t3cutil.DefaultBackupDir, "Directory to back up replaced config files to, for rolling back. Default is "+t3cutil.DefaultBackupDir)
+
+	const provenanceFlagName = "provenance"
+	provenancePtr := getopt.BoolLong(provenanceFlagName, 0, "Whether to write the provenance of generated config lines, linking them to the Traffic Ops objects they were generated from, for t3c-explain. Default is false.")
+
+	const provenanceDirFlagName = "provenance-dir"
+	provenanceDirPtr := getopt.StringLong(provenanceDirFlagName, 0, t3cutil.DefaultProvenanceDir, "Directory to write config line provenance to, if --provenance is set. Default is "+t3cutil.DefaultProvenanceDir)
+
+	const fromBundleFlagName = "from-bundle"
+	fromBundlePtr := getopt.StringLong(fromBundleFlagName, 0, "", "Get all data from the given bundle written by 't3c-request --bundle', rather than Traffic Ops. If set, Traffic Ops is not contacted or updated, and the Traffic Ops URL, user, and password are not required. Default is to use Traffic Ops.")
+
+	const runReportFileFlagName = "run-report-file"
+	runReportFilePtr := getopt.StringLong(runReportFileFlagName, 0, t3cutil.DefaultApplyReportPath, "File to write a JSON report of the run to, with the duration of each phase, Traffic Ops requests, changed files, service action, installed packages, and exit reason. May be empty to not write a report. Default is "+t3cutil.DefaultApplyReportPath)
+
+	const metricsFileFlagName = "metrics-file"
+	metricsFilePtr := getopt.StringLong(metricsFileFlagName, 0, "", "File to write run metrics to, in the node_exporter textfile collector format. Must end in '.prom'. Default is to not write metrics.")
+
+	const healthCheckURLFlagName = "health-check-url"
+	healthCheckURLPtr := getopt.StringLong(healthCheckURLFlagName, 0, "", "URL which must return a 2xx response after the service action, or the config is rolled back. Default is no health check.")
+
+	const reportConfigFilesFlagName = "report-config-files"
+	reportConfigFilesPtr := getopt.EnumLong(reportConfigFilesFlagName, 0, []string{string(t3cutil.ReportConfigFilesFlagNone), string(t3cutil.ReportConfigFilesFlagHashes), string(t3cutil.ReportConfigFilesFlagText), ""}, "", "[none | hashes | text] What to report to Traffic Ops about the config files applied, for previewing changes before updates are queued. 'hashes' reports which files would change, and 'text' also how, at the cost of uploading every non-secure file on every run. Requires Traffic Ops API 5.0. Default is 'none'")
+
 	const reportOnlyFlagName = "report-only"
 	reportOnlyPtr := getopt.BoolLong(reportOnlyFlagName, 'o', "Log information about necessary files and actions, but take no action. Default is false")
 
@@ -380,6 +436,11 @@ If any of the related flags are also set, they override the mode's default behav
 		*useStrategiesPtr = defaultUseStrategies.String()
 	}
 
+	reportConfigFiles := t3cutil.ReportConfigFilesFlagNone
+	if *reportConfigFilesPtr != "" {
+		reportConfigFiles = t3cutil.StrToReportConfigFilesFlag(*reportConfigFilesPtr)
+	}
+
 	logLocationError := log.LogLocationStderr
 	logLocationWarn := log.LogLocationNull
 	logLocationInfo := log.LogLocationNull
@@ -495,24 +556,43 @@ If any of the related flags are also set, they override the mode's default behav
 	toInfoLog = append(toInfoLog, fmt.Sprintf("ATSVersionStr: '%s'\n", atsVersionStr))
 
 	usageStr := "basic usage: t3c-apply --traffic-ops-url=myurl --traffic-ops-user=myuser --traffic-ops-password=mypass --cache-host-name=my-cache"
-	if strings.TrimSpace(toURL) == "" {
-		return Cfg{}, errors.New("Missing required argument --traffic-ops-url or TO_URL environment variable. " + usageStr)
-	}
-	if strings.TrimSpace(toUser) == "" {
-		return Cfg{}, errors.New("Missing required argument --traffic-ops-user or TO_USER environment variable. " + usageStr)
-	}
-	if strings.TrimSpace(toPass) == "" {
-		return Cfg{}, errors.New("Missing required argument --traffic-ops-password or TO_PASS environment variable. " + usageStr)
+	if *fromBundlePtr == "" {
+		if strings.TrimSpace(toURL) == "" {
+			return Cfg{}, errors.New("Missing required argument --traffic-ops-url or TO_URL environment variable. " + usageStr)
+		}
+		if strings.TrimSpace(toUser) == "" {
+			return Cfg{}, errors.New("Missing required argument --traffic-ops-user or TO_USER environment variable. " + usageStr)
+		}
+		if strings.TrimSpace(toPass) == "" {
+			return Cfg{}, errors.New("Missing required argument --traffic-ops-password or TO_PASS environment variable. " + usageStr)
+		}
 	}
 	if strings.TrimSpace(cacheHostName) == "" {
 		return Cfg{}, errors.New("Missing required argument --cache-host-name. " + usageStr)
 	}
 
-	toURLParsed, err := url.Parse(toURL)
-	if err != nil {
-		return Cfg{}, errors.New("parsing Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
-	} else if err = validateURL(toURLParsed); err != nil {
-		return Cfg{}, errors.New("invalid Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
+	if *fromBundlePtr == "" {
+		toURLParsed, err := url.Parse(toURL)
+		if err != nil {
+			return Cfg{}, errors.New("parsing Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
+		} else if err = validateURL(toURLParsed); err != nil {
+			return Cfg{}, errors.New("invalid Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
+		}
+	} else if _, err := os.Stat(*fromBundlePtr); err != nil {
+		return Cfg{}, errors.New("reading bundle '" + *fromBundlePtr + "': " + err.Error())
+	}
+
+	if *metricsFilePtr != "" && !strings.HasSuffix(*metricsFilePtr, ".prom") {
+		return Cfg{}, errors.New("metrics file '" + *metricsFilePtr + "' must end in '.prom' to be read by the node_exporter textfile collector")
+	}
+
+	if *healthCheckURLPtr != "" {
+		healthCheckURL, err := url.Parse(*healthCheckURLPtr)
+		if err != nil {
+			return Cfg{}, errors.New("parsing health check URL '" + *healthCheckURLPtr + "': " + err.Error())
+		} else if err = validateURL(healthCheckURL); err != nil {
+			return Cfg{}, errors.New("invalid health check URL '" + *healthCheckURLPtr + "': " + err.Error())
+		}
 	}
 
 	svcManagement := getOSSvcManagement()
@@ -551,6 +631,15 @@ If any of the related flags are also set, they override the mode's default behav
 		TsConfigDir:                 tsConfigDir,
 		ServiceAction:               t3cutil.ApplyServiceActionFlag(*serviceActionPtr),
 		NoConfirmServiceAction:      *noConfirmServiceAction,
+		NoRollback:                  *noRollbackPtr,
+		BackupDir:                   *backupDirPtr,
+		Provenance:                  *provenancePtr,
+		ProvenanceDir:               *provenanceDirPtr,
+		FromBundle:                  *fromBundlePtr,
+		RunReportFile:               *runReportFilePtr,
+		MetricsFile:                 *metricsFilePtr,
+		HealthCheckURL:              *healthCheckURLPtr,
+		ReportConfigFiles:           reportConfigFiles,
 		ReportOnly:                  *reportOnlyPtr,
 		Files:                       t3cutil.ApplyFilesFlag(*filesPtr),
 		InstallPackages:             *installPackagesPtr,
@@ -661,6 +750,15 @@ func printConfig(cfg Cfg) {
 	log.Debugf("WaitForParents: %v\n", cfg.WaitForParents)
 	log.Debugf("ServiceAction: %v\n", cfg.ServiceAction)
 	log.Debugf("NoConfirmServiceAction: %v\n", cfg.NoConfirmServiceAction)
+	log.Debugf("NoRollback: %v\n", cfg.NoRollback)
+	log.Debugf("BackupDir: %s\n", cfg.BackupDir)
+	log.Debugf("Provenance: %v\n", cfg.Provenance)
+	log.Debugf("ProvenanceDir: %s\n", cfg.ProvenanceDir)
+	log.Debugf("FromBundle: %s\n", cfg.FromBundle)
+	log.Debugf("RunReportFile: %s\n", cfg.RunReportFile)
+	log.Debugf("MetricsFile: %s\n", cfg.MetricsFile)
+	log.Debugf("HealthCheckURL: %s\n", cfg.HealthCheckURL)
+	log.Debugf("ReportConfigFiles: %s\n", cfg.ReportConfigFiles)
 	log.Debugf("YumOptions: %s\n", cfg.YumOptions)
 	log.Debugf("MaxmindLocation: %s\n", cfg.MaxMindLocation)
 }
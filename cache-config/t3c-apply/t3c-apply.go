@@ -55,6 +55,8 @@ const (
 	ExitCodeServicesError     = 138
 	ExitCodeSyncDSError       = 139
 	ExitCodeUserCheckError    = 140
+	ExitCodeRolledBack        = 141
+	ExitCodeRollbackError     = 142
 )
 
 func runSysctl(cfg config.Cfg) {
@@ -77,6 +79,7 @@ const LockFileRetryTimeout = time.Minute
 
 const FailureExitMsg = `CRITICAL FAILURE, ABORTING`
 const PostConfigFailureExitMsg = `CRITICAL FAILURE AFTER SETTING CONFIG, ABORTING`
+const RolledBackExitMsg = `CRITICAL FAILURE AFTER SETTING CONFIG, ROLLED BACK`
 const SuccessExitMsg = `SUCCESS`
 
 func main() {
@@ -189,19 +192,19 @@ func Main() int {
 
 		if err != nil {
 			log.Errorln("Checking revalidate state: " + err.Error())
-			return GitCommitAndExit(ExitCodeRevalidationError, FailureExitMsg, cfg, metaData, oldMetaData)
+			return GitCommitAndExit(ExitCodeRevalidationError, FailureExitMsg, cfg, trops, metaData, oldMetaData)
 		}
 		if syncdsUpdate == torequest.UpdateTropsNotNeeded {
 			log.Infoln("Checking revalidate state: returned UpdateTropsNotNeeded")
 			metaData.Succeeded = true
-			return GitCommitAndExit(ExitCodeRevalidationError, SuccessExitMsg, cfg, metaData, oldMetaData)
+			return GitCommitAndExit(ExitCodeRevalidationError, SuccessExitMsg, cfg, trops, metaData, oldMetaData)
 		}
 
 	} else {
 		syncdsUpdate, err = trops.CheckSyncDSState(metaData)
 		if err != nil {
 			log.Errorln("Checking syncds state: " + err.Error())
-			return GitCommitAndExit(ExitCodeSyncDSError, FailureExitMsg, cfg, metaData, oldMetaData)
+			return GitCommitAndExit(ExitCodeSyncDSError, FailureExitMsg, cfg, trops, metaData, oldMetaData)
 		}
 		if !cfg.IgnoreUpdateFlag && cfg.Files == t3cutil.ApplyFilesFlagAll && syncdsUpdate == torequest.UpdateTropsNotNeeded {
 			// If touching remap.config fails, we want to still try to restart services
@@ -222,7 +225,7 @@ func Main() int {
 				if err := trops.StartServices(&syncdsUpdate, metaData); err != nil {
 					log.Errorln("failed to start services: " + err.Error())
 					metaData.PartialSuccess = true
-					return GitCommitAndExit(ExitCodeServicesError, PostConfigFailureExitMsg, cfg, metaData, oldMetaData)
+					return GitCommitAndExit(ExitCodeServicesError, PostConfigFailureExitMsg, cfg, trops, metaData, oldMetaData)
 				}
 			}
 			finalMsg := SuccessExitMsg
@@ -230,7 +233,7 @@ func Main() int {
 				finalMsg = PostConfigFailureExitMsg
 			}
 			metaData.Succeeded = true
-			return GitCommitAndExit(ExitCodeSuccess, finalMsg, cfg, metaData, oldMetaData)
+			return GitCommitAndExit(ExitCodeSuccess, finalMsg, cfg, trops, metaData, oldMetaData)
 		}
 	}
 
@@ -242,14 +245,14 @@ func Main() int {
 		err = trops.ProcessPackages()
 		if err != nil {
 			log.Errorf("Error processing packages: %s\n", err)
-			return GitCommitAndExit(ExitCodePackagingError, FailureExitMsg, cfg, metaData, oldMetaData)
+			return GitCommitAndExit(ExitCodePackagingError, FailureExitMsg, cfg, trops, metaData, oldMetaData)
 		}
 
 		// check and make sure packages are enabled for startup
 		err = trops.CheckSystemServices()
 		if err != nil {
 			log.Errorf("Error verifying system services: %s\n", err.Error())
-			return GitCommitAndExit(ExitCodeServicesError, FailureExitMsg, cfg, metaData, oldMetaData)
+			return GitCommitAndExit(ExitCodeServicesError, FailureExitMsg, cfg, trops, metaData, oldMetaData)
 		}
 	}
 
@@ -257,7 +260,7 @@ func Main() int {
 	err = trops.GetConfigFileList()
 	if err != nil {
 		log.Errorf("Getting config file list: %s\n", err)
-		return GitCommitAndExit(ExitCodeConfigFilesError, FailureExitMsg, cfg, metaData, oldMetaData)
+		return GitCommitAndExit(ExitCodeConfigFilesError, FailureExitMsg, cfg, trops, metaData, oldMetaData)
 	}
 	syncdsUpdate, err = trops.ProcessConfigFiles(metaData)
 	if err != nil {
@@ -286,8 +289,20 @@ func Main() int {
 
 	if err := trops.StartServices(&syncdsUpdate, metaData); err != nil {
 		log.Errorln("failed to start services: " + err.Error())
+		if trops.ServiceActionFailed && trops.CanRollBack() {
+			return RollBackAndExit(trops, cfg, metaData, oldMetaData)
+		}
+		metaData.PartialSuccess = true
+		return GitCommitAndExit(ExitCodeServicesError, PostConfigFailureExitMsg, cfg, trops, metaData, oldMetaData)
+	}
+
+	if err := trops.CheckHealth(metaData); err != nil {
+		log.Errorln(err.Error())
+		if trops.CanRollBack() {
+			return RollBackAndExit(trops, cfg, metaData, oldMetaData)
+		}
 		metaData.PartialSuccess = true
-		return GitCommitAndExit(ExitCodeServicesError, PostConfigFailureExitMsg, cfg, metaData, oldMetaData)
+		return GitCommitAndExit(ExitCodeServicesError, PostConfigFailureExitMsg, cfg, trops, metaData, oldMetaData)
 	}
 
 	// start 'teakd' if installed.
@@ -318,8 +333,12 @@ func Main() int {
 		log.Errorf("failed to update Traffic Ops: %s\n", err.Error())
 	}
 
+	if err := trops.ReportConfigFiles(&syncdsUpdate); err != nil {
+		log.Warnf("failed to report config files to Traffic Ops, Traffic Ops may be too old to support it: %s\n", err.Error())
+	}
+
 	metaData.Succeeded = true
-	return GitCommitAndExit(ExitCodeSuccess, SuccessExitMsg, cfg, metaData, oldMetaData)
+	return GitCommitAndExit(ExitCodeSuccess, SuccessExitMsg, cfg, trops, metaData, oldMetaData)
 }
 
 func LogPanic(f func() int) (exitCode int) {
@@ -335,9 +354,9 @@ func LogPanic(f func() int) (exitCode int) {
 }
 
 // GitCommitAndExit attempts to git commit all changes, and logs any error.
-// It then logs exitMsg at the Info level, and returns exitCode.
+// It then writes the run report, logs exitMsg at the Info level, and returns exitCode.
 // This is a helper function, to reduce the duplicated commit-log-return into a single line.
-func GitCommitAndExit(exitCode int, exitMsg string, cfg config.Cfg, metaData *t3cutil.ApplyMetaData, oldMetaData *t3cutil.ApplyMetaData) int {
+func GitCommitAndExit(exitCode int, exitMsg string, cfg config.Cfg, trops *torequest.TrafficOpsReq, metaData *t3cutil.ApplyMetaData, oldMetaData *t3cutil.ApplyMetaData) int {
 
 	// metadata isn't actually part of git, but we always want to write it before committing to git, so this is the right place
 
@@ -367,11 +386,37 @@ func GitCommitAndExit(exitCode int, exitMsg string, cfg config.Cfg, metaData *t3
 		t3cutil.WriteActionLog(t3cutil.ActionLogActionApplyEnd, t3cutil.ActionLogStatusFailure, nil)
 	}
 
+	// the report is written last, so its duration includes the git commit
+	WriteRunReport(cfg, trops.FinishReport(exitCode, exitMsg, metaData))
+
 	log.Infoln(exitMsg)
 
 	return exitCode
 }
 
+// RollBackAndExit restores the config files replaced by this run and reloads
+// or restarts ATS with them, reports the failure to Traffic Ops, and then
+// commits and exits via GitCommitAndExit.
+// Returns ExitCodeRolledBack if the rollback succeeded, else ExitCodeRollbackError.
+func RollBackAndExit(trops *torequest.TrafficOpsReq, cfg config.Cfg, metaData *t3cutil.ApplyMetaData, oldMetaData *t3cutil.ApplyMetaData) int {
+	log.Errorln("applying config failed, rolling back to the previous config")
+	exitCode := ExitCodeRolledBack
+	exitMsg := RolledBackExitMsg
+	if err := trops.RollBack(metaData); err != nil {
+		log.Errorln("rolling back config: " + err.Error())
+		metaData.PartialSuccess = true
+		exitCode = ExitCodeRollbackError
+		exitMsg = PostConfigFailureExitMsg
+	} else {
+		metaData.RolledBack = true
+	}
+
+	if err := trops.ReportRollBack(); err != nil {
+		log.Errorln("reporting config apply failure to Traffic Ops: " + err.Error())
+	}
+	return GitCommitAndExit(exitCode, exitMsg, cfg, trops, metaData, oldMetaData)
+}
+
 // CheckMaxmindUpdate will (if a url is set) check for a db on disk.
 // If it exists, issue an IMS to determine if it needs to update the db.
 // If no file or if an update is needed to be done it is downloaded and unpacked.
@@ -395,6 +440,23 @@ func CheckMaxmindUpdate(cfg config.Cfg) bool {
 	return result
 }
 
+// WriteRunReport writes the run report file, and the metrics file if one is
+// configured.
+//
+// On error, an error is written to the log, but no error is returned.
+func WriteRunReport(cfg config.Cfg, report *t3cutil.ApplyReport) {
+	if cfg.RunReportFile != "" {
+		if err := t3cutil.WriteApplyReport(cfg.RunReportFile, report); err != nil {
+			log.Errorln("writing run report: " + err.Error())
+		}
+	}
+	if cfg.MetricsFile != "" {
+		if err := t3cutil.WriteApplyReportMetrics(cfg.MetricsFile, report); err != nil {
+			log.Errorln("writing run metrics: " + err.Error())
+		}
+	}
+}
+
 const MetaDataFileName = `t3c-apply-metadata.json`
 const MetaDataFileMode = 0600
 
@@ -64,10 +64,10 @@ var stripDate = regexp.MustCompile(`\[\w{3}\s{1,2}\d{1,2}\s\d{2}:\d{2}:\d{2}\.\d
 var t3cpath string = filepath.Join(t3cutil.InstallDir(), `t3c`)
 
 // generate runs t3c-generate and returns the result.
-func generate(cfg config.Cfg) ([]t3cutil.ATSConfigFile, error) {
+func generate(cfg config.Cfg) ([]t3cutil.ATSConfigFile, []byte, error) {
 	configData, err := requestConfig(cfg)
 	if err != nil {
-		return nil, errors.New("requesting: " + err.Error())
+		return nil, nil, errors.New("requesting: " + err.Error())
 	}
 	args := []string{
 		`generate`,
@@ -103,26 +103,29 @@ func generate(cfg config.Cfg) ([]t3cutil.ATSConfigFile, error) {
 	args = append(args, "--no-outgoing-ip="+strconv.FormatBool(cfg.NoOutgoingIP))
 	args = append(args, "--disable-parent-config-comments="+strconv.FormatBool(cfg.DisableParentConfigComments))
 	args = append(args, "--use-strategies="+cfg.UseStrategies.String())
+	if cfg.Provenance {
+		args = append(args, "--provenance")
+	}
 
 	generatedFiles, stdErr, code := t3cutil.DoInput(configData, t3cpath, args...)
 	if code != 0 {
 		logSubAppErr(t3cgen+` stdout`, generatedFiles)
 		logSubAppErr(t3cgen+` stderr`, stdErr)
-		return nil, fmt.Errorf("%s returned non-zero exit code %v, see log for output", t3cgen, code)
+		return nil, nil, fmt.Errorf("%s returned non-zero exit code %v, see log for output", t3cgen, code)
 	}
 	logSubApp(t3cgen, stdErr)
 
 	preprocessedBytes, err := preprocess(cfg, configData, generatedFiles)
 	if err != nil {
-		return nil, errors.New("preprocessing config files: " + err.Error())
+		return nil, nil, errors.New("preprocessing config files: " + err.Error())
 	}
 
 	allFiles := []t3cutil.ATSConfigFile{}
 	if err := json.Unmarshal(preprocessedBytes, &allFiles); err != nil {
-		return nil, errors.New("unmarshalling generated files: " + err.Error())
+		return nil, nil, errors.New("unmarshalling generated files: " + err.Error())
 	}
 
-	return allFiles, nil
+	return allFiles, generatedFiles, nil
 }
 
 // preprocess takes the to Data from 't3c-request --get-data=config' and the generated files from 't3c-generate', passes them to `t3c-preprocess`, and returns the result.
@@ -283,7 +286,9 @@ func sendUpdate(cfg config.Cfg, configApplyTime, revalApplyTime *time.Time, conf
 	if _, used := os.LookupEnv("TO_URL"); !used {
 		args = append(args, "--traffic-ops-url="+cfg.TOURL)
 	}
+	start := time.Now()
 	stdOut, stdErr, code := t3cutil.Do(t3cpath, args...)
+	recordTORequest(cfg, t3cupd, "update-status", start, 0, 0, code == 0)
 	if code != 0 {
 		logSubAppErr(t3cupd+` stdout`, stdOut)
 		logSubAppErr(t3cupd+` stderr`, stdErr)
@@ -294,13 +299,99 @@ func sendUpdate(cfg config.Cfg, configApplyTime, revalApplyTime *time.Time, conf
 	return nil
 }
 
+// sendConfigFiles calls t3c-update to report the given generated files, as
+// output by t3c-generate before preprocessing, as the files the server applied.
+func sendConfigFiles(cfg config.Cfg, generatedFiles []byte) error {
+	args := []string{
+		`update`,
+		"--traffic-ops-timeout-milliseconds=" + strconv.FormatInt(int64(cfg.TOTimeoutMS), 10),
+		"--traffic-ops-insecure=" + strconv.FormatBool(cfg.TOInsecure),
+		"--cache-host-name=" + cfg.CacheHostName,
+		"--set-config-files=" + cfg.ReportConfigFiles.String(),
+	}
+
+	if cfg.LogLocationErr == log.LogLocationNull {
+		args = append(args, "-s")
+	}
+	if cfg.LogLocationWarn != log.LogLocationNull {
+		args = append(args, "-v")
+	}
+	if cfg.LogLocationInfo != log.LogLocationNull {
+		args = append(args, "-v")
+	}
+
+	if _, used := os.LookupEnv("TO_USER"); !used {
+		args = append(args, "--traffic-ops-user="+cfg.TOUser)
+	}
+	if _, used := os.LookupEnv("TO_PASS"); !used {
+		args = append(args, "--traffic-ops-password="+cfg.TOPass)
+	}
+	if _, used := os.LookupEnv("TO_URL"); !used {
+		args = append(args, "--traffic-ops-url="+cfg.TOURL)
+	}
+	start := time.Now()
+	stdOut, stdErr, code := t3cutil.DoInput(generatedFiles, t3cpath, args...)
+	recordTORequest(cfg, t3cupd, "config-files", start, len(generatedFiles), 0, code == 0)
+	if code != 0 {
+		logSubAppErr(t3cupd+` stdout`, stdOut)
+		logSubAppErr(t3cupd+` stderr`, stdErr)
+		return fmt.Errorf("%s returned non-zero exit code %v, see log for output", t3cupd, code)
+	}
+	logSubApp(t3cupd, stdErr)
+	log.Infoln(t3cupd + " config files succeeded")
+	return nil
+}
+
+// sendConfigApplyFailure calls t3c-update to report to Traffic Ops that the
+// server failed to apply its config at the given time.
+func sendConfigApplyFailure(cfg config.Cfg, failTime time.Time) error {
+	args := []string{
+		`update`,
+		"--traffic-ops-timeout-milliseconds=" + strconv.FormatInt(int64(cfg.TOTimeoutMS), 10),
+		"--traffic-ops-insecure=" + strconv.FormatBool(cfg.TOInsecure),
+		"--cache-host-name=" + cfg.CacheHostName,
+		"--set-config-apply-failure-time=" + failTime.Format(time.RFC3339Nano),
+	}
+
+	if cfg.LogLocationErr == log.LogLocationNull {
+		args = append(args, "-s")
+	}
+	if cfg.LogLocationWarn != log.LogLocationNull {
+		args = append(args, "-v")
+	}
+	if cfg.LogLocationInfo != log.LogLocationNull {
+		args = append(args, "-v")
+	}
+
+	if _, used := os.LookupEnv("TO_USER"); !used {
+		args = append(args, "--traffic-ops-user="+cfg.TOUser)
+	}
+	if _, used := os.LookupEnv("TO_PASS"); !used {
+		args = append(args, "--traffic-ops-password="+cfg.TOPass)
+	}
+	if _, used := os.LookupEnv("TO_URL"); !used {
+		args = append(args, "--traffic-ops-url="+cfg.TOURL)
+	}
+	start := time.Now()
+	stdOut, stdErr, code := t3cutil.Do(t3cpath, args...)
+	recordTORequest(cfg, t3cupd, "config-apply-failure", start, 0, 0, code == 0)
+	if code != 0 {
+		logSubAppErr(t3cupd+` stdout`, stdOut)
+		logSubAppErr(t3cupd+` stderr`, stdErr)
+		return fmt.Errorf("%s returned non-zero exit code %v, see log for output", t3cupd, code)
+	}
+	logSubApp(t3cupd, stdErr)
+	log.Infoln(t3cupd + " config apply failure succeeded")
+	return nil
+}
+
 // doTail calls t3c-tail, which will read lines from the file at the provided
 // path, and will print lines matching the 'logMatch' regular expression.
 // When a line matching the 'endMatch' regular expression is encountered,
 // t3c-tail will exit - which means it MUST NOT be an empty string or only the
 // first line of the file will ever be read (and possibly printed, if it matches
 // 'logMatch'). In any case, the process will terminate after 'timeoutInMS'
-// milliseconds.
+// milliseconds, and an error is returned if 'endMatch' was not found.
 // Note that apart from an exit code difference on timeout, this is almost
 // exactly equivalent to the bash command:
 //
@@ -315,6 +406,7 @@ func doTail(cfg config.Cfg, file string, logMatch string, endMatch string, timeo
 		"--match=" + logMatch,
 		"--end-match=" + endMatch,
 		"--timeout-ms=" + strconv.Itoa(timeoutInMS),
+		"--fail-on-timeout",
 	}
 	stdOut, stdErr, code := t3cutil.Do(`t3c-tail`, args...)
 	if code >= 1 {
@@ -525,16 +617,22 @@ func request(cfg config.Cfg, command string) ([]byte, error) {
 		args = append(args, "-v")
 	}
 
-	if _, used := os.LookupEnv("TO_USER"); !used {
-		args = append(args, "--traffic-ops-user="+cfg.TOUser)
-	}
-	if _, used := os.LookupEnv("TO_PASS"); !used {
-		args = append(args, "--traffic-ops-password="+cfg.TOPass)
-	}
-	if _, used := os.LookupEnv("TO_URL"); !used {
-		args = append(args, "--traffic-ops-url="+cfg.TOURL)
+	if cfg.FromBundle != "" {
+		args = append(args, "--from-bundle="+cfg.FromBundle)
+	} else {
+		if _, used := os.LookupEnv("TO_USER"); !used {
+			args = append(args, "--traffic-ops-user="+cfg.TOUser)
+		}
+		if _, used := os.LookupEnv("TO_PASS"); !used {
+			args = append(args, "--traffic-ops-password="+cfg.TOPass)
+		}
+		if _, used := os.LookupEnv("TO_URL"); !used {
+			args = append(args, "--traffic-ops-url="+cfg.TOURL)
+		}
 	}
+	start := time.Now()
 	stdOut, stdErr, code := t3cutil.Do(t3cpath, args...)
+	recordTORequest(cfg, t3creq, command, start, 0, len(stdOut), code == 0)
 	if code != 0 {
 		logSubAppErr(t3creq+` stdout`, stdOut)
 		logSubAppErr(t3creq+` stderr`, stdErr)
@@ -548,11 +646,12 @@ func request(cfg config.Cfg, command string) ([]byte, error) {
 
 // requestConfig calls t3c-request and returns the stdout bytes.
 // It also caches the config in /var/lib/trafficcontrol-cache-config and uses the cache to issue IMS requests.
+// If the config is from a bundle, the cache is neither used nor written.
 func requestConfig(cfg config.Cfg) ([]byte, error) {
 	// TODO support /opt
 
 	cacheBts := ([]byte)(nil)
-	if !cfg.NoCache {
+	if !cfg.NoCache && cfg.FromBundle == "" {
 		err := error(nil)
 		if cacheBts, err = ioutil.ReadFile(t3cutil.ApplyCachePath); err != nil {
 			// don't log an error if the cache didn't exist
@@ -583,24 +682,30 @@ func requestConfig(cfg config.Cfg) ([]byte, error) {
 		args = append(args, "-v")
 	}
 
-	if _, used := os.LookupEnv("TO_USER"); !used {
-		args = append(args, "--traffic-ops-user="+cfg.TOUser)
-	}
-	if _, used := os.LookupEnv("TO_PASS"); !used {
-		args = append(args, "--traffic-ops-password="+cfg.TOPass)
-	}
-	if _, used := os.LookupEnv("TO_URL"); !used {
-		args = append(args, "--traffic-ops-url="+cfg.TOURL)
+	if cfg.FromBundle != "" {
+		args = append(args, "--from-bundle="+cfg.FromBundle)
+	} else {
+		if _, used := os.LookupEnv("TO_USER"); !used {
+			args = append(args, "--traffic-ops-user="+cfg.TOUser)
+		}
+		if _, used := os.LookupEnv("TO_PASS"); !used {
+			args = append(args, "--traffic-ops-password="+cfg.TOPass)
+		}
+		if _, used := os.LookupEnv("TO_URL"); !used {
+			args = append(args, "--traffic-ops-url="+cfg.TOURL)
+		}
 	}
 
 	stdOut := ([]byte)(nil)
 	stdErr := ([]byte)(nil)
 	code := 0
+	start := time.Now()
 	if len(cacheBts) > 0 {
 		stdOut, stdErr, code = t3cutil.DoInput(cacheBts, t3cpath, args...)
 	} else {
 		stdOut, stdErr, code = t3cutil.Do(t3cpath, args...)
 	}
+	recordTORequest(cfg, t3creq, "config", start, 0, len(stdOut), code == 0)
 	if code != 0 {
 		logSubAppErr(t3creq+` stdout`, stdOut)
 		logSubAppErr(t3creq+` stderr`, stdErr)
@@ -608,8 +713,11 @@ func requestConfig(cfg config.Cfg) ([]byte, error) {
 	}
 	logSubApp(t3creq, stdErr)
 
-	if err := ioutil.WriteFile(t3cutil.ApplyCachePath, stdOut, 0600); err != nil {
-		log.Errorln("writing config data to cache failed: " + err.Error())
+	// don't cache bundle data, it may be older than Traffic Ops and would make later conditional requests wrong
+	if cfg.FromBundle == "" {
+		if err := ioutil.WriteFile(t3cutil.ApplyCachePath, stdOut, 0600); err != nil {
+			log.Errorln("writing config data to cache failed: " + err.Error())
+		}
 	}
 
 	return stdOut, nil
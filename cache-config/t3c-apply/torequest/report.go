@@ -0,0 +1,109 @@
+package torequest
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"time"
+
+	"github.com/apache/trafficcontrol/cache-config/t3c-apply/config"
+	"github.com/apache/trafficcontrol/cache-config/t3cutil"
+)
+
+// toRequests is the Traffic Ops requests made by the sub-app funcs in cmd.go
+// during this run.
+//
+// Those funcs take the config rather than a TrafficOpsReq, so they record here,
+// and FinishReport moves the requests into the report.
+var toRequests = []t3cutil.ApplyReportTORequest{}
+
+// recordTORequest records a Traffic Ops request made by a sub-app, for the
+// run report. Requests for data from a bundle aren't made to Traffic Ops, and
+// aren't recorded.
+func recordTORequest(cfg config.Cfg, app string, data string, start time.Time, bytesSent int, bytesReceived int, succeeded bool) {
+	if app == t3creq && cfg.FromBundle != "" {
+		return
+	}
+	toRequests = append(toRequests, t3cutil.ApplyReportTORequest{
+		App:             app,
+		Data:            data,
+		Start:           start,
+		DurationSeconds: time.Since(start).Seconds(),
+		BytesSent:       bytesSent,
+		BytesReceived:   bytesReceived,
+		Succeeded:       succeeded,
+	})
+}
+
+// startPhase records the start of the given phase in the run report, and
+// returns a func which records its end.
+//
+// Typical usage is `defer r.startPhase(t3cutil.ApplyPhaseFoo)()`.
+func (r *TrafficOpsReq) startPhase(phase t3cutil.ApplyPhase) func() {
+	start := time.Now()
+	idx := len(r.Report.Phases)
+	r.Report.Phases = append(r.Report.Phases, t3cutil.ApplyReportPhase{Name: phase, Start: start})
+	return func() { r.Report.Phases[idx].DurationSeconds = time.Since(start).Seconds() }
+}
+
+// recordChangedFile records a config file written to disk in the run report.
+// The previous body may be nil if the file didn't exist.
+func (r *TrafficOpsReq) recordChangedFile(path string, body []byte, previousBody []byte) {
+	file := t3cutil.ApplyReportFile{Path: path, SHA256: sha256Hex(body)}
+	if previousBody != nil {
+		file.PreviousSHA256 = sha256Hex(previousBody)
+	}
+	r.Report.ChangedFiles = append(r.Report.ChangedFiles, file)
+}
+
+func sha256Hex(bts []byte) string {
+	sum := sha256.Sum256(bts)
+	return hex.EncodeToString(sum[:])
+}
+
+// FinishReport completes the run report with the results of the run, and
+// returns it. It should be called once, when the run exits.
+func (r *TrafficOpsReq) FinishReport(exitCode int, exitReason string, metaData *t3cutil.ApplyMetaData) *t3cutil.ApplyReport {
+	r.Report.AddTORequests(toRequests)
+	toRequests = []t3cutil.ApplyReportTORequest{}
+
+	r.Report.ServerHostName = r.Cfg.CacheHostName
+	r.Report.AppVersion = r.Cfg.AppVersion()
+	r.Report.Files = r.Cfg.Files.String()
+	r.Report.ReportOnly = r.Cfg.ReportOnly
+	r.Report.ServiceAction = r.serviceActionTaken
+
+	for pkg, _ := range r.installedPkgs {
+		r.Report.InstalledPackages = append(r.Report.InstalledPackages, pkg)
+	}
+	sort.Strings(r.Report.InstalledPackages)
+
+	if metaData != nil {
+		r.Report.Succeeded = metaData.Succeeded
+		r.Report.PartialSuccess = metaData.PartialSuccess
+		r.Report.RolledBack = metaData.RolledBack
+	}
+	r.Report.ExitCode = exitCode
+	r.Report.ExitReason = exitReason
+	r.Report.Finish(time.Now())
+	return r.Report
+}
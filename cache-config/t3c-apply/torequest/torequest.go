@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -51,6 +52,9 @@ const (
 	TailDiagsLogRelative = "/var/log/trafficserver/diags.log"
 	TailRestartTimeOutMS = 60000
 	TailReloadTimeOutMS  = 15000
+	HealthCheckTimeout   = 10 * time.Second
+	HealthCheckRetries   = 3
+	HealthCheckInterval  = 5 * time.Second
 	tailMatch            = `ET_(TASK|NET)\s\d{1,}`
 	tailRestartEnd       = "Traffic Server is fully initialized"
 	tailReloadEnd        = "remap.config finished loading"
@@ -71,6 +75,18 @@ type TrafficOpsReq struct {
 
 	configFiles        map[string]*ConfigFile
 	configFileWarnings map[string][]string
+	generatedFiles     []byte // output of t3c-generate, before preprocessing
+
+	backup *t3cutil.ConfigBackup // backup of the config files replaced by this run, nil if rollback is disabled
+
+	// ServiceActionFailed is whether reloading or restarting ATS failed or
+	// could not be confirmed, in which case the applied config may be bad.
+	ServiceActionFailed bool
+
+	serviceActionTaken t3cutil.ApplyServiceActionFlag // the action actually taken on ATS, none if it wasn't reloaded or restarted
+
+	// Report is the run report, which is completed by FinishReport.
+	Report *t3cutil.ApplyReport
 
 	RestartData
 }
@@ -190,13 +206,20 @@ func (r *TrafficOpsReq) DumpConfigFiles() {
 
 // NewTrafficOpsReq returns a new TrafficOpsReq object.
 func NewTrafficOpsReq(cfg config.Cfg) *TrafficOpsReq {
-	return &TrafficOpsReq{
+	r := &TrafficOpsReq{
 		Cfg:           cfg,
 		pkgs:          map[string]bool{},
 		plugins:       map[string]bool{},
 		configFiles:   map[string]*ConfigFile{},
 		installedPkgs: map[string]struct{}{},
+
+		serviceActionTaken: t3cutil.ApplyServiceActionFlagNone,
+		Report:             t3cutil.NewApplyReport(time.Now()),
 	}
+	if !cfg.NoRollback {
+		r.backup = t3cutil.NewConfigBackup(cfg.BackupDir)
+	}
+	return r
 }
 
 // checkConfigFile checks and audits config files.
@@ -485,12 +508,25 @@ func (r *TrafficOpsReq) replaceCfgFile(cfg *ConfigFile) (*FileRestartData, error
 		return &FileRestartData{Name: cfg.Name}, errors.New("Failed to write temp config file '" + tmpFileName + "': " + err.Error())
 	}
 
+	if r.backup != nil {
+		if err := r.backup.Add(cfg.Path); err != nil {
+			os.Remove(tmpFileName)
+			return &FileRestartData{Name: cfg.Name}, errors.New("Failed to back up '" + cfg.Path + "', not replacing it: " + err.Error())
+		}
+	}
+
+	previousBody, err := ioutil.ReadFile(cfg.Path)
+	if err != nil {
+		previousBody = nil // the file may not exist, and the previous body is only used for the run report, so errors are not fatal
+	}
+
 	log.Infof("Copying temp file '%s' to real '%s'\n", tmpFileName, cfg.Path)
 	if err := os.Rename(tmpFileName, cfg.Path); err != nil {
 		return &FileRestartData{Name: cfg.Name}, errors.New("Failed to move temp '" + tmpFileName + "' to real '" + cfg.Path + "': " + err.Error())
 	}
 	cfg.ChangeApplied = true
 	r.changedFiles = append(r.changedFiles, cfg.Path)
+	r.recordChangedFile(cfg.Path, cfg.Body, previousBody)
 
 	remapConfigReload := cfg.RemapPluginConfig ||
 		cfg.Name == "remap.config" ||
@@ -531,6 +567,8 @@ func (r *TrafficOpsReq) replaceCfgFile(cfg *ConfigFile) (*FileRestartData, error
 // CheckSystemServices is used to verify that packages installed
 // are enabled for startup.
 func (r *TrafficOpsReq) CheckSystemServices() error {
+	defer r.startPhase(t3cutil.ApplyPhaseCheckSystemServices)()
+
 	if r.Cfg.ServiceAction != t3cutil.ApplyServiceActionFlagRestart {
 		return nil
 	}
@@ -616,6 +654,8 @@ func (r *TrafficOpsReq) GetConfigFile(name string) (*ConfigFile, bool) {
 // GetConfigFileList fetches and parses the multipart config files
 // for a cache from traffic ops and loads them into the configFiles map.
 func (r *TrafficOpsReq) GetConfigFileList() error {
+	defer r.startPhase(t3cutil.ApplyPhaseGetConfigFiles)()
+
 	var atsUid int = 0
 	var atsGid int = 0
 
@@ -636,10 +676,11 @@ func (r *TrafficOpsReq) GetConfigFileList() error {
 		}
 	}
 
-	allFiles, err := generate(r.Cfg)
+	allFiles, generatedFiles, err := generate(r.Cfg)
 	if err != nil {
 		return errors.New("requesting data generating config files: " + err.Error())
 	}
+	r.generatedFiles = generatedFiles
 
 	r.configFiles = map[string]*ConfigFile{}
 	r.configFileWarnings = map[string][]string{}
@@ -669,6 +710,15 @@ func (r *TrafficOpsReq) GetConfigFileList() error {
 		}
 	}
 
+	if r.Cfg.Provenance && !r.Cfg.ReportOnly {
+		// provenance is diagnostic, so failing to write it shouldn't fail applying config
+		if written, err := t3cutil.WriteProvenanceFiles(r.Cfg.ProvenanceDir, allFiles); err != nil {
+			log.Errorln("writing config provenance to '" + r.Cfg.ProvenanceDir + "': " + err.Error())
+		} else {
+			log.Infof("wrote provenance of %v config files to '%s'\n", written, r.Cfg.ProvenanceDir)
+		}
+	}
+
 	return nil
 }
 
@@ -684,6 +734,8 @@ func (r *TrafficOpsReq) PrintWarnings() {
 
 // CheckRevalidateState retrieves and returns the revalidate status from Traffic Ops.
 func (r *TrafficOpsReq) CheckRevalidateState(sleepOverride bool) (UpdateStatus, error) {
+	defer r.startPhase(t3cutil.ApplyPhaseCheckRevalidateState)()
+
 	log.Infoln("Checking revalidate state.")
 	if !sleepOverride &&
 		(r.Cfg.ReportOnly || r.Cfg.Files != t3cutil.ApplyFilesFlagReval) {
@@ -737,6 +789,8 @@ func (r *TrafficOpsReq) CheckRevalidateState(sleepOverride bool) (UpdateStatus,
 // CheckSyncDSState retrieves and returns the DS Update status from Traffic Ops.
 // The metaData is this run's metadata. It must not be nil, and this function may add to it.
 func (r *TrafficOpsReq) CheckSyncDSState(metaData *t3cutil.ApplyMetaData) (UpdateStatus, error) {
+	defer r.startPhase(t3cutil.ApplyPhaseCheckSyncDSState)()
+
 	updateStatus := UpdateTropsNotNeeded
 	randDispSec := time.Duration(0)
 	log.Debugln("Checking syncds state.")
@@ -804,6 +858,8 @@ func (r *TrafficOpsReq) CheckReloadRestart(data []FileRestartData) RestartData {
 
 // ProcessConfigFiles processes all config files retrieved from Traffic Ops.
 func (r *TrafficOpsReq) ProcessConfigFiles(metaData *t3cutil.ApplyMetaData) (UpdateStatus, error) {
+	defer r.startPhase(t3cutil.ApplyPhaseProcessConfigFiles)()
+
 	var updateStatus UpdateStatus = UpdateTropsNotNeeded
 
 	log.Infoln(" ======== Start processing config files ========")
@@ -889,6 +945,8 @@ func (r *TrafficOpsReq) ProcessConfigFiles(metaData *t3cutil.ApplyMetaData) (Upd
 // ProcessPackages retrieves a list of required RPM's from Traffic Ops
 // and determines which need to be installed or removed on the cache.
 func (r *TrafficOpsReq) ProcessPackages() error {
+	defer r.startPhase(t3cutil.ApplyPhaseProcessPackages)()
+
 	log.Infoln("Calling ProcessPackages")
 	// get the package list for this cache from Traffic Ops.
 	pkgs, err := getPackages(r.Cfg)
@@ -1070,6 +1128,8 @@ func (r *TrafficOpsReq) RevalidateWhileSleeping(metaData *t3cutil.ApplyMetaData)
 // according to the changed config files and run mode.
 // Returns nil on success or any error.
 func (r *TrafficOpsReq) StartServices(syncdsUpdate *UpdateStatus, metaData *t3cutil.ApplyMetaData) error {
+	defer r.startPhase(t3cutil.ApplyPhaseStartServices)()
+
 	serviceNeeds := t3cutil.ServiceNeedsNothing
 	if r.Cfg.ServiceAction == t3cutil.ApplyServiceActionFlagRestart {
 		serviceNeeds = t3cutil.ServiceNeedsRestart
@@ -1116,15 +1176,23 @@ func (r *TrafficOpsReq) StartServices(syncdsUpdate *UpdateStatus, metaData *t3cu
 		}
 		if _, err := util.ServiceStart("trafficserver", startStr); err != nil {
 			t3cutil.WriteActionLog(t3cutil.ActionLogActionATSRestart, t3cutil.ActionLogStatusFailure, metaData)
+			r.ServiceActionFailed = true
 			return errors.New("failed to restart trafficserver")
 		}
 		t3cutil.WriteActionLog(t3cutil.ActionLogActionATSRestart, t3cutil.ActionLogStatusSuccess, metaData)
+		r.serviceActionTaken = t3cutil.ApplyServiceActionFlagRestart
 		log.Infoln("trafficserver has been " + startStr + "ed")
 
 		if !r.Cfg.NoConfirmServiceAction {
 			log.Infoln("confirming ATS restart succeeded")
 			if err := doTail(r.Cfg, TailDiagsLogRelative, ".*", tailRestartEnd, TailRestartTimeOutMS); err != nil {
-				log.Errorln("error running tail")
+				// Without rollback, a failed confirmation is only logged.
+				if r.backup == nil {
+					log.Errorln("error running tail: ", err)
+				} else {
+					r.ServiceActionFailed = true
+					return errors.New("failed to confirm trafficserver " + startStr + ": " + err.Error())
+				}
 			}
 		} else {
 			log.Infoln("skipping ATS restart success confirmation")
@@ -1143,6 +1211,7 @@ func (r *TrafficOpsReq) StartServices(syncdsUpdate *UpdateStatus, metaData *t3cu
 			log.Infoln("ATS configuration has changed, Running 'traffic_ctl config reload' now.")
 			if _, _, err := util.ExecCommand(config.TSHome+config.TrafficCtl, "config", "reload"); err != nil {
 				t3cutil.WriteActionLog(t3cutil.ActionLogActionATSReload, t3cutil.ActionLogStatusFailure, metaData)
+				r.ServiceActionFailed = true
 
 				if *syncdsUpdate == UpdateTropsNeeded {
 					*syncdsUpdate = UpdateTropsFailed
@@ -1150,6 +1219,7 @@ func (r *TrafficOpsReq) StartServices(syncdsUpdate *UpdateStatus, metaData *t3cu
 				return errors.New("ATS configuration has changed and 'traffic_ctl config reload' failed, check ATS logs: " + err.Error())
 			}
 			t3cutil.WriteActionLog(t3cutil.ActionLogActionATSReload, t3cutil.ActionLogStatusSuccess, metaData)
+			r.serviceActionTaken = t3cutil.ApplyServiceActionFlagReload
 
 			if *syncdsUpdate == UpdateTropsNeeded {
 				*syncdsUpdate = UpdateTropsSuccessful
@@ -1159,7 +1229,15 @@ func (r *TrafficOpsReq) StartServices(syncdsUpdate *UpdateStatus, metaData *t3cu
 			if !r.Cfg.NoConfirmServiceAction {
 				log.Infoln("confirming ATS reload succeeded")
 				if err := doTail(r.Cfg, TailDiagsLogRelative, tailMatch, tailReloadEnd, TailReloadTimeOutMS); err != nil {
-					log.Errorln("error running tail: ", err)
+					// ATS only logs loading remap.config if it changed, so
+					// not seeing it is only a failure if we changed it.
+					// Without rollback, a failed confirmation is only logged.
+					if !r.RemapConfigReload || r.backup == nil {
+						log.Errorln("error running tail: ", err)
+					} else {
+						r.ServiceActionFailed = true
+						return errors.New("failed to confirm ATS reload: " + err.Error())
+					}
 				}
 			} else {
 				log.Infoln("skipping ATS reload success confirmation")
@@ -1173,15 +1251,124 @@ func (r *TrafficOpsReq) StartServices(syncdsUpdate *UpdateStatus, metaData *t3cu
 	return nil
 }
 
+// CheckHealth requests the configured health check URL until it returns a 2xx
+// response, retrying up to HealthCheckRetries times. Does nothing if no health
+// check URL is configured, in report mode, or if no config files were changed.
+// Returns nil on success or any error.
+func (r *TrafficOpsReq) CheckHealth(metaData *t3cutil.ApplyMetaData) error {
+	defer r.startPhase(t3cutil.ApplyPhaseCheckHealth)()
+
+	if r.Cfg.HealthCheckURL == "" || r.Cfg.ReportOnly || len(r.changedFiles) == 0 {
+		return nil
+	}
+	client := &http.Client{Timeout: HealthCheckTimeout}
+	err := error(nil)
+	for i := 0; i < HealthCheckRetries; i++ {
+		if i > 0 {
+			time.Sleep(HealthCheckInterval)
+		}
+		if err = checkHealthURL(client, r.Cfg.HealthCheckURL); err == nil {
+			t3cutil.WriteActionLog(t3cutil.ActionLogActionHealthCheck, t3cutil.ActionLogStatusSuccess, metaData)
+			return nil
+		}
+		log.Warnf("health check attempt %d of %d failed: %s\n", i+1, HealthCheckRetries, err.Error())
+	}
+	t3cutil.WriteActionLog(t3cutil.ActionLogActionHealthCheck, t3cutil.ActionLogStatusFailure, metaData)
+	return errors.New("health check '" + r.Cfg.HealthCheckURL + "' failed: " + err.Error())
+}
+
+func checkHealthURL(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// CanRollBack returns whether this run replaced any config files which can
+// be restored by RollBack.
+func (r *TrafficOpsReq) CanRollBack() bool {
+	return r.backup != nil && r.backup.Len() > 0
+}
+
+// RollBack restores the config files replaced by this run from the backup
+// directory, and reloads or restarts ATS again to pick them up.
+// Returns nil on success or any error.
+func (r *TrafficOpsReq) RollBack(metaData *t3cutil.ApplyMetaData) error {
+	defer r.startPhase(t3cutil.ApplyPhaseRollBack)()
+
+	if !r.CanRollBack() {
+		return errors.New("no config files were backed up")
+	}
+
+	restored, err := t3cutil.RestoreBackup(r.Cfg.BackupDir)
+	if err != nil {
+		t3cutil.WriteActionLog(t3cutil.ActionLogActionRollback, t3cutil.ActionLogStatusFailure, metaData)
+		return errors.New("restoring backup: " + err.Error())
+	}
+	for _, path := range restored {
+		log.Infof("restored '%s'\n", path)
+	}
+	r.changedFiles = restored
+	r.ServiceActionFailed = false
+
+	syncdsUpdate := UpdateTropsNotNeeded
+	if err := r.StartServices(&syncdsUpdate, metaData); err != nil {
+		t3cutil.WriteActionLog(t3cutil.ActionLogActionRollback, t3cutil.ActionLogStatusFailure, metaData)
+		return errors.New("starting services with restored config: " + err.Error())
+	}
+	t3cutil.WriteActionLog(t3cutil.ActionLogActionRollback, t3cutil.ActionLogStatusSuccess, metaData)
+	return nil
+}
+
+// ReportRollBack reports to Traffic Ops that applying the config failed and
+// was rolled back. Does nothing in report mode, or if the config is from a
+// bundle.
+func (r *TrafficOpsReq) ReportRollBack() error {
+	if r.Cfg.ReportOnly || r.Cfg.FromBundle != "" {
+		return nil
+	}
+	return sendConfigApplyFailure(r.Cfg, time.Now())
+}
+
 func (r *TrafficOpsReq) ShowUpdateStatus(flagType []string, start time.Time, curSetting, newSetting bool) {
 	for _, flag := range flagType {
 		log.Infof("%s flag currently set to %v, setting to %v took %v", flag, curSetting, newSetting, time.Since(start).Round(time.Millisecond))
 	}
 }
 
+// ReportConfigFiles reports the config files applied by a full config run to
+// Traffic Ops, so Traffic Ops can preview how they would change before updates
+// are queued. Nothing is reported unless reporting is enabled, nor for reval
+// runs, in report mode, if the config is from a bundle, or if applying the
+// config failed.
+func (r *TrafficOpsReq) ReportConfigFiles(syncdsUpdate *UpdateStatus) error {
+	defer r.startPhase(t3cutil.ApplyPhaseReportConfigFiles)()
+
+	if r.Cfg.ReportConfigFiles == t3cutil.ReportConfigFilesFlagNone || r.Cfg.ReportConfigFiles == t3cutil.ReportConfigFilesFlagInvalid {
+		return nil
+	}
+	if r.Cfg.ReportOnly || r.Cfg.FromBundle != "" || r.Cfg.Files != t3cutil.ApplyFilesFlagAll || *syncdsUpdate == UpdateTropsFailed || len(r.generatedFiles) == 0 {
+		return nil
+	}
+	return sendConfigFiles(r.Cfg, r.generatedFiles)
+}
+
 func (r *TrafficOpsReq) UpdateTrafficOps(syncdsUpdate *UpdateStatus) error {
+	defer r.startPhase(t3cutil.ApplyPhaseUpdateTrafficOps)()
+
 	var performUpdate bool
 
+	if r.Cfg.FromBundle != "" {
+		log.Infoln("Config is from bundle '" + r.Cfg.FromBundle + "', not updating Traffic Ops")
+		return nil
+	}
+
 	serverStatus, err := getUpdateStatus(r.Cfg)
 	if err != nil {
 		return errors.New("failed to update Traffic Ops: " + err.Error())
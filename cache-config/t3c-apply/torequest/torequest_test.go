@@ -138,3 +138,37 @@ func TestGetConfigFile(t *testing.T) {
 		t.Errorf("GetConfigFile('remap.config') failed, expected 'remap.config' got '" + cfg.Name + "'.")
 	}
 }
+
+func TestFinishReport(t *testing.T) {
+	trops := NewTrafficOpsReq(testCfg)
+	endPhase := trops.startPhase(t3cutil.ApplyPhaseProcessConfigFiles)
+	endPhase()
+	trops.recordChangedFile("/opt/trafficserver/etc/trafficserver/remap.config", []byte("new"), []byte("old"))
+	trops.recordChangedFile("/opt/trafficserver/etc/trafficserver/new.config", []byte("new"), nil)
+	trops.installedPkgs["trafficserver"] = struct{}{}
+	trops.serviceActionTaken = t3cutil.ApplyServiceActionFlagReload
+	recordTORequest(testCfg, t3creq, "config", trops.Report.Start, 0, 100, true)
+
+	metaData := t3cutil.NewApplyMetaData()
+	metaData.Succeeded = true
+	report := trops.FinishReport(0, "SUCCESS", metaData)
+
+	if len(report.Phases) != 1 || report.Phases[0].Name != t3cutil.ApplyPhaseProcessConfigFiles {
+		t.Errorf("expected report to have phase %v, actual %+v", t3cutil.ApplyPhaseProcessConfigFiles, report.Phases)
+	}
+	if len(report.ChangedFiles) != 2 || report.ChangedFiles[0].SHA256 != sha256Hex([]byte("new")) || report.ChangedFiles[0].PreviousSHA256 != sha256Hex([]byte("old")) || report.ChangedFiles[1].PreviousSHA256 != "" {
+		t.Errorf("expected report to have changed files with hashes, actual %+v", report.ChangedFiles)
+	}
+	if len(report.TrafficOpsRequests) != 1 || report.TrafficOpsBytesReceived != 100 {
+		t.Errorf("expected report to have 1 Traffic Ops request of 100 bytes, actual %+v", report.TrafficOpsRequests)
+	}
+	if len(report.InstalledPackages) != 1 || report.InstalledPackages[0] != "trafficserver" {
+		t.Errorf("expected report to have installed package trafficserver, actual %+v", report.InstalledPackages)
+	}
+	if report.ServiceAction != t3cutil.ApplyServiceActionFlagReload || !report.Succeeded || report.ExitReason != "SUCCESS" || report.ServerHostName != testCfg.CacheHostName {
+		t.Errorf("expected report to have run results, actual %+v", report)
+	}
+	if len(toRequests) != 0 {
+		t.Errorf("expected finishing the report to clear recorded requests, actual %+v", toRequests)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/cache-config/t3cutil"
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+
+	"github.com/pborman/getopt/v2"
+)
+
+const AppName = "t3c-explain"
+
+// Version is the application version.
+// This is overwritten by the build with the current project version.
+var Version = "0.4"
+
+// GitRevision is the git revision the application was built from.
+// This is overwritten by the build with the current project version.
+var GitRevision = "nogit"
+
+const DefaultTSHome = "/opt/trafficserver"
+
+const (
+	ExitCodeSuccess       = 0
+	ExitCodeUsageError    = 1
+	ExitCodeProvenanceErr = 2
+	ExitCodeLineNoSources = 3
+)
+
+func main() {
+	provenanceDir := getopt.StringLong("provenance-dir", 'p', t3cutil.DefaultProvenanceDir, "Directory t3c-apply wrote config line provenance to. Default is "+t3cutil.DefaultProvenanceDir)
+	tsHome := getopt.StringLong("trafficserver-home", 'R', "", "Trafficserver Package directory, whose config directory relative file names are resolved against. May also be set with the environment variable TS_HOME. Default is "+DefaultTSHome)
+	jsonOut := getopt.BoolLong("json", 'j', "Print the line provenance as JSON, rather than text")
+	version := getopt.BoolLong("version", 'V', "Print version information and exit.")
+	help := getopt.BoolLong("help", 'h', "Print usage information and exit")
+	getopt.Parse()
+
+	if *help {
+		fmt.Println(usageStr())
+		os.Exit(ExitCodeSuccess)
+	} else if *version {
+		fmt.Println(t3cutil.VersionStr(AppName, Version, GitRevision))
+		os.Exit(ExitCodeSuccess)
+	}
+
+	args := getopt.Args()
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, usageStr())
+		os.Exit(ExitCodeUsageError)
+	}
+	line, err := strconv.Atoi(args[1])
+	if err != nil || line < 1 {
+		fmt.Fprintln(os.Stderr, "line must be a positive integer, got '"+args[1]+"'")
+		os.Exit(ExitCodeUsageError)
+	}
+
+	if *tsHome == "" {
+		*tsHome = os.Getenv("TS_HOME")
+	}
+	if *tsHome == "" {
+		*tsHome = DefaultTSHome
+	}
+	cfgFilePath := args[0]
+	if !filepath.IsAbs(cfgFilePath) {
+		cfgFilePath = filepath.Join(*tsHome, "etc", "trafficserver", cfgFilePath)
+	}
+
+	prov, err := t3cutil.ReadProvenanceFile(*provenanceDir, cfgFilePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "getting provenance of '"+cfgFilePath+"' (was t3c-apply run with --provenance?): "+err.Error())
+		os.Exit(ExitCodeProvenanceErr)
+	}
+
+	lp, ok := prov.LineProvenance(line)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s:%d has no known sources; it may be a comment, or the file may not support provenance\n", cfgFilePath, line)
+		os.Exit(ExitCodeLineNoSources)
+	}
+
+	if txt, err := readLine(cfgFilePath, line); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: reading '"+cfgFilePath+"', can't verify the line is unchanged: "+err.Error())
+	} else if txt != lp.Text {
+		fmt.Fprintf(os.Stderr, "warning: %s:%d has changed since its provenance was written at %s, the sources may be wrong\n", cfgFilePath, line, prov.Generated.Format(time.RFC3339))
+	}
+
+	if *jsonOut {
+		if err := json.NewEncoder(os.Stdout).Encode(lp); err != nil {
+			fmt.Fprintln(os.Stderr, "encoding provenance: "+err.Error())
+			os.Exit(ExitCodeProvenanceErr)
+		}
+		os.Exit(ExitCodeSuccess)
+	}
+	writeExplanation(os.Stdout, cfgFilePath, lp)
+}
+
+// readLine returns the given 1-based line of the file at path, without the trailing newline.
+func readLine(path string, line int) (string, error) {
+	bts, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(string(bts), "\n")
+	if line > len(lines) {
+		return "", fmt.Errorf("file has only %d lines", len(lines))
+	}
+	return lines[line-1], nil
+}
+
+// writeExplanation writes the chain of sources of the given line, as human-readable text.
+func writeExplanation(w io.Writer, cfgFilePath string, lp atscfg.LineProvenance) {
+	fmt.Fprintf(w, "%s:%d\n", cfgFilePath, lp.Line)
+	fmt.Fprintf(w, "    %s\n", lp.Text)
+	fmt.Fprintln(w, "generated from:")
+	for _, src := range lp.Sources {
+		fmt.Fprintln(w, "    "+sourceStr(src))
+	}
+}
+
+func sourceStr(src atscfg.ProvenanceSource) string {
+	str := string(src.Type) + " '" + src.Name + "'"
+	if src.ID != nil {
+		str += " (id " + strconv.Itoa(*src.ID) + ")"
+	}
+	if src.Detail != "" {
+		str += ": " + src.Detail
+	}
+	return str
+}
+
+func usageStr() string {
+	return `usage: t3c-explain [--help] [--version]
+	[-j] [-p <provenance dir>] [-R <trafficserver home>] <file> <line>
+
+	Prints the Traffic Ops objects - Delivery Services, Parameters,
+	Topologies, Cache Groups, servers, and Server Capabilities - which the
+	given line of the given config file was generated from.
+
+	The file may be an absolute path, or relative to the ATS config
+	directory. The line is 1-based.
+
+	Provenance is only available for files written by t3c-apply with
+	--provenance.
+`
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/apache/trafficcontrol/cache-config/t3cutil"
 	"github.com/apache/trafficcontrol/lib/go-atscfg"
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
 	"github.com/apache/trafficcontrol/lib/go-log"
 
 	"github.com/pborman/getopt/v2"
@@ -50,6 +51,8 @@ type Cfg struct {
 	LogLocationDebug   string
 	LogLocationWarn    string
 	RevalOnly          bool
+	Provenance         bool
+	FromBundle         string
 	Dir                string
 	UseStrategies      t3cutil.UseStrategiesFlag
 	ViaRelease         bool
@@ -71,12 +74,32 @@ func (cfg Cfg) EventLog() log.LogLocation   { return log.LogLocation(log.LogLoca
 
 func (cfg Cfg) AppVersion() string { return t3cutil.VersionStr(AppName, cfg.Version, cfg.GitRevision) }
 
+// GenerateCfg returns the options with which config files are generated.
+func (cfg Cfg) GenerateCfg() cfgfile.Cfg {
+	return cfgfile.Cfg{
+		RevalOnly:          cfg.RevalOnly,
+		Provenance:         cfg.Provenance,
+		Dir:                cfg.Dir,
+		UseStrategies:      cfg.UseStrategies,
+		ViaRelease:         cfg.ViaRelease,
+		SetDNSLocalBind:    cfg.SetDNSLocalBind,
+		NoOutgoingIP:       cfg.NoOutgoingIP,
+		ATSMajorVersion:    cfg.ATSMajorVersion,
+		ParentComments:     cfg.ParentComments,
+		DefaultEnableH2:    cfg.DefaultEnableH2,
+		DefaultTLSVersions: cfg.DefaultTLSVersions,
+		AppVersion:         cfg.AppVersion(),
+	}
+}
+
 // GetCfg gets the application configuration, from arguments and environment variables.
 func GetCfg(appVersion string, gitRevision string) (Cfg, error) {
 	version := getopt.BoolLong("version", 'V', "Print version information and exit.")
 	listPlugins := getopt.BoolLong("list-plugins", 'l', "Print the list of plugins.")
 	help := getopt.BoolLong("help", 'h', "Print usage information and exit")
 	revalOnly := getopt.BoolLong("revalidate-only", 'y', "Whether to exclude files not named 'regex_revalidate.config'")
+	provenance := getopt.BoolLong("provenance", 'P', "Whether to include the provenance of generated lines, linking them to the Traffic Ops objects they were generated from, for config files which support it. Default is false.")
+	fromBundle := getopt.StringLong("from-bundle", 'B', "", "Read the Traffic Ops data from the given bundle file written by 't3c-request --bundle', rather than stdin. Optional.")
 	dir := getopt.StringLong("dir", 'D', "", "ATS config directory, used for config files without location parameters or with relative paths. May be blank. If blank and any required config file location parameter is missing or relative, will error.")
 	viaRelease := getopt.BoolLong("via-string-release", 'r', "Whether to use the Release value from the RPM package as a replacement for the ATS version specified in the build that is returned in the Via and Server headers from ATS.")
 	dnsLocalBind := getopt.BoolLong("dns-local-bind", 'b', "Whether to use the server's Service Addresses to set the ATS DNS local bind address.")
@@ -163,6 +186,8 @@ func GetCfg(appVersion string, gitRevision string) (Cfg, error) {
 		LogLocationDebug:   logLocationDebug,
 		ListPlugins:        *listPlugins,
 		RevalOnly:          *revalOnly,
+		Provenance:         *provenance,
+		FromBundle:         *fromBundle,
 		Dir:                *dir,
 		ViaRelease:         *viaRelease,
 		SetDNSLocalBind:    *dnsLocalBind,
@@ -69,10 +69,8 @@ func main() {
 	plugins := plugin.Get(cfg)
 	plugins.OnStartup(plugin.StartupData{Cfg: cfg})
 
-	log.Infoln("reading Traffic Ops data from stdin")
-
-	toData := &t3cutil.ConfigData{}
-	if err := json.NewDecoder(os.Stdin).Decode(toData); err != nil {
+	toData, err := readTOData(cfg)
+	if err != nil {
 		log.Errorln("reading and parsing input Traffic Ops data: " + err.Error())
 		os.Exit(config.ExitCodeErrGeneric)
 	}
@@ -103,3 +101,22 @@ func main() {
 
 	os.Exit(config.ExitCodeSuccess)
 }
+
+// readTOData reads the Traffic Ops data from the configured bundle, or stdin if there is none.
+func readTOData(cfg config.Cfg) (*t3cutil.ConfigData, error) {
+	if cfg.FromBundle != "" {
+		log.Infoln("reading Traffic Ops data from bundle '" + cfg.FromBundle + "'")
+		bundle, err := t3cutil.ReadBundleFile(cfg.FromBundle)
+		if err != nil {
+			return nil, err
+		}
+		return bundle.ConfigData()
+	}
+
+	log.Infoln("reading Traffic Ops data from stdin")
+	toData := &t3cutil.ConfigData{}
+	if err := json.NewDecoder(os.Stdin).Decode(toData); err != nil {
+		return nil, err
+	}
+	return toData, nil
+}
@@ -105,6 +105,37 @@ func PreprocessConfigFile(server *atscfg.Server, cfgFile string) string {
 	return cfgFile
 }
 
+// PreprocessProvenance returns the given line provenance of a config file, renumbered and with its text preprocessed
+// to match the preprocessed config file text txt. Preprocessing may split lines with __RETURN__ directives, in which
+// case each resulting line has the provenance of the original line.
+// Lines which can't be found in txt are omitted.
+func PreprocessProvenance(server *atscfg.Server, provenance []atscfg.LineProvenance, txt string) []atscfg.LineProvenance {
+	if len(provenance) == 0 {
+		return provenance
+	}
+	lines := strings.Split(txt, "\n")
+	preprocessed := make([]atscfg.LineProvenance, 0, len(provenance))
+	nextLine := 0 // provenance is in line order, so each line must be after the last one found
+	for _, lp := range provenance {
+		lpTxt := lp.Text
+		if strings.Contains(lpTxt, "__") {
+			lpTxt = PreprocessConfigFile(server, lpTxt)
+		}
+		for _, segment := range strings.Split(lpTxt, "\n") {
+			segment = strings.TrimSpace(segment)
+			for i := nextLine; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) != segment {
+					continue
+				}
+				preprocessed = append(preprocessed, atscfg.LineProvenance{Line: i + 1, Text: lines[i], Sources: lp.Sources})
+				nextLine = i + 1
+				break
+			}
+		}
+	}
+	return preprocessed
+}
+
 func main() {
 	flagHelp := getopt.BoolLong("help", 'h', "Print usage information and exit")
 	flagVersion := getopt.BoolLong("version", 'V', "Print version information and exit.")
@@ -146,6 +177,7 @@ func main() {
 	for fileI, file := range dataFiles.Files {
 		txt := PreprocessConfigFile(dataFiles.Data.Server, file.Text)
 		dataFiles.Files[fileI].Text = txt
+		dataFiles.Files[fileI].Provenance = PreprocessProvenance(dataFiles.Data.Server, file.Provenance, txt)
 	}
 	sort.Sort(t3cutil.ATSConfigFiles(dataFiles.Files))
 	if err := util.WriteConfigs(dataFiles.Files, os.Stdout); err != nil {
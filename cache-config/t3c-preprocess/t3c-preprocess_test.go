@@ -102,3 +102,37 @@ func TestPreprocessConfigFile(t *testing.T) {
 		}
 	})
 }
+
+func TestPreprocessProvenance(t *testing.T) {
+	server := &atscfg.Server{}
+	server.HostName = util.StrPtr("my-edge")
+	server.DomainName = util.StrPtr("example.net")
+	server.Cachegroup = util.StrPtr("my-cg")
+
+	cfgFile := "# comment\nmap a b @plugin=foo.so __RETURN__ map c d\nmap __HOSTNAME__ e\n"
+	dsSources := []atscfg.ProvenanceSource{{Type: atscfg.ProvenanceSourceDeliveryService, Name: "ds0"}}
+	provenance := []atscfg.LineProvenance{
+		{Line: 2, Text: "map a b @plugin=foo.so __RETURN__ map c d", Sources: dsSources},
+		{Line: 3, Text: "map __HOSTNAME__ e", Sources: dsSources},
+	}
+
+	txt := PreprocessConfigFile(server, cfgFile)
+	actual := PreprocessProvenance(server, provenance, txt)
+
+	expected := []atscfg.LineProvenance{
+		{Line: 2, Text: "map a b @plugin=foo.so"},
+		{Line: 3, Text: "map c d"},
+		{Line: 4, Text: "map my-edge e"},
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("PreprocessProvenance expected %v lines, actual %+v", len(expected), actual)
+	}
+	for i, lp := range expected {
+		if actual[i].Line != lp.Line || actual[i].Text != lp.Text {
+			t.Errorf("PreprocessProvenance expected line %v '%v', actual line %v '%v'", lp.Line, lp.Text, actual[i].Line, actual[i].Text)
+		}
+		if len(actual[i].Sources) != 1 || actual[i].Sources[0].Name != "ds0" {
+			t.Errorf("PreprocessProvenance expected line %v sources %+v, actual %+v", lp.Line, dsSources, actual[i].Sources)
+		}
+	}
+}
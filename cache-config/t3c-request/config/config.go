@@ -42,6 +42,10 @@ type Cfg struct {
 	LogLocationError string
 	LogLocationInfo  string
 	LoginDispersion  time.Duration
+	// Bundle is the path to write a bundle of all Traffic Ops data to, or 'stdout'. Empty if no bundle should be written.
+	Bundle string
+	// FromBundle is the path of a bundle to get data from, instead of Traffic Ops. Empty if data should be requested from Traffic Ops.
+	FromBundle string
 	t3cutil.TCCfg
 	Version     string
 	GitRevision string
@@ -74,6 +78,8 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 	revalOnlyPtr := getopt.BoolLong("reval-only", 'r', "[true | false] whether to only fetch data needed to revalidate, versus all config data. Only used if get-data is config")
 	disableProxyPtr := getopt.BoolLong("traffic-ops-disable-proxy", 'p', "[true | false] whether to not use any configure Traffic Ops proxy parameter. Only used if get-data is config")
 	toPassPtr := getopt.StringLong("traffic-ops-password", 'P', "", "Traffic Ops password. Required. May also be set with the environment variable TO_PASS    ")
+	bundlePtr := getopt.StringLong("bundle", 'b', "", "Write a bundle of all Traffic Ops data needed to generate and apply config for the cache to the given file path, or 'stdout'. Optional. May be used by t3c-generate and t3c-apply with --from-bundle to work without Traffic Ops.")
+	fromBundlePtr := getopt.StringLong("from-bundle", 'B', "", "Get data from the given bundle file written with --bundle, rather than Traffic Ops. Optional. If set, Traffic Ops is not contacted and the Traffic Ops URL, user, and password are not required.")
 	oldCfgPtr := getopt.StringLong("old-config", 'c', "", "Old config from a previous config request. Optional. May be a file path, or 'stdin' to read from stdin. Used to make conditional requests.")
 	helpPtr := getopt.BoolLong("help", 'h', "Print usage information and exit")
 	versionPtr := getopt.BoolLong("version", 'V', "Print the app version")
@@ -128,17 +134,26 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 		toPass = os.Getenv("TO_PASS")
 	}
 
-	toURLParsed, err := url.Parse(toURL)
-	if err != nil {
-		return Cfg{}, errors.New("parsing Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
-	} else if err := t3cutil.ValidateURL(toURLParsed); err != nil {
-		return Cfg{}, errors.New("invalid Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
+	if *bundlePtr != "" && *fromBundlePtr != "" {
+		return Cfg{}, errors.New("--bundle and --from-bundle may not be used together")
+	}
+
+	toURLParsed := (*url.URL)(nil)
+	if *fromBundlePtr == "" {
+		err := error(nil)
+		toURLParsed, err = url.Parse(toURL)
+		if err != nil {
+			return Cfg{}, errors.New("parsing Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
+		} else if err := t3cutil.ValidateURL(toURLParsed); err != nil {
+			return Cfg{}, errors.New("invalid Traffic Ops URL from " + urlSourceStr + " '" + toURL + "': " + err.Error())
+		}
 	}
 
 	var cacheHostName string
 	if len(*cacheHostNamePtr) > 0 {
 		cacheHostName = *cacheHostNamePtr
 	} else {
+		err := error(nil)
 		cacheHostName, err = os.Hostname()
 		if err != nil {
 			return Cfg{}, errors.New("could not get the OS hostname, please supply a hostname: " + err.Error())
@@ -152,6 +167,8 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 		LogLocationInfo:  logLocationInfo,
 		LogLocationWarn:  logLocationWarn,
 		LoginDispersion:  dispersion,
+		Bundle:           *bundlePtr,
+		FromBundle:       *fromBundlePtr,
 		TCCfg: t3cutil.TCCfg{
 			CacheHostName:  cacheHostName,
 			GetData:        *getDataPtr,
@@ -191,6 +208,8 @@ func (cfg Cfg) PrintConfig() {
 	log.Debugf("LogLocationInfo: %s\n", cfg.LogLocationInfo)
 	log.Debugf("LogLocationWarn: %s\n", cfg.LogLocationWarn)
 	log.Debugf("LoginDispersion : %s\n", cfg.LoginDispersion)
+	log.Debugf("Bundle: %s\n", cfg.Bundle)
+	log.Debugf("FromBundle: %s\n", cfg.FromBundle)
 	log.Debugf("CacheHostName: %s\n", cfg.CacheHostName)
 	log.Debugf("TOInsecure: %v\n", cfg.TOInsecure)
 	log.Debugf("TOTimeoutMS: %s\n", cfg.TOTimeoutMS)
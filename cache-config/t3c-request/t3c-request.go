@@ -20,8 +20,11 @@ package main
  */
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/apache/trafficcontrol/cache-config/t3c-request/config"
 	"github.com/apache/trafficcontrol/cache-config/t3cutil"
@@ -46,6 +49,10 @@ func main() {
 	}
 	log.Infoln("configuration initialized")
 
+	if cfg.FromBundle != "" {
+		os.Exit(writeDataFromBundle(cfg))
+	}
+
 	// login to traffic ops.
 	cfg.TCCfg.TOClient, err = toreq.New(
 		cfg.TOURL,
@@ -63,7 +70,12 @@ func main() {
 		log.Warnln("Traffic Ops does not support the latest version supported by this app! Falling back to previous major Traffic Ops API version!")
 	}
 
-	if cfg.GetData != "" {
+	if cfg.Bundle != "" {
+		if err := writeBundle(cfg); err != nil {
+			log.Errorf("writing bundle: %s\n", err.Error())
+			os.Exit(3)
+		}
+	} else if cfg.GetData != "" {
 		if err := t3cutil.WriteData(cfg.TCCfg); err != nil {
 			log.Errorf("writing data: %s\n", err.Error())
 			os.Exit(3)
@@ -71,3 +83,41 @@ func main() {
 	}
 	cfg.TCCfg.TOClient.WriteFsCookie(torequtil.CookieCachePath(cfg.TOUser))
 }
+
+// writeDataFromBundle writes the requested data from the config bundle, without contacting Traffic Ops, and returns the exit code.
+func writeDataFromBundle(cfg config.Cfg) int {
+	bundle, err := t3cutil.ReadBundleFile(cfg.FromBundle)
+	if err != nil {
+		log.Errorf("reading bundle: %s\n", err.Error())
+		return 3
+	}
+	if bundle.Manifest.CacheHostName != cfg.CacheHostName {
+		log.Warnf("bundle is for cache '%s', not '%s'; using its data anyway\n", bundle.Manifest.CacheHostName, cfg.CacheHostName)
+	}
+	log.Infof("Getting data '%s' from bundle created %s from %s\n", cfg.GetData, bundle.Manifest.Created.Format(time.RFC3339), bundle.Manifest.TrafficOpsURL)
+	if err := bundle.WriteData(cfg.GetData, os.Stdout); err != nil {
+		log.Errorf("writing data: %s\n", err.Error())
+		return 3
+	}
+	return 0
+}
+
+// writeBundle requests all data from Traffic Ops, and writes it as a bundle to the configured file or stdout.
+func writeBundle(cfg config.Cfg) error {
+	if strings.ToLower(cfg.Bundle) == "stdout" {
+		return t3cutil.WriteBundle(cfg.TCCfg, os.Stdout)
+	}
+	fi, err := os.OpenFile(cfg.Bundle, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.New("creating bundle file '" + cfg.Bundle + "': " + err.Error())
+	}
+	if err := t3cutil.WriteBundle(cfg.TCCfg, fi); err != nil {
+		fi.Close()
+		return err
+	}
+	if err := fi.Close(); err != nil {
+		return errors.New("closing bundle file '" + cfg.Bundle + "': " + err.Error())
+	}
+	log.Infof("wrote bundle '%s'\n", cfg.Bundle)
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/trafficcontrol/cache-config/t3cutil"
+	"github.com/apache/trafficcontrol/lib/go-log"
+
+	"github.com/gofrs/flock"
+	"github.com/pborman/getopt/v2"
+)
+
+const AppName = "t3c-rollback"
+
+// Version is the application version.
+// This is overwritten by the build with the current project version.
+var Version = "0.4"
+
+// GitRevision is the git revision the application was built from.
+// This is overwritten by the build with the current project version.
+var GitRevision = "nogit"
+
+const DefaultTSHome = "/opt/trafficserver"
+
+const (
+	ExitCodeSuccess        = 0
+	ExitCodeUsageError     = 1
+	ExitCodeRestoreError   = 2
+	ExitCodeServiceError   = 3
+	ExitCodeAlreadyRunning = 4
+)
+
+const LockFilePath = "/var/run/t3c.lock"
+
+const (
+	ServiceActionReload  = "reload"
+	ServiceActionRestart = "restart"
+	ServiceActionNone    = "none"
+)
+
+func main() {
+	backupDir := getopt.StringLong("backup-dir", 'b', t3cutil.DefaultBackupDir, "Directory t3c-apply backed up replaced config files to. Default is "+t3cutil.DefaultBackupDir)
+	useGit := getopt.BoolLong("git", 'g', "Restore the ATS config directory from its git repo, rather than from the backup directory")
+	gitRef := getopt.StringLong("git-ref", 'r', "", "Git commit to restore the ATS config directory to. Implies --git. Default is the last commit made by a successful t3c-apply run before HEAD")
+	tsHome := getopt.StringLong("trafficserver-home", 'R', "", "Trafficserver Package directory. May also be set with the environment variable TS_HOME. Default is "+DefaultTSHome)
+	serviceAction := getopt.EnumLong("service-action", 'a', []string{ServiceActionReload, ServiceActionRestart, ServiceActionNone}, ServiceActionReload, "[reload | restart | none] action to perform on Traffic Server after restoring config. Default is 'reload'")
+	verbose := getopt.CounterLong("verbose", 'v', `Log verbosity. Logging is output to stderr. By default, errors are logged. To log warnings, pass '-v'. To log info, pass '-vv'. To omit error logging, see '-s'`)
+	silent := getopt.BoolLong("silent", 's', `Silent. Errors are not logged, and the 'verbose' flag is ignored. If a fatal error occurs, the return code will be non-zero but no text will be output to stderr`)
+	version := getopt.BoolLong("version", 'V', "Print version information and exit.")
+	help := getopt.BoolLong("help", 'h', "Print usage information and exit")
+	getopt.Parse()
+
+	if *help {
+		fmt.Println(usageStr())
+		os.Exit(ExitCodeSuccess)
+	} else if *version {
+		fmt.Println(t3cutil.VersionStr(AppName, Version, GitRevision))
+		os.Exit(ExitCodeSuccess)
+	}
+
+	if err := log.InitCfg(newLogCfg(*silent, *verbose)); err != nil {
+		fmt.Fprintln(os.Stderr, "initializing loggers: "+err.Error())
+		os.Exit(ExitCodeUsageError)
+	}
+
+	if *tsHome == "" {
+		*tsHome = os.Getenv("TS_HOME")
+	}
+	if *tsHome == "" {
+		*tsHome = DefaultTSHome
+	}
+	tsConfigDir := filepath.Join(*tsHome, "etc", "trafficserver")
+
+	lock := flock.New(LockFilePath)
+	if locked, err := lock.TryLock(); err != nil {
+		log.Errorln("locking '" + LockFilePath + "': " + err.Error())
+		os.Exit(ExitCodeAlreadyRunning)
+	} else if !locked {
+		log.Errorln("another t3c is running, not rolling back")
+		os.Exit(ExitCodeAlreadyRunning)
+	}
+	defer lock.Unlock()
+
+	restored := []string{}
+	err := error(nil)
+	if *useGit || *gitRef != "" {
+		ref := *gitRef
+		if ref == "" {
+			if ref, err = t3cutil.LastGoodGitCommit(tsConfigDir); err != nil {
+				log.Errorln("finding last good config: " + err.Error())
+				os.Exit(ExitCodeRestoreError)
+			}
+		}
+		log.Infof("restoring '%s' to git commit '%s'\n", tsConfigDir, ref)
+		restored, err = t3cutil.RestoreGit(tsConfigDir, ref)
+	} else {
+		log.Infof("restoring config files from backup '%s'\n", *backupDir)
+		restored, err = t3cutil.RestoreBackup(*backupDir)
+	}
+	for _, path := range restored {
+		log.Infof("restored '%s'\n", path)
+	}
+	if err != nil {
+		log.Errorln("restoring config: " + err.Error())
+		os.Exit(ExitCodeRestoreError)
+	}
+	if len(restored) == 0 {
+		log.Infoln("no config files needed restored")
+		os.Exit(ExitCodeSuccess)
+	}
+
+	if err := doServiceAction(*serviceAction, *tsHome); err != nil {
+		log.Errorln(err.Error())
+		os.Exit(ExitCodeServiceError)
+	}
+	log.Infof("restored %d config files\n", len(restored))
+}
+
+// doServiceAction reloads or restarts ATS, per the given action.
+func doServiceAction(action string, tsHome string) error {
+	cmd := ""
+	args := []string{}
+	switch action {
+	case ServiceActionNone:
+		log.Infoln("service action is none, not reloading or restarting ATS")
+		return nil
+	case ServiceActionRestart:
+		cmd = "/usr/sbin/service"
+		args = []string{"trafficserver", "restart"}
+	default:
+		cmd = filepath.Join(tsHome, "bin", "traffic_ctl")
+		args = []string{"config", "reload"}
+	}
+	stdOut, stdErr, code := t3cutil.Do(cmd, args...)
+	if code != 0 {
+		return fmt.Errorf("%s %s returned code %d stdout '%s' stderr '%s'", cmd, strings.Join(args, " "), code, stdOut, stdErr)
+	}
+	log.Infof("ATS %s succeeded\n", action)
+	return nil
+}
+
+type logCfg struct {
+	errLoc  log.LogLocation
+	warnLoc log.LogLocation
+	infoLoc log.LogLocation
+}
+
+func (cfg logCfg) ErrorLog() log.LogLocation   { return cfg.errLoc }
+func (cfg logCfg) WarningLog() log.LogLocation { return cfg.warnLoc }
+func (cfg logCfg) InfoLog() log.LogLocation    { return cfg.infoLoc }
+func (cfg logCfg) DebugLog() log.LogLocation   { return cfg.infoLoc } // debug is treated as info, like the other t3c apps
+func (cfg logCfg) EventLog() log.LogLocation   { return log.LogLocationNull }
+
+func newLogCfg(silent bool, verbose int) logCfg {
+	cfg := logCfg{errLoc: log.LogLocationStderr, warnLoc: log.LogLocationNull, infoLoc: log.LogLocationNull}
+	if silent {
+		cfg.errLoc = log.LogLocationNull
+		return cfg
+	}
+	if verbose >= 1 {
+		cfg.warnLoc = log.LogLocationStderr
+	}
+	if verbose >= 2 {
+		cfg.infoLoc = log.LogLocationStderr
+	}
+	return cfg
+}
+
+func usageStr() string {
+	return `usage: t3c-rollback [--help] [--version]
+	[-b <backup dir>] [-g] [-r <git ref>] [-R <trafficserver home>] [-a <reload|restart|none>]
+
+	Restores the config files replaced by the last t3c-apply run which changed
+	any, from its backup directory, and reloads or restarts ATS.
+
+	With --git, instead restores the ATS config directory to the last commit
+	made by a successful t3c-apply run before HEAD, or to the commit given by
+	--git-ref. Only files in the ATS config directory are restored.
+`
+}
@@ -45,11 +45,16 @@ var GitRevision = "nogit"
 // defaultTimeOutMs is 15000 milliseconds, if not included in input.
 var defaultTimeOutMs = 15000
 
+// ExitCodeTimeout is the exit code when --fail-on-timeout is given and the
+// timeout is reached before the end-match is found.
+const ExitCodeTimeout = 2
+
 func main() {
 	file := getopt.StringLong("file", 'f', "", "Path to file to watch")
 	match := getopt.StringLong("match", 'm', ".*", "Regex pattern you want to match while running tail default is .*")
 	endMatch := getopt.StringLong("end-match", 'e', "^timeout", "Regex pattern that will cause tail to exit before timeout")
 	timeOutMs := getopt.Int64Long("timeout-ms", 't', int64(defaultTimeOutMs), "Timeout in milliseconds that will cause tail to exit default is 15000 MS")
+	failOnTimeout := getopt.BoolLong("fail-on-timeout", 'x', "Exit with a nonzero code if the timeout is reached before end-match is found")
 	version := getopt.BoolLong("version", 'V', "Print version information and exit.")
 	help := getopt.BoolLong("help", 'h', "Print usage information and exit")
 	getopt.Parse()
@@ -88,28 +93,35 @@ func main() {
 		os.Exit(1)
 	}
 	timer := time.NewTimer(time.Millisecond * time.Duration(timeOut))
+	ended := make(chan struct{})
 	go func() {
 		for line := range t.Lines {
 			if logMatch.MatchString(line.Text) {
 				fmt.Println(line.Text)
 			}
 			if tailStop.MatchString(line.Text) {
-				if !timer.Stop() {
-					<-timer.C
-				}
-				timer.Reset(0)
+				close(ended)
 				break
 			}
 		}
 	}()
 
-	<-timer.C
-	t.Cleanup()
+	select {
+	case <-ended:
+		timer.Stop()
+		t.Cleanup()
+	case <-timer.C:
+		t.Cleanup()
+		if *failOnTimeout {
+			log.Errorf("timed out after %vms waiting for '%v' in %v\n", timeOut, *endMatch, *file)
+			os.Exit(ExitCodeTimeout)
+		}
+	}
 }
 
 func usageStr() string {
 	return `usage: t3c-tail [--help]
-	-f <path to file> -m <regex to match> -e <regex match to exit> -t <timeout in ms>
+	-f <path to file> -m <regex to match> -e <regex match to exit> -t <timeout in ms> [-x]
 
 	file is  path to the file you want to tail
 
@@ -118,5 +130,7 @@ func usageStr() string {
 	endMatch is a regex used to exit tail when it is found in the logs with out waiting for timeout
 
 	timeOutMs is when tail will stop if endMatch isn't found default is 15000
+
+	fail-on-timeout makes tail exit with code 2 if timeOutMs is reached before endMatch is found
 	`
 }
@@ -47,6 +47,8 @@ type Cfg struct {
 	RevalApplyTime   *time.Time
 	ConfigApplyBool  *bool
 	RevalApplyBool   *bool
+	SetConfigFiles   t3cutil.ReportConfigFilesFlag
+	ConfigFailTime   *time.Time
 	t3cutil.TCCfg
 	Version     string
 	GitRevision string
@@ -75,6 +77,10 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 	configApplyTimeStringPtr := getopt.StringLong(setConfigApplyTimeFlagName, 'q', "", "[RFC3339Nano Timestamp] sets the server's config apply time")
 	const setRevalApplyTimeFlagName = "set-reval-apply-time"
 	revalApplyTimeStringPtr := getopt.StringLong(setRevalApplyTimeFlagName, 'a', "", "[RFC3339Nano Timestamp] sets the server's reval apply time")
+	const setConfigFilesFlagName = "set-config-files"
+	const setConfigApplyFailureTimeFlagName = "set-config-apply-failure-time"
+	configFailTimeStringPtr := getopt.StringLong(setConfigApplyFailureTimeFlagName, 'x', "", "[RFC3339Nano Timestamp] reports that the server failed to apply its config and rolled back at the given time")
+	setConfigFilesPtr := getopt.EnumLong(setConfigFilesFlagName, 'f', []string{string(t3cutil.ReportConfigFilesFlagHashes), string(t3cutil.ReportConfigFilesFlagText), ""}, "", "[hashes | text] Read the JSON array of config files the server applied from stdin, as output by t3c-generate, and report them to Traffic Ops, with only their hashes, or also the text of non-secure files")
 	toInsecurePtr := getopt.BoolLong("traffic-ops-insecure", 'I', "[true | false] ignore certificate errors from Traffic Ops")
 	toTimeoutMSPtr := getopt.IntLong("traffic-ops-timeout-milliseconds", 't', 30000, "Timeout in milli-seconds for Traffic Ops requests, default is 30000")
 	toURLPtr := getopt.StringLong("traffic-ops-url", 'u', "", "Traffic Ops URL. Must be the full URL, including the scheme. Required. May also be set with     the environment variable TO_URL")
@@ -104,8 +110,9 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 
 	// Verify at least one flag is passed
 	if (!getopt.IsSet(setConfigApplyTimeFlagName) && !getopt.IsSet(setRevalApplyTimeFlagName)) &&
-		(!getopt.IsSet(setConfigApplyBoolFlagName) && !getopt.IsSet(setRevalApplyBoolFlagName)) { // TODO: Remove once ATC (v7.0+) is deployed
-		fmt.Printf("Must set either %s or %s. One is at least required.\n", setConfigApplyTimeFlagName, setRevalApplyTimeFlagName)
+		(!getopt.IsSet(setConfigApplyBoolFlagName) && !getopt.IsSet(setRevalApplyBoolFlagName)) && // TODO: Remove once ATC (v7.0+) is deployed
+		!getopt.IsSet(setConfigApplyFailureTimeFlagName) && *setConfigFilesPtr == "" {
+		fmt.Printf("Must set either %s, %s, %s, or %s. One is at least required.\n", setConfigApplyTimeFlagName, setRevalApplyTimeFlagName, setConfigApplyFailureTimeFlagName, setConfigFilesFlagName)
 		os.Exit(0)
 	}
 
@@ -125,6 +132,14 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 		}
 		revalApplyTimePtr = &parsed
 	}
+	var configFailTimePtr *time.Time
+	if getopt.IsSet(setConfigApplyFailureTimeFlagName) {
+		parsed, err := time.Parse(time.RFC3339Nano, *configFailTimeStringPtr)
+		if err != nil {
+			return Cfg{}, errors.New(setConfigApplyFailureTimeFlagName + " must be a valid RFC3339Nano timestamp")
+		}
+		configFailTimePtr = &parsed
+	}
 
 	// TODO: Remove once ATC (v7.0+) is deployed
 	var configApplyBoolPtr, revalApplyBoolPtr *bool
@@ -221,6 +236,8 @@ func InitConfig(appVersion string, gitRevision string) (Cfg, error) {
 		RevalApplyTime:   revalApplyTimePtr,
 		ConfigApplyBool:  configApplyBoolPtr,
 		RevalApplyBool:   revalApplyBoolPtr,
+		SetConfigFiles:   t3cutil.StrToReportConfigFilesFlag(*setConfigFilesPtr),
+		ConfigFailTime:   configFailTimePtr,
 		TCCfg: t3cutil.TCCfg{
 			CacheHostName: cacheHostName,
 			GetData:       "update-status",
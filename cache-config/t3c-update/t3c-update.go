@@ -20,6 +20,7 @@ package main
  */
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -65,6 +66,32 @@ func main() {
 		log.Warnln("Traffic Ops does not support the latest version supported by this app! Falling back to previous major Traffic Ops API version!")
 	}
 
+	if cfg.SetConfigFiles != t3cutil.ReportConfigFilesFlagInvalid {
+		files := []t3cutil.ATSConfigFile{}
+		if err := json.NewDecoder(os.Stdin).Decode(&files); err != nil {
+			log.Errorf("reading config files from stdin: %s\n", err)
+			os.Exit(5)
+		}
+		if _, err := cfg.TCCfg.TOClient.SetServerConfigFiles(tc.CacheName(cfg.TCCfg.CacheHostName), t3cutil.ServerConfigFiles(files, cfg.SetConfigFiles == t3cutil.ReportConfigFilesFlagText)); err != nil {
+			log.Errorf("%s, %s\n", err, cfg.TCCfg.CacheHostName)
+			os.Exit(6)
+		}
+		log.Infof("reported %d config files to Traffic Ops\n", len(files))
+	}
+
+	if cfg.ConfigFailTime != nil {
+		if _, err := cfg.TCCfg.TOClient.SetServerConfigApplyFailure(tc.CacheName(cfg.TCCfg.CacheHostName), *cfg.ConfigFailTime); err != nil {
+			log.Errorf("%s, %s\n", err, cfg.TCCfg.CacheHostName)
+			os.Exit(7)
+		}
+		log.Infof("reported config apply failure at %v to Traffic Ops\n", cfg.ConfigFailTime.Format(time.RFC3339Nano))
+	}
+
+	if cfg.ConfigApplyTime == nil && cfg.RevalApplyTime == nil && cfg.ConfigApplyBool == nil && cfg.RevalApplyBool == nil {
+		cfg.TCCfg.TOClient.WriteFsCookie(torequtil.CookieCachePath(cfg.TOUser))
+		return
+	}
+
 	// *** Compatability requirement until ATC (v7.0+) is deployed with the timestamp features
 	// Use SetUpdateStatus is preferred
 	err = t3cutil.SetUpdateStatusCompat(cfg.TCCfg, tc.CacheName(cfg.TCCfg.CacheHostName), cfg.ConfigApplyTime, cfg.RevalApplyTime, cfg.ConfigApplyBool, cfg.RevalApplyBool)
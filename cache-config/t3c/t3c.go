@@ -45,9 +45,11 @@ var commands = map[string]struct{}{
 	"apply":      struct{}{},
 	"check":      struct{}{},
 	"diff":       struct{}{},
+	"explain":    struct{}{},
 	"generate":   struct{}{},
 	"preprocess": struct{}{},
 	"request":    struct{}{},
+	"rollback":   struct{}{},
 	"tail":       struct{}{},
 	"update":     struct{}{},
 }
@@ -114,9 +116,11 @@ These are the available commands:
 
   check      check that new config can be applied
   diff       diff config files, with logic like ignoring comments
+  explain    print the Traffic Ops objects a config line was generated from
   generate   generate configuration from Traffic Ops data
   preprocess preprocess generated config files
   request    request Traffic Ops data
+  rollback   restore the config replaced by the last apply
   tail       tail a log file
   update     update a cache's queue and reval status in Traffic Ops
 `
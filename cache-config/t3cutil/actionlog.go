@@ -59,6 +59,12 @@ const (
 	// ActionLogActionATSReload is calling service restart on ATS.
 	ActionLogActionATSRestart = ActionLogAction("ats-restart")
 
+	// ActionLogActionHealthCheck is checking the health check URL after applying config.
+	ActionLogActionHealthCheck = ActionLogAction("health-check")
+
+	// ActionLogActionRollback is restoring the previous config files after applying config failed.
+	ActionLogActionRollback = ActionLogAction("rollback")
+
 	// ActionLogActionApplyEnd is the end of the t3c-apply run.
 	ActionLogActionApplyEnd = ActionLogAction("apply-end")
 )
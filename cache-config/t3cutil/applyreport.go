@@ -0,0 +1,291 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// ApplyReportVersion is the version of the run report file.
+// This should be incremented with breaking changes.
+const ApplyReportVersion = 1
+
+// DefaultApplyReportPath is the default path t3c-apply writes its run report to.
+const DefaultApplyReportPath = `/var/lib/trafficcontrol-cache-config/t3c-apply-report.json`
+
+// ApplyReportMetricPrefix is the prefix of every metric in the run report
+// metrics file.
+const ApplyReportMetricPrefix = `t3c_apply_`
+
+// ApplyPhase is a phase of a t3c-apply run, as timed in the run report.
+type ApplyPhase string
+
+const (
+	ApplyPhaseCheckRevalidateState ApplyPhase = "check-revalidate-state"
+	ApplyPhaseCheckSyncDSState     ApplyPhase = "check-syncds-state"
+	ApplyPhaseProcessPackages      ApplyPhase = "process-packages"
+	ApplyPhaseCheckSystemServices  ApplyPhase = "check-system-services"
+	ApplyPhaseGetConfigFiles       ApplyPhase = "get-config-files"
+	ApplyPhaseProcessConfigFiles   ApplyPhase = "process-config-files"
+	ApplyPhaseStartServices        ApplyPhase = "start-services"
+	ApplyPhaseCheckHealth          ApplyPhase = "check-health"
+	ApplyPhaseRollBack             ApplyPhase = "rollback"
+	ApplyPhaseUpdateTrafficOps     ApplyPhase = "update-traffic-ops"
+	ApplyPhaseReportConfigFiles    ApplyPhase = "report-config-files"
+)
+
+// ApplyPhases returns every ApplyPhase, in the order they typically run.
+func ApplyPhases() []ApplyPhase {
+	return []ApplyPhase{
+		ApplyPhaseCheckRevalidateState,
+		ApplyPhaseCheckSyncDSState,
+		ApplyPhaseProcessPackages,
+		ApplyPhaseCheckSystemServices,
+		ApplyPhaseGetConfigFiles,
+		ApplyPhaseProcessConfigFiles,
+		ApplyPhaseStartServices,
+		ApplyPhaseCheckHealth,
+		ApplyPhaseRollBack,
+		ApplyPhaseUpdateTrafficOps,
+		ApplyPhaseReportConfigFiles,
+	}
+}
+
+// ApplyReport is a machine-readable report of a single t3c-apply run.
+//
+// Unlike ApplyMetaData, which records what the run did so later runs can
+// depend on it, the report records how the run went, for monitoring and
+// auditing across many caches.
+type ApplyReport struct {
+	// Version is the report version. See ApplyReportVersion.
+	Version        int    `json:"version"`
+	ServerHostName string `json:"server-hostname"`
+	AppVersion     string `json:"app-version"`
+	// Files is the --files flag of the run, e.g. 'all' or 'reval'.
+	Files      string `json:"files"`
+	ReportOnly bool   `json:"report-only"`
+
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationSeconds float64   `json:"duration-seconds"`
+
+	// Phases is the time taken by each phase of the run, in the order they
+	// were started. Phases may occur more than once, e.g. revalidating while
+	// sleeping processes config files a second time.
+	Phases []ApplyReportPhase `json:"phases"`
+
+	// TrafficOpsRequests is every request made to Traffic Ops via the
+	// t3c-request and t3c-update apps, in the order they were made.
+	// Data read from a bundle is not included.
+	TrafficOpsRequests []ApplyReportTORequest `json:"traffic-ops-requests"`
+	// TrafficOpsBytesReceived is the total bytes of data received from
+	// Traffic Ops by all TrafficOpsRequests.
+	TrafficOpsBytesReceived int `json:"traffic-ops-bytes-received"`
+	// TrafficOpsBytesSent is the total bytes of data sent to Traffic Ops by
+	// all TrafficOpsRequests.
+	TrafficOpsBytesSent int `json:"traffic-ops-bytes-sent"`
+
+	// ChangedFiles is every config file written to disk by the run.
+	ChangedFiles []ApplyReportFile `json:"changed-files"`
+
+	// ServiceAction is the action actually taken on ATS, which is 'none'
+	// if ATS wasn't reloaded or restarted.
+	ServiceAction     ApplyServiceActionFlag `json:"service-action"`
+	InstalledPackages []string               `json:"installed-packages"`
+
+	Succeeded      bool `json:"succeeded"`
+	PartialSuccess bool `json:"partial-success"`
+	RolledBack     bool `json:"rolled-back"`
+	ExitCode       int  `json:"exit-code"`
+	// ExitReason is the final message logged by the run, e.g. 'SUCCESS'.
+	ExitReason string `json:"exit-reason"`
+}
+
+// ApplyReportPhase is the time taken by a single phase of a t3c-apply run.
+type ApplyReportPhase struct {
+	Name            ApplyPhase `json:"name"`
+	Start           time.Time  `json:"start"`
+	DurationSeconds float64    `json:"duration-seconds"`
+}
+
+// ApplyReportTORequest is a single request to Traffic Ops, made by a t3c
+// sub-app.
+type ApplyReportTORequest struct {
+	// App is the sub-app which made the request, e.g. 't3c request'.
+	App string `json:"app"`
+	// Data is what was requested or sent, e.g. 'config' or 'update-status'.
+	Data            string    `json:"data"`
+	Start           time.Time `json:"start"`
+	DurationSeconds float64   `json:"duration-seconds"`
+	BytesReceived   int       `json:"bytes-received"`
+	BytesSent       int       `json:"bytes-sent"`
+	Succeeded       bool      `json:"succeeded"`
+}
+
+// ApplyReportFile is a config file changed by a t3c-apply run.
+type ApplyReportFile struct {
+	Path string `json:"path"`
+	// SHA256 is the hex SHA-256 hash of the new file contents.
+	SHA256 string `json:"sha256"`
+	// PreviousSHA256 is the hex SHA-256 hash of the file contents before the
+	// run, or empty if the file didn't exist.
+	PreviousSHA256 string `json:"previous-sha256,omitempty"`
+}
+
+// NewApplyReport creates a new, empty ApplyReport object, for a run starting
+// at the given time.
+func NewApplyReport(start time.Time) *ApplyReport {
+	return &ApplyReport{
+		Version:            ApplyReportVersion,
+		Start:              start,
+		Phases:             []ApplyReportPhase{},     // construct a slice, so JSON serializes '[]' not 'null'.
+		TrafficOpsRequests: []ApplyReportTORequest{}, // construct a slice, so JSON serializes '[]' not 'null'.
+		ChangedFiles:       []ApplyReportFile{},      // construct a slice, so JSON serializes '[]' not 'null'.
+		InstalledPackages:  []string{},               // construct a slice, so JSON serializes '[]' not 'null'.
+		ServiceAction:      ApplyServiceActionFlagNone,
+	}
+}
+
+// AddTORequests adds the given Traffic Ops requests to the report, and to its
+// byte totals.
+func (rp *ApplyReport) AddTORequests(reqs []ApplyReportTORequest) {
+	for _, req := range reqs {
+		rp.TrafficOpsRequests = append(rp.TrafficOpsRequests, req)
+		rp.TrafficOpsBytesReceived += req.BytesReceived
+		rp.TrafficOpsBytesSent += req.BytesSent
+	}
+}
+
+// Finish sets the end time and duration of the report.
+func (rp *ApplyReport) Finish(end time.Time) {
+	rp.End = end
+	rp.DurationSeconds = end.Sub(rp.Start).Seconds()
+}
+
+// Format returns the report as pretty-printed JSON, to be written to a file.
+func (rp *ApplyReport) Format() ([]byte, error) {
+	bts, err := json.MarshalIndent(rp, "", "  ")
+	if err != nil {
+		return nil, errors.New("marshalling report: " + err.Error())
+	}
+	bts = append(bts, '\n') // newline at the end of the file, so it's a valid POSIX text file
+	return bts, nil
+}
+
+// Metrics returns the report as Prometheus text-format metrics, suitable for
+// the node_exporter textfile collector.
+//
+// Only aggregate values are included, not individual files or requests, so
+// the number of series doesn't vary between runs.
+func (rp *ApplyReport) Metrics() []byte {
+	buf := &bytes.Buffer{}
+	writeMetric := func(name string, help string, typ string, labelsAndVals [][2]string) {
+		fmt.Fprintf(buf, "# HELP %s%s %s\n", ApplyReportMetricPrefix, name, help)
+		fmt.Fprintf(buf, "# TYPE %s%s %s\n", ApplyReportMetricPrefix, name, typ)
+		for _, lv := range labelsAndVals {
+			fmt.Fprintf(buf, "%s%s%s %s\n", ApplyReportMetricPrefix, name, lv[0], lv[1])
+		}
+	}
+	gauge := func(name string, help string, val string) {
+		writeMetric(name, help, "gauge", [][2]string{{"", val}})
+	}
+
+	gauge("last_run_timestamp_seconds", "Unix time the last t3c-apply run finished.", formatMetricFloat(float64(rp.End.UnixNano())/float64(time.Second)))
+	gauge("last_run_duration_seconds", "Duration of the last t3c-apply run.", formatMetricFloat(rp.DurationSeconds))
+	gauge("last_run_exit_code", "Exit code of the last t3c-apply run.", strconv.Itoa(rp.ExitCode))
+	gauge("last_run_success", "Whether the last t3c-apply run succeeded.", formatMetricBool(rp.Succeeded))
+	gauge("last_run_partial_success", "Whether the last t3c-apply run changed config but then failed.", formatMetricBool(rp.PartialSuccess))
+	gauge("last_run_rolled_back", "Whether the last t3c-apply run rolled back the config it applied.", formatMetricBool(rp.RolledBack))
+
+	phaseDurations := map[ApplyPhase]float64{}
+	for _, phase := range rp.Phases {
+		phaseDurations[phase.Name] += phase.DurationSeconds
+	}
+	phaseVals := [][2]string{}
+	for _, phase := range ApplyPhases() {
+		phaseVals = append(phaseVals, [2]string{`{phase="` + string(phase) + `"}`, formatMetricFloat(phaseDurations[phase])})
+	}
+	writeMetric("last_run_phase_duration_seconds", "Total duration of each phase of the last t3c-apply run.", "gauge", phaseVals)
+
+	failedReqs := 0
+	for _, req := range rp.TrafficOpsRequests {
+		if !req.Succeeded {
+			failedReqs++
+		}
+	}
+	gauge("last_run_traffic_ops_requests", "Number of Traffic Ops requests made by the last t3c-apply run.", strconv.Itoa(len(rp.TrafficOpsRequests)))
+	gauge("last_run_traffic_ops_request_failures", "Number of failed Traffic Ops requests made by the last t3c-apply run.", strconv.Itoa(failedReqs))
+	writeMetric("last_run_traffic_ops_bytes", "Bytes of data exchanged with Traffic Ops by the last t3c-apply run.", "gauge", [][2]string{
+		{`{direction="received"}`, strconv.Itoa(rp.TrafficOpsBytesReceived)},
+		{`{direction="sent"}`, strconv.Itoa(rp.TrafficOpsBytesSent)},
+	})
+
+	gauge("last_run_changed_files", "Number of config files changed by the last t3c-apply run.", strconv.Itoa(len(rp.ChangedFiles)))
+	gauge("last_run_installed_packages", "Number of packages installed by the last t3c-apply run.", strconv.Itoa(len(rp.InstalledPackages)))
+
+	actionVals := [][2]string{}
+	for _, action := range []ApplyServiceActionFlag{ApplyServiceActionFlagNone, ApplyServiceActionFlagReload, ApplyServiceActionFlagRestart} {
+		actionVals = append(actionVals, [2]string{`{action="` + action.String() + `"}`, formatMetricBool(rp.ServiceAction == action)})
+	}
+	writeMetric("last_run_service_action", "The action the last t3c-apply run took on ATS.", "gauge", actionVals)
+
+	return buf.Bytes()
+}
+
+func formatMetricFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+func formatMetricBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// WriteApplyReport writes the report JSON to the given path.
+//
+// The file is written atomically, so readers never see a partial report.
+func WriteApplyReport(path string, rp *ApplyReport) error {
+	bts, err := rp.Format()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, bts, 0644, os.Getuid(), os.Getgid()); err != nil {
+		return errors.New("writing report file '" + path + "': " + err.Error())
+	}
+	return nil
+}
+
+// WriteApplyReportMetrics writes the report metrics to the given path, which
+// should be in the node_exporter textfile collector directory, and end in
+// '.prom'.
+//
+// The file is written atomically, as the textfile collector requires.
+func WriteApplyReportMetrics(path string, rp *ApplyReport) error {
+	if err := writeFileAtomic(path, rp.Metrics(), 0644, os.Getuid(), os.Getgid()); err != nil {
+		return errors.New("writing metrics file '" + path + "': " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyReportMetrics(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	rp := NewApplyReport(start)
+	rp.Phases = append(rp.Phases,
+		ApplyReportPhase{Name: ApplyPhaseProcessConfigFiles, Start: start, DurationSeconds: 1.5},
+		ApplyReportPhase{Name: ApplyPhaseProcessConfigFiles, Start: start, DurationSeconds: 0.5},
+	)
+	rp.AddTORequests([]ApplyReportTORequest{
+		{App: "t3c request", Data: "config", BytesReceived: 1000, Succeeded: true},
+		{App: "t3c update", Data: "config-files", BytesSent: 200, Succeeded: false},
+	})
+	rp.ChangedFiles = append(rp.ChangedFiles, ApplyReportFile{Path: "/opt/trafficserver/etc/trafficserver/remap.config", SHA256: "abc"})
+	rp.ServiceAction = ApplyServiceActionFlagReload
+	rp.Succeeded = true
+	rp.Finish(start.Add(3 * time.Second))
+
+	if rp.TrafficOpsBytesReceived != 1000 || rp.TrafficOpsBytesSent != 200 {
+		t.Errorf("expected 1000 bytes received and 200 sent, actual %v and %v", rp.TrafficOpsBytesReceived, rp.TrafficOpsBytesSent)
+	}
+
+	metrics := string(rp.Metrics())
+	expecteds := []string{
+		"t3c_apply_last_run_timestamp_seconds 1700000003\n",
+		"t3c_apply_last_run_duration_seconds 3\n",
+		"t3c_apply_last_run_success 1\n",
+		`t3c_apply_last_run_phase_duration_seconds{phase="process-config-files"} 2` + "\n",
+		`t3c_apply_last_run_phase_duration_seconds{phase="check-health"} 0` + "\n",
+		"t3c_apply_last_run_traffic_ops_requests 2\n",
+		"t3c_apply_last_run_traffic_ops_request_failures 1\n",
+		`t3c_apply_last_run_traffic_ops_bytes{direction="received"} 1000` + "\n",
+		`t3c_apply_last_run_traffic_ops_bytes{direction="sent"} 200` + "\n",
+		"t3c_apply_last_run_changed_files 1\n",
+		`t3c_apply_last_run_service_action{action="reload"} 1` + "\n",
+		`t3c_apply_last_run_service_action{action="restart"} 0` + "\n",
+		"# TYPE t3c_apply_last_run_exit_code gauge\n",
+	}
+	for _, expected := range expecteds {
+		if !strings.Contains(metrics, expected) {
+			t.Errorf("expected metrics to contain '%s', actual:\n%s", strings.TrimSpace(expected), metrics)
+		}
+	}
+}
+
+func TestWriteApplyReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "t3c-apply-report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rp := NewApplyReport(time.Now())
+	rp.ExitReason = "SUCCESS"
+	path := filepath.Join(dir, "report.json")
+	if err := WriteApplyReport(path, rp); err != nil {
+		t.Fatalf("writing report: %v", err)
+	}
+	bts, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading report: %v", err)
+	}
+	actual := &ApplyReport{}
+	if err := json.Unmarshal(bts, actual); err != nil {
+		t.Fatalf("decoding report: %v", err)
+	}
+	if actual.Version != ApplyReportVersion || actual.ExitReason != "SUCCESS" || actual.ChangedFiles == nil {
+		t.Errorf("expected written report to match, actual %+v", actual)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("expected temp report file to be removed, actual stat error %v", err)
+	}
+}
@@ -0,0 +1,322 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultBackupDir is the default directory in which t3c-apply backs up the
+// config files it replaces, so they may be restored by a rollback.
+const DefaultBackupDir = `/var/lib/trafficcontrol-cache-config/backup`
+
+// BackupManifestFileName is the name of the file in a backup directory which
+// lists the backed up files.
+const BackupManifestFileName = `manifest.json`
+
+// backupFilesDirName is the directory in a backup directory containing the
+// contents of the backed up files.
+const backupFilesDirName = `files`
+
+// BackupManifest is the list of config files backed up by a single t3c-apply
+// run.
+type BackupManifest struct {
+	Time  time.Time    `json:"time"`
+	Files []BackupFile `json:"files"`
+}
+
+// BackupFile is a single config file backed up before t3c-apply replaced it.
+type BackupFile struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+	// Existed is whether the file existed before it was replaced. Files which
+	// did not exist are removed when the backup is restored.
+	Existed bool        `json:"existed"`
+	Mode    os.FileMode `json:"mode"`
+	UID     int         `json:"uid"`
+	GID     int         `json:"gid"`
+}
+
+// ConfigBackup backs up config files before they are replaced, into a backup
+// directory which holds the files replaced by the most recent run that
+// replaced any. The directory is only cleared when the first file of a run is
+// backed up, so a run which changes nothing does not lose the previous backup.
+type ConfigBackup struct {
+	dir      string
+	manifest BackupManifest
+	paths    map[string]struct{}
+}
+
+// NewConfigBackup returns a ConfigBackup which backs up files into dir.
+func NewConfigBackup(dir string) *ConfigBackup {
+	return &ConfigBackup{dir: dir, paths: map[string]struct{}{}}
+}
+
+// Len returns the number of files backed up.
+func (b *ConfigBackup) Len() int {
+	return len(b.manifest.Files)
+}
+
+// Add backs up the file at the given path, if it has not already been backed
+// up. It must be called before the file is replaced.
+func (b *ConfigBackup) Add(path string) error {
+	if _, ok := b.paths[path]; ok {
+		return nil
+	}
+	if len(b.paths) == 0 {
+		if err := clearBackupDir(b.dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(b.dir, backupFilesDirName), 0700); err != nil {
+			return errors.New("creating backup directory: " + err.Error())
+		}
+		b.manifest = BackupManifest{Time: time.Now()}
+	}
+
+	file := BackupFile{Path: path}
+	fi, err := os.Stat(path)
+	if err == nil {
+		body, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.New("reading '" + path + "': " + err.Error())
+		}
+		if err := ioutil.WriteFile(backupFilePath(b.dir, path), body, 0600); err != nil {
+			return errors.New("writing backup of '" + path + "': " + err.Error())
+		}
+		file.Existed = true
+		file.Mode = fi.Mode().Perm()
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			file.UID = int(st.Uid)
+			file.GID = int(st.Gid)
+		}
+	} else if !os.IsNotExist(err) {
+		return errors.New("getting info of '" + path + "': " + err.Error())
+	}
+
+	b.manifest.Files = append(b.manifest.Files, file)
+	b.paths[path] = struct{}{}
+	return writeBackupManifest(b.dir, b.manifest)
+}
+
+// clearBackupDir removes the old backup in dir, if any. Only the manifest and
+// the files directory of a backup are removed, and a non-empty directory with
+// no manifest is refused, so a mistaken backup directory never loses
+// unrelated files.
+func clearBackupDir(dir string) error {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.New("reading backup directory: " + err.Error())
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	manifestPath := filepath.Join(dir, BackupManifestFileName)
+	if _, err := os.Stat(manifestPath); os.IsNotExist(err) {
+		return errors.New("backup directory '" + dir + "' is not empty and has no " + BackupManifestFileName + ", refusing to use it")
+	} else if err != nil {
+		return errors.New("getting info of backup manifest: " + err.Error())
+	}
+	if err := os.RemoveAll(filepath.Join(dir, backupFilesDirName)); err != nil {
+		return errors.New("removing old backup files: " + err.Error())
+	}
+	if err := os.Remove(manifestPath); err != nil {
+		return errors.New("removing old backup manifest: " + err.Error())
+	}
+	return nil
+}
+
+// RestoreBackup restores the config files in the given backup directory to
+// their state before they were replaced, removing files which did not exist.
+// Returns the paths of the restored files.
+func RestoreBackup(dir string) ([]string, error) {
+	bts, err := ioutil.ReadFile(filepath.Join(dir, BackupManifestFileName))
+	if err != nil {
+		return nil, errors.New("reading backup manifest: " + err.Error())
+	}
+	manifest := BackupManifest{}
+	if err := json.Unmarshal(bts, &manifest); err != nil {
+		return nil, errors.New("decoding backup manifest: " + err.Error())
+	}
+
+	restored := []string{}
+	for _, file := range manifest.Files {
+		if !file.Existed {
+			if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+				return restored, errors.New("removing '" + file.Path + "': " + err.Error())
+			}
+			restored = append(restored, file.Path)
+			continue
+		}
+		body, err := ioutil.ReadFile(backupFilePath(dir, file.Path))
+		if err != nil {
+			return restored, errors.New("reading backup of '" + file.Path + "': " + err.Error())
+		}
+		if err := writeFileAtomic(file.Path, body, file.Mode, file.UID, file.GID); err != nil {
+			return restored, errors.New("restoring '" + file.Path + "': " + err.Error())
+		}
+		restored = append(restored, file.Path)
+	}
+	return restored, nil
+}
+
+// RestoreGit restores the files of the git repo in dir to their state in the
+// given commit, removing files added since. The owner of each restored file is
+// kept, or for a removed file, set to the owner of dir. The restored state is
+// left uncommitted. Returns the paths of the restored files.
+func RestoreGit(dir string, ref string) ([]string, error) {
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.New("getting info of '" + dir + "': " + err.Error())
+	}
+	dirUID, dirGID := fileOwner(dirInfo)
+
+	changed, err := gitLines(dir, "diff", "--name-only", "--no-renames", ref, "HEAD", "--")
+	if err != nil {
+		return nil, err
+	}
+	added, err := gitLines(dir, "diff", "--name-only", "--no-renames", "--diff-filter=A", ref, "HEAD", "--")
+	if err != nil {
+		return nil, err
+	}
+	isAdded := map[string]struct{}{}
+	for _, name := range added {
+		isAdded[name] = struct{}{}
+	}
+
+	restored := []string{}
+	for _, name := range changed {
+		path := filepath.Join(dir, name)
+		if _, ok := isAdded[name]; ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return restored, errors.New("removing '" + path + "': " + err.Error())
+			}
+			restored = append(restored, path)
+			continue
+		}
+		body, err := gitOutput(dir, "show", ref+":"+name)
+		if err != nil {
+			return restored, err
+		}
+		mode := os.FileMode(0644)
+		uid, gid := dirUID, dirGID
+		if fi, err := os.Stat(path); err == nil {
+			mode = fi.Mode().Perm()
+			uid, gid = fileOwner(fi)
+		}
+		if err := writeFileAtomic(path, body, mode, uid, gid); err != nil {
+			return restored, errors.New("restoring '" + path + "': " + err.Error())
+		}
+		restored = append(restored, path)
+	}
+	return restored, nil
+}
+
+// LastGoodGitCommit returns the hash of the most recent commit before HEAD in
+// the git repo in dir which was made by a successful t3c-apply run.
+func LastGoodGitCommit(dir string) (string, error) {
+	// t3c-apply commit messages are 't3c self <flags> success|fail <time>'.
+	lines, err := gitLines(dir, "log", "--format=%H", "-n", "1", "--grep=^t3c self .* success ", "HEAD~1")
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "", errors.New("no successful t3c-apply commit found before HEAD in '" + dir + "'")
+	}
+	return lines[0], nil
+}
+
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	stdOut := bytes.Buffer{}
+	stdErr := bytes.Buffer{}
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: in dir '%s' returned err %v msg '%s'", strings.Join(args, " "), dir, err, strings.TrimSpace(stdErr.String()))
+	}
+	return stdOut.Bytes(), nil
+}
+
+func gitLines(dir string, args ...string) ([]string, error) {
+	out, err := gitOutput(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func fileOwner(fi os.FileInfo) (int, int) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		return int(st.Uid), int(st.Gid)
+	}
+	return os.Getuid(), os.Getgid()
+}
+
+// backupFilePath returns the path in the backup directory of the backup of the
+// file at path.
+func backupFilePath(dir string, path string) string {
+	return filepath.Join(dir, backupFilesDirName, url.PathEscape(path))
+}
+
+func writeBackupManifest(dir string, manifest BackupManifest) error {
+	bts, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.New("encoding backup manifest: " + err.Error())
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, BackupManifestFileName), bts, 0600); err != nil {
+		return errors.New("writing backup manifest: " + err.Error())
+	}
+	return nil
+}
+
+// writeFileAtomic writes the file via a temp file, and sets its mode and owner.
+func writeFileAtomic(path string, body []byte, mode os.FileMode, uid int, gid int) error {
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, body, mode); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	if err := os.Chown(tmpPath, uid, gid); err != nil && !os.IsPermission(err) {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
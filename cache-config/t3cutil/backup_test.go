@@ -0,0 +1,138 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigBackupRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "t3c-backup-test")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	backupDir := filepath.Join(dir, "backup")
+	existingPath := filepath.Join(dir, "remap.config")
+	newPath := filepath.Join(dir, "new.config")
+
+	if err := ioutil.WriteFile(existingPath, []byte("old"), 0640); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	backup := NewConfigBackup(backupDir)
+	for _, path := range []string{existingPath, newPath, existingPath} {
+		if err := backup.Add(path); err != nil {
+			t.Fatalf("backing up '%s': %v", path, err)
+		}
+	}
+	if backup.Len() != 2 {
+		t.Errorf("expected 2 backed up files, actual %d", backup.Len())
+	}
+
+	if err := ioutil.WriteFile(existingPath, []byte("new"), 0640); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	if err := ioutil.WriteFile(newPath, []byte("new"), 0640); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	restored, err := RestoreBackup(backupDir)
+	if err != nil {
+		t.Fatalf("restoring backup: %v", err)
+	}
+	if len(restored) != 2 {
+		t.Errorf("expected 2 restored files, actual %d", len(restored))
+	}
+
+	if body, err := ioutil.ReadFile(existingPath); err != nil {
+		t.Errorf("reading restored file: %v", err)
+	} else if string(body) != "old" {
+		t.Errorf("expected restored file 'old', actual '%s'", string(body))
+	}
+	if fi, err := os.Stat(existingPath); err != nil {
+		t.Errorf("getting restored file info: %v", err)
+	} else if fi.Mode().Perm() != 0640 {
+		t.Errorf("expected restored file mode %#o, actual %#o", 0640, fi.Mode().Perm())
+	}
+	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+		t.Errorf("expected file which didn't exist before backup to be removed, actual stat err %v", err)
+	}
+
+	// a new run's first backup replaces the old backup
+	backup = NewConfigBackup(backupDir)
+	if err := backup.Add(newPath); err != nil {
+		t.Fatalf("backing up '%s': %v", newPath, err)
+	}
+	if restored, err := RestoreBackup(backupDir); err != nil {
+		t.Fatalf("restoring backup: %v", err)
+	} else if len(restored) != 1 || restored[0] != newPath {
+		t.Errorf("expected new backup to only restore '%s', actual %v", newPath, restored)
+	}
+}
+
+func TestConfigBackupDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "t3c-backup-test")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "remap.config")
+	if err := ioutil.WriteFile(configPath, []byte("old"), 0640); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+
+	// a non-empty directory which isn't a backup is refused, and left alone
+	otherDir := filepath.Join(dir, "other")
+	otherPath := filepath.Join(otherDir, "other.txt")
+	if err := os.Mkdir(otherDir, 0700); err != nil {
+		t.Fatalf("creating dir: %v", err)
+	}
+	if err := ioutil.WriteFile(otherPath, []byte("other"), 0600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	if err := NewConfigBackup(otherDir).Add(configPath); err == nil {
+		t.Error("expected backing up to a non-empty directory without a manifest to fail")
+	}
+	if _, err := os.Stat(otherPath); err != nil {
+		t.Errorf("expected unrelated file to be kept, actual stat err %v", err)
+	}
+
+	// replacing an old backup only removes the backup's own files
+	backupDir := filepath.Join(dir, "backup")
+	if err := NewConfigBackup(backupDir).Add(configPath); err != nil {
+		t.Fatalf("backing up '%s': %v", configPath, err)
+	}
+	keptPath := filepath.Join(backupDir, "kept.txt")
+	if err := ioutil.WriteFile(keptPath, []byte("kept"), 0600); err != nil {
+		t.Fatalf("writing file: %v", err)
+	}
+	if err := NewConfigBackup(backupDir).Add(configPath); err != nil {
+		t.Fatalf("backing up '%s' again: %v", configPath, err)
+	}
+	if _, err := os.Stat(keptPath); err != nil {
+		t.Errorf("expected file not created by the backup to be kept, actual stat err %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+)
+
+// BundleVersion is the version of the bundle format written by WriteBundle.
+// It must be incremented whenever a change is made which older apps can't read.
+const BundleVersion = 1
+
+// BundleManifestFileName is the name of the manifest file within a bundle archive.
+const BundleManifestFileName = `manifest.json`
+
+// BundleDataFileSuffix is the suffix of each data file within a bundle
+// archive, appended to the get-data name, e.g. 'config.json'.
+const BundleDataFileSuffix = `.json`
+
+// BundleManifest describes the contents of a bundle.
+type BundleManifest struct {
+	// Version is the BundleVersion of the app which wrote the bundle.
+	Version       int       `json:"version"`
+	CacheHostName string    `json:"cacheHostName"`
+	Created       time.Time `json:"created"`
+	// T3CVersion is the version of the t3c app which wrote the bundle.
+	T3CVersion string `json:"t3cVersion"`
+	// TrafficOpsURL is the Traffic Ops the data was requested from, for
+	// reference. It's never requested from again.
+	TrafficOpsURL string `json:"trafficOpsURL"`
+	// Data is the get-data names in the bundle, e.g. 'config' and 'packages'.
+	Data []string `json:"data"`
+}
+
+// Bundle is all the Traffic Ops data needed to generate and apply the config
+// of a single cache, as returned by every GetDataFuncs func.
+//
+// Bundles let t3c generate and apply config without Traffic Ops, e.g. to
+// reproduce an issue, run tests against known data, or rebuild a cache while
+// Traffic Ops is unavailable.
+type Bundle struct {
+	Manifest BundleManifest
+	// Data is the output of each get-data func, keyed by the get-data name.
+	Data map[string][]byte
+}
+
+// WriteBundle requests all get-data from Traffic Ops, and writes it to output
+// as a gzipped tar archive.
+//
+// The config data is always requested in full, regardless of cfg.RevalOnly.
+func WriteBundle(cfg TCCfg, output io.Writer) error {
+	cfg.RevalOnly = false
+
+	dataFuncs := GetDataFuncs()
+	names := []string{}
+	for name, _ := range dataFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bundle := &Bundle{
+		Manifest: BundleManifest{
+			Version:       BundleVersion,
+			CacheHostName: cfg.CacheHostName,
+			Created:       time.Now(),
+			T3CVersion:    cfg.T3CVersion,
+			Data:          names,
+		},
+		Data: map[string][]byte{},
+	}
+	if cfg.TOURL != nil {
+		bundle.Manifest.TrafficOpsURL = cfg.TOURL.String()
+	}
+
+	for _, name := range names {
+		log.Infoln("Getting bundle data '" + name + "'")
+		buf := &bytes.Buffer{}
+		if err := dataFuncs[name](cfg, buf); err != nil {
+			return errors.New("getting bundle data '" + name + "': " + err.Error())
+		}
+		bundle.Data[name] = buf.Bytes()
+	}
+	return bundle.Write(output)
+}
+
+// Write writes the bundle to output as a gzipped tar archive, containing the
+// manifest and one JSON file per get-data.
+func (bundle *Bundle) Write(output io.Writer) error {
+	manifestBts, err := json.MarshalIndent(bundle.Manifest, "", "  ")
+	if err != nil {
+		return errors.New("encoding bundle manifest: " + err.Error())
+	}
+
+	gz := gzip.NewWriter(output)
+	tw := tar.NewWriter(gz)
+	if err := writeBundleFile(tw, BundleManifestFileName, manifestBts, bundle.Manifest.Created); err != nil {
+		return err
+	}
+	for _, name := range bundle.Manifest.Data {
+		if err := writeBundleFile(tw, name+BundleDataFileSuffix, bundle.Data[name], bundle.Manifest.Created); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return errors.New("closing bundle archive: " + err.Error())
+	}
+	if err := gz.Close(); err != nil {
+		return errors.New("closing bundle compression: " + err.Error())
+	}
+	return nil
+}
+
+func writeBundleFile(tw *tar.Writer, name string, bts []byte, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(bts)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.New("writing bundle file '" + name + "' header: " + err.Error())
+	}
+	if _, err := tw.Write(bts); err != nil {
+		return errors.New("writing bundle file '" + name + "': " + err.Error())
+	}
+	return nil
+}
+
+// ReadBundleFile reads the bundle at the given path. The path may be 'stdin'
+// to read from stdin.
+func ReadBundleFile(path string) (*Bundle, error) {
+	if strings.ToLower(path) == "stdin" {
+		return ReadBundle(os.Stdin)
+	}
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("opening bundle file '" + path + "': " + err.Error())
+	}
+	defer fi.Close()
+	return ReadBundle(fi)
+}
+
+// ReadBundle reads a bundle as written by Bundle.Write.
+//
+// Returns an error if the bundle is a newer version than this app supports,
+// or is missing any data its manifest lists.
+func ReadBundle(input io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(input)
+	if err != nil {
+		return nil, errors.New("decompressing bundle: " + err.Error())
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.New("reading bundle archive: " + err.Error())
+		}
+		bts, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.New("reading bundle file '" + hdr.Name + "': " + err.Error())
+		}
+		files[hdr.Name] = bts
+	}
+
+	manifestBts, ok := files[BundleManifestFileName]
+	if !ok {
+		return nil, errors.New("bundle has no " + BundleManifestFileName + ", is it a t3c bundle?")
+	}
+	bundle := &Bundle{Data: map[string][]byte{}}
+	if err := json.Unmarshal(manifestBts, &bundle.Manifest); err != nil {
+		return nil, errors.New("decoding bundle manifest: " + err.Error())
+	}
+	if bundle.Manifest.Version < 1 || bundle.Manifest.Version > BundleVersion {
+		return nil, errors.New("bundle version " + strconv.Itoa(bundle.Manifest.Version) + " is not supported, this app supports versions 1 to " + strconv.Itoa(BundleVersion))
+	}
+	for _, name := range bundle.Manifest.Data {
+		bts, ok := files[name+BundleDataFileSuffix]
+		if !ok {
+			return nil, errors.New("bundle manifest lists data '" + name + "' which is missing from the archive")
+		}
+		bundle.Data[name] = bts
+	}
+	return bundle, nil
+}
+
+// WriteData writes the bundle's get-data of the given name to output, exactly
+// as the GetDataFuncs func would have when the bundle was created.
+func (bundle *Bundle) WriteData(getData string, output io.Writer) error {
+	bts, ok := bundle.Data[getData]
+	if !ok {
+		return errors.New("bundle has no data '" + getData + "'")
+	}
+	if _, err := output.Write(bts); err != nil {
+		return errors.New("writing bundle data '" + getData + "': " + err.Error())
+	}
+	return nil
+}
+
+// ConfigData returns the bundle's config data, as used by t3c-generate.
+func (bundle *Bundle) ConfigData() (*ConfigData, error) {
+	bts, ok := bundle.Data[`config`]
+	if !ok {
+		return nil, errors.New("bundle has no config data")
+	}
+	cfgData := &ConfigData{}
+	if err := json.Unmarshal(bts, cfgData); err != nil {
+		return nil, errors.New("decoding bundle config data: " + err.Error())
+	}
+	return cfgData, nil
+}
@@ -0,0 +1,106 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	bundle := &Bundle{
+		Manifest: BundleManifest{
+			Version:       BundleVersion,
+			CacheHostName: "mycache",
+			Created:       time.Now().Truncate(time.Second),
+			T3CVersion:    "abc123",
+			TrafficOpsURL: "https://to.example.net",
+			Data:          []string{"config", "packages"},
+		},
+		Data: map[string][]byte{
+			"config":   []byte(`{"version":"abc123"}` + "\n"),
+			"packages": []byte(`[{"name":"trafficserver","version":"9.1.2"}]` + "\n"),
+		},
+	}
+
+	buf := &bytes.Buffer{}
+	if err := bundle.Write(buf); err != nil {
+		t.Fatalf("writing bundle: %v", err)
+	}
+	actual, err := ReadBundle(buf)
+	if err != nil {
+		t.Fatalf("reading bundle: %v", err)
+	}
+	if actual.Manifest.CacheHostName != "mycache" || actual.Manifest.TrafficOpsURL != "https://to.example.net" || !actual.Manifest.Created.Equal(bundle.Manifest.Created) {
+		t.Errorf("expected manifest %+v, actual %+v", bundle.Manifest, actual.Manifest)
+	}
+
+	out := &bytes.Buffer{}
+	if err := actual.WriteData("packages", out); err != nil {
+		t.Fatalf("writing bundle data: %v", err)
+	}
+	if out.String() != string(bundle.Data["packages"]) {
+		t.Errorf("expected packages data '%s', actual '%s'", bundle.Data["packages"], out.String())
+	}
+	if err := actual.WriteData("statuses", out); err == nil {
+		t.Errorf("expected error writing data not in the bundle, actual nil")
+	}
+
+	cfgData, err := actual.ConfigData()
+	if err != nil {
+		t.Fatalf("getting bundle config data: %v", err)
+	}
+	if cfgData.Version != "abc123" {
+		t.Errorf("expected config data version 'abc123', actual '%s'", cfgData.Version)
+	}
+}
+
+func TestReadBundleVersion(t *testing.T) {
+	bundle := &Bundle{Manifest: BundleManifest{Version: BundleVersion + 1}}
+	buf := &bytes.Buffer{}
+	if err := bundle.Write(buf); err != nil {
+		t.Fatalf("writing bundle: %v", err)
+	}
+	if _, err := ReadBundle(buf); err == nil || !strings.Contains(err.Error(), "not supported") {
+		t.Errorf("expected unsupported version error reading newer bundle, actual %v", err)
+	}
+}
+
+func TestReadBundleInvalid(t *testing.T) {
+	if _, err := ReadBundle(bytes.NewReader([]byte("not a bundle"))); err == nil {
+		t.Errorf("expected error reading non-bundle, actual nil")
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	if err := writeBundleFile(tw, "config.json", []byte(`{}`), time.Now()); err != nil {
+		t.Fatalf("writing archive: %v", err)
+	}
+	tw.Close()
+	gz.Close()
+	if _, err := ReadBundle(buf); err == nil || !strings.Contains(err.Error(), BundleManifestFileName) {
+		t.Errorf("expected missing manifest error reading archive without a manifest, actual %v", err)
+	}
+}
@@ -21,6 +21,8 @@ package t3cutil
 
 import (
 	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
 )
 
 const ApplyCachePath = `/var/lib/trafficcontrol-cache-config/config-data.json`
@@ -81,17 +83,15 @@ func StrToApplyServiceActionFlag(str string) ApplyServiceActionFlag {
 	}
 }
 
-type UseStrategiesFlag string
+type UseStrategiesFlag = cfgfile.UseStrategiesFlag
 
 const (
-	UseStrategiesFlagTrue    UseStrategiesFlag = "true"
-	UseStrategiesFlagCore    UseStrategiesFlag = "core"
-	UseStrategiesFlagFalse   UseStrategiesFlag = "false"
-	UseStrategiesFlagInvalid UseStrategiesFlag = ""
+	UseStrategiesFlagTrue    = cfgfile.UseStrategiesFlagTrue
+	UseStrategiesFlagCore    = cfgfile.UseStrategiesFlagCore
+	UseStrategiesFlagFalse   = cfgfile.UseStrategiesFlagFalse
+	UseStrategiesFlagInvalid = cfgfile.UseStrategiesFlagInvalid
 )
 
-func (fl UseStrategiesFlag) String() string { return string(fl) }
-
 func StrToUseStrategiesFlag(str string) UseStrategiesFlag {
 	str = strings.ToLower(strings.TrimSpace(str))
 	switch UseStrategiesFlag(str) {
@@ -129,6 +129,37 @@ func StrToApplyFilesFlag(str string) ApplyFilesFlag {
 	}
 }
 
+// ReportConfigFilesFlag is what t3c reports to Traffic Ops about the config
+// files it applied.
+type ReportConfigFilesFlag string
+
+const (
+	ReportConfigFilesFlagInvalid ReportConfigFilesFlag = ""
+	// ReportConfigFilesFlagNone reports nothing.
+	ReportConfigFilesFlagNone ReportConfigFilesFlag = "none"
+	// ReportConfigFilesFlagHashes reports each file's hash, so Traffic Ops
+	// can tell which files would change, but not how.
+	ReportConfigFilesFlagHashes ReportConfigFilesFlag = "hashes"
+	// ReportConfigFilesFlagText reports the text of each non-secure file, so
+	// Traffic Ops can show how files would change.
+	ReportConfigFilesFlagText ReportConfigFilesFlag = "text"
+)
+
+func (fl ReportConfigFilesFlag) String() string { return string(fl) }
+
+func StrToReportConfigFilesFlag(str string) ReportConfigFilesFlag {
+	switch ReportConfigFilesFlag(strings.TrimSpace(strings.ToLower(str))) {
+	case ReportConfigFilesFlagNone:
+		return ReportConfigFilesFlagNone
+	case ReportConfigFilesFlagHashes:
+		return ReportConfigFilesFlagHashes
+	case ReportConfigFilesFlagText:
+		return ReportConfigFilesFlagText
+	default:
+		return ReportConfigFilesFlagInvalid
+	}
+}
+
 // Mode is the t3c run mode - syncds, badass, etc.
 type Mode string
 
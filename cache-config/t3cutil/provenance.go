@@ -0,0 +1,107 @@
+package t3cutil
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+)
+
+// DefaultProvenanceDir is the default directory in which t3c-apply writes the
+// provenance sidecar files of the config files it generates.
+const DefaultProvenanceDir = `/var/lib/trafficcontrol-cache-config/provenance`
+
+// ProvenanceFileSuffix is the suffix of provenance sidecar files, appended to
+// the name of the config file they describe.
+const ProvenanceFileSuffix = `.provenance.json`
+
+// ProvenanceFile is the provenance sidecar of a single generated config file,
+// linking its lines to the Traffic Ops objects they were generated from.
+type ProvenanceFile struct {
+	// Path is the absolute path of the config file.
+	Path string `json:"path"`
+	// Generated is the time the config file was generated.
+	Generated time.Time               `json:"generated"`
+	Lines     []atscfg.LineProvenance `json:"lines"`
+}
+
+// ProvenanceFilePath returns the path of the provenance sidecar of the config
+// file at the given absolute path, in the given provenance directory.
+func ProvenanceFilePath(provenanceDir string, cfgFilePath string) string {
+	return filepath.Join(provenanceDir, filepath.Clean(cfgFilePath)+ProvenanceFileSuffix)
+}
+
+// WriteProvenanceFiles writes the provenance sidecar of each of the given
+// files which has provenance to provenanceDir, and returns the number
+// written.
+func WriteProvenanceFiles(provenanceDir string, files []ATSConfigFile) (int, error) {
+	now := time.Now()
+	written := 0
+	for _, file := range files {
+		if len(file.Provenance) == 0 {
+			continue
+		}
+		cfgFilePath := filepath.Join(file.Path, file.Name)
+		sidecarPath := ProvenanceFilePath(provenanceDir, cfgFilePath)
+		bts, err := json.MarshalIndent(ProvenanceFile{Path: cfgFilePath, Generated: now, Lines: file.Provenance}, "", "  ")
+		if err != nil {
+			return written, errors.New("encoding provenance of '" + cfgFilePath + "': " + err.Error())
+		}
+		if err := os.MkdirAll(filepath.Dir(sidecarPath), 0755); err != nil {
+			return written, errors.New("creating provenance directory for '" + cfgFilePath + "': " + err.Error())
+		}
+		if err := ioutil.WriteFile(sidecarPath, bts, 0644); err != nil {
+			return written, errors.New("writing provenance of '" + cfgFilePath + "': " + err.Error())
+		}
+		written++
+	}
+	return written, nil
+}
+
+// ReadProvenanceFile reads the provenance sidecar of the config file at the
+// given absolute path, from provenanceDir.
+func ReadProvenanceFile(provenanceDir string, cfgFilePath string) (ProvenanceFile, error) {
+	bts, err := ioutil.ReadFile(ProvenanceFilePath(provenanceDir, cfgFilePath))
+	if err != nil {
+		return ProvenanceFile{}, errors.New("reading provenance file: " + err.Error())
+	}
+	prov := ProvenanceFile{}
+	if err := json.Unmarshal(bts, &prov); err != nil {
+		return ProvenanceFile{}, errors.New("decoding provenance file: " + err.Error())
+	}
+	return prov, nil
+}
+
+// LineProvenance returns the provenance of the given 1-based line number, and
+// whether the line has any.
+func (pf ProvenanceFile) LineProvenance(line int) (atscfg.LineProvenance, bool) {
+	for _, lp := range pf.Lines {
+		if lp.Line == line {
+			return lp, true
+		}
+	}
+	return atscfg.LineProvenance{}, false
+}
@@ -36,18 +36,15 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
 	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
 )
 
-type ATSConfigFile struct {
-	Name        string   `json:"name"`
-	Path        string   `json:"path"`
-	ContentType string   `json:"content_type"`
-	LineComment string   `json:"line_comment"`
-	Secure      bool     `json:"secure"`
-	Text        string   `json:"text"`
-	Warnings    []string `json:"warnings"`
-}
+// ATSConfigFile is a generated config file. It's defined in
+// lib/go-atscfg/cfgfile, so that Traffic Ops can generate config files without
+// importing cache-config.
+type ATSConfigFile = cfgfile.ATSConfigFile
 
 var installdir string
 
@@ -69,16 +66,29 @@ func InstallDir() string {
 }
 
 // ATSConfigFiles implements sort.Interface and sorts by the Location and then FileNameOnDisk, i.e. the full file path.
-type ATSConfigFiles []ATSConfigFile
-
-func (fs ATSConfigFiles) Len() int { return len(fs) }
-func (fs ATSConfigFiles) Less(i, j int) bool {
-	if fs[i].Path != fs[j].Path {
-		return fs[i].Path < fs[j].Path
+type ATSConfigFiles = cfgfile.ATSConfigFiles
+
+// ServerConfigFiles returns the given generated files as reported to Traffic
+// Ops, as the files the server applied. The text of non-secure files is only
+// reported if withText is true; secure files are always reported with only
+// their hash, never their text.
+func ServerConfigFiles(files []ATSConfigFile, withText bool) []tc.ServerConfigFile {
+	serverFiles := make([]tc.ServerConfigFile, 0, len(files))
+	for _, file := range files {
+		serverFile := tc.ServerConfigFile{
+			Name:        file.Name,
+			Path:        file.Path,
+			LineComment: file.LineComment,
+			Hash:        tc.ServerConfigFileHash(file.Text, file.LineComment),
+		}
+		if withText && !file.Secure {
+			text := file.Text
+			serverFile.Text = &text
+		}
+		serverFiles = append(serverFiles, serverFile)
 	}
-	return fs[i].Name < fs[j].Name
+	return serverFiles
 }
-func (fs ATSConfigFiles) Swap(i, j int) { fs[i], fs[j] = fs[j], fs[i] }
 
 // CommentsFilter is used to remove comment
 // lines from config files while making
@@ -352,6 +362,11 @@ type ApplyMetaData struct {
 	// to determine what was changed, what failed, and what actions need taken.
 	PartialSuccess bool `json:"partial-success"`
 
+	// RolledBack indicates that config files were changed, but reloading or
+	// restarting ATS or the health check failed, so the config files were
+	// restored to their state before the run.
+	RolledBack bool `json:"rolled-back"`
+
 	Actions []ApplyMetaDataAction `json:"actions"`
 }
 type ApplyMetaDataAction struct {
@@ -22,6 +22,8 @@ package t3cutil
 import (
 	"reflect"
 	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
 )
 
 func TestSortAndCombineStrs(t *testing.T) {
@@ -70,3 +72,29 @@ func TestSortAndCombineStrs(t *testing.T) {
 		}
 	}
 }
+
+func TestServerConfigFiles(t *testing.T) {
+	files := []ATSConfigFile{
+		{Name: "remap.config", Path: "/etc/trafficserver", LineComment: "#", Text: "# header\nmap a b\n"},
+		{Name: "a.key", Path: "/etc/trafficserver/ssl", Text: "secret", Secure: true},
+	}
+	if hashesOnly := ServerConfigFiles(files, false); hashesOnly[0].Text != nil {
+		t.Errorf("ServerConfigFiles without text expected no text actual %v", *hashesOnly[0].Text)
+	}
+	actual := ServerConfigFiles(files, true)
+	if len(actual) != 2 {
+		t.Fatalf("ServerConfigFiles expected 2 files actual %+v", actual)
+	}
+	if actual[0].Text == nil || *actual[0].Text != files[0].Text {
+		t.Errorf("ServerConfigFiles expected text of non-secure file actual %v", actual[0].Text)
+	}
+	if expected := tc.ServerConfigFileHash("map a b", "#"); actual[0].Hash != expected {
+		t.Errorf("ServerConfigFiles expected hash %v actual %v", expected, actual[0].Hash)
+	}
+	if actual[1].Text != nil {
+		t.Errorf("ServerConfigFiles expected no text for secure file actual %v", *actual[1].Text)
+	}
+	if actual[1].Hash == "" {
+		t.Errorf("ServerConfigFiles expected hash for secure file actual empty")
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-util"
 	"github.com/apache/trafficcontrol/traffic_ops/toclientlib"
 	toclient "github.com/apache/trafficcontrol/traffic_ops/v4-client"
+	toclientv5 "github.com/apache/trafficcontrol/traffic_ops/v5-client"
 )
 
 func (cl *TOClient) GetProfileByName(profileName string, reqHdr http.Header) (tc.Profile, toclientlib.ReqInf, error) {
@@ -772,6 +773,54 @@ func (cl *TOClient) SetServerUpdateStatus(cacheHostName tc.CacheName, configAppl
 	return reqInf, nil
 }
 
+// SetServerConfigFiles reports the config files the server applied to Traffic Ops.
+// Reporting config files is only in API 5.0, so this uses a 5.0 session sharing
+// the logged-in session's HTTP client and cookie.
+// Returns an error if Traffic Ops is too old to support reporting config files.
+func (cl *TOClient) SetServerConfigFiles(cacheHostName tc.CacheName, files []tc.ServerConfigFile) (toclientlib.ReqInf, error) {
+	if cl.c == nil {
+		return toclientlib.ReqInf{}, errors.New("Traffic Ops older version doesn't support reporting config files")
+	}
+	c := toclientv5.NewSession(cl.c.UserName, cl.c.Password, cl.c.URL, cl.c.UserAgentStr, cl.c.Client, false)
+
+	reqInf := toclientlib.ReqInf{}
+	err := torequtil.GetRetry(cl.NumRetries, "set_server_config_files_"+string(cacheHostName), nil, func(obj interface{}) error {
+		_, toReqInf, err := c.SetServerConfigFiles(string(cacheHostName), files, toclientv5.NewRequestOptions())
+		reqInf = toReqInf
+		if err != nil {
+			return errors.New("setting server config files in Traffic Ops '" + torequtil.MaybeIPStr(reqInf.RemoteAddr) + "': " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return reqInf, errors.New("setting server config files, which requires Traffic Ops API 5.0: " + err.Error())
+	}
+	return reqInf, nil
+}
+
+// SetServerConfigApplyFailure reports to Traffic Ops that the server failed to
+// apply its config at the given time.
+// Returns an error if Traffic Ops is too old to support reporting failures.
+func (cl *TOClient) SetServerConfigApplyFailure(cacheHostName tc.CacheName, failureTime time.Time) (toclientlib.ReqInf, error) {
+	if cl.c == nil {
+		return toclientlib.ReqInf{}, errors.New("Traffic Ops older version doesn't support reporting config apply failures")
+	}
+
+	reqInf := toclientlib.ReqInf{}
+	err := torequtil.GetRetry(cl.NumRetries, "set_server_config_apply_failure_"+string(cacheHostName), nil, func(obj interface{}) error {
+		_, toReqInf, err := cl.c.SetServerConfigApplyFailureTime(string(cacheHostName), failureTime, *ReqOpts(nil))
+		reqInf = toReqInf
+		if err != nil {
+			return errors.New("setting server config apply failure in Traffic Ops '" + torequtil.MaybeIPStr(reqInf.RemoteAddr) + "': " + err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return reqInf, errors.New("setting server config apply failure: " + err.Error())
+	}
+	return reqInf, nil
+}
+
 // SetServerUpdateStatusBoolCompat sets the server's update and reval statuses in Traffic Ops.
 // *** Compatability requirement until ATC (v7.0+) is deployed with the timestamp features
 func (cl *TOClient) SetServerUpdateStatusBoolCompat(cacheHostName tc.CacheName, configApply *time.Time, revalApply *time.Time, configApplyBool *bool, revalApplyBool *bool) (toclientlib.ReqInf, error) {
@@ -18,6 +18,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
@@ -26,6 +27,7 @@ import (
 	"github.com/apache/trafficcontrol/grove/plugin"
 
 	"github.com/apache/trafficcontrol/grove/remap"
+	"github.com/apache/trafficcontrol/grove/remapdata"
 	"github.com/apache/trafficcontrol/grove/stat"
 	"github.com/apache/trafficcontrol/grove/thread"
 	"github.com/apache/trafficcontrol/grove/web"
@@ -69,7 +71,9 @@ type Handler struct {
 	httpConns       *web.ConnMap
 	httpsConns      *web.ConnMap
 	interfaceName   string
-	requestID       uint64 // Atomic - DO NOT access or modify without atomic operations
+	requestID       uint64              // Atomic - DO NOT access or modify without atomic operations
+	revalidating    map[string]struct{} // cache keys with a stale-while-revalidate request in progress
+	revalidatingM   *sync.Mutex
 	// keyThrottlers     Throttlers
 	// nocacheThrottlers Throttlers
 }
@@ -120,7 +124,7 @@ func NewHandler(
 
 	return &Handler{
 		remapper:        remapper,
-		getter:          thread.NewGetter(),
+		getter:          thread.NewGetter(stats),
 		ruleThrottlers:  makeRuleThrottlers(remapper, ruleLimit),
 		strictRFC:       strictRFC,
 		scheme:          scheme,
@@ -134,6 +138,8 @@ func NewHandler(
 		httpConns:       httpConns,
 		httpsConns:      httpsConns,
 		interfaceName:   interfaceName,
+		revalidating:    map[string]struct{}{},
+		revalidatingM:   &sync.Mutex{},
 		// keyThrottlers:     NewThrottlers(keyLimit),
 		// nocacheThrottlers: NewThrottlers(nocacheLimit),
 	}
@@ -173,7 +179,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	conn := (*web.InterceptConn)(nil)
-	if realConn, ok := h.conns.Get(r.RemoteAddr); !ok {
+	if r.ProtoMajor == 3 {
+		// HTTP/3 is served over QUIC, whose connections aren't net.Conns, so there's no InterceptConn to get.
+		h.stats.AddHTTP3Request()
+	} else if realConn, ok := h.conns.Get(r.RemoteAddr); !ok {
 		log.Infof("RemoteAddr '%v' not in Conns (reqid %v)\n", r.RemoteAddr, reqID)
 	} else {
 		if conn, ok = realConn.(*web.InterceptConn); !ok {
@@ -267,11 +276,34 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reqHeaders := r.Header
 	canReuseStored := rfc.CanReuseStored(reqHeaders, cacheObj.RespHeaders, reqCacheControl, cacheObj.RespCacheControl, cacheObj.ReqHeaders, cacheObj.ReqRespTime, cacheObj.RespRespTime, h.strictRFC)
 
+	// purged and invalidated objects must be revalidated or refetched, and are never served stale
+	invalidation, invalidated := remappingProducer.Invalidated(r, cacheObj.ReqRespTime)
+	if invalidated && invalidation == remapdata.InvalidationTypeRefetch {
+		log.Debugf("cache.Handler.ServeHTTP: '%v' invalidated, refetching (reqid %v)\n", cacheKey, reqID)
+		canReuseStored = rfc.ReuseCannot
+	} else if (invalidated || cacheObj.MustRevalidate) && canReuseStored != rfc.ReuseCannot {
+		log.Debugf("cache.Handler.ServeHTTP: '%v' invalidated, revalidating (reqid %v)\n", cacheKey, reqID)
+		invalidated = true
+		canReuseStored = rfc.ReuseMustRevalidate
+	}
+
 	if canReuseStored != rfc.ReuseCan { // run the BeforeParentRequest hook for revalidations / ReuseCannot
 		beforeParentRequestData := plugin.BeforeParentRequestData{Req: r, RemapRule: remappingProducer.Name()}
 		h.plugins.OnBeforeParentRequest(remappingProducer.PluginCfg(), pluginContext, beforeParentRequestData)
 	}
 
+	staleIfError := false
+	if !invalidated && (canReuseStored == rfc.ReuseMustRevalidate || canReuseStored == rfc.ReuseMustRevalidateCanStale) {
+		staleWhileRevalidate := false
+		staleWhileRevalidate, staleIfError = staleAllowed(reqCacheControl, cacheObj, remappingProducer.StaleWhileRevalidate(), remappingProducer.StaleIfError())
+		if staleWhileRevalidate {
+			log.Debugf("cache.Handler.ServeHTTP: '%v' stale, serving while revalidating (reqid %v)\n", cacheKey, reqID)
+			h.revalidateAsync(r, cacheObj, retrier, cacheKey, reqID)
+			h.stats.AddStaleWhileRevalidateHit(r.Host)
+			canReuseStored = rfc.ReuseCan
+		}
+	}
+
 	switch canReuseStored {
 	case rfc.ReuseCan:
 		log.Debugf("cache.Handler.ServeHTTP: '%v' cache hit! (reqid %v)\n", cacheKey, reqID)
@@ -285,8 +317,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	case rfc.ReuseMustRevalidate:
 		log.Debugf("cache.Handler.ServeHTTP: '%v' must revalidate (reqid %v)\n", cacheKey, reqID)
+		oldCacheObj := cacheObj
 		cacheObj, reqHost, err = retrier.Get(r, cacheObj)
-		if err != nil {
+		if staleIfError && isStaleIfError(cacheObj, err) {
+			log.Errorf("retrying get error - serving stale-if-error: %v (reqid %v)\n", err, reqID)
+			cacheObj, reqHost = oldCacheObj, nil
+			h.stats.AddStaleIfErrorHit(r.Host)
+			canReuseStored = rfc.ReuseCan
+		} else if err != nil {
 			log.Errorf("retrying get error: %v (reqid %v)\n", err, reqID)
 			responder.Do()
 			return
@@ -295,7 +333,12 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		log.Debugf("cache.Handler.ServeHTTP: '%v' must revalidate (but allowed stale) (reqid %v)\n", cacheKey, reqID)
 		oldCacheObj := cacheObj
 		cacheObj, reqHost, err = retrier.Get(r, cacheObj)
-		if err != nil {
+		if staleIfError && isStaleIfError(cacheObj, err) {
+			log.Errorf("retrying get error - serving stale-if-error: %v (reqid %v)\n", err, reqID)
+			cacheObj, reqHost = oldCacheObj, nil
+			h.stats.AddStaleIfErrorHit(r.Host)
+			canReuseStored = rfc.ReuseCan
+		} else if err != nil {
 			log.Errorf("retrying get error - serving stale as allowed: %v (reqid %v)\n", err, reqID)
 			cacheObj = oldCacheObj
 		}
@@ -59,10 +59,15 @@ func (r *Retrier) Get(req *http.Request, obj *cacheobj.CacheObj) (*cacheobj.Cach
 		canReuse := func(cacheObj *cacheobj.CacheObj) bool {
 			return cacheobj.CanReuse(r.ReqHdr, r.ReqCacheControl, cacheObj, r.H.strictRFC, true)
 		}
+		keepStale := false
+		if obj != nil {
+			_, keepStale = staleAllowed(r.ReqCacheControl, obj, r.RemappingProducer.StaleWhileRevalidate(), r.RemappingProducer.StaleIfError())
+		}
 		getAndCache := func() *cacheobj.CacheObj {
-			return GetAndCache(remapping.Request, remapping.ProxyURL, remapping.CacheKey, remapping.Name, remapping.Request.Header, r.ReqTime, r.H.strictRFC, remapping.Cache, r.H.ruleThrottlers[remapping.Name], obj, remapping.Timeout, retryFailures, remapping.RetryNum, remapping.RetryCodes, remapping.Transport, r.ReqID)
+			return GetAndCache(remapping.Request, remapping.ProxyURL, remapping.CacheKey, remapping.Name, remapping.Request.Header, r.ReqTime, r.H.strictRFC, remapping.Cache, r.H.ruleThrottlers[remapping.Name], obj, keepStale, remapping.Timeout, retryFailures, remapping.RetryNum, remapping.RetryCodes, remapping.Transport, r.ReqID)
 		}
-		gotObj, getReqID := r.H.getter.Get(remapping.CacheKey, getAndCache, canReuse, r.ReqID)
+		getOpts := thread.GetOpts{Collapse: r.RemappingProducer.CollapsedForwarding(), StatsKey: req.Host}
+		gotObj, getReqID := r.H.getter.Get(remapping.CacheKey, getAndCache, canReuse, r.ReqID, getOpts)
 
 		req := remapping.Request
 		log.Debugf("Retrier.Get Y URI %v %v %v remapping.CacheKey %v rule %v parent %v code %v headers %+v len(body) %v getterid %v (reqid %v)\n", req.URL.Scheme, req.URL.Host, req.URL.EscapedPath(), remapping.CacheKey, remapping.Name, remapping.ProxyURL, gotObj.Code, gotObj.RespHeaders, len(gotObj.Body), getReqID, r.ReqID)
@@ -104,6 +109,7 @@ const ModifiedSinceHdr = "If-Modified-Since"
 
 // GetAndCache makes a client request for the given `http.Request` and caches it if `CanCache`.
 // THe `ruleThrottler` may be nil, in which case the request will be unthrottled.
+// If `keepStale`, the `revalidateObj` may still be served stale if revalidating it returns an error, per RFC5861§4, so an error doesn't replace it in the cache.
 func GetAndCache(
 	req *http.Request,
 	proxyURL *url.URL,
@@ -115,6 +121,7 @@ func GetAndCache(
 	cache icache.Cache,
 	ruleThrottler thread.Throttler,
 	revalidateObj *cacheobj.CacheObj,
+	keepStale bool,
 	timeout time.Duration,
 	cacheFailure bool,
 	retryNum int,
@@ -170,6 +177,9 @@ func GetAndCache(
 			if !rfc.CanCache(req.Method, reqHeader, respCode, respHeader, strictRFC) {
 				return obj // return without caching
 			}
+			if _, ok := rfc.StaleIfErrorCodes[respCode]; ok && keepStale {
+				return obj // don't replace the stored object with an error, which may still be served stale
+			}
 		} else {
 			log.Debugf("GetAndCache revalidating %v len(revalidateObj.Body) %v (reqid %v)\n", cacheKey, len(revalidateObj.Body), reqID)
 			// must copy, because this cache object may be concurrently read by other goroutines
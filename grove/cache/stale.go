@@ -0,0 +1,86 @@
+package cache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+)
+
+// staleAllowed returns whether the given stale cached object may be served while it's revalidated in the background, and whether it may be served if revalidating it fails, per RFC5861. The swrOverride and sieOverride are the remap rule overrides of the stale-while-revalidate and stale-if-error windows, which take precedence over the request and response Cache-Control if not nil.
+func staleAllowed(reqCC rfc.CacheControlMap, obj *cacheobj.CacheObj, swrOverride *time.Duration, sieOverride *time.Duration) (bool, bool) {
+	if !rfc.CanServeStale(obj.RespCacheControl) {
+		return false, false
+	}
+	staleness := rfc.Staleness(obj.RespHeaders, obj.RespCacheControl, obj.ReqRespTime, obj.RespRespTime)
+	if staleness < 0 {
+		return false, false
+	}
+
+	swr, _ := rfc.StaleWhileRevalidate(obj.RespCacheControl)
+	if swrOverride != nil {
+		swr = *swrOverride
+	}
+	sie, _ := rfc.StaleIfError(reqCC, obj.RespCacheControl)
+	if sieOverride != nil {
+		sie = *sieOverride
+	}
+
+	// a client explicitly asking for an end-to-end reload must not be given a stale object without trying the parent
+	canWhileRevalidate := staleness < swr && !reqCC.Has("no-cache")
+	return canWhileRevalidate, staleness < sie
+}
+
+// isStaleIfError returns whether the result of revalidating a cached object is an error which permits serving the object stale, per RFC5861§4.
+func isStaleIfError(obj *cacheobj.CacheObj, err error) bool {
+	if err != nil || obj == nil {
+		return true
+	}
+	_, ok := rfc.StaleIfErrorCodes[obj.Code]
+	return ok
+}
+
+// revalidateAsync revalidates the given stale object with the parent in the background. The request goes through the Retrier, and thus the Getter, so requests which can't be served stale are collapsed into it. Only one background revalidation per cache key is made at a time; if one is already in progress, this does nothing.
+func (h *Handler) revalidateAsync(r *http.Request, obj *cacheobj.CacheObj, retrier *Retrier, cacheKey string, reqID uint64) {
+	h.revalidatingM.Lock()
+	if _, ok := h.revalidating[cacheKey]; ok {
+		h.revalidatingM.Unlock()
+		log.Debugf("cache.Handler.revalidateAsync: '%v' already revalidating (reqid %v)\n", cacheKey, reqID)
+		return
+	}
+	h.revalidating[cacheKey] = struct{}{}
+	h.revalidatingM.Unlock()
+
+	req := r.Clone(context.Background()) // the client request isn't valid after the handler returns
+	go func() {
+		defer func() {
+			h.revalidatingM.Lock()
+			delete(h.revalidating, cacheKey)
+			h.revalidatingM.Unlock()
+		}()
+		newObj, _, err := retrier.Get(req, obj)
+		if err != nil {
+			log.Errorf("background revalidation error for '%v': %v (reqid %v)\n", cacheKey, err, reqID)
+			return
+		}
+		log.Debugf("cache.Handler.revalidateAsync: '%v' revalidated with code %v (reqid %v)\n", cacheKey, newObj.OriginCode, reqID)
+	}()
+}
@@ -0,0 +1,132 @@
+package cache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/memcache"
+	"github.com/apache/trafficcontrol/grove/thread"
+
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+)
+
+// staleObj returns a cached object with the given Cache-Control, which expired 30 seconds ago.
+func staleObj(cacheControl string) *cacheobj.CacheObj {
+	now := time.Now()
+	respTime := now.Add(-90 * time.Second)
+	respHdr := http.Header{}
+	respHdr.Set("Cache-Control", cacheControl)
+	respHdr.Set("Date", respTime.Format(time.RFC1123))
+	return cacheobj.New(http.Header{}, []byte("foo"), http.StatusOK, http.StatusOK, "", respHdr, respTime, respTime, respTime, respTime)
+}
+
+func TestStaleAllowed(t *testing.T) {
+	dur := func(d time.Duration) *time.Duration { return &d }
+
+	type testCase struct {
+		name        string
+		reqCC       string
+		respCC      string
+		swrOverride *time.Duration
+		sieOverride *time.Duration
+		expectedSWR bool
+		expectedSIE bool
+	}
+	testCases := []testCase{
+		{name: "no directives", respCC: "max-age=60"},
+		{name: "within windows", respCC: "max-age=60, stale-while-revalidate=120, stale-if-error=120", expectedSWR: true, expectedSIE: true},
+		{name: "outside windows", respCC: "max-age=60, stale-while-revalidate=10, stale-if-error=10"},
+		{name: "request stale-if-error", reqCC: "stale-if-error=120", respCC: "max-age=60", expectedSIE: true},
+		{name: "request no-cache", reqCC: "no-cache", respCC: "max-age=60, stale-while-revalidate=120, stale-if-error=120", expectedSIE: true},
+		{name: "must-revalidate", respCC: "max-age=60, must-revalidate, stale-while-revalidate=120, stale-if-error=120"},
+		{name: "overrides enable", respCC: "max-age=60", swrOverride: dur(time.Minute), sieOverride: dur(time.Minute), expectedSWR: true, expectedSIE: true},
+		{name: "overrides disable", respCC: "max-age=60, stale-while-revalidate=120, stale-if-error=120", swrOverride: dur(0), sieOverride: dur(0)},
+	}
+
+	for _, tc := range testCases {
+		reqHdr := http.Header{}
+		if tc.reqCC != "" {
+			reqHdr.Set("Cache-Control", tc.reqCC)
+		}
+		swr, sie := staleAllowed(rfc.ParseCacheControl(reqHdr), staleObj(tc.respCC), tc.swrOverride, tc.sieOverride)
+		if swr != tc.expectedSWR {
+			t.Errorf("%v: staleAllowed stale-while-revalidate expected %v actual %v", tc.name, tc.expectedSWR, swr)
+		}
+		if sie != tc.expectedSIE {
+			t.Errorf("%v: staleAllowed stale-if-error expected %v actual %v", tc.name, tc.expectedSIE, sie)
+		}
+	}
+}
+
+func TestIsStaleIfError(t *testing.T) {
+	obj := staleObj("max-age=60")
+	if !isStaleIfError(nil, errors.New("connection refused")) {
+		t.Errorf("isStaleIfError with error expected true actual false")
+	}
+	for _, code := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		obj.Code = code
+		if !isStaleIfError(obj, nil) {
+			t.Errorf("isStaleIfError code %v expected true actual false", code)
+		}
+	}
+	for _, code := range []int{http.StatusOK, http.StatusNotModified, http.StatusNotFound} {
+		obj.Code = code
+		if isStaleIfError(obj, nil) {
+			t.Errorf("isStaleIfError code %v expected false actual true", code)
+		}
+	}
+}
+
+func TestGetAndCacheRevalidationError(t *testing.T) {
+	parent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Date", time.Now().Format(time.RFC1123))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer parent.Close()
+
+	for _, keepStale := range []bool{true, false} {
+		const cacheKey = "foo"
+		obj := staleObj("max-age=60, stale-if-error=600")
+		cache := memcache.New(1024 * 1024)
+		cache.Add(cacheKey, obj)
+
+		req, err := http.NewRequest(http.MethodGet, parent.URL, nil)
+		if err != nil {
+			t.Fatalf("creating request: %v", err)
+		}
+		got := GetAndCache(req, nil, cacheKey, "rule", http.Header{}, time.Now(), false, cache, thread.NewNoThrottler(), obj, keepStale, time.Second, false, 0, map[int]struct{}{}, &http.Transport{}, 0)
+		if got.Code != http.StatusServiceUnavailable {
+			t.Errorf("GetAndCache keepStale %v expected code %v actual %v", keepStale, http.StatusServiceUnavailable, got.Code)
+		}
+
+		cached, ok := cache.Peek(cacheKey)
+		if !ok {
+			t.Fatalf("GetAndCache keepStale %v expected an object cached, actual none", keepStale)
+		}
+		if keepStale && cached != obj {
+			t.Errorf("GetAndCache keepStale %v expected the stale object kept, actual code %v", keepStale, cached.Code)
+		}
+		if !keepStale && cached.Code != http.StatusServiceUnavailable {
+			t.Errorf("GetAndCache keepStale %v expected the error cached, actual code %v", keepStale, cached.Code)
+		}
+	}
+}
@@ -35,6 +35,7 @@ type CacheObj struct {
 	LastModified     time.Time // the origin LastModified if it exists, or Date if it doesn't
 	Size             uint64
 	HitCount         uint64 // the number of times this object was hit
+	MustRevalidate   bool   // whether the object was purged with a REFRESH, and must be revalidated with the parent before it's used again
 }
 
 // ComputeSize computes the size of the given CacheObj. This computation is expensive, as the headers must be iterated over. Thus, the size should be computed once and stored, not computed on-the-fly for every new request for the cached object.
@@ -31,6 +31,8 @@ type Config struct {
 	Port         int  `json:"port"`
 	HTTPSPort    int  `json:"https_port"`
 	DisableHTTP2 bool `json:"disable_http2"`
+	// HTTP3Port is the UDP port to serve HTTP/3 (QUIC) on, with the same certificates as HTTPS. HTTP/3 is advertised to HTTPS clients with an Alt-Svc header. If 0, HTTP/3 is not served.
+	HTTP3Port int `json:"http3_port"`
 	// CacheSizeBytes is the size of the memory cache, in bytes.
 	CacheSizeBytes int    `json:"cache_size_bytes"`
 	RemapRulesFile string `json:"remap_rules_file"`
@@ -61,6 +63,18 @@ type Config struct {
 	CacheFiles           map[string][]CacheFile `json:"cache_files"`
 	// FileMemBytes is the amount of memory to use as an LRU in front of each name in CacheFiles, that is, each named group of files. E.g. if there are 10 files, the amount of memory used will be 10*FileMemBytes+CacheSizeBytes.
 	FileMemBytes int `json:"file_mem_bytes"`
+	// CacheFilesIndexSyncIntervalMS is how often each file in CacheFiles writes the access times of its objects to disk, so the least-recently-used objects can still be evicted first after a restart. Objects accessed since the last sync lose their recency if Grove is killed rather than stopped.
+	CacheFilesIndexSyncIntervalMS int `json:"cache_files_index_sync_interval_ms"`
+	// PurgeAuthToken is the bearer token required by the http_purge plugin. If it's empty, all purge requests are forbidden.
+	PurgeAuthToken string `json:"purge_auth_token"`
+	// ParentHealthTrafficMonitors are the URLs of the Traffic Monitors to poll the CRStates of, to skip parents which are down in consistent-hash parent selection. If empty, parent health isn't polled.
+	ParentHealthTrafficMonitors []string `json:"parent_health_traffic_monitors"`
+	// ParentHealthPollIntervalMS is how often Traffic Monitor CRStates are polled for parent health.
+	ParentHealthPollIntervalMS int `json:"parent_health_poll_interval_ms"`
+	// ParentHealthPollTimeoutMS is the timeout of each Traffic Monitor CRStates request.
+	ParentHealthPollTimeoutMS int `json:"parent_health_poll_timeout_ms"`
+	// ParentHealthPushAuthToken is the bearer token required to push CRStates to the http_parent_health plugin. If it's empty, all pushes are forbidden.
+	ParentHealthPushAuthToken string `json:"parent_health_push_auth_token"`
 }
 
 type CacheFile struct {
@@ -88,27 +102,31 @@ const MSPerSec = 1000
 
 // DefaultConfig is the default configuration for the application, if no configuration file is given, or if a given config setting doesn't exist in the config file.
 var DefaultConfig = Config{
-	RFCCompliant:           true,
-	Port:                   80,
-	DisableHTTP2:           false,
-	HTTPSPort:              443,
-	CacheSizeBytes:         bytesPerGibibyte,
-	RemapRulesFile:         "remap.config",
-	ConcurrentRuleRequests: 100000,
-	ConnectionClose:        false,
-	LogLocationError:       log.LogLocationStderr,
-	LogLocationWarning:     log.LogLocationStdout,
-	LogLocationInfo:        log.LogLocationNull,
-	LogLocationDebug:       log.LogLocationNull,
-	LogLocationEvent:       log.LogLocationStdout,
-	ReqTimeoutMS:           30 * MSPerSec,
-	ReqKeepAliveMS:         30 * MSPerSec,
-	ReqMaxIdleConns:        100,
-	ReqIdleConnTimeoutMS:   90 * MSPerSec,
-	ServerIdleTimeoutMS:    10 * MSPerSec,
-	ServerWriteTimeoutMS:   3 * MSPerSec,
-	ServerReadTimeoutMS:    3 * MSPerSec,
-	FileMemBytes:           bytesPerMebibyte * 100,
+	RFCCompliant:                  true,
+	Port:                          80,
+	DisableHTTP2:                  false,
+	HTTPSPort:                     443,
+	HTTP3Port:                     0,
+	CacheSizeBytes:                bytesPerGibibyte,
+	RemapRulesFile:                "remap.config",
+	ConcurrentRuleRequests:        100000,
+	ConnectionClose:               false,
+	LogLocationError:              log.LogLocationStderr,
+	LogLocationWarning:            log.LogLocationStdout,
+	LogLocationInfo:               log.LogLocationNull,
+	LogLocationDebug:              log.LogLocationNull,
+	LogLocationEvent:              log.LogLocationStdout,
+	ReqTimeoutMS:                  30 * MSPerSec,
+	ReqKeepAliveMS:                30 * MSPerSec,
+	ReqMaxIdleConns:               100,
+	ReqIdleConnTimeoutMS:          90 * MSPerSec,
+	ServerIdleTimeoutMS:           10 * MSPerSec,
+	ServerWriteTimeoutMS:          3 * MSPerSec,
+	ServerReadTimeoutMS:           3 * MSPerSec,
+	FileMemBytes:                  bytesPerMebibyte * 100,
+	CacheFilesIndexSyncIntervalMS: 60 * MSPerSec,
+	ParentHealthPollIntervalMS:    5 * MSPerSec,
+	ParentHealthPollTimeoutMS:     2 * MSPerSec,
 }
 
 // LoadConfig loads the given config file. If an empty string is passed, the default config is returned.
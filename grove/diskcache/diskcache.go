@@ -16,8 +16,11 @@ package diskcache
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
 	"errors"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -34,48 +37,250 @@ type DiskCache struct {
 	sizeBytes    uint64
 	maxSizeBytes uint64
 	lru          *lru.LRU
+
+	// accessed is the last access time of each key accessed since the index was last synced to disk.
+	accessed  map[string]int64
+	accessedM sync.Mutex
+	syncStop  chan struct{}
+	syncDone  chan struct{}
+
+	restoredObjs  uint64
+	restoreTotal  uint64
+	restoreFinish uint32
 }
 
 const BucketName = "b"
 
-func New(path string, cacheSizeBytes uint64) (*DiskCache, error) {
+// IndexBucketName is the name of the bucket indexing each object in BucketName, with the same key and a value of its size and last access time. It is updated in the same transaction as BucketName when objects are added or removed, while access times are synced periodically.
+const IndexBucketName = "i"
+
+// MetaBucketName is the name of the bucket of information about the database itself.
+const MetaBucketName = "m"
+
+// IndexedKey is the key in MetaBucketName which exists if every object in BucketName has an entry in IndexBucketName. Databases created by older versions of Grove have no index, and are indexed when they are first restored.
+const IndexedKey = "indexed"
+
+// DefaultIndexSyncInterval is the default interval at which object access times are written to disk.
+const DefaultIndexSyncInterval = time.Minute
+
+const indexValLen = 16
+
+func New(path string, cacheSizeBytes uint64, indexSyncInterval time.Duration) (*DiskCache, error) {
 	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
 	if err != nil {
 		return nil, errors.New("opening database '" + path + "': " + err.Error())
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists([]byte(BucketName)); err != nil {
-			return errors.New("creating bucket: " + err.Error())
+		newDB := tx.Bucket([]byte(BucketName)) == nil
+		if newDB {
+			if _, err := tx.CreateBucket([]byte(BucketName)); err != nil {
+				return errors.New("creating bucket: " + err.Error())
+			}
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(IndexBucketName)); err != nil {
+			return errors.New("creating index bucket: " + err.Error())
+		}
+		meta, err := tx.CreateBucketIfNotExists([]byte(MetaBucketName))
+		if err != nil {
+			return errors.New("creating meta bucket: " + err.Error())
+		}
+		if newDB {
+			return meta.Put([]byte(IndexedKey), []byte{1}) // a new database is trivially indexed
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, errors.New("creating bucket for database '" + path + "': " + err.Error())
+		db.Close()
+		return nil, errors.New("creating buckets for database '" + path + "': " + err.Error())
 	}
 
-	return &DiskCache{db: db, maxSizeBytes: cacheSizeBytes, lru: lru.NewLRU(), sizeBytes: 0}, nil
+	if indexSyncInterval <= 0 {
+		indexSyncInterval = DefaultIndexSyncInterval
+	}
+	c := &DiskCache{
+		db:            db,
+		maxSizeBytes:  cacheSizeBytes,
+		lru:           lru.NewLRU(),
+		sizeBytes:     0,
+		accessed:      map[string]int64{},
+		syncStop:      make(chan struct{}),
+		syncDone:      make(chan struct{}),
+		restoreFinish: 1, // nothing to restore until ResetAfterRestart is called
+	}
+	go c.syncIndexLoop(indexSyncInterval)
+	return c, nil
 }
 
-// ResetAfterRestart rebuilds the LRU with an arbirtrary order and sets sizeBytes. This seems crazy, but it is better than doing nothing, sice gc is based on the LRU and sizeBytes. In the future, we may want to periodically sync the LRU to disk, but we'll still need to iterate over all keys in the disk DB to avoid orphaning objects.
+// ResetAfterRestart restores the LRU and sizeBytes from the index of the objects on disk, in a goroutine. Objects are restored in the order they were last accessed, as of the last time the index was synced, so the least-recently-used objects are still evicted first after a restart.
+//
+// Objects may be added and requested while the LRU is being restored, but garbage collection doesn't start until it's done, because objects not yet restored can't be evicted.
+//
+// If the database has no index, because it was created by an older version of Grove, every object is indexed first, with an arbitrary order.
+//
 // Note: this assumes the LRU is empty. Don't run twice
 func (c *DiskCache) ResetAfterRestart() {
-	go c.db.View(func(tx *bolt.Tx) error {
+	atomic.StoreUint32(&c.restoreFinish, 0)
+	go func() {
+		start := time.Now()
 		log.Infof("Starting cache recovery from disk for: %s... ", c.db.Path())
-		size := 0
-		b := tx.Bucket([]byte(BucketName))
+		if err := c.restore(); err != nil {
+			log.Errorln("DiskCache recovering '" + c.db.Path() + "': " + err.Error())
+		}
+		size := c.Size()
+		log.Infof("Cache recovery from disk for %s done (%d objects, %d bytes) in %v. ", c.db.Path(), atomic.LoadUint64(&c.restoredObjs), size, time.Since(start))
+		if size > c.maxSizeBytes {
+			c.gc(size)
+		}
+		atomic.StoreUint32(&c.restoreFinish, 1)
+	}()
+}
 
-		cursor := b.Cursor()
+type indexEntry struct {
+	key        string
+	sizeBytes  uint64
+	accessTime int64
+}
+
+func (c *DiskCache) restore() error {
+	if err := c.createIndex(); err != nil {
+		return errors.New("indexing: " + err.Error())
+	}
 
-		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
-			c.lru.Add(string(k), uint64(len(v)))
-			size += len(v)
+	entries := []indexEntry{}
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(IndexBucketName))
+		if b == nil {
+			return errors.New("index bucket does not exist")
+		}
+		return b.ForEach(func(k, v []byte) error {
+			sizeBytes, accessTime, ok := decodeIndexVal(v)
+			if !ok {
+				log.Warnf("DiskCache recovering '%s': malformed index entry for '%s', ignoring", c.db.Path(), string(k))
+				return nil
+			}
+			entries = append(entries, indexEntry{key: string(k), sizeBytes: sizeBytes, accessTime: accessTime})
+			return nil
+		})
+	})
+	if err != nil {
+		return errors.New("reading index: " + err.Error())
+	}
+	atomic.StoreUint64(&c.restoreTotal, uint64(len(entries)))
+
+	// Restore from the most recently used, so keys added since the restart stay newer than every restored key.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].accessTime > entries[j].accessTime })
+	for _, entry := range entries {
+		if c.lru.AddOldest(entry.key, entry.sizeBytes) {
+			atomic.AddUint64(&c.sizeBytes, entry.sizeBytes)
 		}
+		atomic.AddUint64(&c.restoredObjs, 1)
+	}
+	return nil
+}
+
+// createIndex indexes every object of a database created before objects were indexed. Objects are given an access time of zero, so they're evicted before any object accessed since.
+func (c *DiskCache) createIndex() error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket([]byte(MetaBucketName))
+		objs := tx.Bucket([]byte(BucketName))
+		index := tx.Bucket([]byte(IndexBucketName))
+		if meta == nil || objs == nil || index == nil {
+			return errors.New("bucket does not exist")
+		}
+		if meta.Get([]byte(IndexedKey)) != nil {
+			return nil
+		}
+		log.Infof("DiskCache '%s' has no index, indexing all objects", c.db.Path())
+		err := objs.ForEach(func(k, v []byte) error {
+			if index.Get(k) != nil {
+				return nil
+			}
+			return index.Put(k, encodeIndexVal(uint64(len(v)), 0))
+		})
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte(IndexedKey), []byte{1})
+	})
+}
+
+// RestoreProgress returns the number of objects restored by ResetAfterRestart so far, the total number of objects to restore, or 0 if the index hasn't been read yet, and whether restoring is complete.
+func (c *DiskCache) RestoreProgress() (uint64, uint64, bool) {
+	return atomic.LoadUint64(&c.restoredObjs), atomic.LoadUint64(&c.restoreTotal), atomic.LoadUint32(&c.restoreFinish) == 1
+}
 
-		atomic.AddUint64(&c.sizeBytes, uint64(size))
-		log.Infof("Cache recovery from disk for %s done (%d bytes). ", c.db.Path(), c.sizeBytes)
+func (c *DiskCache) restored() bool {
+	return atomic.LoadUint32(&c.restoreFinish) == 1
+}
+
+func encodeIndexVal(sizeBytes uint64, accessTime int64) []byte {
+	v := make([]byte, indexValLen)
+	binary.BigEndian.PutUint64(v, sizeBytes)
+	binary.BigEndian.PutUint64(v[8:], uint64(accessTime))
+	return v
+}
+
+func decodeIndexVal(v []byte) (uint64, int64, bool) {
+	if len(v) != indexValLen {
+		return 0, 0, false
+	}
+	return binary.BigEndian.Uint64(v), int64(binary.BigEndian.Uint64(v[8:])), true
+}
+
+// touched records that the key was accessed, to be written to the index by the next sync.
+func (c *DiskCache) touched(key string) {
+	now := time.Now().UnixNano()
+	c.accessedM.Lock()
+	c.accessed[key] = now
+	c.accessedM.Unlock()
+}
+
+func (c *DiskCache) syncIndexLoop(interval time.Duration) {
+	defer close(c.syncDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.syncStop:
+			return
+		case <-ticker.C:
+			c.syncIndex()
+		}
+	}
+}
+
+// syncIndex writes the access times of keys accessed since the last sync to the index. Keys which have since been removed, or added again, are ignored.
+func (c *DiskCache) syncIndex() {
+	c.accessedM.Lock()
+	accessed := c.accessed
+	c.accessed = map[string]int64{}
+	c.accessedM.Unlock()
+	if len(accessed) == 0 {
+		return
+	}
+
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(IndexBucketName))
+		if b == nil {
+			return errors.New("index bucket does not exist")
+		}
+		for key, accessTime := range accessed {
+			sizeBytes, oldAccessTime, ok := decodeIndexVal(b.Get([]byte(key)))
+			if !ok || oldAccessTime >= accessTime {
+				continue // removed, or added again since it was accessed
+			}
+			if err := b.Put([]byte(key), encodeIndexVal(sizeBytes, accessTime)); err != nil {
+				return err
+			}
+		}
 		return nil
 	})
+	if err != nil {
+		log.Errorln("DiskCache syncing index of '" + c.db.Path() + "': " + err.Error())
+		return
+	}
+	log.Debugf("DiskCache synced %d access times to the index of '%s'\n", len(accessed), c.db.Path())
 }
 
 // Add takes a key and value to add. Returns whether an eviction occurred
@@ -96,20 +301,24 @@ func (c *DiskCache) Add(key string, val *cacheobj.CacheObj) bool {
 
 	err := c.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(BucketName))
-		if b == nil {
+		index := tx.Bucket([]byte(IndexBucketName))
+		if b == nil || index == nil {
 			return errors.New("bucket does not exist")
 		}
-		return b.Put([]byte(key), valBytes)
+		if err := b.Put([]byte(key), valBytes); err != nil {
+			return err
+		}
+		return index.Put([]byte(key), encodeIndexVal(uint64(len(valBytes)), time.Now().UnixNano()))
 	})
 	if err != nil {
 		log.Errorln("DiskCache.Add inserting '" + key + "' in database: " + err.Error())
 		return eviction
 	}
 
-	c.lru.Add(key, uint64(len(valBytes)))
-
-	newSizeBytes := atomic.AddUint64(&c.sizeBytes, uint64(len(valBytes)))
-	if newSizeBytes > c.maxSizeBytes {
+	// If the key already existed, its old value was replaced, so only the difference in size is added.
+	oldSizeBytes := c.lru.Add(key, uint64(len(valBytes)))
+	newSizeBytes := atomic.AddUint64(&c.sizeBytes, uint64(len(valBytes))-oldSizeBytes)
+	if newSizeBytes > c.maxSizeBytes && c.restored() {
 		go c.gc(newSizeBytes)
 	}
 
@@ -133,12 +342,14 @@ func (c *DiskCache) gc(cacheSizeBytes uint64) {
 		log.Debugf("DiskCache.gc deleting key '" + key + "'")
 		err := c.db.Update(func(tx *bolt.Tx) error {
 			b := tx.Bucket([]byte(BucketName))
-			if b == nil {
+			index := tx.Bucket([]byte(IndexBucketName))
+			if b == nil || index == nil {
 				return errors.New("bucket does not exist")
 			}
-			b.Delete([]byte(key))
-
-			return b.Delete([]byte(key))
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+			return index.Delete([]byte(key))
 		})
 		if err != nil {
 			log.Errorln("removing '" + key + "' from cache: " + err.Error())
@@ -150,9 +361,13 @@ func (c *DiskCache) gc(cacheSizeBytes uint64) {
 
 // Get takes a key, and returns its value, and whether it was found, and updates the lru-ness and hitcount
 func (c *DiskCache) Get(key string) (*cacheobj.CacheObj, bool) {
-	val, found := c.Peek(key)
+	val, sizeBytes, found := c.peek(key)
 	if found {
-		c.lru.Add(key, val.Size) // TODO directly call c.ll.MoveToFront
+		// If the key isn't in the LRU, it hasn't been restored yet since a restart. Adding it here means the restore will skip it, so its size is added here instead.
+		if c.lru.Add(key, sizeBytes) == 0 {
+			atomic.AddUint64(&c.sizeBytes, sizeBytes)
+		}
+		c.touched(key)
 		log.Debugln("DiskCache.Get getting '" + key + "' from cache and updating LRU")
 		atomic.AddUint64(&val.HitCount, 1)
 		return val, true
@@ -161,8 +376,21 @@ func (c *DiskCache) Get(key string) (*cacheobj.CacheObj, bool) {
 
 }
 
+// Touch makes the key the most-recently-used, without reading it from disk. This is used by caches in front of the DiskCache, so objects they serve aren't evicted from the DiskCache as if they were never requested. Keys which haven't been restored since a restart are ignored.
+func (c *DiskCache) Touch(key string) {
+	if c.lru.Touch(key) {
+		c.touched(key)
+	}
+}
+
 // Peek takes a key, and returns its value, and whether it was found, without changing the lru-ness or hitcount
 func (c *DiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
+	val, _, found := c.peek(key)
+	return val, found
+}
+
+// peek returns the value of the key, its size on disk, and whether it was found.
+func (c *DiskCache) peek(key string) (*cacheobj.CacheObj, uint64, bool) {
 	log.Debugln("DiskCache.Get key '" + key + "'")
 	valBytes := []byte(nil)
 
@@ -176,30 +404,59 @@ func (c *DiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
 	})
 	if err != nil {
 		log.Errorln("DiskCache.Peek getting '" + key + "' from cache: " + err.Error())
-		return nil, false
+		return nil, 0, false
 	}
 
 	if valBytes == nil {
 		log.Debugln("DiskCache.Peek key '" + key + "' CACHE MISS")
-		return nil, false
+		return nil, 0, false
 	}
 
 	buf := bytes.NewBuffer(valBytes)
 	val := cacheobj.CacheObj{}
 	if err := gob.NewDecoder(buf).Decode(&val); err != nil {
 		log.Errorln("DiskCache.Peek decoding '" + key + "' from cache: " + err.Error())
-		return nil, false
+		return nil, 0, false
 	}
 
 	log.Debugln("DiskCache.Peek key '" + key + "' CACHE HIT")
-	return &val, true
+	return &val, uint64(len(valBytes)), true
+}
+
+// Remove deletes the key and its index entry from the database. Returns whether the key existed.
+func (c *DiskCache) Remove(key string) bool {
+	existed := false
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(BucketName))
+		index := tx.Bucket([]byte(IndexBucketName))
+		if b == nil || index == nil {
+			return errors.New("bucket does not exist")
+		}
+		existed = b.Get([]byte(key)) != nil
+		if err := b.Delete([]byte(key)); err != nil {
+			return err
+		}
+		return index.Delete([]byte(key))
+	})
+	if err != nil {
+		log.Errorln("DiskCache.Remove removing '" + key + "' from database: " + err.Error())
+		return false
+	}
+	if sizeBytes, ok := c.lru.Remove(key); ok {
+		atomic.AddUint64(&c.sizeBytes, ^uint64(sizeBytes-1)) // subtract sizeBytes
+	}
+	return existed
 }
 
 func (c *DiskCache) Size() uint64 {
 	return atomic.LoadUint64(&c.sizeBytes)
 }
 
+// Close stops syncing the index, writes any access times not yet synced, and closes the database.
 func (c *DiskCache) Close() {
+	close(c.syncStop)
+	<-c.syncDone
+	c.syncIndex()
 	c.db.Close()
 }
 
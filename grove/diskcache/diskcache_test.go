@@ -0,0 +1,146 @@
+package diskcache
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/gob"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestObj(body string) *cacheobj.CacheObj {
+	return &cacheobj.CacheObj{Body: []byte(body), Code: 200, Size: uint64(len(body))}
+}
+
+func openRestored(t *testing.T, path string, maxSizeBytes uint64) *DiskCache {
+	t.Helper()
+	c, err := New(path, maxSizeBytes, time.Hour)
+	if err != nil {
+		t.Fatalf("creating disk cache: %v", err)
+	}
+	c.ResetAfterRestart()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, _, done := c.RestoreProgress(); done {
+			return c
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the disk cache to be restored")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRestoreLRUOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	c := openRestored(t, path, 1<<30)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		c.Add(key, newTestObj("body of "+key))
+		time.Sleep(time.Millisecond)
+	}
+	c.Get("a")
+	time.Sleep(time.Millisecond)
+	c.Touch("b")
+	time.Sleep(time.Millisecond)
+	c.Add("c", newTestObj("a new, longer body of c"))
+	expectedKeys := []string{"d", "a", "b", "c"}
+	if keys := c.Keys(); !reflect.DeepEqual(keys, expectedKeys) {
+		t.Fatalf("expected keys %v before restart, actual: %v", expectedKeys, keys)
+	}
+	size := c.Size()
+	c.Close()
+
+	c = openRestored(t, path, 1<<30)
+	if keys := c.Keys(); !reflect.DeepEqual(keys, expectedKeys) {
+		t.Errorf("expected keys %v after restart, actual: %v", expectedKeys, keys)
+	}
+	if c.Size() != size {
+		t.Errorf("expected size %d after restart, actual: %d", size, c.Size())
+	}
+	if restored, total, _ := c.RestoreProgress(); restored != 4 || total != 4 {
+		t.Errorf("expected 4 of 4 objects restored, actual: %d of %d", restored, total)
+	}
+	c.Close()
+
+	// a cache too small for every object evicts the least recently used after restoring
+	c = openRestored(t, path, size-1)
+	if _, ok := c.Peek("d"); ok {
+		t.Error("expected least recently used object 'd' to be evicted after restart")
+	}
+	if _, ok := c.Peek("c"); !ok {
+		t.Error("expected most recently used object 'c' to be kept after restart")
+	}
+	c.Close()
+}
+
+func TestRestoreUnindexed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+
+	// create a database the way older versions did, without an index
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	expectedSize := uint64(0)
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucket([]byte(BucketName))
+		if err != nil {
+			return err
+		}
+		for _, key := range []string{"a", "b", "c"} {
+			buf := bytes.Buffer{}
+			if err := gob.NewEncoder(&buf).Encode(newTestObj("body of " + key)); err != nil {
+				return err
+			}
+			expectedSize += uint64(buf.Len())
+			if err := b.Put([]byte(key), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("writing unindexed database: %v", err)
+	}
+	db.Close()
+
+	c := openRestored(t, path, 1<<30)
+	defer c.Close()
+	if keys := c.Keys(); len(keys) != 3 {
+		t.Errorf("expected 3 keys restored from an unindexed database, actual: %v", keys)
+	}
+	if c.Size() != expectedSize {
+		t.Errorf("expected size %d restored from an unindexed database, actual: %d", expectedSize, c.Size())
+	}
+	err = c.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(MetaBucketName)).Get([]byte(IndexedKey)) == nil {
+			t.Error("expected the database to be marked indexed")
+		}
+		if n := tx.Bucket([]byte(IndexBucketName)).Stats().KeyN; n != 3 {
+			t.Errorf("expected 3 index entries, actual: %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading index: %v", err)
+	}
+}
@@ -16,6 +16,7 @@ package diskcache
 
 import (
 	"errors"
+	"time"
 
 	"github.com/apache/trafficcontrol/grove/cacheobj"
 	"github.com/apache/trafficcontrol/grove/config"
@@ -28,10 +29,11 @@ import (
 // MultiDiskCache is a disk cache using multiple files. It exists primarily to allow caching across multiple physical disks, but may be used for other purposes. For example, it may be more performant to use multiple files, or it may be advantageous to keep each remap rule in its own file. Keys are evenly distributed across the given files via consistent hashing.
 type MultiDiskCache []*DiskCache
 
-func NewMulti(files []config.CacheFile) (*MultiDiskCache, error) {
+// NewMulti creates a MultiDiskCache of the given files, and starts restoring each file's LRU. The indexSyncInterval is how often object access times are written to disk, to restore the LRU from after a restart.
+func NewMulti(files []config.CacheFile, indexSyncInterval time.Duration) (*MultiDiskCache, error) {
 	caches := make([]*DiskCache, len(files), len(files))
 	for i, file := range files {
-		cache, err := New(file.Path, file.Bytes)
+		cache, err := New(file.Path, file.Bytes, indexSyncInterval)
 		if err != nil {
 			return nil, errors.New("creating disk cache '" + file.Path + "': " + err.Error())
 		}
@@ -60,12 +62,22 @@ func (c *MultiDiskCache) Get(key string) (*cacheobj.CacheObj, bool) {
 	return (*c)[i].Get(key)
 }
 
+// Touch makes the key the most-recently-used in the DiskCache it's mapped to.
+func (c *MultiDiskCache) Touch(key string) {
+	(*c)[c.keyIdx(key)].Touch(key)
+}
+
 func (c *MultiDiskCache) Peek(key string) (*cacheobj.CacheObj, bool) {
 	i := c.keyIdx(key)
 	log.Debugf("MultiDiskCache.Get key '%+v' mapped to %+v\n", key, i)
 	return (*c)[i].Peek(key)
 }
 
+// Remove removes the key from the DiskCache it's mapped to. Returns whether the key existed.
+func (c *MultiDiskCache) Remove(key string) bool {
+	return (*c)[c.keyIdx(key)].Remove(key)
+}
+
 func (c *MultiDiskCache) Size() uint64 {
 	sum := uint64(0)
 	for _, cache := range *c {
@@ -74,6 +86,18 @@ func (c *MultiDiskCache) Size() uint64 {
 	return sum
 }
 
+// RestoreProgress returns the sum of the RestoreProgress of each DiskCache, and whether all of them have finished restoring.
+func (c *MultiDiskCache) RestoreProgress() (uint64, uint64, bool) {
+	restored, total, done := uint64(0), uint64(0), true
+	for _, cache := range *c {
+		cRestored, cTotal, cDone := cache.RestoreProgress()
+		restored += cRestored
+		total += cTotal
+		done = done && cDone
+	}
+	return restored, total, done
+}
+
 func (c *MultiDiskCache) Close() {
 	for _, cache := range *c {
 		cache.Close()
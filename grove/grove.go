@@ -31,6 +31,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/quic-go/quic-go/http3"
 	"golang.org/x/net/http2"
 	"golang.org/x/sys/unix"
 
@@ -41,6 +42,7 @@ import (
 	"github.com/apache/trafficcontrol/grove/diskcache"
 	"github.com/apache/trafficcontrol/grove/icache"
 	"github.com/apache/trafficcontrol/grove/memcache"
+	"github.com/apache/trafficcontrol/grove/parenthealth"
 	"github.com/apache/trafficcontrol/grove/plugin"
 	"github.com/apache/trafficcontrol/grove/remap"
 	"github.com/apache/trafficcontrol/grove/remapdata"
@@ -51,6 +53,9 @@ import (
 
 const ShutdownTimeout = 60 * time.Second
 
+// AltSvcMaxAge is how long HTTPS clients are told HTTP/3 is available, when it's served.
+const AltSvcMaxAge = 24 * time.Hour
+
 func main() {
 	runtime.GOMAXPROCS(32) // DEBUG
 	configFileName := flag.String("cfg", "", "The config file path")
@@ -81,11 +86,12 @@ func main() {
 	}
 	log.Init(eventW, errW, warnW, infoW, debugW)
 
-	caches, err := createCaches(cfg.CacheFiles, uint64(cfg.FileMemBytes), uint64(cfg.CacheSizeBytes))
+	caches, err := createCaches(cfg.CacheFiles, uint64(cfg.FileMemBytes), uint64(cfg.CacheSizeBytes), time.Duration(cfg.CacheFilesIndexSyncIntervalMS)*time.Millisecond)
 	if err != nil {
 		log.Errorln("starting service: creating caches: " + err.Error())
 		os.Exit(1)
 	}
+	closeCachesOnSignal(caches, unix.SIGTERM, unix.SIGINT)
 
 	reqTimeout := time.Duration(cfg.ReqTimeoutMS) * time.Millisecond
 	reqKeepAlive := time.Duration(cfg.ReqKeepAliveMS) * time.Millisecond
@@ -93,8 +99,11 @@ func main() {
 	reqIdleConnTimeout := time.Duration(cfg.ReqIdleConnTimeoutMS) * time.Millisecond
 	baseTransport := remap.NewRemappingTransport(reqTimeout, reqKeepAlive, reqMaxIdleConns, reqIdleConnTimeout)
 
+	parentHealth := parenthealth.New()
+	parentHealthPoller := startParentHealthPoller(parentHealth, cfg)
+
 	plugins := plugin.Get(cfg.Plugins)
-	remapper, err := remap.LoadRemapper(cfg.RemapRulesFile, plugins.LoadFuncs(), caches, baseTransport)
+	remapper, err := remap.LoadRemapper(cfg.RemapRulesFile, plugins.LoadFuncs(), caches, baseTransport, parentHealth)
 	if err != nil {
 		log.Errorf("starting service: loading remap rules: %v\n", err)
 		os.Exit(1)
@@ -130,8 +139,22 @@ func main() {
 		}
 	}
 
+	http3Conns := (*web.HTTP3Conns)(nil)
+	http3Listener := net.PacketConn(nil)
+	http3TLSConfig := (*tls.Config)(nil)
+	if cfg.HTTP3Port != 0 {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			log.Errorf("http3_port %v is configured, but no certificate is, not serving HTTP/3\n", cfg.HTTP3Port)
+		} else if http3Listener, http3TLSConfig, err = web.ListenHTTP3(fmt.Sprintf(":%d", cfg.HTTP3Port), certs); err != nil {
+			log.Errorf("creating HTTP/3 listener %v: %v\n", cfg.HTTP3Port, err)
+			os.Exit(1)
+		} else {
+			http3Conns = web.NewHTTP3Conns()
+		}
+	}
+
 	// TODO pass total size for all file groups?
-	stats := stat.New(remapper.Rules(), caches, uint64(cfg.CacheSizeBytes), httpConns, httpsConns, Version)
+	stats := stat.New(remapper.Rules(), caches, uint64(cfg.CacheSizeBytes), httpConns, httpsConns, http3Conns, Version)
 
 	buildHandler := func(scheme string, port string, conns *web.ConnMap, stats stat.Stats, pluginContext map[string]*interface{}) *cache.HandlerPointer {
 		return cache.NewHandlerPointer(cache.NewHandler(
@@ -155,18 +178,26 @@ func main() {
 
 	httpHandler := buildHandler("http", strconv.Itoa(cfg.Port), httpConns, stats, pluginContext)
 	httpsHandler := buildHandler("https", strconv.Itoa(cfg.HTTPSPort), httpsConns, stats, pluginContext)
+	// HTTP/3 requests have no InterceptConn, so there are no conns to look them up in.
+	http3Handler := buildHandler("https", strconv.Itoa(cfg.HTTP3Port), nil, stats, pluginContext)
 
 	idleTimeout := time.Duration(cfg.ServerIdleTimeoutMS) * time.Millisecond
 	readTimeout := time.Duration(cfg.ServerReadTimeoutMS) * time.Millisecond
 	writeTimeout := time.Duration(cfg.ServerWriteTimeoutMS) * time.Millisecond
 
-	plugins.OnStartup(remapper.PluginCfg(), pluginContext, plugin.StartupData{Config: cfg, Shared: remapper.PluginSharedCfg()})
+	plugins.OnStartup(remapper.PluginCfg(), pluginContext, plugin.StartupData{Config: cfg, Shared: remapper.PluginSharedCfg(), Rules: remapper.Rules(), ParentHealth: parentHealth})
 
 	// TODO add config to not serve HTTP (only HTTPS). If port is not set?
 	httpServer := startServer(httpHandler, httpListener, httpConnStateCallback, nil, cfg.Port, idleTimeout, readTimeout, writeTimeout, cfg.DisableHTTP2, "http")
 
+	httpsServerHandler := http.Handler(httpsHandler)
+	if http3Listener != nil {
+		httpsServerHandler = web.AltSvcHandler(httpsHandler, cfg.HTTP3Port, AltSvcMaxAge)
+		startHTTP3Server(http3Handler, http3Listener, http3TLSConfig, cfg.HTTP3Port, idleTimeout, http3Conns)
+	}
+
 	if cfg.CertFile != "" && cfg.KeyFile != "" {
-		httpsServer = startServer(httpsHandler, httpsListener, httpsConnStateCallback, tlsConfig, cfg.HTTPSPort, idleTimeout, readTimeout, writeTimeout, cfg.DisableHTTP2, "https")
+		httpsServer = startServer(httpsServerHandler, httpsListener, httpsConnStateCallback, tlsConfig, cfg.HTTPSPort, idleTimeout, readTimeout, writeTimeout, cfg.DisableHTTP2, "https")
 	}
 
 	reloadConfig := func() {
@@ -195,13 +226,20 @@ func main() {
 
 		plugins = plugin.Get(cfg.Plugins)
 		oldRemapper := remapper
-		remapper, err = remap.LoadRemapper(cfg.RemapRulesFile, plugins.LoadFuncs(), caches, baseTransport)
+		remapper, err = remap.LoadRemapper(cfg.RemapRulesFile, plugins.LoadFuncs(), caches, baseTransport, parentHealth)
 		if err != nil {
 			log.Errorln("reloading config: failed to load remap rules, keeping existing rules: " + err.Error())
 			remapper = oldRemapper
 			return
 		}
 
+		if parentHealthPollerChanged(oldCfg, cfg) {
+			if parentHealthPoller != nil {
+				parentHealthPoller.Stop()
+			}
+			parentHealthPoller = startParentHealthPoller(parentHealth, cfg)
+		}
+
 		if cfg.Port != oldCfg.Port {
 			if httpListener, httpConns, httpConnStateCallback, err = web.InterceptListen("tcp", fmt.Sprintf(":%d", cfg.Port)); err != nil {
 				log.Errorf("reloading config: creating HTTP listener %v: %v\n", cfg.Port, err)
@@ -219,7 +257,12 @@ func main() {
 			}
 		}
 
-		stats = stat.New(remapper.Rules(), caches, uint64(cfg.CacheSizeBytes), httpConns, httpsConns, Version) // TODO copy stats from old stats object?
+		if cfg.HTTP3Port != oldCfg.HTTP3Port {
+			log.Warnln("reloading config: http3_port changed in new config! The HTTP/3 listener cannot be changed without stopping the service. Restart the service to apply the new http3_port.")
+			cfg.HTTP3Port = oldCfg.HTTP3Port
+		}
+
+		stats = stat.New(remapper.Rules(), caches, uint64(cfg.CacheSizeBytes), httpConns, httpsConns, http3Conns, Version) // TODO copy stats from old stats object?
 
 		httpCacheHandler := cache.NewHandler(
 			remapper,
@@ -255,7 +298,24 @@ func main() {
 		)
 		httpsHandler.Set(httpsCacheHandler)
 
-		plugins.OnStartup(remapper.PluginCfg(), pluginContext, plugin.StartupData{Config: cfg, Shared: remapper.PluginSharedCfg()})
+		http3CacheHandler := cache.NewHandler(
+			remapper,
+			uint64(cfg.ConcurrentRuleRequests),
+			stats,
+			"https",
+			strconv.Itoa(cfg.HTTP3Port),
+			nil,
+			cfg.RFCCompliant,
+			cfg.ConnectionClose,
+			plugins,
+			pluginContext,
+			httpConns,
+			httpsConns,
+			cfg.InterfaceName,
+		)
+		http3Handler.Set(http3CacheHandler)
+
+		plugins.OnStartup(remapper.PluginCfg(), pluginContext, plugin.StartupData{Config: cfg, Shared: remapper.PluginSharedCfg(), Rules: remapper.Rules(), ParentHealth: parentHealth})
 
 		if cfg.Port != oldCfg.Port {
 			ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
@@ -286,7 +346,7 @@ func main() {
 				}
 			}
 
-			httpsServer = startServer(httpsHandler, httpsListener, httpsConnStateCallback, tlsConfig, cfg.HTTPSPort, idleTimeout, readTimeout, writeTimeout, cfg.DisableHTTP2, "https")
+			httpsServer = startServer(httpsServerHandler, httpsListener, httpsConnStateCallback, tlsConfig, cfg.HTTPSPort, idleTimeout, readTimeout, writeTimeout, cfg.DisableHTTP2, "https")
 		}
 	}
 
@@ -296,6 +356,31 @@ func main() {
 	signalReloader(unix.SIGHUP, reloadConfig)
 }
 
+// startParentHealthPoller starts polling the config's Traffic Monitors for parent health, and returns the poller, or nil if no Traffic Monitors are configured.
+func startParentHealthPoller(parentHealth *parenthealth.Health, cfg config.Config) *parenthealth.Poller {
+	if len(cfg.ParentHealthTrafficMonitors) == 0 {
+		return nil
+	}
+	if cfg.ParentHealthPollIntervalMS <= 0 {
+		log.Errorf("parent_health_poll_interval_ms %v must be positive, using the default %v\n", cfg.ParentHealthPollIntervalMS, config.DefaultConfig.ParentHealthPollIntervalMS)
+		cfg.ParentHealthPollIntervalMS = config.DefaultConfig.ParentHealthPollIntervalMS
+	}
+	if cfg.ParentHealthPollTimeoutMS <= 0 {
+		log.Errorf("parent_health_poll_timeout_ms %v must be positive, using the default %v\n", cfg.ParentHealthPollTimeoutMS, config.DefaultConfig.ParentHealthPollTimeoutMS)
+		cfg.ParentHealthPollTimeoutMS = config.DefaultConfig.ParentHealthPollTimeoutMS
+	}
+	interval := time.Duration(cfg.ParentHealthPollIntervalMS) * time.Millisecond
+	timeout := time.Duration(cfg.ParentHealthPollTimeoutMS) * time.Millisecond
+	return parenthealth.StartPoller(parentHealth, cfg.ParentHealthTrafficMonitors, interval, timeout)
+}
+
+// parentHealthPollerChanged returns whether the parent health polling config changed, and thus the poller must be restarted.
+func parentHealthPollerChanged(oldCfg config.Config, newCfg config.Config) bool {
+	return !reflect.DeepEqual(oldCfg.ParentHealthTrafficMonitors, newCfg.ParentHealthTrafficMonitors) ||
+		oldCfg.ParentHealthPollIntervalMS != newCfg.ParentHealthPollIntervalMS ||
+		oldCfg.ParentHealthPollTimeoutMS != newCfg.ParentHealthPollTimeoutMS
+}
+
 func profile() {
 	go func() {
 		count := 0
@@ -358,6 +443,18 @@ func startServer(handler http.Handler, listener net.Listener, connState func(net
 	return server
 }
 
+// startHTTP3Server starts an HTTP/3 server on the given UDP socket, and returns it.
+func startHTTP3Server(handler http.Handler, conn net.PacketConn, tlsConfig *tls.Config, port int, idleTimeout time.Duration, conns *web.HTTP3Conns) *http3.Server {
+	server := web.NewHTTP3Server(handler, tlsConfig, port, idleTimeout, conns)
+	go func() {
+		log.Infof("listening on http3://%d\n", port)
+		if err := server.Serve(conn); err != nil {
+			log.Errorf("serving HTTP/3 port %v: %v\n", port, err)
+		}
+	}()
+	return server
+}
+
 func loadCerts(rules []remapdata.RemapRule) ([]tls.Certificate, error) {
 	certs := []tls.Certificate{}
 	for _, rule := range rules {
@@ -380,13 +477,13 @@ func loadCerts(rules []remapdata.RemapRule) ([]tls.Certificate, error) {
 	return certs, nil
 }
 
-// createCaches creates the caches specified in the config. The nameFiles is the map of names to groups of files, nameMemBytes is the amount of memory to use for each named group, and memCacheBytes is the amount of memory to use for the default memory cache.
-func createCaches(nameFiles map[string][]config.CacheFile, nameMemBytes uint64, memCacheBytes uint64) (map[string]icache.Cache, error) {
+// createCaches creates the caches specified in the config. The nameFiles is the map of names to groups of files, nameMemBytes is the amount of memory to use for each named group, memCacheBytes is the amount of memory to use for the default memory cache, and indexSyncInterval is how often disk caches write object access times to disk.
+func createCaches(nameFiles map[string][]config.CacheFile, nameMemBytes uint64, memCacheBytes uint64, indexSyncInterval time.Duration) (map[string]icache.Cache, error) {
 	caches := map[string]icache.Cache{}
 	caches[""] = memcache.New(memCacheBytes) // default empty names to the mem cache
 
 	for name, files := range nameFiles {
-		multiDiskCache, err := diskcache.NewMulti(files)
+		multiDiskCache, err := diskcache.NewMulti(files, indexSyncInterval)
 		if err != nil {
 			return nil, errors.New("creating cache '" + name + "': " + err.Error())
 		}
@@ -396,6 +493,20 @@ func createCaches(nameFiles map[string][]config.CacheFile, nameMemBytes uint64,
 	return caches, nil
 }
 
+// closeCachesOnSignal closes the caches and exits when any of the given signals is received, so disk caches can write the access times of their objects before Grove stops.
+func closeCachesOnSignal(caches map[string]icache.Cache, sigs ...os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	go func() {
+		sig := <-c
+		log.Infof("received signal %v, closing caches and exiting\n", sig)
+		for _, cache := range caches {
+			cache.Close()
+		}
+		os.Exit(0)
+	}()
+}
+
 func cachesChanged(oldCfg, newCfg config.Config) bool {
 	return oldCfg.FileMemBytes == newCfg.FileMemBytes &&
 		oldCfg.CacheSizeBytes != newCfg.CacheSizeBytes &&
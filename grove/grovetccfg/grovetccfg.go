@@ -29,6 +29,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -52,6 +53,10 @@ const GroveConfigPath = "/etc/grove/" + GroveConfigFile
 const ConfigHistory = "cfg_history/"
 const RemapHistory = "remap_history/"
 
+// JobRefetchSuffix and JobRefreshSuffix are appended to the asset URLs of invalidation jobs by Traffic Ops API versions which don't have an invalidation type.
+const JobRefetchSuffix = "##REFETCH##"
+const JobRefreshSuffix = "##REFRESH##"
+
 // Exit codes are defined in the documentation, DO NOT change to iota, to avoid ambiguity.
 const (
 	ExitSuccess                 = 0
@@ -224,7 +229,7 @@ func main() {
 			fmt.Println(time.Now().Format(time.RFC3339Nano) + " Error checking Traffic Ops update pending: " + err.Error())
 			os.Exit(ExitError)
 		}
-		if !needsUpdate {
+		if !needsUpdate && !revalPendingStatus {
 			os.Exit(ExitSuccess) // if no error and no update necessary, return success and print nothing
 		}
 	}
@@ -265,7 +270,7 @@ func main() {
 	// end of API 1.2 stuff
 
 	if hostProfile.Type == tc.GroveProfileType {
-		updateRequired, cfg, err := createGroveCfg(toc, hostServer)
+		updateRequired, cfg, err := createGroveCfg(toc, hostServer, servers)
 		if err != nil {
 			fmt.Println(time.Now().Format(time.RFC3339Nano) + " Error getting config rules for '" + GroveConfigPath + "' :" + err.Error())
 			os.Exit(ExitError)
@@ -340,7 +345,8 @@ func main() {
 	}
 
 	if !*ignoreUpdateFlag {
-		if err := clearUpdatePending(toc, *host, revalPendingStatus); err != nil {
+		// invalidation jobs are applied with every config, so any pending revalidation is cleared along with the update
+		if err := clearUpdatePending(toc, *host, false); err != nil {
 			fmt.Println(time.Now().Format(time.RFC3339Nano) + " Error clearing update pending flag in Traffic Ops (but successfully updated config): " + err.Error())
 			os.Exit(ExitErrorClearingUpdateFlag)
 		}
@@ -349,7 +355,7 @@ func main() {
 	os.Exit(ExitSuccess)
 }
 
-func createGroveCfg(toc *to.Session, server tc.ServerV30) (bool, config.Config, error) {
+func createGroveCfg(toc *to.Session, server tc.ServerV30, servers map[string]tc.ServerV30) (bool, config.Config, error) {
 	var newCfg config.Config
 	var currCfg config.Config
 	var pluginParams = []string{}
@@ -388,6 +394,9 @@ func createGroveCfg(toc *to.Session, server tc.ServerV30) (bool, config.Config,
 		sort.Strings(pluginParams)
 		newCfg.Plugins = pluginParams
 	}
+	if server.CDNName != nil {
+		newCfg.ParentHealthTrafficMonitors = makeTrafficMonitorURLs(servers, *server.CDNName)
+	}
 	// no update is required if the configs are the same
 	areEqual := reflect.DeepEqual(newCfg, currCfg)
 	if areEqual == true {
@@ -399,6 +408,26 @@ func createGroveCfg(toc *to.Session, server tc.ServerV30) (bool, config.Config,
 	}
 }
 
+// makeTrafficMonitorURLs returns the sorted URLs of the ONLINE Traffic Monitors of the given CDN, for Grove to poll parent health from.
+func makeTrafficMonitorURLs(servers map[string]tc.ServerV30, cdnName string) []string {
+	urls := []string{}
+	for _, sv := range servers {
+		if sv.Type != tc.MonitorTypeName || sv.CDNName == nil || *sv.CDNName != cdnName || sv.Status == nil || *sv.Status != string(tc.CacheStatusOnline) {
+			continue
+		}
+		if sv.HostName == nil || sv.DomainName == nil {
+			continue
+		}
+		u := "http://" + *sv.HostName + "." + *sv.DomainName
+		if sv.TCPPort != nil && *sv.TCPPort != 0 && *sv.TCPPort != 80 {
+			u += ":" + strconv.Itoa(*sv.TCPPort)
+		}
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
 func setConfigParameter(cfg *config.Config, name string, value string) error {
 	var err error
 
@@ -409,6 +438,8 @@ func setConfigParameter(cfg *config.Config, name string, value string) error {
 		cfg.Port, err = strconv.Atoi(value)
 	case "https_port":
 		cfg.HTTPSPort, err = strconv.Atoi(value)
+	case "http3_port":
+		cfg.HTTP3Port, err = strconv.Atoi(value)
 	case "cache_size_bytes":
 		cfg.CacheSizeBytes, err = strconv.Atoi(value)
 	case "concurrent_rule_requests":
@@ -449,6 +480,16 @@ func setConfigParameter(cfg *config.Config, name string, value string) error {
 		cfg.ServerReadTimeoutMS, err = strconv.Atoi(value)
 	case "file_mem_bytes":
 		cfg.FileMemBytes, err = strconv.Atoi(value)
+	case "cache_files_index_sync_interval_ms":
+		cfg.CacheFilesIndexSyncIntervalMS, err = strconv.Atoi(value)
+	case "parent_health_poll_interval_ms":
+		cfg.ParentHealthPollIntervalMS, err = strconv.Atoi(value)
+	case "parent_health_poll_timeout_ms":
+		cfg.ParentHealthPollTimeoutMS, err = strconv.Atoi(value)
+	case "purge_auth_token":
+		cfg.PurgeAuthToken = value
+	case "parent_health_push_auth_token":
+		cfg.ParentHealthPushAuthToken = value
 	default:
 		err = fmt.Errorf(time.Now().Format(time.RFC3339Nano) + "No such config parameter '" + name + "', parameter ignored")
 	}
@@ -522,7 +563,14 @@ func createRulesOldAPI(toc *to.Session, host string, certDir string, servers map
 	}
 	dsCerts := makeDSCertMap(cdnSSLKeys)
 
-	return createRulesOld(host, deliveryservices, parents, deliveryserviceRegexes, cdns, serverParameters, dsCerts, certDir)
+	jobs, _, err := toc.GetInvalidationJobsWithHdr(nil, nil, nil)
+	if err != nil {
+		fmt.Println(time.Now().Format(time.RFC3339Nano) + " Error getting Traffic Ops Invalidation Jobs: " + err.Error())
+		os.Exit(1)
+	}
+	dsRevalidations := makeDSRevalidations(jobs, time.Now())
+
+	return createRulesOld(host, deliveryservices, parents, deliveryserviceRegexes, cdns, serverParameters, dsCerts, certDir, dsRevalidations)
 }
 
 // func createRulesNewAPI(toc *to.Session, host string, certDir string) (remap.RemapRules, error) {
@@ -863,6 +911,7 @@ func createRulesOld(
 	hostParams []tc.Parameter,
 	dsCerts map[string]tc.CDNSSLKeys,
 	certDir string,
+	dsRevalidations map[string][]remapdata.Revalidation,
 ) (remap.RemapRules, error) {
 	rules := []remapdata.RemapRule{}
 	allowedIPs, err := getAllowIP(hostParams)
@@ -954,6 +1003,7 @@ func createRulesOld(
 				}
 
 				rule.PluginsShared = map[string]json.RawMessage{}
+				rule.Revalidations = dsRevalidations[*ds.XMLID]
 				// if the delivery service skips the mid's ie, http_no_cache, http_live, and dns_live
 				// only add the url rule to the origin.
 				if dsTypeSkipsMid(dsType) {
@@ -1049,6 +1099,46 @@ func createRulesOld(
 	return remapRules, nil
 }
 
+// makeDSRevalidations returns the Revalidations of the invalidation jobs which are in effect or will be, by delivery service.
+// The job asset URL's scheme and host are removed, because Grove matches revalidations against request URIs, and clients may request a delivery service by any of its hosts.
+func makeDSRevalidations(jobs []tc.InvalidationJob, now time.Time) map[string][]remapdata.Revalidation {
+	dsRevalidations := map[string][]remapdata.Revalidation{}
+	for _, job := range jobs {
+		if job.AssetURL == nil || job.DeliveryService == nil || job.StartTime == nil {
+			continue
+		}
+		ttl := time.Duration(job.TTLHours()) * time.Hour
+		if !now.Before(job.StartTime.Add(ttl)) {
+			continue // expired
+		}
+		invalidationType := remapdata.InvalidationTypeRefresh
+		assetURL := *job.AssetURL
+		if strings.HasSuffix(assetURL, JobRefetchSuffix) {
+			invalidationType = remapdata.InvalidationTypeRefetch
+		}
+		assetURL = strings.TrimSuffix(strings.TrimSuffix(assetURL, JobRefetchSuffix), JobRefreshSuffix)
+		if i := strings.Index(assetURL, "://"); i != -1 {
+			assetURL = assetURL[i+len("://"):]
+		}
+		assetPath := "/"
+		if i := strings.Index(assetURL, "/"); i != -1 {
+			assetPath = assetURL[i:]
+		}
+		regex, err := regexp.Compile("^" + assetPath)
+		if err != nil {
+			fmt.Println(time.Now().Format(time.RFC3339Nano) + " Warning: skipping invalidation job for deliveryservice '" + *job.DeliveryService + "' with invalid asset URL regex '" + *job.AssetURL + "': " + err.Error())
+			continue
+		}
+		dsRevalidations[*job.DeliveryService] = append(dsRevalidations[*job.DeliveryService], remapdata.Revalidation{
+			Regex:     regex,
+			StartTime: job.StartTime.Time,
+			TTL:       ttl,
+			Type:      invalidationType,
+		})
+	}
+	return dsRevalidations
+}
+
 func getCertFileName(cert tc.CDNSSLKeys, dir string) string {
 	return dir + string(os.PathSeparator) + strings.Replace(cert.Hostname, "*.", "", -1) + ".crt"
 }
@@ -25,7 +25,19 @@ type Cache interface {
 	Capacity() uint64
 	Get(key string) (*cacheobj.CacheObj, bool)
 	Peek(key string) (*cacheobj.CacheObj, bool)
+	Remove(key string) bool
 	Keys() []string
 	Size() uint64
 	Close()
 }
+
+// Toucher is implemented by Caches which can mark a key as recently used without reading its value. This allows a tiered cache to keep the LRU of a slow cache accurate when objects are served from a faster cache in front of it.
+type Toucher interface {
+	Touch(key string)
+}
+
+// Restorer is implemented by Caches which restore their contents in the background after a restart.
+type Restorer interface {
+	// RestoreProgress returns the number of objects restored so far, the total number of objects to restore, or 0 if that isn't known yet, and whether restoring is complete.
+	RestoreProgress() (uint64, uint64, bool)
+}
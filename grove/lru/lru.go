@@ -74,3 +74,40 @@ func (c *LRU) Keys() []string {
 	}
 	return arr
 }
+
+// AddOldest adds the key to the LRU as its least-recently-used key, with the given size, unless the key already exists. Returns whether the key was added.
+// This is used to restore an LRU after a restart, from the most- to the least-recently-used key, without disturbing keys added since.
+func (c *LRU) AddOldest(key string, size uint64) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if _, ok := c.lElems[key]; ok {
+		return false
+	}
+	c.lElems[key] = c.l.PushBack(&listObj{key, size})
+	return true
+}
+
+// Touch makes the key the most-recently-used key, without changing its size. Returns whether the key existed.
+func (c *LRU) Touch(key string) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+	elem, ok := c.lElems[key]
+	if !ok {
+		return false
+	}
+	c.l.MoveToFront(elem)
+	return true
+}
+
+// Remove removes the key from the LRU. Returns the size of the removed key, and whether it existed.
+func (c *LRU) Remove(key string) (uint64, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	elem, ok := c.lElems[key]
+	if !ok {
+		return 0, false
+	}
+	c.l.Remove(elem)
+	delete(c.lElems, key)
+	return elem.Value.(*listObj).size, true
+}
@@ -82,6 +82,18 @@ func (c *MemCache) Add(key string, val *cacheobj.CacheObj) bool {
 	return false // TODO remove eviction from interface; it's unnecessary and expensive
 }
 
+// Remove removes the key from the cache. Returns whether the key existed.
+func (c *MemCache) Remove(key string) bool {
+	c.cacheM.Lock()
+	_, ok := c.cache[key]
+	delete(c.cache, key)
+	c.cacheM.Unlock()
+	if sizeBytes, inLRU := c.lru.Remove(key); inLRU {
+		atomic.AddUint64(&c.sizeBytes, ^uint64(sizeBytes-1)) // subtract sizeBytes
+	}
+	return ok
+}
+
 func (c *MemCache) Size() uint64 { return atomic.LoadUint64(&c.sizeBytes) }
 func (c *MemCache) Close()       {}
 
@@ -0,0 +1,101 @@
+package parenthealth
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// parenthealth tracks the availability of parent caches, as determined by Traffic Monitor, so parent selection can skip parents which are down.
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// ParentState is the health of a single parent, as last reported by Traffic Monitor.
+type ParentState struct {
+	Available bool   `json:"available"`
+	Status    string `json:"status"`
+	// Updated is when the state was last received from Traffic Monitor.
+	Updated time.Time `json:"updated"`
+}
+
+// Health is the health of parents, keyed by short hostname, which is how Traffic Monitor names caches. It's safe for concurrent use.
+//
+// Parents Traffic Monitor hasn't reported are considered available, so Grove behaves as it did without health, for parents which aren't monitored, or before Traffic Monitor has been polled.
+type Health struct {
+	states map[string]ParentState
+	m      sync.RWMutex
+}
+
+func New() *Health {
+	return &Health{states: map[string]ParentState{}}
+}
+
+// Available returns whether the parent with the given hostname is available. The hostname may be fully qualified, in which case only its first label is used. Unknown parents are available. It's safe to call on a nil Health, for which all parents are available.
+func (h *Health) Available(hostname string) bool {
+	if h == nil {
+		return true
+	}
+	h.m.RLock()
+	defer h.m.RUnlock()
+	state, ok := h.states[shortHostname(hostname)]
+	return !ok || state.Available
+}
+
+// SetCRStates replaces the health of all parents with the caches of the given Traffic Monitor CRStates, and returns the hostnames whose availability changed.
+func (h *Health) SetCRStates(crStates tc.CRStates, now time.Time) []string {
+	states := make(map[string]ParentState, len(crStates.Caches))
+	for cacheName, avail := range crStates.Caches {
+		states[string(cacheName)] = ParentState{Available: avail.IsAvailable, Status: avail.Status, Updated: now}
+	}
+
+	h.m.Lock()
+	defer h.m.Unlock()
+	changed := []string{}
+	for host, state := range states {
+		if old, ok := h.states[host]; (ok && old.Available != state.Available) || (!ok && !state.Available) {
+			changed = append(changed, host)
+		}
+	}
+	for host, old := range h.states {
+		if _, ok := states[host]; !ok && !old.Available {
+			changed = append(changed, host) // no longer reported, and thus available
+		}
+	}
+	h.states = states
+	return changed
+}
+
+// States returns a copy of the health of all parents Traffic Monitor has reported.
+func (h *Health) States() map[string]ParentState {
+	if h == nil {
+		return map[string]ParentState{}
+	}
+	h.m.RLock()
+	defer h.m.RUnlock()
+	states := make(map[string]ParentState, len(h.states))
+	for host, state := range h.states {
+		states[host] = state
+	}
+	return states
+}
+
+func shortHostname(hostname string) string {
+	if i := strings.Index(hostname, "."); i != -1 {
+		return hostname[:i]
+	}
+	return hostname
+}
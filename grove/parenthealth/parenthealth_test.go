@@ -0,0 +1,77 @@
+package parenthealth
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func TestHealthSetCRStates(t *testing.T) {
+	h := New()
+	if !h.Available("mid-0.example.net") {
+		t.Error("expected unreported parent to be available")
+	}
+
+	now := time.Now()
+	changed := h.SetCRStates(tc.CRStates{Caches: map[tc.CacheName]tc.IsAvailable{
+		"mid-0": {IsAvailable: false, Status: "REPORTED - timed out"},
+		"mid-1": {IsAvailable: true, Status: "REPORTED"},
+	}}, now)
+	if expected := []string{"mid-0"}; !reflect.DeepEqual(changed, expected) {
+		t.Errorf("expected changed %v, actual %v", expected, changed)
+	}
+	if h.Available("mid-0.example.net") {
+		t.Error("expected reported down parent to be unavailable by FQDN")
+	}
+	if h.Available("mid-0") {
+		t.Error("expected reported down parent to be unavailable by short hostname")
+	}
+	if !h.Available("mid-1.example.net") {
+		t.Error("expected reported up parent to be available")
+	}
+	if state := h.States()["mid-0"]; state.Status != "REPORTED - timed out" || !state.Updated.Equal(now) {
+		t.Errorf("expected state status 'REPORTED - timed out' updated %v, actual %+v", now, state)
+	}
+
+	// mid-0 is no longer reported, and mid-1 is marked down
+	changed = h.SetCRStates(tc.CRStates{Caches: map[tc.CacheName]tc.IsAvailable{
+		"mid-1": {IsAvailable: false, Status: "ADMIN_DOWN"},
+	}}, now)
+	sort.Strings(changed)
+	if expected := []string{"mid-0", "mid-1"}; !reflect.DeepEqual(changed, expected) {
+		t.Errorf("expected changed %v, actual %v", expected, changed)
+	}
+	if !h.Available("mid-0") {
+		t.Error("expected parent no longer reported to be available")
+	}
+	if h.Available("mid-1") {
+		t.Error("expected parent marked down to be unavailable")
+	}
+}
+
+func TestHealthNil(t *testing.T) {
+	h := (*Health)(nil)
+	if !h.Available("mid-0") {
+		t.Error("expected nil Health to have all parents available")
+	}
+	if states := h.States(); len(states) != 0 {
+		t.Errorf("expected nil Health to have no states, actual %v", states)
+	}
+}
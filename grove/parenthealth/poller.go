@@ -0,0 +1,90 @@
+package parenthealth
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/tmclient"
+)
+
+// Poller polls Traffic Monitor CRStates, and applies them to a Health.
+type Poller struct {
+	health   *Health
+	monitors []string
+	interval time.Duration
+	timeout  time.Duration
+	stop     chan struct{}
+}
+
+// StartPoller starts polling the CRStates of the given Traffic Monitor URLs every interval, applying them to health. Each poll tries the monitors in order, until one succeeds. If every monitor fails, the previous health is kept.
+func StartPoller(health *Health, monitors []string, interval time.Duration, timeout time.Duration) *Poller {
+	p := &Poller{health: health, monitors: monitors, interval: interval, timeout: timeout, stop: make(chan struct{})}
+	go p.poll()
+	return p
+}
+
+// Stop stops polling. It must not be called more than once.
+func (p *Poller) Stop() {
+	close(p.stop)
+}
+
+func (p *Poller) poll() {
+	log.Infof("parent health polling %v Traffic Monitors every %v\n", len(p.monitors), p.interval)
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		crStates, err := p.getCRStates()
+		if err != nil {
+			log.Errorln("parent health polling, keeping previous parent health: " + err.Error())
+		} else {
+			Apply(p.health, crStates)
+		}
+		select {
+		case <-p.stop:
+			log.Infoln("parent health polling stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// getCRStates returns the CRStates of the first Traffic Monitor to respond successfully.
+func (p *Poller) getCRStates() (tc.CRStates, error) {
+	errs := []string{}
+	for _, monitor := range p.monitors {
+		crStates, err := tmclient.New(monitor, p.timeout).CRStates(false)
+		if err == nil {
+			return crStates, nil
+		}
+		errs = append(errs, monitor+": "+err.Error())
+	}
+	return tc.CRStates{}, errors.New("getting CRStates from all Traffic Monitors failed: " + strings.Join(errs, ", "))
+}
+
+// Apply applies the given CRStates to health, logging parents marked down or up.
+func Apply(health *Health, crStates tc.CRStates) {
+	for _, host := range health.SetCRStates(crStates, time.Now()) {
+		if health.Available(host) {
+			log.Infoln("parent health marking parent '" + host + "' UP")
+		} else {
+			log.Warnln("parent health marking parent '" + host + "' DOWN")
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package plugin
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/parenthealth"
+	"github.com/apache/trafficcontrol/grove/web"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func init() {
+	AddPlugin(10000, Funcs{startup: parentHealthStartup, onRequest: parentHealth})
+}
+
+// ParentHealthEndpoint is our reserved path
+const ParentHealthEndpoint = "/_parent_health"
+
+// MaxParentHealthReqBytes is the largest pushed CRStates body read.
+const MaxParentHealthReqBytes = 10 * 1024 * 1024
+
+type parentHealthContext struct {
+	health    *parenthealth.Health
+	authToken string
+}
+
+func parentHealthStartup(icfg interface{}, d StartupData) {
+	*d.Context = parentHealthContext{health: d.ParentHealth, authToken: d.Config.ParentHealthPushAuthToken}
+	if d.Config.ParentHealthPushAuthToken == "" {
+		log.Warnln("plugin http_parent_health has no parent_health_push_auth_token configured, all pushed CRStates will be forbidden")
+	}
+}
+
+// parentHealth serves the health of parents on GET, and accepts Traffic Monitor CRStates pushed on POST with the configured bearer token, which are applied the same as polled CRStates. If parent health is also polled, the next poll overwrites pushed CRStates.
+func parentHealth(icfg interface{}, d OnRequestData) bool {
+	if !strings.HasPrefix(d.R.URL.Path, ParentHealthEndpoint) {
+		return false
+	}
+	reqTime := time.Now()
+
+	log.Debugf("plugin onrequest http_parent_health calling\n")
+
+	w := d.W
+	req := d.R
+
+	ip, err := web.GetIP(req)
+	if err != nil {
+		code := http.StatusInternalServerError
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+		log.Errorln("parent health failed to get IP: " + ip.String())
+		return true
+	}
+	if !d.StatRules.Allowed(ip) {
+		code := http.StatusForbidden
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+		log.Debugln("parent health IP " + ip.String() + " FORBIDDEN")
+		return true
+	}
+
+	ctx, _ := (*d.Context).(parentHealthContext)
+	health := ctx.health
+
+	respCode := http.StatusOK
+	respBts := []byte{}
+	switch req.Method {
+	case http.MethodGet:
+		if respBts, err = json.Marshal(health.States()); err != nil {
+			respCode = http.StatusInternalServerError
+			respBts = []byte(http.StatusText(respCode))
+			log.Errorln("parent health marshalling states: " + err.Error())
+			break
+		}
+		w.Header().Set("Content-Type", "application/json")
+	case http.MethodPost:
+		if !bearerAuthorized(req, ctx.authToken) {
+			respCode = http.StatusForbidden
+			respBts = []byte(http.StatusText(respCode))
+			log.Warnln("parent health push from IP " + ip.String() + " FORBIDDEN")
+			break
+		}
+		if health == nil {
+			respCode = http.StatusServiceUnavailable
+			respBts = []byte("parent health is not enabled")
+			break
+		}
+		crStates := tc.CRStates{}
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, MaxParentHealthReqBytes)).Decode(&crStates); err != nil {
+			respCode = http.StatusBadRequest
+			respBts = []byte("decoding CRStates: " + err.Error())
+			break
+		}
+		parenthealth.Apply(health, crStates)
+		respCode = http.StatusNoContent
+	default:
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		respCode = http.StatusMethodNotAllowed
+		respBts = []byte(http.StatusText(respCode))
+	}
+	w.WriteHeader(respCode)
+	w.Write(respBts)
+
+	clientIP, _ := web.GetClientIPPort(req)
+	now := time.Now()
+	log.EventRaw(atsEventLogStr(now, clientIP, d.Hostname, req.Host, d.Port, "-", d.Scheme, req.URL.String(), req.Method, req.Proto, respCode, now.Sub(reqTime), uint64(len(respBts)), 0, 0, true, true, getCacheHitStr(true, false), "-", "-", req.UserAgent(), req.Header.Get("X-Money-Trace"), d.RequestID))
+	return true
+}
@@ -0,0 +1,247 @@
+package plugin
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/icache"
+	"github.com/apache/trafficcontrol/grove/remapdata"
+	"github.com/apache/trafficcontrol/grove/web"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+)
+
+func init() {
+	AddPlugin(10000, Funcs{startup: purgeStartup, onRequest: purge})
+}
+
+// PurgeEndpoint is our reserved path
+const PurgeEndpoint = "/_purge"
+
+// MaxPurgeReqBytes is the largest purge request body read.
+const MaxPurgeReqBytes = 64 * 1024
+
+type purgeContext struct {
+	authToken string
+	rules     []remapdata.RemapRule
+}
+
+// PurgeReq is the body of a purge request. Exactly one of Key, Regex, and Rule must be set.
+type PurgeReq struct {
+	// Key is the exact cache key to purge, e.g. "GET:http://origin.example.net/foo.png".
+	Key string `json:"key"`
+	// Regex purges every object whose cache key, without its method, matches it.
+	Regex string `json:"regex"`
+	// Rule purges every object cached by the named remap rule.
+	Rule string `json:"rule"`
+	// Type is REFRESH or REFETCH. The default is REFRESH.
+	Type string `json:"type"`
+}
+
+// PurgeResp is the response to a successful purge request.
+type PurgeResp struct {
+	Purged int `json:"purged"`
+}
+
+func purgeStartup(icfg interface{}, d StartupData) {
+	*d.Context = purgeContext{authToken: d.Config.PurgeAuthToken, rules: d.Rules}
+	if d.Config.PurgeAuthToken == "" {
+		log.Warnln("plugin http_purge has no purge_auth_token configured, all purge requests will be forbidden")
+	}
+}
+
+func purge(icfg interface{}, d OnRequestData) bool {
+	if !strings.HasPrefix(d.R.URL.Path, PurgeEndpoint) {
+		return false
+	}
+	reqTime := time.Now()
+
+	log.Debugf("plugin onrequest http_purge calling\n")
+
+	w := d.W
+	req := d.R
+
+	ip, err := web.GetIP(req)
+	if err != nil {
+		code := http.StatusInternalServerError
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+		log.Errorln("purge failed to get IP: " + ip.String())
+		return true
+	}
+	ctx, ok := (*d.Context).(purgeContext)
+	if !ok || !d.StatRules.Allowed(ip) || !bearerAuthorized(req, ctx.authToken) {
+		code := http.StatusForbidden
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+		log.Warnln("purge from IP " + ip.String() + " FORBIDDEN")
+		return true
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		code := http.StatusMethodNotAllowed
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+		return true
+	}
+
+	purgeReq := PurgeReq{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, MaxPurgeReqBytes)).Decode(&purgeReq); err != nil {
+		code := http.StatusBadRequest
+		w.WriteHeader(code)
+		w.Write([]byte("decoding purge request: " + err.Error()))
+		return true
+	}
+	purged, err := purgeCaches(ctx.rules, purgeReq)
+	if err != nil {
+		code := http.StatusBadRequest
+		w.WriteHeader(code)
+		w.Write([]byte(err.Error()))
+		return true
+	}
+
+	respBts, err := json.Marshal(PurgeResp{Purged: purged})
+	if err != nil {
+		code := http.StatusInternalServerError
+		w.WriteHeader(code)
+		w.Write([]byte(http.StatusText(code)))
+		log.Errorln("purge marshalling response: " + err.Error())
+		return true
+	}
+	respCode := http.StatusOK
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(respCode)
+	w.Write(respBts)
+
+	clientIP, _ := web.GetClientIPPort(req)
+	now := time.Now()
+	// log, so purges can be audited, and so we know if someone is hitting this endpoint when they shouldn't be.
+	log.Infoln("purge from " + clientIP + " key '" + purgeReq.Key + "' regex '" + purgeReq.Regex + "' rule '" + purgeReq.Rule + "' type '" + purgeReq.Type + "' purged " + strconv.Itoa(purged) + " objects")
+	log.EventRaw(atsEventLogStr(now, clientIP, d.Hostname, req.Host, d.Port, "-", d.Scheme, req.URL.String(), req.Method, req.Proto, respCode, now.Sub(reqTime), uint64(len(respBts)), 0, 0, true, true, getCacheHitStr(true, false), "-", "-", req.UserAgent(), req.Header.Get("X-Money-Trace"), d.RequestID))
+	return true
+}
+
+// bearerAuthorized returns whether the request has an Authorization bearer token matching the configured token. If no token is configured, no request is authorized.
+func bearerAuthorized(req *http.Request, authToken string) bool {
+	if authToken == "" {
+		return false
+	}
+	const bearerPrefix = "Bearer "
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, bearerPrefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, bearerPrefix)), []byte(authToken)) == 1
+}
+
+// purgeCaches purges the objects of the given rules' caches matching the purge request, and returns the number of objects purged.
+func purgeCaches(rules []remapdata.RemapRule, purgeReq PurgeReq) (int, error) {
+	invalidationType := remapdata.InvalidationTypeRefresh
+	if purgeReq.Type != "" {
+		if invalidationType = remapdata.InvalidationTypeFromString(purgeReq.Type); invalidationType == remapdata.InvalidationTypeInvalid {
+			return 0, errors.New("invalid type '" + purgeReq.Type + "', must be " + string(remapdata.InvalidationTypeRefresh) + " or " + string(remapdata.InvalidationTypeRefetch))
+		}
+	}
+
+	caches := []icache.Cache{}
+	seenCaches := map[icache.Cache]struct{}{}
+	for _, rule := range rules {
+		if rule.Cache == nil {
+			continue
+		}
+		if _, ok := seenCaches[rule.Cache]; ok {
+			continue
+		}
+		seenCaches[rule.Cache] = struct{}{}
+		caches = append(caches, rule.Cache)
+	}
+
+	purged := 0
+	switch {
+	case purgeReq.Key != "" && purgeReq.Regex == "" && purgeReq.Rule == "":
+		for _, cache := range caches {
+			if invalidateCacheObj(cache, purgeReq.Key, invalidationType) {
+				purged++
+			}
+		}
+	case purgeReq.Regex != "" && purgeReq.Key == "" && purgeReq.Rule == "":
+		regex, err := regexp.Compile(purgeReq.Regex)
+		if err != nil {
+			return 0, errors.New("invalid regex: " + err.Error())
+		}
+		for _, cache := range caches {
+			purged += invalidateCacheKeys(cache, regex.MatchString, invalidationType)
+		}
+	case purgeReq.Rule != "" && purgeReq.Key == "" && purgeReq.Regex == "":
+		found := false
+		for _, rule := range rules {
+			if rule.Name != purgeReq.Rule || rule.Cache == nil || len(rule.To) == 0 {
+				continue
+			}
+			found = true
+			// objects are cached under the rule's first parent. See remapdata.RemapRule.CacheKey.
+			toPrefix := rule.To[0].URL
+			purged += invalidateCacheKeys(rule.Cache, func(uri string) bool { return strings.HasPrefix(uri, toPrefix) }, invalidationType)
+		}
+		if !found {
+			return 0, errors.New("rule '" + purgeReq.Rule + "' not found")
+		}
+	default:
+		return 0, errors.New("exactly one of key, regex, and rule must be given")
+	}
+	return purged, nil
+}
+
+// invalidateCacheKeys invalidates every key of the cache whose URI, that is, the key without its method, matches. Returns the number of objects invalidated.
+func invalidateCacheKeys(cache icache.Cache, match func(uri string) bool, invalidationType remapdata.InvalidationType) int {
+	invalidated := 0
+	for _, key := range cache.Keys() {
+		uri := key
+		if i := strings.Index(key, ":"); i != -1 {
+			uri = key[i+1:]
+		}
+		if match(uri) && invalidateCacheObj(cache, key, invalidationType) {
+			invalidated++
+		}
+	}
+	return invalidated
+}
+
+// invalidateCacheObj invalidates the given key. A REFETCH removes the object from the cache. A REFRESH replaces it with a copy which must be revalidated before it's used again. Returns whether the key was in the cache.
+func invalidateCacheObj(cache icache.Cache, key string, invalidationType remapdata.InvalidationType) bool {
+	if invalidationType == remapdata.InvalidationTypeRefetch {
+		return cache.Remove(key)
+	}
+	obj, ok := cache.Peek(key)
+	if !ok {
+		return false
+	}
+	if obj.MustRevalidate {
+		return true
+	}
+	// must copy, because this cache object may be concurrently read by other goroutines
+	newObj := *obj
+	newObj.MustRevalidate = true
+	cache.Add(key, &newObj)
+	return true
+}
@@ -0,0 +1,130 @@
+package plugin
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/memcache"
+	"github.com/apache/trafficcontrol/grove/remapdata"
+)
+
+func makePurgeTestRules() []remapdata.RemapRule {
+	cache := memcache.New(1 << 20)
+	for _, key := range []string{
+		"GET:http://origin-a.example.net/foo.png",
+		"GET:http://origin-a.example.net/bar.png",
+		"GET:http://origin-a.example.net/bar.css",
+		"GET:http://origin-b.example.net/foo.png",
+	} {
+		cache.Add(key, &cacheobj.CacheObj{Body: []byte(key), Code: http.StatusOK, Size: uint64(len(key))})
+	}
+	return []remapdata.RemapRule{
+		{RemapRuleBase: remapdata.RemapRuleBase{Name: "a"}, To: []remapdata.RemapRuleTo{{RemapRuleToBase: remapdata.RemapRuleToBase{URL: "http://origin-a.example.net"}}}, Cache: cache},
+		{RemapRuleBase: remapdata.RemapRuleBase{Name: "b"}, To: []remapdata.RemapRuleTo{{RemapRuleToBase: remapdata.RemapRuleToBase{URL: "http://origin-b.example.net"}}}, Cache: cache},
+	}
+}
+
+func TestPurgeCaches(t *testing.T) {
+	tests := []struct {
+		name        string
+		req         PurgeReq
+		purged      int
+		refetched   []string
+		revalidated []string
+		err         bool
+	}{
+		{
+			name:        "key",
+			req:         PurgeReq{Key: "GET:http://origin-a.example.net/foo.png"},
+			purged:      1,
+			revalidated: []string{"GET:http://origin-a.example.net/foo.png"},
+		},
+		{
+			name:      "regex refetch",
+			req:       PurgeReq{Regex: `/[a-z]+\.png$`, Type: "refetch"},
+			purged:    3,
+			refetched: []string{"GET:http://origin-a.example.net/foo.png", "GET:http://origin-a.example.net/bar.png", "GET:http://origin-b.example.net/foo.png"},
+		},
+		{
+			name:        "rule",
+			req:         PurgeReq{Rule: "a", Type: "REFRESH"},
+			purged:      3,
+			revalidated: []string{"GET:http://origin-a.example.net/foo.png", "GET:http://origin-a.example.net/bar.png", "GET:http://origin-a.example.net/bar.css"},
+		},
+		{name: "missing key", req: PurgeReq{Key: "GET:http://origin-c.example.net/foo.png"}, purged: 0},
+		{name: "unknown rule", req: PurgeReq{Rule: "c"}, err: true},
+		{name: "invalid type", req: PurgeReq{Key: "GET:http://origin-a.example.net/foo.png", Type: "DELETE"}, err: true},
+		{name: "invalid regex", req: PurgeReq{Regex: "(foo"}, err: true},
+		{name: "key and rule", req: PurgeReq{Key: "GET:http://origin-a.example.net/foo.png", Rule: "a"}, err: true},
+		{name: "nothing", req: PurgeReq{}, err: true},
+	}
+	for _, test := range tests {
+		rules := makePurgeTestRules()
+		cache := rules[0].Cache
+		purged, err := purgeCaches(rules, test.req)
+		if test.err {
+			if err == nil {
+				t.Errorf("%s: expected error, actual: nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if purged != test.purged {
+			t.Errorf("%s: expected %d objects purged, actual: %d", test.name, test.purged, purged)
+		}
+		for _, key := range test.refetched {
+			if _, ok := cache.Peek(key); ok {
+				t.Errorf("%s: expected '%s' to be removed", test.name, key)
+			}
+		}
+		for _, key := range test.revalidated {
+			if obj, ok := cache.Peek(key); !ok || !obj.MustRevalidate {
+				t.Errorf("%s: expected '%s' to be cached and marked for revalidation", test.name, key)
+			}
+		}
+		if len(cache.Keys())+len(test.refetched) != 4 {
+			t.Errorf("%s: expected only %d objects removed, actual: %d", test.name, len(test.refetched), 4-len(cache.Keys()))
+		}
+	}
+}
+
+func TestBearerAuthorized(t *testing.T) {
+	tests := []struct {
+		auth       string
+		token      string
+		authorized bool
+	}{
+		{auth: "Bearer s3cret", token: "s3cret", authorized: true},
+		{auth: "Bearer wrong", token: "s3cret"},
+		{auth: "s3cret", token: "s3cret"},
+		{auth: "", token: "s3cret"},
+		{auth: "Bearer ", token: ""},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost"+PurgeEndpoint, nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		if authorized := bearerAuthorized(req, test.token); authorized != test.authorized {
+			t.Errorf("Authorization '%s' with token '%s': expected authorized %v, actual: %v", test.auth, test.token, test.authorized, authorized)
+		}
+	}
+}
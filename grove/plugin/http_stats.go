@@ -23,8 +23,10 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
+	"github.com/apache/trafficcontrol/grove/parenthealth"
 	"github.com/apache/trafficcontrol/grove/stat"
 	"github.com/apache/trafficcontrol/grove/web"
 
@@ -32,11 +34,15 @@ import (
 )
 
 func init() {
-	AddPlugin(10000, Funcs{onRequest: stats})
+	AddPlugin(10000, Funcs{startup: statsStartup, onRequest: stats})
 }
 
 const StatsEndpoint = "/_astats"
 
+func statsStartup(icfg interface{}, d StartupData) {
+	*d.Context = d.ParentHealth
+}
+
 func stats(icfg interface{}, d OnRequestData) bool {
 	if !strings.HasPrefix(d.R.URL.Path, StatsEndpoint) {
 		log.Debugf("plugin onrequest http_stats returning, not in path '" + d.R.URL.Path + "'\n")
@@ -70,6 +76,8 @@ func stats(icfg interface{}, d OnRequestData) bool {
 	ats := map[string]interface{}{"server": "6.2.1"}
 	if req.URL.Query().Get("application") != "system" {
 		ats = LoadRemapStats(d.Stats, d.HTTPConns, d.HTTPSConns)
+		parentHealth, _ := (*d.Context).(*parenthealth.Health)
+		LoadParentHealthStats(ats, parentHealth)
 	}
 	stats := stat.StatsJSON{System: system, ATS: ats}
 
@@ -103,8 +111,8 @@ func LoadSystemStats(stats stat.Stats, interfaceName string) stat.StatsSystemJSO
 func LoadRemapStats(stats stat.Stats, httpConns *web.ConnMap, httpsConns *web.ConnMap) map[string]interface{} {
 	statsRemaps := stats.Remap()
 	rules := statsRemaps.Rules()
-	jsonStats := make(map[string]interface{}, len(rules)*8) // remap has 8 members: in, out, 2xx, 3xx, 4xx, 5xx, hits, misses
-	jsonStats["server"] = "6.2.1"                           // emulate a good ATS version
+	jsonStats := make(map[string]interface{}, len(rules)*15) // remap has 15 members: in, out, 2xx, 3xx, 4xx, 5xx, hits, misses, stale-while-revalidate hits, stale-if-error hits, collapsed hits, collapsed waiters, collapse fallbacks, origin fetches, origin fetch time
+	jsonStats["server"] = "6.2.1"                            // emulate a good ATS version
 	for _, rule := range rules {
 		ruleName := rule
 		statsRemap, ok := statsRemaps.Stats(ruleName)
@@ -119,17 +127,47 @@ func LoadRemapStats(stats stat.Stats, httpConns *web.ConnMap, httpsConns *web.Co
 		jsonStats["plugin.remap_stats."+ruleName+".status_5xx"] = statsRemap.Status5xx()
 		jsonStats["plugin.remap_stats."+ruleName+".cache_hits"] = statsRemap.CacheHits()
 		jsonStats["plugin.remap_stats."+ruleName+".cache_misses"] = statsRemap.CacheMisses()
+		jsonStats["plugin.remap_stats."+ruleName+".stale_while_revalidate_hits"] = statsRemap.StaleWhileRevalidateHits()
+		jsonStats["plugin.remap_stats."+ruleName+".stale_if_error_hits"] = statsRemap.StaleIfErrorHits()
+		jsonStats["plugin.remap_stats."+ruleName+".collapsed_hits"] = statsRemap.CollapsedHits()
+		jsonStats["plugin.remap_stats."+ruleName+".collapsed_waiters"] = statsRemap.CollapsedWaiters()
+		jsonStats["plugin.remap_stats."+ruleName+".collapse_fallbacks"] = statsRemap.CollapseFallbacks()
+		jsonStats["plugin.remap_stats."+ruleName+".origin_fetches"] = statsRemap.OriginFetches()
+		jsonStats["plugin.remap_stats."+ruleName+".origin_fetch_time_ms"] = uint64(statsRemap.OriginFetchTime() / time.Millisecond)
 	}
 
-	jsonStats["proxy.process.http.current_client_connections"] = httpConns.Len() + httpsConns.Len()
+	jsonStats["proxy.process.http.current_client_connections"] = httpConns.Len() + httpsConns.Len() + int(stats.HTTP3Connections())
+	jsonStats["proxy.process.http3.current_client_connections"] = stats.HTTP3Connections()
+	jsonStats["proxy.process.http3.incoming_requests"] = stats.HTTP3Requests()
 	jsonStats["proxy.process.http.cache_hits"] = stats.CacheHits()
 	jsonStats["proxy.process.http.cache_misses"] = stats.CacheMisses()
+	jsonStats["proxy.process.http.cache_stale_while_revalidate_hits"] = stats.StaleWhileRevalidateHits()
+	jsonStats["proxy.process.http.cache_stale_if_error_hits"] = stats.StaleIfErrorHits()
+	jsonStats["proxy.process.http.collapsed_hits"] = stats.CollapsedHits()
+	jsonStats["proxy.process.http.collapsed_waiters"] = stats.CollapsedWaiters()
+	jsonStats["proxy.process.http.collapse_fallbacks"] = stats.CollapseFallbacks()
+	jsonStats["proxy.process.http.origin_fetches"] = stats.OriginFetches()
+	jsonStats["proxy.process.http.origin_fetch_time_ms"] = uint64(stats.OriginFetchTime() / time.Millisecond)
 	jsonStats["proxy.process.http.cache_capacity_bytes"] = stats.CacheCapacity()
 	jsonStats["proxy.process.http.cache_size_bytes"] = stats.CacheSize()
 
+	restored, restoreTotal, restoreDone := stats.CacheRestoreProgress()
+	jsonStats["proxy.process.cache.restore_objects"] = restored
+	jsonStats["proxy.process.cache.restore_objects_total"] = restoreTotal
+	jsonStats["proxy.process.cache.restore_complete"] = restoreDone
+
 	return jsonStats
 }
 
+// LoadParentHealthStats adds the health of each parent Traffic Monitor has reported to the given stats. The parentHealth may be nil, in which case nothing is added.
+func LoadParentHealthStats(jsonStats map[string]interface{}, parentHealth *parenthealth.Health) {
+	for host, state := range parentHealth.States() {
+		jsonStats["plugin.parent_health."+host+".available"] = state.Available
+		jsonStats["plugin.parent_health."+host+".status"] = state.Status
+		jsonStats["plugin.parent_health."+host+".updated"] = state.Updated.Unix()
+	}
+}
+
 func loadFileAndLog(filename string) string {
 	f, err := ioutil.ReadFile(filename)
 	if err != nil {
@@ -28,6 +28,7 @@ import (
 	"github.com/apache/trafficcontrol/grove/cachedata"
 	"github.com/apache/trafficcontrol/grove/cacheobj"
 	"github.com/apache/trafficcontrol/grove/config"
+	"github.com/apache/trafficcontrol/grove/parenthealth"
 	"github.com/apache/trafficcontrol/grove/remapdata"
 	"github.com/apache/trafficcontrol/grove/stat"
 	"github.com/apache/trafficcontrol/grove/web"
@@ -58,6 +59,10 @@ type StartupData struct {
 	Context *interface{}
 	// Shared is the "plugins_shared" data for all rules. This is a `map[ruleName][key]value`. Keys and values are arbitrary data. This allows plugins to do pre-processing on the config, and store computed data in the context, to save processing during requests.
 	Shared map[string]map[string]json.RawMessage
+	// Rules is the remap rules. This allows plugins to act on the rules' caches, for example to purge them.
+	Rules []remapdata.RemapRule
+	// ParentHealth is the health of parents, used by consistent-hash parent selection. It's nil if parent health isn't polled.
+	ParentHealth *parenthealth.Health
 }
 
 type OnRequestData struct {
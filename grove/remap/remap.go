@@ -22,11 +22,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/apache/trafficcontrol/grove/chash"
 	"github.com/apache/trafficcontrol/grove/icache"
+	"github.com/apache/trafficcontrol/grove/parenthealth"
 	"github.com/apache/trafficcontrol/grove/plugin"
 	"github.com/apache/trafficcontrol/grove/remapdata"
 	"github.com/apache/trafficcontrol/grove/web"
@@ -137,6 +139,26 @@ func (p *RemappingProducer) ProxyStr() string {
 	return "NONE" // TODO const?
 }
 
+// StaleWhileRevalidate returns the rule's override of the stale-while-revalidate window, or nil if responses' Cache-Control should be used.
+func (p *RemappingProducer) StaleWhileRevalidate() *time.Duration {
+	return p.rule.StaleWhileRevalidate
+}
+
+// StaleIfError returns the rule's override of the stale-if-error window, or nil if requests' and responses' Cache-Control should be used.
+func (p *RemappingProducer) StaleIfError() *time.Duration {
+	return p.rule.StaleIfError
+}
+
+// CollapsedForwarding returns how concurrent requests of the rule for the same object are collapsed.
+func (p *RemappingProducer) CollapsedForwarding() remapdata.CollapsedForwarding {
+	return p.rule.CollapsedForwarding
+}
+
+// Invalidated returns how the object cached for the given request, which was stored at the given time, is invalidated by the rule's Revalidations, and whether it is. Revalidation regexes are matched against the request URI, that is, the path and query string.
+func (p *RemappingProducer) Invalidated(r *http.Request, stored time.Time) (remapdata.InvalidationType, bool) {
+	return p.rule.Invalidated(r.RequestURI, stored, time.Now())
+}
+
 var ErrRuleNotFound = errors.New("remap rule not found")
 var ErrIPNotAllowed = errors.New("IP not allowed")
 var ErrNoMoreRetries = errors.New("retry num exceeded")
@@ -253,7 +275,7 @@ func (r literalPrefixRemapper) Remap(s string) (remapdata.RemapRule, bool) {
 }
 
 func (r literalPrefixRemapper) Rules() []remapdata.RemapRule {
-	rules := make([]remapdata.RemapRule, len(r.remap))
+	rules := make([]remapdata.RemapRule, 0, len(r.remap))
 	for _, rule := range r.remap {
 		rules = append(rules, rule)
 	}
@@ -276,23 +298,29 @@ type RemapRulesBase struct {
 
 type RemapRulesJSON struct {
 	RemapRulesBase
-	Rules           []RemapRuleJSON            `json:"rules"`
-	RetryCodes      *[]int                     `json:"retry_codes"`
-	TimeoutMS       *int                       `json:"timeout_ms"`
-	ParentSelection *string                    `json:"parent_selection"`
-	Stats           RemapRulesStatsJSON        `json:"stats"`
-	Plugins         map[string]json.RawMessage `json:"plugins"`
+	Rules                  []RemapRuleJSON            `json:"rules"`
+	RetryCodes             *[]int                     `json:"retry_codes"`
+	TimeoutMS              *int                       `json:"timeout_ms"`
+	ParentSelection        *string                    `json:"parent_selection"`
+	Stats                  RemapRulesStatsJSON        `json:"stats"`
+	Plugins                map[string]json.RawMessage `json:"plugins"`
+	StaleWhileRevalidateMS *int                       `json:"stale_while_revalidate_ms"`
+	StaleIfErrorMS         *int                       `json:"stale_if_error_ms"`
+	CollapsedForwarding    *CollapsedForwardingJSON   `json:"collapsed_forwarding"`
 }
 
 type RemapRules struct {
 	RemapRulesBase
-	Rules           []remapdata.RemapRule
-	RetryCodes      map[int]struct{}
-	Timeout         *time.Duration
-	ParentSelection *remapdata.ParentSelectionType
-	Stats           remapdata.RemapRulesStats
-	Plugins         map[string]interface{}
-	Cache           icache.Cache
+	Rules                []remapdata.RemapRule
+	RetryCodes           map[int]struct{}
+	Timeout              *time.Duration
+	ParentSelection      *remapdata.ParentSelectionType
+	Stats                remapdata.RemapRulesStats
+	Plugins              map[string]interface{}
+	Cache                icache.Cache
+	StaleWhileRevalidate *time.Duration
+	StaleIfError         *time.Duration
+	CollapsedForwarding  remapdata.CollapsedForwarding
 }
 
 type RemapRuleToJSON struct {
@@ -308,18 +336,37 @@ type HdrModder interface {
 
 type RemapRuleJSON struct {
 	remapdata.RemapRuleBase
-	TimeoutMS       *int                       `json:"timeout_ms"`
-	ParentSelection *string                    `json:"parent_selection"`
-	To              []RemapRuleToJSON          `json:"to"`
-	Allow           []string                   `json:"allow"`
-	Deny            []string                   `json:"deny"`
-	RetryCodes      *[]int                     `json:"retry_codes"`
-	CacheName       *string                    `json:"cache_name"`
-	Plugins         map[string]json.RawMessage `json:"plugins"`
-}
-
-// LoadRemapRules returns the loaded rules, the global plugins, the Stats remap rules, and any error
-func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc, caches map[string]icache.Cache, baseTransport *http.Transport) ([]remapdata.RemapRule, map[string]interface{}, *remapdata.RemapRulesStats, error) {
+	TimeoutMS              *int                       `json:"timeout_ms"`
+	ParentSelection        *string                    `json:"parent_selection"`
+	To                     []RemapRuleToJSON          `json:"to"`
+	Allow                  []string                   `json:"allow"`
+	Deny                   []string                   `json:"deny"`
+	RetryCodes             *[]int                     `json:"retry_codes"`
+	CacheName              *string                    `json:"cache_name"`
+	Plugins                map[string]json.RawMessage `json:"plugins"`
+	StaleWhileRevalidateMS *int                       `json:"stale_while_revalidate_ms"`
+	StaleIfErrorMS         *int                       `json:"stale_if_error_ms"`
+	Revalidate             []RevalidationJSON         `json:"revalidate"`
+	CollapsedForwarding    *CollapsedForwardingJSON   `json:"collapsed_forwarding"`
+}
+
+// CollapsedForwardingJSON is a remapdata.CollapsedForwarding. Fields not set in a rule are taken from the global collapsed_forwarding.
+type CollapsedForwardingJSON struct {
+	Enabled       *bool `json:"enabled"`
+	MaxWaiters    *int  `json:"max_waiters"`
+	WaitTimeoutMS *int  `json:"wait_timeout_ms"`
+}
+
+// RevalidationJSON is a remapdata.Revalidation, as written by grovetccfg from Traffic Ops invalidation jobs.
+type RevalidationJSON struct {
+	Regex     string    `json:"regex"`
+	StartTime time.Time `json:"start_time"`
+	TTLHours  uint      `json:"ttl_hours"`
+	Type      string    `json:"type"`
+}
+
+// LoadRemapRules returns the loaded rules, the global plugins, the Stats remap rules, and any error. The parentHealth may be nil, if parent health isn't polled.
+func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc, caches map[string]icache.Cache, baseTransport *http.Transport, parentHealth *parenthealth.Health) ([]remapdata.RemapRule, map[string]interface{}, *remapdata.RemapRulesStats, error) {
 	fmt.Println(time.Now().Format(time.RFC3339Nano) + " Loading Remap Rules")
 	defer func() {
 		fmt.Println(time.Now().Format(time.RFC3339Nano) + " Loaded Remap Rules")
@@ -352,6 +399,15 @@ func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc,
 			return nil, nil, nil, fmt.Errorf("error parsing rules: timeout must be positive: %v", remapRules.Timeout)
 		}
 	}
+	if remapRules.StaleWhileRevalidate, err = makeStaleWindow(remapRulesJSON.StaleWhileRevalidateMS); err != nil {
+		return nil, nil, nil, fmt.Errorf("error parsing rules stale_while_revalidate_ms: %v", err)
+	}
+	if remapRules.StaleIfError, err = makeStaleWindow(remapRulesJSON.StaleIfErrorMS); err != nil {
+		return nil, nil, nil, fmt.Errorf("error parsing rules stale_if_error_ms: %v", err)
+	}
+	if remapRules.CollapsedForwarding, err = makeCollapsedForwarding(remapRulesJSON.CollapsedForwarding, remapdata.CollapsedForwarding{}); err != nil {
+		return nil, nil, nil, fmt.Errorf("error parsing rules collapsed_forwarding: %v", err)
+	}
 	if remapRulesJSON.ParentSelection != nil {
 		ps := remapdata.ParentSelectionTypeFromString(*remapRulesJSON.ParentSelection)
 		if remapRules.ParentSelection = &ps; *remapRules.ParentSelection == remapdata.ParentSelectionTypeInvalid {
@@ -418,10 +474,33 @@ func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc,
 			rule.RetryNum = remapRules.RetryNum
 		}
 
+		if jsonRule.StaleWhileRevalidateMS != nil {
+			if rule.StaleWhileRevalidate, err = makeStaleWindow(jsonRule.StaleWhileRevalidateMS); err != nil {
+				return nil, nil, nil, fmt.Errorf("error parsing rule %v stale_while_revalidate_ms: %v", rule.Name, err)
+			}
+		} else {
+			rule.StaleWhileRevalidate = remapRules.StaleWhileRevalidate
+		}
+		if jsonRule.StaleIfErrorMS != nil {
+			if rule.StaleIfError, err = makeStaleWindow(jsonRule.StaleIfErrorMS); err != nil {
+				return nil, nil, nil, fmt.Errorf("error parsing rule %v stale_if_error_ms: %v", rule.Name, err)
+			}
+		} else {
+			rule.StaleIfError = remapRules.StaleIfError
+		}
+
 		if rule.PluginsShared == nil {
 			rule.PluginsShared = remapRules.PluginsShared
 		}
 
+		if rule.Revalidations, err = makeRevalidations(jsonRule.Revalidate); err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing rule %v revalidate: %v", rule.Name, err)
+		}
+
+		if rule.CollapsedForwarding, err = makeCollapsedForwarding(jsonRule.CollapsedForwarding, remapRules.CollapsedForwarding); err != nil {
+			return nil, nil, nil, fmt.Errorf("error parsing rule %v collapsed_forwarding: %v", rule.Name, err)
+		}
+
 		cacheName := "" // default string is the default cache
 		if jsonRule.CacheName != nil {
 			cacheName = *jsonRule.CacheName
@@ -459,6 +538,7 @@ func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc,
 
 		if *rule.ParentSelection == remapdata.ParentSelectionTypeConsistentHash {
 			rule.ConsistentHash = makeRuleHash(rule)
+			rule.ParentHealth = parentHealth
 		} else {
 		}
 		rules[i] = rule
@@ -467,12 +547,70 @@ func LoadRemapRules(path string, pluginConfigLoaders map[string]plugin.LoadFunc,
 	return rules, remapRules.Plugins, &remapRules.Stats, nil
 }
 
+// makeStaleWindow returns the duration of the given stale window in milliseconds, or nil if it's nil, in which case the window is taken from the Cache-Control of the request and response.
+func makeStaleWindow(ms *int) (*time.Duration, error) {
+	if ms == nil {
+		return nil, nil
+	}
+	if *ms < 0 {
+		return nil, fmt.Errorf("must not be negative: %v", *ms)
+	}
+	d := time.Duration(*ms) * time.Millisecond
+	return &d, nil
+}
+
+// makeRevalidations returns the Revalidations of the given JSON, or an error if any regex or type is invalid.
+func makeRevalidations(revalsJSON []RevalidationJSON) ([]remapdata.Revalidation, error) {
+	revals := make([]remapdata.Revalidation, 0, len(revalsJSON))
+	for _, revalJSON := range revalsJSON {
+		regex, err := regexp.Compile(revalJSON.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("regex '%v' invalid: %v", revalJSON.Regex, err)
+		}
+		reval := remapdata.Revalidation{
+			Regex:     regex,
+			StartTime: revalJSON.StartTime,
+			TTL:       time.Duration(revalJSON.TTLHours) * time.Hour,
+			Type:      remapdata.InvalidationTypeFromString(revalJSON.Type),
+		}
+		if reval.Type == remapdata.InvalidationTypeInvalid {
+			return nil, fmt.Errorf("regex '%v' type invalid: '%v'", revalJSON.Regex, revalJSON.Type)
+		}
+		revals = append(revals, reval)
+	}
+	return revals, nil
+}
+
+// makeCollapsedForwarding returns the CollapsedForwarding of the given JSON, with unset fields taken from base.
+func makeCollapsedForwarding(cfJSON *CollapsedForwardingJSON, base remapdata.CollapsedForwarding) (remapdata.CollapsedForwarding, error) {
+	cf := base
+	if cfJSON == nil {
+		return cf, nil
+	}
+	if cfJSON.Enabled != nil {
+		cf.Disabled = !*cfJSON.Enabled
+	}
+	if cfJSON.MaxWaiters != nil {
+		if *cfJSON.MaxWaiters < 0 {
+			return remapdata.CollapsedForwarding{}, fmt.Errorf("max_waiters must not be negative: %v", *cfJSON.MaxWaiters)
+		}
+		cf.MaxWaiters = *cfJSON.MaxWaiters
+	}
+	if cfJSON.WaitTimeoutMS != nil {
+		if *cfJSON.WaitTimeoutMS < 0 {
+			return remapdata.CollapsedForwarding{}, fmt.Errorf("wait_timeout_ms must not be negative: %v", *cfJSON.WaitTimeoutMS)
+		}
+		cf.WaitTimeout = time.Duration(*cfJSON.WaitTimeoutMS) * time.Millisecond
+	}
+	return cf, nil
+}
+
 const DefaultReplicas = 1024
 
 func makeRuleHash(rule remapdata.RemapRule) chash.ATSConsistentHash {
 	h := chash.NewSimpleATSConsistentHash(DefaultReplicas)
 	for _, to := range rule.To {
-		h.Insert(&chash.ATSConsistentHashNode{Name: to.URL, ProxyURL: to.ProxyURL, Transport: to.Transport}, *to.Weight)
+		h.Insert(&chash.ATSConsistentHashNode{Name: to.URL, ProxyURL: to.ProxyURL, Transport: to.Transport, Hostname: toHostname(to)}, *to.Weight)
 	}
 	if h.First() == nil {
 		fmt.Println(time.Now().Format(time.RFC3339Nano) + " ERROR  makeRuleHash " + rule.Name + " NodeMap empty!")
@@ -481,6 +619,18 @@ func makeRuleHash(rule remapdata.RemapRule) chash.ATSConsistentHash {
 	return h
 }
 
+// toHostname returns the hostname of the parent cache of the given To, which is its proxy if it has one. Returns the empty string if the URL is invalid, which makeTo should have already prevented.
+func toHostname(to remapdata.RemapRuleTo) string {
+	if to.ProxyURL != nil {
+		return to.ProxyURL.Hostname()
+	}
+	u, err := url.Parse(to.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
 func makeTo(tosJSON []RemapRuleToJSON, rule remapdata.RemapRule, baseTransport *http.Transport) ([]remapdata.RemapRuleTo, error) {
 	tos := make([]remapdata.RemapRuleTo, len(tosJSON))
 	for i, toJSON := range tosJSON {
@@ -563,8 +713,8 @@ func makeIPNet(cidr string) (*net.IPNet, error) {
 	return cidrnet, nil
 }
 
-func LoadRemapper(path string, pluginConfigLoaders map[string]plugin.LoadFunc, caches map[string]icache.Cache, baseTransport *http.Transport) (HTTPRequestRemapper, error) {
-	rules, plugins, statRules, err := LoadRemapRules(path, pluginConfigLoaders, caches, baseTransport)
+func LoadRemapper(path string, pluginConfigLoaders map[string]plugin.LoadFunc, caches map[string]icache.Cache, baseTransport *http.Transport, parentHealth *parenthealth.Health) (HTTPRequestRemapper, error) {
+	rules, plugins, statRules, err := LoadRemapRules(path, pluginConfigLoaders, caches, baseTransport, parentHealth)
 	if err != nil {
 		return nil, err
 	}
@@ -590,6 +740,9 @@ func RemapRulesToJSON(r RemapRules) (RemapRulesJSON, error) {
 		j.ParentSelection = &s
 		*j.ParentSelection = string(*r.ParentSelection)
 	}
+	j.StaleWhileRevalidateMS = staleWindowToJSON(r.StaleWhileRevalidate)
+	j.StaleIfErrorMS = staleWindowToJSON(r.StaleIfError)
+	j.CollapsedForwarding = collapsedForwardingToJSON(r.CollapsedForwarding)
 	for _, deny := range r.Stats.Deny {
 		j.Stats.Deny = append(j.Stats.Deny, deny.String())
 	}
@@ -623,9 +776,20 @@ func buildRemapRuleToJSON(r remapdata.RemapRule) RemapRuleJSON {
 		j.ParentSelection = &ps
 		*j.ParentSelection = string(*r.ParentSelection)
 	}
+	j.StaleWhileRevalidateMS = staleWindowToJSON(r.StaleWhileRevalidate)
+	j.StaleIfErrorMS = staleWindowToJSON(r.StaleIfError)
+	j.CollapsedForwarding = collapsedForwardingToJSON(r.CollapsedForwarding)
 	for _, to := range r.To {
 		j.To = append(j.To, RemapRuleToToJSON(to))
 	}
+	for _, reval := range r.Revalidations {
+		j.Revalidate = append(j.Revalidate, RevalidationJSON{
+			Regex:     reval.Regex.String(),
+			StartTime: reval.StartTime,
+			TTLHours:  uint(reval.TTL / time.Hour),
+			Type:      string(reval.Type),
+		})
+	}
 	for _, deny := range r.Deny {
 		j.Deny = append(j.Deny, deny.String())
 	}
@@ -647,6 +811,21 @@ func buildRemapRuleToJSON(r remapdata.RemapRule) RemapRuleJSON {
 	return j
 }
 
+func staleWindowToJSON(d *time.Duration) *int {
+	if d == nil {
+		return nil
+	}
+	ms := int(*d / time.Millisecond)
+	return &ms
+}
+
+func collapsedForwardingToJSON(cf remapdata.CollapsedForwarding) *CollapsedForwardingJSON {
+	enabled := !cf.Disabled
+	maxWaiters := cf.MaxWaiters
+	waitTimeoutMS := int(cf.WaitTimeout / time.Millisecond)
+	return &CollapsedForwardingJSON{Enabled: &enabled, MaxWaiters: &maxWaiters, WaitTimeoutMS: &waitTimeoutMS}
+}
+
 func RemapRuleToToJSON(r remapdata.RemapRuleTo) RemapRuleToJSON {
 	j := RemapRuleToJSON{RemapRuleToBase: r.RemapRuleToBase}
 	if r.ProxyURL != nil {
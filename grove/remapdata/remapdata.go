@@ -21,11 +21,13 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/apache/trafficcontrol/grove/chash"
 	"github.com/apache/trafficcontrol/grove/icache"
+	"github.com/apache/trafficcontrol/grove/parenthealth"
 
 	"github.com/apache/trafficcontrol/lib/go-log"
 )
@@ -61,6 +63,46 @@ func ParentSelectionTypeFromString(s string) ParentSelectionType {
 	return ParentSelectionTypeInvalid
 }
 
+// InvalidationType is how cached objects are invalidated by a purge or Revalidation, with the same semantics as Traffic Ops invalidation jobs.
+type InvalidationType string
+
+const (
+	// InvalidationTypeRefresh marks objects as stale, so they're revalidated with the parent before they're used again.
+	InvalidationTypeRefresh = InvalidationType("REFRESH")
+	// InvalidationTypeRefetch makes objects unusable, so they're fetched again from the parent.
+	InvalidationTypeRefetch = InvalidationType("REFETCH")
+	InvalidationTypeInvalid = InvalidationType("")
+)
+
+func InvalidationTypeFromString(s string) InvalidationType {
+	s = strings.ToUpper(s)
+	if s == string(InvalidationTypeRefresh) {
+		return InvalidationTypeRefresh
+	}
+	if s == string(InvalidationTypeRefetch) {
+		return InvalidationTypeRefetch
+	}
+	return InvalidationTypeInvalid
+}
+
+// Revalidation invalidates cached objects whose request URIs match Regex, if they were stored before StartTime, from StartTime until StartTime+TTL.
+type Revalidation struct {
+	Regex     *regexp.Regexp
+	StartTime time.Time
+	TTL       time.Duration
+	Type      InvalidationType
+}
+
+// CollapsedForwarding configures how concurrent requests for the same uncached object are collapsed into a single parent request. The zero value collapses all concurrent requests, waiting indefinitely.
+type CollapsedForwarding struct {
+	// Disabled makes every request for an uncached object make its own parent request.
+	Disabled bool
+	// MaxWaiters is the most requests which may wait on a single parent request. Requests beyond it make their own parent requests. Zero is unlimited.
+	MaxWaiters int
+	// WaitTimeout is how long a request waits on another's parent request, before making its own. Zero waits indefinitely.
+	WaitTimeout time.Duration
+}
+
 type RemapRulesStats struct {
 	Allow []*net.IPNet
 	Deny  []*net.IPNet
@@ -111,6 +153,16 @@ type RemapRule struct {
 	ConsistentHash  chash.ATSConsistentHash
 	Cache           icache.Cache
 	Plugins         map[string]interface{}
+	// StaleWhileRevalidate, if not nil, overrides the RFC5861 stale-while-revalidate window of responses for this rule. Zero disables serving stale while revalidating.
+	StaleWhileRevalidate *time.Duration
+	// StaleIfError, if not nil, overrides the RFC5861 stale-if-error window of requests and responses for this rule. Zero disables serving stale on parent errors.
+	StaleIfError *time.Duration
+	// Revalidations invalidate cached objects of this rule, typically from Traffic Ops invalidation jobs.
+	Revalidations []Revalidation
+	// CollapsedForwarding configures collapsing concurrent requests of this rule for the same object.
+	CollapsedForwarding CollapsedForwarding
+	// ParentHealth, if not nil, is used to skip parents which are down in consistent-hash parent selection.
+	ParentHealth *parenthealth.Health
 }
 
 func (r *RemapRule) Allowed(ip net.IP) bool {
@@ -133,6 +185,23 @@ func (r *RemapRule) Allowed(ip net.IP) bool {
 	return false
 }
 
+// Invalidated returns how the object cached for the given request URI, which was stored at the given time, is invalidated by the rule's Revalidations at time now, and whether it is. If multiple Revalidations match, REFETCH takes precedence over REFRESH.
+func (r *RemapRule) Invalidated(requestURI string, stored time.Time, now time.Time) (InvalidationType, bool) {
+	invalidation := InvalidationTypeInvalid
+	for _, reval := range r.Revalidations {
+		if !stored.Before(reval.StartTime) || now.Before(reval.StartTime) || !now.Before(reval.StartTime.Add(reval.TTL)) {
+			continue
+		}
+		if !reval.Regex.MatchString(requestURI) {
+			continue
+		}
+		if invalidation = reval.Type; invalidation == InvalidationTypeRefetch {
+			break
+		}
+	}
+	return invalidation, invalidation != InvalidationTypeInvalid
+}
+
 // URI takes a request URI and maps it to the real URI to proxy-and-cache. The `failures` parameter indicates how many parents have tried and failed, indicating to skip to the nth hashed parent. Returns the URI to request, and the proxy URL (if any)
 func (r RemapRule) URI(fromURI string, path string, query string, failures int) (string, *url.URL, *http.Transport) {
 	fromHash := path
@@ -185,9 +254,28 @@ func (r RemapRule) uriGetToConsistentHash(fromURI string, failures int) (string,
 		iter = iter.NextWrap()
 	}
 
+	iter = r.nextAvailable(iter)
 	return iter.Val().Name, iter.Val().ProxyURL, iter.Val().Transport
 }
 
+// nextAvailable returns the first node at or after iter in the ring whose parent is available, the same way ATS skips parents marked down. If no parent is available, it logs and returns iter, since trying a parent which may be down is better than failing the request.
+func (r RemapRule) nextAvailable(iter chash.OrderedMapUint64NodeIterator) chash.OrderedMapUint64NodeIterator {
+	if r.ParentHealth == nil {
+		return iter
+	}
+	start := iter
+	for {
+		if r.ParentHealth.Available(iter.Val().Hostname) {
+			return iter
+		}
+		// compare indexes, not keys, because LowerBound iterators have the looked-up key, not the ring's
+		if iter = iter.NextWrap(); iter.Index() == start.Index() {
+			log.Debugf("RemapRule.URI: Rule '%v': all parents are marked down, using '%v'\n", r.Name, start.Val().Name)
+			return start
+		}
+	}
+}
+
 func (r RemapRule) CacheKey(method string, fromURI string) string {
 	// TODO don't cache on `to`, since it's affected by Parent Selection
 	// TODO add parent selection
@@ -0,0 +1,111 @@
+package remapdata
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/chash"
+	"github.com/apache/trafficcontrol/grove/parenthealth"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func TestRemapRuleInvalidated(t *testing.T) {
+	start := time.Date(2022, 5, 17, 10, 0, 0, 0, time.UTC)
+	rule := RemapRule{Revalidations: []Revalidation{
+		{Regex: regexp.MustCompile(`^/images/.*\.png`), StartTime: start, TTL: 24 * time.Hour, Type: InvalidationTypeRefresh},
+		{Regex: regexp.MustCompile(`^/images/logo\.png`), StartTime: start, TTL: time.Hour, Type: InvalidationTypeRefetch},
+	}}
+
+	tests := []struct {
+		name        string
+		uri         string
+		stored      time.Time
+		now         time.Time
+		invalidated bool
+		typ         InvalidationType
+	}{
+		{name: "refresh", uri: "/images/foo.png?v=1", stored: start.Add(-time.Minute), now: start.Add(2 * time.Hour), invalidated: true, typ: InvalidationTypeRefresh},
+		{name: "refetch over refresh", uri: "/images/logo.png", stored: start.Add(-time.Minute), now: start.Add(time.Minute), invalidated: true, typ: InvalidationTypeRefetch},
+		{name: "refetch expired", uri: "/images/logo.png", stored: start.Add(-time.Minute), now: start.Add(2 * time.Hour), invalidated: true, typ: InvalidationTypeRefresh},
+		{name: "stored after start", uri: "/images/foo.png", stored: start.Add(time.Second), now: start.Add(time.Minute)},
+		{name: "not started", uri: "/images/foo.png", stored: start.Add(-time.Hour), now: start.Add(-time.Minute)},
+		{name: "expired", uri: "/images/foo.png", stored: start.Add(-time.Minute), now: start.Add(24 * time.Hour)},
+		{name: "no match", uri: "/css/images/foo.png", stored: start.Add(-time.Minute), now: start.Add(time.Minute)},
+	}
+	for _, test := range tests {
+		typ, invalidated := rule.Invalidated(test.uri, test.stored, test.now)
+		if invalidated != test.invalidated || typ != test.typ {
+			t.Errorf("%s: expected invalidated %v type '%s', actual: %v '%s'", test.name, test.invalidated, test.typ, invalidated, typ)
+		}
+	}
+}
+
+func TestRemapRuleURIParentHealth(t *testing.T) {
+	ps := ParentSelectionTypeConsistentHash
+	rule := RemapRule{
+		RemapRuleBase:   RemapRuleBase{Name: "foo", From: "http://foo.example.net"},
+		ParentSelection: &ps,
+		ConsistentHash:  chash.NewSimpleATSConsistentHash(1024),
+		ParentHealth:    parenthealth.New(),
+	}
+	for _, host := range []string{"mid-0", "mid-1", "mid-2"} {
+		to := RemapRuleTo{RemapRuleToBase: RemapRuleToBase{URL: "http://" + host + ".example.net"}}
+		rule.To = append(rule.To, to)
+		rule.ConsistentHash.Insert(&chash.ATSConsistentHashNode{Name: to.URL, Hostname: host + ".example.net"}, 1)
+	}
+
+	const path = "/foo.png"
+	uri, _, _ := rule.URI("http://foo.example.net"+path, path, "", 0)
+	hashed := uri[:len(uri)-len(path)]
+
+	// marking a different parent down doesn't change the selected parent
+	crStates := tc.CRStates{Caches: map[tc.CacheName]tc.IsAvailable{}}
+	for _, to := range rule.To {
+		if to.URL != hashed {
+			crStates.Caches[tc.CacheName(to.URL[len("http://"):len(to.URL)-len(".example.net")])] = tc.IsAvailable{IsAvailable: false, Status: "REPORTED"}
+			break
+		}
+	}
+	rule.ParentHealth.SetCRStates(crStates, time.Now())
+	if uri, _, _ := rule.URI("http://foo.example.net"+path, path, "", 0); uri != hashed+path {
+		t.Errorf("expected '%v' with another parent down, actual '%v'", hashed+path, uri)
+	}
+
+	// marking the selected parent down selects another
+	crStates.Caches[tc.CacheName(hashed[len("http://"):len(hashed)-len(".example.net")])] = tc.IsAvailable{IsAvailable: false, Status: "REPORTED"}
+	rule.ParentHealth.SetCRStates(crStates, time.Now())
+	uri, _, _ = rule.URI("http://foo.example.net"+path, path, "", 0)
+	if uri == hashed+path {
+		t.Errorf("expected a parent other than '%v' when it's down, actual '%v'", hashed, uri)
+	}
+	for name := range crStates.Caches {
+		if uri == "http://"+string(name)+".example.net"+path {
+			t.Errorf("expected an available parent, actual down parent '%v'", uri)
+		}
+	}
+
+	// with all parents down, the hashed parent is used
+	for _, to := range rule.To {
+		crStates.Caches[tc.CacheName(to.URL[len("http://"):len(to.URL)-len(".example.net")])] = tc.IsAvailable{IsAvailable: false}
+	}
+	rule.ParentHealth.SetCRStates(crStates, time.Now())
+	if uri, _, _ := rule.URI("http://foo.example.net"+path, path, "", 0); uri != hashed+path {
+		t.Errorf("expected '%v' with all parents down, actual '%v'", hashed+path, uri)
+	}
+}
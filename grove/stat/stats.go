@@ -48,14 +48,49 @@ type Stats interface {
 	Remap() StatsRemaps
 
 	Connections() uint64
+	// HTTP3Connections is the number of open HTTP/3 (QUIC) client connections. They are also included in Connections.
+	HTTP3Connections() uint64
+	// HTTP3Requests is the number of requests received over HTTP/3.
+	HTTP3Requests() uint64
+	AddHTTP3Request()
 
 	CacheHits() uint64
 	AddCacheHit()
 	CacheMisses() uint64
 	AddCacheMiss()
 
+	// StaleWhileRevalidateHits is the number of stale objects served while being revalidated, per RFC5861§3.
+	StaleWhileRevalidateHits() uint64
+	// AddStaleWhileRevalidateHit adds a stale-while-revalidate hit, globally and to the remap rule of the given request FQDN.
+	AddStaleWhileRevalidateHit(reqFQDN string)
+	// StaleIfErrorHits is the number of stale objects served because revalidating them failed, per RFC5861§4.
+	StaleIfErrorHits() uint64
+	// AddStaleIfErrorHit adds a stale-if-error hit, globally and to the remap rule of the given request FQDN.
+	AddStaleIfErrorHit(reqFQDN string)
+
+	// CollapsedHits is the number of requests served by a concurrent request's parent fetch, rather than their own.
+	CollapsedHits() uint64
+	// AddCollapsedHit adds a collapsed hit, globally and to the remap rule of the given request FQDN.
+	AddCollapsedHit(reqFQDN string)
+	// CollapsedWaiters is the number of requests currently waiting on a concurrent request's parent fetch.
+	CollapsedWaiters() int64
+	// AddCollapsedWaiters adds delta to the collapsed waiters, globally and of the remap rule of the given request FQDN.
+	AddCollapsedWaiters(reqFQDN string, delta int64)
+	// CollapseFallbacks is the number of requests which waited on, or tried to wait on, a concurrent request's parent fetch, but made their own, because there were too many waiters, the wait timed out, or the response couldn't be used.
+	CollapseFallbacks() uint64
+	// AddCollapseFallback adds a collapse fallback, globally and to the remap rule of the given request FQDN.
+	AddCollapseFallback(reqFQDN string)
+	// OriginFetches is the number of requests made to parents.
+	OriginFetches() uint64
+	// OriginFetchTime is the total time taken by requests made to parents.
+	OriginFetchTime() time.Duration
+	// AddOriginFetch adds a parent fetch which took the given latency, globally and to the remap rule of the given request FQDN.
+	AddOriginFetch(reqFQDN string, latency time.Duration)
+
 	CacheSize() uint64
 	CacheCapacity() uint64
+	// CacheRestoreProgress is the number of objects restored after a restart by all caches, the total number of objects they have to restore, and whether all of them are done restoring.
+	CacheRestoreProgress() (uint64, uint64, bool)
 
 	// Write writes to the remapRuleStats of s, and returns the bytes written to the connection
 	Write(w http.ResponseWriter, conn *web.InterceptConn, reqFQDN string, remoteAddr string, code int, bytesWritten uint64, cacheHit bool) uint64
@@ -67,18 +102,35 @@ type Stats interface {
 	CachePeek(string, string) (*cacheobj.CacheObj, bool)
 }
 
-func New(remapRules []remapdata.RemapRule, caches map[string]icache.Cache, cacheCapacityBytes uint64, httpConns *web.ConnMap, httpsConns *web.ConnMap, version string) Stats {
+func New(remapRules []remapdata.RemapRule, caches map[string]icache.Cache, cacheCapacityBytes uint64, httpConns *web.ConnMap, httpsConns *web.ConnMap, http3Conns *web.HTTP3Conns, version string) Stats {
 	cacheHits := uint64(0)
 	cacheMisses := uint64(0)
+	staleWhileRevalidateHits := uint64(0)
+	staleIfErrorHits := uint64(0)
+	http3Requests := uint64(0)
+	collapsedHits := uint64(0)
+	collapsedWaiters := int64(0)
+	collapseFallbacks := uint64(0)
+	originFetches := uint64(0)
+	originFetchTimeNS := uint64(0)
 	return &stats{
-		system:             NewStatsSystem(version),
-		remap:              NewStatsRemaps(remapRules),
-		cacheHits:          &cacheHits,
-		cacheMisses:        &cacheMisses,
-		caches:             caches,
-		cacheCapacityBytes: cacheCapacityBytes,
-		httpConns:          httpConns,
-		httpsConns:         httpsConns,
+		system:                   NewStatsSystem(version),
+		remap:                    NewStatsRemaps(remapRules),
+		cacheHits:                &cacheHits,
+		cacheMisses:              &cacheMisses,
+		staleWhileRevalidateHits: &staleWhileRevalidateHits,
+		staleIfErrorHits:         &staleIfErrorHits,
+		caches:                   caches,
+		cacheCapacityBytes:       cacheCapacityBytes,
+		httpConns:                httpConns,
+		httpsConns:               httpsConns,
+		http3Conns:               http3Conns,
+		http3Requests:            &http3Requests,
+		collapsedHits:            &collapsedHits,
+		collapsedWaiters:         &collapsedWaiters,
+		collapseFallbacks:        &collapseFallbacks,
+		originFetches:            &originFetches,
+		originFetchTimeNS:        &originFetchTimeNS,
 	}
 }
 
@@ -134,14 +186,23 @@ func (stats *stats) Write(w http.ResponseWriter, conn *web.InterceptConn, reqFQD
 
 // stats fulfills the Stats interface
 type stats struct {
-	system             StatsSystem
-	remap              StatsRemaps
-	cacheHits          *uint64
-	cacheMisses        *uint64
-	caches             map[string]icache.Cache
-	cacheCapacityBytes uint64
-	httpConns          *web.ConnMap
-	httpsConns         *web.ConnMap
+	system                   StatsSystem
+	remap                    StatsRemaps
+	cacheHits                *uint64
+	cacheMisses              *uint64
+	staleWhileRevalidateHits *uint64
+	staleIfErrorHits         *uint64
+	caches                   map[string]icache.Cache
+	cacheCapacityBytes       uint64
+	httpConns                *web.ConnMap
+	httpsConns               *web.ConnMap
+	http3Conns               *web.HTTP3Conns
+	http3Requests            *uint64
+	collapsedHits            *uint64
+	collapsedWaiters         *int64
+	collapseFallbacks        *uint64
+	originFetches            *uint64
+	originFetchTimeNS        *uint64
 }
 
 func (s stats) Connections() uint64 {
@@ -152,14 +213,74 @@ func (s stats) Connections() uint64 {
 	if s.httpsConns != nil {
 		l += uint64(s.httpsConns.Len())
 	}
+	l += uint64(s.http3Conns.Len())
 	return l
 }
-func (s stats) CacheHits() uint64    { return atomic.LoadUint64(s.cacheHits) }
-func (s stats) AddCacheHit()         { atomic.AddUint64(s.cacheHits, 1) }
-func (s stats) CacheMisses() uint64  { return atomic.LoadUint64(s.cacheMisses) }
-func (s stats) AddCacheMiss()        { atomic.AddUint64(s.cacheMisses, 1) }
-func (s *stats) System() StatsSystem { return StatsSystem(s.system) }
-func (s *stats) Remap() StatsRemaps  { return s.remap }
+func (s stats) HTTP3Connections() uint64 { return uint64(s.http3Conns.Len()) }
+func (s stats) HTTP3Requests() uint64    { return atomic.LoadUint64(s.http3Requests) }
+func (s stats) AddHTTP3Request()         { atomic.AddUint64(s.http3Requests, 1) }
+func (s stats) CacheHits() uint64        { return atomic.LoadUint64(s.cacheHits) }
+func (s stats) AddCacheHit()             { atomic.AddUint64(s.cacheHits, 1) }
+func (s stats) CacheMisses() uint64      { return atomic.LoadUint64(s.cacheMisses) }
+func (s stats) AddCacheMiss()            { atomic.AddUint64(s.cacheMisses, 1) }
+func (s *stats) System() StatsSystem     { return StatsSystem(s.system) }
+func (s *stats) Remap() StatsRemaps      { return s.remap }
+
+func (s stats) StaleWhileRevalidateHits() uint64 {
+	return atomic.LoadUint64(s.staleWhileRevalidateHits)
+}
+func (s stats) StaleIfErrorHits() uint64 { return atomic.LoadUint64(s.staleIfErrorHits) }
+
+func (s stats) AddStaleWhileRevalidateHit(reqFQDN string) {
+	atomic.AddUint64(s.staleWhileRevalidateHits, 1)
+	if remapRuleStats, ok := s.remap.Stats(reqFQDN); ok {
+		remapRuleStats.AddStaleWhileRevalidateHit()
+	}
+}
+
+func (s stats) AddStaleIfErrorHit(reqFQDN string) {
+	atomic.AddUint64(s.staleIfErrorHits, 1)
+	if remapRuleStats, ok := s.remap.Stats(reqFQDN); ok {
+		remapRuleStats.AddStaleIfErrorHit()
+	}
+}
+
+func (s stats) CollapsedHits() uint64     { return atomic.LoadUint64(s.collapsedHits) }
+func (s stats) CollapsedWaiters() int64   { return atomic.LoadInt64(s.collapsedWaiters) }
+func (s stats) CollapseFallbacks() uint64 { return atomic.LoadUint64(s.collapseFallbacks) }
+func (s stats) OriginFetches() uint64     { return atomic.LoadUint64(s.originFetches) }
+func (s stats) OriginFetchTime() time.Duration {
+	return time.Duration(atomic.LoadUint64(s.originFetchTimeNS))
+}
+
+func (s stats) AddCollapsedHit(reqFQDN string) {
+	atomic.AddUint64(s.collapsedHits, 1)
+	if remapRuleStats, ok := s.remap.Stats(reqFQDN); ok {
+		remapRuleStats.AddCollapsedHit()
+	}
+}
+
+func (s stats) AddCollapsedWaiters(reqFQDN string, delta int64) {
+	atomic.AddInt64(s.collapsedWaiters, delta)
+	if remapRuleStats, ok := s.remap.Stats(reqFQDN); ok {
+		remapRuleStats.AddCollapsedWaiters(delta)
+	}
+}
+
+func (s stats) AddCollapseFallback(reqFQDN string) {
+	atomic.AddUint64(s.collapseFallbacks, 1)
+	if remapRuleStats, ok := s.remap.Stats(reqFQDN); ok {
+		remapRuleStats.AddCollapseFallback()
+	}
+}
+
+func (s stats) AddOriginFetch(reqFQDN string, latency time.Duration) {
+	atomic.AddUint64(s.originFetches, 1)
+	atomic.AddUint64(s.originFetchTimeNS, uint64(latency))
+	if remapRuleStats, ok := s.remap.Stats(reqFQDN); ok {
+		remapRuleStats.AddOriginFetch(latency)
+	}
+}
 
 // CacheSizeByName returns the size of tha cache for a particular cache
 func (s stats) CacheSizeByName(cName string) (uint64, bool) {
@@ -206,6 +327,21 @@ func (s stats) CacheCapacityByName(cName string) (uint64, bool) {
 
 func (s stats) CacheCapacity() uint64 { return s.cacheCapacityBytes }
 
+func (s stats) CacheRestoreProgress() (uint64, uint64, bool) {
+	restored, total, done := uint64(0), uint64(0), true
+	for _, c := range s.caches {
+		restorer, ok := c.(icache.Restorer)
+		if !ok {
+			continue
+		}
+		cRestored, cTotal, cDone := restorer.RestoreProgress()
+		restored += cRestored
+		total += cTotal
+		done = done && cDone
+	}
+	return restored, total, done
+}
+
 type StatsRemaps interface {
 	Stats(fqdn string) (StatsRemap, bool)
 	Rules() []string
@@ -229,6 +365,21 @@ type StatsRemap interface {
 	AddCacheHit()
 	CacheMisses() uint64
 	AddCacheMiss()
+
+	StaleWhileRevalidateHits() uint64
+	AddStaleWhileRevalidateHit()
+	StaleIfErrorHits() uint64
+	AddStaleIfErrorHit()
+
+	CollapsedHits() uint64
+	AddCollapsedHit()
+	CollapsedWaiters() int64
+	AddCollapsedWaiters(int64)
+	CollapseFallbacks() uint64
+	AddCollapseFallback()
+	OriginFetches() uint64
+	OriginFetchTime() time.Duration
+	AddOriginFetch(time.Duration)
 }
 
 func getFromFQDN(r remapdata.RemapRule) string {
@@ -281,6 +432,15 @@ type statsRemap struct {
 	status5xx   uint64
 	cacheHits   uint64
 	cacheMisses uint64
+
+	staleWhileRevalidateHits uint64
+	staleIfErrorHits         uint64
+
+	collapsedHits     uint64
+	collapsedWaiters  int64
+	collapseFallbacks uint64
+	originFetches     uint64
+	originFetchTimeNS uint64
 }
 
 func (r *statsRemap) InBytes() uint64       { return atomic.LoadUint64(&r.inBytes) }
@@ -302,6 +462,29 @@ func (r *statsRemap) AddCacheHit()      { atomic.AddUint64(&r.cacheHits, 1) }
 func (r *statsRemap) CacheMisses() uint64 { return atomic.LoadUint64(&r.cacheMisses) }
 func (r *statsRemap) AddCacheMiss()       { atomic.AddUint64(&r.cacheMisses, 1) }
 
+func (r *statsRemap) StaleWhileRevalidateHits() uint64 {
+	return atomic.LoadUint64(&r.staleWhileRevalidateHits)
+}
+func (r *statsRemap) AddStaleWhileRevalidateHit() { atomic.AddUint64(&r.staleWhileRevalidateHits, 1) }
+
+func (r *statsRemap) StaleIfErrorHits() uint64 { return atomic.LoadUint64(&r.staleIfErrorHits) }
+func (r *statsRemap) AddStaleIfErrorHit()      { atomic.AddUint64(&r.staleIfErrorHits, 1) }
+
+func (r *statsRemap) CollapsedHits() uint64           { return atomic.LoadUint64(&r.collapsedHits) }
+func (r *statsRemap) AddCollapsedHit()                { atomic.AddUint64(&r.collapsedHits, 1) }
+func (r *statsRemap) CollapsedWaiters() int64         { return atomic.LoadInt64(&r.collapsedWaiters) }
+func (r *statsRemap) AddCollapsedWaiters(delta int64) { atomic.AddInt64(&r.collapsedWaiters, delta) }
+func (r *statsRemap) CollapseFallbacks() uint64       { return atomic.LoadUint64(&r.collapseFallbacks) }
+func (r *statsRemap) AddCollapseFallback()            { atomic.AddUint64(&r.collapseFallbacks, 1) }
+func (r *statsRemap) OriginFetches() uint64           { return atomic.LoadUint64(&r.originFetches) }
+func (r *statsRemap) OriginFetchTime() time.Duration {
+	return time.Duration(atomic.LoadUint64(&r.originFetchTimeNS))
+}
+func (r *statsRemap) AddOriginFetch(latency time.Duration) {
+	atomic.AddUint64(&r.originFetches, 1)
+	atomic.AddUint64(&r.originFetchTimeNS, uint64(latency))
+}
+
 func NewStatsSystem(version string) StatsSystem {
 	return &statsSystem{version: version}
 }
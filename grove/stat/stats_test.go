@@ -84,7 +84,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		expected := 10
 		StatsInc(httpConns, expected, &addrs)
 		if actual := stats.Connections(); actual != uint64(expected) {
@@ -96,7 +96,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		expected := 10
 		StatsInc(httpsConns, expected, &addrs)
 		if actual := stats.Connections(); actual != uint64(expected) {
@@ -108,7 +108,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		expected := 10
 		StatsInc(httpConns, expected, &addrs)
 		StatsInc(httpsConns, expected, &addrs)
@@ -122,7 +122,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		count := 10
 		StatsInc(httpConns, count, &addrs)
 		StatsDec(httpConns, count, &addrs)
@@ -135,7 +135,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		count := 10
 		StatsInc(httpsConns, count, &addrs)
 		StatsDec(httpsConns, count, &addrs)
@@ -148,7 +148,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		count := 10
 		StatsInc(httpConns, count, &addrs)
 		StatsInc(httpsConns, count, &addrs)
@@ -163,7 +163,7 @@ func TestStatsCount(t *testing.T) {
 		httpsConns := web.NewConnMap()
 		addrs := []string{}
 		r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo"}}
-		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, "fakeversion")
+		stats := New([]remapdata.RemapRule{r}, nil, 0, httpConns, httpsConns, nil, "fakeversion")
 		count := 10
 		StatsInc(httpConns, count, &addrs)
 		StatsDec(httpConns, 1, &addrs)
@@ -173,3 +173,48 @@ func TestStatsCount(t *testing.T) {
 	}
 
 }
+
+func TestStatsStaleHits(t *testing.T) {
+	r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo", From: "http://foo.example.net"}}
+	stats := New([]remapdata.RemapRule{r}, nil, 0, web.NewConnMap(), web.NewConnMap(), nil, "fakeversion")
+
+	stats.AddStaleWhileRevalidateHit("foo.example.net")
+	stats.AddStaleWhileRevalidateHit("foo.example.net")
+	stats.AddStaleIfErrorHit("foo.example.net")
+	stats.AddStaleIfErrorHit("nonexistent.example.net") // counted globally, but not in any rule
+
+	if actual := stats.StaleWhileRevalidateHits(); actual != 2 {
+		t.Errorf("Stats.StaleWhileRevalidateHits() expected %v actual %v", 2, actual)
+	}
+	if actual := stats.StaleIfErrorHits(); actual != 2 {
+		t.Errorf("Stats.StaleIfErrorHits() expected %v actual %v", 2, actual)
+	}
+
+	remapStats, ok := stats.Remap().Stats("foo.example.net")
+	if !ok {
+		t.Fatalf("Stats.Remap().Stats expected rule 'foo.example.net', actual not found")
+	}
+	if actual := remapStats.StaleWhileRevalidateHits(); actual != 2 {
+		t.Errorf("StatsRemap.StaleWhileRevalidateHits() expected %v actual %v", 2, actual)
+	}
+	if actual := remapStats.StaleIfErrorHits(); actual != 1 {
+		t.Errorf("StatsRemap.StaleIfErrorHits() expected %v actual %v", 1, actual)
+	}
+}
+
+func TestStatsHTTP3(t *testing.T) {
+	r := remapdata.RemapRule{RemapRuleBase: remapdata.RemapRuleBase{Name: "foo", From: "https://foo.example.net"}}
+	stats := New([]remapdata.RemapRule{r}, nil, 0, web.NewConnMap(), web.NewConnMap(), web.NewHTTP3Conns(), "fakeversion")
+
+	stats.AddHTTP3Request()
+	stats.AddHTTP3Request()
+	if actual := stats.HTTP3Requests(); actual != 2 {
+		t.Errorf("Stats.HTTP3Requests() expected %v actual %v", 2, actual)
+	}
+	if actual := stats.HTTP3Connections(); actual != 0 {
+		t.Errorf("Stats.HTTP3Connections() expected %v actual %v", 0, actual)
+	}
+	if actual := stats.Connections(); actual != 0 {
+		t.Errorf("Stats.Connections() expected %v actual %v", 0, actual)
+	}
+}
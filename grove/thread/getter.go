@@ -16,12 +16,34 @@ package thread
 
 import (
 	"sync"
+	"time"
 
 	cacheobj "github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/remapdata"
 )
 
 type Getter interface {
-	Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, reqID uint64) (*cacheobj.CacheObj, uint64)
+	Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, reqID uint64, opts GetOpts) (*cacheobj.CacheObj, uint64)
+}
+
+// GetOpts are the options of a single Get.
+type GetOpts struct {
+	// Collapse is how the Get is collapsed with concurrent Gets of the same key.
+	Collapse remapdata.CollapsedForwarding
+	// StatsKey is the remap rule FQDN the Get's stats are recorded to.
+	StatsKey string
+}
+
+// GetterStats records how a Getter collapses requests. It's fulfilled by stat.Stats.
+type GetterStats interface {
+	// AddCollapsedHit records a request served by another request's parent fetch.
+	AddCollapsedHit(reqFQDN string)
+	// AddCollapsedWaiters adds delta to the number of requests waiting on another request's parent fetch.
+	AddCollapsedWaiters(reqFQDN string, delta int64)
+	// AddCollapseFallback records a request which couldn't be collapsed, and made its own parent fetch.
+	AddCollapseFallback(reqFQDN string)
+	// AddOriginFetch records a parent fetch, and how long it took.
+	AddOriginFetch(reqFQDN string, latency time.Duration)
 }
 
 type GetterResp struct {
@@ -29,8 +51,8 @@ type GetterResp struct {
 	GetReqID uint64
 }
 
-func NewGetter() Getter {
-	return &getter{waiters: map[string][]chan GetterResp{}}
+func NewGetter(stats GetterStats) Getter {
+	return &getter{waiters: map[string][]chan GetterResp{}, stats: stats}
 }
 
 // getter implements Getter, and does a fan-in so only one real request is made to the parent at any given time, and then that object is given to all concurrent requesters.
@@ -43,29 +65,38 @@ func NewGetter() Getter {
 // If the Author response can't be used, all Waiters make their own requests.
 // Note this assumes an uncacheable response for one request is likely uncacheable for all, and it's faster and less load on the origin if so.
 // If it's likely the author request is uncacheable, but a different waiter is cacheable for all other waiters, this will be more network, more origin load, and more work. If that's the case for you, consider creating another type that fulfills the Getter interface, and making the Getter configurable.
+//
+// Collapsing is limited by the GetOpts of each request. If collapsing is disabled, the request doesn't become an Author or Waiter, and makes its own request. If the Author already has the maximum Waiters, or a Waiter times out, it makes its own request. A timed-out Waiter's chan is left in the waiters, which is safe because it's buffered.
 type getter struct {
 	// waiters is a map of cache keys to chans for getters.
 	waiters  map[string][]chan GetterResp
 	waitersM sync.Mutex
+	stats    GetterStats
 }
 
-func (g *getter) Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, reqID uint64) (*cacheobj.CacheObj, uint64) {
+func (g *getter) Get(key string, actualGet func() *cacheobj.CacheObj, canUse func(*cacheobj.CacheObj) bool, reqID uint64, opts GetOpts) (*cacheobj.CacheObj, uint64) {
+	if opts.Collapse.Disabled {
+		return g.fetch(actualGet, opts.StatsKey), reqID
+	}
+
 	isAuthor := false
+	isWaiter := false
 	// Buffered for performance, so the author can iterate over all wait chans without blocking.
 	// Note this is unused if isAuthor becomes true.
 	getChan := make(chan GetterResp, 1)
 
 	g.waitersM.Lock()
-	if _, ok := g.waiters[key]; !ok {
+	if waiters, ok := g.waiters[key]; !ok {
 		isAuthor = true
 		g.waiters[key] = []chan GetterResp{}
-	} else {
-		g.waiters[key] = append(g.waiters[key], getChan)
+	} else if opts.Collapse.MaxWaiters == 0 || len(waiters) < opts.Collapse.MaxWaiters {
+		isWaiter = true
+		g.waiters[key] = append(waiters, getChan)
 	}
 	g.waitersM.Unlock()
 
 	if isAuthor {
-		obj := actualGet()
+		obj := g.fetch(actualGet, opts.StatsKey)
 		waitResp := GetterResp{CacheObj: obj, GetReqID: reqID}
 
 		g.waitersM.Lock()
@@ -78,10 +109,40 @@ func (g *getter) Get(key string, actualGet func() *cacheobj.CacheObj, canUse fun
 		return obj, reqID
 	}
 
-	if waitResp := <-getChan; canUse(waitResp.CacheObj) {
-		return waitResp.CacheObj, waitResp.GetReqID
+	if isWaiter {
+		g.stats.AddCollapsedWaiters(opts.StatsKey, 1)
+		waitResp, ok := wait(getChan, opts.Collapse.WaitTimeout)
+		g.stats.AddCollapsedWaiters(opts.StatsKey, -1)
+		if ok && canUse(waitResp.CacheObj) {
+			g.stats.AddCollapsedHit(opts.StatsKey)
+			return waitResp.CacheObj, waitResp.GetReqID
+		}
 	}
 
-	// if the Author response can't be used, all Waiters make their own requests
-	return actualGet(), reqID
+	// if the Author response can't be used, or this request couldn't wait for it, make our own request
+	g.stats.AddCollapseFallback(opts.StatsKey)
+	return g.fetch(actualGet, opts.StatsKey), reqID
+}
+
+// fetch calls actualGet, recording it as an origin fetch.
+func (g *getter) fetch(actualGet func() *cacheobj.CacheObj, statsKey string) *cacheobj.CacheObj {
+	start := time.Now()
+	obj := actualGet()
+	g.stats.AddOriginFetch(statsKey, time.Since(start))
+	return obj
+}
+
+// wait waits for the response on getChan, up to timeout, or indefinitely if timeout is 0. Returns the response, and whether one was received.
+func wait(getChan <-chan GetterResp, timeout time.Duration) (GetterResp, bool) {
+	if timeout <= 0 {
+		return <-getChan, true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case waitResp := <-getChan:
+		return waitResp, true
+	case <-timer.C:
+		return GetterResp{}, false
+	}
 }
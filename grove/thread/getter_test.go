@@ -0,0 +1,115 @@
+package thread
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/grove/cacheobj"
+	"github.com/apache/trafficcontrol/grove/remapdata"
+)
+
+type fakeGetterStats struct {
+	collapsedHits     int64
+	collapsedWaiters  int64
+	collapseFallbacks int64
+	originFetches     int64
+}
+
+func (s *fakeGetterStats) AddCollapsedHit(string) { atomic.AddInt64(&s.collapsedHits, 1) }
+func (s *fakeGetterStats) AddCollapsedWaiters(_ string, delta int64) {
+	atomic.AddInt64(&s.collapsedWaiters, delta)
+}
+func (s *fakeGetterStats) AddCollapseFallback(string) { atomic.AddInt64(&s.collapseFallbacks, 1) }
+func (s *fakeGetterStats) AddOriginFetch(string, time.Duration) {
+	atomic.AddInt64(&s.originFetches, 1)
+}
+
+// getConcurrently makes n concurrent Gets of the same key, whose parent fetches block until release is closed, and returns once all have finished.
+func getConcurrently(g Getter, n int, opts GetOpts, release chan struct{}) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(reqID uint64) {
+			defer wg.Done()
+			actualGet := func() *cacheobj.CacheObj {
+				<-release
+				return &cacheobj.CacheObj{Code: 200}
+			}
+			canUse := func(*cacheobj.CacheObj) bool { return true }
+			g.Get("GET:http://origin.example.net/foo", actualGet, canUse, reqID, opts)
+		}(uint64(i))
+	}
+	// give the Gets time to become the Author and Waiters, before the parent responds
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+}
+
+func TestGetterCollapses(t *testing.T) {
+	stats := &fakeGetterStats{}
+	getConcurrently(NewGetter(stats), 10, GetOpts{}, make(chan struct{}))
+	if stats.originFetches != 1 {
+		t.Errorf("expected 1 origin fetch, actual %v", stats.originFetches)
+	}
+	if stats.collapsedHits != 9 {
+		t.Errorf("expected 9 collapsed hits, actual %v", stats.collapsedHits)
+	}
+	if stats.collapsedWaiters != 0 {
+		t.Errorf("expected 0 collapsed waiters after all Gets finished, actual %v", stats.collapsedWaiters)
+	}
+}
+
+func TestGetterCollapseDisabled(t *testing.T) {
+	stats := &fakeGetterStats{}
+	getConcurrently(NewGetter(stats), 10, GetOpts{Collapse: remapdata.CollapsedForwarding{Disabled: true}}, make(chan struct{}))
+	if stats.originFetches != 10 {
+		t.Errorf("expected 10 origin fetches, actual %v", stats.originFetches)
+	}
+	if stats.collapsedHits != 0 || stats.collapseFallbacks != 0 {
+		t.Errorf("expected no collapsed hits or fallbacks, actual %v hits %v fallbacks", stats.collapsedHits, stats.collapseFallbacks)
+	}
+}
+
+func TestGetterMaxWaiters(t *testing.T) {
+	stats := &fakeGetterStats{}
+	getConcurrently(NewGetter(stats), 10, GetOpts{Collapse: remapdata.CollapsedForwarding{MaxWaiters: 3}}, make(chan struct{}))
+	if stats.collapsedHits != 3 {
+		t.Errorf("expected 3 collapsed hits, actual %v", stats.collapsedHits)
+	}
+	if stats.collapseFallbacks != 6 {
+		t.Errorf("expected 6 collapse fallbacks, actual %v", stats.collapseFallbacks)
+	}
+	if stats.originFetches != 7 {
+		t.Errorf("expected 7 origin fetches, actual %v", stats.originFetches)
+	}
+}
+
+func TestGetterWaitTimeout(t *testing.T) {
+	stats := &fakeGetterStats{}
+	getConcurrently(NewGetter(stats), 10, GetOpts{Collapse: remapdata.CollapsedForwarding{WaitTimeout: time.Millisecond}}, make(chan struct{}))
+	if stats.collapsedHits != 0 {
+		t.Errorf("expected 0 collapsed hits, actual %v", stats.collapsedHits)
+	}
+	if stats.collapseFallbacks != 9 {
+		t.Errorf("expected 9 collapse fallbacks, actual %v", stats.collapseFallbacks)
+	}
+	if stats.originFetches != 10 {
+		t.Errorf("expected 10 origin fetches, actual %v", stats.originFetches)
+	}
+}
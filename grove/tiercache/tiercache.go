@@ -37,11 +37,16 @@ func New(first, second icache.Cache) *TierCache {
 }
 
 // Get returns the object if it's in the first cache. Else, it returns the object from the second cache. Else, false.
+// If the object is in the first cache and the second cache is an icache.Toucher, it is touched in the second cache, so objects served from the first cache stay recently-used in the second.
 func (c *TierCache) Get(key string) (*cacheobj.CacheObj, bool) {
 	log.Debugln("TierCache.Get '" + key + "' calling")
 	v, ok := c.first.Get(key)
 	log.Debugf("TierCache.Get '"+key+"' FOUND FIRST: %+v\n", ok)
-	if !ok {
+	if ok {
+		if toucher, isToucher := c.second.(icache.Toucher); isToucher {
+			toucher.Touch(key)
+		}
+	} else {
 		v, ok = c.second.Get(key)
 		if ok {
 			// if it was in second but not first, add back to first (LRU behavior)
@@ -69,6 +74,13 @@ func (c *TierCache) Add(key string, val *cacheobj.CacheObj) bool {
 	return aevict || bevict
 }
 
+// Remove removes the key from both internal caches. Returns whether either contained it.
+func (c *TierCache) Remove(key string) bool {
+	aok := c.first.Remove(key)
+	bok := c.second.Remove(key)
+	return aok || bok
+}
+
 // Size returns the size of the second cache. This is because, since all objects are added to both, they are presumed to have the same content, and the second is presumed to be larger.
 //
 // For example, if the first is a memory cache and the second is a disk cache, it's most useful to report the size used on disk.
@@ -88,3 +100,11 @@ func (c *TierCache) Keys() []string {
 
 // Capacity returns the maximum size in bytes of the cache
 func (c *TierCache) Capacity() uint64 { return c.second.Capacity() }
+
+// RestoreProgress returns the RestoreProgress of the second cache, if it's an icache.Restorer. Otherwise, there is nothing to restore, and it returns 0, 0, true.
+func (c *TierCache) RestoreProgress() (uint64, uint64, bool) {
+	if restorer, ok := c.second.(icache.Restorer); ok {
+		return restorer.RestoreProgress()
+	}
+	return 0, 0, true
+}
@@ -0,0 +1,88 @@
+package web
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTP3Conns counts the open QUIC connections of an HTTP/3 server. QUIC connections aren't net.Conns, so they can't be tracked in a ConnMap.
+type HTTP3Conns struct {
+	conns int64 // atomic: MUST NOT access without sync.atomic
+}
+
+func NewHTTP3Conns() *HTTP3Conns {
+	return &HTTP3Conns{}
+}
+
+// ConnContext counts the given connection until it's closed. It must be set as the ConnContext of the http3.Server whose connections are counted.
+func (c *HTTP3Conns) ConnContext(ctx context.Context, conn quic.Connection) context.Context {
+	atomic.AddInt64(&c.conns, 1)
+	go func() {
+		<-conn.Context().Done()
+		atomic.AddInt64(&c.conns, -1)
+	}()
+	return ctx
+}
+
+// Len returns the number of open connections. It's safe to call on a nil HTTP3Conns, which has no connections.
+func (c *HTTP3Conns) Len() int {
+	if c == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&c.conns))
+}
+
+// ListenHTTP3 creates the UDP socket to serve HTTP/3 on, and returns it with the tls.Config to serve it with, which uses the given certificates.
+func ListenHTTP3(laddr string, certs []tls.Certificate) (net.PacketConn, *tls.Config, error) {
+	config := &tls.Config{}
+	config.Certificates = certs
+	config.BuildNameToCertificate()
+	conn, err := net.ListenPacket("udp", laddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, http3.ConfigureTLSConfig(config), nil
+}
+
+// NewHTTP3Server returns an http3.Server serving the given handler, with its connections counted by conns. The server must be started with Serve, passing a socket from ListenHTTP3.
+func NewHTTP3Server(handler http.Handler, tlsConfig *tls.Config, port int, idleTimeout time.Duration, conns *HTTP3Conns) *http3.Server {
+	return &http3.Server{
+		Handler:     handler,
+		TLSConfig:   tlsConfig,
+		Port:        port,
+		IdleTimeout: idleTimeout,
+		QUICConfig:  &quic.Config{MaxIdleTimeout: idleTimeout},
+		ConnContext: conns.ConnContext,
+	}
+}
+
+// AltSvcHandler wraps the given handler, advertising HTTP/3 on the given UDP port to clients with an Alt-Svc header, per RFC 7838, valid for maxAge.
+func AltSvcHandler(handler http.Handler, port int, maxAge time.Duration) http.Handler {
+	altSvc := fmt.Sprintf(`h3=":%d"; ma=%d`, port, int(maxAge/time.Second))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Alt-Svc", altSvc)
+		handler.ServeHTTP(w, r)
+	})
+}
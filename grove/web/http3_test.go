@@ -0,0 +1,43 @@
+package web
+
+/*
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+   http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAltSvcHandler(t *testing.T) {
+	h := AltSvcHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), 8443, 24*time.Hour)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.net/", nil))
+	if expected, actual := `h3=":8443"; ma=86400`, w.Header().Get("Alt-Svc"); expected != actual {
+		t.Errorf("expected Alt-Svc '%v', actual '%v'", expected, actual)
+	}
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected the wrapped handler's code %v, actual %v", http.StatusNoContent, w.Code)
+	}
+}
+
+func TestHTTP3ConnsNil(t *testing.T) {
+	if l := (*HTTP3Conns)(nil).Len(); l != 0 {
+		t.Errorf("expected nil HTTP3Conns Len 0, actual %v", l)
+	}
+}
@@ -0,0 +1,107 @@
+package cfgfile
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+	"github.com/apache/trafficcontrol/lib/go-log"
+)
+
+// GetAllConfigs gets all config files for toData.Server.
+func GetAllConfigs(
+	toData *ConfigData,
+	cfg Cfg,
+) ([]ATSConfigFile, error) {
+	if toData.Server.HostName == nil {
+		return nil, errors.New("server hostname is nil")
+	}
+
+	configFiles, warnings, err := MakeConfigFilesList(toData, cfg.Dir, cfg.ATSMajorVersion)
+	logWarnings("generating config files list: ", warnings)
+	if err != nil {
+		return nil, errors.New("creating meta: " + err.Error())
+	}
+
+	genTime := time.Now()
+	hdrCommentTxt := makeHeaderComment(*toData.Server.HostName, cfg.AppVersion, toData.TrafficOpsURL, toData.TrafficOpsAddresses, genTime)
+
+	hasSSLMultiCertConfig := false
+	configs := []ATSConfigFile{}
+	for _, fi := range configFiles {
+		if cfg.RevalOnly && fi.Name != atscfg.RegexRevalidateFileName {
+			continue
+		}
+		atsCfg, err := GetConfigFile(toData, fi, hdrCommentTxt, cfg)
+		if err != nil {
+			return nil, errors.New("getting config file '" + fi.Name + "': " + err.Error())
+		}
+		if fi.Name == atscfg.SSLMultiCertConfigFileName {
+			hasSSLMultiCertConfig = true
+		}
+		configs = append(configs, ATSConfigFile{
+			Name:        fi.Name,
+			Path:        fi.Path,
+			Text:        atsCfg.Text,
+			Secure:      atsCfg.Secure,
+			ContentType: atsCfg.ContentType,
+			LineComment: atsCfg.LineComment,
+			Warnings:    atsCfg.Warnings,
+			Provenance:  atsCfg.Provenance,
+		})
+	}
+
+	if hasSSLMultiCertConfig {
+		sslConfigs, err := GetSSLCertsAndKeyFiles(toData)
+		if err != nil {
+			return nil, errors.New("getting ssl key and cert config files: " + err.Error())
+		}
+		configs = append(configs, sslConfigs...)
+	}
+
+	return configs, nil
+}
+
+func makeHeaderComment(serverHostName string, appVersion string, toURL string, toIPs []string, genTime time.Time) string {
+	return fmt.Sprintf(
+		`DO NOT EDIT - Generated for %v by %v from %v ips %v on %v`,
+		serverHostName,
+		appVersion,
+		toURL,
+		makeIPStr(toIPs),
+		genTime.UTC().Format(time.RFC3339Nano),
+	)
+}
+
+func makeIPStr(ips []string) string {
+	return `(` + strings.Join(ips, `,`) + `)`
+}
+
+// logWarnings writes all strings in warnings to the warning log, with the context prefix.
+// If warnings is empty, no log is written.
+func logWarnings(context string, warnings []string) {
+	for _, warn := range warnings {
+		log.Warnln(context + warn)
+	}
+}
@@ -0,0 +1,192 @@
+// Package cfgfile generates all of the config files of a cache server from
+// the Traffic Ops data t3c requests, as t3c-generate does.
+package cfgfile
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// Cfg is the options with which config files are generated.
+type Cfg struct {
+	RevalOnly          bool
+	Provenance         bool
+	Dir                string
+	UseStrategies      UseStrategiesFlag
+	ViaRelease         bool
+	SetDNSLocalBind    bool
+	NoOutgoingIP       bool
+	ATSMajorVersion    uint
+	ParentComments     bool
+	DefaultEnableH2    bool
+	DefaultTLSVersions []atscfg.TLSVersion
+	// AppVersion is the name and version of the application generating the
+	// files, which is written in their header comments.
+	AppVersion string
+}
+
+// UseStrategiesFlag is whether to generate strategies.yaml, and whether to use
+// it instead of parent.config.
+type UseStrategiesFlag string
+
+const (
+	UseStrategiesFlagTrue    UseStrategiesFlag = "true"
+	UseStrategiesFlagCore    UseStrategiesFlag = "core"
+	UseStrategiesFlagFalse   UseStrategiesFlag = "false"
+	UseStrategiesFlagInvalid UseStrategiesFlag = ""
+)
+
+func (fl UseStrategiesFlag) String() string { return string(fl) }
+
+// ATSConfigFile is a generated config file.
+type ATSConfigFile struct {
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	ContentType string   `json:"content_type"`
+	LineComment string   `json:"line_comment"`
+	Secure      bool     `json:"secure"`
+	Text        string   `json:"text"`
+	Warnings    []string `json:"warnings"`
+	// Provenance is the Traffic Ops objects each line was generated from.
+	// It's only generated on request, and omitted for files which don't support it.
+	Provenance []atscfg.LineProvenance `json:"provenance,omitempty"`
+}
+
+// ATSConfigFiles implements sort.Interface and sorts by the Location and then FileNameOnDisk, i.e. the full file path.
+type ATSConfigFiles []ATSConfigFile
+
+func (fs ATSConfigFiles) Len() int { return len(fs) }
+func (fs ATSConfigFiles) Less(i, j int) bool {
+	if fs[i].Path != fs[j].Path {
+		return fs[i].Path < fs[j].Path
+	}
+	return fs[i].Name < fs[j].Name
+}
+func (fs ATSConfigFiles) Swap(i, j int) { fs[i], fs[j] = fs[j], fs[i] }
+
+// ConfigData is the Traffic Ops data from which the config files of a cache
+// server are generated.
+type ConfigData struct {
+	// Version is the version of the application which created the config data,
+	// primarily used for cache invalidation.
+	Version string `json:"version"`
+
+	// Servers must be all the servers from Traffic Ops. May include servers not on the current cdn.
+	Servers []atscfg.Server `json:"servers,omitempty"`
+
+	// CacheGroups must be all cachegroups in Traffic Ops with Servers on the current server's cdn. May also include CacheGroups without servers on the current cdn.
+	CacheGroups []tc.CacheGroupNullable `json:"cache_groups,omitempty"`
+
+	// GlobalParams must be all Parameters in Traffic Ops on the tc.GlobalProfileName Profile. Must not include other parameters.
+	GlobalParams []tc.Parameter `json:"global_parameters,omitempty"`
+
+	// ServerProfilesParams must be all Parameters on the Profiles of the current server. Must not include other Parameters.
+	ServerProfilesParams map[atscfg.ProfileName][]tc.Parameter `json:"server_profiles_parameters,omitempty"`
+
+	// ServerParams is constructed from Server and ServerParams. Must not include other Parameters.
+	// It's ok for other apps using this data to serialize and deserialize this to pass it around,
+	// but t3c-request must always use ServerProfilesParams to re-populate this, and do If-Modified-Since requests from that.
+	// This must never be used in an If-Modified-Since check, or populated wholesale from a single profile's endpoint.
+	ServerParams []tc.Parameter `json:"server_params,omitempty"`
+
+	// CacheKeyConfigParams must be all Parameters with the "cachekey.config" (compat)
+	CacheKeyConfigParams []tc.Parameter `json:"cachekey_config_parameters,omitempty"`
+
+	// RemapConfigParams must be all Parameters with the ConfigFile "remap.config"
+	RemapConfigParams []tc.Parameter `json:"remap_config_parameters,omitempty"`
+
+	// ParentConfigParams must be all Parameters with the ConfigFile "parent.config.
+	ParentConfigParams []tc.Parameter `json:"parent_config_parameters,omitempty"`
+
+	// DeliveryServices must include all Delivery Services on the current server's cdn, including those not assigned to the server. Must not include delivery services on other cdns.
+	DeliveryServices []atscfg.DeliveryService `json:"delivery_services,omitempty"`
+
+	// DeliveryServiceServers must include all delivery service servers in Traffic Ops for all delivery services on the current cdn, including those not assigned to the current server.
+	DeliveryServiceServers []atscfg.DeliveryServiceServer `json:"delivery_service_servers,omitempty"`
+
+	// Server must be the server we're fetching configs from
+	Server *atscfg.Server `json:"server,omitempty"`
+
+	// Jobs must be all Jobs on the server's CDN. May include jobs on other CDNs.
+	Jobs []atscfg.InvalidationJob `json:"jobs,omitempty"`
+
+	// CDN must be the CDN of the server.
+	CDN *tc.CDN `json:"cdn,omitempty"`
+
+	// DeliveryServiceRegexes must be all regexes on all delivery services on this server's cdn.
+	DeliveryServiceRegexes []tc.DeliveryServiceRegexes `json:"delivery_service_regexes,omitempty"`
+
+	// URISigningKeys must be a map of every delivery service which is URI Signed, to its keys.
+	URISigningKeys map[tc.DeliveryServiceName][]byte `json:"uri_signing_keys,omitempty"`
+
+	// URLSigKeys must be a map of every delivery service which uses URL Sig, to its keys.
+	URLSigKeys map[tc.DeliveryServiceName]tc.URLSigKeys `json:"url_sig_keys,omitempty"`
+
+	// ServerCapabilities must be a map of all server IDs on this server's CDN, to a set of their capabilities. May also include servers from other cdns.
+	ServerCapabilities map[int]map[atscfg.ServerCapability]struct{} `json:"server_capabilities,omitempty"`
+
+	// DSRequiredCapabilities must be a map of all delivery service IDs on this server's CDN, to a set of their required capabilities. Delivery Services with no required capabilities may not have an entry in the map.
+	DSRequiredCapabilities map[int]map[atscfg.ServerCapability]struct{} `json:"delivery_service_required_capabilities,omitempty"`
+
+	// SSLKeys must be all the ssl keys for the server's cdn.
+	SSLKeys []tc.CDNSSLKeys `json:"ssl_keys,omitempty"`
+
+	// Topologies must be all the topologies for the server's cdn.
+	// May incude topologies of other cdns.
+	Topologies []tc.Topology `json:"topologies,omitempty"`
+
+	// TrafficOpsAddresses is the list of IP addresses used to request data. Because of proxies and load balancers,
+	// multiple addresses may be used for the multiple requests necessary to fetch all data.
+	TrafficOpsAddresses []string `json:"traffic_ops_addresses,omitempty"`
+	TrafficOpsURL       string   `json:"traffic_ops_url,omitempty"`
+
+	MetaData ConfigDataMetaData `json:"metadata"`
+}
+
+type ConfigDataMetaData struct {
+	CacheHostName          string                                 `json:"cache_host_name"`
+	Servers                ReqMetaData                            `json:"servers"`
+	CacheGroups            ReqMetaData                            `json:"cache_groups"`
+	GlobalParams           ReqMetaData                            `json:"global_parameters"`
+	ServerProfilesParams   map[atscfg.ProfileName]ReqMetaData     `json:"server_profiles_parameters"`
+	CacheKeyConfigParams   ReqMetaData                            `json:"cachekey_config_parameters"`
+	RemapConfigParams      ReqMetaData                            `json:"remap_config_parameters"`
+	ParentConfigParams     ReqMetaData                            `json:"parent_config_parameters"`
+	DeliveryServices       ReqMetaData                            `json:"delivery_services"`
+	DeliveryServiceServers ReqMetaData                            `json:"delivery_service_servers"`
+	Jobs                   ReqMetaData                            `json:"jobs"`
+	CDN                    ReqMetaData                            `json:"cdn"`
+	DeliveryServiceRegexes ReqMetaData                            `json:"delivery_service_regexes"`
+	URISigningKeys         map[tc.DeliveryServiceName]ReqMetaData `json:"uri_signing_keys"`
+	URLSigKeys             map[tc.DeliveryServiceName]ReqMetaData `json:"url_sig_keys"`
+	ServerCapabilities     ReqMetaData                            `json:"server_capabilities"`
+	DSRequiredCapabilities ReqMetaData                            `json:"delivery_service_required_capabilities"`
+	SSLKeys                ReqMetaData                            `json:"ssl_keys"`
+	Topologies             ReqMetaData                            `json:"topologies"`
+}
+
+// ReqMetaData has response headers for Conditional Requests.
+type ReqMetaData struct {
+	LastModified string `json:"last_modified"`
+	Date         string `json:"date"`
+	ETag         string `json:"etag"`
+}
@@ -23,16 +23,15 @@ import (
 	"strings"
 	"time"
 
-	"github.com/apache/trafficcontrol/cache-config/t3c-generate/config"
-	"github.com/apache/trafficcontrol/cache-config/t3cutil"
 	"github.com/apache/trafficcontrol/lib/go-atscfg"
 	"github.com/apache/trafficcontrol/lib/go-log"
 )
 
 // # DO NOT EDIT - Generated for odol-atsec-sea-22 by Traffic Ops (https://trafficops.comcast.net/) on Mon Oct 26 16:22:19 UTC 2020
 
-// GetConfigFile returns the text of the generated config file, the MIME Content Type of the config file, and any error.
-func GetConfigFile(toData *t3cutil.ConfigData, fileInfo atscfg.CfgMeta, hdrCommentTxt string, thiscfg config.Cfg) (string, string, bool, string, []string, error) {
+// GetConfigFile returns the generated config file, with its text, MIME Content Type, line comment, and warnings, and any error.
+// The config file's line provenance is omitted unless thiscfg.Provenance is set.
+func GetConfigFile(toData *ConfigData, fileInfo atscfg.CfgMeta, hdrCommentTxt string, thiscfg Cfg) (atscfg.Cfg, error) {
 	start := time.Now()
 	defer func() {
 		log.Infof("GetConfigFile %v took %v\n", fileInfo.Name, time.Since(start).Round(time.Millisecond))
@@ -44,12 +43,15 @@ func GetConfigFile(toData *t3cutil.ConfigData, fileInfo atscfg.CfgMeta, hdrComme
 	logWarnings("getting config file '"+fileInfo.Name+"': ", cfg.Warnings)
 
 	if err != nil {
-		return "", "", false, "", []string{}, err
+		return atscfg.Cfg{}, err
 	}
-	return cfg.Text, cfg.ContentType, cfg.Secure, cfg.LineComment, cfg.Warnings, nil
+	if !thiscfg.Provenance {
+		cfg.Provenance = nil
+	}
+	return cfg, nil
 }
 
-type ConfigFileFunc func(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error)
+type ConfigFileFunc func(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error)
 
 type ConfigFilePrefixSuffixFunc struct {
 	Prefix string
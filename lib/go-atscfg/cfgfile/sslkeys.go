@@ -24,18 +24,17 @@ import (
 	"encoding/base64"
 	"errors"
 
-	"github.com/apache/trafficcontrol/cache-config/t3cutil"
 	"github.com/apache/trafficcontrol/lib/go-atscfg"
 	"github.com/apache/trafficcontrol/lib/go-log"
 	"github.com/apache/trafficcontrol/lib/go-tc"
 )
 
-func GetSSLCertsAndKeyFiles(toData *t3cutil.ConfigData) ([]t3cutil.ATSConfigFile, error) {
+func GetSSLCertsAndKeyFiles(toData *ConfigData) ([]ATSConfigFile, error) {
 	dses, dsWarns := atscfg.DeliveryServicesToSSLMultiCertDSes(toData.DeliveryServices)
 	logWarnings("Getting SSL files: Making SSL MultiCert DSes: ", dsWarns)
 	dses = atscfg.GetSSLMultiCertDotConfigDeliveryServices(dses)
 
-	configs := []t3cutil.ATSConfigFile{}
+	configs := []ATSConfigFile{}
 	for _, keys := range toData.SSLKeys {
 		dsName := tc.DeliveryServiceName(keys.DeliveryService)
 		ds, ok := dses[dsName]
@@ -69,7 +68,7 @@ func GetSSLCertsAndKeyFiles(toData *t3cutil.ConfigData) ([]t3cutil.ATSConfigFile
 
 		certName, keyName := atscfg.GetSSLMultiCertDotConfigCertAndKeyName(dsName, ds)
 
-		keyFile := t3cutil.ATSConfigFile{}
+		keyFile := ATSConfigFile{}
 		keyFile.Name = keyName
 		keyFile.Path = "/opt/trafficserver/etc/trafficserver/ssl/" // TODO read config, don't hard code
 		keyFile.Text = string(key)
@@ -77,7 +76,7 @@ func GetSSLCertsAndKeyFiles(toData *t3cutil.ConfigData) ([]t3cutil.ATSConfigFile
 		keyFile.Warnings = keyPairErr
 		configs = append(configs, keyFile)
 
-		certFile := t3cutil.ATSConfigFile{}
+		certFile := ATSConfigFile{}
 		certFile.Name = certName
 		certFile.Path = "/opt/trafficserver/etc/trafficserver/ssl/" // TODO read config, don't hard code
 		certFile.Text = string(cert)
@@ -20,8 +20,6 @@ package cfgfile
  */
 
 import (
-	"github.com/apache/trafficcontrol/cache-config/t3c-generate/config"
-	"github.com/apache/trafficcontrol/cache-config/t3cutil"
 	"github.com/apache/trafficcontrol/lib/go-atscfg"
 	"github.com/apache/trafficcontrol/lib/go-tc"
 )
@@ -35,7 +33,7 @@ import (
 // The atsMajorVersion may be 0 to default to the Server Package Parameter.
 //
 // MakeConfigFilesList returns the list of config files, any warnings, and any error.
-func MakeConfigFilesList(toData *t3cutil.ConfigData, dir string, atsMajorVersion uint) ([]atscfg.CfgMeta, []string, error) {
+func MakeConfigFilesList(toData *ConfigData, dir string, atsMajorVersion uint) ([]atscfg.CfgMeta, []string, error) {
 	configFiles, warnings, err := atscfg.MakeConfigFilesList(
 		dir,
 		toData.Server,
@@ -52,46 +50,46 @@ func MakeConfigFilesList(toData *t3cutil.ConfigData, dir string, atsMajorVersion
 	return configFiles, warnings, err
 }
 
-func Make12MFacts(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func Make12MFacts(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.Config12MFactsOpts{HdrComment: hdrCommentTxt}
 	return atscfg.Make12MFacts(toData.Server, opts)
 }
 
-func MakeATSDotRules(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeATSDotRules(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.ATSDotRulesOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeATSDotRules(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeAstatsDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeAstatsDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.AStatsDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeAStatsDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeBGFetchDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeBGFetchDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.BGFetchDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeBGFetchDotConfig(toData.Server, opts)
 }
 
-func MakeCacheDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeCacheDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.CacheDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeCacheDotConfig(toData.Server, toData.Servers, toData.DeliveryServices, toData.DeliveryServiceServers, opts)
 }
 
-func MakeChkconfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeChkconfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeChkconfig(toData.ServerParams, nil)
 }
 
-func MakeDropQStringDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeDropQStringDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.DropQStringDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeDropQStringDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeHostingDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeHostingDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.HostingDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeHostingDotConfig(toData.Server, toData.Servers, toData.ServerParams, toData.DeliveryServices, toData.DeliveryServiceServers, toData.Topologies, opts)
 }
 
-func MakeIPAllowDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeIPAllowDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.IPAllowDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeIPAllowDotConfig(
 		toData.ServerParams,
@@ -103,7 +101,7 @@ func MakeIPAllowDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommen
 	)
 }
 
-func MakeIPAllowDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeIPAllowDotYAML(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.IPAllowDotYAMLOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeIPAllowDotYAML(
 		toData.ServerParams,
@@ -115,12 +113,12 @@ func MakeIPAllowDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentT
 	)
 }
 
-func MakeLoggingDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeLoggingDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.LoggingDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeLoggingDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeLoggingDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeLoggingDotYAML(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeLoggingDotYAML(
 		toData.Server,
 		toData.ServerParams,
@@ -131,7 +129,7 @@ func MakeLoggingDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentT
 	)
 }
 
-func MakeSSLServerNameYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeSSLServerNameYAML(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeSSLServerNameYAML(
 		toData.Server,
 		toData.DeliveryServices,
@@ -152,7 +150,7 @@ func MakeSSLServerNameYAML(toData *t3cutil.ConfigData, fileName string, hdrComme
 	)
 }
 
-func MakeSNIDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeSNIDotYAML(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeSNIDotYAML(
 		toData.Server,
 		toData.DeliveryServices,
@@ -173,16 +171,16 @@ func MakeSNIDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt s
 	)
 }
 
-func MakeLogsXMLDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeLogsXMLDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.LogsXMLDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeLogsXMLDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakePackages(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakePackages(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakePackages(toData.ServerParams, nil)
 }
 
-func MakeParentDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeParentDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeParentDotConfig(
 		toData.DeliveryServices,
 		toData.Server,
@@ -203,12 +201,12 @@ func MakeParentDotConfig(toData *t3cutil.ConfigData, fileName string, hdrComment
 	)
 }
 
-func MakePluginDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakePluginDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.PluginDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakePluginDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeRecordsDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeRecordsDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeRecordsDotConfig(
 		toData.Server,
 		toData.ServerParams,
@@ -221,12 +219,12 @@ func MakeRecordsDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommen
 	)
 }
 
-func MakeRegexRevalidateDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeRegexRevalidateDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.RegexRevalidateDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeRegexRevalidateDotConfig(toData.Server, toData.DeliveryServices, toData.GlobalParams, toData.Jobs, opts)
 }
 
-func MakeRemapDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeRemapDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	remapAndCacheKeyParams := []tc.Parameter{}
 	remapAndCacheKeyParams = append(remapAndCacheKeyParams, toData.RemapConfigParams...)
 	remapAndCacheKeyParams = append(remapAndCacheKeyParams, toData.CacheKeyConfigParams...)
@@ -246,34 +244,34 @@ func MakeRemapDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentT
 		&atscfg.RemapDotConfigOpts{
 			HdrComment:        hdrCommentTxt,
 			VerboseComments:   true,
-			UseStrategies:     cfg.UseStrategies == t3cutil.UseStrategiesFlagTrue || cfg.UseStrategies == t3cutil.UseStrategiesFlagCore,
-			UseStrategiesCore: cfg.UseStrategies == t3cutil.UseStrategiesFlagCore,
+			UseStrategies:     cfg.UseStrategies == UseStrategiesFlagTrue || cfg.UseStrategies == UseStrategiesFlagCore,
+			UseStrategiesCore: cfg.UseStrategies == UseStrategiesFlagCore,
 			ATSMajorVersion:   cfg.ATSMajorVersion,
 		},
 	)
 }
 
-func MakeSSLMultiCertDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeSSLMultiCertDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.SSLMultiCertDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeSSLMultiCertDotConfig(toData.Server, toData.DeliveryServices, opts)
 }
 
-func MakeStorageDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeStorageDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.StorageDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeStorageDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeSysCtlDotConf(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeSysCtlDotConf(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.SysCtlDotConfOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeSysCtlDotConf(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeVolumeDotConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeVolumeDotConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.VolumeDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeVolumeDotConfig(toData.Server, toData.ServerParams, opts)
 }
 
-func MakeHeaderRewrite(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeHeaderRewrite(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeHeaderRewriteDotConfig(
 		fileName,
 		toData.DeliveryServices,
@@ -292,26 +290,26 @@ func MakeHeaderRewrite(toData *t3cutil.ConfigData, fileName string, hdrCommentTx
 	)
 }
 
-func MakeRegexRemap(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeRegexRemap(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.RegexRemapDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeRegexRemapDotConfig(fileName, toData.Server, toData.DeliveryServices, opts)
 }
 
-func MakeSetDSCP(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeSetDSCP(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.SetDSCPDotConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeSetDSCPDotConfig(fileName, toData.Server, opts)
 }
 
-func MakeURLSigConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeURLSigConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.URLSigConfigOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeURLSigConfig(fileName, toData.Server, toData.ServerParams, toData.URLSigKeys, opts)
 }
 
-func MakeURISigningConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeURISigningConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeURISigningConfig(fileName, toData.URISigningKeys, nil)
 }
 
-func MakeStrategiesDotYAML(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeStrategiesDotYAML(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	return atscfg.MakeStrategiesDotYAML(
 		toData.DeliveryServices,
 		toData.Server,
@@ -332,7 +330,7 @@ func MakeStrategiesDotYAML(toData *t3cutil.ConfigData, fileName string, hdrComme
 	)
 }
 
-func MakeUnknownConfig(toData *t3cutil.ConfigData, fileName string, hdrCommentTxt string, cfg config.Cfg) (atscfg.Cfg, error) {
+func MakeUnknownConfig(toData *ConfigData, fileName string, hdrCommentTxt string, cfg Cfg) (atscfg.Cfg, error) {
 	opts := &atscfg.ServerUnknownOpts{HdrComment: hdrCommentTxt}
 	return atscfg.MakeServerUnknown(fileName, toData.Server, toData.ServerParams, opts)
 }
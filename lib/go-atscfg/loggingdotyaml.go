@@ -31,6 +31,34 @@ const LoggingYAMLFileName = "logging.yaml"
 const ContentTypeLoggingDotYAML = ContentTypeYAML
 const LineCommentLoggingDotYAML = LineCommentHash
 
+// CDNiLogFormatName is the name of the logging.yaml format of the access log
+// from which CDNi logging records (RFC 7937) are made.
+const CDNiLogFormatName = "cdni"
+
+// CDNiLogFormat is the ATS log format of the CDNi access log. Its fields are
+// separated by tabs, in the order the CDNiLogField constants give.
+const CDNiLogFormat = "%<cqtq>\t%<ttms>\t%<chi>\t%<phi>\t%<phn>\t%<cqhm>\t%<cquuh>\t%<cquuc>\t%<cqhv>\t%<pssc>\t%<pshl>\t%<pscl>\t%<crc>\t%<{User-Agent}cqh>\t%<{Referer}cqh>"
+
+// The indices of the fields of CDNiLogFormat.
+const (
+	CDNiLogFieldTimestamp = iota
+	CDNiLogFieldTimeTakenMS
+	CDNiLogFieldClientIP
+	CDNiLogFieldServerIP
+	CDNiLogFieldServerHostName
+	CDNiLogFieldMethod
+	CDNiLogFieldHost
+	CDNiLogFieldURL
+	CDNiLogFieldProtocol
+	CDNiLogFieldStatus
+	CDNiLogFieldHeaderBytes
+	CDNiLogFieldContentBytes
+	CDNiLogFieldCacheResult
+	CDNiLogFieldUserAgent
+	CDNiLogFieldReferer
+	CDNiLogFieldCount
+)
+
 // LoggingDotYAMLOpts contains settings to configure generation options.
 type LoggingDotYAMLOpts struct {
 	// HdrComment is the header comment to include at the beginning of the file.
@@ -101,6 +129,12 @@ func MakeLoggingDotYAML(
 		}
 	}
 
+	cdniLogFilename := paramData["CDNiLog.Filename"]
+	if cdniLogFilename != "" {
+		text += indentSpaces + " - name: " + CDNiLogFormatName + " \n"
+		text += indentSpaces + "   format: '" + CDNiLogFormat + "'\n"
+	}
+
 	text += indentSpaces + "filters:\n"
 	for i := 0; i < maxLogObjects; i++ {
 		logFilterField := "LogFilter"
@@ -171,6 +205,25 @@ func MakeLoggingDotYAML(
 		}
 	}
 
+	if cdniLogFilename != "" {
+		if firstObject {
+			text += "\n" + indentSpaces + "logs:\n"
+		}
+		text += indentSpaces + " - mode: ascii\n"
+		text += indentSpaces + "   filename: " + cdniLogFilename + "\n"
+		text += indentSpaces + "   format: " + CDNiLogFormatName + "\n"
+		for _, field := range []struct{ param, key string }{
+			{"CDNiLog.RollingEnabled", "rolling_enabled"},
+			{"CDNiLog.RollingIntervalSec", "rolling_interval_sec"},
+			{"CDNiLog.RollingOffsetHr", "rolling_offset_hr"},
+			{"CDNiLog.RollingSizeMb", "rolling_size_mb"},
+		} {
+			if val := paramData[field.param]; val != "" {
+				text += indentSpaces + "   " + field.key + ": " + val + "\n"
+			}
+		}
+	}
+
 	return Cfg{
 		Text:        text,
 		ContentType: ContentTypeLoggingDotYAML,
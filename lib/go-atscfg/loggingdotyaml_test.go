@@ -172,3 +172,62 @@ func TestMakeLoggingDotYAMLMultiFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeLoggingDotYAMLCDNi(t *testing.T) {
+	paramData := makeParamsFromMap("serverProfile", LoggingYAMLFileName, map[string]string{
+		"LogFormat.Name":             "myFormatName",
+		"LogFormat.Format":           "myFormat",
+		"CDNiLog.Filename":           "cdni",
+		"CDNiLog.RollingIntervalSec": "300",
+	})
+
+	server := makeGenericServer()
+	server.ProfileNames = []string{"myProfile"}
+
+	cfg, err := MakeLoggingDotYAML(server, paramData, &LoggingDotYAMLOpts{ATSMajorVersion: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := cfg.Text
+
+	var v struct {
+		Formats []struct {
+			Name   string
+			Format string
+		}
+		Logs []struct {
+			Mode                 string
+			Filename             string
+			Format               string
+			Rolling_interval_sec int
+		}
+	}
+	if err := yaml.Unmarshal([]byte(txt), &v); err != nil {
+		t.Fatalf("expected config to parse as yaml document '%v', actual: '%v'", err, txt)
+	}
+	if len(v.Formats) != 2 || v.Formats[1].Name != CDNiLogFormatName || v.Formats[1].Format != CDNiLogFormat {
+		t.Errorf("expected config to contain the CDNi format after 'myFormatName', actual: '%v'", txt)
+	}
+	if n := len(strings.Split(CDNiLogFormat, "\t")); n != CDNiLogFieldCount {
+		t.Errorf("expected the CDNi format to have %d fields, actual: %d", CDNiLogFieldCount, n)
+	}
+	if len(v.Logs) != 1 {
+		t.Fatalf("expected config to contain 1 'logs' element, actual: '%v'", txt)
+	}
+	if log := v.Logs[0]; log.Mode != "ascii" || log.Filename != "cdni" || log.Format != CDNiLogFormatName || log.Rolling_interval_sec != 300 {
+		t.Errorf("expected the CDNi log object, actual: %+v", log)
+	}
+
+	paramData = makeParamsFromMap("serverProfile", LoggingYAMLFileName, map[string]string{
+		"LogFormat.Name":             "myFormatName",
+		"LogFormat.Format":           "myFormat",
+		"CDNiLog.RollingIntervalSec": "300",
+	})
+	cfg, err = MakeLoggingDotYAML(server, paramData, &LoggingDotYAMLOpts{ATSMajorVersion: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(cfg.Text, CDNiLogFormatName) {
+		t.Errorf("expected config without a CDNiLog.Filename Parameter to omit the CDNi log, actual: '%v'", cfg.Text)
+	}
+}
@@ -123,6 +123,10 @@ type ParentAbstractionService struct {
 	// Becomes parent.config weight directive
 	// Becomes strategies.yaml TODO
 	Weight float64
+
+	// Sources are the Traffic Ops objects the service was generated from.
+	// This is used for config line provenance, and doesn't become any config directive.
+	Sources []ProvenanceSource
 }
 
 // ParentAbstractionServices implements sort.Interface
@@ -273,7 +277,9 @@ var DefaultUnavailableServerRetryCodes = []int{503}
 
 const DefaultIgnoreQueryStringInParentSelection = false
 
-func parentAbstractionToParentDotConfig(pa *ParentAbstraction, opt *ParentConfigOpts, atsMajorVersion uint) (string, []string, error) {
+// parentAbstractionToParentDotConfig returns the parent.config text of the given abstraction, any warnings, and any error.
+// The Sources of each service are recorded in prov.
+func parentAbstractionToParentDotConfig(pa *ParentAbstraction, opt *ParentConfigOpts, atsMajorVersion uint, prov provenance) (string, []string, error) {
 	warnings := []string{}
 	txt := ""
 
@@ -294,6 +300,7 @@ func parentAbstractionToParentDotConfig(pa *ParentAbstraction, opt *ParentConfig
 			// TODO don't error? No single delivery service should be able to break others.
 			return "", warnings, errors.New("creating parent.config line from service: " + err.Error())
 		}
+		prov.add(svcLine, svc.Sources)
 		txt += svcLine + "\n"
 
 		processedOriginsToDSNames[svc.DestDomain] = svc.Name
@@ -146,7 +146,8 @@ func MakeParentDotConfig(
 		return Cfg{}, makeErr(warnings, err.Error())
 	}
 
-	text, paWarns, err := parentAbstractionToParentDotConfig(parentAbstraction, opt, atsMajorVersion)
+	prov := provenance{}
+	text, paWarns, err := parentAbstractionToParentDotConfig(parentAbstraction, opt, atsMajorVersion, prov)
 	warnings = append(warnings, paWarns...)
 	if err != nil {
 		return Cfg{}, makeErr(warnings, err.Error())
@@ -162,6 +163,7 @@ func MakeParentDotConfig(
 		ContentType: ContentTypeParentDotConfig,
 		LineComment: LineCommentParentDotConfig,
 		Warnings:    warnings,
+		Provenance:  prov.lines(hdr + text),
 	}, nil
 }
 
@@ -679,9 +681,99 @@ func makeParentDotConfigData(
 		parentAbstraction.Services = append(parentAbstraction.Services, defaultDestText)
 	}
 
+	addParentServicesProvenance(parentAbstraction.Services, dses, server, servers, parentConfigParamsWithProfiles, dsRequiredCapabilities)
+
 	return parentAbstraction, warnings, nil
 }
 
+// addParentServicesProvenance sets the Sources of the given services: the service's Delivery Service and its Topology,
+// the parent servers and their Cache Groups, the parent.config Parameters of the Delivery Service and server Profiles,
+// and the Delivery Service's Required Capabilities.
+func addParentServicesProvenance(
+	svcs []*ParentAbstractionService,
+	dses []DeliveryService,
+	server *Server,
+	servers []Server,
+	parentConfigParams []parameterWithProfiles,
+	dsRequiredCapabilities map[int]map[ServerCapability]struct{},
+) {
+	dsNames := map[string]*DeliveryService{}
+	for i, ds := range dses {
+		if ds.XMLID != nil {
+			dsNames[*ds.XMLID] = &dses[i]
+		}
+	}
+
+	fqdnServers := map[string]*Server{}
+	for i, sv := range servers {
+		if sv.HostName != nil && sv.DomainName != nil {
+			fqdnServers[*sv.HostName+"."+*sv.DomainName] = &servers[i]
+		}
+	}
+
+	serverProfiles := map[string]struct{}{}
+	for _, profile := range server.ProfileNames {
+		serverProfiles[profile] = struct{}{}
+	}
+
+	for _, svc := range svcs {
+		sources := []ProvenanceSource{}
+		dsProfile := ""
+		ds, isDS := dsNames[svc.Name]
+		if isDS {
+			sources = append(sources, dsProvenanceSource(ds))
+			if ds.Topology != nil && *ds.Topology != "" {
+				sources = append(sources, topologyProvenanceSources(*ds.Topology, *server.Cachegroup)...)
+			}
+			if ds.ProfileName != nil {
+				dsProfile = *ds.ProfileName
+			}
+		}
+		sources = append(sources, cacheGroupProvenanceSource(*server.Cachegroup, server.CachegroupID, "server cache group"))
+
+		for _, parents := range []struct {
+			parents []*ParentAbstractionServiceParent
+			detail  string
+		}{
+			{svc.Parents, "parent"},
+			{svc.SecondaryParents, "secondary parent"},
+		} {
+			for _, parent := range parents.parents {
+				sv, ok := fqdnServers[parent.FQDN]
+				if !ok {
+					continue // parents which aren't servers are origins, which come from the Delivery Service
+				}
+				src := ProvenanceSource{Type: ProvenanceSourceServer, Name: parent.FQDN, Detail: parents.detail}
+				if sv.ID != nil {
+					src.ID = util.IntPtr(*sv.ID)
+				}
+				sources = append(sources, src)
+				if sv.Cachegroup != nil {
+					sources = append(sources, cacheGroupProvenanceSource(*sv.Cachegroup, sv.CachegroupID, parents.detail+" cache group"))
+				}
+			}
+		}
+
+		for _, param := range parentConfigParams {
+			for _, profile := range param.ProfileNames {
+				if isDS && profile == dsProfile {
+					sources = append(sources, paramProvenanceSource(param.Parameter, "delivery service profile '"+profile+"'"))
+					break
+				}
+				if _, ok := serverProfiles[profile]; ok {
+					sources = append(sources, paramProvenanceSource(param.Parameter, "server profile '"+profile+"'"))
+					break
+				}
+			}
+		}
+
+		if isDS && ds.ID != nil {
+			sources = append(sources, capabilityProvenanceSources(dsRequiredCapabilities[*ds.ID])...)
+		}
+		svc.Sources = appendProvenanceSources(nil, sources)
+	}
+}
+
 // makeParentComment creates the parent line comment and returns it.
 // If addComments is false, returns the empty string. This exists for composability.
 // Either dsName or topology may be the empty string.
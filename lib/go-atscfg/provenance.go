@@ -0,0 +1,170 @@
+package atscfg
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+)
+
+// ProvenanceSourceType is the type of Traffic Ops object a generated config line came from.
+type ProvenanceSourceType string
+
+const (
+	ProvenanceSourceDeliveryService  = ProvenanceSourceType("deliveryservice")
+	ProvenanceSourceParameter        = ProvenanceSourceType("parameter")
+	ProvenanceSourceTopology         = ProvenanceSourceType("topology")
+	ProvenanceSourceTopologyNode     = ProvenanceSourceType("topologynode")
+	ProvenanceSourceCacheGroup       = ProvenanceSourceType("cachegroup")
+	ProvenanceSourceServer           = ProvenanceSourceType("server")
+	ProvenanceSourceServerCapability = ProvenanceSourceType("servercapability")
+)
+
+// ProvenanceSource is a Traffic Ops object which contributed to a generated config line.
+type ProvenanceSource struct {
+	Type ProvenanceSourceType `json:"type"`
+	// ID is the integral, unique identifier of the object, if it has one.
+	ID *int `json:"id,omitempty"`
+	// Name is the name of the object, e.g. the Delivery Service XMLID, or the Parameter name.
+	Name string `json:"name"`
+	// Detail is a human-readable description of how the object contributed to the line, e.g. the Parameter config file and value.
+	Detail string `json:"detail,omitempty"`
+}
+
+// LineProvenance is the sources of a single line of a generated config file.
+type LineProvenance struct {
+	// Line is the 1-based line number in the generated config file text.
+	Line int `json:"line"`
+	// Text is the text of the line, without the trailing newline.
+	// This can be used to verify the line number is still correct, if the file has been modified since it was generated.
+	Text    string             `json:"text"`
+	Sources []ProvenanceSource `json:"sources"`
+}
+
+// provenance records the sources of generated config lines, keyed by line text.
+//
+// Keying by text rather than line number lets generators record sources as they build lines, before sorting and joining them into the final text. Lines with identical text will share sources, which is fine, because generators don't emit identical lines from different objects.
+type provenance map[string][]ProvenanceSource
+
+// add records that each line of txt came from the given sources.
+func (pr provenance) add(txt string, sources []ProvenanceSource) {
+	if len(sources) == 0 {
+		return
+	}
+	for _, line := range strings.Split(txt, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		pr[line] = appendProvenanceSources(pr[line], sources)
+	}
+}
+
+// lines returns the provenance of every line of the final text txt which has any recorded sources.
+func (pr provenance) lines(txt string) []LineProvenance {
+	lines := []LineProvenance{}
+	for i, line := range strings.Split(txt, "\n") {
+		sources, ok := pr[line]
+		if !ok {
+			continue
+		}
+		lines = append(lines, LineProvenance{Line: i + 1, Text: line, Sources: sources})
+	}
+	return lines
+}
+
+// appendProvenanceSources appends the sources not already in existing.
+func appendProvenanceSources(existing []ProvenanceSource, sources []ProvenanceSource) []ProvenanceSource {
+	for _, src := range sources {
+		found := false
+		for _, ex := range existing {
+			if ex.Type == src.Type && ex.Name == src.Name && ex.Detail == src.Detail {
+				found = true
+				break
+			}
+		}
+		if !found {
+			existing = append(existing, src)
+		}
+	}
+	return existing
+}
+
+// dsProvenanceSource returns the provenance source of the given Delivery Service, which must have a non-nil XMLID.
+func dsProvenanceSource(ds *DeliveryService) ProvenanceSource {
+	src := ProvenanceSource{Type: ProvenanceSourceDeliveryService, Name: *ds.XMLID}
+	if ds.ID != nil {
+		src.ID = util.IntPtr(*ds.ID)
+	}
+	if ds.OrgServerFQDN != nil && *ds.OrgServerFQDN != "" {
+		src.Detail = "origin '" + *ds.OrgServerFQDN + "'"
+	}
+	return src
+}
+
+// topologyProvenanceSources returns the provenance sources of the given Topology, and of the node of the given server Cache Group within it.
+// If topologyName is empty, returns nil.
+func topologyProvenanceSources(topologyName string, serverCacheGroup string) []ProvenanceSource {
+	if topologyName == "" {
+		return nil
+	}
+	return []ProvenanceSource{
+		{Type: ProvenanceSourceTopology, Name: topologyName},
+		{Type: ProvenanceSourceTopologyNode, Name: serverCacheGroup, Detail: "node of topology '" + topologyName + "'"},
+	}
+}
+
+// cacheGroupProvenanceSource returns the provenance source of the given Cache Group.
+func cacheGroupProvenanceSource(name string, id *int, detail string) ProvenanceSource {
+	src := ProvenanceSource{Type: ProvenanceSourceCacheGroup, Name: name, Detail: detail}
+	if id != nil {
+		src.ID = util.IntPtr(*id)
+	}
+	return src
+}
+
+// paramProvenanceSource returns the provenance source of the given Parameter.
+func paramProvenanceSource(param tc.Parameter, detail string) ProvenanceSource {
+	if detail != "" {
+		detail = ", " + detail
+	}
+	return ProvenanceSource{
+		Type:   ProvenanceSourceParameter,
+		ID:     util.IntPtr(param.ID),
+		Name:   param.Name,
+		Detail: "config file '" + param.ConfigFile + "' value '" + param.Value + "'" + detail,
+	}
+}
+
+// capabilityProvenanceSources returns the provenance sources of the given Delivery Service Required Capabilities, sorted by name.
+func capabilityProvenanceSources(requiredCapabilities map[ServerCapability]struct{}) []ProvenanceSource {
+	caps := []string{}
+	for capability, _ := range requiredCapabilities {
+		caps = append(caps, string(capability))
+	}
+	sort.Strings(caps)
+	sources := []ProvenanceSource{}
+	for _, capability := range caps {
+		sources = append(sources, ProvenanceSource{Type: ProvenanceSourceServerCapability, Name: capability, Detail: "required by delivery service"})
+	}
+	return sources
+}
@@ -0,0 +1,204 @@
+package atscfg
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+)
+
+func TestProvenanceLines(t *testing.T) {
+	prov := provenance{}
+	dsSrc := ProvenanceSource{Type: ProvenanceSourceDeliveryService, Name: "ds0"}
+	paramSrc := ProvenanceSource{Type: ProvenanceSourceParameter, Name: "p"}
+	prov.add("b line\na line\n", []ProvenanceSource{dsSrc})
+	prov.add("a line\n", []ProvenanceSource{dsSrc, paramSrc})
+
+	lines := prov.lines("# header\na line\nb line\nc line\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines with provenance, actual %+v", lines)
+	}
+	if lines[0].Line != 2 || lines[0].Text != "a line" || len(lines[0].Sources) != 2 {
+		t.Errorf("expected line 2 'a line' with ds and param sources, actual %+v", lines[0])
+	}
+	if lines[1].Line != 3 || lines[1].Text != "b line" || len(lines[1].Sources) != 1 {
+		t.Errorf("expected line 3 'b line' with ds source, actual %+v", lines[1])
+	}
+}
+
+func TestMakeRemapDotConfigProvenance(t *testing.T) {
+	server := makeTestRemapServer()
+	server.Type = "EDGE"
+
+	ds := DeliveryService{}
+	ds.ID = util.IntPtr(48)
+	dsType := tc.DSType("HTTP_LIVE_NATNL")
+	ds.Type = &dsType
+	ds.OrgServerFQDN = util.StrPtr("origin.example.test")
+	ds.XMLID = util.StrPtr("mydsname")
+	ds.DSCP = util.IntPtr(0)
+	ds.RoutingName = util.StrPtr("myroutingname")
+	ds.Protocol = util.IntPtr(int(tc.DSProtocolHTTP))
+	ds.ProfileID = util.IntPtr(49)
+	ds.ProfileName = util.StrPtr("dsprofile")
+	ds.Active = util.BoolPtr(true)
+
+	dss := []DeliveryServiceServer{{Server: *server.ID, DeliveryService: *ds.ID}}
+	dsRegexes := []tc.DeliveryServiceRegexes{{
+		DSName:  *ds.XMLID,
+		Regexes: []tc.DeliveryServiceRegex{{Type: string(tc.DSMatchTypeHostRegex), Pattern: `myliteralpattern__http__foo`}},
+	}}
+	cdn := &tc.CDN{DomainName: "cdndomain.example", Name: "my-cdn-name"}
+	remapConfigParams := []tc.Parameter{{
+		ID:         77,
+		Name:       "cachekey.pparam",
+		ConfigFile: "remap.config",
+		Value:      "--cachekeykey=cachekeyval",
+		Profiles:   []byte(`["dsprofile"]`),
+	}}
+	dsRequiredCapabilities := map[int]map[ServerCapability]struct{}{*ds.ID: {"fast": {}}}
+	serverCapabilities := map[int]map[ServerCapability]struct{}{*server.ID: {"fast": {}}}
+
+	cfg, err := MakeRemapDotConfig(server, []DeliveryService{ds}, dss, dsRegexes, nil, cdn, remapConfigParams, nil, nil, serverCapabilities, dsRequiredCapabilities, `/opt/trafficserver/etc/trafficserver`, &RemapDotConfigOpts{HdrComment: "myHeaderComment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txtLines := strings.Split(cfg.Text, "\n")
+	mapLines := 0
+	for _, line := range txtLines {
+		if strings.HasPrefix(line, "map") {
+			mapLines++
+		}
+	}
+	if mapLines == 0 || len(cfg.Provenance) != mapLines {
+		t.Fatalf("expected provenance for each of the %v map lines, actual %+v", mapLines, cfg.Provenance)
+	}
+	for _, lp := range cfg.Provenance {
+		if txtLines[lp.Line-1] != lp.Text {
+			t.Errorf("expected provenance line %v text to match the config text '%v', actual '%v'", lp.Line, txtLines[lp.Line-1], lp.Text)
+		}
+		if !provenanceHasSource(lp.Sources, ProvenanceSourceDeliveryService, "mydsname", 48) {
+			t.Errorf("expected line %v provenance to include ds 'mydsname', actual %+v", lp.Line, lp.Sources)
+		}
+		if !provenanceHasSource(lp.Sources, ProvenanceSourceParameter, "cachekey.pparam", 77) {
+			t.Errorf("expected line %v provenance to include parameter 77, actual %+v", lp.Line, lp.Sources)
+		}
+		if !provenanceHasSource(lp.Sources, ProvenanceSourceServerCapability, "fast", 0) {
+			t.Errorf("expected line %v provenance to include required capability 'fast', actual %+v", lp.Line, lp.Sources)
+		}
+	}
+}
+
+func TestMakeParentDotConfigProvenance(t *testing.T) {
+	hdr := &ParentConfigOpts{AddComments: true, HdrComment: "myHeaderComment"}
+
+	ds0 := makeParentDS()
+	ds0.XMLID = util.StrPtr("ds0")
+	ds0Type := tc.DSTypeHTTP
+	ds0.Type = &ds0Type
+	ds0.OrgServerFQDN = util.StrPtr("http://ds0.example.net")
+
+	parentConfigParams := []tc.Parameter{{
+		ID:         88,
+		Name:       ParentConfigRetryKeysDefault.Algorithm,
+		ConfigFile: "parent.config",
+		Value:      tc.AlgorithmConsistentHash,
+		Profiles:   []byte(`["serverprofile"]`),
+	}}
+	serverParams := []tc.Parameter{{
+		Name:       "trafficserver",
+		ConfigFile: "package",
+		Value:      "9",
+		Profiles:   []byte(`["global"]`),
+	}}
+
+	server := makeTestParentServer()
+
+	mid0 := makeTestParentServer()
+	mid0.Cachegroup = util.StrPtr("midCG")
+	mid0.CachegroupID = util.IntPtr(423)
+	mid0.HostName = util.StrPtr("mymid0")
+	mid0.ID = util.IntPtr(45)
+	setIP(mid0, "192.168.2.2")
+
+	eCG := &tc.CacheGroupNullable{}
+	eCG.Name = server.Cachegroup
+	eCG.ID = server.CachegroupID
+	eCG.ParentName = mid0.Cachegroup
+	eCG.ParentCachegroupID = mid0.CachegroupID
+	eCGType := tc.CacheGroupEdgeTypeName
+	eCG.Type = &eCGType
+
+	mCG := &tc.CacheGroupNullable{}
+	mCG.Name = mid0.Cachegroup
+	mCG.ID = mid0.CachegroupID
+	mCGType := tc.CacheGroupMidTypeName
+	mCG.Type = &mCGType
+
+	dss := []DeliveryServiceServer{{Server: *server.ID, DeliveryService: *ds0.ID}}
+	cdn := &tc.CDN{DomainName: "cdndomain.example", Name: "my-cdn-name"}
+
+	cfg, err := MakeParentDotConfig([]DeliveryService{*ds0}, server, []Server{*server, *mid0}, nil, serverParams, parentConfigParams, nil, nil, []tc.CacheGroupNullable{*eCG, *mCG}, dss, cdn, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsLine := (*LineProvenance)(nil)
+	for i, lp := range cfg.Provenance {
+		if strings.HasPrefix(lp.Text, "dest_domain=ds0.example.net") {
+			dsLine = &cfg.Provenance[i]
+		}
+	}
+	if dsLine == nil {
+		t.Fatalf("expected provenance for ds0 parent line, actual %+v text '%v'", cfg.Provenance, cfg.Text)
+	}
+	if txtLines := strings.Split(cfg.Text, "\n"); txtLines[dsLine.Line-1] != dsLine.Text {
+		t.Errorf("expected provenance line %v text to match the config text '%v', actual '%v'", dsLine.Line, txtLines[dsLine.Line-1], dsLine.Text)
+	}
+	if !provenanceHasSource(dsLine.Sources, ProvenanceSourceDeliveryService, "ds0", 42) {
+		t.Errorf("expected ds0 line provenance to include ds 'ds0', actual %+v", dsLine.Sources)
+	}
+	if !provenanceHasSource(dsLine.Sources, ProvenanceSourceServer, "mymid0.mydomain.example.net", 45) {
+		t.Errorf("expected ds0 line provenance to include parent server 'mymid0', actual %+v", dsLine.Sources)
+	}
+	if !provenanceHasSource(dsLine.Sources, ProvenanceSourceCacheGroup, "midCG", 423) {
+		t.Errorf("expected ds0 line provenance to include parent cache group 'midCG', actual %+v", dsLine.Sources)
+	}
+	if !provenanceHasSource(dsLine.Sources, ProvenanceSourceParameter, ParentConfigRetryKeysDefault.Algorithm, 88) {
+		t.Errorf("expected ds0 line provenance to include server profile parameter 88, actual %+v", dsLine.Sources)
+	}
+}
+
+// provenanceHasSource returns whether sources contains a source of the given type and name, and the given ID if it's not 0.
+func provenanceHasSource(sources []ProvenanceSource, typ ProvenanceSourceType, name string, id int) bool {
+	for _, src := range sources {
+		if src.Type != typ || src.Name != name {
+			continue
+		}
+		if id == 0 || (src.ID != nil && *src.ID == id) {
+			return true
+		}
+	}
+	return false
+}
@@ -126,10 +126,11 @@ func MakeRemapDotConfig(
 	hdr := makeHdrComment(opt.HdrComment)
 	txt := ""
 	typeWarns := []string{}
+	prov := provenance{}
 	if tc.CacheTypeFromString(server.Type) == tc.CacheTypeMid {
-		txt, typeWarns, err = getServerConfigRemapDotConfigForMid(atsMajorVersion, dsProfilesConfigParams, dses, dsRegexes, hdr, server, nameTopologies, cacheGroups, serverCapabilities, dsRequiredCapabilities, configDir, opt)
+		txt, typeWarns, err = getServerConfigRemapDotConfigForMid(atsMajorVersion, dsProfilesConfigParams, dses, dsRegexes, hdr, server, nameTopologies, cacheGroups, serverCapabilities, dsRequiredCapabilities, configDir, opt, prov)
 	} else {
-		txt, typeWarns, err = getServerConfigRemapDotConfigForEdge(dsProfilesConfigParams, serverPackageParamData, dses, dsRegexes, atsMajorVersion, hdr, server, nameTopologies, cacheGroups, serverCapabilities, dsRequiredCapabilities, cdnDomain, configDir, opt)
+		txt, typeWarns, err = getServerConfigRemapDotConfigForEdge(dsProfilesConfigParams, serverPackageParamData, dses, dsRegexes, atsMajorVersion, hdr, server, nameTopologies, cacheGroups, serverCapabilities, dsRequiredCapabilities, cdnDomain, configDir, opt, prov)
 	}
 	warnings = append(warnings, typeWarns...)
 	if err != nil {
@@ -141,9 +142,24 @@ func MakeRemapDotConfig(
 		ContentType: ContentTypeRemapDotConfig,
 		LineComment: LineCommentRemapDotConfig,
 		Warnings:    warnings,
+		Provenance:  prov.lines(txt),
 	}, nil
 }
 
+// remapDSProvenance returns the provenance sources of the remap lines of the given Delivery Service.
+func remapDSProvenance(ds *DeliveryService, server *Server, topologyName string, remapConfigParams []tc.Parameter, requiredCapabilities map[ServerCapability]struct{}) []ProvenanceSource {
+	sources := []ProvenanceSource{dsProvenanceSource(ds)}
+	if topologyName != "" {
+		sources = append(sources, topologyProvenanceSources(topologyName, *server.Cachegroup)...)
+	} else {
+		sources = append(sources, cacheGroupProvenanceSource(*server.Cachegroup, server.CachegroupID, "server cache group"))
+	}
+	for _, param := range remapConfigParams {
+		sources = append(sources, paramProvenanceSource(param, "delivery service profile"))
+	}
+	return append(sources, capabilityProvenanceSources(requiredCapabilities)...)
+}
+
 // This sticks the DS parameters in a map.
 // remap.config parameters use "<plugin>.pparam" key
 // cachekey.config parameters retain the 'cachekey.config' key
@@ -211,10 +227,18 @@ func paramsStringOldFor(parameters []tc.Parameter, warnings *[]string) (paramsSt
 }
 
 // Handles special case for cachekey
-func cachekeyArgsFor(configParamsMap map[string][]tc.Parameter, warnings *[]string) (argsString string) {
+func cachekeyArgsFor(configParamsMap map[string][]tc.Parameter, hasPolicy bool, warnings *[]string) (argsString string) {
 
 	hasCachekey := false
 
+	if hasPolicy {
+		_, hasPParams := configParamsMap["cachekey.pparam"]
+		_, hasConfigParams := configParamsMap["cachekey.config"]
+		if hasPParams || hasConfigParams {
+			*warnings = append(*warnings, "Both cache key policy and cachekey parameters assigned, parameters will be appended to the policy arguments")
+		}
+	}
+
 	if params, ok := configParamsMap["cachekey.pparam"]; ok {
 		argsString += paramsStringFor(params, warnings)
 		hasCachekey = true
@@ -230,6 +254,65 @@ func cachekeyArgsFor(configParamsMap map[string][]tc.Parameter, warnings *[]stri
 	return
 }
 
+// cacheKeyPolicyArgs returns the cachekey plugin arguments for the given Delivery Service cache key policy, for the given ATS major version.
+// Returns an empty string if policy is nil.
+func cacheKeyPolicyArgs(policy *tc.CacheKeyPolicy, atsMajorVersion uint, dsName string, warnings *[]string) string {
+	if policy == nil {
+		return ""
+	}
+	if atsMajorVersion < 7 {
+		*warnings = append(*warnings, "ds '"+dsName+"' has a cache key policy, which is not supported on ATS version "+strconv.Itoa(int(atsMajorVersion))+", omitting!")
+		return ""
+	}
+
+	args := ""
+	if policy.RemoveAllParams {
+		args += ` @pparam=--remove-all-params=true`
+	}
+	if len(policy.IncludeParams) > 0 {
+		args += ` @pparam=--include-params=` + strings.Join(policy.IncludeParams, ",")
+	}
+	if len(policy.ExcludeParams) > 0 {
+		args += ` @pparam=--exclude-params=` + strings.Join(policy.ExcludeParams, ",")
+	}
+	if policy.SortParams {
+		args += ` @pparam=--sort-params=true`
+	}
+	if len(policy.IncludeHeaders) > 0 {
+		args += ` @pparam=--include-headers=` + strings.Join(policy.IncludeHeaders, ",")
+	}
+	if len(policy.IncludeCookies) > 0 {
+		args += ` @pparam=--include-cookies=` + strings.Join(policy.IncludeCookies, ",")
+	}
+	if policy.UserAgentCapture != nil {
+		args += ` @pparam=--ua-capture=` + *policy.UserAgentCapture
+	}
+
+	// ATS 9 renamed the User-Agent class arguments, and ATS 10 removed the old names.
+	allowArg, denyArg := `--ua-allowlist`, `--ua-denylist`
+	if atsMajorVersion < 9 {
+		allowArg, denyArg = `--ua-whitelist`, `--ua-blacklist`
+	}
+	for _, class := range policy.UserAgentClasses {
+		arg := allowArg
+		if class.Deny {
+			arg = denyArg
+		}
+		args += ` @pparam=` + arg + `=` + class.Name + `:` + class.File
+	}
+
+	if policy.RemovePath {
+		args += ` @pparam=--remove-path=true`
+	}
+	if policy.PathCapture != nil {
+		args += ` @pparam=--capture-path=` + *policy.PathCapture
+	}
+	if policy.CanonicalPrefix {
+		args += ` @pparam=--canonical-prefix=true`
+	}
+	return args
+}
+
 // lastPrePostRemapLinesFor Returns any pre or post raw remap lines.
 func lastPrePostRemapLinesFor(dsConfigParamsMap map[string][]tc.Parameter, dsid string) ([]string, []string) {
 	preRemapLines := []string{}
@@ -266,9 +349,11 @@ func getServerConfigRemapDotConfigForMid(
 	dsRequiredCapabilities map[int]map[ServerCapability]struct{},
 	configDir string,
 	opts *RemapDotConfigOpts,
+	prov provenance,
 ) (string, []string, error) {
 	warnings := []string{}
 	midRemaps := map[string]string{}
+	midRemapSources := map[string][]ProvenanceSource{}
 	preRemapLines := []string{}
 	postRemapLines := []string{}
 	for _, ds := range dses {
@@ -324,13 +409,15 @@ func getServerConfigRemapDotConfigForMid(
 			cachekeyArgs = getQStringIgnoreRemap(atsMajorVersion)
 		}
 
+		cachekeyArgs += cacheKeyPolicyArgs(ds.CacheKeyPolicy, atsMajorVersion, *ds.XMLID, &warnings)
+
 		dsConfigParamsMap := map[string][]tc.Parameter{}
 		if nil != ds.ProfileID {
 			dsConfigParamsMap = classifyConfigParams(profilesConfigParams[*ds.ProfileID])
 		}
 
 		if len(dsConfigParamsMap) > 0 {
-			cachekeyArgs += cachekeyArgsFor(dsConfigParamsMap, &warnings)
+			cachekeyArgs += cachekeyArgsFor(dsConfigParamsMap, ds.CacheKeyPolicy != nil, &warnings)
 		}
 
 		if cachekeyArgs != "" {
@@ -355,8 +442,19 @@ func getServerConfigRemapDotConfigForMid(
 			mapTo = strings.Replace(mapTo, `https://`, `http://`, -1)
 		}
 
+		topologyName := ""
+		if hasTopology {
+			topologyName = topology.Name
+		}
+		dsProfileParams := []tc.Parameter{}
+		if ds.ProfileID != nil {
+			dsProfileParams = profilesConfigParams[*ds.ProfileID]
+		}
+		sources := remapDSProvenance(&ds, server, topologyName, dsProfileParams, dsRequiredCapabilities[*ds.ID])
+
 		if midRemap != "" {
 			midRemaps[remapFrom] = mapTo + midRemap
+			midRemapSources[remapFrom] = sources
 		}
 
 		// Any raw pre or post pend
@@ -365,6 +463,8 @@ func getServerConfigRemapDotConfigForMid(
 		// Add to pre/post remap lines if this is last tier
 		if len(dsPreRemaps) > 0 || len(dsPostRemaps) > 0 {
 			if isLastCache {
+				prov.add(strings.Join(dsPreRemaps, ""), sources)
+				prov.add(strings.Join(dsPostRemaps, ""), sources)
 				preRemapLines = append(preRemapLines, dsPreRemaps...)
 				postRemapLines = append(postRemapLines, dsPostRemaps...)
 			}
@@ -374,7 +474,9 @@ func getServerConfigRemapDotConfigForMid(
 	textLines := []string{}
 
 	for originFQDN, midRemap := range midRemaps {
-		textLines = append(textLines, "map "+originFQDN+" "+midRemap+"\n")
+		line := "map " + originFQDN + " " + midRemap + "\n"
+		prov.add(line, midRemapSources[originFQDN])
+		textLines = append(textLines, line)
 	}
 
 	sort.Strings(preRemapLines)
@@ -407,6 +509,7 @@ func getServerConfigRemapDotConfigForEdge(
 	cdnDomain string,
 	configDir string,
 	opts *RemapDotConfigOpts,
+	prov provenance,
 ) (string, []string, error) {
 	warnings := []string{}
 	textLines := []string{}
@@ -428,6 +531,16 @@ func getServerConfigRemapDotConfigForEdge(
 				continue
 			}
 		}
+		topologyName := ""
+		if hasTopology {
+			topologyName = topology.Name
+		}
+		profileremapConfigParams := []tc.Parameter{}
+		if ds.ProfileID != nil {
+			profileremapConfigParams = profilesRemapConfigParams[*ds.ProfileID]
+		}
+		sources := remapDSProvenance(&ds, server, topologyName, profileremapConfigParams, dsRequiredCapabilities[*ds.ID])
+
 		remapText := ""
 		if *ds.Type == tc.DSTypeAnyMap {
 			if ds.RemapText == nil {
@@ -435,6 +548,7 @@ func getServerConfigRemapDotConfigForEdge(
 				continue
 			}
 			remapText = *ds.RemapText + "\n"
+			prov.add(remapText, sources)
 			textLines = append(textLines, remapText)
 			continue
 		}
@@ -453,10 +567,6 @@ func getServerConfigRemapDotConfigForEdge(
 			}
 
 			for _, line := range remapLines {
-				profileremapConfigParams := []tc.Parameter{}
-				if ds.ProfileID != nil {
-					profileremapConfigParams = profilesRemapConfigParams[*ds.ProfileID]
-				}
 				remapWarns := []string{}
 				dsLines := RemapLines{}
 				dsLines, remapWarns, err = buildEdgeRemapLine(atsMajorVersion, server, serverPackageParamData, remapText, ds, line.From, line.To, profileremapConfigParams, cacheGroups, nameTopologies, configDir, opts)
@@ -467,6 +577,8 @@ func getServerConfigRemapDotConfigForEdge(
 				if len(dsLines.Pre) > 0 || len(dsLines.Post) > 0 {
 					preRemapLines = append(preRemapLines, dsLines.Pre...)
 					postRemapLines = append(postRemapLines, dsLines.Post...)
+					prov.add(strings.Join(dsLines.Pre, ""), sources)
+					prov.add(strings.Join(dsLines.Post, ""), sources)
 				}
 
 				if err != nil {
@@ -480,6 +592,7 @@ func getServerConfigRemapDotConfigForEdge(
 			}
 		}
 
+		prov.add(remapText, sources)
 		textLines = append(textLines, remapText)
 	}
 
@@ -587,8 +700,10 @@ func buildEdgeRemapLine(
 		}
 	}
 
+	cachekeyArgs += cacheKeyPolicyArgs(ds.CacheKeyPolicy, atsMajorVersion, *ds.XMLID, &warnings)
+
 	if len(dsConfigParamsMap) > 0 {
-		cachekeyArgs += cachekeyArgsFor(dsConfigParamsMap, &warnings)
+		cachekeyArgs += cachekeyArgsFor(dsConfigParamsMap, ds.CacheKeyPolicy != nil, &warnings)
 	}
 
 	if cachekeyArgs != "" {
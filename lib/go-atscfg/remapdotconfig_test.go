@@ -4498,6 +4498,89 @@ func TestMakeRemapDotConfigEdgeCacheKeyParams(t *testing.T) {
 	}
 }
 
+func TestMakeRemapDotConfigEdgeCacheKeyPolicy(t *testing.T) {
+	server := makeTestRemapServer()
+	server.Type = "EDGE"
+
+	ds := DeliveryService{}
+	ds.ID = util.IntPtr(48)
+	dsType := tc.DSType("HTTP_LIVE_NATNL")
+	ds.Type = &dsType
+	ds.OrgServerFQDN = util.StrPtr("origin.example.test")
+	ds.XMLID = util.StrPtr("mydsname")
+	ds.QStringIgnore = util.IntPtr(int(tc.QueryStringIgnoreUseInCacheKeyAndPassUp))
+	ds.DSCP = util.IntPtr(0)
+	ds.RoutingName = util.StrPtr("myroutingname")
+	ds.Protocol = util.IntPtr(int(tc.DSProtocolHTTP))
+	ds.Active = util.BoolPtr(true)
+	ds.CacheKeyPolicy = &tc.CacheKeyPolicy{
+		IncludeParams:    []string{"a", "b"},
+		ExcludeParams:    []string{"c"},
+		SortParams:       true,
+		IncludeHeaders:   []string{"X-Foo"},
+		IncludeCookies:   []string{"session"},
+		UserAgentCapture: util.StrPtr(`/(Mozilla\/\d).*/$1/`),
+		UserAgentClasses: []tc.CacheKeyUserAgentClass{
+			{Name: "mobile", File: "ua_mobile.config"},
+			{Name: "bot", File: "ua_bot.config", Deny: true},
+		},
+		PathCapture:     util.StrPtr(`/^\/v\d+(\/.*)$/$1/`),
+		CanonicalPrefix: true,
+	}
+
+	dss := []DeliveryServiceServer{{Server: *server.ID, DeliveryService: *ds.ID}}
+	dsRegexes := []tc.DeliveryServiceRegexes{{
+		DSName:  *ds.XMLID,
+		Regexes: []tc.DeliveryServiceRegex{{Type: string(tc.DSMatchTypeHostRegex), Pattern: `myliteralpattern__http__foo`}},
+	}}
+	cdn := &tc.CDN{DomainName: "cdndomain.example", Name: "my-cdn-name"}
+
+	expectedCommon := ` @plugin=cachekey.so @pparam=--include-params=a,b @pparam=--exclude-params=c @pparam=--sort-params=true` +
+		` @pparam=--include-headers=X-Foo @pparam=--include-cookies=session @pparam=--ua-capture=/(Mozilla\/\d).*/$1/`
+	expectedPath := ` @pparam=--capture-path=/^\/v\d+(\/.*)$/$1/ @pparam=--canonical-prefix=true`
+
+	for atsMajorVersion, expectedUA := range map[uint]string{
+		8:  ` @pparam=--ua-whitelist=mobile:ua_mobile.config @pparam=--ua-blacklist=bot:ua_bot.config`,
+		9:  ` @pparam=--ua-allowlist=mobile:ua_mobile.config @pparam=--ua-denylist=bot:ua_bot.config`,
+		10: ` @pparam=--ua-allowlist=mobile:ua_mobile.config @pparam=--ua-denylist=bot:ua_bot.config`,
+	} {
+		cfg, err := MakeRemapDotConfig(server, []DeliveryService{ds}, dss, dsRegexes, nil, cdn, nil, nil, nil, nil, nil, `/opt/trafficserver/etc/trafficserver`, &RemapDotConfigOpts{ATSMajorVersion: atsMajorVersion})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := expectedCommon + expectedUA + expectedPath
+		if !strings.Contains(cfg.Text, expected) {
+			t.Errorf("expected remap on ATS %v edge server with ds cache key policy to contain cachekey args '%v', actual '%v'", atsMajorVersion, expected, cfg.Text)
+		}
+	}
+}
+
+func TestMakeRemapDotConfigMidCacheKeyPolicy(t *testing.T) {
+	server := makeTestRemapServer()
+	server.Type = "MID"
+
+	ds := DeliveryService{}
+	ds.ID = util.IntPtr(48)
+	dsType := tc.DSType("HTTP")
+	ds.Type = &dsType
+	ds.OrgServerFQDN = util.StrPtr("origin.example.test")
+	ds.XMLID = util.StrPtr("mydsname")
+	ds.DSCP = util.IntPtr(0)
+	ds.Active = util.BoolPtr(true)
+	ds.CacheKeyPolicy = &tc.CacheKeyPolicy{RemoveAllParams: true, RemovePath: true}
+
+	dss := []DeliveryServiceServer{{Server: *server.ID, DeliveryService: *ds.ID}}
+	cdn := &tc.CDN{DomainName: "cdndomain.example", Name: "my-cdn-name"}
+
+	cfg, err := MakeRemapDotConfig(server, []DeliveryService{ds}, dss, nil, nil, cdn, nil, nil, nil, nil, nil, `/opt/trafficserver/etc/trafficserver`, &RemapDotConfigOpts{ATSMajorVersion: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := ` @plugin=cachekey.so @pparam=--remove-all-params=true @pparam=--remove-path=true`; !strings.Contains(cfg.Text, expected) {
+		t.Errorf("expected remap on mid server with ds cache key policy to contain cachekey args '%v', actual '%v'", expected, cfg.Text)
+	}
+}
+
 func TestMakeRemapDotConfigEdgeRegexRemap(t *testing.T) {
 	hdr := "myHeaderComment"
 
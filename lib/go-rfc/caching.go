@@ -390,6 +390,47 @@ func FreshFor(respHeaders http.Header, respCC CacheControlMap, reqTime, respTime
 	return freshnessLifetime - currentAge
 }
 
+// StaleIfErrorCodes provides fast lookup of whether a HTTP response code is an
+// error which, per RFC5861§4, permits a stale response to be served in its
+// place.
+var StaleIfErrorCodes = map[int]struct{}{
+	http.StatusInternalServerError: {},
+	http.StatusBadGateway:          {},
+	http.StatusServiceUnavailable:  {},
+	http.StatusGatewayTimeout:      {},
+}
+
+// Staleness gives the duration for which an HTTP response has been stale, or a
+// negative duration if it is still fresh. It takes the same arguments as
+// FreshFor.
+func Staleness(respHeaders http.Header, respCC CacheControlMap, reqTime, respTime time.Time) time.Duration {
+	return -FreshFor(respHeaders, respCC, reqTime, respTime)
+}
+
+// CanServeStale returns whether a response's Cache-Control permits it to be
+// served stale at all, regardless of any RFC5861 extensions.
+func CanServeStale(respCC CacheControlMap) bool {
+	return !respCC.Has("must-revalidate") && !respCC.Has("proxy-revalidate") && !respCC.Has("no-cache") && !respCC.Has("no-store")
+}
+
+// StaleWhileRevalidate returns the duration after a response becomes stale
+// during which it may be served while it is revalidated in the background, per
+// RFC5861§3, and whether the response specified one.
+func StaleWhileRevalidate(respCC CacheControlMap) (time.Duration, bool) {
+	return getHTTPDeltaSecondsCacheControl(respCC, "stale-while-revalidate")
+}
+
+// StaleIfError returns the duration after a response becomes stale during
+// which it may be served if revalidating it results in an error, per
+// RFC5861§4, and whether either the request or the response specified one. A
+// request's stale-if-error takes precedence over the response's.
+func StaleIfError(reqCC CacheControlMap, respCC CacheControlMap) (time.Duration, bool) {
+	if d, ok := getHTTPDeltaSecondsCacheControl(reqCC, "stale-if-error"); ok {
+		return d, true
+	}
+	return getHTTPDeltaSecondsCacheControl(respCC, "stale-if-error")
+}
+
 // Reuse is an "enumerated" type describing the necessary behavior of a cache
 // with regard to its cached objects.
 type Reuse int
@@ -946,3 +946,54 @@ func BenchmarkCanReuseStored(b *testing.B) {
 		CanReuseStored(reqHdr, respHdr, reqCC, respCC, respReqHdrs, respReqTime, respRespTime, strictRFC)
 	}
 }
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	if d, ok := StaleWhileRevalidate(CacheControlMap{"max-age": "60", "stale-while-revalidate": "30"}); !ok || d != 30*time.Second {
+		t.Errorf("StaleWhileRevalidate expected 30s true, actual %v %v", d, ok)
+	}
+	if _, ok := StaleWhileRevalidate(CacheControlMap{"max-age": "60"}); ok {
+		t.Error("StaleWhileRevalidate without the directive expected false, actual true")
+	}
+	if _, ok := StaleWhileRevalidate(CacheControlMap{"stale-while-revalidate": "soon"}); ok {
+		t.Error("StaleWhileRevalidate with an invalid value expected false, actual true")
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	respCC := CacheControlMap{"stale-if-error": "600"}
+	if d, ok := StaleIfError(CacheControlMap{}, respCC); !ok || d != 600*time.Second {
+		t.Errorf("StaleIfError from response expected 600s true, actual %v %v", d, ok)
+	}
+	if d, ok := StaleIfError(CacheControlMap{"stale-if-error": "5"}, respCC); !ok || d != 5*time.Second {
+		t.Errorf("StaleIfError from request expected 5s true, actual %v %v", d, ok)
+	}
+	if _, ok := StaleIfError(CacheControlMap{}, CacheControlMap{}); ok {
+		t.Error("StaleIfError without the directive expected false, actual true")
+	}
+}
+
+func TestCanServeStale(t *testing.T) {
+	if !CanServeStale(CacheControlMap{"max-age": "60", "stale-while-revalidate": "30"}) {
+		t.Error("CanServeStale expected true, actual false")
+	}
+	for _, directive := range []string{"must-revalidate", "proxy-revalidate", "no-cache", "no-store"} {
+		if CanServeStale(CacheControlMap{directive: ""}) {
+			t.Errorf("CanServeStale with %s expected false, actual true", directive)
+		}
+	}
+}
+
+func TestStaleness(t *testing.T) {
+	now := time.Now()
+	date := now.Add(-90 * time.Second)
+	respHdr := http.Header{"Date": {date.Format(time.RFC1123)}}
+	respCC := CacheControlMap{"max-age": "60"}
+
+	staleness := Staleness(respHdr, respCC, date, date)
+	if staleness < 29*time.Second || staleness > 32*time.Second {
+		t.Errorf("Staleness of a 90s old response with max-age 60 expected ~30s, actual %v", staleness)
+	}
+	if staleness := Staleness(respHdr, CacheControlMap{"max-age": "600"}, date, date); staleness >= 0 {
+		t.Errorf("Staleness of a fresh response expected negative, actual %v", staleness)
+	}
+}
@@ -0,0 +1,65 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"time"
+)
+
+// CDNiDelegationRequest is the type of a request to delegate a Delivery
+// Service to a CDNi downstream CDN (dCDN).
+type CDNiDelegationRequest struct {
+	// DCDN is the name of the dCDN, as configured in the "dcdns" of the
+	// "cdni" section of Traffic Ops's configuration.
+	DCDN string `json:"dcdn"`
+	// Host is the hostname by which the dCDN serves the Delivery Service's
+	// content, to which Traffic Router redirects delegated clients.
+	Host string `json:"host"`
+}
+
+// CDNiDelegation is the delegation of a Delivery Service to a CDNi downstream
+// CDN (dCDN).
+type CDNiDelegation struct {
+	DeliveryServiceID int    `json:"deliveryServiceId"`
+	XMLID             string `json:"xmlId"`
+	DCDN              string `json:"dcdn"`
+	Host              string `json:"host"`
+	// Footprints are those the dCDN advertised it can deliver the Delivery
+	// Service's content to, when its configuration was last pushed.
+	Footprints []CDNiFootprint `json:"footprints"`
+	// LastPushed is when the Delivery Service's configuration was last pushed
+	// to the dCDN.
+	LastPushed  *time.Time `json:"lastPushed"`
+	LastUpdated time.Time  `json:"lastUpdated"`
+}
+
+// CDNiFootprint is a set of clients, identified as described by RFC 8006
+// section 4.2.2.2, e.g. by their IPv4 CIDRs or country codes.
+type CDNiFootprint struct {
+	Type  string   `json:"footprint-type"`
+	Value []string `json:"footprint-value"`
+}
+
+// CDNiDelegationResponse is the type of the response of Traffic Ops to
+// requests for, or changes to, the delegation of a Delivery Service.
+type CDNiDelegationResponse struct {
+	Response CDNiDelegation `json:"response"`
+	Alerts
+}
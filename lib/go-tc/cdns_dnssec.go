@@ -42,6 +42,33 @@ const (
 	DNSSECStatusExisting   = "existing"
 )
 
+// DNSSEC key algorithms, as assigned by IANA in
+// https://www.iana.org/assignments/dns-sec-alg-numbers/dns-sec-alg-numbers.xhtml
+const (
+	// DNSSECAlgorithmRSASHA1 is deprecated, but is the default for backwards
+	// compatibility with existing CDNs.
+	DNSSECAlgorithmRSASHA1         = 5
+	DNSSECAlgorithmRSASHA256       = 8
+	DNSSECAlgorithmECDSAP256SHA256 = 13
+	DNSSECAlgorithmECDSAP384SHA384 = 14
+	DNSSECAlgorithmED25519         = 15
+)
+
+// DefaultDNSSECAlgorithm is the algorithm of DNSSEC keys generated for a CDN
+// which has no keys, when no algorithm is requested.
+const DefaultDNSSECAlgorithm = DNSSECAlgorithmRSASHA1
+
+// DNSSECAlgorithms returns the DNSSEC key algorithms Traffic Ops can generate.
+func DNSSECAlgorithms() []interface{} {
+	return []interface{}{
+		uint8(DNSSECAlgorithmRSASHA1),
+		uint8(DNSSECAlgorithmRSASHA256),
+		uint8(DNSSECAlgorithmECDSAP256SHA256),
+		uint8(DNSSECAlgorithmECDSAP384SHA384),
+		uint8(DNSSECAlgorithmED25519),
+	}
+}
+
 // CDNDNSSECKeysResponse is the type of a response from Traffic Ops to GET
 // requests made to its /cdns/name/{{name}}/dnsseckeys API endpoint.
 type CDNDNSSECKeysResponse struct {
@@ -94,6 +121,10 @@ type DNSSECKeySet struct {
 type DNSSECKeySetV11 struct {
 	ZSK []DNSSECKeyV11 `json:"zsk"`
 	KSK []DNSSECKeyV11 `json:"ksk"`
+	// Rollover is the algorithm rollover of a CDN's keys, if one has been
+	// started. It is only set in the key set of the CDN, never of a Delivery
+	// Service.
+	Rollover *DNSSECAlgorithmRollover `json:"rollover,omitempty"`
 }
 
 // A DNSSECKey is a DNSSEC Key (Key-Signing or Zone-Signing) and all associated
@@ -185,6 +216,10 @@ type CDNDNSSECGenerateReq struct {
 	KSKExpirationDays *util.JSONIntStr          `json:"kskExpirationDays"`
 	ZSKExpirationDays *util.JSONIntStr          `json:"zskExpirationDays"`
 	EffectiveDateUnix *CDNDNSSECGenerateReqDate `json:"effectiveDate"`
+	// Algorithm is the DNSSEC key algorithm to generate. If nil, the
+	// algorithm of the CDN's existing keys is used, or
+	// DefaultDNSSECAlgorithm if it has none.
+	Algorithm *uint8 `json:"algorithm,omitempty"`
 }
 
 // Validate implements the
@@ -196,6 +231,7 @@ func (r CDNDNSSECGenerateReq) Validate(tx *sql.Tx) error {
 		"ttl":               validation.Validate(r.TTL, validation.NotNil),
 		"kskExpirationDays": validation.Validate(r.KSKExpirationDays, validation.NotNil),
 		"zskExpirationDays": validation.Validate(r.ZSKExpirationDays, validation.NotNil),
+		"algorithm":         validation.Validate(r.Algorithm, validation.In(DNSSECAlgorithms()...)),
 		// effective date is optional
 	}
 	return util.JoinErrs(tovalidate.ToErrors(validateErrs))
@@ -0,0 +1,156 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc/tovalidate"
+	"github.com/apache/trafficcontrol/lib/go-util"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// The stages of a DNSSEC algorithm rollover, in order. This is the
+// double-signature algorithm rollover of RFC 6781 section 4.1.4.
+const (
+	// DNSSECRolloverStagePublish is the first stage, in which keys of the new
+	// algorithm are published, and every zone is signed with both the old
+	// and new algorithms.
+	DNSSECRolloverStagePublish = "publish"
+	// DNSSECRolloverStageDSUpdate is the stage in which the DS record of the
+	// CDN's new KSK has replaced the old DS record in the parent zone, and
+	// the old DS record is expiring from resolver caches.
+	DNSSECRolloverStageDSUpdate = "ds-update"
+	// DNSSECRolloverStageRetire is the stage in which the DNSKEY records of
+	// the old algorithm's keys are removed, but may still be cached, so the
+	// old algorithm's keys still sign.
+	DNSSECRolloverStageRetire = "retire"
+	// DNSSECRolloverStageRetireSignatures is the stage in which the old
+	// algorithm's keys are expired and no longer sign, but their signatures
+	// may still be cached.
+	DNSSECRolloverStageRetireSignatures = "retire-signatures"
+	// DNSSECRolloverStageComplete is the final stage, in which the old
+	// algorithm's keys have been removed.
+	DNSSECRolloverStageComplete = "complete"
+)
+
+// DNSSECRolloverStages returns the stages of a DNSSEC algorithm rollover, in
+// order.
+func DNSSECRolloverStages() []string {
+	return []string{
+		DNSSECRolloverStagePublish,
+		DNSSECRolloverStageDSUpdate,
+		DNSSECRolloverStageRetire,
+		DNSSECRolloverStageRetireSignatures,
+		DNSSECRolloverStageComplete,
+	}
+}
+
+// NextDNSSECRolloverStage returns the stage which follows the given DNSSEC
+// algorithm rollover stage, or an empty string if there is none.
+func NextDNSSECRolloverStage(stage string) string {
+	stages := DNSSECRolloverStages()
+	for i, st := range stages {
+		if st == stage && i+1 < len(stages) {
+			return stages[i+1]
+		}
+	}
+	return ""
+}
+
+// DNSSECAlgorithmRollover is the state of a CDN's DNSSEC algorithm rollover,
+// as stored in Traffic Vault with the CDN's keys.
+type DNSSECAlgorithmRollover struct {
+	FromAlgorithm uint8  `json:"fromAlgorithm"`
+	ToAlgorithm   uint8  `json:"toAlgorithm"`
+	Stage         string `json:"stage"`
+	// DSTTLSeconds is the TTL of the CDN's DS record in its parent zone,
+	// which is how long the old DS record may be cached after it's replaced.
+	DSTTLSeconds uint64    `json:"dsTTL"`
+	Started      time.Time `json:"started"`
+	StageStarted time.Time `json:"stageStarted"`
+	// NextStageAllowed is the earliest time the rollover may advance to the
+	// next stage, when the records of the previous stage have expired from
+	// resolver caches.
+	NextStageAllowed time.Time `json:"nextStageAllowed"`
+	// LastUpdatedBy is the username of the user who last started or advanced
+	// the rollover.
+	LastUpdatedBy string `json:"lastUpdatedBy"`
+}
+
+// InProgress returns whether the rollover has been started and not completed.
+func (r *DNSSECAlgorithmRollover) InProgress() bool {
+	return r != nil && r.Stage != DNSSECRolloverStageComplete
+}
+
+// DefaultDNSSECRolloverDSTTL is the DS record TTL used for a DNSSEC algorithm
+// rollover, when none is requested. This is the DS TTL of many TLDs.
+const DefaultDNSSECRolloverDSTTL = 86400
+
+// CDNDNSSECRolloverReq is the request to start a DNSSEC algorithm rollover of
+// a CDN, made to the /cdns/{{name}}/dnsseckeys/rollover endpoint.
+type CDNDNSSECRolloverReq struct {
+	// Algorithm is the algorithm to roll over to.
+	Algorithm *uint8 `json:"algorithm"`
+	// DSTTL is the TTL in seconds of the CDN's DS record in its parent zone.
+	// If nil, DefaultDNSSECRolloverDSTTL is used.
+	DSTTL *uint64 `json:"dsTTL"`
+}
+
+// Validate implements the
+// github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api.ParseValidator
+// interface.
+func (r CDNDNSSECRolloverReq) Validate(tx *sql.Tx) error {
+	validateErrs := validation.Errors{
+		"algorithm": validation.Validate(r.Algorithm, validation.NotNil, validation.In(DNSSECAlgorithms()...)),
+	}
+	return util.JoinErrs(tovalidate.ToErrors(validateErrs))
+}
+
+// CDNDNSSECRolloverUpdateReq is the request to advance a CDN's DNSSEC
+// algorithm rollover to its next stage.
+type CDNDNSSECRolloverUpdateReq struct {
+	// Stage is the stage to advance to, which must be the next stage of the
+	// rollover. It is required to guard against advancing twice.
+	Stage *string `json:"stage"`
+}
+
+// Validate implements the
+// github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api.ParseValidator
+// interface.
+func (r CDNDNSSECRolloverUpdateReq) Validate(tx *sql.Tx) error {
+	stages := []interface{}{}
+	for _, stage := range DNSSECRolloverStages() {
+		stages = append(stages, stage)
+	}
+	validateErrs := validation.Errors{
+		"stage": validation.Validate(r.Stage, validation.NotNil, validation.In(stages...)),
+	}
+	return util.JoinErrs(tovalidate.ToErrors(validateErrs))
+}
+
+// CDNDNSSECRolloverResponse is the type of a response from Traffic Ops to
+// requests made to its /cdns/{{name}}/dnsseckeys/rollover endpoint.
+type CDNDNSSECRolloverResponse struct {
+	Response DNSSECAlgorithmRollover `json:"response"`
+	Alerts
+}
@@ -0,0 +1,205 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// ServerConfigFile is a configuration file which a cache server last applied,
+// as reported to Traffic Ops by t3c.
+type ServerConfigFile struct {
+	// Name is the name of the file, e.g. "remap.config".
+	Name string `json:"name" db:"name"`
+	// Path is the directory in which the file was placed.
+	Path string `json:"path" db:"path"`
+	// LineComment is the string which begins a comment line in the file, if
+	// the file format supports comments.
+	LineComment string `json:"lineComment" db:"line_comment"`
+	// Hash is the hex-encoded SHA-256 hash of the file's text, as computed
+	// by ServerConfigFileHash. It is always computed by Traffic Ops for
+	// files with a Text, and must be given by clients for files without one.
+	Hash string `json:"hash" db:"hash"`
+	// Text is the generated text of the file, before t3c preprocessing.
+	// Secure files, e.g. private keys, are reported without their Text.
+	Text *string `json:"text" db:"contents"`
+	// LastUpdated is the time at which the file was reported.
+	LastUpdated *time.Time `json:"lastUpdated,omitempty" db:"last_updated"`
+}
+
+// ServerConfigFilesResponse is the type of a response from Traffic Ops to a
+// request to its /servers/{{host name}}/config_files endpoint.
+type ServerConfigFilesResponse struct {
+	Response []ServerConfigFile `json:"response"`
+	Alerts
+}
+
+// ServerConfigFileHash returns the hash of the given config file text, used to
+// compare files generated at different times. Carriage returns, surrounding
+// whitespace, and comment lines beginning with lineComment are ignored,
+// because generated files contain a header comment with the time they were
+// generated.
+func ServerConfigFileHash(text string, lineComment string) string {
+	sum := sha256.Sum256([]byte(NormalizeServerConfigFileText(text, lineComment)))
+	return hex.EncodeToString(sum[:])
+}
+
+// NormalizeServerConfigFileText returns the given config file text without
+// carriage returns, surrounding whitespace, or comment lines beginning with
+// lineComment. If lineComment is empty, no lines are removed.
+func NormalizeServerConfigFileText(text string, lineComment string) string {
+	text = strings.TrimSpace(strings.Replace(text, "\r\n", "\n", -1))
+	if lineComment == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if strings.HasPrefix(line, lineComment) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// ConfigFilePreviewStatus is how a generated configuration file differs from
+// the one a cache server last applied.
+type ConfigFilePreviewStatus string
+
+const (
+	// ConfigFilePreviewAdded indicates a file would be generated which the
+	// server has not applied.
+	ConfigFilePreviewAdded = ConfigFilePreviewStatus("added")
+	// ConfigFilePreviewRemoved indicates a file the server applied would no
+	// longer be generated.
+	ConfigFilePreviewRemoved = ConfigFilePreviewStatus("removed")
+	// ConfigFilePreviewChanged indicates a file would be generated with
+	// different contents than the server applied.
+	ConfigFilePreviewChanged = ConfigFilePreviewStatus("changed")
+)
+
+// ConfigFilePreview is the difference between a single configuration file as
+// it would be generated for a cache server from the current Traffic Ops data,
+// and the file the server last applied.
+type ConfigFilePreview struct {
+	Name   string                  `json:"name"`
+	Path   string                  `json:"path"`
+	Status ConfigFilePreviewStatus `json:"status"`
+	// Diff is a line diff from the applied to the generated file, with
+	// removed lines prefixed by "-" and added lines prefixed by "+". It is
+	// only given for changed files, and is omitted for secure files and files
+	// whose applied text is unknown.
+	Diff *string `json:"diff,omitempty"`
+}
+
+// ServerConfigPreview is the set of configuration files which would change on
+// a single cache server, if it applied the configuration generated from the
+// current Traffic Ops data.
+type ServerConfigPreview struct {
+	HostName   string `json:"hostName"`
+	ID         int    `json:"id"`
+	Cachegroup string `json:"cachegroup"`
+	// ConfigApplyTime is the last time the server reported applying a
+	// configuration update, if ever.
+	ConfigApplyTime *time.Time `json:"configApplyTime"`
+	// Reported is whether the server has ever reported the configuration files
+	// it applied. If not, every generated file is shown as added.
+	Reported bool                `json:"reported"`
+	Files    []ConfigFilePreview `json:"files"`
+	// Error is set if generating the server's configuration failed.
+	Error string `json:"error,omitempty"`
+}
+
+// CRConfigSectionDiff is the difference between the keys of one section, e.g.
+// "deliveryServices", of two CRConfigs.
+type CRConfigSectionDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+	// Fields is every field which differs between the old and new values of
+	// the Changed keys, sorted by key and then field.
+	Fields []CRConfigFieldDiff `json:"fields,omitempty"`
+}
+
+// CRConfigFieldDiff is a single changed field of the value of a key in a
+// CRConfig section, e.g. the "protocol.acceptHttps" field of the "demo1" key of
+// the "deliveryServices" section.
+type CRConfigFieldDiff struct {
+	// Key is the changed key of the section.
+	Key string `json:"key"`
+	// Field is the path of the changed field within the key's value, with
+	// object properties separated by "." and array elements given as "[i]". It
+	// is empty if the value is not an object or array, or is an array whose
+	// length changed, in which case the whole value is given.
+	Field string `json:"field"`
+	// Old is the JSON of the old value of the field, or null if it had none.
+	Old json.RawMessage `json:"old"`
+	// New is the JSON of the new value of the field, or null if it has none.
+	New json.RawMessage `json:"new"`
+}
+
+// CRConfigDiff is the structural difference between two CRConfigs, by section.
+type CRConfigDiff struct {
+	Config           CRConfigSectionDiff `json:"config"`
+	ContentServers   CRConfigSectionDiff `json:"contentServers"`
+	ContentRouters   CRConfigSectionDiff `json:"contentRouters"`
+	DeliveryServices CRConfigSectionDiff `json:"deliveryServices"`
+	EdgeLocations    CRConfigSectionDiff `json:"edgeLocations"`
+	RouterLocations  CRConfigSectionDiff `json:"trafficRouterLocations"`
+	Monitors         CRConfigSectionDiff `json:"monitors"`
+	Stats            CRConfigSectionDiff `json:"stats"`
+	Topologies       CRConfigSectionDiff `json:"topologies"`
+}
+
+// CRConfigDiffResponse is the type of a response from Traffic Ops to a request
+// to its /cdns/{{name}}/snapshot/diff endpoint.
+type CRConfigDiffResponse struct {
+	Response CRConfigDiff `json:"response"`
+	Alerts
+}
+
+// CDNConfigPreview is a dry run of snapshotting and queuing updates on a CDN:
+// how the CDN's CRConfig and its cache servers' configuration files would
+// change, given the current Traffic Ops data.
+type CDNConfigPreview struct {
+	CDNName string `json:"cdnName"`
+	// SnapshotExists is whether the CDN has a current CRConfig snapshot. If
+	// not, every key of the new CRConfig is shown as added.
+	SnapshotExists bool         `json:"snapshotExists"`
+	CRConfig       CRConfigDiff `json:"crconfig"`
+	// Servers contains every cache server in the preview with at least one
+	// changed configuration file, or which failed to generate.
+	Servers []ServerConfigPreview `json:"servers"`
+	// UnchangedServers is the number of servers in the preview with no
+	// changed configuration files.
+	UnchangedServers int `json:"unchangedServers"`
+}
+
+// CDNConfigPreviewResponse is the type of a response from Traffic Ops to a
+// request to its /cdns/{{name}}/preview endpoint.
+type CDNConfigPreviewResponse struct {
+	Response CDNConfigPreview `json:"response"`
+	Alerts
+}
@@ -135,6 +135,7 @@ type CRConfigTrafficOpsServer struct {
 type CRConfigDeliveryService struct {
 	AnonymousBlockingEnabled  *string                               `json:"anonymousBlockingEnabled,omitempty"`
 	BypassDestination         map[string]*CRConfigBypassDestination `json:"bypassDestination,omitempty"`
+	CDNiDelegation            *CRConfigCDNiDelegation               `json:"cdniDelegation,omitempty"`
 	ConsistentHashQueryParams []string                              `json:"consistentHashQueryParams,omitempty"`
 	ConsistentHashRegex       *string                               `json:"consistentHashRegex,omitempty"`
 	CoverageZoneOnly          bool                                  `json:"coverageZoneOnly,string"`
@@ -193,6 +194,15 @@ type CRConfigStaticDNSEntry struct {
 	Value string `json:"value"`
 }
 
+// CRConfigCDNiDelegation is the delegation of a Delivery Service to a CDNi
+// downstream CDN (dCDN) in a CDN Snapshot, named with "CRConfig" for legacy
+// reasons. Traffic Router redirects clients in the Footprints to Host.
+type CRConfigCDNiDelegation struct {
+	DCDN       string          `json:"dcdn"`
+	Host       string          `json:"host"`
+	Footprints []CDNiFootprint `json:"footprints"`
+}
+
 // CRConfigBypassDestination is a network location to which excess traffic
 // requesting a Delivery Service's content will be directed when the Service's
 // ability to deliver content is deemed to be saturated, named with "CRConfig"
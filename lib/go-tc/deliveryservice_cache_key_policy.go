@@ -0,0 +1,152 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/apache/trafficcontrol/lib/go-tc/tovalidate"
+	"github.com/apache/trafficcontrol/lib/go-util"
+
+	"github.com/go-ozzo/ozzo-validation"
+)
+
+// CacheKeyPolicy is a Delivery Service's policy for how cache servers build
+// the cache key of its content. It's rendered into remap.config as arguments
+// to the Apache Traffic Server cachekey plugin.
+type CacheKeyPolicy struct {
+	// IncludeParams are the query string parameters included in the cache
+	// key. If empty, all parameters not in ExcludeParams are included.
+	IncludeParams []string `json:"includeParams,omitempty"`
+	// ExcludeParams are the query string parameters excluded from the cache
+	// key.
+	ExcludeParams []string `json:"excludeParams,omitempty"`
+	// SortParams is whether query string parameters are sorted in the cache
+	// key, so requests differing only in parameter order share a cache key.
+	SortParams bool `json:"sortParams"`
+	// RemoveAllParams is whether the query string is removed from the cache
+	// key entirely. It may not be combined with IncludeParams, ExcludeParams,
+	// or SortParams.
+	RemoveAllParams bool `json:"removeAllParams"`
+
+	// IncludeHeaders are the request headers whose values are included in
+	// the cache key.
+	IncludeHeaders []string `json:"includeHeaders,omitempty"`
+	// IncludeCookies are the request cookies whose values are included in
+	// the cache key.
+	IncludeCookies []string `json:"includeCookies,omitempty"`
+
+	// UserAgentCapture is a capture definition - a regular expression, or
+	// "/<regex>/<replacement>/" - applied to the User-Agent request header,
+	// whose result is included in the cache key.
+	UserAgentCapture *string `json:"userAgentCapture,omitempty"`
+	// UserAgentClasses are classes of User-Agents, whose matching class name
+	// is included in the cache key.
+	UserAgentClasses []CacheKeyUserAgentClass `json:"userAgentClasses,omitempty"`
+
+	// RemovePath is whether the URI path is removed from the cache key. It
+	// may not be combined with PathCapture.
+	RemovePath bool `json:"removePath"`
+	// PathCapture is a capture definition - a regular expression, or
+	// "/<regex>/<replacement>/" - applied to the URI path, whose result
+	// replaces the path in the cache key.
+	PathCapture *string `json:"pathCapture,omitempty"`
+	// CanonicalPrefix is whether the cache key prefix is the scheme, host,
+	// and port of the request, rather than just the host and port.
+	CanonicalPrefix bool `json:"canonicalPrefix"`
+}
+
+// CacheKeyUserAgentClass is a class of User-Agents, for a CacheKeyPolicy.
+type CacheKeyUserAgentClass struct {
+	// Name is the name of the class, included in the cache key of requests
+	// whose User-Agent is in the class.
+	Name string `json:"name"`
+	// File is the name of the file of User-Agent regular expressions, one per
+	// line, which define the class. It's relative to the Apache Traffic
+	// Server config directory, and must be placed there by operators.
+	File string `json:"file"`
+	// Deny is whether the class is requests whose User-Agent matches none of
+	// the expressions in File, rather than any of them.
+	Deny bool `json:"deny"`
+}
+
+// Validate returns an error if the CacheKeyPolicy is invalid. A nil policy is
+// valid.
+func (p *CacheKeyPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	noSpaces := validation.NewStringRule(tovalidate.NoSpaces, "cannot contain spaces")
+	captureDefinition := validation.NewStringRule(tovalidate.IsValidCaptureDefinition, "must be a valid regular expression, or '/<regex>/<replacement>/'")
+	errs := tovalidate.ToErrors(validation.Errors{
+		"includeParams":    validateCacheKeyNames(p.IncludeParams),
+		"excludeParams":    validateCacheKeyNames(p.ExcludeParams),
+		"includeHeaders":   validateCacheKeyNames(p.IncludeHeaders),
+		"includeCookies":   validateCacheKeyNames(p.IncludeCookies),
+		"userAgentCapture": validation.Validate(p.UserAgentCapture, noSpaces, captureDefinition),
+		"userAgentClasses": validateCacheKeyUserAgentClasses(p.UserAgentClasses),
+		"pathCapture":      validation.Validate(p.PathCapture, noSpaces, captureDefinition),
+	})
+	if p.RemoveAllParams && (len(p.IncludeParams) > 0 || len(p.ExcludeParams) > 0 || p.SortParams) {
+		errs = append(errs, errors.New("removeAllParams cannot be combined with includeParams, excludeParams, or sortParams"))
+	}
+	if p.RemovePath && p.PathCapture != nil {
+		errs = append(errs, errors.New("removePath cannot be combined with pathCapture"))
+	}
+	return util.JoinErrs(errs)
+}
+
+// validateCacheKeyNames returns an error if any of the given names of query
+// parameters, headers, or cookies is empty, duplicated, or can't be written
+// in a cachekey plugin list argument.
+func validateCacheKeyNames(names []string) error {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name == "" {
+			return errors.New("cannot contain empty names")
+		}
+		if !tovalidate.NoSpaces(name) || !tovalidate.NoCommas(name) || !tovalidate.NoLineBreaks(name) {
+			return fmt.Errorf("name '%s' cannot contain spaces, commas, or line breaks", name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate name '%s'", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
+func validateCacheKeyUserAgentClasses(classes []CacheKeyUserAgentClass) error {
+	seen := make(map[string]struct{}, len(classes))
+	for _, class := range classes {
+		if !tovalidate.IsAlphanumericUnderscoreDash(class.Name) {
+			return fmt.Errorf("class name '%s' must be non-empty, and contain only alphanumeric, underscore, or dash characters", class.Name)
+		}
+		if _, ok := seen[class.Name]; ok {
+			return fmt.Errorf("duplicate class name '%s'", class.Name)
+		}
+		seen[class.Name] = struct{}{}
+		if class.File == "" || !tovalidate.NoSpaces(class.File) || !tovalidate.NoLineBreaks(class.File) {
+			return fmt.Errorf("class '%s' file must be non-empty, and cannot contain spaces or line breaks", class.Name)
+		}
+	}
+	return nil
+}
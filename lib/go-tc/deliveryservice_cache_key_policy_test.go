@@ -0,0 +1,69 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-util"
+)
+
+func TestCacheKeyPolicyValidate(t *testing.T) {
+	valid := map[string]*CacheKeyPolicy{
+		"nil":   nil,
+		"empty": {},
+		"full": {
+			IncludeParams:    []string{"a", "b"},
+			ExcludeParams:    []string{"c"},
+			SortParams:       true,
+			IncludeHeaders:   []string{"X-Foo"},
+			IncludeCookies:   []string{"session"},
+			UserAgentCapture: util.StrPtr(`/(Mozilla\/\d).*/$1/`),
+			UserAgentClasses: []CacheKeyUserAgentClass{{Name: "mobile", File: "ua_mobile.config"}},
+			PathCapture:      util.StrPtr(`^/v\d+/`),
+			CanonicalPrefix:  true,
+		},
+		"remove all": {RemoveAllParams: true, RemovePath: true},
+	}
+	for name, policy := range valid {
+		if err := policy.Validate(); err != nil {
+			t.Errorf("expected %s policy to be valid, actual error: %v", name, err)
+		}
+	}
+
+	invalid := map[string]*CacheKeyPolicy{
+		"empty param":          {IncludeParams: []string{""}},
+		"param with comma":     {ExcludeParams: []string{"a,b"}},
+		"duplicate header":     {IncludeHeaders: []string{"X-Foo", "X-Foo"}},
+		"cookie with space":    {IncludeCookies: []string{"a b"}},
+		"bad ua capture":       {UserAgentCapture: util.StrPtr(`/(/$1/`)},
+		"malformed capture":    {PathCapture: util.StrPtr(`/a/b`)},
+		"bad class name":       {UserAgentClasses: []CacheKeyUserAgentClass{{Name: "a:b", File: "f"}}},
+		"missing class file":   {UserAgentClasses: []CacheKeyUserAgentClass{{Name: "a"}}},
+		"duplicate class":      {UserAgentClasses: []CacheKeyUserAgentClass{{Name: "a", File: "f"}, {Name: "a", File: "g"}}},
+		"remove and include":   {RemoveAllParams: true, IncludeParams: []string{"a"}},
+		"remove path and capt": {RemovePath: true, PathCapture: util.StrPtr(`^/a`)},
+	}
+	for name, policy := range invalid {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("expected %s policy to be invalid, actual no error", name)
+		}
+	}
+}
@@ -60,10 +60,17 @@ type DeliveryServicesResponseV40 struct {
 	Alerts
 }
 
+// DeliveryServicesResponseV41 is the type of a response from the
+// /api/4.1/deliveryservices Traffic Ops endpoint.
+type DeliveryServicesResponseV41 struct {
+	Response []DeliveryServiceV41 `json:"response"`
+	Alerts
+}
+
 // DeliveryServicesResponseV4 is the type of a response from the
 // /api/4.x/deliveryservices Traffic Ops endpoint.
 // It always points to the type for the latest minor version of APIv4.
-type DeliveryServicesResponseV4 = DeliveryServicesResponseV40
+type DeliveryServicesResponseV4 = DeliveryServicesResponseV41
 
 // DeliveryServicesNullableResponse roughly models the structure of responses
 // from Traffic Ops to GET requests made to its
@@ -249,9 +256,20 @@ type DeliveryServiceV40 struct {
 	GeoLimitCountries GeoLimitCountriesType `json:"geoLimitCountries"`
 }
 
+// DeliveryServiceV41 is a Delivery Service as it appears in version 4.1 of the
+// Traffic Ops API.
+type DeliveryServiceV41 struct {
+	DeliveryServiceV40
+
+	// CacheKeyPolicy is the policy for how cache servers build the cache key
+	// of the Delivery Service's content. If nil, the cache key is built from
+	// the Delivery Service's qstringIgnore and cachekey Parameters alone.
+	CacheKeyPolicy *CacheKeyPolicy `json:"cacheKeyPolicy"`
+}
+
 // DeliveryServiceV4 is a Delivery Service as it appears in version 4 of the
 // Traffic Ops API - it always points to the highest minor version in APIv4.
-type DeliveryServiceV4 = DeliveryServiceV40
+type DeliveryServiceV4 = DeliveryServiceV41
 
 // These are the TLS Versions known by Apache Traffic Control to exist.
 const (
@@ -306,7 +324,7 @@ func newerTLSVersionsDisallowedMessage(old string, newer []string) string {
 // This does NOT verify that the Delivery Service's TLS versions are _valid_,
 // it ONLY creates warnings based on conditions that are possibly detrimental
 // to CDN operation, but can, in fact, work.
-func (ds DeliveryServiceV4) TLSVersionsAlerts() Alerts {
+func (ds DeliveryServiceV40) TLSVersionsAlerts() Alerts {
 	vers := ds.TLSVersions
 	messages := []string{}
 
@@ -883,14 +901,16 @@ func (ds *DeliveryServiceNullableV30) UpgradeToV4() DeliveryServiceV4 {
 		geo = strings.Split(str, ",")
 	}
 	return DeliveryServiceV4{
-		DeliveryServiceFieldsV31:         ds.DeliveryServiceFieldsV31,
-		DeliveryServiceFieldsV30:         ds.DeliveryServiceFieldsV30,
-		DeliveryServiceFieldsV15:         ds.DeliveryServiceFieldsV15,
-		DeliveryServiceFieldsV14:         ds.DeliveryServiceFieldsV14,
-		DeliveryServiceFieldsV13:         ds.DeliveryServiceFieldsV13,
-		DeliveryServiceNullableFieldsV11: ds.DeliveryServiceNullableFieldsV11,
-		TLSVersions:                      nil,
-		GeoLimitCountries:                geo,
+		DeliveryServiceV40: DeliveryServiceV40{
+			DeliveryServiceFieldsV31:         ds.DeliveryServiceFieldsV31,
+			DeliveryServiceFieldsV30:         ds.DeliveryServiceFieldsV30,
+			DeliveryServiceFieldsV15:         ds.DeliveryServiceFieldsV15,
+			DeliveryServiceFieldsV14:         ds.DeliveryServiceFieldsV14,
+			DeliveryServiceFieldsV13:         ds.DeliveryServiceFieldsV13,
+			DeliveryServiceNullableFieldsV11: ds.DeliveryServiceNullableFieldsV11,
+			TLSVersions:                      nil,
+			GeoLimitCountries:                geo,
+		},
 	}
 }
 
@@ -1141,8 +1161,17 @@ type DeliveryServiceSafeUpdateResponseV40 struct {
 	Response []DeliveryServiceV40 `json:"response"`
 }
 
+// DeliveryServiceSafeUpdateResponseV41 represents Traffic Ops's response to a PUT
+// request to its /api/4.1/deliveryservices/{{ID}}/safe endpoint.
+type DeliveryServiceSafeUpdateResponseV41 struct {
+	Alerts
+	// Response contains the representation of the Delivery Service after it has
+	// been updated.
+	Response []DeliveryServiceV41 `json:"response"`
+}
+
 // DeliveryServiceSafeUpdateResponseV4 represents TrafficOps's response to a
 // PUT request to its /api/4.x/deliveryservices/{{ID}}/safe endpoint.
 // This is always a type alias for the structure of a response in the latest
 // minor APIv4 version.
-type DeliveryServiceSafeUpdateResponseV4 = DeliveryServiceSafeUpdateResponseV40
+type DeliveryServiceSafeUpdateResponseV4 = DeliveryServiceSafeUpdateResponseV41
@@ -0,0 +1,129 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"time"
+)
+
+// RolloutStatus is the state of a staged rollout of queued updates.
+type RolloutStatus string
+
+const (
+	// RolloutStatusRunning is the status of a Rollout whose current wave has
+	// been queued, and which will proceed to its next wave once the current
+	// wave has applied its config and stayed healthy.
+	RolloutStatusRunning = RolloutStatus("running")
+	// RolloutStatusPaused is the status of a Rollout which will not proceed
+	// until it is resumed, either because a user paused it, or because a
+	// server in its current wave failed to apply its config or was marked
+	// unavailable by Traffic Monitor.
+	RolloutStatusPaused = RolloutStatus("paused")
+	// RolloutStatusAborted is the status of a Rollout which was aborted by a
+	// user. Its un-applied updates were dequeued, and it will never proceed.
+	RolloutStatusAborted = RolloutStatus("aborted")
+	// RolloutStatusCompleted is the status of a Rollout all of whose waves
+	// have applied their config and stayed healthy.
+	RolloutStatusCompleted = RolloutStatus("completed")
+)
+
+// RolloutRequest is the type of a request to create a Rollout.
+type RolloutRequest struct {
+	CDNID int `json:"cdnId"`
+	// Topology optionally limits the Rollout to the servers of the CDN in the
+	// Cache Groups of the Topology, like topologies/{name}/queue_update.
+	Topology *string `json:"topology"`
+	// CanaryCacheGroups are the names of the Cache Groups whose servers are
+	// updated in the first, canary, wave.
+	CanaryCacheGroups []string `json:"canaryCacheGroups"`
+	// CanaryPercent is the percentage of the Rollout's servers, rounded up,
+	// which are updated in the canary wave, in addition to those in
+	// CanaryCacheGroups.
+	CanaryPercent int `json:"canaryPercent"`
+	// WavePercent is the percentage of the Rollout's servers, rounded up,
+	// which are updated in each wave after the canary wave. If zero, all
+	// remaining servers are updated in a single wave.
+	WavePercent int `json:"wavePercent"`
+	// SoakTime is the number of seconds each wave must stay healthy in
+	// Traffic Monitor after applying its config before the next wave is
+	// queued.
+	SoakTime int `json:"soakTime"`
+}
+
+// Rollout is a staged rollout of queued updates to the servers of a CDN.
+type Rollout struct {
+	ID                int           `json:"id"`
+	CDNID             int           `json:"cdnId"`
+	CDNName           string        `json:"cdnName"`
+	Topology          *string       `json:"topology"`
+	CanaryCacheGroups []string      `json:"canaryCacheGroups"`
+	CanaryPercent     int           `json:"canaryPercent"`
+	WavePercent       int           `json:"wavePercent"`
+	SoakTime          int           `json:"soakTime"`
+	Status            RolloutStatus `json:"status"`
+	// StatusMessage is the reason the Rollout was last paused, if any.
+	StatusMessage *string `json:"statusMessage"`
+	// CurrentWave is the index of the last wave to be queued, starting from
+	// the canary wave, 0.
+	CurrentWave int `json:"currentWave"`
+	// Waves is the total number of waves, including the canary wave.
+	Waves int `json:"waves"`
+	// WaveQueuedTime is the time CurrentWave was queued.
+	WaveQueuedTime *time.Time `json:"waveQueuedTime"`
+	// WaveAppliedTime is when all servers of CurrentWave were first seen to
+	// have applied their config, or null if they haven't yet. The wave's soak
+	// time starts from this time.
+	WaveAppliedTime *time.Time `json:"waveAppliedTime"`
+	Author          string     `json:"author"`
+	LastUpdated     time.Time  `json:"lastUpdated"`
+	// Servers are the servers of the Rollout. They are only given when
+	// requesting a single Rollout.
+	Servers []RolloutServer `json:"servers,omitempty"`
+}
+
+// RolloutServer is the state of a single server in a Rollout.
+type RolloutServer struct {
+	ServerID   int    `json:"serverId"`
+	HostName   string `json:"hostName"`
+	CacheGroup string `json:"cacheGroup"`
+	Wave       int    `json:"wave"`
+	// QueuedTime is when the server's wave was queued, or null if it has not
+	// been yet.
+	QueuedTime *time.Time `json:"queuedTime"`
+	// Applied is whether the server has applied the config queued for it.
+	Applied bool `json:"applied"`
+	// ApplyFailed is whether the server reported a failure to apply the
+	// config queued for it, and has not applied it since.
+	ApplyFailed bool `json:"applyFailed"`
+}
+
+// RolloutsResponse is the type of the response of Traffic Ops to requests for
+// Rollouts.
+type RolloutsResponse struct {
+	Response []Rollout `json:"response"`
+	Alerts
+}
+
+// RolloutResponse is the type of the response of Traffic Ops to requests for,
+// or changes to, a single Rollout.
+type RolloutResponse struct {
+	Response Rollout `json:"response"`
+	Alerts
+}
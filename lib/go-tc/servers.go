@@ -1229,6 +1229,9 @@ type ServerUpdateStatusV40 struct {
 	ConfigApplyTime      *time.Time `json:"config_apply_time"`
 	RevalidateUpdateTime *time.Time `json:"revalidate_update_time"`
 	RevalidateApplyTime  *time.Time `json:"revalidate_apply_time"`
+	// ConfigApplyFailureTime is the last time the server reported that
+	// applying its config failed and was rolled back, or nil if it never has.
+	ConfigApplyFailureTime *time.Time `json:"config_apply_failure_time"`
 }
 
 // Downgrade strips the Config and Revalidate timestamps from
@@ -0,0 +1,59 @@
+package tc
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SnapshotHistory is a Snapshot of a CDN retained by Traffic Ops, which may be
+// rolled back to.
+type SnapshotHistory struct {
+	ID  int    `json:"id" db:"id"`
+	CDN string `json:"cdn" db:"cdn"`
+	// Author is the username of the user who took the Snapshot, or rolled
+	// back to it.
+	Author string `json:"author" db:"author"`
+	// ChangeLogID is the ID of the change log entry of the Snapshot, if any.
+	ChangeLogID *int `json:"changeLogID" db:"log_id"`
+	// RollbackOf is the ID of the history entry this Snapshot re-published,
+	// if it was made by a rollback and that entry is still retained.
+	RollbackOf  *int      `json:"rollbackOf" db:"rollback_of"`
+	LastUpdated time.Time `json:"lastUpdated" db:"last_updated"`
+	// CRConfig and Monitoring are the contents of the Snapshot. They are only
+	// given when requesting a single history entry.
+	CRConfig   *CRConfig       `json:"crconfig,omitempty"`
+	Monitoring json.RawMessage `json:"monitoring,omitempty"`
+}
+
+// SnapshotHistoryResponse is the type of the response of Traffic Ops to
+// requests for the Snapshot history of a CDN.
+type SnapshotHistoryResponse struct {
+	Response []SnapshotHistory `json:"response"`
+	Alerts
+}
+
+// SnapshotHistoryEntryResponse is the type of the response of Traffic Ops to
+// requests for, or rollbacks to, a single Snapshot history entry.
+type SnapshotHistoryEntryResponse struct {
+	Response SnapshotHistory `json:"response"`
+	Alerts
+}
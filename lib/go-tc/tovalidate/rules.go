@@ -55,6 +55,51 @@ func NoPeriods(str string) bool {
 	return !strings.ContainsAny(str, ".")
 }
 
+// NoCommas returns true if the string has no commas.
+func NoCommas(str string) bool {
+	return !strings.ContainsAny(str, ",")
+}
+
+// IsValidCaptureDefinition returns true if the string is a capture definition
+// as used by Apache Traffic Server plugins, which is either a regular
+// expression, or a capture and replacement of the form
+// "/<regex>/<replacement>/".
+//
+// Note Apache Traffic Server uses PCRE, so this rejects PCRE-only syntax, such
+// as lookaheads, which Go's regular expressions don't support.
+func IsValidCaptureDefinition(str string) bool {
+	if str == "" {
+		return false
+	}
+	regex := str
+	if strings.HasPrefix(str, "/") {
+		parts := splitUnescaped(str, '/')
+		if len(parts) != 4 || parts[3] != "" || parts[1] == "" {
+			return false
+		}
+		regex = parts[1]
+	}
+	_, err := regexp.Compile(regex)
+	return err == nil
+}
+
+// splitUnescaped splits str on each sep not escaped by a preceding backslash.
+func splitUnescaped(str string, sep byte) []string {
+	parts := []string{}
+	start := 0
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' {
+			i++
+			continue
+		}
+		if str[i] == sep {
+			parts = append(parts, str[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, str[start:])
+}
+
 // IsOneOfString generates a validator function returning whether a passed
 // string is in the given set of strings.
 func IsOneOfString(set ...string) func(string) bool {
@@ -500,8 +500,25 @@ type TMParameters struct {
 	HealthPollingURL        string `json:"health.polling.url"`
 	HealthPollingFormat     string `json:"health.polling.format"`
 	HealthPollingType       string `json:"health.polling.type"`
-	HistoryCount            int    `json:"history.count"`
-	MinFreeKbps             int64
+	// HealthPollingTLSCA is the path on the Traffic Monitor host to a PEM
+	// bundle of the CAs used to verify the certificates of caches polled over
+	// HTTPS.
+	HealthPollingTLSCA string `json:"health.polling.tls.ca"`
+	// HealthPollingTLSCert and HealthPollingTLSKey are the paths on the
+	// Traffic Monitor host to the PEM certificate and key Traffic Monitor
+	// presents to caches polled over HTTPS, for mutual TLS.
+	HealthPollingTLSCert string `json:"health.polling.tls.cert"`
+	HealthPollingTLSKey  string `json:"health.polling.tls.key"`
+	// HealthPollingTLSServerName is the TLS server name (SNI) sent to caches
+	// polled over HTTPS, and used to verify their certificates, overriding
+	// their FQDN.
+	HealthPollingTLSServerName string `json:"health.polling.tls.servername"`
+	// HealthPollingHTTP2 is whether to use HTTP/2 when polling caches over
+	// HTTPS, "true" or "false", or empty to use the Traffic Monitor's
+	// configured default.
+	HealthPollingHTTP2 string `json:"health.polling.http2"`
+	HistoryCount       int    `json:"history.count"`
+	MinFreeKbps        int64
 	// HealthThresholdJSONParameters contains the Parameters contained in the
 	// Thresholds field, formatted as individual string Parameters, rather than as
 	// a JSON object.
@@ -607,6 +624,48 @@ func (params *TMParameters) UnmarshalJSON(bytes []byte) (err error) {
 		}
 	}
 
+	if vi, ok := raw["health.polling.tls.ca"]; ok {
+		if v, ok := vi.(string); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.polling.tls.ca expected string, got %v", vi)
+		} else {
+			params.HealthPollingTLSCA = v
+		}
+	}
+
+	if vi, ok := raw["health.polling.tls.cert"]; ok {
+		if v, ok := vi.(string); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.polling.tls.cert expected string, got %v", vi)
+		} else {
+			params.HealthPollingTLSCert = v
+		}
+	}
+
+	if vi, ok := raw["health.polling.tls.key"]; ok {
+		if v, ok := vi.(string); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.polling.tls.key expected string, got %v", vi)
+		} else {
+			params.HealthPollingTLSKey = v
+		}
+	}
+
+	if vi, ok := raw["health.polling.tls.servername"]; ok {
+		if v, ok := vi.(string); !ok {
+			return fmt.Errorf("Unmarshalling TMParameters health.polling.tls.servername expected string, got %v", vi)
+		} else {
+			params.HealthPollingTLSServerName = v
+		}
+	}
+
+	if vi, ok := raw["health.polling.http2"]; ok {
+		if v, ok := vi.(bool); ok {
+			params.HealthPollingHTTP2 = strconv.FormatBool(v)
+		} else if v, ok := vi.(string); ok {
+			params.HealthPollingHTTP2 = v
+		} else {
+			return fmt.Errorf("Unmarshalling TMParameters health.polling.http2 expected boolean or string, got %v", vi)
+		}
+	}
+
 	if vi, ok := raw["history.count"]; ok {
 		if v, ok := vi.(float64); !ok {
 			return fmt.Errorf("Unmarshalling TMParameters history.count expected integer, got %v", vi)
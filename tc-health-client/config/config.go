@@ -44,6 +44,7 @@ import (
 var userAgent = "tc-health-client/1.0"
 var tmPollingInterval time.Duration
 var toRequestTimeout time.Duration
+var probeTimeout time.Duration
 var toSession *toclient.Session = nil
 
 const (
@@ -56,6 +57,31 @@ const (
 	DefaultTrafficServerBinDir      = "/opt/trafficserver/bin"
 	DefaultUnavailablePollThreshold = 2
 	DefaultMarkupPollThreshold      = 1
+	DefaultProbeType                = ProbeTypeHTTP
+	DefaultProbePolicy              = ProbePolicyTMAndProbe
+	DefaultProbeHTTPPath            = "/"
+	DefaultProbeTimeout             = 2 * time.Second
+)
+
+// the active probe types used to check a parent's health directly
+// from this host.
+const (
+	ProbeTypeHTTP = "http"
+	ProbeTypeTCP  = "tcp"
+)
+
+// the policies used to combine the Traffic Monitor availability of a
+// parent with the availability found by an active probe.
+const (
+	// a parent is available only if both Traffic Monitor and the probe
+	// report it available.
+	ProbePolicyTMAndProbe = "tm-and-probe"
+	// a parent is available if either Traffic Monitor or the probe
+	// report it available.
+	ProbePolicyTMOrProbe = "tm-or-probe"
+	// a parent's availability is decided by the probe alone, Traffic
+	// Monitor availability is used only for parents that can't be probed.
+	ProbePolicyProbeOnly = "probe-only"
 )
 
 type Cfg struct {
@@ -76,6 +102,11 @@ type Cfg struct {
 	TrafficServerBinDir      string          `json:"trafficserver-bin-dir"`
 	PollStateJSONLog         string          `json:"poll-state-json-log"`
 	EnablePollStateLog       bool            `json:"enable-poll-state-log"`
+	EnableActiveProbes       bool            `json:"enable-active-probes"`
+	ProbeType                string          `json:"probe-type"`
+	ProbePolicy              string          `json:"probe-policy"`
+	ProbeHTTPPath            string          `json:"probe-http-path"`
+	ProbeTimeoutSeconds      string          `json:"probe-timeout-seconds"`
 	TrafficMonitors          map[string]bool `json:"trafficmonitors,omitempty"`
 	HealthClientConfigFile   util.ConfigFile
 	CredentialFile           util.ConfigFile
@@ -284,6 +315,10 @@ func GetRequestTimeout() time.Duration {
 	return toRequestTimeout
 }
 
+func GetProbeTimeout() time.Duration {
+	return probeTimeout
+}
+
 func LoadConfig(cfg *Cfg) (bool, error) {
 	updated := false
 	configFile := cfg.HealthClientConfigFile.Filename
@@ -328,6 +363,29 @@ func LoadConfig(cfg *Cfg) (bool, error) {
 		if cfg.PollStateJSONLog == "" {
 			cfg.PollStateJSONLog = DefaultPollStateJSONLog
 		}
+		if cfg.ProbeType == "" {
+			cfg.ProbeType = DefaultProbeType
+		}
+		if cfg.ProbeType != ProbeTypeHTTP && cfg.ProbeType != ProbeTypeTCP {
+			return updated, errors.New("invalid probe-type: " + cfg.ProbeType + ", valid probe types are '" + ProbeTypeHTTP + "' or '" + ProbeTypeTCP + "'")
+		}
+		if cfg.ProbePolicy == "" {
+			cfg.ProbePolicy = DefaultProbePolicy
+		}
+		if cfg.ProbePolicy != ProbePolicyTMAndProbe && cfg.ProbePolicy != ProbePolicyTMOrProbe && cfg.ProbePolicy != ProbePolicyProbeOnly {
+			return updated, errors.New("invalid probe-policy: " + cfg.ProbePolicy + ", valid probe policies are '" + ProbePolicyTMAndProbe + "', '" + ProbePolicyTMOrProbe + "' or '" + ProbePolicyProbeOnly + "'")
+		}
+		if cfg.ProbeHTTPPath == "" {
+			cfg.ProbeHTTPPath = DefaultProbeHTTPPath
+		}
+		if !strings.HasPrefix(cfg.ProbeHTTPPath, "/") {
+			cfg.ProbeHTTPPath = "/" + cfg.ProbeHTTPPath
+		}
+		if cfg.ProbeTimeoutSeconds == "" {
+			probeTimeout = DefaultProbeTimeout
+		} else if probeTimeout, err = time.ParseDuration(cfg.ProbeTimeoutSeconds); err != nil {
+			return updated, errors.New("parsing ProbeTimeoutSeconds: " + err.Error())
+		}
 
 		cfg.HealthClientConfigFile.LastModifyTime = modTime
 
@@ -376,6 +434,11 @@ func UpdateConfig(cfg *Cfg, newCfg *Cfg) {
 	cfg.HealthClientConfigFile = newCfg.HealthClientConfigFile
 	cfg.PollStateJSONLog = newCfg.PollStateJSONLog
 	cfg.EnablePollStateLog = newCfg.EnablePollStateLog
+	cfg.EnableActiveProbes = newCfg.EnableActiveProbes
+	cfg.ProbeType = newCfg.ProbeType
+	cfg.ProbePolicy = newCfg.ProbePolicy
+	cfg.ProbeHTTPPath = newCfg.ProbeHTTPPath
+	cfg.ProbeTimeoutSeconds = newCfg.ProbeTimeoutSeconds
 }
 
 func Usage() {
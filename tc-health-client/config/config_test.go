@@ -113,6 +113,24 @@ func TestLoadConfig(t *testing.T) {
 	if bindir != expect {
 		t.Fatalf("expected '%s', got %s\n", expect, bindir)
 	}
+
+	expect = ProbeTypeHTTP
+	probeType := cfg.ProbeType
+	if probeType != expect {
+		t.Fatalf("expected '%s', got %s\n", expect, probeType)
+	}
+
+	expect = ProbePolicyTMAndProbe
+	probePolicy := cfg.ProbePolicy
+	if probePolicy != expect {
+		t.Fatalf("expected '%s', got %s\n", expect, probePolicy)
+	}
+
+	expectd := DefaultProbeTimeout
+	probeTimeout := GetProbeTimeout()
+	if probeTimeout != expectd {
+		t.Fatalf("expected '%v', got %v\n", expectd, probeTimeout)
+	}
 }
 
 func TestGetCredentialsFromFile(t *testing.T) {
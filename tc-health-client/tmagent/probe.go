@@ -0,0 +1,174 @@
+package tmagent
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/tc-health-client/config"
+)
+
+// the sources that may report a parent unavailable, recorded in a
+// parents MarkdownSource so that markdowns from this hosts own probes
+// can be told apart from Traffic Monitor markdowns.
+const (
+	MarkdownSourceTM         = "tm"
+	MarkdownSourceProbe      = "probe"
+	MarkdownSourceTMAndProbe = "tm+probe"
+)
+
+// the address used to actively probe a parent, from the parent's
+// 'parent.config' or 'strategies.yaml' entry.
+type ProbeTarget struct {
+	Fqdn           string
+	Port           int
+	Scheme         string
+	HealthCheckURL string
+}
+
+// the URL used for an HTTP probe of the target.  the 'strategies.yaml'
+// health check url is used if there is one.
+func (t ProbeTarget) url(path string) string {
+	if t.HealthCheckURL != "" {
+		return t.HealthCheckURL
+	}
+	scheme := t.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + net.JoinHostPort(t.Fqdn, strconv.Itoa(t.Port)) + path
+}
+
+// adds or updates the probe target of a parent.
+func (c *ParentInfo) addProbeTarget(hostName string, target ProbeTarget) {
+	if c.ProbeTargets == nil {
+		c.ProbeTargets = make(map[string]ProbeTarget)
+	}
+	c.ProbeTargets[hostName] = target
+}
+
+// probes every parent that has a probe target concurrently, and
+// returns the probe availability of each keyed by the parent hostName.
+// parents that could not be probed are not in the returned map.
+func (c *ParentInfo) probeParents() map[string]bool {
+	timeout := config.GetProbeTimeout()
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// skipping verification is acceptable here, probes only check
+			// that the parent answers, and nothing from the response is
+			// trusted or used but its status.  parents rarely have a
+			// certificate for their own hostname, so verifying would mark
+			// healthy parents down.
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			// each probe opens a new connection, so that it tests the parent
+			// is still accepting them, and no idle connections are left open
+			// between polls.
+			DisableKeepAlives: true,
+		},
+		// a redirect is an answer, do not follow it.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	results := make(map[string]bool)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for hostName, _ := range c.Parents {
+		target, ok := c.ProbeTargets[hostName]
+		if !ok || target.Port == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(hostName string, target ProbeTarget) {
+			defer wg.Done()
+			var err error
+			switch c.Cfg.ProbeType {
+			case config.ProbeTypeTCP:
+				err = probeTCP(target, timeout)
+			default:
+				err = probeHTTP(client, target.url(c.Cfg.ProbeHTTPPath))
+			}
+			if err != nil {
+				log.Infof("%s probe of %s failed: %s\n", c.Cfg.ProbeType, hostName, err.Error())
+			}
+			mutex.Lock()
+			results[hostName] = err == nil
+			mutex.Unlock()
+		}(hostName, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// a parent is available to a TCP probe if it accepts a connection.
+func probeTCP(target ProbeTarget, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(target.Fqdn, strconv.Itoa(target.Port)), timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// a parent is available to an HTTP probe if it answers a HEAD request
+// with any status other than a server error.
+func probeHTTP(client *http.Client, url string) error {
+	resp, err := client.Head(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.New(url + " returned " + resp.Status)
+	}
+	return nil
+}
+
+// combines the Traffic Monitor availability of a parent with its probe
+// availability according to the probe policy.  returns the combined
+// availability and, when unavailable, the markdown source.
+func combineAvailability(policy string, tmAvailable bool, probeAvailable bool) (bool, string) {
+	available := tmAvailable && probeAvailable
+	switch policy {
+	case config.ProbePolicyTMOrProbe:
+		available = tmAvailable || probeAvailable
+	case config.ProbePolicyProbeOnly:
+		available = probeAvailable
+	}
+	if available {
+		return true, ""
+	}
+
+	if !tmAvailable && !probeAvailable && policy != config.ProbePolicyProbeOnly {
+		return false, MarkdownSourceTMAndProbe
+	} else if !probeAvailable {
+		return false, MarkdownSourceProbe
+	}
+	return false, MarkdownSourceTM
+}
@@ -0,0 +1,149 @@
+package tmagent
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/apache/trafficcontrol/tc-health-client/config"
+	"github.com/apache/trafficcontrol/tc-health-client/util"
+)
+
+func TestCombineAvailability(t *testing.T) {
+	tests := []struct {
+		policy         string
+		tmAvailable    bool
+		probeAvailable bool
+		available      bool
+		source         string
+	}{
+		{config.ProbePolicyTMAndProbe, true, true, true, ""},
+		{config.ProbePolicyTMAndProbe, false, true, false, MarkdownSourceTM},
+		{config.ProbePolicyTMAndProbe, true, false, false, MarkdownSourceProbe},
+		{config.ProbePolicyTMAndProbe, false, false, false, MarkdownSourceTMAndProbe},
+		{config.ProbePolicyTMOrProbe, false, true, true, ""},
+		{config.ProbePolicyTMOrProbe, true, false, true, ""},
+		{config.ProbePolicyTMOrProbe, false, false, false, MarkdownSourceTMAndProbe},
+		{config.ProbePolicyProbeOnly, false, true, true, ""},
+		{config.ProbePolicyProbeOnly, true, false, false, MarkdownSourceProbe},
+		{config.ProbePolicyProbeOnly, false, false, false, MarkdownSourceProbe},
+	}
+	for _, test := range tests {
+		available, source := combineAvailability(test.policy, test.tmAvailable, test.probeAvailable)
+		if available != test.available || source != test.source {
+			t.Errorf("%s with tm %v and probe %v: expected %v '%s', got %v '%s'",
+				test.policy, test.tmAvailable, test.probeAvailable, test.available, test.source, available, source)
+		}
+	}
+}
+
+func TestReadProbeTargets(t *testing.T) {
+	pi := ParentInfo{
+		ParentDotConfig:   util.ConfigFile{Filename: "test_files/etc/parent.config"},
+		StrategiesDotYaml: util.ConfigFile{Filename: "test_files/etc/strategies.yaml"},
+	}
+
+	parentStatus := make(map[string]ParentStatus)
+	if err := pi.readParentConfig(parentStatus); err != nil {
+		t.Fatalf("failed readParentConfig(): %s\n", err.Error())
+	}
+	if err := pi.readStrategies(parentStatus); err != nil {
+		t.Fatalf("failed readStrategies(): %s\n", err.Error())
+	}
+
+	if len(pi.ProbeTargets) != len(parentStatus) {
+		t.Fatalf("expected a probe target for each of %d parents, got %d\n", len(parentStatus), len(pi.ProbeTargets))
+	}
+
+	target := pi.ProbeTargets["cdn-mid-01"]
+	if target.Fqdn != "cdn-mid-01.bar.net" || target.Port != 80 {
+		t.Errorf("expected parent.config probe target cdn-mid-01.bar.net:80, got %+v\n", target)
+	}
+	expect := "http://cdn-mid-01.bar.net:80/health"
+	if url := target.url("/health"); url != expect {
+		t.Errorf("expected probe url '%s', got '%s'\n", expect, url)
+	}
+
+	target = pi.ProbeTargets["edge-01"]
+	expect = "http://192.168.1.19:80"
+	if url := target.url("/health"); url != expect {
+		t.Errorf("expected strategies health check url '%s', got '%s'\n", expect, url)
+	}
+}
+
+func TestProbeParents(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer up.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	// a port with nothing listening.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %s\n", err.Error())
+	}
+	closedPort := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	pi := ParentInfo{
+		Parents: map[string]ParentStatus{
+			"up":       {Fqdn: "up"},
+			"failing":  {Fqdn: "failing"},
+			"down":     {Fqdn: "down"},
+			"unprobed": {Fqdn: "unprobed"},
+		},
+		Cfg: config.Cfg{ProbeType: config.ProbeTypeHTTP, ProbeHTTPPath: "/"},
+	}
+	pi.addProbeTarget("up", serverTarget(up))
+	pi.addProbeTarget("failing", serverTarget(failing))
+	pi.addProbeTarget("down", ProbeTarget{Fqdn: "127.0.0.1", Port: closedPort})
+
+	probes := pi.probeParents()
+	expected := map[string]bool{"up": true, "failing": false, "down": false}
+	if len(probes) != len(expected) {
+		t.Fatalf("expected %d probe results, got %v\n", len(expected), probes)
+	}
+	for hostName, available := range expected {
+		if probes[hostName] != available {
+			t.Errorf("expected http probe of %s to be %v, got %v\n", hostName, available, probes[hostName])
+		}
+	}
+
+	pi.Cfg.ProbeType = config.ProbeTypeTCP
+	probes = pi.probeParents()
+	expected = map[string]bool{"up": true, "failing": true, "down": false}
+	for hostName, available := range expected {
+		if probes[hostName] != available {
+			t.Errorf("expected tcp probe of %s to be %v, got %v\n", hostName, available, probes[hostName])
+		}
+	}
+}
+
+func serverTarget(srv *httptest.Server) ProbeTarget {
+	addr := srv.Listener.Addr().(*net.TCPAddr)
+	return ProbeTarget{Fqdn: addr.IP.String(), Port: addr.Port, Scheme: "http"}
+}
@@ -32,6 +32,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -70,6 +71,7 @@ type ParentInfo struct {
 	TrafficServerBinDir    string
 	TrafficServerConfigDir string
 	Parents                map[string]ParentStatus
+	ProbeTargets           map[string]ProbeTarget
 	Cfg                    config.Cfg
 }
 
@@ -94,6 +96,9 @@ type ParentStatus struct {
 	LastTmPoll           int64
 	UnavailablePollCount int
 	MarkUpPollCount      int
+	LastProbe            int64
+	ProbeAvailable       bool
+	MarkdownSource       string
 }
 
 // used to get the overall parent availablity from the
@@ -349,26 +354,42 @@ func (c *ParentInfo) PollAndUpdateCacheStatus() {
 			continue
 		}
 
+		// actively probe the parents from this host if enabled.
+		var probes map[string]bool
+		if c.Cfg.EnableActiveProbes {
+			probes = c.probeParents()
+		}
+
 		for k, v := range caches {
 			hostName := string(k)
 			cs, ok := c.Parents[hostName]
 			if ok {
 				// update the polling time
 				cs.LastTmPoll = now
+				available := v.IsAvailable
+				source := MarkdownSourceTM
+				if probeAvailable, probed := probes[hostName]; probed {
+					cs.LastProbe = now
+					cs.ProbeAvailable = probeAvailable
+					available, source = combineAvailability(c.Cfg.ProbePolicy, v.IsAvailable, probeAvailable)
+				}
+				// keep the markdown source current while the parent remains down.
+				if !available && !cs.available(c.Cfg.ReasonCode) && cs.MarkdownSource != "" {
+					cs.MarkdownSource = source
+				}
 				c.Parents[hostName] = cs
-				tmAvailable := v.IsAvailable
-				if cs.available(c.Cfg.ReasonCode) != tmAvailable {
+				if cs.available(c.Cfg.ReasonCode) != available {
 					// do not mark down if the configuration disables mark downs.
-					if !c.Cfg.EnableActiveMarkdowns && !tmAvailable {
-						log.Infof("TM reports that %s is not available and should be marked DOWN but, mark downs are disabled by configuration", hostName)
+					if !c.Cfg.EnableActiveMarkdowns && !available {
+						log.Infof("%s reports that %s is not available and should be marked DOWN but, mark downs are disabled by configuration", source, hostName)
 					} else {
-						if err = c.markParent(cs.Fqdn, v.Status, tmAvailable); err != nil {
+						if err = c.markParent(cs.Fqdn, v.Status, available, source); err != nil {
 							log.Errorln(err.Error())
 						}
 					}
 				}
 				// if the host is available clear the unavailable poll count if not 0.
-				if cs.available(c.Cfg.ReasonCode) && tmAvailable {
+				if cs.available(c.Cfg.ReasonCode) && available {
 					if cs.UnavailablePollCount > 0 {
 						log.Debugf("resetting the UnavailablePollCount for %s from %d to 0",
 							hostName, cs.UnavailablePollCount)
@@ -527,8 +548,9 @@ func (c *ParentInfo) execTrafficCtl(fqdn string, available bool) error {
 }
 
 // used to mark a parent as up or down in the trafficserver HostStatus
-// subsystem.
-func (c *ParentInfo) markParent(fqdn string, cacheStatus string, available bool) error {
+// subsystem.  source is the markdown source that reports the parent
+// unavailable, it is recorded when the parent is marked down.
+func (c *ParentInfo) markParent(fqdn string, cacheStatus string, available bool, source string) error {
 	var hostAvailable bool
 	var err error
 	hostName := parseFqdn(fqdn)
@@ -547,7 +569,7 @@ func (c *ParentInfo) markParent(fqdn string, cacheStatus string, available bool)
 		if !available { // unavailable
 			unavailablePollCount += 1
 			if unavailablePollCount < c.Cfg.UnavailablePollThreshold {
-				log.Infof("TM indicates %s is unavailable but the UnavailablePollThreshold has not been reached", hostName)
+				log.Infof("%s indicates %s is unavailable but the UnavailablePollThreshold has not been reached", source, hostName)
 				hostAvailable = true
 			} else {
 				// marking the host down
@@ -559,14 +581,14 @@ func (c *ParentInfo) markParent(fqdn string, cacheStatus string, available bool)
 					// reset the poll counts
 					markUpPollCount = 0
 					unavailablePollCount = 0
-					log.Infof("marked parent %s DOWN, cache status was: %s\n", hostName, cacheStatus)
+					log.Infof("marked parent %s DOWN by %s, cache status was: %s\n", hostName, source, cacheStatus)
 				}
 			}
 		} else { // available
 			// marking the host up
 			markUpPollCount += 1
 			if markUpPollCount < c.Cfg.MarkUpPollThreshold {
-				log.Infof("TM indicates %s is available but the MarkUpPollThreshold has not been reached", hostName)
+				log.Infof("%s is available but the MarkUpPollThreshold has not been reached", hostName)
 				hostAvailable = false
 			} else {
 				err = c.execTrafficCtl(fqdn, available)
@@ -596,6 +618,11 @@ func (c *ParentInfo) markParent(fqdn string, cacheStatus string, available bool)
 			pv.LocalReason = localReason
 			pv.UnavailablePollCount = unavailablePollCount
 			pv.MarkUpPollCount = markUpPollCount
+			if hostAvailable {
+				pv.MarkdownSource = ""
+			} else {
+				pv.MarkdownSource = source
+			}
 			c.Parents[hostName] = pv
 			log.Debugf("Updated parent status: %v", pv)
 		}
@@ -706,6 +733,9 @@ func (c *ParentInfo) readHostStatus(parentStatus map[string]ParentStatus) error
 						pstat.LastTmPoll = pv.LastTmPoll
 						pstat.UnavailablePollCount = pv.UnavailablePollCount
 						pstat.MarkUpPollCount = pv.MarkUpPollCount
+						pstat.LastProbe = pv.LastProbe
+						pstat.ProbeAvailable = pv.ProbeAvailable
+						pstat.MarkdownSource = pv.MarkdownSource
 						parentStatus[hostName] = pstat
 					}
 				}
@@ -768,6 +798,11 @@ func (c *ParentInfo) readParentConfig(parentStatus map[string]ParentStatus) erro
 					if len(parent) == 2 {
 						fqdn := parent[0]
 						hostName := parseFqdn(fqdn)
+						if _, ok := c.ProbeTargets[hostName]; !ok {
+							// the port may be followed by '|weight'.
+							port, _ := strconv.Atoi(strings.TrimSpace(strings.Split(parent[1], "|")[0]))
+							c.addProbeTarget(hostName, ProbeTarget{Fqdn: strings.TrimSpace(fqdn), Port: port})
+						}
 						_, ok := parentStatus[hostName]
 						// create the ParentStatus struct and add it to the
 						// Parents map only if an entry in the map does not
@@ -858,6 +893,17 @@ func (c *ParentInfo) readStrategies(parentStatus map[string]ParentStatus) error
 	for _, host := range strategies.Hosts {
 		fqdn := host.HostName
 		hostName := parseFqdn(fqdn)
+		// the first protocol is used to probe the parent, strategies
+		// targets replace any from 'parent.config' as they may have a
+		// health check url.
+		if len(host.Protocols) > 0 {
+			c.addProbeTarget(hostName, ProbeTarget{
+				Fqdn:           strings.TrimSpace(fqdn),
+				Port:           host.Protocols[0].Port,
+				Scheme:         host.Protocols[0].Scheme,
+				HealthCheckURL: host.Protocols[0].Health_check_url,
+			})
+		}
 		// create the ParentStatus struct and add it to the
 		// Parents map only if an entry in the map does not
 		// already exist.
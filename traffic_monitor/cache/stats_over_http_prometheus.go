@@ -0,0 +1,370 @@
+package cache
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/traffic_monitor/poller"
+	"github.com/apache/trafficcontrol/traffic_monitor/todata"
+)
+
+// The stats_over_http plugin of newer ATS versions can emit its statistics in
+// the Prometheus text exposition format. Metric names are the ATS stat names
+// with every character not allowed by Prometheus (notably '.' and '-')
+// replaced by '_', e.g. "plugin_system_stats_loadavg_one".
+//
+// Because a remap_stats host can't be recovered from a sanitized metric name,
+// remap stats must carry their host as a label, e.g.
+// `plugin_remap_stats_in_bytes{host="edge.ds.example.net"} 1234`. They are
+// returned in the miscellaneous stats under the same names stats_over_http
+// uses, e.g. "plugin.remap_stats.edge.ds.example.net.in_bytes".
+
+const promRemapStatPrefix = "plugin_remap_stats_"
+const promRemapStatHostLabel = "host"
+const promNetStatPrefix = "plugin_system_stats_net_"
+
+func init() {
+	registerDecoder("stats_over_http_prometheus", statsOverHTTPPrometheusParse, statsOverHTTPPrometheusPrecompute)
+}
+
+func statsOverHTTPPrometheusParse(cacheName string, data io.Reader, pollCTX interface{}) (Statistics, map[string]interface{}, error) {
+	var stats Statistics
+	if data == nil {
+		log.Warnf("Cannot read stats data for cache '%s' - nil data reader", cacheName)
+		return stats, nil, errors.New("handler got nil reader")
+	}
+
+	ctx := pollCTX.(*poller.HTTPPollCtx)
+	if ctype := ctx.HTTPHeader.Get("Content-Type"); ctype != "" {
+		mediaType, _, err := mime.ParseMediaType(ctype)
+		if err != nil || (mediaType != "text/plain" && mediaType != "application/openmetrics-text") {
+			return stats, nil, fmt.Errorf("stats Content-Type (%s) can not be parsed by statsOverHTTPPrometheus", ctype)
+		}
+	}
+
+	statMap, err := statsOverHTTPPrometheusParseText(data)
+	if err != nil {
+		return stats, nil, err
+	}
+
+	if stats.Loadavg, err = parsePrometheusLoadAvg(statMap); err != nil {
+		return stats, nil, fmt.Errorf("Error parsing loadavg for cache '%s': %v", cacheName, err)
+	}
+
+	stats.Interfaces = parsePrometheusInterfaces(statMap)
+	if len(stats.Interfaces) < 1 {
+		return stats, nil, fmt.Errorf("cache '%s' had no interfaces", cacheName)
+	}
+
+	return stats, statMap, nil
+}
+
+// statsOverHTTPPrometheusParseText parses the samples of a Prometheus text
+// exposition into a map of stat names to their float64 values. Comments, and
+// samples which can't be parsed, are skipped. Sample timestamps are ignored.
+func statsOverHTTPPrometheusParseText(data io.Reader) (map[string]interface{}, error) {
+	statMap := map[string]interface{}{}
+	scanner := bufio.NewScanner(data)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		name, value, err := parsePrometheusSample(line)
+		if err != nil {
+			log.Debugf("skipping invalid stats_over_http Prometheus sample '%s': %v", line, err)
+			continue
+		}
+		statMap[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading stats_over_http Prometheus payload: %v", err)
+	}
+	if len(statMap) < 1 {
+		return nil, errors.New("no valid data found in stats_over_http payload with Prometheus format")
+	}
+	return statMap, nil
+}
+
+// parsePrometheusSample parses a single sample line, and returns the name
+// under which it should be stored, and its value.
+func parsePrometheusSample(line string) (string, float64, error) {
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd < 1 {
+		return "", 0, errors.New("sample has no value")
+	}
+	name := line[:nameEnd]
+	rest := line[nameEnd:]
+
+	labels := map[string]string{}
+	rawLabels := ""
+	if rest[0] == '{' {
+		labelsEnd := 0
+		var err error
+		if labels, labelsEnd, err = parsePrometheusLabels(rest); err != nil {
+			return "", 0, err
+		}
+		rawLabels = rest[:labelsEnd]
+		rest = rest[labelsEnd:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) < 1 {
+		return "", 0, errors.New("sample has no value")
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("parsing value: %v", err)
+	}
+
+	if host, ok := labels[promRemapStatHostLabel]; ok && strings.HasPrefix(name, promRemapStatPrefix) {
+		return "plugin.remap_stats." + host + "." + strings.TrimPrefix(name, promRemapStatPrefix), value, nil
+	}
+	return name + rawLabels, value, nil
+}
+
+// parsePrometheusLabels parses the label set at the start of s, which must
+// begin with '{'. It returns the labels, and the index in s just past the
+// closing '}'.
+func parsePrometheusLabels(s string) (map[string]string, int, error) {
+	labels := map[string]string{}
+	i := 1
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == ',') {
+			i++
+		}
+		if i >= len(s) {
+			return nil, 0, errors.New("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		eq := strings.IndexByte(s[i:], '=')
+		if eq < 0 {
+			return nil, 0, errors.New("label has no value")
+		}
+		labelName := strings.TrimSpace(s[i : i+eq])
+		i += eq + 1
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("value of label '%s' is not quoted", labelName)
+		}
+		i++
+
+		labelValue := strings.Builder{}
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] != '\\' || i+1 >= len(s) {
+				labelValue.WriteByte(s[i])
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				labelValue.WriteByte('\n')
+			default:
+				labelValue.WriteByte(s[i])
+			}
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("value of label '%s' is unterminated", labelName)
+		}
+		i++
+		labels[labelName] = labelValue.String()
+	}
+}
+
+func parsePrometheusLoadAvg(stats map[string]interface{}) (Loadavg, error) {
+	var load Loadavg
+	loadStats := []struct {
+		name string
+		val  *float64
+	}{
+		{name: "plugin_system_stats_loadavg_one", val: &load.One},
+		{name: "plugin_system_stats_loadavg_five", val: &load.Five},
+		{name: "plugin_system_stats_loadavg_fifteen", val: &load.Fifteen},
+	}
+	for _, loadStat := range loadStats {
+		stat, ok := stats[loadStat.name]
+		if !ok {
+			return load, errors.New("Data was missing '" + loadStat.name + "'")
+		}
+		*loadStat.val = stat.(float64) / LOADAVG_SHIFT
+		delete(stats, loadStat.name)
+	}
+
+	stat, ok := stats["plugin_system_stats_current_processes"]
+	if !ok {
+		return load, errors.New("Data was missing 'plugin_system_stats_current_processes'")
+	}
+	if procs := stat.(float64); procs > math.MaxUint64 {
+		return load, fmt.Errorf("current number of processes cannot be represented as a uint64 - too big (%v)", stat)
+	} else if procs < 0 || math.IsNaN(procs) {
+		return load, fmt.Errorf("current_processes must be a non-negative number, got %v", stat)
+	} else {
+		load.TotalProcesses = uint64(procs)
+	}
+	delete(stats, "plugin_system_stats_current_processes")
+	return load, nil
+}
+
+// parsePrometheusInterfaces returns the network interfaces described by the
+// given stats. Interface names containing characters Prometheus doesn't allow
+// in metric names, such as VLAN interfaces like "eth0.100", are returned
+// sanitized, e.g. "eth0_100".
+func parsePrometheusInterfaces(stats map[string]interface{}) map[string]Interface {
+	ifaces := make(map[string]Interface)
+	for stat, value := range stats {
+		if !strings.HasPrefix(stat, promNetStatPrefix) {
+			continue
+		}
+		stat = strings.TrimPrefix(stat, promNetStatPrefix)
+		val := value.(float64)
+		switch {
+		case strings.HasSuffix(stat, "_rx_bytes"):
+			name := strings.TrimSuffix(stat, "_rx_bytes")
+			if val < 0 || val > math.MaxUint64 || math.IsNaN(val) {
+				log.Warnf("received bytes for interface '%s' cannot be represented as a uint64 (%v)", name, value)
+				continue
+			}
+			tmp := ifaces[name]
+			tmp.BytesIn = uint64(val)
+			ifaces[name] = tmp
+		case strings.HasSuffix(stat, "_tx_bytes"):
+			name := strings.TrimSuffix(stat, "_tx_bytes")
+			if val < 0 || val > math.MaxUint64 || math.IsNaN(val) {
+				log.Warnf("transmitted bytes for interface '%s' cannot be represented as a uint64 (%v)", name, value)
+				continue
+			}
+			tmp := ifaces[name]
+			tmp.BytesOut = uint64(val)
+			ifaces[name] = tmp
+		case strings.HasSuffix(stat, "_speed"):
+			name := strings.TrimSuffix(stat, "_speed")
+			if val > math.MaxInt64 || val < math.MinInt64 || math.IsNaN(val) {
+				log.Warnf("speed of interface '%s' outside of representable integer range: %v", name, value)
+				continue
+			}
+			tmp := ifaces[name]
+			tmp.Speed = int64(val)
+			ifaces[name] = tmp
+		}
+	}
+	return ifaces
+}
+
+func statsOverHTTPPrometheusPrecompute(cacheName string, data todata.TOData, stats Statistics, miscStats map[string]interface{}) PrecomputedData {
+	var precomputed PrecomputedData
+	precomputed.DeliveryServiceStats = make(map[string]*DSStat)
+
+	precomputed.OutBytes = 0
+	precomputed.MaxKbps = 0
+	for _, iface := range stats.Interfaces {
+		precomputed.OutBytes += iface.BytesOut
+		if iface.Speed > precomputed.MaxKbps {
+			precomputed.MaxKbps = iface.Speed
+		}
+	}
+	precomputed.MaxKbps *= 1000
+
+	for stat, value := range miscStats {
+		if strings.HasPrefix(stat, promRemapStatPrefix) {
+			err := errors.New("remap stat has no '" + promRemapStatHostLabel + "' label")
+			log.Infof("precomputing cache %s stat %s value %v error %v", cacheName, stat, value, err)
+			precomputed.Errors = append(precomputed.Errors, err)
+			continue
+		}
+		if !strings.HasPrefix(stat, "plugin.remap_stats.") {
+			continue
+		}
+
+		trimmedStat := strings.TrimPrefix(stat, "plugin.remap_stats.")
+		statSep := strings.LastIndexByte(trimmedStat, '.')
+		if statSep < 0 {
+			statSep = 0
+		}
+		hostParts := strings.SplitN(trimmedStat[:statSep], ".", 3)
+		if len(hostParts) < 3 {
+			err := errors.New("stat has no remap_stats deliveryservice and name parts")
+			log.Infof("precomputing cache %s stat %s value %v error %v", cacheName, stat, value, err)
+			precomputed.Errors = append(precomputed.Errors, err)
+			continue
+		}
+		statName := trimmedStat[statSep+1:]
+		ds, ok := data.DeliveryServiceRegexes.DeliveryService(hostParts[2], hostParts[1], hostParts[0])
+		if !ok {
+			err := errors.New("No Delivery Service match for stat")
+			log.Infof("precomputing cache %s stat %s value %v error %v", cacheName, stat, value, err)
+			precomputed.Errors = append(precomputed.Errors, err)
+			continue
+		}
+		if ds == "" {
+			err := errors.New("Empty Delivery Service FQDN")
+			log.Infof("precomputing cache %s stat %s value %v error %v", cacheName, stat, value, err)
+			precomputed.Errors = append(precomputed.Errors, err)
+			continue
+		}
+
+		val, ok := value.(float64)
+		if !ok || val < 0 || val > math.MaxUint64 || math.IsNaN(val) {
+			err := fmt.Errorf("couldn't parse numeric stat: value '%v' out of range for uint64", value)
+			log.Infof("precomputing cache %s stat %s value %v error %v", cacheName, stat, value, err)
+			precomputed.Errors = append(precomputed.Errors, err)
+			continue
+		}
+		parsedStat := uint64(val)
+
+		dsName := string(ds)
+		dsStat, ok := precomputed.DeliveryServiceStats[dsName]
+		if !ok || dsStat == nil {
+			dsStat = new(DSStat)
+		}
+
+		switch statName {
+		case "status_2xx":
+			dsStat.Status2xx += parsedStat
+		case "status_3xx":
+			dsStat.Status3xx += parsedStat
+		case "status_4xx":
+			dsStat.Status4xx += parsedStat
+		case "status_5xx":
+			dsStat.Status5xx += parsedStat
+		case "out_bytes":
+			dsStat.OutBytes += parsedStat
+		case "in_bytes":
+			dsStat.InBytes += parsedStat
+		default:
+			err := fmt.Errorf("Unknown stat '%s'", statName)
+			log.Infof("precomputing cache %s stat %s value %v error %v", cacheName, stat, value, err)
+			precomputed.Errors = append(precomputed.Errors, err)
+			continue
+		}
+		precomputed.DeliveryServiceStats[dsName] = dsStat
+	}
+	return precomputed
+}
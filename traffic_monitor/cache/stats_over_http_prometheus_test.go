@@ -0,0 +1,198 @@
+package cache
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/traffic_monitor/poller"
+)
+
+func TestStatsOverHTTPPrometheusParse(t *testing.T) {
+	fd, err := os.Open("stats_over_http.prom")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pl := &poller.HTTPPollCtx{HTTPHeader: http.Header{}}
+	ctx := interface{}(pl)
+	ctx.(*poller.HTTPPollCtx).HTTPHeader.Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	stats, misc, err := statsOverHTTPPrometheusParse("test", fd, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if misc["plugin.remap_stats.edge-cache-0.delivery.service.zero.in_bytes"] != float64(296727207) {
+		t.Errorf("Expected 296727207 for remap_stats edge-cache in_bytes, got %v", misc["plugin.remap_stats.edge-cache-0.delivery.service.zero.in_bytes"])
+	}
+	if misc["proxy_process_http_completed_requests"] != float64(26220072200) {
+		t.Errorf("Expected 26220072200 for proxy_process_http_completed_requests, got %v", misc["proxy_process_http_completed_requests"])
+	}
+
+	if stats.Loadavg.One <= 0.092773437 || stats.Loadavg.One >= 0.092773439 {
+		t.Errorf("Incorrect one-minute loadavg, expected roughly 0.092773438, got '%.10f'", stats.Loadavg.One)
+	}
+	if stats.Loadavg.Five <= 0.25439453 || stats.Loadavg.Five >= 0.254394532 {
+		t.Errorf("Incorrect five-minute loadavg, expected roughly 0.254394531, got %.10f", stats.Loadavg.Five)
+	}
+	if stats.Loadavg.Fifteen <= 0.639160155 || stats.Loadavg.Fifteen >= 0.639160157 {
+		t.Errorf("Incorrect fifteen-minute loadavg, expected roughly 0.639160156, got %.10f", stats.Loadavg.Fifteen)
+	}
+	if stats.Loadavg.TotalProcesses != 803 {
+		t.Errorf("Incorrect current_processes, expected 803, got %d", stats.Loadavg.TotalProcesses)
+	}
+
+	if len(stats.Interfaces) != 1 {
+		t.Errorf("Expected exactly one interface, got %d", len(stats.Interfaces))
+		if len(stats.Interfaces) < 1 {
+			t.FailNow()
+		}
+	}
+
+	iface, ok := stats.Interfaces["docker0"]
+	if !ok {
+		t.Fatal("Didn't find the expected 'docker0' network interface")
+	}
+	if iface.Speed != 70000 {
+		t.Errorf("Incorrect interface speed, expected 70000, got %d", iface.Speed)
+	}
+	if iface.BytesIn != 4363732 {
+		t.Errorf("Incorrect interface rx_bytes, expected 4363732, got %d", iface.BytesIn)
+	}
+	if iface.BytesOut != 237634637 {
+		t.Errorf("Incorrect interface tx_bytes, expected 237634637, got %d", iface.BytesOut)
+	}
+}
+
+func TestStatsOverHTTPPrometheusParseContentType(t *testing.T) {
+	pl := &poller.HTTPPollCtx{HTTPHeader: http.Header{}}
+	pl.HTTPHeader.Set("Content-Type", "application/json")
+	if _, _, err := statsOverHTTPPrometheusParse("test", strings.NewReader("{}"), pl); err == nil {
+		t.Error("expected an error parsing a JSON payload, actual: nil")
+	}
+}
+
+func TestParsePrometheusSample(t *testing.T) {
+	tests := []struct {
+		line  string
+		name  string
+		value float64
+		err   bool
+	}{
+		{line: "proxy_process_http_completed_requests 42", name: "proxy_process_http_completed_requests", value: 42},
+		{line: "proxy_process_http_completed_requests 42 1652781600000", name: "proxy_process_http_completed_requests", value: 42},
+		{line: `plugin_remap_stats_out_bytes{host="ds0.example.invalid"} 1.5e3`, name: "plugin.remap_stats.ds0.example.invalid.out_bytes", value: 1500},
+		{line: `plugin_remap_stats_out_bytes{ foo="a, b}", host="ds0.example.invalid",} 7`, name: "plugin.remap_stats.ds0.example.invalid.out_bytes", value: 7},
+		{line: `proxy_process_ssl_errors{type="a\"b"} 3`, name: `proxy_process_ssl_errors{type="a\"b"}`, value: 3},
+		{line: "proxy_process_http_completed_requests", err: true},
+		{line: "proxy_process_http_completed_requests abc", err: true},
+		{line: `plugin_remap_stats_out_bytes{host="ds0.example.invalid} 7`, err: true},
+		{line: `plugin_remap_stats_out_bytes{host=ds0} 7`, err: true},
+	}
+	for _, test := range tests {
+		name, value, err := parsePrometheusSample(test.line)
+		if test.err {
+			if err == nil {
+				t.Errorf("expected an error parsing '%s', actual: nil", test.line)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsing '%s': unexpected error: %v", test.line, err)
+			continue
+		}
+		if name != test.name || value != test.value {
+			t.Errorf("parsing '%s': expected %s = %v, actual: %s = %v", test.line, test.name, test.value, name, value)
+		}
+	}
+}
+
+func TestStatsOverHTTPPrometheusPrecompute(t *testing.T) {
+	dsNameFQDNs := getMockTODataDSNameDirectMatches()
+	toData := getMockTOData(dsNameFQDNs)
+	cacheName := "cache0"
+	rawStats := getMockRawStats(cacheName, dsNameFQDNs)
+	outBytes := uint64(987655443321)
+	infSpeedMbps := int64(9876554433210)
+	stats := getMockStatistics(infSpeedMbps, outBytes)
+
+	prc := statsOverHTTPPrometheusPrecompute(cacheName, toData, stats, rawStats)
+
+	if len(prc.Errors) != 0 {
+		t.Fatalf("statsOverHTTPPrometheusPrecompute Errors expected 0, actual: %+v\n", prc.Errors)
+	}
+	if prc.OutBytes != outBytes {
+		t.Fatalf("statsOverHTTPPrometheusPrecompute OutBytes expected %d, actual: %d\n", outBytes, prc.OutBytes)
+	}
+	if prc.MaxKbps != infSpeedMbps*1000 {
+		t.Fatalf("statsOverHTTPPrometheusPrecompute MaxKbps expected %d, actual: %d\n", infSpeedMbps*1000, prc.MaxKbps)
+	}
+
+	for dsName, dsFQDN := range dsNameFQDNs {
+		dsStat, ok := prc.DeliveryServiceStats[string(dsName)]
+		if !ok {
+			t.Fatalf("statsOverHTTPPrometheusPrecompute DeliveryServiceStats expected %+v, actual: missing\n", dsName)
+		}
+		if statName := "plugin.remap_stats." + dsFQDN + ".in_bytes"; dsStat.InBytes != uint64(rawStats[statName].(float64)) {
+			t.Errorf("DeliveryServiceStats[%+v].InBytes expected %+v, actual: %+v\n", dsName, uint64(rawStats[statName].(float64)), dsStat.InBytes)
+		}
+		if statName := "plugin.remap_stats." + dsFQDN + ".out_bytes"; dsStat.OutBytes != uint64(rawStats[statName].(float64)) {
+			t.Errorf("DeliveryServiceStats[%+v].OutBytes expected %+v, actual: %+v\n", dsName, uint64(rawStats[statName].(float64)), dsStat.OutBytes)
+		}
+		if statName := "plugin.remap_stats." + dsFQDN + ".status_2xx"; dsStat.Status2xx != uint64(rawStats[statName].(float64)) {
+			t.Errorf("DeliveryServiceStats[%+v].Status2xx expected %+v, actual: %+v\n", dsName, uint64(rawStats[statName].(float64)), dsStat.Status2xx)
+		}
+		if statName := "plugin.remap_stats." + dsFQDN + ".status_5xx"; dsStat.Status5xx != uint64(rawStats[statName].(float64)) {
+			t.Errorf("DeliveryServiceStats[%+v].Status5xx expected %+v, actual: %+v\n", dsName, uint64(rawStats[statName].(float64)), dsStat.Status5xx)
+		}
+	}
+
+	rawStats["plugin_remap_stats_ds0_example_invalid_in_bytes"] = float64(1)
+	if prc := statsOverHTTPPrometheusPrecompute(cacheName, toData, stats, rawStats); len(prc.Errors) != 1 {
+		t.Errorf("expected an error for a remap stat without a host label, actual: %+v", prc.Errors)
+	}
+}
+
+func BenchmarkStatsPrometheus(b *testing.B) {
+	file, err := ioutil.ReadFile("stats_over_http.prom")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pl := &poller.HTTPPollCtx{HTTPHeader: http.Header{}}
+	ctx := interface{}(pl)
+	ctx.(*poller.HTTPPollCtx).HTTPHeader.Set("Content-Type", "text/plain; version=0.0.4")
+	// Reset benchmark timer to not include reading the file
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := statsOverHTTPPrometheusParse("test", bytes.NewReader(file), ctx)
+
+		if err != nil {
+			b.Error(err)
+		}
+	}
+}
@@ -114,6 +114,20 @@ type Config struct {
 	// A MIME-Type that will be sent in the Accept HTTP header in requests to
 	// cache servers for health and stats data.
 	HTTPPollingFormat string `json:"http_polling_format"`
+	// The path to a PEM bundle of the CAs used to verify the certificates of
+	// cache servers polled over HTTPS. If empty, the system CAs are used.
+	HTTPPollingCA string `json:"http_polling_ca"`
+	// The paths to the PEM certificate and key presented to cache servers
+	// polled over HTTPS, for mutual TLS. If empty, no client certificate is
+	// presented.
+	HTTPPollingClientCert string `json:"http_polling_client_cert"`
+	HTTPPollingClientKey  string `json:"http_polling_client_key"`
+	// Controls whether HTTP/2 is used when polling cache servers over HTTPS.
+	HTTPPollingHTTP2 bool `json:"http_polling_http2"`
+	// The TLS server name (SNI) sent to cache servers polled over HTTPS, and
+	// used to verify their certificates. If empty, each cache server's FQDN
+	// is used.
+	HTTPPollingServerName string `json:"http_polling_server_name"`
 	// Sets the timeout duration for all HTTP operations - peer-polling and
 	// health data polling.
 	HTTPTimeout time.Duration `json:"-"`
@@ -144,6 +144,12 @@ func MakeDispatchMap(
 		"/api/crconfig-history": wrap(WrapErr(errorCount, func() ([]byte, error) {
 			return srvAPICRConfigHist(toSession)
 		}, rfc.ApplicationJSON)),
+		"/metrics": wrap(func(w http.ResponseWriter, r *http.Request) {
+			contentType, openMetrics := metricsContentType(r)
+			WrapBytes(func() []byte {
+				return srvMetrics(openMetrics, statInfoHistory, statResultHistory, healthHistory, lastHealthDurations, localCacheStatus, dsStats, events, monitorConfig)
+			}, contentType)(w, r)
+		}),
 	}
 	return addTrailingSlashEndpoints(dispatchMap)
 }
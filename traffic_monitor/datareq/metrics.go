@@ -0,0 +1,323 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/dsdata"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+	"github.com/apache/trafficcontrol/traffic_monitor/threadsafe"
+)
+
+// ContentTypeOpenMetrics is the Content-Type of the OpenMetrics text
+// exposition format, served to scrapers which ask for it.
+const ContentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// ContentTypePrometheusText is the Content-Type of the legacy Prometheus text
+// exposition format, served to scrapers which don't ask for OpenMetrics.
+const ContentTypePrometheusText = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsNamespace is the prefix of every metric name served by /metrics.
+const MetricsNamespace = "trafficmonitor"
+
+// metricsContentType returns the exposition Content-Type to serve for the
+// given request, and whether it is OpenMetrics (as opposed to the legacy
+// Prometheus text format).
+func metricsContentType(r *http.Request) (string, bool) {
+	for _, accept := range r.Header["Accept"] {
+		for _, mediaType := range strings.Split(accept, ",") {
+			mediaType = strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0])
+			if strings.EqualFold(mediaType, "application/openmetrics-text") {
+				return ContentTypeOpenMetrics, true
+			}
+		}
+	}
+	return ContentTypePrometheusText, false
+}
+
+// srvMetrics returns the current health and statistics of every cache server
+// and Delivery Service monitored by this Traffic Monitor, in the OpenMetrics
+// text exposition format if openMetrics is true, else the legacy Prometheus
+// text format.
+func srvMetrics(
+	openMetrics bool,
+	statInfoHistory threadsafe.ResultInfoHistory,
+	statResultHistory threadsafe.ResultStatHistory,
+	healthHistory threadsafe.ResultHistory,
+	lastHealthDurations threadsafe.DurationMap,
+	localCacheStatus threadsafe.CacheAvailableStatus,
+	dsStats threadsafe.DSStatsReader,
+	events health.ThreadsafeEvents,
+	monitorConfig threadsafe.TrafficMonitorConfigMap,
+) []byte {
+	w := newMetricsWriter(openMetrics)
+	mc := monitorConfig.Get()
+	writeCacheMetrics(w, mc, localCacheStatus.Get(), healthHistory.Get(), statInfoHistory.Get(), lastHealthDurations.Get(), createCacheConnections(statResultHistory))
+	writeDSMetrics(w, mc, dsStats.Get())
+	writeEventMetrics(w, events.Get())
+	return w.Bytes()
+}
+
+// writeCacheMetrics writes the availability, bandwidth, connection and poll
+// latency metrics of each cache server in the given monitoring config.
+func writeCacheMetrics(
+	w *metricsWriter,
+	mc tc.TrafficMonitorConfigMap,
+	statuses cache.AvailableStatuses,
+	healthHistory map[tc.CacheName][]cache.Result,
+	statInfoHistory cache.ResultInfoHistory,
+	lastHealthDurations map[tc.CacheName]time.Duration,
+	conns map[string]int64,
+) {
+	cacheNames := make([]string, 0, len(mc.TrafficServer))
+	for name := range mc.TrafficServer {
+		cacheNames = append(cacheNames, name)
+	}
+	sort.Strings(cacheNames)
+
+	available := w.Family("cache_available", "gauge", "Whether the cache server is available, 1 for available and 0 for unavailable.")
+	ipAvailable := w.Family("cache_ip_available", "gauge", "Whether the cache server is available over the labeled IP version.")
+	infKbps := w.Family("cache_interface_kbps", "gauge", "The outgoing bandwidth of the cache server network interface, in kilobits per second, as of its latest health poll.")
+	infMaxKbps := w.Family("cache_interface_max_kbps", "gauge", "The configured maximum bandwidth of the cache server network interface, in kilobits per second.")
+	connections := w.Family("cache_connections", "gauge", "The number of client connections open on the cache server, as of its latest stat poll.")
+	pollDuration := w.Family("cache_poll_duration_seconds", "gauge", "The time taken by the latest poll of the cache server, by poller.")
+	loadAvg := w.Family("cache_load_average", "gauge", "The one-minute load average of the cache server, as of its latest stat poll.")
+
+	for _, name := range cacheNames {
+		server := mc.TrafficServer[name]
+		labels := []string{"cache", name, "cachegroup", server.CacheGroup, "type", server.Type, "status", server.ServerStatus}
+
+		status, ok := statuses[name]
+		if tc.CacheStatusFromString(server.ServerStatus) == tc.CacheStatusOnline {
+			status.ProcessedAvailable = true
+			status.Available.IPv4 = server.IPv4() != ""
+			status.Available.IPv6 = server.IPv6() != ""
+		} else if !ok {
+			status = cache.AvailableStatus{}
+		}
+		available.Sample(boolMetric(status.ProcessedAvailable), labels...)
+		ipAvailable.Sample(boolMetric(status.Available.IPv4), "cache", name, "ip_version", "4")
+		ipAvailable.Sample(boolMetric(status.Available.IPv6), "cache", name, "ip_version", "6")
+
+		var latestHealth *cache.Result
+		if results := healthHistory[tc.CacheName(name)]; len(results) > 0 {
+			latestHealth = &results[0]
+		}
+		for _, inf := range server.Interfaces {
+			if latestHealth != nil {
+				if vitals, ok := latestHealth.InterfaceVitals[inf.Name]; ok {
+					infKbps.Sample(float64(vitals.KbpsOut), "cache", name, "interface", inf.Name)
+				}
+			}
+			if inf.MaxBandwidth != nil {
+				infMaxKbps.Sample(float64(*inf.MaxBandwidth), "cache", name, "interface", inf.Name)
+			}
+		}
+
+		if conn, ok := conns[name]; ok {
+			connections.Sample(float64(conn), "cache", name)
+		}
+		if d, ok := lastHealthDurations[tc.CacheName(name)]; ok {
+			pollDuration.Sample(d.Seconds(), "cache", name, "poller", "health")
+		}
+		if infos := statInfoHistory[tc.CacheName(name)]; len(infos) > 0 {
+			pollDuration.Sample(infos[0].RequestTime.Seconds(), "cache", name, "poller", "stat")
+			loadAvg.Sample(infos[0].Vitals.LoadAvg, "cache", name)
+		}
+	}
+}
+
+// writeDSMetrics writes the total bandwidth, transaction and response code
+// metrics of each Delivery Service in the given monitoring config.
+func writeDSMetrics(w *metricsWriter, mc tc.TrafficMonitorConfigMap, stats dsdata.StatsReadonly) {
+	dsNames := make([]string, 0, len(mc.DeliveryService))
+	for name := range mc.DeliveryService {
+		dsNames = append(dsNames, name)
+	}
+	sort.Strings(dsNames)
+
+	available := w.Family("ds_available", "gauge", "Whether the Delivery Service is available, 1 for available and 0 for unavailable.")
+	cachesConfigured := w.Family("ds_caches_configured", "gauge", "The number of cache servers assigned to the Delivery Service.")
+	cachesAvailable := w.Family("ds_caches_available", "gauge", "The number of available cache servers assigned to the Delivery Service.")
+	kbps := w.Family("ds_kbps", "gauge", "The bandwidth served by the Delivery Service, in kilobits per second.")
+	tps := w.Family("ds_tps", "gauge", "The transactions per second served by the Delivery Service, by response status class.")
+	responses := w.Family("ds_responses", "counter", "The number of responses served by the Delivery Service, by response status class.")
+	outBytes := w.Family("ds_out_bytes", "counter", "The number of bytes served by the Delivery Service.")
+
+	for _, name := range dsNames {
+		stat, ok := stats.Get(tc.DeliveryServiceName(name))
+		if !ok {
+			continue
+		}
+		common := stat.Common()
+		available.Sample(boolMetric(common.Available().Value), "ds", name)
+		cachesConfigured.Sample(float64(common.CachesConfigured().Value), "ds", name)
+		cachesAvailable.Sample(float64(common.CachesAvailable().Value), "ds", name)
+
+		total := stat.Total()
+		if total == nil {
+			continue
+		}
+		kbps.Sample(total.Kbps.Value, "ds", name)
+		tps.Sample(total.TpsTotal.Value, "ds", name, "status_class", "total")
+		tps.Sample(total.Tps2xx.Value, "ds", name, "status_class", "2xx")
+		tps.Sample(total.Tps3xx.Value, "ds", name, "status_class", "3xx")
+		tps.Sample(total.Tps4xx.Value, "ds", name, "status_class", "4xx")
+		tps.Sample(total.Tps5xx.Value, "ds", name, "status_class", "5xx")
+		responses.Sample(float64(total.Status2xx.Value), "ds", name, "status_class", "2xx")
+		responses.Sample(float64(total.Status3xx.Value), "ds", name, "status_class", "3xx")
+		responses.Sample(float64(total.Status4xx.Value), "ds", name, "status_class", "4xx")
+		responses.Sample(float64(total.Status5xx.Value), "ds", name, "status_class", "5xx")
+		outBytes.Sample(float64(total.OutBytes.Value), "ds", name)
+	}
+}
+
+// writeEventMetrics writes the number of events ever logged, and the number of
+// retained events in the event log by type and resulting availability.
+func writeEventMetrics(w *metricsWriter, events []health.Event) {
+	total := w.Family("events", "counter", "The number of events logged since Traffic Monitor started.")
+	retained := w.Family("event_log_events", "gauge", "The number of events currently retained in the event log, by server type and resulting availability.")
+
+	// Events are stored newest-first, and indices are assigned sequentially
+	// from zero, so the newest event's index is one less than the total.
+	count := uint64(0)
+	if len(events) > 0 {
+		count = events[0].Index + 1
+	}
+	total.Sample(float64(count))
+
+	type eventKey struct {
+		Type      string
+		Available bool
+	}
+	counts := map[eventKey]uint64{}
+	for _, e := range events {
+		counts[eventKey{Type: e.Type, Available: e.Available}]++
+	}
+	keys := make([]eventKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return !keys[i].Available && keys[j].Available
+	})
+	for _, k := range keys {
+		retained.Sample(float64(counts[k]), "type", k.Type, "available", strconv.FormatBool(k.Available))
+	}
+}
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsWriter builds a text exposition of metric families. Families are
+// written in the order they are created, and a family with no samples is
+// omitted entirely.
+type metricsWriter struct {
+	openMetrics bool
+	families    []*metricFamily
+}
+
+// metricFamily is a single named metric with its samples.
+type metricFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples bytes.Buffer
+}
+
+func newMetricsWriter(openMetrics bool) *metricsWriter {
+	return &metricsWriter{openMetrics: openMetrics}
+}
+
+// Family creates a new metric family with the given name, which will be
+// prefixed with the MetricsNamespace. The typ must be "gauge" or "counter".
+func (w *metricsWriter) Family(name, typ, help string) *metricFamily {
+	f := &metricFamily{name: MetricsNamespace + "_" + name, typ: typ, help: help}
+	w.families = append(w.families, f)
+	return f
+}
+
+// Sample adds a sample with the given value and labels to the family. The
+// labels must be alternating label names and values.
+func (f *metricFamily) Sample(val float64, labels ...string) {
+	f.samples.WriteString(f.name)
+	if f.typ == "counter" {
+		f.samples.WriteString("_total")
+	}
+	if len(labels) > 1 {
+		f.samples.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				f.samples.WriteByte(',')
+			}
+			f.samples.WriteString(labels[i])
+			f.samples.WriteString(`="`)
+			f.samples.WriteString(escapeLabelValue(labels[i+1]))
+			f.samples.WriteByte('"')
+		}
+		f.samples.WriteByte('}')
+	}
+	f.samples.WriteByte(' ')
+	f.samples.WriteString(strconv.FormatFloat(val, 'g', -1, 64))
+	f.samples.WriteByte('\n')
+}
+
+// Bytes returns the exposition of all families with at least one sample.
+func (w *metricsWriter) Bytes() []byte {
+	buf := bytes.Buffer{}
+	for _, f := range w.families {
+		if f.samples.Len() == 0 {
+			continue
+		}
+		name := f.name
+		if f.typ == "counter" && !w.openMetrics {
+			// the legacy format names counter families by their sample name
+			name += "_total"
+		}
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ)
+		buf.Write(f.samples.Bytes())
+	}
+	if w.openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	return buf.Bytes()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
@@ -0,0 +1,141 @@
+package datareq
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_monitor/cache"
+	"github.com/apache/trafficcontrol/traffic_monitor/health"
+)
+
+func TestMetricsContentType(t *testing.T) {
+	r, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	if ct, om := metricsContentType(r); om || ct != ContentTypePrometheusText {
+		t.Errorf("expected Prometheus text without an Accept header, actual: '%s'", ct)
+	}
+
+	r.Header.Set("Accept", "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5")
+	if ct, om := metricsContentType(r); !om || ct != ContentTypeOpenMetrics {
+		t.Errorf("expected OpenMetrics when accepted, actual: '%s'", ct)
+	}
+}
+
+func TestMetricsWriter(t *testing.T) {
+	for _, openMetrics := range []bool{true, false} {
+		w := newMetricsWriter(openMetrics)
+		w.Family("empty", "gauge", "Never written.")
+		w.Family("foo", "gauge", "A gauge.").Sample(1.5, "name", "a\"b\\c\nd")
+		w.Family("bar", "counter", "A counter.").Sample(42)
+		actual := string(w.Bytes())
+
+		expected := "# HELP trafficmonitor_foo A gauge.\n" +
+			"# TYPE trafficmonitor_foo gauge\n" +
+			`trafficmonitor_foo{name="a\"b\\c\nd"} 1.5` + "\n"
+		if openMetrics {
+			expected += "# HELP trafficmonitor_bar A counter.\n" +
+				"# TYPE trafficmonitor_bar counter\n" +
+				"trafficmonitor_bar_total 42\n" +
+				"# EOF\n"
+		} else {
+			expected += "# HELP trafficmonitor_bar_total A counter.\n" +
+				"# TYPE trafficmonitor_bar_total counter\n" +
+				"trafficmonitor_bar_total 42\n"
+		}
+		if actual != expected {
+			t.Errorf("openMetrics=%t expected:\n%s\nactual:\n%s", openMetrics, expected, actual)
+		}
+	}
+}
+
+func TestWriteCacheMetrics(t *testing.T) {
+	mc := tc.TrafficMonitorConfigMap{
+		TrafficServer: map[string]tc.TrafficServer{
+			"edge": {
+				CacheGroup:   "cg",
+				Type:         "EDGE",
+				ServerStatus: tc.CacheStatusReported.String(),
+				Interfaces: []tc.ServerInterfaceInfo{
+					{Name: "eth0", MaxBandwidth: util.Uint64Ptr(1000)},
+				},
+			},
+		},
+	}
+	statuses := cache.AvailableStatuses{
+		"edge": {
+			Available:          cache.AvailableTuple{IPv4: true},
+			ProcessedAvailable: true,
+		},
+	}
+	healthHistory := map[tc.CacheName][]cache.Result{
+		"edge": {{InterfaceVitals: map[string]cache.Vitals{"eth0": {KbpsOut: 300}}}},
+	}
+	durations := map[tc.CacheName]time.Duration{"edge": 250 * time.Millisecond}
+
+	w := newMetricsWriter(true)
+	writeCacheMetrics(w, mc, statuses, healthHistory, cache.ResultInfoHistory{}, durations, map[string]int64{"edge": 7})
+	actual := string(w.Bytes())
+
+	for _, expected := range []string{
+		`trafficmonitor_cache_available{cache="edge",cachegroup="cg",type="EDGE",status="REPORTED"} 1`,
+		`trafficmonitor_cache_ip_available{cache="edge",ip_version="4"} 1`,
+		`trafficmonitor_cache_ip_available{cache="edge",ip_version="6"} 0`,
+		`trafficmonitor_cache_interface_kbps{cache="edge",interface="eth0"} 300`,
+		`trafficmonitor_cache_interface_max_kbps{cache="edge",interface="eth0"} 1000`,
+		`trafficmonitor_cache_connections{cache="edge"} 7`,
+		`trafficmonitor_cache_poll_duration_seconds{cache="edge",poller="health"} 0.25`,
+	} {
+		if !strings.Contains(actual, expected+"\n") {
+			t.Errorf("expected metrics to contain '%s', actual:\n%s", expected, actual)
+		}
+	}
+	if strings.Contains(actual, "cache_load_average") {
+		t.Errorf("expected no load average without stat history, actual:\n%s", actual)
+	}
+}
+
+func TestWriteEventMetrics(t *testing.T) {
+	events := []health.Event{
+		{Index: 11, Type: "EDGE", Available: true},
+		{Index: 10, Type: "EDGE", Available: false},
+		{Index: 9, Type: "EDGE", Available: false},
+	}
+	w := newMetricsWriter(true)
+	writeEventMetrics(w, events)
+	actual := string(w.Bytes())
+
+	for _, expected := range []string{
+		`trafficmonitor_events_total 12`,
+		`trafficmonitor_event_log_events{type="EDGE",available="false"} 2`,
+		`trafficmonitor_event_log_events{type="EDGE",available="true"} 1`,
+	} {
+		if !strings.Contains(actual, expected+"\n") {
+			t.Errorf("expected metrics to contain '%s', actual:\n%s", expected, actual)
+		}
+	}
+}
@@ -299,11 +299,13 @@ func monitorConfigListen(
 				log.Warnln("profile " + srv.Profile + " health.connection.timeout Parameter is missing or zero, using default " + DefaultHealthConnectionTimeout.String())
 			}
 
-			healthURLs[srv.HostName] = poller.PollConfig{URL: pollURL4Str, URLv6: pollURL6Str, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType}
+			tlsCfg := createServerPollTLSConfig(cfg, monitorConfig.Profile[srv.Profile].Parameters)
+
+			healthURLs[srv.HostName] = poller.PollConfig{URL: pollURL4Str, URLv6: pollURL6Str, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType, TLS: tlsCfg}
 
 			statURL4 := createServerStatPollURL(pollURL4Str)
 			statURL6 := createServerStatPollURL(pollURL6Str)
-			statURLs[srv.HostName] = poller.PollConfig{URL: statURL4, URLv6: statURL6, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType}
+			statURLs[srv.HostName] = poller.PollConfig{URL: statURL4, URLv6: statURL6, Host: srv.FQDN, Timeout: connTimeout, Format: format, PollType: pollType, TLS: tlsCfg}
 		}
 
 		peerSet := map[tc.TrafficMonitorName]struct{}{}
@@ -554,6 +556,38 @@ func insertPorts(pollingURLStr string, srv tc.TrafficServer) string {
 	return pollingURLStr
 }
 
+// createServerPollTLSConfig returns the TLS configuration for polling caches
+// over HTTPS, from the given Profile Parameters, or the configured defaults
+// for those which are empty. The client certificate and key are overridden
+// together.
+func createServerPollTLSConfig(cfg config.Config, params tc.TMParameters) poller.TLSConfig {
+	tlsCfg := poller.TLSConfig{
+		CA:         cfg.HTTPPollingCA,
+		ClientCert: cfg.HTTPPollingClientCert,
+		ClientKey:  cfg.HTTPPollingClientKey,
+		ServerName: cfg.HTTPPollingServerName,
+		HTTP2:      cfg.HTTPPollingHTTP2,
+	}
+	if params.HealthPollingTLSCA != "" {
+		tlsCfg.CA = params.HealthPollingTLSCA
+	}
+	if params.HealthPollingTLSCert != "" || params.HealthPollingTLSKey != "" {
+		tlsCfg.ClientCert = params.HealthPollingTLSCert
+		tlsCfg.ClientKey = params.HealthPollingTLSKey
+	}
+	if params.HealthPollingTLSServerName != "" {
+		tlsCfg.ServerName = params.HealthPollingTLSServerName
+	}
+	if params.HealthPollingHTTP2 != "" {
+		if http2, err := strconv.ParseBool(params.HealthPollingHTTP2); err != nil {
+			log.Warnf("health.polling.http2 '%s' is not a boolean, using default %t", params.HealthPollingHTTP2, tlsCfg.HTTP2)
+		} else {
+			tlsCfg.HTTP2 = http2
+		}
+	}
+	return tlsCfg
+}
+
 // createServerStatPollURL takes the health polling URL string, and modifies it to be the stat poll URL.
 // Note this does not replace template variables with server values, healthPollURLStr must be the health URL for a given server, not a template.
 func createServerStatPollURL(healthPollURLStr string) string {
@@ -24,6 +24,8 @@ import (
 	"testing"
 
 	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_monitor/config"
+	"github.com/apache/trafficcontrol/traffic_monitor/poller"
 )
 
 func TestCreateServerHealthPollURL(t *testing.T) {
@@ -268,6 +270,37 @@ func TestCreateServerStatPollURL(t *testing.T) {
 	}
 }
 
+func TestCreateServerPollTLSConfig(t *testing.T) {
+	cfg := config.DefaultConfig
+	cfg.HTTPPollingCA = "/etc/tm/ca.pem"
+	cfg.HTTPPollingClientCert = "/etc/tm/client.crt"
+	cfg.HTTPPollingClientKey = "/etc/tm/client.key"
+	cfg.HTTPPollingHTTP2 = true
+
+	actual := createServerPollTLSConfig(cfg, tc.TMParameters{})
+	expected := poller.TLSConfig{CA: "/etc/tm/ca.pem", ClientCert: "/etc/tm/client.crt", ClientKey: "/etc/tm/client.key", HTTP2: true}
+	if actual != expected {
+		t.Errorf("expected TLS config with no parameters to be the configured defaults %+v, actual: %+v", expected, actual)
+	}
+
+	params := tc.TMParameters{
+		HealthPollingTLSCA:         "/etc/tm/edge-ca.pem",
+		HealthPollingTLSCert:       "/etc/tm/edge.crt",
+		HealthPollingTLSServerName: "stats.edge.example",
+		HealthPollingHTTP2:         "false",
+	}
+	actual = createServerPollTLSConfig(cfg, params)
+	expected = poller.TLSConfig{CA: "/etc/tm/edge-ca.pem", ClientCert: "/etc/tm/edge.crt", ClientKey: "", ServerName: "stats.edge.example", HTTP2: false}
+	if actual != expected {
+		t.Errorf("expected TLS config parameters to override defaults %+v, actual: %+v", expected, actual)
+	}
+
+	params = tc.TMParameters{HealthPollingHTTP2: "sometimes"}
+	if actual = createServerPollTLSConfig(cfg, params); !actual.HTTP2 {
+		t.Errorf("expected invalid health.polling.http2 parameter to use default true, actual: false")
+	}
+}
+
 func TestGetCacheGroupsToPoll(t *testing.T) {
 	monitors := map[string]tc.TrafficMonitor{
 		"tm2": {
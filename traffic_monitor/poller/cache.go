@@ -47,6 +47,7 @@ type PollConfig struct {
 	Timeout  time.Duration
 	Format   string
 	PollType string
+	TLS      TLSConfig
 }
 
 type CachePollerConfig struct {
@@ -114,6 +115,8 @@ func (p CachePoller) Poll() {
 				Timeout:     info.Timeout,
 				NoKeepAlive: info.NoKeepAlive,
 				PollerID:    info.ID,
+				Host:        info.Host,
+				TLS:         info.TLS,
 			}
 			pollerCtx := interface{}(nil)
 			if pollerObj.Init != nil {
@@ -20,9 +20,12 @@ package poller
  */
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
 
@@ -42,9 +45,10 @@ func httpGlobalInit(cfg config.Config, appData config.StaticAppData) interface{}
 		Timeout:   cfg.HTTPTimeout,
 	}
 	return &HTTPPollGlobalCtx{
-		UserAgent:    appData.UserAgent,
-		Client:       sharedClient,
-		FormatAccept: cfg.HTTPPollingFormat,
+		UserAgent:     appData.UserAgent,
+		Client:        sharedClient,
+		FormatAccept:  cfg.HTTPPollingFormat,
+		TLSTransports: map[tlsTransportKey]*http.Transport{},
 	}
 }
 
@@ -70,8 +74,18 @@ func httpInit(cfg PollerConfig, globalCtxI interface{}) interface{} {
 		}
 	}
 
+	client := gctx.Client
+	if cfg.TLS != (TLSConfig{}) {
+		tlsClient, err := newTLSClient(cfg, client.Timeout, gctx.TLSTransports)
+		if err != nil {
+			log.Errorf("creating TLS client for poller ID '%s', polling without TLS configuration: %s\n", cfg.PollerID, err.Error())
+		} else {
+			client = tlsClient
+		}
+	}
+
 	return &HTTPPollCtx{
-		Client:       gctx.Client,
+		Client:       client,
 		UserAgent:    gctx.UserAgent,
 		NoKeepAlive:  cfg.NoKeepAlive,
 		PollerID:     cfg.PollerID,
@@ -79,10 +93,72 @@ func httpInit(cfg PollerConfig, globalCtxI interface{}) interface{} {
 	}
 }
 
+// tlsTransportKey identifies the transports of pollers which poll with the
+// same TLS configuration, and may share connections.
+type tlsTransportKey struct {
+	TLS         TLSConfig
+	ServerName  string
+	NoKeepAlive bool
+}
+
+// newTLSClient returns a client for the given poller, which uses its TLS
+// configuration when polling over HTTPS.
+//
+// Pollers are initialized again whenever their configuration changes, so the
+// transport of each TLS configuration is kept in transports and reused, rather
+// than leaving the idle connections of a new transport open every time.
+func newTLSClient(cfg PollerConfig, timeout time.Duration, transports map[tlsTransportKey]*http.Transport) (*http.Client, error) {
+	if cfg.Timeout != 0 {
+		timeout = cfg.Timeout
+	}
+	key := tlsTransportKey{TLS: cfg.TLS, ServerName: cfg.TLS.ServerName, NoKeepAlive: cfg.NoKeepAlive}
+	if key.ServerName == "" {
+		key.ServerName = cfg.Host
+		if host, _, err := net.SplitHostPort(cfg.Host); err == nil {
+			key.ServerName = host
+		}
+	}
+	if transport, ok := transports[key]; ok {
+		return &http.Client{Transport: transport, Timeout: timeout}, nil
+	}
+
+	tlsCfg := &tls.Config{ServerName: key.ServerName}
+	if cfg.TLS.CA != "" {
+		caPEM, err := ioutil.ReadFile(cfg.TLS.CA)
+		if err != nil {
+			return nil, errors.New("reading CA file: " + err.Error())
+		}
+		tlsCfg.RootCAs = x509.NewCertPool()
+		if !tlsCfg.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("CA file '" + cfg.TLS.CA + "' contains no PEM certificates")
+		}
+	}
+	if cfg.TLS.ClientCert != "" || cfg.TLS.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.ClientCert, cfg.TLS.ClientKey)
+		if err != nil {
+			return nil, errors.New("loading client certificate: " + err.Error())
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	// Setting TLSClientConfig disables HTTP/2 unless it's forced, so HTTP/2 is
+	// only used when configured.
+	transport := &http.Transport{
+		TLSClientConfig:   tlsCfg,
+		ForceAttemptHTTP2: cfg.TLS.HTTP2,
+		DisableKeepAlives: cfg.NoKeepAlive,
+	}
+	transports[key] = transport
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
 type HTTPPollGlobalCtx struct {
 	Client       *http.Client
 	UserAgent    string
 	FormatAccept string
+	// TLSTransports are the transports of pollers with TLS configurations,
+	// shared by those with the same configuration.
+	TLSTransports map[tlsTransportKey]*http.Transport
 }
 
 type HTTPPollCtx struct {
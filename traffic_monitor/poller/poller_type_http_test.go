@@ -0,0 +1,170 @@
+package poller
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert creates a certificate for the given DNS name, signed by the given
+// parent, or self-signed if parent is nil, and returns it with its key.
+func testCert(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent = tmpl
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+	return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writePEM(t *testing.T, path string, typ string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+func TestHTTPPollMutualTLS(t *testing.T) {
+	ca, caKey, _ := testCert(t, "Test CA", nil, nil)
+	_, _, serverCert := testCert(t, "cache.example", ca, caKey)
+	clientCert, clientKey, _ := testCert(t, "monitor.example", ca, caKey)
+
+	dir := t.TempDir()
+	caPath := filepath.Join(dir, "ca.pem")
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+	writePEM(t, caPath, "CERTIFICATE", ca.Raw)
+	writePEM(t, certPath, "CERTIFICATE", clientCert.Raw)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatalf("marshalling client key: %v", err)
+	}
+	writePEM(t, keyPath, "EC PRIVATE KEY", keyDER)
+
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    caPool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	gctx := &HTTPPollGlobalCtx{Client: &http.Client{Transport: &http.Transport{}, Timeout: 5 * time.Second}, UserAgent: "test", TLSTransports: map[tlsTransportKey]*http.Transport{}}
+
+	tests := []struct {
+		name      string
+		cfg       PollerConfig
+		expectErr bool
+		proto     string
+	}{
+		{
+			name:      "no TLS config",
+			cfg:       PollerConfig{PollerID: "no-tls", Host: "cache.example"},
+			expectErr: true,
+		},
+		{
+			name:      "no client certificate",
+			cfg:       PollerConfig{PollerID: "no-client-cert", Host: "cache.example", TLS: TLSConfig{CA: caPath}},
+			expectErr: true,
+		},
+		{
+			name:      "wrong server name",
+			cfg:       PollerConfig{PollerID: "wrong-sni", Host: "cache.example", TLS: TLSConfig{CA: caPath, ClientCert: certPath, ClientKey: keyPath, ServerName: "other.example"}},
+			expectErr: true,
+		},
+		{
+			name:  "mutual TLS",
+			cfg:   PollerConfig{PollerID: "mtls", Host: "cache.example", TLS: TLSConfig{CA: caPath, ClientCert: certPath, ClientKey: keyPath}},
+			proto: "HTTP/1.1",
+		},
+		{
+			name:  "mutual TLS with HTTP/2",
+			cfg:   PollerConfig{PollerID: "mtls-h2", Host: "cache.example:443", TLS: TLSConfig{CA: caPath, ClientCert: certPath, ClientKey: keyPath, HTTP2: true}},
+			proto: "HTTP/2.0",
+		},
+	}
+	for _, test := range tests {
+		ctx := httpInit(test.cfg, gctx)
+		bts, _, _, err := httpPoll(ctx, srv.URL, test.cfg.Host, 1)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%s: expected error, actual: nil", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error, actual: %v", test.name, err)
+		} else if string(bts) != test.proto {
+			t.Errorf("%s: expected protocol %s, actual: %s", test.name, test.proto, string(bts))
+		}
+	}
+
+	// initializing a poller again reuses the transport of its TLS configuration, rather than leaking the connections of a new one
+	cfg := tests[3].cfg
+	first := httpInit(cfg, gctx).(*HTTPPollCtx).Client.Transport
+	cfg.Timeout = time.Second
+	if again := httpInit(cfg, gctx).(*HTTPPollCtx).Client.Transport; again != first {
+		t.Error("expected initializing a poller with the same TLS configuration to reuse its transport")
+	}
+	cfg.TLS.HTTP2 = true
+	if other := httpInit(cfg, gctx).(*HTTPPollCtx).Client.Transport; other == first {
+		t.Error("expected initializing a poller with a different TLS configuration to use another transport")
+	}
+}
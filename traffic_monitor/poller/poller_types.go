@@ -39,6 +39,26 @@ type PollerConfig struct {
 	Timeout     time.Duration
 	NoKeepAlive bool
 	PollerID    string
+	// Host is the FQDN of the polled cache, which is used as the TLS server
+	// name if TLS.ServerName is empty.
+	Host string
+	TLS  TLSConfig
+}
+
+// TLSConfig is the TLS configuration of a poller, for caches polled over
+// HTTPS. Its zero value uses the poller type's defaults.
+type TLSConfig struct {
+	// CA is the path to a PEM bundle of the CAs used to verify the cache's
+	// certificate. If empty, the system CAs are used.
+	CA string
+	// ClientCert and ClientKey are the paths to the PEM certificate and key
+	// presented to the cache. If empty, no client certificate is presented.
+	ClientCert string
+	ClientKey  string
+	// ServerName overrides the cache's FQDN as the TLS server name.
+	ServerName string
+	// HTTP2 is whether to use HTTP/2.
+	HTTP2 bool
 }
 
 // PollerGlobalInit performs global initialization, and returns a global context object.
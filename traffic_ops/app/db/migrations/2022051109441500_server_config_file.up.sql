@@ -0,0 +1,28 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with this
+ * work for additional information regarding copyright ownership.  The ASF
+ * licenses this file to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+ * License for the specific language governing permissions and limitations under
+ * the License.
+ */
+
+CREATE TABLE IF NOT EXISTS public.server_config_file (
+    server bigint NOT NULL,
+    name text NOT NULL,
+    path text NOT NULL,
+    line_comment text NOT NULL DEFAULT '',
+    hash text NOT NULL,
+    contents text,
+    last_updated timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pk_server_config_file PRIMARY KEY (server, path, name),
+    CONSTRAINT fk_server_config_file_server FOREIGN KEY (server) REFERENCES public.server(id) ON DELETE CASCADE
+);
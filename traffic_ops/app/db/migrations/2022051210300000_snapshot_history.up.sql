@@ -0,0 +1,36 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with this
+ * work for additional information regarding copyright ownership.  The ASF
+ * licenses this file to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+ * License for the specific language governing permissions and limitations under
+ * the License.
+ */
+
+CREATE TABLE IF NOT EXISTS public.snapshot_history (
+    id bigserial NOT NULL,
+    cdn text NOT NULL,
+    crconfig json NOT NULL,
+    monitoring json NOT NULL,
+    author text NOT NULL,
+    log_id bigint,
+    rollback_of bigint,
+    last_updated timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pk_snapshot_history PRIMARY KEY (id),
+    CONSTRAINT fk_snapshot_history_cdn FOREIGN KEY (cdn) REFERENCES public.cdn(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    CONSTRAINT fk_snapshot_history_rollback_of FOREIGN KEY (rollback_of) REFERENCES public.snapshot_history(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS snapshot_history_cdn_last_updated_idx ON public.snapshot_history (cdn, last_updated DESC);
+
+INSERT INTO public.snapshot_history (cdn, crconfig, monitoring, author, last_updated)
+SELECT s.cdn, s.crconfig, s.monitoring, COALESCE(s.crconfig->'stats'->>'tm_user', ''), s.last_updated
+FROM public.snapshot AS s;
@@ -0,0 +1,54 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with this
+ * work for additional information regarding copyright ownership.  The ASF
+ * licenses this file to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+ * License for the specific language governing permissions and limitations under
+ * the License.
+ */
+
+CREATE TABLE IF NOT EXISTS public.rollout (
+    id bigserial NOT NULL,
+    cdn_id bigint NOT NULL,
+    topology text,
+    canary_cachegroups text[] NOT NULL DEFAULT '{}',
+    canary_percent integer NOT NULL DEFAULT 0,
+    wave_percent integer NOT NULL DEFAULT 0,
+    soak_time integer NOT NULL DEFAULT 0,
+    status text NOT NULL DEFAULT 'running',
+    status_message text,
+    current_wave integer NOT NULL DEFAULT 0,
+    waves integer NOT NULL,
+    wave_queued_time timestamp with time zone,
+    wave_applied_time timestamp with time zone,
+    author text NOT NULL,
+    last_updated timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pk_rollout PRIMARY KEY (id),
+    CONSTRAINT fk_rollout_cdn FOREIGN KEY (cdn_id) REFERENCES public.cdn(id) ON DELETE CASCADE,
+    CONSTRAINT fk_rollout_topology FOREIGN KEY (topology) REFERENCES public.topology(name) ON UPDATE CASCADE ON DELETE SET NULL,
+    CONSTRAINT rollout_status_check CHECK (status IN ('running', 'paused', 'aborted', 'completed')),
+    CONSTRAINT rollout_percent_check CHECK (canary_percent BETWEEN 0 AND 100 AND wave_percent BETWEEN 0 AND 100)
+);
+
+CREATE TABLE IF NOT EXISTS public.rollout_server (
+    rollout_id bigint NOT NULL,
+    server_id bigint NOT NULL,
+    wave integer NOT NULL,
+    queued_time timestamp with time zone,
+    CONSTRAINT pk_rollout_server PRIMARY KEY (rollout_id, server_id),
+    CONSTRAINT fk_rollout_server_rollout FOREIGN KEY (rollout_id) REFERENCES public.rollout(id) ON DELETE CASCADE,
+    CONSTRAINT fk_rollout_server_server FOREIGN KEY (server_id) REFERENCES public.server(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS rollout_status_idx ON public.rollout (status);
+
+-- at most one Rollout of each CDN may be in progress at a time
+CREATE UNIQUE INDEX IF NOT EXISTS rollout_active_cdn_idx ON public.rollout (cdn_id) WHERE status IN ('running', 'paused');
@@ -0,0 +1,26 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with this
+ * work for additional information regarding copyright ownership.  The ASF
+ * licenses this file to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+ * License for the specific language governing permissions and limitations under
+ * the License.
+ */
+
+-- The typed cache key policy of a Delivery Service, rendered by t3c into
+-- cachekey plugin arguments. Delivery Services without a policy have no row.
+CREATE TABLE IF NOT EXISTS public.deliveryservice_cache_key_policy (
+    deliveryservice bigint NOT NULL,
+    policy jsonb NOT NULL,
+    last_updated timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pk_deliveryservice_cache_key_policy PRIMARY KEY (deliveryservice),
+    CONSTRAINT fk_deliveryservice_cache_key_policy_deliveryservice FOREIGN KEY (deliveryservice) REFERENCES public.deliveryservice(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
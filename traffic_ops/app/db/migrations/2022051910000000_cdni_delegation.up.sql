@@ -0,0 +1,30 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with this
+ * work for additional information regarding copyright ownership.  The ASF
+ * licenses this file to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+ * License for the specific language governing permissions and limitations under
+ * the License.
+ */
+
+-- The delegation of a Delivery Service to a CDNi downstream CDN (dCDN). The
+-- footprints are those the dCDN advertised it can deliver the Delivery
+-- Service's content to, as of the last configuration push.
+CREATE TABLE IF NOT EXISTS public.cdni_delegation (
+    deliveryservice bigint NOT NULL,
+    dcdn text NOT NULL,
+    host text NOT NULL,
+    footprints jsonb NOT NULL DEFAULT '[]',
+    last_pushed timestamp with time zone,
+    last_updated timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT pk_cdni_delegation PRIMARY KEY (deliveryservice),
+    CONSTRAINT fk_cdni_delegation_deliveryservice FOREIGN KEY (deliveryservice) REFERENCES public.deliveryservice(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
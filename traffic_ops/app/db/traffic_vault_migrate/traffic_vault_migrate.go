@@ -56,8 +56,9 @@ var (
 		LogLocationDebug:   log.LogLocationNull,
 		LogLocationEvent:   log.LogLocationNull,
 	}
-	riakBE RiakBackend = RiakBackend{}
-	pgBE   PGBackend   = PGBackend{}
+	riakBE  RiakBackend  = RiakBackend{}
+	pgBE    PGBackend    = PGBackend{}
+	vaultBE VaultBackend = VaultBackend{}
 )
 
 func init() {
@@ -107,7 +108,7 @@ func init() {
 // supportBackends returns the backends available in this tool.
 func supportedBackends() []TVBackend {
 	return []TVBackend{
-		&riakBE, &pgBE,
+		&riakBE, &pgBE, &vaultBE,
 	}
 }
 
@@ -28,6 +28,7 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-tc"
 	"github.com/apache/trafficcontrol/lib/go-util"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/test"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/hashicorpvault/kv/kvtest"
 
 	"github.com/lestrrat-go/jwx/jwk"
 )
@@ -200,3 +201,76 @@ func TestPGBackend(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestVaultBackend(t *testing.T) {
+	vault := kvtest.NewVault()
+	defer vault.Close()
+
+	vb := VaultBackend{
+		cfg: VaultConfig{
+			Address: vault.URL,
+			Token:   kvtest.Token,
+			Mount:   kvtest.Mount,
+			Prefix:  "trafficvault",
+		},
+	}
+	if err := vb.Start(); err != nil {
+		t.Fatal(err)
+	}
+	testBackend(t, &vb)
+
+	if err := vb.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := vb.GetSSLKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vb.Insert(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vb.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := vb.GetSSLKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Version != "1" || keys[1].Version != latestVersion {
+		t.Fatalf("expected version 1 and latest ssl keys, got: %+v", keys)
+	}
+	if !reflect.DeepEqual(keys[0].DeliveryServiceSSLKeys, expected[0].DeliveryServiceSSLKeys) || !reflect.DeepEqual(keys[1].DeliveryServiceSSLKeys, expected[0].DeliveryServiceSSLKeys) {
+		t.Fatal("expected fetched ssl keys to be the same as those inserted")
+	}
+	if dnssecKeys, err := vb.GetDNSSecKeys(); err != nil || len(dnssecKeys) != 1 {
+		t.Fatalf("expected one dnssec key, got: %d, err: %v", len(dnssecKeys), err)
+	}
+	if urlKeys, err := vb.GetURLSigKeys(); err != nil || len(urlKeys) != 1 {
+		t.Fatalf("expected one url key, got: %d, err: %v", len(urlKeys), err)
+	}
+	if uriKeys, err := vb.GetURISignKeys(); err != nil || len(uriKeys) != 1 {
+		t.Fatalf("expected one uri key, got: %d, err: %v", len(uriKeys), err)
+	}
+}
+
+func TestSSLKeysInsertOrder(t *testing.T) {
+	key := func(ds string, keyVersion int64, version string) SSLKey {
+		return SSLKey{DeliveryServiceSSLKeys: tc.DeliveryServiceSSLKeys{DeliveryService: ds, Version: util.JSONIntStr(keyVersion)}, Version: version}
+	}
+	keys := []SSLKey{
+		key("b", 1, "1"),
+		key("a", 3, latestVersion),
+		key("a", 3, "3"),
+		key("a", 10, "10"),
+		key("a", 2, "2"),
+	}
+	expected := []SSLKey{
+		key("a", 2, "2"),
+		key("a", 10, "10"),
+		key("a", 3, latestVersion),
+		key("b", 1, "1"),
+	}
+	if actual := sslKeysInsertOrder(keys); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/hashicorpvault"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/hashicorpvault/kv"
+)
+
+const latestVersion = "latest"
+
+// VaultConfig represents the configuration options available to the Vault backend.
+type VaultConfig struct {
+	Address   string `json:"address"`
+	Token     string `json:"token"`
+	RoleID    string `json:"role_id"`
+	SecretID  string `json:"secret_id"`
+	LoginPath string `json:"login_path"`
+	Mount     string `json:"mount"`
+	Prefix    string `json:"prefix"`
+	Insecure  bool   `json:"insecure"`
+	// Timeout is the number of seconds each request should use.
+	Timeout int `json:"timeout"`
+}
+
+// VaultBackend is the HashiCorp Vault implementation of TVBackend.
+type VaultBackend struct {
+	sslKeys        []SSLKey
+	dnssecKeys     []DNSSecKey
+	uriSigningKeys []URISignKey
+	urlSigKeys     []URLSigKey
+	cfg            VaultConfig
+	tv             *hashicorpvault.HashiCorpVault
+	client         *kv.Client
+}
+
+// String returns a high level overview of the backend and its keys.
+func (vb *VaultBackend) String() string {
+	data := fmt.Sprintf("Vault server %s (%s/%s)\n", vb.cfg.Address, vb.cfg.Mount, vb.cfg.Prefix)
+	data += fmt.Sprintf("\tSSL Keys: %d\n", len(vb.sslKeys))
+	data += fmt.Sprintf("\tDNSSec Keys: %d\n", len(vb.dnssecKeys))
+	data += fmt.Sprintf("\tURI Signing Keys: %d\n", len(vb.uriSigningKeys))
+	data += fmt.Sprintf("\tURL Sig Keys: %d\n", len(vb.urlSigKeys))
+	return data
+}
+
+// Name returns the name for this backend.
+func (vb *VaultBackend) Name() string {
+	return "Vault"
+}
+
+// ReadConfigFile takes in a filename and will read it into the backends config.
+func (vb *VaultBackend) ReadConfigFile(configFile string) error {
+	if err := UnmarshalConfig(configFile, &vb.cfg); err != nil {
+		return err
+	}
+	if vb.cfg.Address == "" {
+		return errors.New("Vault address is required")
+	}
+	if vb.cfg.Token == "" && (vb.cfg.RoleID == "" || vb.cfg.SecretID == "") {
+		return errors.New("one of either a Vault token or role_id and secret_id is required")
+	}
+	if vb.cfg.Mount == "" {
+		vb.cfg.Mount = kv.DefaultMount
+	}
+	if vb.cfg.Prefix == "" {
+		vb.cfg.Prefix = "trafficvault"
+	}
+	vb.cfg.Prefix = strings.Trim(vb.cfg.Prefix, "/")
+	if vb.cfg.Timeout == 0 {
+		vb.cfg.Timeout = 30
+	}
+	return nil
+}
+
+// Start initiates the connection to the backend DB.
+func (vb *VaultBackend) Start() error {
+	vb.client = kv.NewClient(kv.Config{
+		Address:   vb.cfg.Address,
+		Mount:     vb.cfg.Mount,
+		Token:     vb.cfg.Token,
+		RoleID:    vb.cfg.RoleID,
+		SecretID:  vb.cfg.SecretID,
+		LoginPath: vb.cfg.LoginPath,
+		Timeout:   time.Duration(vb.cfg.Timeout) * time.Second,
+		Insecure:  vb.cfg.Insecure,
+		UserAgent: "traffic_vault_migrate",
+	})
+	if err := vb.client.Login(context.Background()); err != nil {
+		return fmt.Errorf("unable to log in to Vault: %w", err)
+	}
+	vb.tv = hashicorpvault.New(vb.client, vb.cfg.Prefix)
+	vb.sslKeys = nil
+	vb.dnssecKeys = nil
+	vb.uriSigningKeys = nil
+	vb.urlSigKeys = nil
+	return nil
+}
+
+// Close terminates the connection to the backend DB.
+func (vb *VaultBackend) Close() error {
+	return nil
+}
+
+// Ping checks the connection to the backend DB.
+func (vb *VaultBackend) Ping() error {
+	_, err := vb.client.Health(context.Background())
+	return err
+}
+
+// ValidateKey validates that the keys are valid (in most cases, certain fields are not null).
+func (vb *VaultBackend) ValidateKey() []string {
+	var errs []string
+	for _, key := range vb.sslKeys {
+		if key.DeliveryService == "" {
+			errs = append(errs, fmt.Sprintf("SSL Key '%s': DeliveryService is blank!", key.Key))
+		}
+		if key.Version == "" {
+			errs = append(errs, fmt.Sprintf("SSL Key '%s': Version is blank!", key.DeliveryService))
+		}
+	}
+	for _, key := range vb.dnssecKeys {
+		if key.CDN == "" {
+			errs = append(errs, "DNSSEC Key: CDN is blank!")
+		}
+	}
+	for _, key := range vb.uriSigningKeys {
+		if key.DeliveryService == "" {
+			errs = append(errs, "URI Signing Key: DeliveryService is blank!")
+		}
+	}
+	for _, key := range vb.urlSigKeys {
+		if key.DeliveryService == "" {
+			errs = append(errs, "URL Sig Key: DeliveryService is blank!")
+		}
+	}
+	return errs
+}
+
+// Fetch gets all of the keys from the backend DB.
+func (vb *VaultBackend) Fetch() error {
+	ctx := context.Background()
+	if err := vb.fetchSSLKeys(ctx); err != nil {
+		return err
+	}
+
+	cdns, err := vb.tv.ListKeys(ctx, hashicorpvault.DNSSECKeysPath)
+	if err != nil {
+		return fmt.Errorf("VaultDNSSec fetch: %w", err)
+	}
+	vb.dnssecKeys = make([]DNSSecKey, 0, len(cdns))
+	for _, cdn := range cdns {
+		keys, ok, err := vb.tv.GetDNSSECKeys(cdn, nil, ctx)
+		if err != nil {
+			return fmt.Errorf("VaultDNSSec fetch '%s': %w", cdn, err)
+		}
+		if ok {
+			vb.dnssecKeys = append(vb.dnssecKeys, DNSSecKey{CDN: cdn, DNSSECKeysTrafficVault: keys})
+		}
+	}
+
+	xmlIDs, err := vb.tv.ListKeys(ctx, hashicorpvault.URLSigKeysPath)
+	if err != nil {
+		return fmt.Errorf("VaultURLSig fetch: %w", err)
+	}
+	vb.urlSigKeys = make([]URLSigKey, 0, len(xmlIDs))
+	for _, xmlID := range xmlIDs {
+		keys, ok, err := vb.tv.GetURLSigKeys(xmlID, nil, ctx)
+		if err != nil {
+			return fmt.Errorf("VaultURLSig fetch '%s': %w", xmlID, err)
+		}
+		if ok {
+			vb.urlSigKeys = append(vb.urlSigKeys, URLSigKey{DeliveryService: xmlID, URLSigKeys: keys})
+		}
+	}
+
+	xmlIDs, err = vb.tv.ListKeys(ctx, hashicorpvault.URISigningKeysPath)
+	if err != nil {
+		return fmt.Errorf("VaultURISign fetch: %w", err)
+	}
+	vb.uriSigningKeys = make([]URISignKey, 0, len(xmlIDs))
+	for _, xmlID := range xmlIDs {
+		data, ok, err := vb.tv.GetURISigningKeys(xmlID, nil, ctx)
+		if err != nil {
+			return fmt.Errorf("VaultURISign fetch '%s': %w", xmlID, err)
+		}
+		if !ok {
+			continue
+		}
+		keys := tc.JWKSMap{}
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return fmt.Errorf("VaultURISign fetch '%s' unable to unmarshal keys: %w", xmlID, err)
+		}
+		vb.uriSigningKeys = append(vb.uriSigningKeys, URISignKey{DeliveryService: xmlID, Keys: keys})
+	}
+	return nil
+}
+
+// fetchSSLKeys gets every retained version of every Delivery Service's SSL
+// keys, plus the latest version of each as version "latest", matching the
+// representation of the other backends.
+func (vb *VaultBackend) fetchSSLKeys(ctx context.Context) error {
+	xmlIDs, err := vb.tv.ListKeys(ctx, hashicorpvault.SSLKeysPath)
+	if err != nil {
+		return fmt.Errorf("VaultSSLKey fetch: %w", err)
+	}
+	vb.sslKeys = []SSLKey{}
+	for _, xmlID := range xmlIDs {
+		versions, err := vb.tv.GetDeliveryServiceSSLKeyVersions(xmlID, ctx)
+		if err != nil {
+			return fmt.Errorf("VaultSSLKey fetch '%s' versions: %w", xmlID, err)
+		}
+		for _, version := range versions {
+			v := strconv.FormatInt(version, 10)
+			keys, ok, err := vb.tv.GetDeliveryServiceSSLKeys(xmlID, v, nil, ctx)
+			if err != nil {
+				return fmt.Errorf("VaultSSLKey fetch '%s' version %s: %w", xmlID, v, err)
+			}
+			if ok {
+				vb.sslKeys = append(vb.sslKeys, SSLKey{DeliveryServiceSSLKeys: keys.DeliveryServiceSSLKeys, Version: v})
+			}
+		}
+		keys, ok, err := vb.tv.GetDeliveryServiceSSLKeys(xmlID, "", nil, ctx)
+		if err != nil {
+			return fmt.Errorf("VaultSSLKey fetch '%s' latest: %w", xmlID, err)
+		}
+		if ok {
+			vb.sslKeys = append(vb.sslKeys, SSLKey{DeliveryServiceSSLKeys: keys.DeliveryServiceSSLKeys, Version: latestVersion})
+		}
+	}
+	return nil
+}
+
+// Insert takes the current keys and inserts them into the backend DB. SSL keys
+// are copied as they are, so keys with an unparseable certificate are migrated
+// too instead of aborting the migration.
+func (vb *VaultBackend) Insert() error {
+	ctx := context.Background()
+	for _, key := range sslKeysInsertOrder(vb.sslKeys) {
+		if err := vb.tv.CopyDeliveryServiceSSLKeys(key.DeliveryServiceSSLKeys, ctx); err != nil {
+			return fmt.Errorf("VaultSSLKey insert '%s' version %s: %w", key.DeliveryService, key.Version, err)
+		}
+	}
+	for _, key := range vb.dnssecKeys {
+		if err := vb.tv.PutDNSSECKeys(key.CDN, key.DNSSECKeysTrafficVault, nil, ctx); err != nil {
+			return fmt.Errorf("VaultDNSSec insert '%s': %w", key.CDN, err)
+		}
+	}
+	for _, key := range vb.urlSigKeys {
+		if err := vb.tv.PutURLSigKeys(key.DeliveryService, key.URLSigKeys, nil, ctx); err != nil {
+			return fmt.Errorf("VaultURLSig insert '%s': %w", key.DeliveryService, err)
+		}
+	}
+	for _, key := range vb.uriSigningKeys {
+		data, err := json.Marshal(key.Keys)
+		if err != nil {
+			return fmt.Errorf("VaultURISign insert '%s' unable to marshal keys: %w", key.DeliveryService, err)
+		}
+		if err := vb.tv.PutURISigningKeys(key.DeliveryService, data, nil, ctx); err != nil {
+			return fmt.Errorf("VaultURISign insert '%s': %w", key.DeliveryService, err)
+		}
+	}
+	return nil
+}
+
+// sslKeysInsertOrder returns the SSL keys in the order in which they must be
+// written to Vault so that each Delivery Service's "latest" keys are written
+// last, and so become the current version of its secret. Numbered versions
+// which duplicate the latest keys are omitted, since writing the latest keys
+// maps their version as well.
+func sslKeysInsertOrder(keys []SSLKey) []SSLKey {
+	latest := map[string]SSLKey{}
+	for _, key := range keys {
+		if key.Version == latestVersion {
+			latest[key.DeliveryService] = key
+		}
+	}
+	ordered := make([]SSLKey, 0, len(keys))
+	for _, key := range keys {
+		if key.Version == latestVersion {
+			continue
+		}
+		if l, ok := latest[key.DeliveryService]; ok && strconv.FormatInt(l.DeliveryServiceSSLKeys.Version.ToInt64(), 10) == key.Version {
+			continue
+		}
+		ordered = append(ordered, key)
+	}
+	for _, key := range latest {
+		ordered = append(ordered, key)
+	}
+	sort.SliceStable(ordered, func(a, b int) bool {
+		if ordered[a].DeliveryService != ordered[b].DeliveryService {
+			return ordered[a].DeliveryService < ordered[b].DeliveryService
+		}
+		if ordered[a].Version == latestVersion || ordered[b].Version == latestVersion {
+			return ordered[b].Version == latestVersion && ordered[a].Version != latestVersion
+		}
+		versionA, _ := strconv.ParseInt(ordered[a].Version, 10, 64)
+		versionB, _ := strconv.ParseInt(ordered[b].Version, 10, 64)
+		return versionA < versionB
+	})
+	return ordered
+}
+
+// GetSSLKeys converts the backends internal key representation into the common representation (SSLKey).
+func (vb *VaultBackend) GetSSLKeys() ([]SSLKey, error) {
+	return vb.sslKeys, nil
+}
+
+// SetSSLKeys takes in keys and converts & encrypts the data into the backends internal format.
+func (vb *VaultBackend) SetSSLKeys(keys []SSLKey) error {
+	vb.sslKeys = keys
+	return nil
+}
+
+// GetDNSSecKeys converts the backends internal key representation into the common representation (DNSSecKey).
+func (vb *VaultBackend) GetDNSSecKeys() ([]DNSSecKey, error) {
+	return vb.dnssecKeys, nil
+}
+
+// SetDNSSecKeys takes in keys and converts & encrypts the data into the backends internal format.
+func (vb *VaultBackend) SetDNSSecKeys(keys []DNSSecKey) error {
+	vb.dnssecKeys = keys
+	return nil
+}
+
+// GetURISignKeys converts the backends internal key representation into the common representation (URISignKey).
+func (vb *VaultBackend) GetURISignKeys() ([]URISignKey, error) {
+	return vb.uriSigningKeys, nil
+}
+
+// SetURISignKeys takes in keys and converts & encrypts the data into the backends internal format.
+func (vb *VaultBackend) SetURISignKeys(keys []URISignKey) error {
+	vb.uriSigningKeys = keys
+	return nil
+}
+
+// GetURLSigKeys converts the backends internal key representation into the common representation (URLSigKey).
+func (vb *VaultBackend) GetURLSigKeys() ([]URLSigKey, error) {
+	return vb.urlSigKeys, nil
+}
+
+// SetURLSigKeys takes in keys and converts & encrypts the data into the backends internal format.
+func (vb *VaultBackend) SetURLSigKeys(keys []URLSigKey) error {
+	vb.urlSigKeys = keys
+	return nil
+}
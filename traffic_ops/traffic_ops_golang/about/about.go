@@ -21,6 +21,7 @@ package about
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime"
 	"strings"
@@ -74,6 +75,12 @@ func SetAbout(s string) {
 	}
 }
 
+// UserAgent returns the User-Agent of requests Traffic Ops makes to other
+// services.
+func UserAgent() string {
+	return fmt.Sprintf("TrafficOps/%s (Go)", About.RPMVersion)
+}
+
 // Handler returns info about running Traffic Ops
 func Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -86,6 +86,16 @@ func CreateChangeLogRawErr(level string, msg string, user *auth.CurrentUser, tx
 	return nil
 }
 
+// CreateChangeLogRawID creates a change log entry with the given message, and
+// returns its ID.
+func CreateChangeLogRawID(level string, msg string, user *auth.CurrentUser, tx *sql.Tx) (int, error) {
+	id := 0
+	if err := tx.QueryRow(`INSERT INTO log (level, message, tm_user) VALUES ($1, $2, $3) RETURNING id`, level, msg, user.ID).Scan(&id); err != nil {
+		return 0, errors.New("Inserting change log level '" + level + "' message '" + msg + "' user '" + user.UserName + "': " + err.Error())
+	}
+	return id, nil
+}
+
 func CreateChangeLogRawTx(level string, msg string, user *auth.CurrentUser, tx *sql.Tx) {
 	if _, err := tx.Exec(`INSERT INTO log (level, message, tm_user) VALUES ($1, $2, $3)`, level, msg, user.ID); err != nil {
 		log.Errorln("Inserting change log level '" + level + "' message '" + msg + "' user '" + user.UserName + "': " + err.Error())
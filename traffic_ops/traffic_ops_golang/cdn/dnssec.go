@@ -93,7 +93,28 @@ func CreateDNSSECKeys(w http.ResponseWriter, r *http.Request) {
 		api.HandleErr(w, r, inf.Tx.Tx, statusCode, userErr, sysErr)
 		return
 	}
-	if err := generateStoreDNSSECKeys(inf.Tx.Tx, cdnName, cdnDomain, uint64(*req.TTL), uint64(*req.KSKExpirationDays), uint64(*req.ZSKExpirationDays), int64(*req.EffectiveDateUnix), inf.Vault, r.Context()); err != nil {
+
+	oldKeys, _, err := inf.Vault.GetDNSSECKeys(cdnName, inf.Tx.Tx, r.Context())
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting old dnssec keys: "+err.Error()))
+		return
+	}
+	oldCDNKeys := oldKeys[cdnName]
+	if oldCDNKeys.Rollover.InProgress() {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusConflict, errors.New("a DNSSEC algorithm rollover of cdn '"+cdnName+"' is in progress, it must be completed or cancelled before generating keys"), nil)
+		return
+	}
+	alerts := tc.Alerts{}
+	algorithm := deliveryservice.GetDNSSECKeysAlgorithm(oldCDNKeys.KSK, tc.DefaultDNSSECAlgorithm)
+	if req.Algorithm != nil {
+		if len(oldCDNKeys.KSK) > 0 && *req.Algorithm != algorithm {
+			alerts.AddNewAlert(tc.WarnLevel, "the DNSSEC algorithm of cdn '"+cdnName+"' was changed from "+strconv.Itoa(int(algorithm))+" to "+strconv.Itoa(int(*req.Algorithm))+" without a rollover, its DS record must be replaced in the parent zone before the keys are effective; use an algorithm rollover to change the algorithm of a CDN in use")
+		}
+		algorithm = *req.Algorithm
+	}
+	alerts.AddAlerts(dnssecAlgorithmAlerts(algorithm))
+
+	if err := generateStoreDNSSECKeys(inf.Tx.Tx, cdnName, cdnDomain, uint64(*req.TTL), uint64(*req.KSKExpirationDays), uint64(*req.ZSKExpirationDays), int64(*req.EffectiveDateUnix), algorithm, inf.Vault, r.Context()); err != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("generating and storing DNSSEC CDN keys: "+err.Error()))
 		return
 	}
@@ -116,8 +137,18 @@ func CreateDNSSECKeys(w http.ResponseWriter, r *http.Request) {
 			log.Errorln("generating CDN DNSSEC keys: committing transaction for changelog: " + err.Error())
 		}
 	}()
-	api.CreateChangeLogRawTx(api.ApiChange, "CDN: "+cdnName+", ID: "+strconv.Itoa(cdnID)+", ACTION: Generated DNSSEC keys", inf.User, logTx.Tx)
-	api.WriteResp(w, r, "Successfully created dnssec keys for "+cdnName)
+	api.CreateChangeLogRawTx(api.ApiChange, "CDN: "+cdnName+", ID: "+strconv.Itoa(cdnID)+", ACTION: Generated DNSSEC keys with algorithm "+strconv.Itoa(int(algorithm)), inf.User, logTx.Tx)
+	api.WriteAlertsObj(w, r, http.StatusOK, alerts, "Successfully created dnssec keys for "+cdnName)
+}
+
+// dnssecAlgorithmAlerts returns warnings about using keys of the given DNSSEC algorithm, if any.
+func dnssecAlgorithmAlerts(algorithm uint8) tc.Alerts {
+	alerts := tc.Alerts{}
+	switch algorithm {
+	case tc.DNSSECAlgorithmRSASHA1:
+		alerts.AddNewAlert(tc.WarnLevel, "DNSSEC algorithm 5 (RSASHA1) is deprecated, algorithm 13 (ECDSAP256SHA256) is recommended")
+	}
+	return alerts
 }
 
 // DefaultDSTTL is the default DS Record TTL to use, if no CDN Snapshot exists, or if no tld.ttls.DS parameter exists.
@@ -186,6 +217,7 @@ func generateStoreDNSSECKeys(
 	kExpDays uint64,
 	zExpDays uint64,
 	effectiveDateUnix int64,
+	algorithm uint8,
 	tv trafficvault.TrafficVault,
 	ctx context.Context,
 ) error {
@@ -211,12 +243,12 @@ func generateStoreDNSSECKeys(
 	cdnDNSDomain = strings.ToLower(cdnDNSDomain)
 
 	inception := time.Now()
-	newCDNZSK, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECZSKType, cdnDNSDomain, ttl, inception, inception.Add(zExp), tc.DNSSECKeyStatusNew, time.Unix(effectiveDateUnix, 0), false)
+	newCDNZSK, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECZSKType, cdnDNSDomain, ttl, inception, inception.Add(zExp), tc.DNSSECKeyStatusNew, time.Unix(effectiveDateUnix, 0), false, algorithm)
 	if err != nil {
 		return errors.New("creating zsk for cdn: " + err.Error())
 	}
 
-	newCDNKSK, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECKSKType, cdnDNSDomain, ttl, inception, inception.Add(kExp), tc.DNSSECKeyStatusNew, time.Unix(effectiveDateUnix, 0), true)
+	newCDNKSK, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECKSKType, cdnDNSDomain, ttl, inception, inception.Add(kExp), tc.DNSSECKeyStatusNew, time.Unix(effectiveDateUnix, 0), true, algorithm)
 	if err != nil {
 		return errors.New("creating ksk for cdn: " + err.Error())
 	}
@@ -35,6 +35,7 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-util"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/auth"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault"
 
@@ -112,7 +113,7 @@ func refreshDNSSECKeys(ctx context.Context) (int, bool, int, error, error) {
 			unsetInDNSSECKeyRefresh()
 			return 0, false, status, usrErr, sysErr
 		}
-		go doDNSSECKeyRefresh(tx, db, tv, jobID, user) // doDNSSECKeyRefresh takes ownership of tx and MUST close it.
+		go doDNSSECKeyRefresh(tx, db, tv, cfg.DSPublications, jobID, user) // doDNSSECKeyRefresh takes ownership of tx and MUST close it.
 		return jobID, true, http.StatusAccepted, nil, nil
 	} else {
 		log.Infoln("RefreshDNSSECKeys called, while server was concurrently executing a refresh, doing nothing")
@@ -127,9 +128,10 @@ const DNSSECKeyRefreshDefaultKSKExpiration = time.Duration(365) * time.Hour * 24
 const DNSSECKeyRefreshDefaultZSKExpiration = time.Duration(30) * time.Hour * 24
 
 // doDNSSECKeyRefresh refreshes the CDN's DNSSEC keys, as necessary.
+// The DS records of CDNs with a DS publication in dsPubs are published in their parent zones, and their old KSKs aren't retired until the parent serves the new DS records.
 // This takes ownership of tx, and MUST call `tx.Close()`.
 // This SHOULD only be called if setInDNSSECKeyRefresh() returned true, in which case this MUST call unsetInDNSSECKeyRefresh() before returning.
-func doDNSSECKeyRefresh(tx *sql.Tx, asyncDB *sqlx.DB, tv trafficvault.TrafficVault, jobID int, user *auth.CurrentUser) {
+func doDNSSECKeyRefresh(tx *sql.Tx, asyncDB *sqlx.DB, tv trafficvault.TrafficVault, dsPubs []config.ConfigDSPublication, jobID int, user *auth.CurrentUser) {
 	doCommit := true
 	defer func() {
 		if doCommit {
@@ -326,6 +328,26 @@ func doDNSSECKeyRefresh(tx *sql.Tx, asyncDB *sqlx.DB, tv trafficvault.TrafficVau
 				}
 			}
 		}
+		for _, pub := range dsPubs {
+			if pub.CDN != string(cdnInf.CDNName) {
+				continue
+			}
+			cdnKeys := keys[string(cdnInf.CDNName)]
+			unserved, publishErr := syncDSRecords(pub, cdnInf.CDNDomain, cdnKeys, time.Now())
+			if publishErr != nil {
+				log.Errorln("refreshing DNSSEC Keys: publishing DS records of cdn '" + string(cdnInf.CDNName) + "': " + publishErr.Error())
+				errCount++
+			}
+			if held, err := holdDNSSECKSKRetirement(cdnKeys, unserved, errors.Is(publishErr, errDSRecordsUnchecked), time.Now()); err != nil {
+				log.Errorln("refreshing DNSSEC Keys: holding KSK retirement of cdn '" + string(cdnInf.CDNName) + "': " + err.Error())
+				errCount++
+			} else if held {
+				updateCount++
+			}
+			if err := updateDSPublicationNotification(tx, string(cdnInf.CDNName), user.UserName, unserved, publishErr); err != nil {
+				log.Errorln("refreshing DNSSEC Keys: updating DS publication notification of cdn '" + string(cdnInf.CDNName) + "': " + err.Error())
+			}
+		}
 		if updateCount > 0 {
 			if err := tv.PutDNSSECKeys(string(cdnInf.CDNName), keys, tx, context.Background()); err != nil {
 				log.Errorln("refreshing DNSSEC Keys: putting keys into Traffic Vault for cdn '" + string(cdnInf.CDNName) + "': " + err.Error())
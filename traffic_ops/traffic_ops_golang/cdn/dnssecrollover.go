@@ -0,0 +1,562 @@
+package cdn
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+)
+
+// DNSSECKeyStatusRetiring is the status of the keys of the algorithm a DNSSEC
+// algorithm rollover is rolling over from. Retiring keys still sign zones, but
+// aren't refreshed.
+const DNSSECKeyStatusRetiring = "retiring"
+
+// DNSSECKeyStatusUnpublished is the status of the keys of the algorithm a
+// DNSSEC algorithm rollover is rolling over from, once their DNSKEY records are
+// removed. Traffic Router doesn't serve the DNSKEY records of unpublished keys,
+// but still signs zones with them until they expire.
+const DNSSECKeyStatusUnpublished = "unpublished"
+
+// DNSSECRolloverRetiringKeyMinLifetime is the minimum time retiring keys are
+// valid for when a DNSSEC algorithm rollover starts, because they aren't
+// refreshed during the rollover.
+const DNSSECRolloverRetiringKeyMinLifetime = 30 * 24 * time.Hour
+
+// GetDNSSECRollover is the handler for GET requests for a CDN's DNSSEC algorithm rollover.
+func GetDNSSECRollover(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+	if !inf.Config.TrafficVaultEnabled {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting CDN DNSSEC rollover: Traffic Vault is not configured"))
+		return
+	}
+
+	cdnName := inf.Params["name"]
+	keys, _, err := inf.Vault.GetDNSSECKeys(cdnName, inf.Tx.Tx, r.Context())
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting CDN DNSSEC keys: "+err.Error()))
+		return
+	}
+	rollover := keys[cdnName].Rollover
+	if rollover == nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("cdn '"+cdnName+"' has no DNSSEC algorithm rollover"), nil)
+		return
+	}
+	api.WriteResp(w, r, rollover)
+}
+
+// StartDNSSECRollover is the handler for POST requests to start a CDN's DNSSEC algorithm rollover.
+func StartDNSSECRollover(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	req := tc.CDNDNSSECRolloverReq{}
+	if err := api.Parse(r.Body, inf.Tx.Tx, &req); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("parsing request: "+err.Error()), nil)
+		return
+	}
+
+	cdnName, cdnID, keys, wait, userErr, sysErr, errCode := getDNSSECRolloverInfo(inf, r.Context())
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	dsPub, dsPubOK := inf.Config.GetDSPublication(cdnName)
+	dsTTL := uint64(tc.DefaultDNSSECRolloverDSTTL)
+	if req.DSTTL != nil {
+		dsTTL = *req.DSTTL
+	} else if dsPubOK {
+		dsTTL = uint64(dsPub.DSTTLSec)
+	}
+
+	userErr, sysErr, errCode = startDNSSECRollover(keys, cdnName, *req.Algorithm, dsTTL, wait, time.Now(), inf.User.UserName)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	if err := inf.Vault.PutDNSSECKeys(cdnName, keys, inf.Tx.Tx, r.Context()); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("putting CDN DNSSEC keys: "+err.Error()))
+		return
+	}
+
+	rollover := keys[cdnName].Rollover
+	alerts := dnssecAlgorithmAlerts(rollover.ToAlgorithm)
+	if dsPubOK {
+		alerts.AddNewAlert(tc.InfoLevel, "after "+rollover.NextStageAllowed.Format(time.RFC3339)+", advance the rollover to '"+tc.DNSSECRolloverStageDSUpdate+"' to publish the new DS record of cdn '"+cdnName+"' in its parent zone")
+	} else if dsText, err := getDNSSECRolloverDSRecordText(keys[cdnName]); err != nil {
+		log.Errorln("starting CDN DNSSEC rollover: making DS record text: " + err.Error())
+	} else {
+		alerts.AddNewAlert(tc.InfoLevel, "after "+rollover.NextStageAllowed.Format(time.RFC3339)+", replace the DS record of cdn '"+cdnName+"' in its parent zone with '"+dsText+"' and advance the rollover to '"+tc.DNSSECRolloverStageDSUpdate+"'")
+	}
+	alerts.AddNewAlert(tc.SuccessLevel, "Started DNSSEC algorithm rollover of cdn '"+cdnName+"' to algorithm "+strconv.Itoa(int(rollover.ToAlgorithm)))
+
+	// generating keys for every Delivery Service may take long enough for the main transaction to time out
+	if err := createDNSSECRolloverChangeLog(r, inf, "CDN: "+cdnName+", ID: "+strconv.Itoa(cdnID)+", ACTION: Started DNSSEC algorithm rollover from "+strconv.Itoa(int(rollover.FromAlgorithm))+" to "+strconv.Itoa(int(rollover.ToAlgorithm))); err != nil {
+		log.Errorln("starting CDN DNSSEC rollover: creating changelog: " + err.Error())
+	}
+	api.WriteAlertsObj(w, r, http.StatusOK, alerts, rollover)
+}
+
+// UpdateDNSSECRollover is the handler for PUT requests to advance a CDN's DNSSEC algorithm rollover to its next stage.
+func UpdateDNSSECRollover(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	req := tc.CDNDNSSECRolloverUpdateReq{}
+	if err := api.Parse(r.Body, inf.Tx.Tx, &req); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("parsing request: "+err.Error()), nil)
+		return
+	}
+
+	cdnName, cdnID, keys, wait, userErr, sysErr, errCode := getDNSSECRolloverInfo(inf, r.Context())
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	dsPub, dsPubOK := inf.Config.GetDSPublication(cdnName)
+	cdnDomain := ""
+	if dsPubOK {
+		domain, ok, err := dbhelpers.GetCDNDomainFromName(inf.Tx.Tx, tc.CDNName(cdnName))
+		if err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting CDN domain: "+err.Error()))
+			return
+		} else if !ok {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("cdn '"+cdnName+"' not found"), nil)
+			return
+		}
+		cdnDomain = domain
+	}
+
+	// the old keys may only be retired once the parent zone serves the new DS record
+	if dsPubOK && *req.Stage == tc.DNSSECRolloverStageRetire && keys[cdnName].Rollover.InProgress() && keys[cdnName].Rollover.Stage == tc.DNSSECRolloverStageDSUpdate {
+		desired, err := getDesiredDSRecords(keys[cdnName], time.Duration(dsPub.DSTTLSec)*time.Second, time.Now())
+		if err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting desired DS records: "+err.Error()))
+			return
+		}
+		unserved, err := getUnservedDSRecords(dsPub, cdnDomain, desired)
+		if err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadGateway, errors.New("checking the DS records of the parent zone of cdn '"+cdnName+"': "+err.Error()), nil)
+			return
+		}
+		if len(unserved) > 0 {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusConflict, fmt.Errorf("the parent zone of cdn '%s' doesn't serve the new DS record with tag %d yet", cdnName, unserved[0].KeyTag), nil)
+			return
+		}
+	}
+
+	if userErr, errCode = advanceDNSSECRollover(keys, cdnName, *req.Stage, wait, time.Now(), inf.User.UserName); userErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, nil)
+		return
+	}
+	if err := inf.Vault.PutDNSSECKeys(cdnName, keys, inf.Tx.Tx, r.Context()); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("putting CDN DNSSEC keys: "+err.Error()))
+		return
+	}
+
+	rollover := keys[cdnName].Rollover
+	alerts := tc.Alerts{}
+	if dsPubOK && rollover.Stage == tc.DNSSECRolloverStageDSUpdate {
+		alerts = syncDSRecordsAlerts(dsPub, cdnDomain, keys[cdnName])
+	}
+	api.CreateChangeLogRawTx(api.ApiChange, "CDN: "+cdnName+", ID: "+strconv.Itoa(cdnID)+", ACTION: Advanced DNSSEC algorithm rollover to "+rollover.Stage, inf.User, inf.Tx.Tx)
+	alerts.AddNewAlert(tc.SuccessLevel, "Advanced DNSSEC algorithm rollover of cdn '"+cdnName+"' to '"+rollover.Stage+"'")
+	api.WriteAlertsObj(w, r, http.StatusOK, alerts, rollover)
+}
+
+// CancelDNSSECRollover is the handler for DELETE requests to cancel a CDN's DNSSEC algorithm rollover, which may only be done before the DS record is updated.
+func CancelDNSSECRollover(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	cdnName, cdnID, keys, _, userErr, sysErr, errCode := getDNSSECRolloverInfo(inf, r.Context())
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	if userErr, errCode = cancelDNSSECRollover(keys, cdnName); userErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, nil)
+		return
+	}
+	if err := inf.Vault.PutDNSSECKeys(cdnName, keys, inf.Tx.Tx, r.Context()); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("putting CDN DNSSEC keys: "+err.Error()))
+		return
+	}
+
+	api.CreateChangeLogRawTx(api.ApiChange, "CDN: "+cdnName+", ID: "+strconv.Itoa(cdnID)+", ACTION: Cancelled DNSSEC algorithm rollover", inf.User, inf.Tx.Tx)
+	api.WriteRespAlert(w, r, tc.SuccessLevel, "Cancelled DNSSEC algorithm rollover of cdn '"+cdnName+"'")
+}
+
+// getDNSSECRolloverInfo checks that the current user may modify the requested CDN, and returns its name, ID, DNSSEC keys, and the time to wait for records to expire from resolver caches between rollover stages.
+func getDNSSECRolloverInfo(inf *api.APIInfo, ctx context.Context) (string, int, tc.DNSSECKeysTrafficVault, time.Duration, error, error, int) {
+	if !inf.Config.TrafficVaultEnabled {
+		return "", 0, nil, 0, nil, errors.New("DNSSEC rollover: Traffic Vault is not configured"), http.StatusInternalServerError
+	}
+	cdnName := inf.Params["name"]
+	cdnID, ok, err := getCDNIDFromName(inf.Tx.Tx, tc.CDNName(cdnName))
+	if err != nil {
+		return "", 0, nil, 0, nil, errors.New("getting cdn ID from name '" + cdnName + "': " + err.Error()), http.StatusInternalServerError
+	} else if !ok {
+		return "", 0, nil, 0, errors.New("cdn '" + cdnName + "' not found"), nil, http.StatusNotFound
+	}
+	userErr, sysErr, errCode := dbhelpers.CheckIfCurrentUserCanModifyCDN(inf.Tx.Tx, cdnName, inf.User.UserName)
+	if userErr != nil || sysErr != nil {
+		return "", 0, nil, 0, userErr, sysErr, errCode
+	}
+
+	keys, ok, err := inf.Vault.GetDNSSECKeys(cdnName, inf.Tx.Tx, ctx)
+	if err != nil {
+		return "", 0, nil, 0, nil, errors.New("getting CDN DNSSEC keys: " + err.Error()), http.StatusInternalServerError
+	} else if !ok || len(keys[cdnName].KSK) == 0 {
+		return "", 0, nil, 0, errors.New("cdn '" + cdnName + "' has no DNSSEC keys"), nil, http.StatusBadRequest
+	}
+
+	ttl, multiplier, err := getKSKParams(inf.Tx.Tx, tc.CDNName(cdnName))
+	if err != nil {
+		return "", 0, nil, 0, nil, errors.New("getting CDN KSK parameters: " + err.Error()), http.StatusInternalServerError
+	}
+	wait := time.Duration(DefaultKSKTTLSeconds) * time.Second
+	if ttl != nil {
+		wait = time.Duration(*ttl) * time.Second
+	}
+	if multiplier != nil {
+		wait *= time.Duration(*multiplier)
+	} else {
+		wait *= DefaultKSKEffectiveMultiplier
+	}
+	return cdnName, cdnID, keys, wait, nil, nil, http.StatusOK
+}
+
+// createDNSSECRolloverChangeLog creates a changelog entry in its own transaction, with its own timeout.
+func createDNSSECRolloverChangeLog(r *http.Request, inf *api.APIInfo, msg string) error {
+	db, err := api.GetDB(r.Context())
+	if err != nil {
+		return errors.New("getting DB from request context: " + err.Error())
+	}
+	logCtx, logCancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer logCancel()
+	logTx, err := db.BeginTxx(logCtx, nil)
+	if err != nil {
+		return errors.New("beginning transaction: " + err.Error())
+	}
+	api.CreateChangeLogRawTx(api.ApiChange, msg, inf.User, logTx.Tx)
+	if err := logTx.Commit(); err != nil && err != sql.ErrTxDone {
+		return errors.New("committing transaction: " + err.Error())
+	}
+	return nil
+}
+
+// startDNSSECRollover starts a rollover of the CDN's DNSSEC keys to the given algorithm, adding keys of that algorithm to the key set of the CDN and every Delivery Service in keys.
+// The keys of the CDN's current algorithm are kept, with the status DNSSECKeyStatusRetiring, so zones are signed with both algorithms.
+// Returns a user error, system error, and HTTP status.
+func startDNSSECRollover(keys tc.DNSSECKeysTrafficVault, cdnName string, algorithm uint8, dsTTL uint64, wait time.Duration, now time.Time, user string) (error, error, int) {
+	cdnKeys := keys[cdnName]
+	if cdnKeys.Rollover.InProgress() {
+		return fmt.Errorf("cdn '%s' already has a DNSSEC algorithm rollover to algorithm %d in stage '%s'", cdnName, cdnKeys.Rollover.ToAlgorithm, cdnKeys.Rollover.Stage), nil, http.StatusConflict
+	}
+	fromAlgorithm := deliveryservice.GetDNSSECKeysAlgorithm(cdnKeys.KSK, 0)
+	if fromAlgorithm == 0 {
+		return nil, errors.New("getting the DNSSEC algorithm of cdn '" + cdnName + "': no valid KSK with status '" + tc.DNSSECKeyStatusNew + "'"), http.StatusInternalServerError
+	}
+	if fromAlgorithm == algorithm {
+		return fmt.Errorf("the DNSSEC keys of cdn '%s' already use algorithm %d", cdnName, algorithm), nil, http.StatusBadRequest
+	}
+
+	names := make([]string, 0, len(keys))
+	for name, _ := range keys {
+		names = append(names, name)
+	}
+	type rolloverResult struct {
+		name    string
+		keySet  tc.DNSSECKeySetV11
+		err     error
+		skipped bool
+	}
+	numWorkers := int(math.Max(1, math.Floor(float64(runtime.NumCPU())*DNSSECGenerationCPURatio)))
+	sem := make(chan struct{}, numWorkers)
+	results := make(chan rolloverResult, len(names))
+	wg := sync.WaitGroup{}
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			keySet := keys[name]
+			// Delivery Services created since the CDN's keys were generated, or during an earlier rollover, may already use the algorithm
+			if name != cdnName && deliveryservice.GetDNSSECKeysAlgorithm(keySet.KSK, 0) == algorithm {
+				results <- rolloverResult{name: name, skipped: true}
+				return
+			}
+			tld := name == cdnName
+			newKeySet, err := addDNSSECRolloverKeys(keySet, algorithm, tld, now)
+			results <- rolloverResult{name: name, keySet: newKeySet, err: err}
+		}(name)
+	}
+	wg.Wait()
+	close(results)
+
+	for res := range results {
+		if res.err != nil {
+			return nil, fmt.Errorf("generating algorithm %d DNSSEC keys for '%s': %w", algorithm, res.name, res.err), http.StatusInternalServerError
+		}
+		if !res.skipped {
+			keys[res.name] = res.keySet
+		}
+	}
+
+	cdnKeys = keys[cdnName]
+	cdnKeys.Rollover = &tc.DNSSECAlgorithmRollover{
+		FromAlgorithm:    fromAlgorithm,
+		ToAlgorithm:      algorithm,
+		Stage:            tc.DNSSECRolloverStagePublish,
+		DSTTLSeconds:     dsTTL,
+		Started:          now,
+		StageStarted:     now,
+		NextStageAllowed: now.Add(wait),
+		LastUpdatedBy:    user,
+	}
+	keys[cdnName] = cdnKeys
+	return nil, nil, http.StatusOK
+}
+
+// addDNSSECRolloverKeys returns the key set with new keys of the given algorithm, and its current keys retiring.
+// The new keys have the name, TTL, and lifetime of the current keys. If tld, the new KSK has a DS record.
+func addDNSSECRolloverKeys(keySet tc.DNSSECKeySetV11, algorithm uint8, tld bool, now time.Time) (tc.DNSSECKeySetV11, error) {
+	addKeys := func(keyType string, keys []tc.DNSSECKeyV11) ([]tc.DNSSECKeyV11, error) {
+		newKeys := []tc.DNSSECKeyV11{}
+		for _, key := range keys {
+			if key.Status != tc.DNSSECKeyStatusNew {
+				newKeys = append(newKeys, key)
+				continue
+			}
+			lifetime := time.Duration(key.ExpirationDateUnix-key.InceptionDateUnix) * time.Second
+			ttl := time.Duration(key.TTLSeconds) * time.Second
+			newKey, err := deliveryservice.GetDNSSECKeysV11(keyType, key.Name, ttl, now, now.Add(lifetime), tc.DNSSECKeyStatusNew, now, tld, algorithm)
+			if err != nil {
+				return nil, errors.New("generating " + keyType + ": " + err.Error())
+			}
+			key.Status = DNSSECKeyStatusRetiring
+			if minExpiration := now.Add(DNSSECRolloverRetiringKeyMinLifetime).Unix(); key.ExpirationDateUnix < minExpiration {
+				key.ExpirationDateUnix = minExpiration
+			}
+			newKeys = append([]tc.DNSSECKeyV11{newKey}, newKeys...)
+			newKeys = append(newKeys, key)
+		}
+		if len(newKeys) == len(keys) {
+			return nil, errors.New("no " + keyType + " with status '" + tc.DNSSECKeyStatusNew + "'")
+		}
+		return newKeys, nil
+	}
+
+	zsk, err := addKeys(tc.DNSSECZSKType, keySet.ZSK)
+	if err != nil {
+		return tc.DNSSECKeySetV11{}, err
+	}
+	ksk, err := addKeys(tc.DNSSECKSKType, keySet.KSK)
+	if err != nil {
+		return tc.DNSSECKeySetV11{}, err
+	}
+	keySet.ZSK = zsk
+	keySet.KSK = ksk
+	return keySet, nil
+}
+
+// advanceDNSSECRollover advances the CDN's DNSSEC algorithm rollover to the given stage, which must be its next stage.
+// Advancing to 'retire' unpublishes the retiring keys, advancing to 'retire-signatures' expires them, and advancing to 'complete' removes the keys of the old algorithm from every key set in keys.
+// Returns a user error and HTTP status.
+func advanceDNSSECRollover(keys tc.DNSSECKeysTrafficVault, cdnName string, stage string, wait time.Duration, now time.Time, user string) (error, int) {
+	cdnKeys := keys[cdnName]
+	rollover := cdnKeys.Rollover
+	if !rollover.InProgress() {
+		return errors.New("cdn '" + cdnName + "' has no DNSSEC algorithm rollover in progress"), http.StatusNotFound
+	}
+	if next := tc.NextDNSSECRolloverStage(rollover.Stage); stage != next {
+		return errors.New("the DNSSEC algorithm rollover of cdn '" + cdnName + "' is in stage '" + rollover.Stage + "', it can only be advanced to '" + next + "'"), http.StatusBadRequest
+	}
+	if now.Before(rollover.NextStageAllowed) {
+		return errors.New("the DNSSEC algorithm rollover of cdn '" + cdnName + "' can't be advanced to '" + stage + "' until " + rollover.NextStageAllowed.Format(time.RFC3339) + ", when the records of stage '" + rollover.Stage + "' have expired from caches"), http.StatusConflict
+	}
+
+	switch stage {
+	case tc.DNSSECRolloverStageDSUpdate:
+		// the old DS record may be cached for its TTL after it's replaced
+		dsWait := time.Duration(rollover.DSTTLSeconds) * time.Second
+		if wait > dsWait {
+			dsWait = wait
+		}
+		rollover.NextStageAllowed = now.Add(dsWait)
+	case tc.DNSSECRolloverStageRetire:
+		// the old DNSKEY records are removed first, and the old signatures only once they have expired from caches (RFC 6781 section 4.1.4)
+		for name, keySet := range keys {
+			keySet.ZSK = unpublishDNSSECRolloverKeys(keySet.ZSK)
+			keySet.KSK = unpublishDNSSECRolloverKeys(keySet.KSK)
+			keys[name] = keySet
+		}
+		cdnKeys = keys[cdnName]
+		rollover.NextStageAllowed = now.Add(wait)
+	case tc.DNSSECRolloverStageRetireSignatures:
+		for name, keySet := range keys {
+			keySet.ZSK = retireDNSSECRolloverKeys(keySet.ZSK, now)
+			keySet.KSK = retireDNSSECRolloverKeys(keySet.KSK, now)
+			keys[name] = keySet
+		}
+		cdnKeys = keys[cdnName]
+		rollover.NextStageAllowed = now.Add(wait)
+	case tc.DNSSECRolloverStageComplete:
+		for name, keySet := range keys {
+			keySet.ZSK = removeDNSSECAlgorithmKeys(keySet.ZSK, rollover.FromAlgorithm)
+			keySet.KSK = removeDNSSECAlgorithmKeys(keySet.KSK, rollover.FromAlgorithm)
+			keys[name] = keySet
+		}
+		cdnKeys = keys[cdnName]
+		rollover.NextStageAllowed = now
+	}
+	rollover.Stage = stage
+	rollover.StageStarted = now
+	rollover.LastUpdatedBy = user
+	cdnKeys.Rollover = rollover
+	keys[cdnName] = cdnKeys
+	return nil, http.StatusOK
+}
+
+// unpublishDNSSECRolloverKeys unpublishes the retiring keys, so their DNSKEY records are removed while they still sign zones.
+func unpublishDNSSECRolloverKeys(keys []tc.DNSSECKeyV11) []tc.DNSSECKeyV11 {
+	for i, key := range keys {
+		if key.Status == DNSSECKeyStatusRetiring {
+			keys[i].Status = DNSSECKeyStatusUnpublished
+		}
+	}
+	return keys
+}
+
+// retireDNSSECRolloverKeys expires the unpublished keys, so they no longer sign zones.
+// They keep their status, so their DNSKEY records aren't served again while they may be cached.
+func retireDNSSECRolloverKeys(keys []tc.DNSSECKeyV11, now time.Time) []tc.DNSSECKeyV11 {
+	for i, key := range keys {
+		if key.Status == DNSSECKeyStatusUnpublished {
+			keys[i].ExpirationDateUnix = now.Unix()
+		}
+	}
+	return keys
+}
+
+// removeDNSSECAlgorithmKeys returns the keys which aren't of the given algorithm.
+func removeDNSSECAlgorithmKeys(keys []tc.DNSSECKeyV11, algorithm uint8) []tc.DNSSECKeyV11 {
+	kept := []tc.DNSSECKeyV11{}
+	for _, key := range keys {
+		if keyAlgorithm, err := deliveryservice.GetDNSSECKeyAlgorithm(key); err == nil && keyAlgorithm == algorithm {
+			continue
+		}
+		kept = append(kept, key)
+	}
+	return kept
+}
+
+// cancelDNSSECRollover cancels the CDN's DNSSEC algorithm rollover, removing the keys of the new algorithm and restoring the retiring keys.
+// This is only possible in the 'publish' stage, before the DS record of the new algorithm is in the parent zone.
+// Returns a user error and HTTP status.
+func cancelDNSSECRollover(keys tc.DNSSECKeysTrafficVault, cdnName string) (error, int) {
+	rollover := keys[cdnName].Rollover
+	if !rollover.InProgress() {
+		return errors.New("cdn '" + cdnName + "' has no DNSSEC algorithm rollover in progress"), http.StatusNotFound
+	}
+	if rollover.Stage != tc.DNSSECRolloverStagePublish {
+		return errors.New("the DNSSEC algorithm rollover of cdn '" + cdnName + "' is in stage '" + rollover.Stage + "', it can only be cancelled in stage '" + tc.DNSSECRolloverStagePublish + "'"), http.StatusConflict
+	}
+
+	restore := func(keys []tc.DNSSECKeyV11) []tc.DNSSECKeyV11 {
+		keys = removeDNSSECAlgorithmKeys(keys, rollover.ToAlgorithm)
+		for i, key := range keys {
+			if key.Status == DNSSECKeyStatusRetiring {
+				keys[i].Status = tc.DNSSECKeyStatusNew
+			}
+		}
+		return keys
+	}
+	for name, keySet := range keys {
+		// Delivery Services created during the rollover only have keys of the new algorithm; removing them makes the next refresh regenerate them with the old algorithm
+		if name != cdnName && deliveryservice.GetDNSSECKeysAlgorithm(keySet.KSK, 0) == rollover.ToAlgorithm && !hasDNSSECKeyStatus(keySet.KSK, DNSSECKeyStatusRetiring) {
+			delete(keys, name)
+			continue
+		}
+		keySet.ZSK = restore(keySet.ZSK)
+		keySet.KSK = restore(keySet.KSK)
+		keys[name] = keySet
+	}
+	cdnKeys := keys[cdnName]
+	cdnKeys.Rollover = nil
+	keys[cdnName] = cdnKeys
+	return nil, http.StatusOK
+}
+
+func hasDNSSECKeyStatus(keys []tc.DNSSECKeyV11, status string) bool {
+	for _, key := range keys {
+		if key.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// getDNSSECRolloverDSRecordText returns the text of the DS record of the CDN's new KSK, to be published in its parent zone.
+func getDNSSECRolloverDSRecordText(cdnKeys tc.DNSSECKeySetV11) (string, error) {
+	for _, ksk := range cdnKeys.KSK {
+		if ksk.Status != tc.DNSSECKeyStatusNew || ksk.DSRecord == nil {
+			continue
+		}
+		return deliveryservice.MakeDSRecordText(ksk, time.Duration(cdnKeys.Rollover.DSTTLSeconds)*time.Second)
+	}
+	return "", errors.New("no KSK with status '" + tc.DNSSECKeyStatusNew + "' and a DS record")
+}
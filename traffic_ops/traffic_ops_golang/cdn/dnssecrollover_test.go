@@ -0,0 +1,235 @@
+package cdn
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+
+	"github.com/miekg/dns"
+)
+
+func getTestDNSSECRolloverKeys(t *testing.T, algorithm uint8, now time.Time) tc.DNSSECKeysTrafficVault {
+	keys := tc.DNSSECKeysTrafficVault{}
+	for name, domain := range map[string]string{"cdn1": "cdn1.example.test.", "ds1": "ds1.cdn1.example.test."} {
+		tld := name == "cdn1"
+		zsk, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECZSKType, domain, time.Minute, now, now.Add(24*time.Hour), tc.DNSSECKeyStatusNew, now, tld, algorithm)
+		if err != nil {
+			t.Fatalf("generating ZSK: %v", err)
+		}
+		ksk, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECKSKType, domain, time.Minute, now, now.Add(24*time.Hour), tc.DNSSECKeyStatusNew, now, tld, algorithm)
+		if err != nil {
+			t.Fatalf("generating KSK: %v", err)
+		}
+		keys[name] = tc.DNSSECKeySetV11{ZSK: []tc.DNSSECKeyV11{zsk}, KSK: []tc.DNSSECKeyV11{ksk}}
+	}
+	return keys
+}
+
+func countDNSSECAlgorithmKeys(t *testing.T, keys []tc.DNSSECKeyV11, algorithm uint8, status string) int {
+	count := 0
+	for _, key := range keys {
+		keyAlgorithm, err := deliveryservice.GetDNSSECKeyAlgorithm(key)
+		if err != nil {
+			t.Fatalf("getting key algorithm: %v", err)
+		}
+		if keyAlgorithm == algorithm && key.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+func TestDNSSECRollover(t *testing.T) {
+	now := time.Now()
+	wait := 2 * time.Minute
+	from := uint8(tc.DNSSECAlgorithmECDSAP256SHA256)
+	to := uint8(tc.DNSSECAlgorithmECDSAP384SHA384)
+	keys := getTestDNSSECRolloverKeys(t, from, now)
+
+	if userErr, sysErr, _ := startDNSSECRollover(keys, "cdn1", from, 3600, wait, now, "admin"); userErr == nil || sysErr != nil {
+		t.Errorf("expected starting a rollover to the current algorithm to yield a user error, actual user error %v system error %v", userErr, sysErr)
+	}
+	if userErr, sysErr, _ := startDNSSECRollover(keys, "cdn1", to, 3600, wait, now, "admin"); userErr != nil || sysErr != nil {
+		t.Fatalf("starting rollover: unexpected user error %v system error %v", userErr, sysErr)
+	}
+
+	rollover := keys["cdn1"].Rollover
+	if rollover == nil || rollover.Stage != tc.DNSSECRolloverStagePublish || rollover.FromAlgorithm != from || rollover.ToAlgorithm != to || !rollover.NextStageAllowed.Equal(now.Add(wait)) {
+		t.Fatalf("expected a '%s' rollover from %d to %d allowed to advance at %v, actual %+v", tc.DNSSECRolloverStagePublish, from, to, now.Add(wait), rollover)
+	}
+	for name, keySet := range keys {
+		for keyType, keyList := range map[string][]tc.DNSSECKeyV11{tc.DNSSECKSKType: keySet.KSK, tc.DNSSECZSKType: keySet.ZSK} {
+			if count := countDNSSECAlgorithmKeys(t, keyList, to, tc.DNSSECKeyStatusNew); count != 1 {
+				t.Errorf("%s %s: expected 1 new key of algorithm %d, actual %d", name, keyType, to, count)
+			}
+			if count := countDNSSECAlgorithmKeys(t, keyList, from, DNSSECKeyStatusRetiring); count != 1 {
+				t.Errorf("%s %s: expected 1 retiring key of algorithm %d, actual %d", name, keyType, from, count)
+			}
+		}
+		if algorithm := deliveryservice.GetDNSSECKeysAlgorithm(keySet.KSK, 0); algorithm != to {
+			t.Errorf("%s: expected the algorithm of new keys to be %d, actual %d", name, to, algorithm)
+		}
+	}
+	if keys["cdn1"].KSK[0].DSRecord == nil {
+		t.Error("expected the new CDN KSK to have a DS record")
+	}
+	if keys["ds1"].KSK[0].DSRecord != nil {
+		t.Error("expected the new Delivery Service KSK to not have a DS record")
+	}
+	if retiring := keys["cdn1"].ZSK[1]; retiring.ExpirationDateUnix < now.Add(DNSSECRolloverRetiringKeyMinLifetime).Unix() {
+		t.Errorf("expected retiring keys to be valid until at least %v, actual %v", now.Add(DNSSECRolloverRetiringKeyMinLifetime), time.Unix(retiring.ExpirationDateUnix, 0))
+	}
+
+	if userErr, sysErr, code := startDNSSECRollover(keys, "cdn1", tc.DNSSECAlgorithmRSASHA256, 3600, wait, now, "admin"); userErr == nil || sysErr != nil || code != http.StatusConflict {
+		t.Errorf("expected starting a second rollover to yield a conflict, actual user error %v system error %v code %d", userErr, sysErr, code)
+	}
+
+	if userErr, code := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageDSUpdate, wait, now.Add(time.Minute), "admin"); userErr == nil || code != http.StatusConflict {
+		t.Errorf("expected advancing the rollover early to yield a conflict, actual error %v code %d", userErr, code)
+	}
+	if userErr, code := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageRetire, wait, now.Add(wait), "admin"); userErr == nil || code != http.StatusBadRequest {
+		t.Errorf("expected skipping a rollover stage to yield a bad request, actual error %v code %d", userErr, code)
+	}
+
+	now = now.Add(wait)
+	if userErr, _ := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageDSUpdate, wait, now, "admin"); userErr != nil {
+		t.Fatalf("advancing rollover to '%s': unexpected error %v", tc.DNSSECRolloverStageDSUpdate, userErr)
+	}
+	if next := keys["cdn1"].Rollover.NextStageAllowed; !next.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected the '%s' stage to wait for the DS TTL until %v, actual %v", tc.DNSSECRolloverStageDSUpdate, now.Add(time.Hour), next)
+	}
+	if userErr, code := cancelDNSSECRollover(keys, "cdn1"); userErr == nil || code != http.StatusConflict {
+		t.Errorf("expected cancelling the rollover after '%s' to yield a conflict, actual error %v code %d", tc.DNSSECRolloverStagePublish, userErr, code)
+	}
+
+	now = now.Add(time.Hour)
+	if userErr, _ := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageRetire, wait, now, "admin"); userErr != nil {
+		t.Fatalf("advancing rollover to '%s': unexpected error %v", tc.DNSSECRolloverStageRetire, userErr)
+	}
+	for name, keySet := range keys {
+		for keyType, keyList := range map[string][]tc.DNSSECKeyV11{tc.DNSSECKSKType: keySet.KSK, tc.DNSSECZSKType: keySet.ZSK} {
+			if count := countDNSSECAlgorithmKeys(t, keyList, from, DNSSECKeyStatusUnpublished); count != 1 {
+				t.Errorf("%s %s: expected 1 unpublished key of algorithm %d, actual %d", name, keyType, from, count)
+			}
+			// the old algorithm's keys still sign until its DNSKEY records have expired from caches
+			for _, key := range keyList {
+				if key.Status == DNSSECKeyStatusUnpublished && key.ExpirationDateUnix <= now.Unix() {
+					t.Errorf("%s %s: expected the unpublished key to not be expired, actual expiration %v", name, keyType, time.Unix(key.ExpirationDateUnix, 0))
+				}
+			}
+		}
+	}
+	if userErr, code := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageComplete, wait, now.Add(wait), "admin"); userErr == nil || code != http.StatusBadRequest {
+		t.Errorf("expected completing the rollover before '%s' to yield a bad request, actual error %v code %d", tc.DNSSECRolloverStageRetireSignatures, userErr, code)
+	}
+	if userErr, code := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageRetireSignatures, wait, now.Add(time.Minute), "admin"); userErr == nil || code != http.StatusConflict {
+		t.Errorf("expected removing the old signatures before the old DNSKEY records expire to yield a conflict, actual error %v code %d", userErr, code)
+	}
+
+	now = now.Add(wait)
+	if userErr, _ := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageRetireSignatures, wait, now, "admin"); userErr != nil {
+		t.Fatalf("advancing rollover to '%s': unexpected error %v", tc.DNSSECRolloverStageRetireSignatures, userErr)
+	}
+	for name, keySet := range keys {
+		for keyType, keyList := range map[string][]tc.DNSSECKeyV11{tc.DNSSECKSKType: keySet.KSK, tc.DNSSECZSKType: keySet.ZSK} {
+			for _, key := range keyList {
+				if key.Status == DNSSECKeyStatusUnpublished && key.ExpirationDateUnix != now.Unix() {
+					t.Errorf("%s %s: expected the unpublished key to expire at %v, actual %v", name, keyType, now, time.Unix(key.ExpirationDateUnix, 0))
+				}
+			}
+			if count := countDNSSECAlgorithmKeys(t, keyList, from, DNSSECKeyStatusUnpublished); count != 1 {
+				t.Errorf("%s %s: expected the expired key of algorithm %d to stay unpublished, actual %d unpublished keys", name, keyType, from, count)
+			}
+		}
+	}
+
+	now = now.Add(wait)
+	if userErr, _ := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStageComplete, wait, now, "admin"); userErr != nil {
+		t.Fatalf("advancing rollover to '%s': unexpected error %v", tc.DNSSECRolloverStageComplete, userErr)
+	}
+	for name, keySet := range keys {
+		if len(keySet.KSK) != 1 || len(keySet.ZSK) != 1 {
+			t.Errorf("%s: expected only the new algorithm's keys, actual %d KSKs and %d ZSKs", name, len(keySet.KSK), len(keySet.ZSK))
+		}
+	}
+	if keys["cdn1"].Rollover.InProgress() {
+		t.Error("expected a completed rollover to not be in progress")
+	}
+	if userErr, code := advanceDNSSECRollover(keys, "cdn1", tc.DNSSECRolloverStagePublish, wait, now, "admin"); userErr == nil || code != http.StatusNotFound {
+		t.Errorf("expected advancing a completed rollover to yield not found, actual error %v code %d", userErr, code)
+	}
+}
+
+func TestCancelDNSSECRollover(t *testing.T) {
+	now := time.Now()
+	from := uint8(tc.DNSSECAlgorithmECDSAP256SHA256)
+	to := uint8(tc.DNSSECAlgorithmECDSAP384SHA384)
+	keys := getTestDNSSECRolloverKeys(t, from, now)
+	if userErr, sysErr, _ := startDNSSECRollover(keys, "cdn1", to, 3600, time.Minute, now, "admin"); userErr != nil || sysErr != nil {
+		t.Fatalf("starting rollover: unexpected user error %v system error %v", userErr, sysErr)
+	}
+
+	// a Delivery Service created during the rollover
+	dsKeys, err := deliveryservice.CreateDNSSECKeys([]string{"http://ds2.cdn1.example.test"}, keys["cdn1"], time.Hour, time.Hour, time.Minute, false)
+	if err != nil {
+		t.Fatalf("creating Delivery Service keys: %v", err)
+	}
+	keys["ds2"] = dsKeys
+
+	if userErr, _ := cancelDNSSECRollover(keys, "cdn1"); userErr != nil {
+		t.Fatalf("cancelling rollover: unexpected error %v", userErr)
+	}
+	if keys["cdn1"].Rollover != nil {
+		t.Errorf("expected a cancelled rollover to be removed, actual %+v", keys["cdn1"].Rollover)
+	}
+	if _, ok := keys["ds2"]; ok {
+		t.Error("expected the keys of a Delivery Service created during the rollover to be removed")
+	}
+	for _, name := range []string{"cdn1", "ds1"} {
+		keySet := keys[name]
+		if len(keySet.KSK) != 1 || len(keySet.ZSK) != 1 {
+			t.Fatalf("%s: expected only the old algorithm's keys, actual %d KSKs and %d ZSKs", name, len(keySet.KSK), len(keySet.ZSK))
+		}
+		if countDNSSECAlgorithmKeys(t, keySet.KSK, from, tc.DNSSECKeyStatusNew) != 1 || countDNSSECAlgorithmKeys(t, keySet.ZSK, from, tc.DNSSECKeyStatusNew) != 1 {
+			t.Errorf("%s: expected the retiring keys to be restored to '%s'", name, tc.DNSSECKeyStatusNew)
+		}
+	}
+}
+
+func TestDNSSECAlgorithmValidation(t *testing.T) {
+	for _, algorithm := range []uint8{tc.DNSSECAlgorithmRSASHA256, tc.DNSSECAlgorithmECDSAP384SHA384, tc.DNSSECAlgorithmED25519} {
+		if err := (tc.CDNDNSSECRolloverReq{Algorithm: &algorithm}).Validate(nil); err != nil {
+			t.Errorf("expected rolling over to algorithm %d to be valid, actual error %v", algorithm, err)
+		}
+	}
+	unknown := uint8(dns.ED448)
+	if err := (tc.CDNDNSSECRolloverReq{Algorithm: &unknown}).Validate(nil); err == nil {
+		t.Error("expected rolling over to algorithm 16 (ED448) to be invalid")
+	}
+	if err := (tc.CDNDNSSECGenerateReq{Algorithm: &unknown}).Validate(nil); err == nil || !strings.Contains(err.Error(), "algorithm") {
+		t.Errorf("expected generating algorithm 16 (ED448) keys to be invalid, actual error %v", err)
+	}
+}
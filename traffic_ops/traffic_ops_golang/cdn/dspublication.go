@@ -0,0 +1,343 @@
+package cdn
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+
+	"github.com/miekg/dns"
+)
+
+// DSPublicationRetirementHold is how long the expiration of a CDN's KSK is
+// extended by, when the parent zone serves its DS record but not the DS
+// record of its replacement, so it keeps signing until the parent is updated.
+const DSPublicationRetirementHold = 7 * 24 * time.Hour
+
+// dsPublicationNotificationPrefix starts the CDN notifications raised when a
+// parent zone is stale, so they can be removed when it's updated.
+const dsPublicationNotificationPrefix = "DNSSEC DS publication: "
+
+// errDSRecordsUnchecked is wrapped by the errors of syncDSRecords when the
+// parent zone's name servers couldn't be checked, so no DS record is known to
+// be served.
+var errDSRecordsUnchecked = errors.New("checking parent DS records")
+
+// DSPublicationWebhookRequest is the body POSTed to a DS publication webhook.
+type DSPublicationWebhookRequest struct {
+	CDN  string `json:"cdn"`
+	Name string `json:"name"`
+	// DSRecords are the DS records the parent zone should serve for Name,
+	// replacing any others, in the RFC 1035 zone file format.
+	DSRecords []string `json:"dsRecords"`
+}
+
+// getDesiredDSRecords returns the DS records the parent zone should serve for the CDN's KSKs at the given time. Those are the DS records of every unexpired KSK,
+// so a new KSK's DS record is published while its predecessor still signs. During a DNSSEC algorithm rollover, only the old algorithm's DS records are wanted
+// before the 'ds-update' stage, and only the new algorithm's after.
+func getDesiredDSRecords(cdnKeys tc.DNSSECKeySetV11, ttl time.Duration, now time.Time) ([]*dns.DS, error) {
+	records := []*dns.DS{}
+	for _, ksk := range cdnKeys.KSK {
+		if ksk.DSRecord == nil || ksk.ExpirationDateUnix <= now.Unix() {
+			continue
+		}
+		if rollover := cdnKeys.Rollover; rollover.InProgress() {
+			algorithm, err := deliveryservice.GetDNSSECKeyAlgorithm(ksk)
+			if err != nil {
+				return nil, errors.New("getting KSK algorithm: " + err.Error())
+			}
+			if rollover.Stage == tc.DNSSECRolloverStagePublish && algorithm != rollover.FromAlgorithm {
+				continue
+			} else if rollover.Stage != tc.DNSSECRolloverStagePublish && algorithm != rollover.ToAlgorithm {
+				continue
+			}
+		}
+		ds, err := deliveryservice.MakeDSRecord(ksk, ttl)
+		if err != nil {
+			return nil, errors.New("making DS record: " + err.Error())
+		}
+		records = append(records, ds)
+	}
+	return records, nil
+}
+
+// dsRecordKey returns a key identifying the DS record, ignoring its TTL.
+func dsRecordKey(ds *dns.DS) string {
+	return fmt.Sprintf("%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType, strings.ToUpper(ds.Digest))
+}
+
+// getMissingDSRecords returns the desired DS records which aren't served.
+func getMissingDSRecords(served []*dns.DS, desired []*dns.DS) []*dns.DS {
+	servedKeys := map[string]struct{}{}
+	for _, ds := range served {
+		servedKeys[dsRecordKey(ds)] = struct{}{}
+	}
+	missing := []*dns.DS{}
+	for _, ds := range desired {
+		if _, ok := servedKeys[dsRecordKey(ds)]; !ok {
+			missing = append(missing, ds)
+		}
+	}
+	return missing
+}
+
+// withDNSPort returns the name server host with the DNS port, if it has no port.
+func withDNSPort(nameserver string) string {
+	if _, _, err := net.SplitHostPort(nameserver); err == nil {
+		return nameserver
+	}
+	return net.JoinHostPort(nameserver, "53")
+}
+
+// queryDSRecords returns the DS records of name served by the name server.
+func queryDSRecords(nameserver string, name string, timeout time.Duration) ([]*dns.DS, error) {
+	msg := &dns.Msg{}
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeDS)
+	msg.RecursionDesired = false
+	client := dns.Client{Timeout: timeout}
+	resp, _, err := client.Exchange(msg, withDNSPort(nameserver))
+	if err != nil {
+		return nil, errors.New("querying '" + nameserver + "': " + err.Error())
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return nil, errors.New("querying '" + nameserver + "': " + dns.RcodeToString[resp.Rcode])
+	}
+	records := []*dns.DS{}
+	for _, rr := range resp.Answer {
+		if ds, ok := rr.(*dns.DS); ok {
+			records = append(records, ds)
+		}
+	}
+	return records, nil
+}
+
+// getUnservedDSRecords queries each of the publication's name servers, and returns the desired DS records which any of them doesn't serve.
+func getUnservedDSRecords(pub config.ConfigDSPublication, name string, desired []*dns.DS) ([]*dns.DS, error) {
+	timeout := time.Duration(pub.TimeoutSec) * time.Second
+	unserved := map[string]*dns.DS{}
+	for _, nameserver := range pub.CheckNameservers {
+		served, err := queryDSRecords(nameserver, name, timeout)
+		if err != nil {
+			return nil, err
+		}
+		for _, ds := range getMissingDSRecords(served, desired) {
+			unserved[dsRecordKey(ds)] = ds
+		}
+	}
+	records := []*dns.DS{}
+	for _, ds := range unserved {
+		records = append(records, ds)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].KeyTag < records[j].KeyTag })
+	return records, nil
+}
+
+// publishDSRecords replaces the DS records of name in the parent zone with the given records.
+func publishDSRecords(pub config.ConfigDSPublication, name string, records []*dns.DS) error {
+	switch pub.Method {
+	case config.DSPublicationMethodRFC2136:
+		return publishDSRecordsRFC2136(pub, name, records)
+	case config.DSPublicationMethodWebhook:
+		return publishDSRecordsWebhook(pub, name, records)
+	}
+	return errors.New("unknown DS publication method '" + pub.Method + "'")
+}
+
+// publishDSRecordsRFC2136 replaces the DS records of name with an RFC 2136 dynamic update, signed with TSIG if a key is configured.
+func publishDSRecordsRFC2136(pub config.ConfigDSPublication, name string, records []*dns.DS) error {
+	msg := &dns.Msg{}
+	msg.SetUpdate(dns.Fqdn(pub.Zone))
+	msg.RemoveRRset([]dns.RR{&dns.DS{Hdr: dns.RR_Header{Name: dns.Fqdn(name), Rrtype: dns.TypeDS, Class: dns.ClassINET}}})
+	rrs := make([]dns.RR, 0, len(records))
+	for _, ds := range records {
+		rrs = append(rrs, ds)
+	}
+	msg.Insert(rrs)
+
+	client := dns.Client{Net: "tcp", Timeout: time.Duration(pub.TimeoutSec) * time.Second}
+	if pub.TSIGKeyName != "" {
+		keyName := dns.Fqdn(pub.TSIGKeyName)
+		client.TsigSecret = map[string]string{keyName: pub.TSIGSecret}
+		msg.SetTsig(keyName, dns.Fqdn(pub.TSIGAlgorithm), 300, time.Now().Unix())
+	}
+	resp, _, err := client.Exchange(msg, withDNSPort(pub.Server))
+	if err != nil {
+		return errors.New("sending update to '" + pub.Server + "': " + err.Error())
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return errors.New("update to '" + pub.Server + "' was refused: " + dns.RcodeToString[resp.Rcode])
+	}
+	return nil
+}
+
+// publishDSRecordsWebhook POSTs the DS records of name to the publication's webhook.
+func publishDSRecordsWebhook(pub config.ConfigDSPublication, name string, records []*dns.DS) error {
+	req := DSPublicationWebhookRequest{CDN: pub.CDN, Name: dns.Fqdn(name), DSRecords: []string{}}
+	for _, ds := range records {
+		req.DSRecords = append(req.DSRecords, ds.String())
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return errors.New("marshalling webhook request: " + err.Error())
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, pub.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return errors.New("creating webhook request: " + err.Error())
+	}
+	httpReq.Header.Set(rfc.ContentType, rfc.ApplicationJSON)
+	for header, val := range pub.WebhookHeaders {
+		httpReq.Header.Set(header, val)
+	}
+	client := http.Client{Timeout: time.Duration(pub.TimeoutSec) * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return errors.New("requesting webhook: " + err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("webhook returned " + resp.Status)
+	}
+	return nil
+}
+
+// syncDSRecords publishes the CDN's desired DS records in its parent zone, if any of the parent's name servers doesn't serve them.
+// Returns the desired DS records which are still not served afterward; name servers which aren't the primary may take a while to be updated.
+// If the parent's name servers can't be checked, every desired DS record is returned as unserved, with an error wrapping errDSRecordsUnchecked.
+func syncDSRecords(pub config.ConfigDSPublication, cdnDomain string, cdnKeys tc.DNSSECKeySetV11, now time.Time) ([]*dns.DS, error) {
+	desired, err := getDesiredDSRecords(cdnKeys, time.Duration(pub.DSTTLSec)*time.Second, now)
+	if err != nil {
+		return nil, errors.New("getting desired DS records: " + err.Error())
+	}
+	if len(desired) == 0 {
+		return nil, nil
+	}
+	unserved, err := getUnservedDSRecords(pub, cdnDomain, desired)
+	if err != nil {
+		return desired, fmt.Errorf("%w: %s", errDSRecordsUnchecked, err.Error())
+	}
+	if len(unserved) == 0 {
+		return nil, nil
+	}
+	log.Infof("publishing %d DS records of cdn '%s' in parent zone, %d are not served\n", len(desired), pub.CDN, len(unserved))
+	if err := publishDSRecords(pub, cdnDomain, desired); err != nil {
+		return unserved, errors.New("publishing DS records: " + err.Error())
+	}
+	if unserved, err = getUnservedDSRecords(pub, cdnDomain, desired); err != nil {
+		return desired, fmt.Errorf("%w after publishing: %s", errDSRecordsUnchecked, err.Error())
+	}
+	return unserved, nil
+}
+
+// syncDSRecordsAlerts publishes the CDN's DS records in its parent zone, returning alerts for the result.
+func syncDSRecordsAlerts(pub config.ConfigDSPublication, cdnDomain string, cdnKeys tc.DNSSECKeySetV11) tc.Alerts {
+	alerts := tc.Alerts{}
+	unserved, err := syncDSRecords(pub, cdnDomain, cdnKeys, time.Now())
+	if err != nil {
+		log.Errorln("publishing DS records of cdn '" + pub.CDN + "': " + err.Error())
+		alerts.AddNewAlert(tc.ErrorLevel, "publishing the DS records of cdn '"+pub.CDN+"' in its parent zone failed")
+	} else if len(unserved) > 0 {
+		alerts.AddNewAlert(tc.WarnLevel, "published the DS records of cdn '"+pub.CDN+"', but not all name servers of its parent zone serve them yet")
+	} else {
+		alerts.AddNewAlert(tc.InfoLevel, "the parent zone of cdn '"+pub.CDN+"' serves its DS records")
+	}
+	return alerts
+}
+
+// holdDNSSECKSKRetirement extends the expiration of the CDN's KSKs whose DS records are served by the parent zone, when the DS records of others aren't,
+// so they keep signing until the parent is updated. If unchecked, the parent zone couldn't be checked, and every unexpired KSK is held, since none of
+// their replacements is known to be served. Returns whether any KSK was changed.
+func holdDNSSECKSKRetirement(cdnKeys tc.DNSSECKeySetV11, unserved []*dns.DS, unchecked bool, now time.Time) (bool, error) {
+	if len(unserved) == 0 && !unchecked {
+		return false, nil
+	}
+	unservedKeys := map[string]struct{}{}
+	for _, ds := range unserved {
+		unservedKeys[dsRecordKey(ds)] = struct{}{}
+	}
+	hold := now.Add(DSPublicationRetirementHold).Unix()
+	changed := false
+	for i, ksk := range cdnKeys.KSK {
+		if ksk.DSRecord == nil || ksk.ExpirationDateUnix <= now.Unix() || ksk.ExpirationDateUnix >= hold {
+			continue
+		}
+		ds, err := deliveryservice.MakeDSRecord(ksk, 0)
+		if err != nil {
+			return changed, errors.New("making DS record: " + err.Error())
+		}
+		if _, ok := unservedKeys[dsRecordKey(ds)]; ok && !unchecked {
+			continue
+		}
+		log.Warnf("the parent zone doesn't serve the DS record of the replacement of the KSK with tag %d of '%s', extending its expiration to %s\n", ds.KeyTag, ksk.Name, time.Unix(hold, 0).Format(time.RFC3339))
+		cdnKeys.KSK[i].ExpirationDateUnix = hold
+		changed = true
+	}
+	return changed, nil
+}
+
+// updateDSPublicationNotification raises a CDN notification when the parent zone doesn't serve the given DS records, and removes it when it does.
+func updateDSPublicationNotification(tx *sql.Tx, cdnName string, userName string, unserved []*dns.DS, publishErr error) error {
+	if len(unserved) == 0 && publishErr == nil {
+		if _, err := tx.Exec(`DELETE FROM cdn_notification WHERE cdn = $1 AND notification LIKE $2`, cdnName, dsPublicationNotificationPrefix+"%"); err != nil {
+			return errors.New("deleting DS publication notifications: " + err.Error())
+		}
+		return nil
+	}
+
+	msg := dsPublicationNotificationPrefix + "the parent zone of cdn '" + cdnName + "' is stale"
+	if len(unserved) > 0 {
+		tags := []string{}
+		for _, ds := range unserved {
+			tags = append(tags, strconv.Itoa(int(ds.KeyTag)))
+		}
+		msg += ", it doesn't serve the DS records of the KSKs with tags " + strings.Join(tags, ", ")
+	}
+	if publishErr != nil {
+		msg += ", and publishing them failed"
+	}
+	exists := false
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM cdn_notification WHERE cdn = $1 AND notification = $2)`, cdnName, msg).Scan(&exists); err != nil {
+		return errors.New("checking for DS publication notification: " + err.Error())
+	}
+	if exists {
+		return nil
+	}
+	if _, err := tx.Exec(`DELETE FROM cdn_notification WHERE cdn = $1 AND notification LIKE $2`, cdnName, dsPublicationNotificationPrefix+"%"); err != nil {
+		return errors.New("deleting old DS publication notifications: " + err.Error())
+	}
+	if _, err := tx.Exec(`INSERT INTO cdn_notification (cdn, "user", notification) VALUES ($1, $2, $3)`, cdnName, userName, msg); err != nil {
+		return errors.New("inserting DS publication notification: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,308 @@
+package cdn
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+
+	"github.com/miekg/dns"
+)
+
+const testTSIGSecret = "c2VjcmV0LWtleS1mb3ItdGVzdHM="
+
+// testParentZone is a parent zone name server, which serves DS records and accepts TSIG-signed dynamic updates of them.
+type testParentZone struct {
+	m       sync.Mutex
+	records []*dns.DS
+	updates int
+}
+
+func (p *testParentZone) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+	resp := &dns.Msg{}
+	resp.SetReply(req)
+	p.m.Lock()
+	defer p.m.Unlock()
+	if req.Opcode == dns.OpcodeUpdate {
+		if req.IsTsig() == nil || w.TsigStatus() != nil {
+			resp.Rcode = dns.RcodeNotAuth
+		} else {
+			p.records = []*dns.DS{}
+			for _, rr := range req.Ns {
+				if ds, ok := rr.(*dns.DS); ok && ds.Hdr.Class == dns.ClassINET {
+					p.records = append(p.records, ds)
+				}
+			}
+			p.updates++
+		}
+		resp.SetTsig(req.IsTsig().Hdr.Name, req.IsTsig().Algorithm, 300, time.Now().Unix())
+	} else {
+		for _, ds := range p.records {
+			resp.Answer = append(resp.Answer, ds)
+		}
+	}
+	w.WriteMsg(resp)
+}
+
+// startTestParentZone starts the parent zone name server, returning its UDP and TCP addresses.
+func startTestParentZone(t *testing.T, parent *testParentZone) (string, string) {
+	tsig := map[string]string{"to-key.": testTSIGSecret}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening on UDP: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening on TCP: %v", err)
+	}
+	acceptAll := func(dns.Header) dns.MsgAcceptAction { return dns.MsgAccept }
+	udpServer := &dns.Server{PacketConn: conn, Handler: parent, TsigSecret: tsig, MsgAcceptFunc: acceptAll}
+	tcpServer := &dns.Server{Listener: listener, Handler: parent, TsigSecret: tsig, MsgAcceptFunc: acceptAll}
+	go udpServer.ActivateAndServe()
+	go tcpServer.ActivateAndServe()
+	t.Cleanup(func() {
+		udpServer.Shutdown()
+		tcpServer.Shutdown()
+	})
+	return conn.LocalAddr().String(), listener.Addr().String()
+}
+
+func getTestDSPublicationKeys(t *testing.T, now time.Time) tc.DNSSECKeySetV11 {
+	keys := tc.DNSSECKeySetV11{}
+	for _, effective := range []time.Time{now.Add(-48 * time.Hour), now} {
+		ksk, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECKSKType, "cdn1.example.test.", time.Minute, effective, effective.Add(24*time.Hour), tc.DNSSECKeyStatusNew, effective, true, tc.DNSSECAlgorithmECDSAP256SHA256)
+		if err != nil {
+			t.Fatalf("generating KSK: %v", err)
+		}
+		keys.KSK = append(keys.KSK, ksk)
+	}
+	return keys
+}
+
+func TestGetDesiredDSRecords(t *testing.T) {
+	now := time.Now()
+	keys := getTestDSPublicationKeys(t, now)
+	desired, err := getDesiredDSRecords(keys, time.Hour, now)
+	if err != nil {
+		t.Fatalf("getting desired DS records: %v", err)
+	}
+	if len(desired) != 1 {
+		t.Fatalf("expected the DS record of only the unexpired KSK, actual %d records", len(desired))
+	}
+	if desired[0].Hdr.Ttl != 3600 {
+		t.Errorf("expected the DS record TTL to be 3600, actual %d", desired[0].Hdr.Ttl)
+	}
+
+	// a KSK generated by a rollover, which isn't wanted in the parent zone until 'ds-update'
+	zsk, err := deliveryservice.GetDNSSECKeysV11(tc.DNSSECZSKType, "cdn1.example.test.", time.Minute, now, now.Add(24*time.Hour), tc.DNSSECKeyStatusNew, now, true, tc.DNSSECAlgorithmECDSAP256SHA256)
+	if err != nil {
+		t.Fatalf("generating ZSK: %v", err)
+	}
+	keys.ZSK = []tc.DNSSECKeyV11{zsk}
+	keys.KSK = keys.KSK[1:]
+	rolloverKeys := tc.DNSSECKeysTrafficVault{"cdn1": keys}
+	if userErr, sysErr, _ := startDNSSECRollover(rolloverKeys, "cdn1", tc.DNSSECAlgorithmECDSAP384SHA384, 3600, time.Minute, now, "admin"); userErr != nil || sysErr != nil {
+		t.Fatalf("starting rollover: unexpected user error %v system error %v", userErr, sysErr)
+	}
+	for stage, expected := range map[string]uint8{tc.DNSSECRolloverStagePublish: tc.DNSSECAlgorithmECDSAP256SHA256, tc.DNSSECRolloverStageDSUpdate: tc.DNSSECAlgorithmECDSAP384SHA384} {
+		rolloverKeys["cdn1"].Rollover.Stage = stage
+		desired, err := getDesiredDSRecords(rolloverKeys["cdn1"], time.Hour, now)
+		if err != nil {
+			t.Fatalf("getting desired DS records: %v", err)
+		}
+		if len(desired) != 1 || desired[0].Algorithm != expected {
+			t.Errorf("rollover stage '%s': expected the DS record of only the algorithm %d KSK, actual %v", stage, expected, desired)
+		}
+	}
+}
+
+func TestSyncDSRecordsRFC2136(t *testing.T) {
+	now := time.Now()
+	keys := getTestDSPublicationKeys(t, now)
+	parent := &testParentZone{}
+	udpAddr, tcpAddr := startTestParentZone(t, parent)
+	pub := config.ConfigDSPublication{
+		CDN:              "cdn1",
+		Method:           config.DSPublicationMethodRFC2136,
+		Zone:             "example.test",
+		Server:           tcpAddr,
+		TSIGKeyName:      "to-key",
+		TSIGAlgorithm:    dns.HmacSHA256,
+		TSIGSecret:       testTSIGSecret,
+		CheckNameservers: []string{udpAddr},
+		DSTTLSec:         3600,
+		TimeoutSec:       5,
+	}
+
+	unserved, err := syncDSRecords(pub, "cdn1.example.test", keys, now)
+	if err != nil {
+		t.Fatalf("syncing DS records: %v", err)
+	}
+	if len(unserved) != 0 {
+		t.Errorf("expected the parent zone to serve every DS record after publishing, actual %d unserved", len(unserved))
+	}
+	if parent.updates != 1 || len(parent.records) != 1 {
+		t.Fatalf("expected 1 update publishing 1 DS record, actual %d updates publishing %d records", parent.updates, len(parent.records))
+	}
+
+	if _, err := syncDSRecords(pub, "cdn1.example.test", keys, now); err != nil {
+		t.Fatalf("syncing DS records: %v", err)
+	}
+	if parent.updates != 1 {
+		t.Errorf("expected no update when the parent zone serves the DS records, actual %d updates", parent.updates)
+	}
+
+	pub.TSIGSecret = "d3Jvbmcta2V5"
+	keys.KSK[0].ExpirationDateUnix = now.Add(time.Hour).Unix()
+	if unserved, err := syncDSRecords(pub, "cdn1.example.test", keys, now); err == nil || len(unserved) != 1 {
+		t.Errorf("expected an update with the wrong TSIG key to fail with 1 unserved DS record, actual error %v and %d unserved", err, len(unserved))
+	}
+}
+
+func TestSyncDSRecordsWebhook(t *testing.T) {
+	now := time.Now()
+	keys := getTestDSPublicationKeys(t, now)
+	parent := &testParentZone{}
+	udpAddr, _ := startTestParentZone(t, parent)
+
+	requests := []DSPublicationWebhookRequest{}
+	registrar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer registrar-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		req := DSPublicationWebhookRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer registrar.Close()
+
+	pub := config.ConfigDSPublication{
+		CDN:              "cdn1",
+		Method:           config.DSPublicationMethodWebhook,
+		WebhookURL:       registrar.URL,
+		WebhookHeaders:   map[string]string{"Authorization": "Bearer registrar-token"},
+		CheckNameservers: []string{udpAddr},
+		DSTTLSec:         3600,
+		TimeoutSec:       5,
+	}
+	unserved, err := syncDSRecords(pub, "cdn1.example.test", keys, now)
+	if err != nil {
+		t.Fatalf("syncing DS records: %v", err)
+	}
+	// the registrar doesn't update the parent zone immediately
+	if len(unserved) != 1 {
+		t.Errorf("expected 1 unserved DS record, actual %d", len(unserved))
+	}
+	if len(requests) != 1 || requests[0].CDN != "cdn1" || requests[0].Name != "cdn1.example.test." || len(requests[0].DSRecords) != 1 {
+		t.Fatalf("expected 1 webhook request with 1 DS record of cdn1.example.test., actual %+v", requests)
+	}
+	if _, err := dns.NewRR(requests[0].DSRecords[0]); err != nil {
+		t.Errorf("expected the webhook DS record to be in the zone file format, actual '%s': %v", requests[0].DSRecords[0], err)
+	}
+
+	pub.WebhookHeaders = nil
+	if _, err := syncDSRecords(pub, "cdn1.example.test", keys, now); err == nil {
+		t.Error("expected an unauthorized webhook request to fail")
+	}
+}
+
+func TestHoldDNSSECKSKRetirement(t *testing.T) {
+	now := time.Now()
+	keys := getTestDSPublicationKeys(t, now)
+	oldKSK := keys.KSK[0]
+	oldKSK.EffectiveDateUnix = now.Add(-time.Hour).Unix()
+	oldKSK.ExpirationDateUnix = now.Add(time.Hour).Unix()
+	keys.KSK = []tc.DNSSECKeyV11{oldKSK, keys.KSK[1]}
+	newDS, err := deliveryservice.MakeDSRecord(keys.KSK[1], time.Hour)
+	if err != nil {
+		t.Fatalf("making DS record: %v", err)
+	}
+
+	if held, err := holdDNSSECKSKRetirement(keys, nil, false, now); err != nil || held {
+		t.Errorf("expected no hold when every DS record is served, actual hold %t error %v", held, err)
+	}
+	held, err := holdDNSSECKSKRetirement(keys, []*dns.DS{newDS}, false, now)
+	if err != nil || !held {
+		t.Fatalf("expected a hold when the new DS record isn't served, actual hold %t error %v", held, err)
+	}
+	if expected := now.Add(DSPublicationRetirementHold).Unix(); keys.KSK[0].ExpirationDateUnix != expected {
+		t.Errorf("expected the old KSK expiration to be extended to %d, actual %d", expected, keys.KSK[0].ExpirationDateUnix)
+	}
+	if keys.KSK[1].ExpirationDateUnix != now.Add(24*time.Hour).Unix() {
+		t.Errorf("expected the new KSK expiration to be unchanged, actual %d", keys.KSK[1].ExpirationDateUnix)
+	}
+}
+
+func TestSyncDSRecordsCheckFailure(t *testing.T) {
+	now := time.Now()
+	keys := getTestDSPublicationKeys(t, now)
+	oldKSK := keys.KSK[0]
+	oldKSK.EffectiveDateUnix = now.Add(-time.Hour).Unix()
+	oldKSK.ExpirationDateUnix = now.Add(time.Hour).Unix()
+	keys.KSK = []tc.DNSSECKeyV11{oldKSK, keys.KSK[1]}
+
+	// a name server which can't be reached
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening on UDP: %v", err)
+	}
+	unreachable := conn.LocalAddr().String()
+	conn.Close()
+
+	pub := config.ConfigDSPublication{
+		CDN:              "cdn1",
+		Method:           config.DSPublicationMethodRFC2136,
+		Zone:             "example.test",
+		Server:           unreachable,
+		CheckNameservers: []string{unreachable},
+		DSTTLSec:         3600,
+		TimeoutSec:       1,
+	}
+	unserved, err := syncDSRecords(pub, "cdn1.example.test", keys, now)
+	if !errors.Is(err, errDSRecordsUnchecked) {
+		t.Fatalf("expected checking an unreachable name server to fail, actual error %v", err)
+	}
+	// without proof the parent serves them, every desired DS record is unserved
+	if len(unserved) != 2 {
+		t.Fatalf("expected 2 unserved DS records when the check fails, actual %d", len(unserved))
+	}
+	held, err := holdDNSSECKSKRetirement(keys, unserved, true, now)
+	if err != nil || !held {
+		t.Fatalf("expected a hold when the parent zone can't be checked, actual hold %t error %v", held, err)
+	}
+	if expected := now.Add(DSPublicationRetirementHold).Unix(); keys.KSK[0].ExpirationDateUnix != expected {
+		t.Errorf("expected the old KSK expiration to be extended to %d, actual %d", expected, keys.KSK[0].ExpirationDateUnix)
+	}
+}
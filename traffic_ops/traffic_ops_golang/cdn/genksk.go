@@ -105,6 +105,11 @@ func GenerateKSK(w http.ResponseWriter, r *http.Request) {
 	}
 	if !ok {
 		log.Warnln("Generating CDN '" + string(cdnName) + "' KSK: no keys found in Traffic Vault, generating and inserting new key anyway")
+		dnssecKeys = tc.DNSSECKeysTrafficVault{}
+	}
+	if dnssecKeys[string(cdnName)].Rollover.InProgress() {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusConflict, errors.New("a DNSSEC algorithm rollover of cdn '"+string(cdnName)+"' is in progress, it must be completed or cancelled before generating a KSK"), nil)
+		return
 	}
 
 	isKSK := true
@@ -121,6 +126,11 @@ func GenerateKSK(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	api.CreateChangeLogRawTx(api.ApiChange, "CDN: "+string(cdnName)+", ID: "+strconv.Itoa(cdnID)+", ACTION: Generated KSK DNSSEC keys", inf.User, inf.Tx.Tx)
+	if dsPub, ok := inf.Config.GetDSPublication(string(cdnName)); ok {
+		alerts := syncDSRecordsAlerts(dsPub, cdnDomain, dnssecKeys[string(cdnName)])
+		api.WriteAlertsObj(w, r, http.StatusOK, alerts, "Successfully generated ksk dnssec keys for "+string(cdnName))
+		return
+	}
 	api.WriteResp(w, r, "Successfully generated ksk dnssec keys for "+string(cdnName))
 }
 
@@ -181,6 +191,7 @@ const DefaultDNSSECKeyTTL = 60 * time.Second
 
 // regenExpiredKeys regenerates expired keys. The key is the map key into the keys object, which may be a CDN name or a delivery service name.
 // The name is the name of the key, either the CDN name or the Delivery Service name. If existingKeys contains any keys marked "new", the name argument is not used, but the name of the previously-new key is used instead. These should match, and a warning is logged if they differ.
+// The new key has the algorithm of the previously-new key. Keys of other algorithms, which exist during an algorithm rollover, are kept.
 func regenExpiredKeys(typeKSK bool, name string, existingKeys tc.DNSSECKeySetV11, effectiveDate time.Time, tld bool, resetExp bool) (tc.DNSSECKeySetV11, error) {
 	existingKey := ([]tc.DNSSECKeyV11)(nil)
 	if typeKSK {
@@ -222,11 +233,14 @@ func regenExpiredKeys(typeKSK bool, name string, existingKeys tc.DNSSECKeySetV11
 		newExpiration = time.Now().Add(time.Duration(expirationDays) * time.Hour * 24)
 	}
 
+	// if there is no previously-new key, use the algorithm of the other key type
+	algorithm := deliveryservice.GetDNSSECKeysAlgorithm(existingKey, deliveryservice.GetDNSSECKeysAlgorithm(existingKeys.KSK, deliveryservice.GetDNSSECKeysAlgorithm(existingKeys.ZSK, tc.DefaultDNSSECAlgorithm)))
+
 	keyType := tc.DNSSECKSKType
 	if !typeKSK {
 		keyType = tc.DNSSECZSKType
 	}
-	newKey, err := deliveryservice.GetDNSSECKeysV11(keyType, name, ttl, newInception, newExpiration, tc.DNSSECKeyStatusNew, effectiveDate, tld)
+	newKey, err := deliveryservice.GetDNSSECKeysV11(keyType, name, ttl, newInception, newExpiration, tc.DNSSECKeyStatusNew, effectiveDate, tld, algorithm)
 	if err != nil {
 		return tc.DNSSECKeySetV11{}, errors.New("getting and generating DNSSEC keys: " + err.Error())
 	}
@@ -240,12 +254,17 @@ func regenExpiredKeys(typeKSK bool, name string, existingKeys tc.DNSSECKeySetV11
 
 		newKeys = append(newKeys, oldKey)
 	}
+	for _, key := range existingKey {
+		if keyAlgorithm, err := deliveryservice.GetDNSSECKeyAlgorithm(key); err == nil && keyAlgorithm != algorithm {
+			newKeys = append(newKeys, key)
+		}
+	}
 
-	regenKeys := tc.DNSSECKeySetV11{}
+	regenKeys := existingKeys
 	if typeKSK {
-		regenKeys = tc.DNSSECKeySetV11{ZSK: existingKeys.ZSK, KSK: newKeys}
+		regenKeys.KSK = newKeys
 	} else {
-		regenKeys = tc.DNSSECKeySetV11{ZSK: newKeys, KSK: existingKeys.KSK}
+		regenKeys.ZSK = newKeys
 	}
 	return regenKeys, nil
 }
@@ -0,0 +1,170 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// dcdnTokenLifetime is how long the tokens signed for downstream CDNs are
+// valid.
+const dcdnTokenLifetime = 5 * time.Minute
+
+// maxDCdnErrorBodyLength is the most of a downstream CDN's error response
+// body included in an error.
+const maxDCdnErrorBodyLength = 512
+
+// dcdnClient is a client of the CDNi API of a downstream CDN (dCDN).
+type dcdnClient struct {
+	conf config.CdniDCdnConf
+	// ucdnID is the CDNi identifier of this CDN, the issuer of the tokens
+	// signed for the dCDN.
+	ucdnID string
+	client *http.Client
+}
+
+func newDCdnClient(conf config.CdniDCdnConf, ucdnID string) *dcdnClient {
+	return &dcdnClient{
+		conf:   conf,
+		ucdnID: ucdnID,
+		client: &http.Client{Timeout: time.Duration(conf.TimeoutSec) * time.Second},
+	}
+}
+
+// token returns the bearer token sent to the dCDN: the token it issued, or
+// else a token signed with the secret shared with it.
+func (c *dcdnClient) token() (string, error) {
+	if c.conf.Token != "" {
+		return c.conf.Token, nil
+	}
+	now := time.Now()
+	token, err := jwt.NewBuilder().
+		Issuer(c.ucdnID).
+		Audience([]string{c.conf.Name}).
+		IssuedAt(now).
+		Expiration(now.Add(dcdnTokenLifetime)).
+		Build()
+	if err != nil {
+		return "", fmt.Errorf("building token: %w", err)
+	}
+	signed, err := jwt.Sign(token, jwa.HS256, []byte(c.conf.JWTSecret))
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+	return string(signed), nil
+}
+
+// do makes a request of the dCDN's CDNi API at the path, relative to its
+// URL, and decodes the response body into respObj, if it's not nil. Non-2xx
+// responses are errors.
+func (c *dcdnClient) do(ctx context.Context, method string, path string, reqObj interface{}, respObj interface{}) error {
+	reqURL := c.conf.URL + path
+	var body io.Reader
+	if reqObj != nil {
+		reqBody, err := json.Marshal(reqObj)
+		if err != nil {
+			return fmt.Errorf("encoding request body: %w", err)
+		}
+		body = bytes.NewReader(reqBody)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set(rfc.Authorization, "Bearer "+token)
+	if reqObj != nil {
+		req.Header.Set(rfc.ContentType, rfc.ApplicationJSON)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, reqURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxDCdnErrorBodyLength))
+		return fmt.Errorf("%s %s: dCDN '%s' responded %d: %s", method, reqURL, c.conf.Name, resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	if respObj == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(respObj); err != nil {
+		return fmt.Errorf("%s %s: decoding response body: %w", method, reqURL, err)
+	}
+	return nil
+}
+
+// getAdvertisement returns the FCI advertisement of the dCDN.
+func (c *dcdnClient) getAdvertisement(ctx context.Context) (advertisement, error) {
+	adv := advertisement{}
+	if err := c.do(ctx, http.MethodGet, "OC/FCI/advertisement", nil, &adv); err != nil {
+		return advertisement{}, err
+	}
+	if adv.Capabilities == nil {
+		return advertisement{}, errors.New("dCDN '" + c.conf.Name + "' advertised no capabilities")
+	}
+	return adv, nil
+}
+
+// putHostMetadata pushes the generic metadata of the host to the dCDN.
+func (c *dcdnClient) putHostMetadata(ctx context.Context, host string, metadata []GenericMetadata) error {
+	metadataList, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("encoding host metadata: %w", err)
+	}
+	reqObj := GenericHostMetadata{
+		Host:         host,
+		HostMetadata: HostMetadataList{Metadata: metadataList},
+	}
+	return c.do(ctx, http.MethodPut, "OC/CI/configuration/"+url.PathEscape(host), reqObj, nil)
+}
+
+// advertisement is an FCI advertisement of a dCDN, whose capability values
+// are decoded according to their types once they're needed.
+type advertisement struct {
+	Capabilities []advertisedCapability `json:"capabilities"`
+}
+
+// advertisedCapability is a capability of an FCI advertisement.
+type advertisedCapability struct {
+	CapabilityType  SupportedCapabilities `json:"capability-type"`
+	CapabilityValue json.RawMessage       `json:"capability-value"`
+	Footprints      []Footprint           `json:"footprints"`
+}
@@ -0,0 +1,500 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/tenant"
+
+	"github.com/asaskevich/govalidator"
+)
+
+const (
+	selectDelegationQuery = `
+SELECT cd.deliveryservice, ds.xml_id, cd.dcdn, cd.host, cd.footprints, cd.last_pushed, cd.last_updated
+FROM cdni_delegation AS cd
+JOIN deliveryservice AS ds ON ds.id = cd.deliveryservice
+WHERE cd.deliveryservice = $1`
+	upsertDelegationQuery = `
+INSERT INTO cdni_delegation (deliveryservice, dcdn, host, footprints, last_pushed)
+VALUES ($1, $2, $3, $4, now())
+ON CONFLICT (deliveryservice) DO UPDATE SET
+	dcdn = EXCLUDED.dcdn,
+	host = EXCLUDED.host,
+	footprints = EXCLUDED.footprints,
+	last_pushed = EXCLUDED.last_pushed,
+	last_updated = now()
+RETURNING last_pushed, last_updated`
+	deleteDelegationQuery = `DELETE FROM cdni_delegation WHERE deliveryservice = $1`
+)
+
+// GetDelegation returns the delegation of a Delivery Service to a downstream
+// CDN.
+func GetDelegation(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	dsID := inf.IntParams["id"]
+	if errCode, userErr, sysErr := checkDelegationTenant(inf, dsID); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	delegation, ok, err := getDelegation(inf.Tx.Tx, dsID)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("delivery service %d is not delegated to a dCDN", dsID), nil)
+		return
+	}
+	api.WriteResp(w, r, delegation)
+}
+
+// PutDelegation delegates a Delivery Service to a downstream CDN. The
+// Delivery Service's configuration is pushed to the dCDN as generic host
+// metadata, and the footprints the dCDN advertises it can deliver the content
+// to are stored, for Traffic Router to redirect clients in them to the dCDN
+// once the CDN is snapshotted.
+//
+// The request's transaction is committed before the dCDN is contacted, and
+// the delegation is written in a new transaction afterward, so that no
+// database connection or lock is held for as long as the dCDN takes to
+// respond.
+func PutDelegation(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+	tx := inf.Tx.Tx
+
+	var req tc.CDNiDelegationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.HandleErr(w, r, tx, http.StatusBadRequest, fmt.Errorf("decoding request: %w", err), nil)
+		return
+	}
+	dcdnConf, ok := inf.Config.Cdni.GetDCdn(req.DCDN)
+	if !ok {
+		api.HandleErr(w, r, tx, http.StatusBadRequest, fmt.Errorf("dcdn '%s' is not configured", req.DCDN), nil)
+		return
+	}
+	if !govalidator.IsDNSName(req.Host) {
+		api.HandleErr(w, r, tx, http.StatusBadRequest, errors.New("host must be a valid hostname"), nil)
+		return
+	}
+
+	dsID := inf.IntParams["id"]
+	if errCode, userErr, sysErr := checkDelegationTenant(inf, dsID); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+	dses, userErr, sysErr, errCode := deliveryservice.GetDeliveryServices(deliveryservice.SelectDeliveryServicesQuery+" WHERE ds.id = :id", map[string]interface{}{"id": dsID}, inf.Tx)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+	if len(dses) == 0 {
+		api.HandleErr(w, r, tx, http.StatusNotFound, fmt.Errorf("delivery service %d not found", dsID), nil)
+		return
+	}
+	ds := dses[0]
+	if userErr, sysErr, errCode := dbhelpers.CheckIfCurrentUserCanModifyCDN(tx, *ds.CDNName, inf.User.UserName); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+	if ds.Type == nil || !ds.Type.IsHTTP() {
+		api.HandleErr(w, r, tx, http.StatusBadRequest, errors.New("only HTTP delivery services can be delegated, because Traffic Router redirects delegated clients with HTTP redirects"), nil)
+		return
+	}
+
+	metadata, err := dsHostMetadata(ds)
+	if err != nil {
+		api.HandleErr(w, r, tx, http.StatusBadRequest, fmt.Errorf("delivery service cannot be delegated: %w", err), nil)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("committing transaction: %w", err))
+		return
+	}
+
+	client := newDCdnClient(dcdnConf, inf.Config.Cdni.UCdnId)
+	adv, err := client.getAdvertisement(r.Context())
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusBadGateway, fmt.Errorf("getting the FCI advertisement of dcdn '%s': %w", req.DCDN, err), nil)
+		return
+	}
+	footprints, err := delegatedFootprints(adv, dsDeliveryProtocols(ds.Protocol), metadata)
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusBadRequest, fmt.Errorf("dcdn '%s' cannot deliver the delivery service: %w", req.DCDN, err), nil)
+		return
+	}
+	if err := client.putHostMetadata(r.Context(), req.Host, metadata); err != nil {
+		api.HandleErr(w, r, nil, http.StatusBadGateway, fmt.Errorf("pushing the configuration of host '%s' to dcdn '%s': %w", req.Host, req.DCDN, err), nil)
+		return
+	}
+
+	footprintsJSON, err := json.Marshal(footprints)
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusInternalServerError, nil, fmt.Errorf("encoding footprints: %w", err))
+		return
+	}
+
+	db, err := api.GetDB(r.Context())
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusInternalServerError, nil, fmt.Errorf("getting db: %w", err))
+		return
+	}
+	dbCtx, dbClose := context.WithTimeout(r.Context(), time.Duration(inf.Config.DBQueryTimeoutSeconds)*time.Second)
+	defer dbClose()
+	tx, err = db.BeginTx(dbCtx, nil)
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusInternalServerError, nil, fmt.Errorf("beginning transaction: %w", err))
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Errorln("rolling back CDNi delegation transaction: " + err.Error())
+		}
+	}()
+	// the CDN may have been locked by another user while the dCDN was contacted
+	if userErr, sysErr, errCode := dbhelpers.CheckIfCurrentUserCanModifyCDN(tx, *ds.CDNName, inf.User.UserName); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+	delegation := tc.CDNiDelegation{
+		DeliveryServiceID: dsID,
+		XMLID:             *ds.XMLID,
+		DCDN:              req.DCDN,
+		Host:              req.Host,
+		Footprints:        footprints,
+	}
+	if err := tx.QueryRow(upsertDelegationQuery, dsID, req.DCDN, req.Host, footprintsJSON).Scan(&delegation.LastPushed, &delegation.LastUpdated); err != nil {
+		userErr, sysErr, errCode := api.ParseDBError(err)
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+
+	api.CreateChangeLogRawTx(api.ApiChange, "DS: "+*ds.XMLID+", ID: "+strconv.Itoa(dsID)+", ACTION: Delegated to CDNi dCDN "+req.DCDN+" as host "+req.Host, inf.User, tx)
+	if err := tx.Commit(); err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("committing transaction: %w", err))
+		return
+	}
+	msg := "Delivery service delegated to dCDN " + req.DCDN + ". Snapshot CDN " + *ds.CDNName + " for Traffic Router to redirect clients in the dCDN's footprints."
+	api.WriteRespAlertObj(w, r, tc.SuccessLevel, msg, delegation)
+}
+
+// DeleteDelegation removes the delegation of a Delivery Service to a
+// downstream CDN. The dCDN is not notified, since CDNi offers no way to
+// remove a host's configuration.
+func DeleteDelegation(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+	tx := inf.Tx.Tx
+
+	dsID := inf.IntParams["id"]
+	if errCode, userErr, sysErr := checkDelegationTenant(inf, dsID); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+	delegation, ok, err := getDelegation(tx, dsID)
+	if err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	if !ok {
+		api.HandleErr(w, r, tx, http.StatusNotFound, fmt.Errorf("delivery service %d is not delegated to a dCDN", dsID), nil)
+		return
+	}
+	cdnID, _, err := dbhelpers.GetDSCDNIdFromID(tx, dsID)
+	if err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("getting CDN of delivery service %d: %w", dsID, err))
+		return
+	}
+	if userErr, sysErr, errCode := dbhelpers.CheckIfCurrentUserCanModifyCDNWithID(tx, int64(cdnID), inf.User.UserName); userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, tx, errCode, userErr, sysErr)
+		return
+	}
+
+	if _, err := tx.Exec(deleteDelegationQuery, dsID); err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("deleting CDNi delegation of delivery service %d: %w", dsID, err))
+		return
+	}
+
+	api.CreateChangeLogRawTx(api.ApiChange, "DS: "+delegation.XMLID+", ID: "+strconv.Itoa(dsID)+", ACTION: Removed delegation to CDNi dCDN "+delegation.DCDN, inf.User, tx)
+	api.WriteRespAlert(w, r, tc.SuccessLevel, "Delivery service delegation to dCDN "+delegation.DCDN+" removed. Snapshot the CDN for Traffic Router to stop redirecting clients to the dCDN.")
+}
+
+// checkDelegationTenant checks that the Delivery Service exists, and that the
+// user is authorized on its Tenant.
+func checkDelegationTenant(inf *api.APIInfo, dsID int) (int, error, error) {
+	dsTenantID, ok, err := tenant.GetDSTenantIDByIDTx(inf.Tx.Tx, dsID)
+	if err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("getting tenant of delivery service %d: %w", dsID, err)
+	}
+	if !ok {
+		return http.StatusNotFound, fmt.Errorf("delivery service %d not found", dsID), nil
+	}
+	if dsTenantID == nil {
+		return http.StatusOK, nil, nil
+	}
+	if authorized, err := tenant.IsResourceAuthorizedToUserTx(*dsTenantID, inf.User, inf.Tx.Tx); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("checking tenant: %w", err)
+	} else if !authorized {
+		return http.StatusForbidden, errors.New("not authorized on this tenant"), nil
+	}
+	return http.StatusOK, nil, nil
+}
+
+// getDelegation returns the delegation of the Delivery Service, and whether
+// it's delegated.
+func getDelegation(tx *sql.Tx, dsID int) (tc.CDNiDelegation, bool, error) {
+	delegation := tc.CDNiDelegation{}
+	footprints := []byte{}
+	if err := tx.QueryRow(selectDelegationQuery, dsID).Scan(&delegation.DeliveryServiceID, &delegation.XMLID, &delegation.DCDN, &delegation.Host, &footprints, &delegation.LastPushed, &delegation.LastUpdated); err != nil {
+		if err == sql.ErrNoRows {
+			return tc.CDNiDelegation{}, false, nil
+		}
+		return tc.CDNiDelegation{}, false, fmt.Errorf("querying CDNi delegation of delivery service %d: %w", dsID, err)
+	}
+	if err := json.Unmarshal(footprints, &delegation.Footprints); err != nil {
+		return tc.CDNiDelegation{}, false, fmt.Errorf("decoding CDNi delegation footprints of delivery service %d: %w", dsID, err)
+	}
+	return delegation, true, nil
+}
+
+// dsHostMetadata translates a Delivery Service into the generic metadata of
+// the host by which a dCDN delivers its content: its primary Origin, protocol
+// and Geo Limit countries. Its errors are suitable for the user.
+func dsHostMetadata(ds tc.DeliveryServiceV4) ([]GenericMetadata, error) {
+	if ds.OrgServerFQDN == nil || *ds.OrgServerFQDN == "" {
+		return nil, errors.New("it has no origin")
+	}
+	origin, err := url.Parse(*ds.OrgServerFQDN)
+	if err != nil || origin.Host == "" {
+		return nil, fmt.Errorf("invalid origin '%s'", *ds.OrgServerFQDN)
+	}
+	source := Source{Endpoints: []string{origin.Host}}
+	switch origin.Scheme {
+	case "http":
+		source.Protocol = HTTP11
+	case "https":
+		source.Protocol = HTTPS11
+	default:
+		return nil, fmt.Errorf("unsupported origin scheme '%s'", origin.Scheme)
+	}
+	metadata := []GenericMetadata{}
+	if err := appendMetadata(&metadata, MiSourceMetadata, SourceMetadata{Sources: []Source{source}}); err != nil {
+		return nil, err
+	}
+
+	protocol := tc.DSProtocolHTTP
+	if ds.Protocol != nil {
+		protocol = *ds.Protocol
+	}
+	switch protocol {
+	case tc.DSProtocolHTTP:
+		err = appendMetadata(&metadata, MiProtocolACL, ProtocolACL{ProtocolACL: []ProtocolRule{{Action: ACLDeny, Protocols: []Protocol{HTTPS11}}}})
+	case tc.DSProtocolHTTPS:
+		err = appendMetadata(&metadata, MiProtocolACL, ProtocolACL{ProtocolACL: []ProtocolRule{{Action: ACLDeny, Protocols: []Protocol{HTTP11}}}})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ds.GeoLimit != nil && *ds.GeoLimit != 0 {
+		if *ds.GeoLimit == 1 {
+			return nil, errors.New("a geo limit of the Coverage Zone File only cannot be expressed by footprints")
+		}
+		countries := make([]string, 0, len(ds.GeoLimitCountries))
+		for _, country := range ds.GeoLimitCountries {
+			countries = append(countries, strings.ToLower(country))
+		}
+		acl := LocationACL{Locations: []LocationRule{{Action: ACLAllow, Footprints: []Footprint{{FootprintType: CountryCode, FootprintValue: countries}}}}}
+		if err := appendMetadata(&metadata, MiLocationACL, acl); err != nil {
+			return nil, err
+		}
+	}
+	return metadata, nil
+}
+
+// appendMetadata appends the generic metadata of the type and value.
+func appendMetadata(metadata *[]GenericMetadata, typ SupportedGenericMetadataType, value interface{}) error {
+	bts, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding %s: %w", typ, err)
+	}
+	*metadata = append(*metadata, GenericMetadata{Type: typ, Value: bts})
+	return nil
+}
+
+// dsDeliveryProtocols returns the protocols by which content of a Delivery
+// Service with the given protocol is delivered.
+func dsDeliveryProtocols(protocol *int) []Protocol {
+	if protocol == nil {
+		return []Protocol{HTTP11}
+	}
+	switch *protocol {
+	case tc.DSProtocolHTTP:
+		return []Protocol{HTTP11}
+	case tc.DSProtocolHTTPS:
+		return []Protocol{HTTPS11}
+	}
+	return []Protocol{HTTP11, HTTPS11}
+}
+
+// routerFootprintTypes are the footprint types which Traffic Router matches
+// clients against.
+var routerFootprintTypes = map[FootprintType]struct{}{
+	Ipv4Cidr:    {},
+	Ipv6Cidr:    {},
+	CountryCode: {},
+}
+
+// delegatedFootprints returns the footprints to which the dCDN advertises it
+// can deliver content by all of the protocols, combined into one footprint
+// per footprint type. The dCDN must advertise HTTP-I redirection, since
+// Traffic Router redirects delegated clients with HTTP redirects, and all of
+// the metadata types. Capabilities without footprints are ignored, because
+// Traffic Router would redirect all clients on their account, and so are
+// footprints of types Traffic Router doesn't match, such as asn.
+func delegatedFootprints(adv advertisement, protocols []Protocol, metadata []GenericMetadata) ([]tc.CDNiFootprint, error) {
+	httpIterative := false
+	supportedMetadata := map[SupportedGenericMetadataType]struct{}{}
+	footprintValues := map[FootprintType][]string{}
+	footprintTypes := []FootprintType{}
+	seen := map[FootprintType]map[string]struct{}{}
+
+	for _, capability := range adv.Capabilities {
+		switch capability.CapabilityType {
+		case FciRedirectionMode:
+			value := RedirectionModeCapabilityValue{}
+			if err := json.Unmarshal(capability.CapabilityValue, &value); err != nil {
+				return nil, fmt.Errorf("decoding %s capability: %w", capability.CapabilityType, err)
+			}
+			for _, mode := range value.RedirectionModes {
+				if mode == HTTPIterative {
+					httpIterative = true
+				}
+			}
+		case FciMetadata:
+			value := MetadataCapabilityValue{}
+			if err := json.Unmarshal(capability.CapabilityValue, &value); err != nil {
+				return nil, fmt.Errorf("decoding %s capability: %w", capability.CapabilityType, err)
+			}
+			for _, typ := range value.Metadata {
+				supportedMetadata[typ] = struct{}{}
+			}
+		case FciDeliveryProtocol:
+			value := DeliveryProtocolCapabilityValue{}
+			if err := json.Unmarshal(capability.CapabilityValue, &value); err != nil {
+				return nil, fmt.Errorf("decoding %s capability: %w", capability.CapabilityType, err)
+			}
+			if !containsProtocols(value.DeliveryProtocols, protocols) {
+				continue
+			}
+			for _, footprint := range capability.Footprints {
+				if _, ok := routerFootprintTypes[footprint.FootprintType]; !ok {
+					continue
+				}
+				if _, ok := seen[footprint.FootprintType]; !ok {
+					seen[footprint.FootprintType] = map[string]struct{}{}
+					footprintTypes = append(footprintTypes, footprint.FootprintType)
+				}
+				for _, v := range footprint.FootprintValue {
+					if _, ok := seen[footprint.FootprintType][v]; ok {
+						continue
+					}
+					seen[footprint.FootprintType][v] = struct{}{}
+					footprintValues[footprint.FootprintType] = append(footprintValues[footprint.FootprintType], v)
+				}
+			}
+		}
+	}
+
+	if !httpIterative {
+		return nil, fmt.Errorf("it does not advertise the %s redirection mode", HTTPIterative)
+	}
+	for _, m := range metadata {
+		if _, ok := supportedMetadata[m.Type]; !ok {
+			return nil, fmt.Errorf("it does not advertise support of %s metadata", m.Type)
+		}
+	}
+
+	footprints := make([]tc.CDNiFootprint, 0, len(footprintTypes))
+	for _, typ := range footprintTypes {
+		if len(footprintValues[typ]) == 0 {
+			continue
+		}
+		footprints = append(footprints, tc.CDNiFootprint{Type: string(typ), Value: footprintValues[typ]})
+	}
+	if len(footprints) == 0 {
+		protocolNames := make([]string, 0, len(protocols))
+		for _, p := range protocols {
+			protocolNames = append(protocolNames, string(p))
+		}
+		return nil, fmt.Errorf("it advertises no %s, %s or %s footprints to which it can deliver %s", Ipv4Cidr, Ipv6Cidr, CountryCode, strings.Join(protocolNames, " and "))
+	}
+	return footprints, nil
+}
+
+// containsProtocols returns whether all of the wanted protocols are in the
+// protocols.
+func containsProtocols(protocols []Protocol, wanted []Protocol) bool {
+	for _, w := range wanted {
+		found := false
+		for _, p := range protocols {
+			if p == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,284 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// fakeDCdn is a downstream CDN serving an FCI advertisement, and recording
+// the host metadata pushed to it.
+type fakeDCdn struct {
+	t            *testing.T
+	secret       string
+	capabilities Capabilities
+	hosts        map[string]GenericHostMetadata
+}
+
+func (d *fakeDCdn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bearer := strings.TrimPrefix(r.Header.Get(rfc.Authorization), "Bearer ")
+	token, err := jwt.Parse([]byte(bearer), jwt.WithVerify(jwa.HS256, []byte(d.secret)), jwt.WithValidate(true))
+	if err != nil || token.Issuer() != "ucdn1" || !reflect.DeepEqual(token.Audience(), []string{"dcdn1"}) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"alerts":[{"text":"invalid token","level":"error"}]}`))
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/5.0/OC/FCI/advertisement":
+		json.NewEncoder(w).Encode(d.capabilities)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/5.0/OC/CI/configuration/"):
+		hostMetadata := GenericHostMetadata{}
+		if err := json.NewDecoder(r.Body).Decode(&hostMetadata); err != nil {
+			d.t.Errorf("decoding pushed host metadata: %v", err)
+		}
+		d.hosts[strings.TrimPrefix(r.URL.Path, "/api/5.0/OC/CI/configuration/")] = hostMetadata
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeDCdn(t *testing.T, secret string) (*fakeDCdn, *httptest.Server) {
+	footprints := []Footprint{
+		{FootprintType: Ipv4Cidr, FootprintValue: []string{"192.0.2.0/24"}},
+		{FootprintType: CountryCode, FootprintValue: []string{"us"}},
+	}
+	dcdn := &fakeDCdn{
+		t:      t,
+		secret: secret,
+		capabilities: Capabilities{Capabilities: []Capability{
+			{
+				CapabilityType:  FciDeliveryProtocol,
+				CapabilityValue: DeliveryProtocolCapabilityValue{DeliveryProtocols: []Protocol{HTTP11}},
+				Footprints:      footprints,
+			},
+			{
+				CapabilityType:  FciDeliveryProtocol,
+				CapabilityValue: DeliveryProtocolCapabilityValue{DeliveryProtocols: []Protocol{HTTP11, HTTPS11}},
+				Footprints:      []Footprint{{FootprintType: Ipv4Cidr, FootprintValue: []string{"192.0.2.0/24", "198.51.100.0/24"}}},
+			},
+			{
+				CapabilityType:  FciRedirectionMode,
+				CapabilityValue: RedirectionModeCapabilityValue{RedirectionModes: []RedirectionMode{HTTPIterative}},
+				Footprints:      footprints,
+			},
+			{
+				CapabilityType:  FciMetadata,
+				CapabilityValue: MetadataCapabilityValue{Metadata: supportedGenericMetadataTypes},
+				Footprints:      footprints,
+			},
+		}},
+		hosts: map[string]GenericHostMetadata{},
+	}
+	return dcdn, httptest.NewServer(dcdn)
+}
+
+func TestDelegation(t *testing.T) {
+	dcdn, srv := newFakeDCdn(t, "secret")
+	defer srv.Close()
+
+	client := newDCdnClient(config.CdniDCdnConf{Name: "dcdn1", URL: srv.URL + "/api/5.0/", JWTSecret: "secret", TimeoutSec: 5}, "ucdn1")
+	ds := tc.DeliveryServiceV4{}
+	ds.OrgServerFQDN = util.StrPtr("https://origin.example.com:8443")
+	ds.Protocol = util.IntPtr(tc.DSProtocolHTTP)
+	ds.GeoLimit = util.IntPtr(2)
+	ds.GeoLimitCountries = []string{"US"}
+
+	metadata, err := dsHostMetadata(ds)
+	if err != nil {
+		t.Fatalf("expected no error translating the delivery service, got: %v", err)
+	}
+	adv, err := client.getAdvertisement(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error getting the advertisement, got: %v", err)
+	}
+	footprints, err := delegatedFootprints(adv, dsDeliveryProtocols(ds.Protocol), metadata)
+	if err != nil {
+		t.Fatalf("expected no error getting the delegated footprints, got: %v", err)
+	}
+	expected := []tc.CDNiFootprint{
+		{Type: "ipv4cidr", Value: []string{"192.0.2.0/24", "198.51.100.0/24"}},
+		{Type: "countrycode", Value: []string{"us"}},
+	}
+	if !reflect.DeepEqual(footprints, expected) {
+		t.Errorf("expected footprints %+v, got: %+v", expected, footprints)
+	}
+
+	if err := client.putHostMetadata(context.Background(), "video.example.com", metadata); err != nil {
+		t.Fatalf("expected no error pushing host metadata, got: %v", err)
+	}
+	pushed, ok := dcdn.hosts["video.example.com"]
+	if !ok {
+		t.Fatalf("expected the metadata of host video.example.com to be pushed, got: %+v", dcdn.hosts)
+	}
+	pushedMetadata := []GenericMetadata{}
+	if err := json.Unmarshal(pushed.HostMetadata.Metadata, &pushedMetadata); err != nil {
+		t.Fatalf("decoding pushed metadata: %v", err)
+	}
+
+	// The pushed metadata must be accepted by a Traffic Ops dCDN.
+	hostDS, err := translateHostMetadata(pushedMetadata, true)
+	if err != nil {
+		t.Fatalf("expected no error translating the pushed metadata, got: %v", err)
+	}
+	if len(hostDS.Origins) != 1 || hostDS.Origins[0].String() != *ds.OrgServerFQDN {
+		t.Errorf("expected origin %s, got: %+v", *ds.OrgServerFQDN, hostDS.Origins)
+	}
+	if hostDS.Protocol != tc.DSProtocolHTTP {
+		t.Errorf("expected protocol %d, got: %d", tc.DSProtocolHTTP, hostDS.Protocol)
+	}
+	if hostDS.GeoLimit != 2 || !reflect.DeepEqual(hostDS.GeoLimitCountries, []string{"US"}) {
+		t.Errorf("expected geo limit 2 to US, got: %d to %v", hostDS.GeoLimit, hostDS.GeoLimitCountries)
+	}
+}
+
+func TestDCdnClientErrors(t *testing.T) {
+	_, srv := newFakeDCdn(t, "secret")
+	defer srv.Close()
+
+	client := newDCdnClient(config.CdniDCdnConf{Name: "dcdn1", URL: srv.URL + "/api/5.0/", JWTSecret: "wrong", TimeoutSec: 5}, "ucdn1")
+	if _, err := client.getAdvertisement(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an unauthorized error, got: %v", err)
+	}
+
+	client = newDCdnClient(config.CdniDCdnConf{Name: "dcdn1", URL: srv.URL + "/api/5.0/", Token: "not-a-jwt", TimeoutSec: 5}, "")
+	if err := client.putHostMetadata(context.Background(), "video.example.com", nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an unauthorized error, got: %v", err)
+	}
+}
+
+func TestDelegatedFootprintsErrors(t *testing.T) {
+	source := []GenericMetadata{metadata(t, MiSourceMetadata, `{"sources": [{"endpoints": ["origin.example.com"], "protocol": "http/1.1"}]}`)}
+	capability := func(typ SupportedCapabilities, value string, footprints ...Footprint) advertisedCapability {
+		return advertisedCapability{CapabilityType: typ, CapabilityValue: json.RawMessage(value), Footprints: footprints}
+	}
+	footprint := Footprint{FootprintType: Ipv4Cidr, FootprintValue: []string{"192.0.2.0/24"}}
+	redirection := capability(FciRedirectionMode, `{"redirection-modes": ["HTTP-I"]}`)
+	meta := capability(FciMetadata, `{"metadata": ["MI.SourceMetadata"]}`)
+	delivery := capability(FciDeliveryProtocol, `{"delivery-protocols": ["http/1.1"]}`, footprint)
+
+	tests := map[string]struct {
+		capabilities []advertisedCapability
+		protocols    []Protocol
+	}{
+		"no HTTP-I redirection": {
+			[]advertisedCapability{capability(FciRedirectionMode, `{"redirection-modes": ["DNS-I"]}`), meta, delivery},
+			[]Protocol{HTTP11},
+		},
+		"unsupported metadata": {
+			[]advertisedCapability{redirection, capability(FciMetadata, `{"metadata": ["MI.CachePolicy"]}`), delivery},
+			[]Protocol{HTTP11},
+		},
+		"no HTTPS delivery": {
+			[]advertisedCapability{redirection, meta, delivery},
+			[]Protocol{HTTP11, HTTPS11},
+		},
+		"no footprints": {
+			[]advertisedCapability{redirection, meta, capability(FciDeliveryProtocol, `{"delivery-protocols": ["http/1.1"]}`)},
+			[]Protocol{HTTP11},
+		},
+		"only footprints Traffic Router cannot match": {
+			[]advertisedCapability{redirection, meta, capability(FciDeliveryProtocol, `{"delivery-protocols": ["http/1.1"]}`, Footprint{FootprintType: Asn, FootprintValue: []string{"as64496"}}, Footprint{FootprintType: "subdivisioncode", FootprintValue: []string{"us-co"}})},
+			[]Protocol{HTTP11},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := delegatedFootprints(advertisement{Capabilities: test.capabilities}, test.protocols, source); err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestDSHostMetadataErrors(t *testing.T) {
+	tests := map[string]tc.DeliveryServiceV4{}
+
+	noOrigin := tc.DeliveryServiceV4{}
+	tests["no origin"] = noOrigin
+
+	czfOnly := tc.DeliveryServiceV4{}
+	czfOnly.OrgServerFQDN = util.StrPtr("http://origin.example.com")
+	czfOnly.GeoLimit = util.IntPtr(1)
+	tests["coverage zone geo limit"] = czfOnly
+
+	badScheme := tc.DeliveryServiceV4{}
+	badScheme.OrgServerFQDN = util.StrPtr("ftp://origin.example.com")
+	tests["unsupported origin scheme"] = badScheme
+
+	for name, ds := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := dsHostMetadata(ds); err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestDelegatedFootprintsIgnoresUnmatchedTypes(t *testing.T) {
+	source := []GenericMetadata{metadata(t, MiSourceMetadata, `{"sources": [{"endpoints": ["origin.example.com"], "protocol": "http/1.1"}]}`)}
+	adv := advertisement{Capabilities: []advertisedCapability{
+		{CapabilityType: FciRedirectionMode, CapabilityValue: json.RawMessage(`{"redirection-modes": ["HTTP-I"]}`)},
+		{CapabilityType: FciMetadata, CapabilityValue: json.RawMessage(`{"metadata": ["MI.SourceMetadata"]}`)},
+		{
+			CapabilityType:  FciDeliveryProtocol,
+			CapabilityValue: json.RawMessage(`{"delivery-protocols": ["http/1.1"]}`),
+			Footprints: []Footprint{
+				{FootprintType: Asn, FootprintValue: []string{"as64496"}},
+				{FootprintType: Ipv6Cidr, FootprintValue: []string{"2001:db8::/32"}},
+			},
+		},
+	}}
+	footprints, err := delegatedFootprints(adv, []Protocol{HTTP11}, source)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []tc.CDNiFootprint{{Type: "ipv6cidr", Value: []string{"2001:db8::/32"}}}
+	if !reflect.DeepEqual(footprints, expected) {
+		t.Errorf("expected footprints %+v, got: %+v", expected, footprints)
+	}
+}
+
+func TestFootprintsEqual(t *testing.T) {
+	encoded := []byte(`[{"footprint-type":"ipv4cidr","footprint-value":["192.0.2.0/24"]}]`)
+	stored := []byte(`[{"footprint-type": "ipv4cidr", "footprint-value": ["192.0.2.0/24"]}]`)
+	if !footprintsEqual(encoded, stored) {
+		t.Error("expected footprints differing only in whitespace to be equal")
+	}
+	changed := []byte(`[{"footprint-type": "ipv4cidr", "footprint-value": ["198.51.100.0/24"]}]`)
+	if footprintsEqual(encoded, changed) {
+		t.Error("expected footprints with different values to differ")
+	}
+}
@@ -0,0 +1,218 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	selectDelegationsQuery = `
+SELECT deliveryservice, dcdn, host, footprints FROM cdni_delegation ORDER BY deliveryservice`
+	// updateDelegationFootprintsQuery only updates a delegation which hasn't
+	// been changed since its footprints were refreshed.
+	updateDelegationFootprintsQuery = `
+UPDATE cdni_delegation SET footprints = $4, last_updated = now()
+WHERE deliveryservice = $1 AND dcdn = $2 AND host = $3 AND footprints = $5`
+)
+
+// storedDelegation is a delegation as stored, with its encoded footprints.
+type storedDelegation struct {
+	dsID       int
+	dcdn       string
+	host       string
+	footprints []byte
+}
+
+var delegationRefresherOnce = sync.Once{}
+
+// StartDelegationRefresher starts refreshing the footprints of delegated
+// Delivery Services from their dCDNs' advertisements every interval, which
+// otherwise are only fetched when a delegation is created or updated. If
+// interval is not positive, or CDNi isn't configured, footprints are never
+// refreshed.
+//
+// The refresher stops when ctx is done, and the returned channel is closed
+// once it has.
+func StartDelegationRefresher(ctx context.Context, interval time.Duration, db *sqlx.DB, timeout time.Duration, cdniConf *config.CdniConf) <-chan struct{} {
+	done := make(chan struct{})
+	started := false
+	delegationRefresherOnce.Do(func() {
+		if interval <= 0 || cdniConf == nil {
+			return
+		}
+		started = true
+		go func() {
+			defer close(done)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					refreshDelegations(ctx, db, timeout, cdniConf)
+				}
+			}
+		}()
+	})
+	if !started {
+		close(done)
+	}
+	return done
+}
+
+func refreshDelegations(ctx context.Context, db *sqlx.DB, timeout time.Duration, cdniConf *config.CdniConf) {
+	delegations, err := getStoredDelegations(db, timeout)
+	if err != nil {
+		log.Errorln("refreshing CDNi delegation footprints: " + err.Error())
+		return
+	}
+	for _, delegation := range delegations {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := refreshDelegation(ctx, db, timeout, cdniConf, delegation); err != nil {
+			log.Warnf("refreshing footprints of delivery service %d delegated to dcdn '%s', keeping its previous footprints: %s", delegation.dsID, delegation.dcdn, err.Error())
+		}
+	}
+}
+
+func getStoredDelegations(db *sqlx.DB, timeout time.Duration) ([]storedDelegation, error) {
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	rows, err := db.QueryContext(dbCtx, selectDelegationsQuery)
+	if err != nil {
+		return nil, errors.New("querying delegations: " + err.Error())
+	}
+	defer log.Close(rows, "closing delegation rows")
+	delegations := []storedDelegation{}
+	for rows.Next() {
+		d := storedDelegation{}
+		if err := rows.Scan(&d.dsID, &d.dcdn, &d.host, &d.footprints); err != nil {
+			return nil, errors.New("scanning delegations: " + err.Error())
+		}
+		delegations = append(delegations, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating delegation rows: " + err.Error())
+	}
+	return delegations, nil
+}
+
+// refreshDelegation fetches the dCDN's advertisement outside of any
+// transaction, and stores the footprints it now advertises for the Delivery
+// Service. Traffic Router only sees changed footprints once the CDN is
+// snapshotted.
+func refreshDelegation(ctx context.Context, db *sqlx.DB, timeout time.Duration, cdniConf *config.CdniConf, delegation storedDelegation) error {
+	dcdnConf, ok := cdniConf.GetDCdn(delegation.dcdn)
+	if !ok {
+		return errors.New("dcdn is no longer configured")
+	}
+	ds, err := getDelegatedDS(db, timeout, delegation.dsID)
+	if err != nil {
+		return err
+	}
+	metadata, err := dsHostMetadata(ds)
+	if err != nil {
+		return fmt.Errorf("delivery service cannot be delegated: %w", err)
+	}
+
+	adv, err := newDCdnClient(dcdnConf, cdniConf.UCdnId).getAdvertisement(ctx)
+	if err != nil {
+		return fmt.Errorf("getting the FCI advertisement: %w", err)
+	}
+	footprints, err := delegatedFootprints(adv, dsDeliveryProtocols(ds.Protocol), metadata)
+	if err != nil {
+		return fmt.Errorf("dcdn cannot deliver the delivery service: %w", err)
+	}
+	footprintsJSON, err := json.Marshal(footprints)
+	if err != nil {
+		return fmt.Errorf("encoding footprints: %w", err)
+	}
+	if footprintsEqual(footprintsJSON, delegation.footprints) {
+		return nil
+	}
+
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	result, err := db.ExecContext(dbCtx, updateDelegationFootprintsQuery, delegation.dsID, delegation.dcdn, delegation.host, footprintsJSON, delegation.footprints)
+	if err != nil {
+		return fmt.Errorf("updating footprints: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+		log.Infof("footprints of delivery service %d delegated to dcdn '%s' changed, snapshot its CDN for Traffic Router to use them", delegation.dsID, delegation.dcdn)
+	}
+	return nil
+}
+
+// getDelegatedDS returns the delegated Delivery Service.
+func getDelegatedDS(db *sqlx.DB, timeout time.Duration, dsID int) (tc.DeliveryServiceV4, error) {
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	tx, err := db.BeginTxx(dbCtx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return tc.DeliveryServiceV4{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Errorln("rolling back CDNi delegation refresh transaction: " + err.Error())
+		}
+	}()
+	dses, userErr, sysErr, _ := deliveryservice.GetDeliveryServices(deliveryservice.SelectDeliveryServicesQuery+" WHERE ds.id = :id", map[string]interface{}{"id": dsID}, tx)
+	if userErr != nil {
+		return tc.DeliveryServiceV4{}, fmt.Errorf("getting delivery service: %w", userErr)
+	}
+	if sysErr != nil {
+		return tc.DeliveryServiceV4{}, fmt.Errorf("getting delivery service: %w", sysErr)
+	}
+	if len(dses) == 0 {
+		return tc.DeliveryServiceV4{}, errors.New("delivery service not found")
+	}
+	return dses[0], nil
+}
+
+// footprintsEqual returns whether the encoded footprints are the same, since
+// the database may not store them byte-for-byte as encoded.
+func footprintsEqual(a []byte, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	fa, fb := []tc.CDNiFootprint{}, []tc.CDNiFootprint{}
+	if json.Unmarshal(a, &fa) != nil || json.Unmarshal(b, &fb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(fa, fb)
+}
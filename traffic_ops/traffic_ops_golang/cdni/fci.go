@@ -0,0 +1,151 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+
+	"github.com/lib/pq"
+)
+
+// cacheGroupFootprintsQuery selects the ASNs of each edge Cache Group of the
+// given CDN. Cache Groups without Coordinates are left out, because Traffic
+// Router cannot route geolocated clients to them.
+const cacheGroupFootprintsQuery = `
+SELECT cg.name, ARRAY_AGG(a.asn::text ORDER BY a.asn)
+FROM cachegroup AS cg
+JOIN coordinate AS co ON co.id = cg.coordinate
+JOIN type AS t ON t.id = cg.type
+JOIN asn AS a ON a.cachegroup = cg.id
+WHERE t.name = $1
+AND EXISTS (
+	SELECT 1 FROM server AS s
+	JOIN cdn ON cdn.id = s.cdn_id
+	WHERE s.cachegroup = cg.id AND cdn.name = $2
+)
+GROUP BY cg.name
+ORDER BY cg.name`
+
+// getServiceCapabilities returns the delivery protocol, acquisition protocol,
+// redirection mode and metadata capabilities of the configured CDN, each with
+// the footprints of the CDN's edge Cache Groups.
+func getServiceCapabilities(inf *api.APIInfo) (Capabilities, error) {
+	if inf.Config.Cdni.CDNName == "" {
+		return Capabilities{}, nil
+	}
+
+	footprints, err := getCacheGroupFootprints(inf.Tx.Tx, inf.Config.Cdni.CDNName)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	deliveryProtocols := []Protocol{HTTP11}
+	if inf.Config.TrafficVaultEnabled {
+		deliveryProtocols = append(deliveryProtocols, HTTPS11)
+	}
+
+	return Capabilities{
+		Capabilities: []Capability{
+			{
+				CapabilityType:  FciDeliveryProtocol,
+				CapabilityValue: DeliveryProtocolCapabilityValue{DeliveryProtocols: deliveryProtocols},
+				Footprints:      footprints,
+			},
+			{
+				CapabilityType:  FciAcquisitionProtocol,
+				CapabilityValue: AcquisitionProtocolCapabilityValue{AcquisitionProtocols: []Protocol{HTTP11, HTTPS11}},
+				Footprints:      footprints,
+			},
+			{
+				CapabilityType:  FciRedirectionMode,
+				CapabilityValue: RedirectionModeCapabilityValue{RedirectionModes: []RedirectionMode{HTTPIterative}},
+				Footprints:      footprints,
+			},
+			{
+				CapabilityType:  FciMetadata,
+				CapabilityValue: MetadataCapabilityValue{Metadata: supportedGenericMetadataTypes},
+				Footprints:      footprints,
+			},
+		},
+	}, nil
+}
+
+// getCacheGroupFootprints returns an ASN footprint for each edge Cache Group
+// of the named CDN.
+func getCacheGroupFootprints(tx *sql.Tx, cdnName string) ([]Footprint, error) {
+	rows, err := tx.Query(cacheGroupFootprintsQuery, tc.CacheGroupEdgeTypeName, cdnName)
+	if err != nil {
+		return nil, fmt.Errorf("querying cache group footprints: %w", err)
+	}
+	defer log.Close(rows, "closing cache group footprints query")
+
+	footprints := []Footprint{}
+	for rows.Next() {
+		var cacheGroup string
+		var asns []string
+		if err := rows.Scan(&cacheGroup, pq.Array(&asns)); err != nil {
+			return nil, fmt.Errorf("scanning cache group footprints: %w", err)
+		}
+		footprints = append(footprints, asnFootprint(asns))
+	}
+	return footprints, nil
+}
+
+// asnFootprint returns an ASN footprint of the given AS numbers, in the
+// "as<number>" form RFC 8006 uses for footprint values.
+func asnFootprint(asns []string) Footprint {
+	footprint := Footprint{
+		FootprintType:  Asn,
+		FootprintValue: make([]string, 0, len(asns)),
+	}
+	for _, asn := range asns {
+		footprint.FootprintValue = append(footprint.FootprintValue, "as"+asn)
+	}
+	return footprint
+}
+
+// DeliveryProtocolCapabilityValue contains the protocols by which the dCDN
+// can deliver content to clients.
+type DeliveryProtocolCapabilityValue struct {
+	DeliveryProtocols []Protocol `json:"delivery-protocols"`
+}
+
+// AcquisitionProtocolCapabilityValue contains the protocols by which the dCDN
+// can acquire content from origins.
+type AcquisitionProtocolCapabilityValue struct {
+	AcquisitionProtocols []Protocol `json:"acquisition-protocols"`
+}
+
+// RedirectionModeCapabilityValue contains the redirection modes the dCDN
+// supports.
+type RedirectionModeCapabilityValue struct {
+	RedirectionModes []RedirectionMode `json:"redirection-modes"`
+}
+
+// MetadataCapabilityValue contains the generic metadata types the dCDN
+// supports.
+type MetadataCapabilityValue struct {
+	Metadata []SupportedGenericMetadataType `json:"metadata"`
+}
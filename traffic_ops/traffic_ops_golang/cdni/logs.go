@@ -0,0 +1,228 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+)
+
+const (
+	// LogFileExtension is the file name extension of CDNi logging files.
+	LogFileExtension = ".log"
+
+	// LogFileContentType is the media type of CDNi logging files (RFC 7937).
+	LogFileContentType = "application/cdni; ptype=logging-file"
+
+	hostXMLIDsQuery = `
+SELECT DISTINCT ds.xml_id
+FROM cdni_host AS ch
+JOIN deliveryservice AS ds ON ds.id = ch.deliveryservice
+WHERE ch.ucdn = $1
+ORDER BY ds.xml_id`
+)
+
+// LogFiles is the list of the CDNi logging files available to a uCDN.
+type LogFiles struct {
+	Logs []LogFile `json:"logs"`
+}
+
+// LogFile is a CDNi logging file (RFC 7937) of the records of requests for
+// the content of a Delivery Service.
+type LogFile struct {
+	DeliveryService string    `json:"deliveryService"`
+	Name            string    `json:"name"`
+	Size            int64     `json:"size"`
+	LastModified    time.Time `json:"lastModified"`
+}
+
+// GetLogs lists the CDNi logging files of the Delivery Services of the uCDN's
+// hosts.
+func GetLogs(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, nil, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	ucdn, errCode, userErr, sysErr := checkLogsRequest(r, inf)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	xmlIDs, err := getHostXMLIDs(inf.Tx.Tx, ucdn)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	files, err := listLogFiles(inf.Config.Cdni.LogDir, xmlIDs)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	api.WriteRespRaw(w, r, LogFiles{Logs: files})
+}
+
+// GetLog serves a CDNi logging file of a Delivery Service of one of the
+// uCDN's hosts.
+func GetLog(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"xmlId", "name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	ucdn, errCode, userErr, sysErr := checkLogsRequest(r, inf)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+
+	xmlID := inf.Params["xmlId"]
+	name := inf.Params["name"]
+	xmlIDs, err := getHostXMLIDs(inf.Tx.Tx, ucdn)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	i := sort.SearchStrings(xmlIDs, xmlID)
+	if i == len(xmlIDs) || xmlIDs[i] != xmlID || !isLogFileName(name) {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("no such log file"), nil)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(inf.Config.Cdni.LogDir, xmlID, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("no such log file"), nil)
+		return
+	} else if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("opening CDNi log file: %w", err))
+		return
+	}
+	defer log.Close(file, "closing CDNi log file")
+
+	info, err := file.Stat()
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("getting CDNi log file info: %w", err))
+		return
+	}
+
+	w.Header().Set(rfc.ContentType, LogFileContentType)
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// checkLogsRequest checks that serving CDNi logs is configured, and returns
+// the uCDN of the request's bearer token.
+func checkLogsRequest(r *http.Request, inf *api.APIInfo) (string, int, error, error) {
+	if inf.Config.Cdni == nil || inf.Config.Secrets[0] == "" || inf.Config.Cdni.DCdnId == "" {
+		return "", http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain CDNi information")
+	}
+	if inf.Config.Cdni.LogDir == "" {
+		return "", http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain a CDNi log_dir")
+	}
+
+	ucdn, err := checkBearerToken(getBearerToken(r), inf)
+	if err != nil {
+		return "", http.StatusBadRequest, err, nil
+	}
+	return ucdn, http.StatusOK, nil, nil
+}
+
+// getHostXMLIDs returns the sorted XMLIDs of the Delivery Services of the
+// uCDN's hosts.
+func getHostXMLIDs(tx *sql.Tx, ucdn string) ([]string, error) {
+	rows, err := tx.Query(hostXMLIDsQuery, ucdn)
+	if err != nil {
+		return nil, fmt.Errorf("querying delivery services of uCDN %s: %w", ucdn, err)
+	}
+	defer log.Close(rows, "closing uCDN delivery services query")
+
+	xmlIDs := []string{}
+	for rows.Next() {
+		xmlID := ""
+		if err := rows.Scan(&xmlID); err != nil {
+			return nil, fmt.Errorf("scanning delivery services of uCDN %s: %w", ucdn, err)
+		}
+		xmlIDs = append(xmlIDs, xmlID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over delivery services of uCDN %s: %w", ucdn, err)
+	}
+	return xmlIDs, nil
+}
+
+// listLogFiles returns the CDNi logging files in the directories of the
+// Delivery Services in the log directory. Delivery Services without a
+// directory have no logging files.
+func listLogFiles(logDir string, xmlIDs []string) ([]LogFile, error) {
+	files := []LogFile{}
+	for _, xmlID := range xmlIDs {
+		entries, err := os.ReadDir(filepath.Join(logDir, xmlID))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("reading CDNi log directory of delivery service %s: %w", xmlID, err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !isLogFileName(entry.Name()) {
+				continue
+			}
+			info, err := entry.Info()
+			if errors.Is(err, fs.ErrNotExist) {
+				continue // removed since the directory was read
+			} else if err != nil {
+				return nil, fmt.Errorf("getting info of CDNi log file %s of delivery service %s: %w", entry.Name(), xmlID, err)
+			}
+			files = append(files, LogFile{
+				DeliveryService: xmlID,
+				Name:            entry.Name(),
+				Size:            info.Size(),
+				LastModified:    info.ModTime(),
+			})
+		}
+	}
+	return files, nil
+}
+
+// isLogFileName returns whether name is the name of a CDNi logging file, and
+// not a path or a hidden file, such as one still being written.
+func isLogFileName(name string) bool {
+	return name != "" &&
+		!strings.HasPrefix(name, ".") &&
+		!strings.ContainsAny(name, `/\`) &&
+		strings.HasSuffix(name, LogFileExtension)
+}
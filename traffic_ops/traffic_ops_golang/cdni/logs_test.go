@@ -0,0 +1,108 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestGetHostXMLIDs(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"xml_id"}).AddRow("cdni-ucdn1-a").AddRow("cdni-ucdn1-b")
+	mock.ExpectQuery("SELECT DISTINCT ds.xml_id").WithArgs("ucdn1").WillReturnRows(rows)
+	mock.ExpectCommit()
+
+	tx, err := mockDB.Begin()
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+	xmlIDs, err := getHostXMLIDs(tx, "ucdn1")
+	if err != nil {
+		t.Fatalf("getHostXMLIDs: %v", err)
+	}
+	if len(xmlIDs) != 2 || xmlIDs[0] != "cdni-ucdn1-a" || xmlIDs[1] != "cdni-ucdn1-b" {
+		t.Errorf("expected XMLIDs [cdni-ucdn1-a cdni-ucdn1-b], actual: %v", xmlIDs)
+	}
+	tx.Commit()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, contents string) {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("ds1/20220520T100000Z_edge1.log", "#version:\tcdni/1.0\r\n")
+	write("ds1/.20220520T100500Z_edge1.log", "")
+	write("ds1/notes.txt", "")
+	write("ds2/20220520T100000Z_edge1.log", "")
+	write("other/20220520T100000Z_edge1.log", "")
+	if err := os.Mkdir(filepath.Join(dir, "ds1", "sub.log"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := listLogFiles(dir, []string{"ds1", "ds2", "ds3"})
+	if err != nil {
+		t.Fatalf("listLogFiles: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 log files, actual: %+v", files)
+	}
+	if files[0].DeliveryService != "ds1" || files[0].Name != "20220520T100000Z_edge1.log" || files[0].Size != 20 {
+		t.Errorf("expected log file 20220520T100000Z_edge1.log of ds1 of 20 bytes, actual: %+v", files[0])
+	}
+	if files[1].DeliveryService != "ds2" || files[1].Name != "20220520T100000Z_edge1.log" || files[1].Size != 0 {
+		t.Errorf("expected log file 20220520T100000Z_edge1.log of ds2 of 0 bytes, actual: %+v", files[1])
+	}
+}
+
+func TestIsLogFileName(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"20220520T100000Z_edge1.log": true,
+		"":                           false,
+		".log":                       false,
+		".20220520T100000Z.log":      false,
+		"20220520T100000Z.txt":       false,
+		"../ds2/a.log":               false,
+		`..\ds2\a.log`:               false,
+	} {
+		if actual := isLogFileName(name); actual != expected {
+			t.Errorf("isLogFileName(%q): expected %t, actual: %t", name, expected, actual)
+		}
+	}
+}
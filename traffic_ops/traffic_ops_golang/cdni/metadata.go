@@ -0,0 +1,565 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+)
+
+const (
+	// maxXMLIDLength is the longest XMLID a Delivery Service may have.
+	maxXMLIDLength = 48
+
+	// headerRewriteLineSeparator separates the lines of a header rewrite
+	// Delivery Service field.
+	headerRewriteLineSeparator = "__RETURN__"
+
+	hostRegexQuery = `
+SELECT count(*) FROM deliveryservice_regex AS dsr
+JOIN regex AS re ON re.id = dsr.regex
+JOIN type AS t ON t.id = re.type
+WHERE dsr.deliveryservice = $1 AND t.name = $2 AND re.pattern = $3`
+	insertHostRegexQuery = `INSERT INTO regex (type, pattern) VALUES ((SELECT id FROM type WHERE name = $1), $2) RETURNING id`
+	insertDSRegexQuery   = `
+INSERT INTO deliveryservice_regex (deliveryservice, regex, set_number)
+VALUES ($1, $2, (SELECT COALESCE(MAX(set_number), -1) + 1 FROM deliveryservice_regex WHERE deliveryservice = $1))`
+
+	deleteSecondaryOriginsQuery = `DELETE FROM origin WHERE deliveryservice = $1 AND NOT is_primary`
+	insertSecondaryOriginQuery  = `INSERT INTO origin (name, fqdn, protocol, is_primary, port, deliveryservice, tenant) VALUES ($1, $2, $3, FALSE, $4, $5, $6)`
+
+	upsertHostQuery = `
+INSERT INTO cdni_host (ucdn, host, deliveryservice) VALUES ($1, $2, $3)
+ON CONFLICT (ucdn, host) DO UPDATE SET deliveryservice = EXCLUDED.deliveryservice, last_updated = now()`
+)
+
+var nonLabelChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// hostDeliveryService contains the Delivery Service properties translated
+// from the generic metadata of a uCDN host.
+type hostDeliveryService struct {
+	// Origins are the endpoints of the host's sources, of which the first is
+	// the Delivery Service's primary Origin.
+	Origins           []originEndpoint
+	Protocol          int
+	GeoLimit          int
+	GeoLimitCountries []string
+	// HeaderRewrite holds the header_rewrite rules of the host's time window
+	// ACL and cache policy, or is empty if it has neither.
+	HeaderRewrite string
+}
+
+// originEndpoint is an endpoint from which content is acquired.
+type originEndpoint struct {
+	Protocol string
+	FQDN     string
+	Port     *int
+}
+
+// String returns the endpoint in the form of a Delivery Service's
+// orgServerFqdn.
+func (o originEndpoint) String() string {
+	s := o.Protocol + "://" + o.FQDN
+	if o.Port != nil {
+		s += ":" + strconv.Itoa(*o.Port)
+	}
+	return s
+}
+
+// translateHostMetadata translates the generic metadata of a uCDN host into
+// Delivery Service properties. Its errors are suitable for the user, naming
+// the metadata which cannot be expressed by a Delivery Service.
+func translateHostMetadata(metadata []GenericMetadata, httpsSupported bool) (hostDeliveryService, error) {
+	hostDS := hostDeliveryService{}
+	var sources *SourceMetadata
+	var protocolACL *ProtocolACL
+	var headerRewriteLines []string
+	var cachePolicyLines []string
+
+	for _, m := range metadata {
+		var err error
+		switch m.Type {
+		case MiSourceMetadata:
+			sources = &SourceMetadata{}
+			err = json.Unmarshal(m.Value, sources)
+		case MiProtocolACL:
+			protocolACL = &ProtocolACL{}
+			err = json.Unmarshal(m.Value, protocolACL)
+		case MiLocationACL:
+			var acl LocationACL
+			if err = json.Unmarshal(m.Value, &acl); err == nil {
+				hostDS.GeoLimit, hostDS.GeoLimitCountries, err = translateLocationACL(acl)
+			}
+		case MiTimeWindowACL:
+			var acl TimeWindowACL
+			if err = json.Unmarshal(m.Value, &acl); err == nil {
+				headerRewriteLines, err = translateTimeWindowACL(acl)
+			}
+		case MiCachePolicy:
+			var policy CachePolicy
+			if err = json.Unmarshal(m.Value, &policy); err == nil {
+				cachePolicyLines = translateCachePolicy(policy)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return hostDeliveryService{}, fmt.Errorf("%s: %w", m.Type, err)
+		}
+	}
+
+	if sources == nil {
+		return hostDeliveryService{}, fmt.Errorf("%s is required to create a Delivery Service", MiSourceMetadata)
+	}
+	origins, err := translateSourceMetadata(*sources)
+	if err != nil {
+		return hostDeliveryService{}, fmt.Errorf("%s: %w", MiSourceMetadata, err)
+	}
+	hostDS.Origins = origins
+
+	hostDS.Protocol, err = translateProtocolACL(protocolACL, httpsSupported)
+	if err != nil {
+		return hostDeliveryService{}, fmt.Errorf("%s: %w", MiProtocolACL, err)
+	}
+
+	headerRewriteLines = append(headerRewriteLines, cachePolicyLines...)
+	hostDS.HeaderRewrite = strings.Join(headerRewriteLines, headerRewriteLineSeparator)
+	return hostDS, nil
+}
+
+// translateSourceMetadata returns the endpoints of all sources, in order.
+func translateSourceMetadata(sources SourceMetadata) ([]originEndpoint, error) {
+	origins := []originEndpoint{}
+	for _, source := range sources.Sources {
+		if len(source.AcquisitionAuth) > 0 && string(source.AcquisitionAuth) != "null" {
+			return nil, errors.New("acquisition-auth is not supported")
+		}
+		var scheme string
+		switch source.Protocol {
+		case HTTP11, "":
+			scheme = "http"
+		case HTTPS11:
+			scheme = "https"
+		default:
+			return nil, fmt.Errorf("unsupported acquisition protocol '%s'", source.Protocol)
+		}
+		for _, endpoint := range source.Endpoints {
+			origin, err := parseEndpoint(scheme, endpoint)
+			if err != nil {
+				return nil, err
+			}
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return nil, errors.New("at least one source endpoint is required")
+	}
+	return origins, nil
+}
+
+// parseEndpoint parses an endpoint of the form host[:port].
+func parseEndpoint(scheme string, endpoint string) (originEndpoint, error) {
+	origin := originEndpoint{Protocol: scheme, FQDN: endpoint}
+	if host, port, err := net.SplitHostPort(endpoint); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return originEndpoint{}, fmt.Errorf("invalid port in endpoint '%s'", endpoint)
+		}
+		origin.FQDN = host
+		origin.Port = util.IntPtr(p)
+	}
+	if origin.FQDN == "" {
+		return originEndpoint{}, fmt.Errorf("invalid endpoint '%s'", endpoint)
+	}
+	return origin, nil
+}
+
+// translateProtocolACL returns the Delivery Service protocol allowing the
+// delivery protocols the ACL allows. The first rule matching a protocol
+// applies, and protocols no rule matches are allowed. HTTPS is only allowed
+// where it is supported.
+func translateProtocolACL(acl *ProtocolACL, httpsSupported bool) (int, error) {
+	if acl == nil {
+		if httpsSupported {
+			return tc.DSProtocolHTTPAndHTTPS, nil
+		}
+		return tc.DSProtocolHTTP, nil
+	}
+
+	allowed := func(protocol Protocol) (bool, error) {
+		for _, rule := range acl.ProtocolACL {
+			if !rule.Action.isValid() {
+				return false, fmt.Errorf("invalid action '%s'", rule.Action)
+			}
+			for _, p := range rule.Protocols {
+				if p == protocol {
+					return rule.Action == ACLAllow, nil
+				}
+			}
+		}
+		return true, nil
+	}
+
+	httpAllowed, err := allowed(HTTP11)
+	if err != nil {
+		return 0, err
+	}
+	httpsAllowed, err := allowed(HTTPS11)
+	if err != nil {
+		return 0, err
+	}
+	if httpsAllowed && !httpsSupported {
+		if !httpAllowed {
+			return 0, errors.New("https/1.1 delivery is not supported")
+		}
+		httpsAllowed = false
+	}
+
+	switch {
+	case httpAllowed && httpsAllowed:
+		return tc.DSProtocolHTTPAndHTTPS, nil
+	case httpsAllowed:
+		return tc.DSProtocolHTTPS, nil
+	case httpAllowed:
+		return tc.DSProtocolHTTP, nil
+	}
+	return 0, errors.New("no supported delivery protocol is allowed")
+}
+
+// translateLocationACL returns the Geo Limit and countries allowing the
+// country code footprints of the ACL's allow rules. Deny rules, and other
+// footprint types, cannot be expressed by a Delivery Service.
+func translateLocationACL(acl LocationACL) (int, []string, error) {
+	countries := []string{}
+	for _, rule := range acl.Locations {
+		if rule.Action != ACLAllow {
+			return 0, nil, fmt.Errorf("unsupported action '%s': only allow rules are supported", rule.Action)
+		}
+		for _, footprint := range rule.Footprints {
+			if footprint.FootprintType != CountryCode {
+				return 0, nil, fmt.Errorf("unsupported footprint type '%s': only %s footprints are supported", footprint.FootprintType, CountryCode)
+			}
+			for _, country := range footprint.FootprintValue {
+				countries = append(countries, strings.ToUpper(country))
+			}
+		}
+	}
+	if len(countries) == 0 {
+		return 0, nil, nil
+	}
+	return 2, countries, nil
+}
+
+// translateTimeWindowACL returns header_rewrite rules refusing requests with
+// a 403 outside the ACL's allowed time window, and inside its denied ones.
+func translateTimeWindowACL(acl TimeWindowACL) ([]string, error) {
+	lines := []string{}
+	var allowed *TimeWindow
+	for _, rule := range acl.Times {
+		if !rule.Action.isValid() {
+			return nil, fmt.Errorf("invalid action '%s'", rule.Action)
+		}
+		for i, window := range rule.Windows {
+			if window.End <= window.Start {
+				return nil, fmt.Errorf("window ending at %d does not start before it ends", window.End)
+			}
+			if rule.Action == ACLDeny {
+				lines = append(lines,
+					"cond %{NOW} >"+strconv.FormatInt(window.Start-1, 10)+" [AND]",
+					"cond %{NOW} <"+strconv.FormatInt(window.End, 10),
+					"set-status 403",
+				)
+				continue
+			}
+			if allowed != nil {
+				return nil, errors.New("only one allowed window is supported")
+			}
+			allowed = &rule.Windows[i]
+		}
+	}
+	if allowed != nil {
+		lines = append(lines,
+			"cond %{NOW} <"+strconv.FormatInt(allowed.Start, 10)+" [OR]",
+			"cond %{NOW} >"+strconv.FormatInt(allowed.End-1, 10),
+			"set-status 403",
+		)
+	}
+	return lines, nil
+}
+
+// translateCachePolicy returns header_rewrite rules setting the
+// Cache-Control of responses from the Origin to the policy's internal TTL,
+// and of responses to clients to its external TTL. Unless the policy is
+// forced, they only apply to responses without a Cache-Control. Negative TTLs
+// leave the Origin's own policy in place.
+func translateCachePolicy(policy CachePolicy) []string {
+	lines := []string{}
+	rule := func(hook string, ttl *int64) {
+		if ttl == nil || *ttl < 0 {
+			return
+		}
+		cacheControl := "no-store"
+		if *ttl > 0 {
+			cacheControl = "max-age=" + strconv.FormatInt(*ttl, 10)
+		}
+		if policy.Force {
+			lines = append(lines, "cond %{"+hook+"}")
+		} else {
+			lines = append(lines, "cond %{"+hook+"} [AND]", `cond %{HEADER:Cache-Control} =""`)
+		}
+		lines = append(lines, `set-header Cache-Control "`+cacheControl+`"`)
+	}
+	rule("READ_RESPONSE_HDR_HOOK", policy.Internal)
+	rule("SEND_RESPONSE_HDR_HOOK", policy.External)
+	return lines
+}
+
+// hostXMLID returns the XMLID of the Delivery Service of the uCDN's host.
+// Names too long for an XMLID are shortened, keeping them unique with a hash
+// of the whole name.
+func hostXMLID(ucdn string, host string) string {
+	label := func(s string) string {
+		return strings.Trim(nonLabelChars.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	}
+	xmlID := "cdni-" + label(ucdn) + "-" + label(host)
+	if len(xmlID) <= maxXMLIDLength {
+		return xmlID
+	}
+	h := fnv.New32a()
+	h.Write([]byte(ucdn + "/" + host))
+	suffix := fmt.Sprintf("-%08x", h.Sum32())
+	return strings.TrimRight(xmlID[:maxXMLIDLength-len(suffix)], "-") + suffix
+}
+
+// putHostDeliveryService creates the Delivery Service of the uCDN's host in
+// the configured CDN, or updates it if it already exists, and gives it the
+// Origins and host regular expression of the host.
+func putHostDeliveryService(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, ucdn string, host string, hostDS hostDeliveryService) (int, error, error) {
+	if inf.Config.Cdni.CDNName == "" {
+		return http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain a CDNi cdn_name")
+	}
+	tx := inf.Tx.Tx
+	xmlID := hostXMLID(ucdn, host)
+
+	query := deliveryservice.SelectDeliveryServicesQuery + " WHERE ds.xml_id = :xmlid"
+	existing, userErr, sysErr, errCode := deliveryservice.GetDeliveryServices(query, map[string]interface{}{"xmlid": xmlID}, inf.Tx)
+	if userErr != nil || sysErr != nil {
+		return errCode, userErr, sysErr
+	}
+
+	var ds tc.DeliveryServiceV4
+	if len(existing) > 0 {
+		ds = existing[0]
+	} else {
+		cdnID, ok, err := dbhelpers.GetCDNIDFromName(tx, tc.CDNName(inf.Config.Cdni.CDNName))
+		if err != nil {
+			return http.StatusInternalServerError, nil, fmt.Errorf("getting CDNi CDN ID: %w", err)
+		} else if !ok {
+			return http.StatusInternalServerError, nil, fmt.Errorf("CDNi CDN '%s' does not exist", inf.Config.Cdni.CDNName)
+		}
+		typeID, ok, err := dbhelpers.GetTypeIDByName(tc.DSTypeHTTP.String(), tx)
+		if err != nil {
+			return http.StatusInternalServerError, nil, fmt.Errorf("getting %s type ID: %w", tc.DSTypeHTTP, err)
+		} else if !ok {
+			return http.StatusInternalServerError, nil, fmt.Errorf("type '%s' does not exist", tc.DSTypeHTTP)
+		}
+		displayName := host
+		if len(displayName) > maxXMLIDLength {
+			displayName = displayName[:maxXMLIDLength]
+		}
+		ds.Active = util.BoolPtr(true)
+		ds.CDNID = &cdnID
+		ds.DisplayName = &displayName
+		ds.DSCP = util.IntPtr(0)
+		ds.GeoProvider = util.IntPtr(0)
+		ds.InitialDispersion = util.IntPtr(1)
+		ds.IPV6RoutingEnabled = util.BoolPtr(true)
+		ds.LogsEnabled = util.BoolPtr(true)
+		ds.LongDesc = util.StrPtr("CDNi host " + host + " of uCDN " + ucdn)
+		ds.MissLat = util.FloatPtr(0)
+		ds.MissLong = util.FloatPtr(0)
+		ds.MultiSiteOrigin = util.BoolPtr(false)
+		ds.QStringIgnore = util.IntPtr(0)
+		ds.RangeRequestHandling = util.IntPtr(0)
+		ds.RegionalGeoBlocking = util.BoolPtr(false)
+		ds.TenantID = util.IntPtr(inf.User.TenantID)
+		ds.TypeID = &typeID
+		ds.XMLID = &xmlID
+	}
+
+	ds.OrgServerFQDN = util.StrPtr(hostDS.Origins[0].String())
+	ds.Protocol = util.IntPtr(hostDS.Protocol)
+	ds.GeoLimit = util.IntPtr(hostDS.GeoLimit)
+	ds.GeoLimitCountries = hostDS.GeoLimitCountries
+	var headerRewrite *string
+	if hostDS.HeaderRewrite != "" {
+		headerRewrite = util.StrPtr(hostDS.HeaderRewrite)
+	}
+	if ds.Topology != nil {
+		ds.FirstHeaderRewrite = headerRewrite
+	} else {
+		ds.EdgeHeaderRewrite = headerRewrite
+	}
+
+	if userErr, sysErr, errCode := dbhelpers.CheckIfCurrentUserCanModifyCDNWithID(tx, int64(*ds.CDNID), inf.User.UserName); userErr != nil || sysErr != nil {
+		return errCode, userErr, sysErr
+	}
+
+	var res *tc.DeliveryServiceV4
+	if ds.ID == nil {
+		res, errCode, userErr, sysErr = deliveryservice.CreateV4(w, r, inf, ds)
+	} else {
+		res, errCode, userErr, sysErr = deliveryservice.UpdateV4(w, r, inf, ds)
+	}
+	if userErr != nil || sysErr != nil {
+		return errCode, userErr, sysErr
+	}
+
+	if err := ensureHostRegex(tx, *res.ID, host); err != nil {
+		return http.StatusInternalServerError, nil, err
+	}
+	if _, err := tx.Exec(upsertHostQuery, ucdn, host, *res.ID); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("recording uCDN %s host %s of delivery service %s: %w", ucdn, host, xmlID, err)
+	}
+
+	if _, err := tx.Exec(deleteSecondaryOriginsQuery, *res.ID); err != nil {
+		return http.StatusInternalServerError, nil, fmt.Errorf("deleting secondary origins of delivery service %s: %w", xmlID, err)
+	}
+	for i, origin := range hostDS.Origins[1:] {
+		name := xmlID + "-" + strconv.Itoa(i+1)
+		if _, err := tx.Exec(insertSecondaryOriginQuery, name, origin.FQDN, origin.Protocol, origin.Port, *res.ID, *res.TenantID); err != nil {
+			usrErr, sysErr, code := api.ParseDBError(err)
+			return code, usrErr, sysErr
+		}
+		api.CreateChangeLogRawTx(api.ApiChange, "ORIGIN: "+name+", ACTION: Created CDNi secondary origin for delivery service "+xmlID, inf.User, tx)
+	}
+	return http.StatusOK, nil, nil
+}
+
+// ensureHostRegex adds a host regular expression matching exactly the host to
+// the Delivery Service, if it does not have one already.
+func ensureHostRegex(tx *sql.Tx, dsID int, host string) error {
+	pattern := regexp.QuoteMeta(host)
+	count := 0
+	if err := tx.QueryRow(hostRegexQuery, dsID, tc.DSMatchTypeHostRegex.String(), pattern).Scan(&count); err != nil {
+		return fmt.Errorf("querying host regex of delivery service %d: %w", dsID, err)
+	}
+	if count > 0 {
+		return nil
+	}
+	regexID := 0
+	if err := tx.QueryRow(insertHostRegexQuery, tc.DSMatchTypeHostRegex.String(), pattern).Scan(&regexID); err != nil {
+		return fmt.Errorf("inserting host regex: %w", err)
+	}
+	if _, err := tx.Exec(insertDSRegexQuery, dsID, regexID); err != nil {
+		return fmt.Errorf("inserting host regex of delivery service %d: %w", dsID, err)
+	}
+	return nil
+}
+
+// ACLAction is a string of the action of a CDNi ACL rule.
+type ACLAction string
+
+const (
+	ACLAllow ACLAction = "allow"
+	ACLDeny  ACLAction = "deny"
+)
+
+func (a ACLAction) isValid() bool {
+	return a == ACLAllow || a == ACLDeny
+}
+
+// SourceMetadata contains the sources from which the dCDN acquires content.
+type SourceMetadata struct {
+	Sources []Source `json:"sources"`
+}
+
+// Source contains the endpoints of a source and the protocol with which
+// content is acquired from them.
+type Source struct {
+	AcquisitionAuth json.RawMessage `json:"acquisition-auth,omitempty"`
+	Endpoints       []string        `json:"endpoints"`
+	Protocol        Protocol        `json:"protocol"`
+}
+
+// LocationACL contains rules allowing or denying delivery to footprints.
+type LocationACL struct {
+	Locations []LocationRule `json:"locations"`
+}
+
+// LocationRule contains the action of a location ACL rule and the footprints
+// to which it applies.
+type LocationRule struct {
+	Action     ACLAction   `json:"action"`
+	Footprints []Footprint `json:"footprints"`
+}
+
+// TimeWindowACL contains rules allowing or denying delivery in time windows.
+type TimeWindowACL struct {
+	Times []TimeWindowRule `json:"times"`
+}
+
+// TimeWindowRule contains the action of a time window ACL rule and the
+// windows to which it applies.
+type TimeWindowRule struct {
+	Action  ACLAction    `json:"action"`
+	Windows []TimeWindow `json:"windows"`
+}
+
+// TimeWindow contains the start and end of a time window as Unix timestamps
+// (in seconds).
+type TimeWindow struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// ProtocolACL contains rules allowing or denying delivery protocols.
+type ProtocolACL struct {
+	ProtocolACL []ProtocolRule `json:"protocol-acl"`
+}
+
+// ProtocolRule contains the action of a protocol ACL rule and the protocols
+// to which it applies.
+type ProtocolRule struct {
+	Action    ACLAction  `json:"action"`
+	Protocols []Protocol `json:"protocols"`
+}
+
+// CachePolicy contains the TTLs (in seconds) for which content is cached by
+// the dCDN and by clients.
+type CachePolicy struct {
+	Internal *int64 `json:"internal,omitempty"`
+	External *int64 `json:"external,omitempty"`
+	Force    bool   `json:"force"`
+}
@@ -0,0 +1,199 @@
+package cdni
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func metadata(t *testing.T, typ SupportedGenericMetadataType, value string) GenericMetadata {
+	if !json.Valid([]byte(value)) {
+		t.Fatalf("invalid test metadata value for %s: %s", typ, value)
+	}
+	return GenericMetadata{Type: typ, Value: json.RawMessage(value)}
+}
+
+func TestTranslateHostMetadata(t *testing.T) {
+	hostDS, err := translateHostMetadata([]GenericMetadata{
+		metadata(t, MiSourceMetadata, `{"sources": [{"endpoints": ["origin.example.com", "backup.example.com:8443"], "protocol": "https/1.1"}]}`),
+		metadata(t, MiLocationACL, `{"locations": [{"action": "allow", "footprints": [{"footprint-type": "countrycode", "footprint-value": ["us", "ca"]}]}]}`),
+		metadata(t, MiProtocolACL, `{"protocol-acl": [{"action": "deny", "protocols": ["https/1.1"]}]}`),
+		metadata(t, MiTimeWindowACL, `{"times": [{"action": "allow", "windows": [{"start": 1000, "end": 2000}]}]}`),
+		metadata(t, MiCachePolicy, `{"internal": 3600, "external": 60, "force": true}`),
+	}, true)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	if len(hostDS.Origins) != 2 {
+		t.Fatalf("expected 2 origins, got: %+v", hostDS.Origins)
+	}
+	if s := hostDS.Origins[0].String(); s != "https://origin.example.com" {
+		t.Errorf("expected primary origin 'https://origin.example.com', got: '%s'", s)
+	}
+	if s := hostDS.Origins[1].String(); s != "https://backup.example.com:8443" {
+		t.Errorf("expected secondary origin 'https://backup.example.com:8443', got: '%s'", s)
+	}
+	if hostDS.Protocol != tc.DSProtocolHTTP {
+		t.Errorf("expected protocol %d, got: %d", tc.DSProtocolHTTP, hostDS.Protocol)
+	}
+	if hostDS.GeoLimit != 2 || !reflect.DeepEqual(hostDS.GeoLimitCountries, []string{"US", "CA"}) {
+		t.Errorf("expected geo limit 2 to US and CA, got: %d to %v", hostDS.GeoLimit, hostDS.GeoLimitCountries)
+	}
+
+	expected := strings.Join([]string{
+		"cond %{NOW} <1000 [OR]",
+		"cond %{NOW} >1999",
+		"set-status 403",
+		"cond %{READ_RESPONSE_HDR_HOOK}",
+		`set-header Cache-Control "max-age=3600"`,
+		"cond %{SEND_RESPONSE_HDR_HOOK}",
+		`set-header Cache-Control "max-age=60"`,
+	}, headerRewriteLineSeparator)
+	if hostDS.HeaderRewrite != expected {
+		t.Errorf("expected header rewrite '%s', got: '%s'", expected, hostDS.HeaderRewrite)
+	}
+}
+
+func TestTranslateHostMetadataErrors(t *testing.T) {
+	source := metadata(t, MiSourceMetadata, `{"sources": [{"endpoints": ["origin.example.com"], "protocol": "http/1.1"}]}`)
+	tests := map[string][]GenericMetadata{
+		"missing source": {
+			metadata(t, MiCachePolicy, `{"internal": 60}`),
+		},
+		"source without endpoints": {
+			metadata(t, MiSourceMetadata, `{"sources": [{"endpoints": [], "protocol": "http/1.1"}]}`),
+		},
+		"acquisition auth": {
+			metadata(t, MiSourceMetadata, `{"sources": [{"acquisition-auth": {"auth-type": "MI.HeaderAuth"}, "endpoints": ["origin.example.com"]}]}`),
+		},
+		"invalid port": {
+			metadata(t, MiSourceMetadata, `{"sources": [{"endpoints": ["origin.example.com:99999"]}]}`),
+		},
+		"location deny rule": {
+			source,
+			metadata(t, MiLocationACL, `{"locations": [{"action": "deny", "footprints": [{"footprint-type": "countrycode", "footprint-value": ["us"]}]}]}`),
+		},
+		"location asn footprint": {
+			source,
+			metadata(t, MiLocationACL, `{"locations": [{"action": "allow", "footprints": [{"footprint-type": "asn", "footprint-value": ["as64496"]}]}]}`),
+		},
+		"two allowed windows": {
+			source,
+			metadata(t, MiTimeWindowACL, `{"times": [{"action": "allow", "windows": [{"start": 1, "end": 2}, {"start": 3, "end": 4}]}]}`),
+		},
+		"window ending before it starts": {
+			source,
+			metadata(t, MiTimeWindowACL, `{"times": [{"action": "deny", "windows": [{"start": 2, "end": 1}]}]}`),
+		},
+		"only unsupported https allowed": {
+			source,
+			metadata(t, MiProtocolACL, `{"protocol-acl": [{"action": "deny", "protocols": ["http/1.1"]}]}`),
+		},
+	}
+	for name, md := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := translateHostMetadata(md, false); err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestTranslateProtocolACL(t *testing.T) {
+	tests := []struct {
+		name           string
+		acl            *ProtocolACL
+		httpsSupported bool
+		expected       int
+	}{
+		{"no ACL without HTTPS", nil, false, tc.DSProtocolHTTP},
+		{"no ACL with HTTPS", nil, true, tc.DSProtocolHTTPAndHTTPS},
+		{"HTTPS only", &ProtocolACL{ProtocolACL: []ProtocolRule{{Action: ACLDeny, Protocols: []Protocol{HTTP11}}}}, true, tc.DSProtocolHTTPS},
+		{"first rule applies", &ProtocolACL{ProtocolACL: []ProtocolRule{
+			{Action: ACLAllow, Protocols: []Protocol{HTTPS11}},
+			{Action: ACLDeny, Protocols: []Protocol{HTTP11, HTTPS11}},
+		}}, true, tc.DSProtocolHTTPS},
+		{"HTTPS allowed but unsupported", &ProtocolACL{ProtocolACL: []ProtocolRule{{Action: ACLAllow, Protocols: []Protocol{HTTP11, HTTPS11}}}}, false, tc.DSProtocolHTTP},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			protocol, err := translateProtocolACL(test.acl, test.httpsSupported)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if protocol != test.expected {
+				t.Errorf("expected protocol %d, got: %d", test.expected, protocol)
+			}
+		})
+	}
+}
+
+func TestTranslateTimeWindowACLDeny(t *testing.T) {
+	lines, err := translateTimeWindowACL(TimeWindowACL{Times: []TimeWindowRule{{Action: ACLDeny, Windows: []TimeWindow{{Start: 100, End: 200}}}}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expected := []string{"cond %{NOW} >99 [AND]", "cond %{NOW} <200", "set-status 403"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got: %v", expected, lines)
+	}
+}
+
+func TestTranslateCachePolicy(t *testing.T) {
+	internal := int64(0)
+	external := int64(-1)
+	lines := translateCachePolicy(CachePolicy{Internal: &internal, External: &external})
+	expected := []string{
+		"cond %{READ_RESPONSE_HDR_HOOK} [AND]",
+		`cond %{HEADER:Cache-Control} =""`,
+		`set-header Cache-Control "no-store"`,
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %v, got: %v", expected, lines)
+	}
+}
+
+func TestHostXMLID(t *testing.T) {
+	if xmlID := hostXMLID("UCDN1", "video.example.com"); xmlID != "cdni-ucdn1-video-example-com" {
+		t.Errorf("expected 'cdni-ucdn1-video-example-com', got: '%s'", xmlID)
+	}
+
+	long := hostXMLID("ucdn1", "a-very-long-host-name.for-some-video-content.example.com")
+	if len(long) > maxXMLIDLength {
+		t.Errorf("expected an XMLID of at most %d characters, got: '%s'", maxXMLIDLength, long)
+	}
+	other := hostXMLID("ucdn1", "a-very-long-host-name.for-some-video-content.example.net")
+	if long == other {
+		t.Errorf("expected shortened XMLIDs of different hosts to differ, both were: '%s'", long)
+	}
+}
+
+func TestASNFootprint(t *testing.T) {
+	footprint := asnFootprint([]string{"64496", "64497"})
+	if footprint.FootprintType != Asn || !reflect.DeepEqual(footprint.FootprintValue, []string{"as64496", "as64497"}) {
+		t.Errorf("expected asn footprint of as64496 and as64497, got: %+v", footprint)
+	}
+}
@@ -94,10 +94,17 @@ func GetCapabilities(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	serviceCaps, err := getServiceCapabilities(inf)
+	if err != nil {
+		api.HandleErr(w, r, nil, http.StatusInternalServerError, nil, err)
+		return
+	}
+
 	fciCaps := Capabilities{}
-	capsList := make([]Capability, 0, len(capacities.Capabilities)+len(telemetries.Capabilities))
+	capsList := make([]Capability, 0, len(capacities.Capabilities)+len(telemetries.Capabilities)+len(serviceCaps.Capabilities))
 	capsList = append(capsList, capacities.Capabilities...)
 	capsList = append(capsList, telemetries.Capabilities...)
+	capsList = append(capsList, serviceCaps.Capabilities...)
 
 	fciCaps.Capabilities = capsList
 
@@ -132,10 +139,6 @@ func PutHostConfiguration(w http.ResponseWriter, r *http.Request) {
 	defer inf.Close()
 
 	host := inf.Params["host"]
-	if errCode, userErr, sysErr := validateHostExists(host, inf.Tx.Tx); userErr != nil || sysErr != nil {
-		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
-		return
-	}
 
 	if inf.Config.Cdni == nil || inf.Config.Secrets[0] == "" || inf.Config.Cdni.DCdnId == "" {
 		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("cdn.conf does not contain CDNi information"))
@@ -156,6 +159,23 @@ func PutHostConfiguration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Hosts being onboarded have no capacity limits yet, so only capacity
+	// limit updates need an existing host.
+	var hostMetadata []GenericMetadata
+	if err := json.Unmarshal(genericHostRequest.HostMetadata.Metadata, &hostMetadata); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, fmt.Errorf("host-metadata must contain a list of generic metadata: %w", err), nil)
+		return
+	}
+	for _, m := range hostMetadata {
+		if m.Type == MiRequestedCapacityLimits {
+			if errCode, userErr, sysErr := validateHostExists(host, inf.Tx.Tx); userErr != nil || sysErr != nil {
+				api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+				return
+			}
+			break
+		}
+	}
+
 	db, err := api.GetDB(r.Context())
 	if err != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("getting async db: %w", err))
@@ -401,6 +421,26 @@ func PutConfigurationResponse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var dsMetadata []GenericMetadata
+	for _, updatedData := range updatedDataList {
+		if updatedData.Type.isDeliveryServiceMetadata() {
+			dsMetadata = append(dsMetadata, updatedData)
+		}
+	}
+
+	var hostDS hostDeliveryService
+	if len(dsMetadata) != 0 {
+		if host == "" {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("delivery service metadata requires a host"), nil)
+			return
+		}
+		hostDS, err = translateHostMetadata(dsMetadata, inf.Config.TrafficVaultEnabled)
+		if err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, fmt.Errorf("translating metadata for host %s: %w", host, err), nil)
+			return
+		}
+	}
+
 	for _, updatedData := range updatedDataList {
 		switch updatedData.Type {
 		case MiRequestedCapacityLimits:
@@ -443,7 +483,14 @@ func PutConfigurationResponse(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if asyncErr := api.UpdateAsyncStatus(db, api.AsyncSucceeded, "Capacity requested update has been completed.", asyncId, true); asyncErr != nil {
+	if len(dsMetadata) != 0 {
+		if errCode, userErr, sysErr := putHostDeliveryService(w, r, inf, ucdn, host, hostDS); userErr != nil || sysErr != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+			return
+		}
+	}
+
+	if asyncErr := api.UpdateAsyncStatus(db, api.AsyncSucceeded, "Requested configuration update has been completed.", asyncId, true); asyncErr != nil {
 		log.Errorf("updating async status for id %v: %v", asyncId, asyncErr)
 	}
 	status, err := deleteCapabilityRequest(reqId, inf.Tx.Tx)
@@ -742,8 +789,12 @@ const (
 type SupportedCapabilities string
 
 const (
-	FciTelemetry      SupportedCapabilities = "FCI.Telemetry"
-	FciCapacityLimits                       = "FCI.CapacityLimits"
+	FciTelemetry           SupportedCapabilities = "FCI.Telemetry"
+	FciCapacityLimits                            = "FCI.CapacityLimits"
+	FciDeliveryProtocol                          = "FCI.DeliveryProtocol"
+	FciAcquisitionProtocol                       = "FCI.AcquisitionProtocol"
+	FciRedirectionMode                           = "FCI.RedirectionMode"
+	FciMetadata                                  = "FCI.Metadata"
 )
 
 // SupportedGenericMetadataType is a string of the supported metadata type.
@@ -751,16 +802,58 @@ type SupportedGenericMetadataType string
 
 const (
 	MiRequestedCapacityLimits SupportedGenericMetadataType = "MI.RequestedCapacityLimits"
+	MiSourceMetadata          SupportedGenericMetadataType = "MI.SourceMetadata"
+	MiLocationACL             SupportedGenericMetadataType = "MI.LocationACL"
+	MiTimeWindowACL           SupportedGenericMetadataType = "MI.TimeWindowACL"
+	MiProtocolACL             SupportedGenericMetadataType = "MI.ProtocolACL"
+	MiCachePolicy             SupportedGenericMetadataType = "MI.CachePolicy"
 )
 
+// supportedGenericMetadataTypes are the generic metadata types advertised in
+// the FCI.Metadata capability.
+var supportedGenericMetadataTypes = []SupportedGenericMetadataType{
+	MiRequestedCapacityLimits,
+	MiSourceMetadata,
+	MiLocationACL,
+	MiTimeWindowACL,
+	MiProtocolACL,
+	MiCachePolicy,
+}
+
 func (s SupportedGenericMetadataType) isValid() bool {
-	switch s {
-	case MiRequestedCapacityLimits:
-		return true
+	for _, t := range supportedGenericMetadataTypes {
+		if s == t {
+			return true
+		}
 	}
 	return false
 }
 
+// isDeliveryServiceMetadata returns whether the metadata type is translated
+// into a Delivery Service, rather than applied to the CDNi capabilities.
+func (s SupportedGenericMetadataType) isDeliveryServiceMetadata() bool {
+	return s.isValid() && s != MiRequestedCapacityLimits
+}
+
+// Protocol is a string of a CDNi protocol, as used for delivery and
+// acquisition.
+type Protocol string
+
+const (
+	HTTP11  Protocol = "http/1.1"
+	HTTPS11 Protocol = "https/1.1"
+)
+
+// RedirectionMode is a string of a CDNi request redirection mode.
+type RedirectionMode string
+
+const (
+	DNSIterative  RedirectionMode = "DNS-I"
+	DNSRecursive  RedirectionMode = "DNS-R"
+	HTTPIterative RedirectionMode = "HTTP-I"
+	HTTPRecursive RedirectionMode = "HTTP-R"
+)
+
 // TelemetrySourceType is a string of the telemetry source type. Right now only "generic" is supported.
 type TelemetrySourceType string
 
@@ -95,6 +95,7 @@ type Config struct {
 	RoleBasedPermissions                      bool                    `json:"role_based_permissions"`
 	DefaultCertificateInfo                    *DefaultCertificateInfo `json:"default_certificate_info"`
 	Cdni                                      *CdniConf               `json:"cdni"`
+	DSPublications                            []ConfigDSPublication   `json:"dnssec_ds_publication"`
 }
 
 // ConfigHypnotoad carries http setting for hypnotoad (mojolicious) server
@@ -150,6 +151,15 @@ type ConfigTrafficOpsGolang struct {
 	// CRConfigEmulateOldPath is whether to emulate the legacy CRConfig request path when generating a new CRConfig. This primarily exists in the event a tool relies on the legacy path '/tools/write_crconfig'.
 	// Deprecated: will be removed in the next major version.
 	CRConfigEmulateOldPath bool `json:"crconfig_emulate_old_path"`
+	// SnapshotHistoryCount is the number of Snapshots retained for each CDN,
+	// including its current Snapshot, which may be rolled back to. If zero,
+	// SnapshotHistoryCountDefault is used. If negative, no history is kept.
+	SnapshotHistoryCount int `json:"snapshot_history_count"`
+	// RolloutCheckIntervalSec is the interval in seconds between checks of
+	// running Rollouts, which proceed to their next wave. If zero,
+	// RolloutCheckIntervalSecDefault is used. If negative, Rollouts are never
+	// checked, and so never proceed past their canary wave.
+	RolloutCheckIntervalSec int `json:"rollout_check_interval_sec"`
 }
 
 // RoutingBlacklist contains a list of route IDs that are disabled,
@@ -208,6 +218,97 @@ type ConfigAcmeAccount struct {
 	HmacEncoded  string `json:"hmac_encoded"`
 }
 
+// The methods by which Traffic Ops may publish a CDN's DS records in its parent zone.
+const (
+	// DSPublicationMethodRFC2136 publishes DS records with RFC 2136 dynamic updates, signed with TSIG.
+	DSPublicationMethodRFC2136 = "rfc2136"
+	// DSPublicationMethodWebhook publishes DS records by POSTing them to a webhook, such as a registrar's API.
+	DSPublicationMethodWebhook = "webhook"
+)
+
+// DSPublicationTimeoutSecDefault is the default timeout of DS record publication and parent zone queries.
+const DSPublicationTimeoutSecDefault = 10
+
+// DSPublicationDSTTLSecDefault is the default TTL of the DS records published in a parent zone.
+const DSPublicationDSTTLSecDefault = 86400
+
+// ConfigDSPublication contains the configuration for automatically publishing a CDN's DS records in its parent zone.
+type ConfigDSPublication struct {
+	CDN    string `json:"cdn"`
+	Method string `json:"method"`
+	// Zone is the parent zone, which RFC 2136 updates are made to.
+	Zone string `json:"zone"`
+	// Server is the host and port of the parent zone's primary name server, which RFC 2136 updates are sent to.
+	Server        string `json:"server"`
+	TSIGKeyName   string `json:"tsig_key_name"`
+	TSIGAlgorithm string `json:"tsig_algorithm"`
+	// TSIGSecret is the base64 encoded TSIG secret.
+	TSIGSecret     string            `json:"tsig_secret"`
+	WebhookURL     string            `json:"webhook_url"`
+	WebhookHeaders map[string]string `json:"webhook_headers"`
+	// CheckNameservers are the hosts and ports of the parent zone's name servers, queried to check that they serve the DS records. The default is Server.
+	CheckNameservers []string `json:"check_nameservers"`
+	DSTTLSec         int      `json:"ds_ttl_sec"`
+	TimeoutSec       int      `json:"timeout_sec"`
+}
+
+// GetDSPublication returns the DS record publication configuration of the given CDN, and whether it has one.
+func (c Config) GetDSPublication(cdnName string) (ConfigDSPublication, bool) {
+	for _, pub := range c.DSPublications {
+		if pub.CDN == cdnName {
+			return pub, true
+		}
+	}
+	return ConfigDSPublication{}, false
+}
+
+// validateDSPublications validates the DS record publication configuration, and sets its defaults.
+func validateDSPublications(pubs []ConfigDSPublication) error {
+	cdns := map[string]struct{}{}
+	for i, pub := range pubs {
+		if pub.CDN == "" {
+			return fmt.Errorf("dnssec_ds_publication[%d]: missing cdn", i)
+		}
+		if _, ok := cdns[pub.CDN]; ok {
+			return fmt.Errorf("dnssec_ds_publication: cdn '%s' is configured more than once", pub.CDN)
+		}
+		cdns[pub.CDN] = struct{}{}
+
+		switch pub.Method {
+		case DSPublicationMethodRFC2136:
+			if pub.Zone == "" || pub.Server == "" {
+				return fmt.Errorf("dnssec_ds_publication cdn '%s': method '%s' requires zone and server", pub.CDN, pub.Method)
+			}
+			if (pub.TSIGKeyName == "") != (pub.TSIGSecret == "") {
+				return fmt.Errorf("dnssec_ds_publication cdn '%s': tsig_key_name and tsig_secret must both be set or both be empty", pub.CDN)
+			}
+			if len(pub.CheckNameservers) == 0 {
+				pub.CheckNameservers = []string{pub.Server}
+			}
+		case DSPublicationMethodWebhook:
+			if pub.WebhookURL == "" {
+				return fmt.Errorf("dnssec_ds_publication cdn '%s': method '%s' requires webhook_url", pub.CDN, pub.Method)
+			}
+			if len(pub.CheckNameservers) == 0 {
+				return fmt.Errorf("dnssec_ds_publication cdn '%s': method '%s' requires check_nameservers", pub.CDN, pub.Method)
+			}
+		default:
+			return fmt.Errorf("dnssec_ds_publication cdn '%s': method must be '%s' or '%s'", pub.CDN, DSPublicationMethodRFC2136, DSPublicationMethodWebhook)
+		}
+		if pub.TSIGAlgorithm == "" {
+			pub.TSIGAlgorithm = "hmac-sha256."
+		}
+		if pub.DSTTLSec <= 0 {
+			pub.DSTTLSec = DSPublicationDSTTLSecDefault
+		}
+		if pub.TimeoutSec <= 0 {
+			pub.TimeoutSec = DSPublicationTimeoutSecDefault
+		}
+		pubs[i] = pub
+	}
+	return nil
+}
+
 type DefaultCertificateInfo struct {
 	BusinessUnit string `json:"business_unit"`
 	City         string `json:"city"`
@@ -272,6 +373,106 @@ type ConfigInflux struct {
 
 type CdniConf struct {
 	DCdnId string `json:"dcdn_id"`
+	// CDNName is the name of the CDN whose Cache Groups make up the
+	// advertised footprints, and in which Delivery Services are created from
+	// accepted CDNi host metadata.
+	CDNName string `json:"cdn_name"`
+	// UCdnId is the CDNi identifier of this CDN as an upstream CDN (uCDN),
+	// the issuer of the tokens it signs for downstream CDNs.
+	UCdnId string `json:"ucdn_id"`
+	// DCdns are the downstream CDNs to which Delivery Services may be
+	// delegated.
+	DCdns []CdniDCdnConf `json:"dcdns"`
+	// DelegationRefreshIntervalSec is the interval in seconds between
+	// refreshes of the footprints of delegated Delivery Services from their
+	// dCDNs' advertisements. If zero, CdniDelegationRefreshIntervalSecDefault
+	// is used. If negative, footprints are only fetched when a delegation is
+	// created or updated.
+	DelegationRefreshIntervalSec int `json:"delegation_refresh_interval_sec"`
+	// LogDir is the directory of the CDNi logging files (RFC 7937) served to
+	// uCDNs, which holds a directory per Delivery Service named by its XMLID.
+	LogDir string `json:"log_dir"`
+}
+
+// CdniDCdnTimeoutSecDefault is the default timeout of requests to a
+// downstream CDN's CDNi API.
+const CdniDCdnTimeoutSecDefault = 30
+
+// CdniDelegationRefreshIntervalSecDefault is the default interval between
+// refreshes of the footprints of delegated Delivery Services.
+const CdniDelegationRefreshIntervalSecDefault = 3600
+
+// CdniDCdnConf contains the configuration of a downstream CDN (dCDN), to which
+// Delivery Services may be delegated.
+type CdniDCdnConf struct {
+	// Name is the CDNi identifier of the dCDN, the audience of the tokens
+	// signed for it.
+	Name string `json:"name"`
+	// URL is the base URL of the dCDN's CDNi API, to which e.g.
+	// "OC/FCI/advertisement" is appended.
+	URL string `json:"url"`
+	// Token is a bearer token issued by the dCDN. If it's empty, tokens are
+	// signed with JWTSecret.
+	Token string `json:"token"`
+	// JWTSecret is the secret shared with the dCDN, with which HS256 tokens
+	// are signed.
+	JWTSecret  string `json:"jwt_secret"`
+	TimeoutSec int    `json:"timeout_sec"`
+}
+
+// GetDCdn returns the configuration of the named downstream CDN, and whether
+// it's configured.
+func (c *CdniConf) GetDCdn(name string) (CdniDCdnConf, bool) {
+	if c == nil {
+		return CdniDCdnConf{}, false
+	}
+	for _, dcdn := range c.DCdns {
+		if dcdn.Name == name {
+			return dcdn, true
+		}
+	}
+	return CdniDCdnConf{}, false
+}
+
+// validateCdniDCdns validates the downstream CDN configuration, and sets its
+// defaults.
+func validateCdniDCdns(c *CdniConf) error {
+	if c == nil {
+		return nil
+	}
+	if c.DelegationRefreshIntervalSec == 0 {
+		c.DelegationRefreshIntervalSec = CdniDelegationRefreshIntervalSecDefault
+	} else if c.DelegationRefreshIntervalSec < 0 {
+		c.DelegationRefreshIntervalSec = 0
+	}
+	names := map[string]struct{}{}
+	for i, dcdn := range c.DCdns {
+		if dcdn.Name == "" {
+			return fmt.Errorf("cdni.dcdns[%d]: missing name", i)
+		}
+		if _, ok := names[dcdn.Name]; ok {
+			return fmt.Errorf("cdni.dcdns: dcdn '%s' is configured more than once", dcdn.Name)
+		}
+		names[dcdn.Name] = struct{}{}
+
+		if u, err := url.Parse(dcdn.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("cdni.dcdns dcdn '%s': url must be an absolute http or https URL", dcdn.Name)
+		}
+		if !strings.HasSuffix(dcdn.URL, "/") {
+			dcdn.URL += "/"
+		}
+		if (dcdn.Token == "") == (dcdn.JWTSecret == "") {
+			return fmt.Errorf("cdni.dcdns dcdn '%s': exactly one of token and jwt_secret must be set", dcdn.Name)
+		}
+		if dcdn.JWTSecret != "" && c.UCdnId == "" {
+			return fmt.Errorf("cdni.dcdns dcdn '%s': jwt_secret requires cdni.ucdn_id", dcdn.Name)
+		}
+		if dcdn.TimeoutSec <= 0 {
+			dcdn.TimeoutSec = CdniDCdnTimeoutSecDefault
+		}
+		c.DCdns[i] = dcdn
+	}
+	return nil
 }
 
 // NewFakeConfig returns a fake Config struct with just enough data to view Routes.
@@ -453,6 +654,8 @@ func (c Config) GetKeyPath() string {
 const (
 	DBMaxIdleConnectionsDefault     = 10 // if this is higher than MaxDBConnections it will be automatically adjusted below it by the db/sql library
 	DBConnMaxLifetimeSecondsDefault = 60
+	SnapshotHistoryCountDefault     = 10
+	RolloutCheckIntervalSecDefault  = 30
 )
 
 // ParseConfig validates required fields, and parses non-JSON types
@@ -488,6 +691,16 @@ func ParseConfig(cfg Config) (Config, error) {
 	if cfg.DBQueryTimeoutSeconds == 0 {
 		cfg.DBQueryTimeoutSeconds = DefaultDBQueryTimeoutSecs
 	}
+	if cfg.SnapshotHistoryCount == 0 {
+		cfg.SnapshotHistoryCount = SnapshotHistoryCountDefault
+	} else if cfg.SnapshotHistoryCount < 0 {
+		cfg.SnapshotHistoryCount = 0
+	}
+	if cfg.RolloutCheckIntervalSec == 0 {
+		cfg.RolloutCheckIntervalSec = RolloutCheckIntervalSecDefault
+	} else if cfg.RolloutCheckIntervalSec < 0 {
+		cfg.RolloutCheckIntervalSec = 0
+	}
 	if cfg.UserCacheRefreshIntervalSec < 0 {
 		cfg.UserCacheRefreshIntervalSec = 0
 	}
@@ -542,6 +755,14 @@ func ParseConfig(cfg Config) (Config, error) {
 		return Config{}, err
 	}
 
+	if err := validateDSPublications(cfg.DSPublications); err != nil {
+		return Config{}, err
+	}
+
+	if err := validateCdniDCdns(cfg.Cdni); err != nil {
+		return Config{}, err
+	}
+
 	return cfg, nil
 }
 
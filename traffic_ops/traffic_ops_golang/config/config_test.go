@@ -318,3 +318,147 @@ func TestValidateRoutingBlacklist(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateDSPublications(t *testing.T) {
+	type testCase struct {
+		Input     []ConfigDSPublication
+		ExpectErr bool
+	}
+	testCases := []testCase{
+		{
+			Input:     nil,
+			ExpectErr: false,
+		},
+		{
+			Input:     []ConfigDSPublication{{CDN: "cdn1", Method: DSPublicationMethodRFC2136, Zone: "example.test", Server: "192.0.2.1"}},
+			ExpectErr: false,
+		},
+		{
+			Input:     []ConfigDSPublication{{CDN: "cdn1", Method: DSPublicationMethodRFC2136, Zone: "example.test"}},
+			ExpectErr: true,
+		},
+		{
+			Input:     []ConfigDSPublication{{CDN: "cdn1", Method: DSPublicationMethodRFC2136, Zone: "example.test", Server: "192.0.2.1", TSIGKeyName: "to-key"}},
+			ExpectErr: true,
+		},
+		{
+			Input:     []ConfigDSPublication{{CDN: "cdn1", Method: DSPublicationMethodWebhook, WebhookURL: "https://registrar.example.test/ds", CheckNameservers: []string{"192.0.2.1"}}},
+			ExpectErr: false,
+		},
+		{
+			Input:     []ConfigDSPublication{{CDN: "cdn1", Method: DSPublicationMethodWebhook, WebhookURL: "https://registrar.example.test/ds"}},
+			ExpectErr: true,
+		},
+		{
+			Input:     []ConfigDSPublication{{CDN: "cdn1", Method: "email"}},
+			ExpectErr: true,
+		},
+		{
+			Input:     []ConfigDSPublication{{Method: DSPublicationMethodRFC2136, Zone: "example.test", Server: "192.0.2.1"}},
+			ExpectErr: true,
+		},
+		{
+			Input: []ConfigDSPublication{
+				{CDN: "cdn1", Method: DSPublicationMethodRFC2136, Zone: "example.test", Server: "192.0.2.1"},
+				{CDN: "cdn1", Method: DSPublicationMethodRFC2136, Zone: "example.test", Server: "192.0.2.2"},
+			},
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		if err := validateDSPublications(tc.Input); err != nil && !tc.ExpectErr {
+			t.Errorf("Expected: no error, actual: %v", err)
+		} else if err == nil && tc.ExpectErr {
+			t.Errorf("Expected: non-nil error, actual: nil")
+		}
+	}
+
+	pubs := []ConfigDSPublication{{CDN: "cdn1", Method: DSPublicationMethodRFC2136, Zone: "example.test", Server: "192.0.2.1"}}
+	if err := validateDSPublications(pubs); err != nil {
+		t.Fatalf("Expected: no error, actual: %v", err)
+	}
+	if pub := pubs[0]; len(pub.CheckNameservers) != 1 || pub.CheckNameservers[0] != pub.Server || pub.DSTTLSec != DSPublicationDSTTLSecDefault || pub.TimeoutSec != DSPublicationTimeoutSecDefault {
+		t.Errorf("Expected: defaults of check_nameservers [%s], ds_ttl_sec %d and timeout_sec %d, actual: %+v", pub.Server, DSPublicationDSTTLSecDefault, DSPublicationTimeoutSecDefault, pub)
+	}
+}
+
+func TestValidateCdniDCdns(t *testing.T) {
+	type testCase struct {
+		Input     CdniConf
+		ExpectErr bool
+	}
+	testCases := []testCase{
+		{
+			Input:     CdniConf{},
+			ExpectErr: false,
+		},
+		{
+			Input:     CdniConf{DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0/", Token: "token"}}},
+			ExpectErr: false,
+		},
+		{
+			Input:     CdniConf{UCdnId: "ucdn1", DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0/", JWTSecret: "secret"}}},
+			ExpectErr: false,
+		},
+		{
+			Input:     CdniConf{DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0/", JWTSecret: "secret"}}},
+			ExpectErr: true,
+		},
+		{
+			Input:     CdniConf{DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0/"}}},
+			ExpectErr: true,
+		},
+		{
+			Input:     CdniConf{UCdnId: "ucdn1", DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0/", Token: "token", JWTSecret: "secret"}}},
+			ExpectErr: true,
+		},
+		{
+			Input:     CdniConf{DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "dcdn1.example.test", Token: "token"}}},
+			ExpectErr: true,
+		},
+		{
+			Input:     CdniConf{DCdns: []CdniDCdnConf{{URL: "https://dcdn1.example.test/api/5.0/", Token: "token"}}},
+			ExpectErr: true,
+		},
+		{
+			Input: CdniConf{DCdns: []CdniDCdnConf{
+				{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0/", Token: "token"},
+				{Name: "dcdn1", URL: "https://dcdn2.example.test/api/5.0/", Token: "token"},
+			}},
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		if err := validateCdniDCdns(&tc.Input); err != nil && !tc.ExpectErr {
+			t.Errorf("Expected: no error, actual: %v", err)
+		} else if err == nil && tc.ExpectErr {
+			t.Errorf("Expected: non-nil error, actual: nil")
+		}
+	}
+
+	cdni := &CdniConf{DCdns: []CdniDCdnConf{{Name: "dcdn1", URL: "https://dcdn1.example.test/api/5.0", Token: "token"}}}
+	if err := validateCdniDCdns(cdni); err != nil {
+		t.Fatalf("Expected: no error, actual: %v", err)
+	}
+	dcdn, ok := cdni.GetDCdn("dcdn1")
+	if !ok {
+		t.Fatal("Expected: dcdn1 to be configured, actual: missing")
+	}
+	if dcdn.URL != "https://dcdn1.example.test/api/5.0/" {
+		t.Errorf("Expected: url with a trailing slash, actual: %s", dcdn.URL)
+	}
+	if dcdn.TimeoutSec != CdniDCdnTimeoutSecDefault {
+		t.Errorf("Expected: default timeout %d, actual: %d", CdniDCdnTimeoutSecDefault, dcdn.TimeoutSec)
+	}
+	if cdni.DelegationRefreshIntervalSec != CdniDelegationRefreshIntervalSecDefault {
+		t.Errorf("Expected: default delegation refresh interval %d, actual: %d", CdniDelegationRefreshIntervalSecDefault, cdni.DelegationRefreshIntervalSec)
+	}
+
+	cdni = &CdniConf{DelegationRefreshIntervalSec: -1}
+	if err := validateCdniDCdns(cdni); err != nil {
+		t.Fatalf("Expected: no error, actual: %v", err)
+	}
+	if cdni.DelegationRefreshIntervalSec != 0 {
+		t.Errorf("Expected: a negative delegation refresh interval to disable refreshes, actual: %d", cdni.DelegationRefreshIntervalSec)
+	}
+}
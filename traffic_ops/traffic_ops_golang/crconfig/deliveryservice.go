@@ -21,6 +21,7 @@ package crconfig
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strconv"
@@ -91,6 +92,11 @@ func makeDSes(cdn string, domain string, tx *sql.Tx) (map[string]tc.CRConfigDeli
 		return nil, errors.New("getting static DNS entries: " + err.Error())
 	}
 
+	cdniDelegations, err := getCDNiDelegations(cdn, tx)
+	if err != nil {
+		return nil, errors.New("getting CDNi delegations: " + err.Error())
+	}
+
 	q := `
 SELECT d.anonymous_blocking_enabled,
        d.consistent_hash_regex,
@@ -411,6 +417,7 @@ AND d.active = true
 		}
 
 		ds.StaticDNSEntries = staticDNSEntries[tc.DeliveryServiceName(xmlID)]
+		ds.CDNiDelegation = cdniDelegations[tc.DeliveryServiceName(xmlID)]
 
 		dses[xmlID] = ds
 	}
@@ -454,6 +461,42 @@ and d.active = true
 	return entries, nil
 }
 
+// getCDNiDelegations returns the delegations of the CDN's active Delivery
+// Services to CDNi downstream CDNs.
+func getCDNiDelegations(cdn string, tx *sql.Tx) (map[tc.DeliveryServiceName]*tc.CRConfigCDNiDelegation, error) {
+	delegations := map[tc.DeliveryServiceName]*tc.CRConfigCDNiDelegation{}
+
+	q := `
+select d.xml_id as ds, cd.dcdn, cd.host, cd.footprints
+from cdni_delegation as cd
+inner join deliveryservice as d on d.id = cd.deliveryservice
+where d.cdn_id = (select id from cdn where name = $1)
+and d.active = true
+`
+	rows, err := tx.Query(q, cdn)
+	if err != nil {
+		return nil, errors.New("querying CDNi delegations: " + err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		ds := ""
+		delegation := tc.CRConfigCDNiDelegation{}
+		footprints := []byte{}
+		if err := rows.Scan(&ds, &delegation.DCDN, &delegation.Host, &footprints); err != nil {
+			return nil, errors.New("scanning CDNi delegations: " + err.Error())
+		}
+		if err := json.Unmarshal(footprints, &delegation.Footprints); err != nil {
+			return nil, errors.New("decoding CDNi delegation footprints of delivery service '" + ds + "': " + err.Error())
+		}
+		delegations[tc.DeliveryServiceName(ds)] = &delegation
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating CDNi delegation rows: " + err.Error())
+	}
+	return delegations, nil
+}
+
 func getDSRegexesDomains(cdn string, domain string, tx *sql.Tx) (map[string][]*tc.MatchSet, map[string][]string, error) {
 	dsmatchsets := map[string][]*tc.MatchSet{}
 	domains := map[string][]string{}
@@ -119,9 +119,18 @@ func randDS() tc.CRConfigDeliveryService {
 }
 
 func ExpectedMakeDSes() map[string]tc.CRConfigDeliveryService {
+	ds2 := randDS()
+	ds2.CDNiDelegation = &tc.CRConfigCDNiDelegation{
+		DCDN: test.RandStr(),
+		Host: test.RandStr(),
+		Footprints: []tc.CDNiFootprint{
+			{Type: "ipv4cidr", Value: []string{"192.0.2.0/24"}},
+			{Type: "countrycode", Value: []string{"us"}},
+		},
+	}
 	return map[string]tc.CRConfigDeliveryService{
 		"ds1": randDS(),
-		"ds2": randDS(),
+		"ds2": ds2,
 	}
 }
 
@@ -229,11 +238,13 @@ func TestMakeDSesGeoLimit(t *testing.T) {
 	}
 	expectedMatchsets, expectedDomains := ExpectedGetDSRegexesDomains(expectedDSParams)
 	expectedStaticDNSEntries := ExpectedGetStaticDNSEntries(expected)
+	expectedCDNiDelegations := ExpectedGetCDNiDelegations(expected)
 
 	mock.ExpectBegin()
 	MockGetServerProfileParams(mock, expectedParams, cdn)
 	MockGetDSRegexesDomains(mock, expectedMatchsets, expectedDomains, cdn)
 	MockGetStaticDNSEntries(mock, expectedStaticDNSEntries, cdn)
+	MockGetCDNiDelegations(mock, expectedCDNiDelegations, cdn)
 	MockMakeDSes(mock, expected, cdn, "US,CA")
 	mock.ExpectCommit()
 
@@ -289,11 +300,13 @@ func TestMakeDSes(t *testing.T) {
 	}
 	expectedMatchsets, expectedDomains := ExpectedGetDSRegexesDomains(expectedDSParams)
 	expectedStaticDNSEntries := ExpectedGetStaticDNSEntries(expected)
+	expectedCDNiDelegations := ExpectedGetCDNiDelegations(expected)
 
 	mock.ExpectBegin()
 	MockGetServerProfileParams(mock, expectedParams, cdn)
 	MockGetDSRegexesDomains(mock, expectedMatchsets, expectedDomains, cdn)
 	MockGetStaticDNSEntries(mock, expectedStaticDNSEntries, cdn)
+	MockGetCDNiDelegations(mock, expectedCDNiDelegations, cdn)
 	MockMakeDSes(mock, expected, cdn, "")
 	mock.ExpectCommit()
 
@@ -538,3 +551,55 @@ func TestGetStaticDNSEntries(t *testing.T) {
 		t.Errorf("getDSRegexesDomains expected: %+v, actual: %+v", expected, actual)
 	}
 }
+
+func ExpectedGetCDNiDelegations(expectedMakeDSes map[string]tc.CRConfigDeliveryService) map[tc.DeliveryServiceName]*tc.CRConfigCDNiDelegation {
+	expected := map[tc.DeliveryServiceName]*tc.CRConfigCDNiDelegation{}
+	for dsName, ds := range expectedMakeDSes {
+		if ds.CDNiDelegation != nil {
+			expected[tc.DeliveryServiceName(dsName)] = ds.CDNiDelegation
+		}
+	}
+	return expected
+}
+
+func MockGetCDNiDelegations(mock sqlmock.Sqlmock, expected map[tc.DeliveryServiceName]*tc.CRConfigCDNiDelegation, cdn string) {
+	rows := sqlmock.NewRows([]string{"ds", "dcdn", "host", "footprints"})
+	for dsName, delegation := range expected {
+		footprints, _ := json.Marshal(delegation.Footprints)
+		rows = rows.AddRow(dsName, delegation.DCDN, delegation.Host, footprints)
+	}
+	mock.ExpectQuery("select").WithArgs(cdn).WillReturnRows(rows)
+}
+
+func TestGetCDNiDelegations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cdn := "mycdn"
+
+	expectedMakeDSes := ExpectedMakeDSes()
+	expected := ExpectedGetCDNiDelegations(expectedMakeDSes)
+
+	mock.ExpectBegin()
+	MockGetCDNiDelegations(mock, expected, cdn)
+	mock.ExpectCommit()
+
+	dbCtx, cancelTx := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelTx()
+	tx, err := db.BeginTx(dbCtx, nil)
+	if err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+	defer tx.Commit()
+
+	actual, err := getCDNiDelegations(cdn, tx)
+	if err != nil {
+		t.Fatalf("getCDNiDelegations expected: nil error, actual: %v", err)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("getCDNiDelegations expected: %+v, actual: %+v", expected, actual)
+	}
+}
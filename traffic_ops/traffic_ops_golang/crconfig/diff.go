@@ -0,0 +1,200 @@
+package crconfig
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// Diff returns the structural difference between the old and new CRConfigs,
+// e.g. the current snapshot and the CRConfig created by Make, including every
+// field which changed in each changed key.
+//
+// Stats fields which change on every snapshot - the date, and the host and
+// user which made it - are ignored.
+func Diff(old *tc.CRConfig, new *tc.CRConfig) (tc.CRConfigDiff, error) {
+	diff := tc.CRConfigDiff{}
+	errs := []error{}
+	section := func(oldSection interface{}, newSection interface{}) tc.CRConfigSectionDiff {
+		sectionDiff, err := diffSection(oldSection, newSection)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return sectionDiff
+	}
+	diff.Config = section(old.Config, new.Config)
+	diff.ContentServers = section(old.ContentServers, new.ContentServers)
+	diff.ContentRouters = section(old.ContentRouters, new.ContentRouters)
+	diff.DeliveryServices = section(old.DeliveryServices, new.DeliveryServices)
+	diff.EdgeLocations = section(old.EdgeLocations, new.EdgeLocations)
+	diff.RouterLocations = section(old.RouterLocations, new.RouterLocations)
+	diff.Monitors = section(old.Monitors, new.Monitors)
+	diff.Stats = section(comparableStats(old.Stats), comparableStats(new.Stats))
+	diff.Topologies = section(old.Topologies, new.Topologies)
+	if len(errs) > 0 {
+		return tc.CRConfigDiff{}, errors.New("diffing CRConfig sections: " + errs[0].Error())
+	}
+	return diff, nil
+}
+
+// DiffSnapshot returns the difference between the given snapshot of a CDN, as
+// returned by GetSnapshot, and the CRConfig which Make would create for it now.
+func DiffSnapshot(tx *sql.Tx, snapshot string, cdn string, user string, toHost string, toVersion string, useTOHost bool, emulateOldPath bool) (tc.CRConfigDiff, error) {
+	current := tc.CRConfig{}
+	if err := json.Unmarshal([]byte(snapshot), &current); err != nil {
+		return tc.CRConfigDiff{}, errors.New("unmarshalling snapshot: " + err.Error())
+	}
+	crc, err := Make(tx, cdn, user, toHost, toVersion, useTOHost, emulateOldPath)
+	if err != nil {
+		return tc.CRConfigDiff{}, errors.New("making CRConfig: " + err.Error())
+	}
+	return Diff(&current, crc)
+}
+
+// comparableStats returns the given stats without the fields which change on
+// every snapshot.
+func comparableStats(stats tc.CRConfigStats) tc.CRConfigStats {
+	stats.DateUnixSeconds = nil
+	stats.TMHost = nil
+	stats.TMPath = nil
+	stats.TMUser = nil
+	return stats
+}
+
+// diffSection returns the keys added, removed, and changed between the old and
+// new sections, which must be JSON-serializable as objects.
+func diffSection(oldSection interface{}, newSection interface{}) (tc.CRConfigSectionDiff, error) {
+	diff := tc.CRConfigSectionDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+
+	oldObj, err := sectionObject(oldSection)
+	if err != nil {
+		return diff, err
+	}
+	newObj, err := sectionObject(newSection)
+	if err != nil {
+		return diff, err
+	}
+
+	for key, newVal := range newObj {
+		oldVal, ok := oldObj[key]
+		if !ok {
+			diff.Added = append(diff.Added, key)
+		} else if !bytes.Equal(oldVal, newVal) {
+			diff.Changed = append(diff.Changed, key)
+			diff.Fields = appendFieldDiffs(diff.Fields, key, "", oldVal, newVal)
+		}
+	}
+	for key := range oldObj {
+		if _, ok := newObj[key]; !ok {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	sort.SliceStable(diff.Fields, func(i, j int) bool { return diff.Fields[i].Key < diff.Fields[j].Key })
+	return diff, nil
+}
+
+// appendFieldDiffs appends the fields which differ between the old and new
+// JSON values of the given key to fields, in order. Objects are compared by
+// property, and arrays of the same length by element; any other differing
+// value is appended whole.
+func appendFieldDiffs(fields []tc.CRConfigFieldDiff, key string, path string, oldVal json.RawMessage, newVal json.RawMessage) []tc.CRConfigFieldDiff {
+	if bytes.Equal(oldVal, newVal) {
+		return fields
+	}
+
+	oldObj := map[string]json.RawMessage{}
+	newObj := map[string]json.RawMessage{}
+	if isJSONObject(oldVal) && isJSONObject(newVal) && json.Unmarshal(oldVal, &oldObj) == nil && json.Unmarshal(newVal, &newObj) == nil {
+		names := make([]string, 0, len(oldObj)+len(newObj))
+		for name := range oldObj {
+			names = append(names, name)
+		}
+		for name := range newObj {
+			if _, ok := oldObj[name]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			fields = appendFieldDiffs(fields, key, fieldPath, oldObj[name], newObj[name])
+		}
+		return fields
+	}
+
+	oldArr := []json.RawMessage{}
+	newArr := []json.RawMessage{}
+	if isJSONArray(oldVal) && isJSONArray(newVal) && json.Unmarshal(oldVal, &oldArr) == nil && json.Unmarshal(newVal, &newArr) == nil && len(oldArr) == len(newArr) {
+		for i := range oldArr {
+			fields = appendFieldDiffs(fields, key, path+"["+strconv.Itoa(i)+"]", oldArr[i], newArr[i])
+		}
+		return fields
+	}
+
+	return append(fields, tc.CRConfigFieldDiff{Key: key, Field: path, Old: jsonOrNull(oldVal), New: jsonOrNull(newVal)})
+}
+
+func isJSONObject(val json.RawMessage) bool {
+	return len(val) > 0 && val[0] == '{'
+}
+
+func isJSONArray(val json.RawMessage) bool {
+	return len(val) > 0 && val[0] == '['
+}
+
+// jsonOrNull returns the given JSON, or the JSON null if it is empty, i.e. the
+// field did not exist.
+func jsonOrNull(val json.RawMessage) json.RawMessage {
+	if len(val) == 0 {
+		return json.RawMessage("null")
+	}
+	return val
+}
+
+// sectionObject returns the JSON of each key in the given section. Because
+// encoding/json serializes map keys in sorted order, equal values always
+// produce equal JSON.
+func sectionObject(section interface{}) (map[string]json.RawMessage, error) {
+	bts, err := json.Marshal(section)
+	if err != nil {
+		return nil, errors.New("marshalling: " + err.Error())
+	}
+	obj := map[string]json.RawMessage{}
+	if bytes.Equal(bts, []byte("null")) {
+		return obj, nil
+	}
+	if err := json.Unmarshal(bts, &obj); err != nil {
+		return nil, errors.New("unmarshalling: " + err.Error())
+	}
+	return obj, nil
+}
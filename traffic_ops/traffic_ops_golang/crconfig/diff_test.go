@@ -0,0 +1,144 @@
+package crconfig
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+)
+
+func TestDiff(t *testing.T) {
+	old := &tc.CRConfig{
+		Config: map[string]interface{}{"domain_name": "old.example", "ttls": map[string]string{"A": "60"}},
+		DeliveryServices: map[string]tc.CRConfigDeliveryService{
+			"ds1": {Protocol: &tc.CRConfigDeliveryServiceProtocol{}},
+			"ds2": {},
+		},
+		EdgeLocations: map[string]tc.CRConfigLatitudeLongitude{"cg1": {Lat: 1, Lon: 2}},
+		Stats: tc.CRConfigStats{
+			CDNName:         util.StrPtr("cdn"),
+			DateUnixSeconds: util.Int64Ptr(1),
+			TMUser:          util.StrPtr("alice"),
+		},
+	}
+	new := &tc.CRConfig{
+		Config: map[string]interface{}{"domain_name": "new.example", "ttls": map[string]string{"A": "60"}},
+		DeliveryServices: map[string]tc.CRConfigDeliveryService{
+			"ds2": {},
+			"ds3": {},
+		},
+		EdgeLocations: map[string]tc.CRConfigLatitudeLongitude{"cg1": {Lat: 1, Lon: 2}},
+		Stats: tc.CRConfigStats{
+			CDNName:         util.StrPtr("cdn"),
+			DateUnixSeconds: util.Int64Ptr(2),
+			TMUser:          util.StrPtr("bob"),
+		},
+	}
+
+	diff, err := Diff(old, new)
+	if err != nil {
+		t.Fatalf("Diff expected: nil error, actual: %v", err)
+	}
+
+	empty := tc.CRConfigSectionDiff{Added: []string{}, Removed: []string{}, Changed: []string{}}
+	expectedConfig := tc.CRConfigSectionDiff{
+		Added:   []string{},
+		Removed: []string{},
+		Changed: []string{"domain_name"},
+		Fields:  []tc.CRConfigFieldDiff{{Key: "domain_name", Old: json.RawMessage(`"old.example"`), New: json.RawMessage(`"new.example"`)}},
+	}
+	if expected := expectedConfig; !reflect.DeepEqual(expected, diff.Config) {
+		t.Errorf("Diff config expected: %+v, actual: %+v", expected, diff.Config)
+	}
+	if expected := (tc.CRConfigSectionDiff{Added: []string{"ds3"}, Removed: []string{"ds1"}, Changed: []string{}}); !reflect.DeepEqual(expected, diff.DeliveryServices) {
+		t.Errorf("Diff deliveryServices expected: %+v, actual: %+v", expected, diff.DeliveryServices)
+	}
+	if !reflect.DeepEqual(empty, diff.EdgeLocations) {
+		t.Errorf("Diff edgeLocations expected: %+v, actual: %+v", empty, diff.EdgeLocations)
+	}
+	if !reflect.DeepEqual(empty, diff.Stats) {
+		t.Errorf("Diff stats expected no changes to date or user, actual: %+v", diff.Stats)
+	}
+	if !reflect.DeepEqual(empty, diff.ContentServers) {
+		t.Errorf("Diff contentServers expected: %+v, actual: %+v", empty, diff.ContentServers)
+	}
+}
+
+func TestDiffEmptySnapshot(t *testing.T) {
+	new := &tc.CRConfig{
+		ContentServers: map[string]tc.CRConfigTrafficOpsServer{"edge1": {}, "edge0": {}},
+	}
+	diff, err := Diff(&tc.CRConfig{}, new)
+	if err != nil {
+		t.Fatalf("Diff expected: nil error, actual: %v", err)
+	}
+	if expected := []string{"edge0", "edge1"}; !reflect.DeepEqual(expected, diff.ContentServers.Added) {
+		t.Errorf("Diff contentServers added expected: %+v, actual: %+v", expected, diff.ContentServers.Added)
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	old := &tc.CRConfig{
+		DeliveryServices: map[string]tc.CRConfigDeliveryService{
+			"ds1": {
+				Protocol:  &tc.CRConfigDeliveryServiceProtocol{AcceptHTTP: util.BoolPtr(true), AcceptHTTPS: false},
+				MatchSets: []*tc.MatchSet{{Protocol: "HTTP", MatchList: []tc.MatchList{{Regex: `.*\.old\..*`, MatchType: "HOST"}}}},
+				Domains:   []string{"old.example"},
+			},
+		},
+	}
+	new := &tc.CRConfig{
+		DeliveryServices: map[string]tc.CRConfigDeliveryService{
+			"ds1": {
+				Protocol:          &tc.CRConfigDeliveryServiceProtocol{AcceptHTTP: util.BoolPtr(true), AcceptHTTPS: true},
+				MatchSets:         []*tc.MatchSet{{Protocol: "HTTP", MatchList: []tc.MatchList{{Regex: `.*\.new\..*`, MatchType: "HOST"}}}},
+				Domains:           []string{"new.example", "other.example"},
+				IP6RoutingEnabled: util.BoolPtr(true),
+			},
+		},
+	}
+
+	diff, err := Diff(old, new)
+	if err != nil {
+		t.Fatalf("Diff expected: nil error, actual: %v", err)
+	}
+	if expected := []string{"ds1"}; !reflect.DeepEqual(expected, diff.DeliveryServices.Changed) {
+		t.Errorf("Diff deliveryServices changed expected: %+v, actual: %+v", expected, diff.DeliveryServices.Changed)
+	}
+
+	expected := []tc.CRConfigFieldDiff{
+		{Key: "ds1", Field: "domains", Old: json.RawMessage(`["old.example"]`), New: json.RawMessage(`["new.example","other.example"]`)},
+		{Key: "ds1", Field: "ip6RoutingEnabled", Old: json.RawMessage(`null`), New: json.RawMessage(`"true"`)},
+		{Key: "ds1", Field: "matchsets[0].matchlist[0].regex", Old: json.RawMessage(`".*\\.old\\..*"`), New: json.RawMessage(`".*\\.new\\..*"`)},
+		{Key: "ds1", Field: "protocol.acceptHttps", Old: json.RawMessage(`"false"`), New: json.RawMessage(`"true"`)},
+	}
+	if len(diff.DeliveryServices.Fields) != len(expected) {
+		t.Fatalf("Diff deliveryServices fields expected: %d fields, actual: %+v", len(expected), diff.DeliveryServices.Fields)
+	}
+	for i, field := range diff.DeliveryServices.Fields {
+		if field.Key != expected[i].Key || field.Field != expected[i].Field || string(field.Old) != string(expected[i].Old) || string(field.New) != string(expected[i].New) {
+			t.Errorf("Diff deliveryServices field %d expected: %s %s %s -> %s, actual: %s %s %s -> %s", i, expected[i].Key, expected[i].Field, expected[i].Old, expected[i].New, field.Key, field.Field, field.Old, field.New)
+		}
+	}
+}
@@ -89,6 +89,37 @@ func SnapshotGetHandler(w http.ResponseWriter, r *http.Request) {
 	api.WriteResp(w, r, decoded)
 }
 
+// SnapshotDiffHandler serves the difference between the CRConfig in the
+// snapshot table and the CRConfig which would be created by snapshotting now.
+func SnapshotDiffHandler(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"cdn"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	snapshot, cdnExists, err := GetSnapshot(inf.Tx.Tx, inf.Params["cdn"])
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("getting snapshot: "+err.Error()))
+		return
+	}
+	if !cdnExists {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("CDN not found"), nil)
+		return
+	}
+
+	start := time.Now()
+	emulate := inf.Config.CRConfigEmulateOldPath || inf.Version.Major < 4
+	diff, err := DiffSnapshot(inf.Tx.Tx, snapshot, inf.Params["cdn"], inf.User.UserName, r.Host, inf.Config.Version, inf.Config.CRConfigUseRequestHost, emulate)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("diffing snapshot for cdn '%s': %w", inf.Params["cdn"], err))
+		return
+	}
+	log.Infof("CRConfig time to diff: %+v\n", time.Since(start))
+	api.WriteResp(w, r, diff)
+}
+
 // SnapshotGetMonitoringHandler gets and serves the CRConfig from the snapshot table.
 func SnapshotGetMonitoringHandler(w http.ResponseWriter, r *http.Request) {
 	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"cdn"}, nil)
@@ -184,6 +215,117 @@ func SnapshotHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	api.CreateChangeLogRawTx(api.ApiChange, "CDN: "+cdn+", ID: "+strconv.Itoa(id)+", ACTION: Snapshot of CRConfig and Monitor", inf.User, inf.Tx.Tx)
+	logID, err := api.CreateChangeLogRawID(api.ApiChange, "CDN: "+cdn+", ID: "+strconv.Itoa(id)+", ACTION: Snapshot of CRConfig and Monitor", inf.User, inf.Tx.Tx)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	if _, err := SaveSnapshotHistory(inf.Tx.Tx, cdn, inf.User.UserName, &logID, nil, inf.Config.SnapshotHistoryCount); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New(r.RemoteAddr+" snapshotting CRConfig and Monitoring: "+err.Error()))
+		return
+	}
 	api.WriteResp(w, r, "SUCCESS")
 }
+
+// SnapshotHistoryHandler serves the retained Snapshots of a CDN, without their
+// contents.
+func SnapshotHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"cdn"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	cdn := inf.Params["cdn"]
+	if ok, err := dbhelpers.CDNExists(cdn, inf.Tx.Tx); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("checking CDN existence: "+err.Error()))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("CDN not found"), nil)
+		return
+	}
+
+	history, err := GetSnapshotHistory(inf.Tx.Tx, cdn)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	api.WriteResp(w, r, history)
+}
+
+// SnapshotHistoryEntryHandler serves a single retained Snapshot of a CDN,
+// including its contents.
+func SnapshotHistoryEntryHandler(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"cdn", "id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	entry, ok, err := GetSnapshotHistoryEntry(inf.Tx.Tx, inf.Params["cdn"], inf.IntParams["id"])
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("no snapshot history entry %d for CDN '%s'", inf.IntParams["id"], inf.Params["cdn"]), nil)
+		return
+	}
+	api.WriteResp(w, r, entry)
+}
+
+// SnapshotRollbackHandler re-publishes a retained Snapshot of a CDN as its
+// current Snapshot.
+func SnapshotRollbackHandler(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"cdn", "id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	cdn := inf.Params["cdn"]
+	historyID := inf.IntParams["id"]
+	cdnID, ok, err := dbhelpers.GetCDNIDFromName(inf.Tx.Tx, tc.CDNName(cdn))
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("Error getting CDN ID from name: "+err.Error()))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, errors.New("CDN not found"), nil)
+		return
+	}
+	userErr, sysErr, statusCode := dbhelpers.CheckIfCurrentUserHasCdnLock(inf.Tx.Tx, cdn, inf.User.UserName)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, statusCode, userErr, sysErr)
+		return
+	}
+
+	entry, ok, err := GetSnapshotHistoryEntry(inf.Tx.Tx, cdn, historyID)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("no snapshot history entry %d for CDN '%s'", historyID, cdn), nil)
+		return
+	}
+
+	if err := Rollback(inf.Tx.Tx, entry, inf.User.UserName); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New(r.RemoteAddr+" rolling back CRConfig and Monitoring: "+err.Error()))
+		return
+	}
+
+	logID, err := api.CreateChangeLogRawID(api.ApiChange, "CDN: "+cdn+", ID: "+strconv.Itoa(cdnID)+", ACTION: Rollback of CRConfig and Monitor to Snapshot history ID "+strconv.Itoa(historyID), inf.User, inf.Tx.Tx)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	newID, err := SaveSnapshotHistory(inf.Tx.Tx, cdn, inf.User.UserName, &logID, &historyID, inf.Config.SnapshotHistoryCount)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New(r.RemoteAddr+" rolling back CRConfig and Monitoring: "+err.Error()))
+		return
+	}
+
+	result := tc.SnapshotHistory{ID: newID, CDN: cdn, Author: inf.User.UserName, ChangeLogID: &logID, RollbackOf: &historyID, LastUpdated: time.Now()}
+	api.WriteRespAlertObj(w, r, tc.SuccessLevel, fmt.Sprintf("Snapshot of CDN '%s' rolled back to history entry %d", cdn, historyID), result)
+}
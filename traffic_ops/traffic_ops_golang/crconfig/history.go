@@ -0,0 +1,149 @@
+package crconfig
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/monitoring"
+)
+
+const insertSnapshotHistoryQuery = `
+INSERT INTO snapshot_history (cdn, crconfig, monitoring, author, log_id, rollback_of, last_updated)
+SELECT s.cdn, s.crconfig, s.monitoring, $2, $3, $4, s.last_updated
+FROM snapshot AS s
+WHERE s.cdn = $1
+RETURNING id
+`
+
+const pruneSnapshotHistoryQuery = `
+DELETE FROM snapshot_history
+WHERE cdn = $1
+AND id NOT IN (
+	SELECT id
+	FROM snapshot_history
+	WHERE cdn = $1
+	ORDER BY last_updated DESC, id DESC
+	LIMIT $2
+)
+`
+
+const selectSnapshotHistoryQuery = `
+SELECT id, cdn, author, log_id, rollback_of, last_updated
+FROM snapshot_history
+WHERE cdn = $1
+ORDER BY last_updated DESC, id DESC
+`
+
+const selectSnapshotHistoryEntryQuery = `
+SELECT id, cdn, author, log_id, rollback_of, last_updated, crconfig, monitoring
+FROM snapshot_history
+WHERE cdn = $1 AND id = $2
+`
+
+// SaveSnapshotHistory copies the current Snapshot of the given CDN, as written
+// by Snapshot, into its history, and removes all but the newest keep entries.
+// If keep is not positive, no history is saved, and 0 is returned; otherwise
+// the ID of the new history entry is returned.
+func SaveSnapshotHistory(tx *sql.Tx, cdn string, author string, logID *int, rollbackOf *int, keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+	id := 0
+	if err := tx.QueryRow(insertSnapshotHistoryQuery, cdn, author, logID, rollbackOf).Scan(&id); err != nil {
+		return 0, errors.New("inserting snapshot history: " + err.Error())
+	}
+	if _, err := tx.Exec(pruneSnapshotHistoryQuery, cdn, keep); err != nil {
+		return 0, errors.New("pruning snapshot history: " + err.Error())
+	}
+	return id, nil
+}
+
+// GetSnapshotHistory returns the Snapshot history of the given CDN, newest
+// first, without the contents of each Snapshot.
+func GetSnapshotHistory(tx *sql.Tx, cdn string) ([]tc.SnapshotHistory, error) {
+	rows, err := tx.Query(selectSnapshotHistoryQuery, cdn)
+	if err != nil {
+		return nil, errors.New("querying snapshot history: " + err.Error())
+	}
+	defer log.Close(rows, "closing snapshot history rows")
+
+	history := []tc.SnapshotHistory{}
+	for rows.Next() {
+		entry := tc.SnapshotHistory{}
+		if err := rows.Scan(&entry.ID, &entry.CDN, &entry.Author, &entry.ChangeLogID, &entry.RollbackOf, &entry.LastUpdated); err != nil {
+			return nil, errors.New("scanning snapshot history: " + err.Error())
+		}
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating snapshot history rows: " + err.Error())
+	}
+	return history, nil
+}
+
+// GetSnapshotHistoryEntry returns the Snapshot history entry of the given CDN
+// with the given ID, including its contents. If no such entry exists, false is
+// returned.
+func GetSnapshotHistoryEntry(tx *sql.Tx, cdn string, id int) (tc.SnapshotHistory, bool, error) {
+	entry := tc.SnapshotHistory{}
+	crcBts := []byte{}
+	monitoringBts := []byte{}
+	if err := tx.QueryRow(selectSnapshotHistoryEntryQuery, cdn, id).Scan(&entry.ID, &entry.CDN, &entry.Author, &entry.ChangeLogID, &entry.RollbackOf, &entry.LastUpdated, &crcBts, &monitoringBts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entry, false, nil
+		}
+		return entry, false, errors.New("querying snapshot history entry: " + err.Error())
+	}
+	entry.CRConfig = &tc.CRConfig{}
+	if err := json.Unmarshal(crcBts, entry.CRConfig); err != nil {
+		return entry, false, errors.New("unmarshalling snapshot history CRConfig: " + err.Error())
+	}
+	entry.Monitoring = json.RawMessage(monitoringBts)
+	return entry, true, nil
+}
+
+// Rollback re-publishes the given Snapshot history entry, which must include
+// its contents, as the current Snapshot of its CDN. The CRConfig's date and
+// user are set to now and the given user, so that Traffic Router accepts it
+// as newer than the Snapshot it replaces.
+func Rollback(tx *sql.Tx, entry tc.SnapshotHistory, user string) error {
+	if entry.CRConfig == nil {
+		return errors.New("snapshot history entry has no CRConfig")
+	}
+	crc := *entry.CRConfig
+	date := time.Now().Unix()
+	crc.Stats.DateUnixSeconds = &date
+	crc.Stats.TMUser = &user
+	if crc.Stats.CDNName == nil {
+		crc.Stats.CDNName = &entry.CDN
+	}
+
+	monitoringJSON := &monitoring.Monitoring{}
+	if err := json.Unmarshal(entry.Monitoring, monitoringJSON); err != nil {
+		return errors.New("unmarshalling snapshot history monitoring: " + err.Error())
+	}
+	return Snapshot(tx, &crc, monitoringJSON)
+}
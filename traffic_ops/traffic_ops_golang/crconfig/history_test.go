@@ -0,0 +1,116 @@
+package crconfig
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func TestSaveSnapshotHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	logID := 42
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO snapshot_history").WithArgs("mycdn", "alice", logID, nil).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectExec("DELETE FROM snapshot_history").WithArgs("mycdn", 3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	dbCtx, cancelTx := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelTx()
+	tx, err := db.BeginTx(dbCtx, nil)
+	if err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	id, err := SaveSnapshotHistory(tx, "mycdn", "alice", &logID, nil, 3)
+	if err != nil {
+		t.Fatalf("SaveSnapshotHistory err expected: nil, actual: %v", err)
+	}
+	if id != 7 {
+		t.Errorf("SaveSnapshotHistory id expected: 7, actual: %d", id)
+	}
+
+	// A non-positive count disables history, so no queries are expected.
+	if id, err := SaveSnapshotHistory(tx, "mycdn", "alice", &logID, nil, 0); err != nil || id != 0 {
+		t.Errorf("SaveSnapshotHistory with no history expected: 0, nil; actual: %d, %v", id, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	cdn := "mycdn"
+	oldDate := int64(1)
+	oldUser := "alice"
+	crc := tc.CRConfig{Stats: tc.CRConfigStats{CDNName: &cdn, DateUnixSeconds: &oldDate, TMUser: &oldUser}}
+	rows := sqlmock.NewRows([]string{"id", "cdn", "author", "log_id", "rollback_of", "last_updated", "crconfig", "monitoring"})
+	crcBts, err := json.Marshal(crc)
+	if err != nil {
+		t.Fatalf("marshalling CRConfig: %v", err)
+	}
+	rows.AddRow(3, cdn, oldUser, 10, nil, time.Unix(oldDate, 0), crcBts, []byte(`{"trafficServers":[]}`))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WithArgs(cdn, 3).WillReturnRows(rows)
+	mock.ExpectExec("insert").WithArgs(cdn, Any{}, AnyTime{}, Any{}).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	dbCtx, cancelTx := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancelTx()
+	tx, err := db.BeginTx(dbCtx, nil)
+	if err != nil {
+		t.Fatalf("creating transaction: %v", err)
+	}
+
+	entry, ok, err := GetSnapshotHistoryEntry(tx, cdn, 3)
+	if err != nil {
+		t.Fatalf("GetSnapshotHistoryEntry err expected: nil, actual: %v", err)
+	} else if !ok {
+		t.Fatalf("GetSnapshotHistoryEntry exists expected: true, actual: false")
+	}
+	if entry.ChangeLogID == nil || *entry.ChangeLogID != 10 {
+		t.Errorf("GetSnapshotHistoryEntry change log ID expected: 10, actual: %v", entry.ChangeLogID)
+	}
+
+	if err := Rollback(tx, entry, "bob"); err != nil {
+		t.Fatalf("Rollback err expected: nil, actual: %v", err)
+	}
+	if *entry.CRConfig.Stats.TMUser != oldUser || *entry.CRConfig.Stats.DateUnixSeconds != oldDate {
+		t.Errorf("Rollback expected: not to modify the history entry, actual: %+v", entry.CRConfig.Stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
@@ -1985,6 +1985,21 @@ WHERE server.id = $2;`
 	return nil
 }
 
+// SetApplyFailureForServerWithTime sets the time the server last failed to
+// apply its config.
+func SetApplyFailureForServerWithTime(tx *sql.Tx, serverID int64, failureTime time.Time) error {
+	query := `
+UPDATE public.server
+SET config_apply_failure_time = $1
+WHERE server.id = $2;`
+
+	if _, err := tx.Exec(query, failureTime, serverID); err != nil {
+		return fmt.Errorf("setting config apply failure for ServerID %d with time %v: %w", serverID, failureTime, err)
+	}
+
+	return nil
+}
+
 // QueueRevalForServer sets the revalidate update time for the server to now.
 func QueueRevalForServer(tx *sql.Tx, serverID int64) error {
 	query := `
@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strconv"
@@ -178,12 +179,15 @@ func CreateV40(w http.ResponseWriter, r *http.Request) {
 	}
 	defer inf.Close()
 
-	ds := tc.DeliveryServiceV40{}
+	ds := tc.DeliveryServiceV4{}
 	if err := json.NewDecoder(r.Body).Decode(&ds); err != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("decoding: "+err.Error()), nil)
 		return
 	}
-	res, status, userErr, sysErr := createV40(w, r, inf, ds, true)
+	if inf.Version.Major == 4 && inf.Version.Minor < 1 {
+		ds.CacheKeyPolicy = nil
+	}
+	res, status, userErr, sysErr := createV41(w, r, inf, ds, true)
 	if userErr != nil || sysErr != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, status, userErr, sysErr)
 		return
@@ -192,7 +196,24 @@ func CreateV40(w http.ResponseWriter, r *http.Request) {
 	alerts.AddNewAlert(tc.SuccessLevel, "Delivery Service creation was successful")
 
 	w.Header().Set("Location", fmt.Sprintf("/api/4.0/deliveryservices?id=%d", *res.ID))
-	api.WriteAlertsObj(w, r, http.StatusCreated, alerts, []tc.DeliveryServiceV40{*res})
+	if inf.Version.Major == 4 && inf.Version.Minor < 1 {
+		api.WriteAlertsObj(w, r, http.StatusCreated, alerts, []tc.DeliveryServiceV40{res.DeliveryServiceV40})
+		return
+	}
+	api.WriteAlertsObj(w, r, http.StatusCreated, alerts, []tc.DeliveryServiceV4{*res})
+}
+
+// CreateV4 creates the given Delivery Service as a request to create it in API
+// version 4 or later would, for Delivery Services created by Traffic Ops
+// itself on a user's behalf, e.g. from CDNi metadata.
+func CreateV4(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, ds tc.DeliveryServiceV4) (*tc.DeliveryServiceV4, int, error, error) {
+	return createV41(w, r, inf, ds, true)
+}
+
+// UpdateV4 updates the given Delivery Service, which must have an ID, as a
+// request to update it in API version 4 or later would.
+func UpdateV4(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, ds tc.DeliveryServiceV4) (*tc.DeliveryServiceV4, int, error, error) {
+	return updateV41(w, r, inf, &ds, true)
 }
 
 func createV30(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV30 tc.DeliveryServiceV30) (*tc.DeliveryServiceV30, int, error, error) {
@@ -207,12 +228,12 @@ func createV31(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV31 t
 	tx := inf.Tx.Tx
 	dsNullable := tc.DeliveryServiceNullableV30(dsV31)
 	ds := dsNullable.UpgradeToV4()
-	res, status, userErr, sysErr := createV40(w, r, inf, tc.DeliveryServiceV40(ds), false)
+	res, status, userErr, sysErr := createV41(w, r, inf, ds, false)
 	if res == nil {
 		return nil, status, userErr, sysErr
 	}
 
-	ds = tc.DeliveryServiceV4(*res)
+	ds = *res
 	if dsV31.CacheURL != nil {
 		_, err := tx.Exec("UPDATE deliveryservice SET cacheurl = $1 WHERE ID = $2",
 			&dsV31.CacheURL,
@@ -260,11 +281,64 @@ func recreateTLSVersions(versions []string, dsid int, tx *sql.Tx) error {
 	return nil
 }
 
+const upsertCacheKeyPolicyQuery = `
+INSERT INTO public.deliveryservice_cache_key_policy (deliveryservice, policy)
+VALUES ($1, $2)
+ON CONFLICT (deliveryservice) DO UPDATE SET policy = EXCLUDED.policy, last_updated = now()
+`
+
+// GetDSCacheKeyPolicy retrieves the cache key policy of the Delivery Service
+// with the given ID, which is nil if it has none.
+func GetDSCacheKeyPolicy(dsID int, tx *sql.Tx) (*tc.CacheKeyPolicy, error) {
+	var policyBts []byte
+	err := tx.QueryRow(`SELECT policy FROM public.deliveryservice_cache_key_policy WHERE deliveryservice = $1`, dsID).Scan(&policyBts)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	policy := tc.CacheKeyPolicy{}
+	if err := json.Unmarshal(policyBts, &policy); err != nil {
+		return nil, fmt.Errorf("decoding cache key policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// hasJSONField returns whether the JSON object in body has the given field,
+// even if its value is null.
+func hasJSONField(body []byte, field string) bool {
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return false
+	}
+	_, ok := obj[field]
+	return ok
+}
+
+// setCacheKeyPolicy sets the cache key policy of the Delivery Service with the
+// given ID, removing any existing policy if policy is nil.
+func setCacheKeyPolicy(policy *tc.CacheKeyPolicy, dsid int, tx *sql.Tx) error {
+	if policy == nil {
+		if _, err := tx.Exec(`DELETE FROM public.deliveryservice_cache_key_policy WHERE deliveryservice = $1`, dsid); err != nil {
+			return fmt.Errorf("removing cache key policy for DS #%d: %w", dsid, err)
+		}
+		return nil
+	}
+	policyBts, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("encoding cache key policy: %w", err)
+	}
+	if _, err := tx.Exec(upsertCacheKeyPolicyQuery, dsid, policyBts); err != nil {
+		return fmt.Errorf("setting cache key policy for DS #%d: %w", dsid, err)
+	}
+	return nil
+}
+
 // create creates the given ds in the database, and returns the DS with its id and other fields created on insert set. On error, the HTTP status code, user error, and system error are returned. The status code SHOULD NOT be used, if both errors are nil.
-func createV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 tc.DeliveryServiceV40, omitExtraLongDescFields bool) (*tc.DeliveryServiceV40, int, error, error) {
+func createV41(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV41 tc.DeliveryServiceV41, omitExtraLongDescFields bool) (*tc.DeliveryServiceV41, int, error, error) {
 	user := inf.User
 	tx := inf.Tx.Tx
-	ds := tc.DeliveryServiceV4(dsV40)
+	ds := tc.DeliveryServiceV4(dsV41)
 	err := Validate(tx, &ds)
 	var geoLimitCountries string
 	if err != nil {
@@ -465,6 +539,12 @@ func createV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 t
 		return nil, http.StatusInternalServerError, nil, fmt.Errorf("creating TLS versions for new Delivery Service: %w", err)
 	}
 
+	if ds.CacheKeyPolicy != nil {
+		if err := setCacheKeyPolicy(ds.CacheKeyPolicy, *ds.ID, tx); err != nil {
+			return nil, http.StatusInternalServerError, nil, fmt.Errorf("creating cache key policy for new Delivery Service: %w", err)
+		}
+	}
+
 	if err := createDefaultRegex(tx, *ds.ID, *ds.XMLID); err != nil {
 		return nil, http.StatusInternalServerError, nil, errors.New("creating default regex: " + err.Error())
 	}
@@ -513,16 +593,16 @@ func createV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 t
 		return nil, http.StatusInternalServerError, nil, errors.New("error writing to audit log: " + err.Error())
 	}
 
-	dsV40 = ds
+	dsV41 = ds
 
 	if inf.Config.TrafficVaultEnabled && ds.Protocol != nil && (*ds.Protocol == tc.DSProtocolHTTPS || *ds.Protocol == tc.DSProtocolHTTPAndHTTPS || *ds.Protocol == tc.DSProtocolHTTPToHTTPS) {
-		err, errCode := GeneratePlaceholderSelfSignedCert(dsV40, inf, r.Context())
+		err, errCode := GeneratePlaceholderSelfSignedCert(dsV41, inf, r.Context())
 		if err != nil || errCode != http.StatusOK {
 			return nil, errCode, nil, fmt.Errorf("creating self signed default cert: %v", err)
 		}
 	}
 
-	return &dsV40, http.StatusOK, nil, nil
+	return &dsV41, http.StatusOK, nil, nil
 }
 
 func createDefaultRegex(tx *sql.Tx, dsID int, xmlID string) error {
@@ -570,8 +650,10 @@ func (ds *TODeliveryService) Read(h http.Header, useIMS bool) ([]interface{}, er
 	for _, ds := range dses {
 		switch {
 		// NOTE: it's required to handle minor version cases in a descending >= manner
-		case version.Major > 3:
+		case version.Major > 4 || version.Major == 4 && version.Minor >= 1:
 			returnable = append(returnable, ds.RemoveLD1AndLD2())
+		case version.Major == 4:
+			returnable = append(returnable, ds.RemoveLD1AndLD2().DeliveryServiceV40)
 		case version.Major >= 3 && version.Minor >= 1:
 			returnable = append(returnable, ds.DowngradeToV31())
 		case version.Major >= 3:
@@ -639,12 +721,27 @@ func UpdateV40(w http.ResponseWriter, r *http.Request) {
 	defer inf.Close()
 	id := inf.IntParams["id"]
 
-	ds := tc.DeliveryServiceV40{}
-	if err := json.NewDecoder(r.Body).Decode(&ds); err != nil {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("reading request body: "+err.Error()), nil)
+		return
+	}
+	ds := tc.DeliveryServiceV4{}
+	if err := json.Unmarshal(body, &ds); err != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("malformed JSON: "+err.Error()), nil)
 		return
 	}
 	ds.ID = &id
+
+	// The cache key policy is only replaced (or removed, by an explicit null)
+	// by requests in an API version that has it; otherwise, the existing
+	// policy is kept.
+	if inf.Version.Major == 4 && inf.Version.Minor < 1 || !hasJSONField(body, "cacheKeyPolicy") {
+		if ds.CacheKeyPolicy, err = GetDSCacheKeyPolicy(id, inf.Tx.Tx); err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("getting cache key policy for DS #%d: %w", id, err))
+			return
+		}
+	}
 	_, cdn, _, err := dbhelpers.GetDSNameAndCDNFromID(inf.Tx.Tx, id)
 	if err != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("deliveryservice update: getting CDN from DS ID "+err.Error()))
@@ -655,7 +752,7 @@ func UpdateV40(w http.ResponseWriter, r *http.Request) {
 		api.HandleErr(w, r, inf.Tx.Tx, statusCode, userErr, sysErr)
 		return
 	}
-	res, status, userErr, sysErr := updateV40(w, r, inf, &ds, true)
+	res, status, userErr, sysErr := updateV41(w, r, inf, &ds, true)
 	if userErr != nil || sysErr != nil {
 		api.HandleErr(w, r, inf.Tx.Tx, status, userErr, sysErr)
 		return
@@ -663,7 +760,11 @@ func UpdateV40(w http.ResponseWriter, r *http.Request) {
 	alerts := res.TLSVersionsAlerts()
 	alerts.AddNewAlert(tc.SuccessLevel, "Delivery Service update was successful")
 
-	api.WriteAlertsObj(w, r, http.StatusOK, alerts, []tc.DeliveryServiceV40{*res})
+	if inf.Version.Major == 4 && inf.Version.Minor < 1 {
+		api.WriteAlertsObj(w, r, http.StatusOK, alerts, []tc.DeliveryServiceV40{res.DeliveryServiceV40})
+		return
+	}
+	api.WriteAlertsObj(w, r, http.StatusOK, alerts, []tc.DeliveryServiceV4{*res})
 }
 
 func updateV30(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV30 *tc.DeliveryServiceV30) (*tc.DeliveryServiceV30, int, error, error) {
@@ -693,18 +794,21 @@ WHERE
 func updateV31(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV31 *tc.DeliveryServiceV31) (*tc.DeliveryServiceV31, int, error, error) {
 	dsNull := tc.DeliveryServiceNullableV30(*dsV31)
 	ds := dsNull.UpgradeToV4()
-	dsV40 := ds
-	if dsV40.ID == nil {
+	dsV41 := ds
+	if dsV41.ID == nil {
 		return nil, http.StatusInternalServerError, nil, errors.New("cannot update a Delivery Service with nil ID")
 	}
 
 	tx := inf.Tx.Tx
 	var sysErr error
-	if dsV40.TLSVersions, sysErr = GetDSTLSVersions(*dsV40.ID, tx); sysErr != nil {
-		return nil, http.StatusInternalServerError, nil, fmt.Errorf("getting TLS versions for DS #%d in API version < 4.0: %w", *dsV40.ID, sysErr)
+	if dsV41.TLSVersions, sysErr = GetDSTLSVersions(*dsV41.ID, tx); sysErr != nil {
+		return nil, http.StatusInternalServerError, nil, fmt.Errorf("getting TLS versions for DS #%d in API version < 4.0: %w", *dsV41.ID, sysErr)
+	}
+	if dsV41.CacheKeyPolicy, sysErr = GetDSCacheKeyPolicy(*dsV41.ID, tx); sysErr != nil {
+		return nil, http.StatusInternalServerError, nil, fmt.Errorf("getting cache key policy for DS #%d in API version < 4.1: %w", *dsV41.ID, sysErr)
 	}
 
-	res, status, usrErr, sysErr := updateV40(w, r, inf, &dsV40, false)
+	res, status, usrErr, sysErr := updateV41(w, r, inf, &dsV41, false)
 	if res == nil || usrErr != nil || sysErr != nil {
 		return nil, status, usrErr, sysErr
 	}
@@ -726,10 +830,10 @@ func updateV31(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV31 *
 	oldRes := tc.DeliveryServiceV31(ds.DowngradeToV31())
 	return &oldRes, http.StatusOK, nil, nil
 }
-func updateV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 *tc.DeliveryServiceV40, omitExtraLongDescFields bool) (*tc.DeliveryServiceV40, int, error, error) {
+func updateV41(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV41 *tc.DeliveryServiceV41, omitExtraLongDescFields bool) (*tc.DeliveryServiceV41, int, error, error) {
 	tx := inf.Tx.Tx
 	user := inf.User
-	ds := tc.DeliveryServiceV4(*dsV40)
+	ds := tc.DeliveryServiceV4(*dsV41)
 	if err := Validate(tx, &ds); err != nil {
 		return nil, http.StatusBadRequest, errors.New("invalid request: " + err.Error()), nil
 	}
@@ -994,6 +1098,10 @@ func updateV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 *
 		return nil, http.StatusInternalServerError, nil, fmt.Errorf("updating TLS versions for DS #%d: %w", *ds.ID, err)
 	}
 
+	if err := setCacheKeyPolicy(ds.CacheKeyPolicy, *ds.ID, tx); err != nil {
+		return nil, http.StatusInternalServerError, nil, err
+	}
+
 	newDSType, err := getTypeFromID(*ds.TypeID, tx)
 	if err != nil {
 		return nil, http.StatusInternalServerError, nil, errors.New("getting delivery service type after update: " + err.Error())
@@ -1044,16 +1152,16 @@ func updateV40(w http.ResponseWriter, r *http.Request, inf *api.APIInfo, dsV40 *
 		return nil, http.StatusInternalServerError, nil, errors.New("writing change log entry: " + err.Error())
 	}
 
-	dsV40 = (*tc.DeliveryServiceV40)(&ds)
+	dsV41 = (*tc.DeliveryServiceV41)(&ds)
 
 	if inf.Config.TrafficVaultEnabled && ds.Protocol != nil && (*ds.Protocol == tc.DSProtocolHTTPS || *ds.Protocol == tc.DSProtocolHTTPAndHTTPS || *ds.Protocol == tc.DSProtocolHTTPToHTTPS) {
-		err, errCode := GeneratePlaceholderSelfSignedCert(*dsV40, inf, r.Context())
+		err, errCode := GeneratePlaceholderSelfSignedCert(*dsV41, inf, r.Context())
 		if err != nil || errCode != http.StatusOK {
 			return nil, errCode, nil, fmt.Errorf("creating self signed default cert: %v", err)
 		}
 	}
 
-	return dsV40, http.StatusOK, nil, nil
+	return dsV41, http.StatusOK, nil, nil
 }
 
 // Delete is the DeliveryService implementation of the Deleter interface.
@@ -1286,6 +1394,9 @@ func Validate(tx *sql.Tx, ds *tc.DeliveryServiceV4) error {
 	if err := validateGeoLimitCountries(ds); err != nil {
 		errs = append(errs, err)
 	}
+	if err := ds.CacheKeyPolicy.Validate(); err != nil {
+		errs = append(errs, errors.New("cacheKeyPolicy: "+err.Error()))
+	}
 	if err := validateTopologyFields(ds); err != nil {
 		errs = append(errs, err)
 	}
@@ -1584,6 +1695,7 @@ func GetDeliveryServices(query string, queryValues map[string]interface{}, tx *s
 	for rows.Next() {
 		ds := tc.DeliveryServiceV4{}
 		cdnDomain := ""
+		cacheKeyPolicy := []byte(nil)
 		err := rows.Scan(&ds.Active,
 			&ds.AnonymousBlockingEnabled,
 			&ds.CCRDNSTTL,
@@ -1653,12 +1765,20 @@ func GetDeliveryServices(query string, queryValues map[string]interface{}, tx *s
 			&ds.Type,
 			&ds.TypeID,
 			&ds.XMLID,
-			&cdnDomain)
+			&cdnDomain,
+			&cacheKeyPolicy)
 
 		if err != nil {
 			return nil, nil, fmt.Errorf("getting delivery services: %v", err), http.StatusInternalServerError
 		}
 
+		if cacheKeyPolicy != nil {
+			ds.CacheKeyPolicy = &tc.CacheKeyPolicy{}
+			if err := json.Unmarshal(cacheKeyPolicy, ds.CacheKeyPolicy); err != nil {
+				return nil, nil, fmt.Errorf("decoding delivery service '%s' cache key policy: %v", *ds.XMLID, err), http.StatusInternalServerError
+			}
+		}
+
 		if geoLimitCountries != nil && *geoLimitCountries != "" {
 			geo := strings.Split(*geoLimitCountries, ",")
 			ds.GeoLimitCountries = geo
@@ -2191,7 +2311,8 @@ ds.active,
 	type.name,
 	ds.type AS type_id,
 	ds.xml_id,
-	cdn.domain_name AS cdn_domain
+	cdn.domain_name AS cdn_domain,
+	(SELECT ckp.policy FROM deliveryservice_cache_key_policy ckp WHERE ckp.deliveryservice = ds.id) AS cache_key_policy
 FROM deliveryservice AS ds
 JOIN type ON ds.type = type.id
 JOIN cdn ON ds.cdn_id = cdn.id
@@ -334,6 +334,7 @@ func TestReadGetDeliveryServices(t *testing.T) {
 		"type_id",
 		"xml_id",
 		"cdn_domain",
+		"cache_key_policy",
 	})
 	dsRows.AddRow(
 		true,
@@ -406,17 +407,69 @@ func TestReadGetDeliveryServices(t *testing.T) {
 		1,
 		"demo1",
 		"mycdn.ciab.test",
+		[]byte(`{"includeParams":["a","b"],"sortParams":true}`),
 	)
 	mock.ExpectQuery("^SELECT.*ORDER BY ds.xml_id$").WillReturnRows(dsRows)
 	regexRows := sqlmock.NewRows([]string{"ds_name", "type", "pattern", "set_number"})
 	regexRows.AddRow("demo1", "hostregexp", "", 0)
 	mock.ExpectQuery("SELECT ds\\.xml_id as ds_name, t\\.name as type, r\\.pattern, COALESCE\\(dsr\\.set_number, 0\\) FROM regex").WillReturnRows(regexRows)
 
-	_, userErr, sysErr, _, _ := readGetDeliveryServices(nil, nil, db.MustBegin(), &u, false)
+	dses, userErr, sysErr, _, _ := readGetDeliveryServices(nil, nil, db.MustBegin(), &u, false)
 	if userErr != nil {
 		t.Errorf("Unexpected user error reading Delivery Services: %v", userErr)
 	}
 	if sysErr != nil {
 		t.Errorf("Unexpected system error reading Delivery Services: %v", sysErr)
 	}
+	if len(dses) != 1 {
+		t.Fatalf("Expected 1 Delivery Service, got: %d", len(dses))
+	}
+	if policy := dses[0].CacheKeyPolicy; policy == nil || !policy.SortParams || len(policy.IncludeParams) != 2 {
+		t.Errorf("Expected cache key policy including 2 sorted params, got: %+v", policy)
+	}
+}
+
+func TestGetDSCacheKeyPolicy(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer mockDB.Close()
+
+	db := sqlx.NewDb(mockDB, "sqlmock")
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT policy FROM public.deliveryservice_cache_key_policy").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"policy"}).AddRow([]byte(`{"sortParams":true}`)))
+	mock.ExpectQuery("SELECT policy FROM public.deliveryservice_cache_key_policy").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"policy"}))
+	tx := db.MustBegin().Tx
+
+	policy, err := GetDSCacheKeyPolicy(1, tx)
+	if err != nil {
+		t.Fatalf("Unexpected error getting cache key policy: %v", err)
+	}
+	if policy == nil || !policy.SortParams {
+		t.Errorf("Expected cache key policy sorting params, got: %+v", policy)
+	}
+
+	policy, err = GetDSCacheKeyPolicy(2, tx)
+	if err != nil {
+		t.Fatalf("Unexpected error getting missing cache key policy: %v", err)
+	}
+	if policy != nil {
+		t.Errorf("Expected no cache key policy, got: %+v", policy)
+	}
+}
+
+func TestHasJSONField(t *testing.T) {
+	body := []byte(`{"xmlId":"demo1","cacheKeyPolicy":null}`)
+	if !hasJSONField(body, "cacheKeyPolicy") {
+		t.Error("Expected a null field to be present")
+	}
+	if hasJSONField(body, "tlsVersions") {
+		t.Error("Expected an omitted field to be absent")
+	}
+	if hasJSONField([]byte(`[]`), "cacheKeyPolicy") {
+		t.Error("Expected a non-object to have no fields")
+	}
 }
@@ -81,20 +81,23 @@ func CreateDNSSECKeys(exampleURLs []string, cdnKeys tc.DNSSECKeySetV11, kskExpir
 	zExpiration := inception.Add(zskExpiration)
 	kExpiration := inception.Add(kskExpiration)
 
+	// Delivery Service keys use the algorithm of the CDN's keys.
+	algorithm := GetDNSSECKeysAlgorithm(cdnKeys.KSK, tc.DefaultDNSSECAlgorithm)
+
 	tld := false
 	effectiveDate := inception
-	zsk, err := GetDNSSECKeysV11(tc.DNSSECZSKType, dsName, ttl, inception, zExpiration, tc.DNSSECKeyStatusNew, effectiveDate, tld)
+	zsk, err := GetDNSSECKeysV11(tc.DNSSECZSKType, dsName, ttl, inception, zExpiration, tc.DNSSECKeyStatusNew, effectiveDate, tld, algorithm)
 	if err != nil {
 		return tc.DNSSECKeySetV11{}, errors.New("getting DNSSEC keys for ZSK: " + err.Error())
 	}
-	ksk, err := GetDNSSECKeysV11(tc.DNSSECKSKType, dsName, ttl, inception, kExpiration, tc.DNSSECKeyStatusNew, effectiveDate, tld)
+	ksk, err := GetDNSSECKeysV11(tc.DNSSECKSKType, dsName, ttl, inception, kExpiration, tc.DNSSECKeyStatusNew, effectiveDate, tld, algorithm)
 	if err != nil {
 		return tc.DNSSECKeySetV11{}, errors.New("getting DNSSEC keys for KSK: " + err.Error())
 	}
 	return tc.DNSSECKeySetV11{ZSK: []tc.DNSSECKeyV11{zsk}, KSK: []tc.DNSSECKeyV11{ksk}}, nil
 }
 
-func GetDNSSECKeysV11(keyType string, dsName string, ttl time.Duration, inception time.Time, expiration time.Time, status string, effectiveDate time.Time, tld bool) (tc.DNSSECKeyV11, error) {
+func GetDNSSECKeysV11(keyType string, dsName string, ttl time.Duration, inception time.Time, expiration time.Time, status string, effectiveDate time.Time, tld bool, algorithm uint8) (tc.DNSSECKeyV11, error) {
 	key := tc.DNSSECKeyV11{
 		InceptionDateUnix:  inception.Unix(),
 		ExpirationDateUnix: expiration.Unix(),
@@ -105,21 +108,41 @@ func GetDNSSECKeysV11(keyType string, dsName string, ttl time.Duration, inceptio
 	}
 	isKSK := keyType != tc.DNSSECZSKType
 	err := error(nil)
-	key.Public, key.Private, key.DSRecord, err = genKeys(dsName, isKSK, ttl, tld)
+	key.Public, key.Private, key.DSRecord, err = genKeys(dsName, isKSK, ttl, tld, algorithm)
 	return key, err
 }
 
+// dnssecKeyBits returns the size in bits of keys generated with the given algorithm.
+func dnssecKeyBits(algorithm uint8, ksk bool) (int, error) {
+	switch algorithm {
+	case dns.RSASHA1:
+		// emulates the old Perl Traffic Ops key sizes
+		if ksk {
+			return 2048, nil
+		}
+		return 1024, nil
+	case dns.RSASHA256:
+		return 2048, nil
+	case dns.ECDSAP256SHA256, dns.ED25519:
+		return 256, nil
+	case dns.ECDSAP384SHA384:
+		return 384, nil
+	}
+	return 0, fmt.Errorf("unsupported DNSSEC algorithm %d", algorithm)
+}
+
 // genKeys generates keys for DNSSEC for a delivery service. Returns the public key, private key, and DS record (which will be nil if ksk or tld is false).
 // This emulates the old Perl Traffic Ops behavior: the public key is of the RFC1035 single-line zone file format, base64 encoded; the private key is of the BIND private-key-file format, base64 encoded; the DSRecord contains the algorithm, digest type, and digest.
-func genKeys(dsName string, ksk bool, ttl time.Duration, tld bool) (string, string, *tc.DNSSECKeyDSRecordV11, error) {
-	bits := 1024
+func genKeys(dsName string, ksk bool, ttl time.Duration, tld bool, algorithm uint8) (string, string, *tc.DNSSECKeyDSRecordV11, error) {
 	flags := 256
-	algorithm := dns.RSASHA1 // 5 - http://www.iana.org/assignments/dns-sec-alg-numbers/dns-sec-alg-numbers.xhtml
 	protocol := 3
 
 	if ksk {
 		flags |= 1
-		bits *= 2
+	}
+	bits, err := dnssecKeyBits(algorithm, ksk)
+	if err != nil {
+		return "", "", nil, err
 	}
 
 	// Note: currently, the Router appears to hard-code this in what it generates for the DS record (or at least the "Publish this" log message).
@@ -135,7 +158,7 @@ func genKeys(dsName string, ksk bool, ttl time.Duration, tld bool) (string, stri
 		},
 		Flags:     uint16(flags),
 		Protocol:  uint8(protocol),
-		Algorithm: algorithm,
+		Algorithm: algorithm, // http://www.iana.org/assignments/dns-sec-alg-numbers/dns-sec-alg-numbers.xhtml
 	}
 
 	priKey, err := dnskey.Generate(bits)
@@ -196,6 +219,38 @@ func getKeyExpiration(keys []tc.DNSSECKeyV11, defaultExpiration time.Duration) t
 	return defaultExpiration
 }
 
+// GetDNSSECKeyAlgorithm returns the algorithm of the given key, from its public key record.
+func GetDNSSECKeyAlgorithm(key tc.DNSSECKeyV11) (uint8, error) {
+	publicKeyBts, err := base64.StdEncoding.DecodeString(strings.Replace(key.Public, "\n", "", -1))
+	if err != nil {
+		return 0, fmt.Errorf("decoding public key base64: %w", err)
+	}
+	// the public key is the RFC 1035 single-line zone file format: "name ttl IN DNSKEY flags protocol algorithm keyBytes".
+	fields := strings.Fields(string(publicKeyBts))
+	if len(fields) < 8 {
+		return 0, errors.New("malformed public key: not enough fields")
+	}
+	algorithm, err := strconv.ParseUint(fields[6], 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("malformed public key: can't parse algorithm '%s' as uint8: %w", fields[6], err)
+	}
+	return uint8(algorithm), nil
+}
+
+// GetDNSSECKeysAlgorithm returns the algorithm of the first key with the status 'new' in keys, or defaultAlgorithm if there is no such key or its algorithm can't be determined.
+func GetDNSSECKeysAlgorithm(keys []tc.DNSSECKeyV11, defaultAlgorithm uint8) uint8 {
+	for _, key := range keys {
+		if key.Status != tc.DNSSECKeyStatusNew {
+			continue
+		}
+		if algorithm, err := GetDNSSECKeyAlgorithm(key); err == nil {
+			return algorithm
+		}
+		break
+	}
+	return defaultAlgorithm
+}
+
 func getKeyTTL(keys []tc.DNSSECKeyV11, defaultTTL time.Duration) time.Duration {
 	for _, key := range keys {
 		if key.Status != tc.DNSSECKeyStatusNew {
@@ -234,7 +289,20 @@ func MakeDNSSECKeysFromTrafficVaultKeys(riakKeys tc.DNSSECKeysTrafficVault, dsTT
 	return tc.DNSSECKeys(keys), nil
 }
 
+// MakeDSRecordText returns the text of the DS record of the KSK, in the RFC 1035 zone file format.
 func MakeDSRecordText(ksk tc.DNSSECKeyV11, ttl time.Duration) (string, error) {
+	ds, err := MakeDSRecord(ksk, ttl)
+	if err != nil {
+		return "", err
+	}
+	return ds.String(), nil
+}
+
+// MakeDSRecord returns the DS record of the KSK, with the given TTL.
+func MakeDSRecord(ksk tc.DNSSECKeyV11, ttl time.Duration) (*dns.DS, error) {
+	if ksk.DSRecord == nil {
+		return nil, errors.New("ksk has no DS record")
+	}
 	kskPublic := strings.Replace(ksk.Public, `\n`, "", -1) // note this is replacing the actual string slash-n not a newline. Because Perl.
 	kskPublic = strings.Replace(kskPublic, "\n", "", -1)
 	kskPublicBts := []byte(kskPublic)
@@ -242,24 +310,24 @@ func MakeDSRecordText(ksk tc.DNSSECKeyV11, ttl time.Duration) (string, error) {
 	publicKeyBts := make([]byte, publicKeyBtsLen)
 	publicKeyBtsLen, err := base64.StdEncoding.Decode(publicKeyBts, kskPublicBts)
 	if err != nil {
-		return "", fmt.Errorf("decoding ksk public key base64: %w", err)
+		return nil, fmt.Errorf("decoding ksk public key base64: %w", err)
 	}
 	publicKeyBts = publicKeyBts[:publicKeyBtsLen]
 
 	// ksk.Public isn't just the public key, it's the RFC 1035 single-line zone file format: "name ttl IN DNSKEY flags protocol algorithm keyBytes".
 	fields := strings.Fields(string(publicKeyBts))
 	if len(fields) < 8 {
-		return "", errors.New("malformed ksk public key: not enough fields")
+		return nil, errors.New("malformed ksk public key: not enough fields")
 	}
 	flagsStr := fields[4]
 	protocolStr := fields[5]
 	flags, err := strconv.ParseUint(flagsStr, 10, 16)
 	if err != nil {
-		return "", fmt.Errorf("malformed ksk public key: can't parse flags '%s' as uint16: %w", flagsStr, err)
+		return nil, fmt.Errorf("malformed ksk public key: can't parse flags '%s' as uint16: %w", flagsStr, err)
 	}
 	protocol, err := strconv.ParseUint(protocolStr, 10, 8)
 	if err != nil {
-		return "", fmt.Errorf("malformed ksk public key: can't parse protocol '%s' as uint8: %w", protocolStr, err)
+		return nil, fmt.Errorf("malformed ksk public key: can't parse protocol '%s' as uint8: %w", protocolStr, err)
 	}
 
 	realPublicKey := fields[7] // the Riak ksk.Public key is actually the RFC1035 single-line zone file format. For which the 7th field from 0 is the actual public key.
@@ -279,7 +347,7 @@ func MakeDSRecordText(ksk tc.DNSSECKeyV11, ttl time.Duration) (string, error) {
 
 	ds := dnsKey.ToDS(uint8(ksk.DSRecord.DigestType))
 	if ds == nil {
-		return "", errors.New("failed to convert DNSKEY to DS record (is some field in the KSK invalid?)")
+		return nil, errors.New("failed to convert DNSKEY to DS record (is some field in the KSK invalid?)")
 	}
-	return ds.String(), nil
+	return ds, nil
 }
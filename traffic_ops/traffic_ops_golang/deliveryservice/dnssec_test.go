@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"math"
 	"testing"
+	"time"
 
 	"github.com/apache/trafficcontrol/lib/go-tc"
 )
@@ -83,3 +84,49 @@ func TestMakeDSRecordText(t *testing.T) {
 		t.Errorf("Unexpected error for a valid 'Public' field: %v", err)
 	}
 }
+
+func TestGetDNSSECKeysV11Algorithms(t *testing.T) {
+	inception := time.Now()
+	for _, algorithm := range []uint8{tc.DNSSECAlgorithmRSASHA256, tc.DNSSECAlgorithmECDSAP256SHA256, tc.DNSSECAlgorithmECDSAP384SHA384, tc.DNSSECAlgorithmED25519} {
+		ksk, err := GetDNSSECKeysV11(tc.DNSSECKSKType, "cdn.example.test.", time.Minute, inception, inception.Add(time.Hour), tc.DNSSECKeyStatusNew, inception, true, algorithm)
+		if err != nil {
+			t.Errorf("generating algorithm %d KSK: unexpected error: %v", algorithm, err)
+			continue
+		}
+		if keyAlgorithm, err := GetDNSSECKeyAlgorithm(ksk); err != nil {
+			t.Errorf("getting the algorithm of an algorithm %d KSK: unexpected error: %v", algorithm, err)
+		} else if keyAlgorithm != algorithm {
+			t.Errorf("expected KSK algorithm %d, actual %d", algorithm, keyAlgorithm)
+		}
+		if ksk.DSRecord == nil || ksk.DSRecord.Algorithm != int64(algorithm) {
+			t.Errorf("expected a top level KSK to have an algorithm %d DS record, actual %+v", algorithm, ksk.DSRecord)
+		}
+		if _, err := MakeDSRecordText(ksk, time.Hour); err != nil {
+			t.Errorf("making the DS record text of an algorithm %d KSK: unexpected error: %v", algorithm, err)
+		}
+	}
+
+	if _, err := GetDNSSECKeysV11(tc.DNSSECZSKType, "cdn.example.test.", time.Minute, inception, inception.Add(time.Hour), tc.DNSSECKeyStatusNew, inception, false, 3); err == nil {
+		t.Error("expected generating a key with unsupported algorithm 3 to yield an error")
+	}
+}
+
+func TestGetDNSSECKeysAlgorithm(t *testing.T) {
+	inception := time.Now()
+	ksk, err := GetDNSSECKeysV11(tc.DNSSECKSKType, "ds.cdn.example.test.", time.Minute, inception, inception.Add(time.Hour), tc.DNSSECKeyStatusNew, inception, false, tc.DNSSECAlgorithmECDSAP256SHA256)
+	if err != nil {
+		t.Fatalf("generating KSK: unexpected error: %v", err)
+	}
+	expired := ksk
+	expired.Status = tc.DNSSECKeyStatusExpired
+
+	if algorithm := GetDNSSECKeysAlgorithm(nil, tc.DefaultDNSSECAlgorithm); algorithm != tc.DefaultDNSSECAlgorithm {
+		t.Errorf("expected no keys to have the default algorithm %d, actual %d", tc.DefaultDNSSECAlgorithm, algorithm)
+	}
+	if algorithm := GetDNSSECKeysAlgorithm([]tc.DNSSECKeyV11{expired}, 0); algorithm != 0 {
+		t.Errorf("expected only expired keys to have the default algorithm 0, actual %d", algorithm)
+	}
+	if algorithm := GetDNSSECKeysAlgorithm([]tc.DNSSECKeyV11{expired, ksk}, 0); algorithm != tc.DNSSECAlgorithmECDSAP256SHA256 {
+		t.Errorf("expected algorithm %d, actual %d", tc.DNSSECAlgorithmECDSAP256SHA256, algorithm)
+	}
+}
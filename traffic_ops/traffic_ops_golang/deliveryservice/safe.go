@@ -145,6 +145,10 @@ func UpdateSafe(w http.ResponseWriter, r *http.Request) {
 		default:
 			fallthrough
 		case 4:
+			if inf.Version.Major == 4 && inf.Version.Minor < 1 {
+				api.WriteRespAlertObj(w, r, tc.SuccessLevel, alertMsg, []tc.DeliveryServiceV40{dses[0].DeliveryServiceV40})
+				break
+			}
 			api.WriteRespAlertObj(w, r, tc.SuccessLevel, alertMsg, dses)
 		case 3:
 			if inf.Version.Minor >= 1 {
@@ -160,8 +160,7 @@ func LoginHandler(db *sqlx.DB, cfg config.Config) http.HandlerFunc {
 				var jwtSigned []byte
 				jwtBuilder := jwt.NewBuilder()
 
-				emptyConf := config.CdniConf{}
-				if cfg.Cdni != nil && *cfg.Cdni != emptyConf {
+				if cfg.Cdni != nil && cfg.Cdni.DCdnId != "" {
 					ucdn, err := auth.GetUserUcdn(form, db, dbCtx)
 					if err != nil {
 						// log but do not error out since this is optional in the JWT for CDNi integration
@@ -0,0 +1,454 @@
+package preview
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/auth"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/cachegroup"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/parameter"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/server"
+
+	"github.com/lib/pq"
+)
+
+const selectParametersByProfileQuery = `
+SELECT p.id, p.name, p.config_file, p.value, p.secure, p.last_updated, json_agg(pr.name) AS profiles
+FROM parameter p
+JOIN profile_parameter pp ON pp.parameter = p.id
+JOIN profile pr ON pr.id = pp.profile
+WHERE pr.name = ANY($1)
+GROUP BY p.id
+`
+
+const selectParametersByConfigFileQuery = `
+SELECT p.id, p.name, p.config_file, p.value, p.secure, p.last_updated, json_agg(pr.name) AS profiles
+FROM parameter p
+JOIN profile_parameter pp ON pp.parameter = p.id
+JOIN profile pr ON pr.id = pp.profile
+WHERE p.config_file = $1
+GROUP BY p.id
+`
+
+const selectDeliveryServiceServersQuery = `
+SELECT dss.server, dss.deliveryservice
+FROM deliveryservice_server dss
+JOIN deliveryservice ds ON ds.id = dss.deliveryservice
+WHERE ds.cdn_id = $1
+`
+
+const selectJobsQuery = `
+SELECT job.id,
+	job.asset_url,
+	u.username,
+	ds.xml_id,
+	job.ttl_hr,
+	job.invalidation_type,
+	job.start_time
+FROM job
+JOIN tm_user u ON job.job_user = u.id
+JOIN deliveryservice ds ON job.job_deliveryservice = ds.id
+WHERE ds.cdn_id = $1
+AND job.start_time >= NOW() - CAST(
+	(SELECT COALESCE(
+		(SELECT value
+		FROM parameter
+		WHERE name = 'maxRevalDurationDays'
+		AND config_file = 'regex_revalidate.config'
+		LIMIT 1),
+		'90'))
+	|| ' days' AS INTERVAL)
+`
+
+const selectDeliveryServiceRegexesQuery = `
+SELECT ds.xml_id, t.name, COALESCE(dsr.set_number, 0), r.pattern
+FROM deliveryservice_regex dsr
+JOIN deliveryservice ds ON ds.id = dsr.deliveryservice
+JOIN regex r ON r.id = dsr.regex
+JOIN type t ON t.id = r.type
+WHERE ds.cdn_id = $1
+ORDER BY ds.xml_id, dsr.set_number
+`
+
+const selectServerCapabilitiesQuery = `
+SELECT server, server_capability
+FROM server_server_capability
+`
+
+const selectDeliveryServiceRequiredCapabilitiesQuery = `
+SELECT deliveryservice_id, required_capability
+FROM deliveryservices_required_capability
+`
+
+const selectTopologiesQuery = `
+SELECT t.name, t.description, tc.id, tc.cachegroup,
+	(SELECT ARRAY_AGG(CAST(tcp.parent AS INT) ORDER BY tcp.rank ASC)
+	FROM topology_cachegroup_parents tcp
+	WHERE tcp.child = tc.id) AS parents
+FROM topology t
+JOIN topology_cachegroup tc ON t.name = tc.topology
+ORDER BY t.name, tc.id
+`
+
+// configData is the Traffic Ops data about a CDN from which the configuration
+// files of each of its cache servers are generated.
+type configData struct {
+	// cfgfile.ConfigData is the data common to all servers in the CDN. Its
+	// Server and ServerParams must be set for each server before generating.
+	cfgfile.ConfigData
+	// ProfileParams are the Parameters of every Profile used by a server in
+	// the CDN, from which each server's Parameters are layered.
+	ProfileParams []tc.Parameter
+}
+
+// getConfigData gets the same data from the database that t3c requests from
+// the Traffic Ops API to generate the configuration of cache servers in the
+// given CDN.
+func getConfigData(ctx context.Context, inf *api.APIInfo, cdn tc.CDN) (*configData, error) {
+	tx := inf.Tx.Tx
+	data := &configData{}
+	data.CDN = &cdn
+
+	servers, err := server.GetServersV40(inf.Tx, inf.User)
+	if err != nil {
+		return nil, errors.New("getting servers: " + err.Error())
+	}
+	data.Servers = make([]atscfg.Server, 0, len(servers))
+	for _, sv := range servers {
+		data.Servers = append(data.Servers, atscfg.Server(sv))
+	}
+
+	profileNames := []string{}
+	seenProfiles := map[string]struct{}{}
+	for _, sv := range servers {
+		if sv.CDNID == nil || *sv.CDNID != cdn.ID {
+			continue
+		}
+		for _, profileName := range sv.ProfileNames {
+			if _, ok := seenProfiles[profileName]; ok {
+				continue
+			}
+			seenProfiles[profileName] = struct{}{}
+			profileNames = append(profileNames, profileName)
+		}
+	}
+
+	canReadSecure := canReadSecureParameters(inf)
+	if data.GlobalParams, err = getParameters(tx, selectParametersByProfileQuery, pq.Array([]string{tc.GlobalProfileName}), canReadSecure); err != nil {
+		return nil, errors.New("getting global parameters: " + err.Error())
+	}
+	if data.ProfileParams, err = getParameters(tx, selectParametersByProfileQuery, pq.Array(profileNames), canReadSecure); err != nil {
+		return nil, errors.New("getting server profile parameters: " + err.Error())
+	}
+	if data.CacheKeyConfigParams, err = getParameters(tx, selectParametersByConfigFileQuery, "cachekey.config", canReadSecure); err != nil {
+		return nil, errors.New("getting cachekey.config parameters: " + err.Error())
+	}
+	if data.RemapConfigParams, err = getParameters(tx, selectParametersByConfigFileQuery, "remap.config", canReadSecure); err != nil {
+		return nil, errors.New("getting remap.config parameters: " + err.Error())
+	}
+	if data.ParentConfigParams, err = getParameters(tx, selectParametersByConfigFileQuery, "parent.config", canReadSecure); err != nil {
+		return nil, errors.New("getting parent.config parameters: " + err.Error())
+	}
+
+	if data.CacheGroups, err = getCacheGroups(inf); err != nil {
+		return nil, errors.New("getting cache groups: " + err.Error())
+	}
+
+	dses, userErr, sysErr, _ := deliveryservice.GetDeliveryServices(deliveryservice.SelectDeliveryServicesQuery+" WHERE ds.cdn_id = :cdn_id ORDER BY ds.xml_id", map[string]interface{}{"cdn_id": cdn.ID}, inf.Tx)
+	if userErr != nil || sysErr != nil {
+		return nil, fmt.Errorf("getting delivery services: %v %v", userErr, sysErr)
+	}
+	data.DeliveryServices = make([]atscfg.DeliveryService, 0, len(dses))
+	for _, ds := range dses {
+		data.DeliveryServices = append(data.DeliveryServices, atscfg.DeliveryService(ds))
+	}
+
+	if data.DeliveryServiceServers, err = getDeliveryServiceServers(tx, cdn.ID); err != nil {
+		return nil, errors.New("getting delivery service servers: " + err.Error())
+	}
+	if data.Jobs, err = getJobs(tx, cdn.ID); err != nil {
+		return nil, errors.New("getting jobs: " + err.Error())
+	}
+	if data.DeliveryServiceRegexes, err = getDeliveryServiceRegexes(tx, cdn.ID); err != nil {
+		return nil, errors.New("getting delivery service regexes: " + err.Error())
+	}
+	if data.ServerCapabilities, err = getCapabilities(tx, selectServerCapabilitiesQuery); err != nil {
+		return nil, errors.New("getting server capabilities: " + err.Error())
+	}
+	if data.DSRequiredCapabilities, err = getCapabilities(tx, selectDeliveryServiceRequiredCapabilitiesQuery); err != nil {
+		return nil, errors.New("getting delivery service required capabilities: " + err.Error())
+	}
+	if data.Topologies, err = getTopologies(tx); err != nil {
+		return nil, errors.New("getting topologies: " + err.Error())
+	}
+
+	if inf.Config.TrafficVaultEnabled {
+		if err := getKeys(ctx, inf, data); err != nil {
+			return nil, errors.New("getting keys from Traffic Vault: " + err.Error())
+		}
+	} else {
+		log.Warnln("config preview: Traffic Vault is not configured, generating without SSL, URL Sig, or URI Signing keys")
+	}
+
+	return data, nil
+}
+
+// canReadSecureParameters returns whether the current user may read the values
+// of secure Parameters, in the same way as the /parameters endpoint.
+func canReadSecureParameters(inf *api.APIInfo) bool {
+	if inf.Version.Major >= 4 && inf.Config.RoleBasedPermissions {
+		return inf.User.Can("PARAMETER-SECURE:READ")
+	}
+	return inf.User.PrivLevel >= auth.PrivLevelAdmin
+}
+
+func getParameters(tx *sql.Tx, query string, arg interface{}, canReadSecure bool) ([]tc.Parameter, error) {
+	rows, err := tx.Query(query, arg)
+	if err != nil {
+		return nil, errors.New("querying: " + err.Error())
+	}
+	defer log.Close(rows, "closing parameter rows")
+
+	params := []tc.Parameter{}
+	for rows.Next() {
+		param := tc.Parameter{}
+		profiles := []byte{}
+		if err := rows.Scan(&param.ID, &param.Name, &param.ConfigFile, &param.Value, &param.Secure, &param.LastUpdated, &profiles); err != nil {
+			return nil, errors.New("scanning: " + err.Error())
+		}
+		param.Profiles = json.RawMessage(profiles)
+		if param.Secure && !canReadSecure {
+			param.Value = parameter.HiddenField
+		}
+		params = append(params, param)
+	}
+	return params, rows.Err()
+}
+
+func getCacheGroups(inf *api.APIInfo) ([]tc.CacheGroupNullable, error) {
+	names := []string{}
+	if err := inf.Tx.Select(&names, `SELECT name FROM cachegroup`); err != nil {
+		return nil, errors.New("querying names: " + err.Error())
+	}
+	cgMap, userErr, sysErr, _ := cachegroup.GetCacheGroupsByName(names, inf.Tx)
+	if userErr != nil || sysErr != nil {
+		return nil, fmt.Errorf("%v %v", userErr, sysErr)
+	}
+	cgs := make([]tc.CacheGroupNullable, 0, len(cgMap))
+	for _, name := range names {
+		if cg, ok := cgMap[name]; ok {
+			cgs = append(cgs, cg)
+		}
+	}
+	return cgs, nil
+}
+
+func getDeliveryServiceServers(tx *sql.Tx, cdnID int) ([]atscfg.DeliveryServiceServer, error) {
+	rows, err := tx.Query(selectDeliveryServiceServersQuery, cdnID)
+	if err != nil {
+		return nil, errors.New("querying: " + err.Error())
+	}
+	defer log.Close(rows, "closing delivery service server rows")
+
+	dss := []atscfg.DeliveryServiceServer{}
+	for rows.Next() {
+		d := atscfg.DeliveryServiceServer{}
+		if err := rows.Scan(&d.Server, &d.DeliveryService); err != nil {
+			return nil, errors.New("scanning: " + err.Error())
+		}
+		dss = append(dss, d)
+	}
+	return dss, rows.Err()
+}
+
+func getJobs(tx *sql.Tx, cdnID int) ([]atscfg.InvalidationJob, error) {
+	rows, err := tx.Query(selectJobsQuery, cdnID)
+	if err != nil {
+		return nil, errors.New("querying: " + err.Error())
+	}
+	defer log.Close(rows, "closing job rows")
+
+	jobs := []atscfg.InvalidationJob{}
+	for rows.Next() {
+		job := atscfg.InvalidationJob{}
+		if err := rows.Scan(&job.ID, &job.AssetURL, &job.CreatedBy, &job.DeliveryService, &job.TTLHours, &job.InvalidationType, &job.StartTime); err != nil {
+			return nil, errors.New("scanning: " + err.Error())
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func getDeliveryServiceRegexes(tx *sql.Tx, cdnID int) ([]tc.DeliveryServiceRegexes, error) {
+	rows, err := tx.Query(selectDeliveryServiceRegexesQuery, cdnID)
+	if err != nil {
+		return nil, errors.New("querying: " + err.Error())
+	}
+	defer log.Close(rows, "closing delivery service regex rows")
+
+	dsRegexes := []tc.DeliveryServiceRegexes{}
+	for rows.Next() {
+		dsName := ""
+		regex := tc.DeliveryServiceRegex{}
+		if err := rows.Scan(&dsName, &regex.Type, &regex.SetNumber, &regex.Pattern); err != nil {
+			return nil, errors.New("scanning: " + err.Error())
+		}
+		if len(dsRegexes) == 0 || dsRegexes[len(dsRegexes)-1].DSName != dsName {
+			dsRegexes = append(dsRegexes, tc.DeliveryServiceRegexes{DSName: dsName})
+		}
+		last := &dsRegexes[len(dsRegexes)-1]
+		last.Regexes = append(last.Regexes, regex)
+	}
+	return dsRegexes, rows.Err()
+}
+
+// getCapabilities returns the capabilities of each server or delivery service,
+// from a query returning rows of IDs and capabilities.
+func getCapabilities(tx *sql.Tx, query string) (map[int]map[atscfg.ServerCapability]struct{}, error) {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, errors.New("querying: " + err.Error())
+	}
+	defer log.Close(rows, "closing capability rows")
+
+	caps := map[int]map[atscfg.ServerCapability]struct{}{}
+	for rows.Next() {
+		id := 0
+		capability := ""
+		if err := rows.Scan(&id, &capability); err != nil {
+			return nil, errors.New("scanning: " + err.Error())
+		}
+		if _, ok := caps[id]; !ok {
+			caps[id] = map[atscfg.ServerCapability]struct{}{}
+		}
+		caps[id][atscfg.ServerCapability(capability)] = struct{}{}
+	}
+	return caps, rows.Err()
+}
+
+func getTopologies(tx *sql.Tx) ([]tc.Topology, error) {
+	rows, err := tx.Query(selectTopologiesQuery)
+	if err != nil {
+		return nil, errors.New("querying: " + err.Error())
+	}
+	defer log.Close(rows, "closing topology rows")
+
+	topologies := []tc.Topology{}
+	for rows.Next() {
+		name := ""
+		description := ""
+		node := tc.TopologyNode{Parents: []int{}}
+		parents := pq.Int64Array{}
+		if err := rows.Scan(&name, &description, &node.Id, &node.Cachegroup, &parents); err != nil {
+			return nil, errors.New("scanning: " + err.Error())
+		}
+		for _, parent := range parents {
+			node.Parents = append(node.Parents, int(parent))
+		}
+		if len(topologies) == 0 || topologies[len(topologies)-1].Name != name {
+			topologies = append(topologies, tc.Topology{Name: name, Description: description, Nodes: []tc.TopologyNode{}})
+		}
+		last := &topologies[len(topologies)-1]
+		last.Nodes = append(last.Nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// parents are queried as node IDs, but Topologies reference their parents
+	// by index in the array of nodes.
+	for _, topology := range topologies {
+		indices := map[int]int{}
+		for i, node := range topology.Nodes {
+			indices[node.Id] = i
+		}
+		for _, node := range topology.Nodes {
+			for i, parent := range node.Parents {
+				node.Parents[i] = indices[parent]
+			}
+		}
+	}
+	return topologies, nil
+}
+
+// getKeys gets the SSL keys of the CDN, and the URL Sig and URI Signing keys of
+// its delivery services which use them. Delivery services with missing keys
+// are skipped, as t3c does.
+func getKeys(ctx context.Context, inf *api.APIInfo, data *configData) error {
+	sslKeys, err := inf.Vault.GetCDNSSLKeys(data.CDN.Name, inf.Tx.Tx, ctx)
+	if err != nil {
+		return errors.New("getting CDN SSL keys: " + err.Error())
+	}
+	data.SSLKeys = make([]tc.CDNSSLKeys, 0, len(sslKeys))
+	for _, key := range sslKeys {
+		data.SSLKeys = append(data.SSLKeys, tc.CDNSSLKeys{
+			DeliveryService: key.DeliveryService,
+			Hostname:        key.HostName,
+			Certificate:     tc.CDNSSLKeysCertificate{Crt: key.Certificate.Crt, Key: key.Certificate.Key},
+		})
+	}
+
+	data.URLSigKeys = map[tc.DeliveryServiceName]tc.URLSigKeys{}
+	data.URISigningKeys = map[tc.DeliveryServiceName][]byte{}
+	for _, ds := range data.DeliveryServices {
+		if ds.XMLID == nil || ds.SigningAlgorithm == nil {
+			continue
+		}
+		switch *ds.SigningAlgorithm {
+		case tc.SigningAlgorithmURLSig:
+			keys, ok, err := inf.Vault.GetURLSigKeys(*ds.XMLID, inf.Tx.Tx, ctx)
+			if err != nil {
+				return errors.New("getting URL Sig keys for delivery service '" + *ds.XMLID + "': " + err.Error())
+			} else if !ok {
+				log.Warnln("config preview: delivery service '" + *ds.XMLID + "' is url_sig, but keys not found! Skipping!")
+				continue
+			}
+			data.URLSigKeys[tc.DeliveryServiceName(*ds.XMLID)] = keys
+		case tc.SigningAlgorithmURISigning:
+			keys, ok, err := inf.Vault.GetURISigningKeys(*ds.XMLID, inf.Tx.Tx, ctx)
+			if err != nil {
+				return errors.New("getting URI Signing keys for delivery service '" + *ds.XMLID + "': " + err.Error())
+			} else if !ok {
+				log.Warnln("config preview: delivery service '" + *ds.XMLID + "' is uri_signing, but keys not found! Skipping!")
+				continue
+			}
+			data.URISigningKeys[tc.DeliveryServiceName(*ds.XMLID)] = keys
+		}
+	}
+	return nil
+}
+
+// isCache returns whether the given server is a cache, for which t3c
+// generates configuration files.
+func isCache(sv atscfg.Server) bool {
+	return sv.Type != "" && (strings.HasPrefix(sv.Type, tc.EdgeTypePrefix) || strings.HasPrefix(sv.Type, tc.MidTypePrefix))
+}
@@ -0,0 +1,86 @@
+package preview
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+
+	"github.com/kylelemons/godebug/diff"
+)
+
+// diffFiles returns the files which differ between those generated for a
+// server and those it last reported applying, sorted by path and name.
+func diffFiles(generated []cfgfile.ATSConfigFile, applied []tc.ServerConfigFile) []tc.ConfigFilePreview {
+	appliedFiles := map[string]tc.ServerConfigFile{}
+	for _, file := range applied {
+		appliedFiles[file.Path+"/"+file.Name] = file
+	}
+
+	previews := []tc.ConfigFilePreview{}
+	for _, gen := range generated {
+		key := gen.Path + "/" + gen.Name
+		old, ok := appliedFiles[key]
+		delete(appliedFiles, key)
+		if !ok {
+			previews = append(previews, tc.ConfigFilePreview{Name: gen.Name, Path: gen.Path, Status: tc.ConfigFilePreviewAdded})
+			continue
+		}
+		if tc.ServerConfigFileHash(gen.Text, gen.LineComment) == old.Hash {
+			continue
+		}
+		preview := tc.ConfigFilePreview{Name: gen.Name, Path: gen.Path, Status: tc.ConfigFilePreviewChanged}
+		if !gen.Secure && old.Text != nil {
+			lines := lineDiff(tc.NormalizeServerConfigFileText(*old.Text, old.LineComment), tc.NormalizeServerConfigFileText(gen.Text, gen.LineComment))
+			preview.Diff = &lines
+		}
+		previews = append(previews, preview)
+	}
+	for _, old := range appliedFiles {
+		previews = append(previews, tc.ConfigFilePreview{Name: old.Name, Path: old.Path, Status: tc.ConfigFilePreviewRemoved})
+	}
+
+	sort.Slice(previews, func(i, j int) bool {
+		if previews[i].Path != previews[j].Path {
+			return previews[i].Path < previews[j].Path
+		}
+		return previews[i].Name < previews[j].Name
+	})
+	return previews
+}
+
+// lineDiff returns the lines removed from oldText prefixed with "-", and the
+// lines added in newText prefixed with "+". Unchanged lines are omitted.
+func lineDiff(oldText string, newText string) string {
+	chunks := diff.DiffChunks(strings.Split(oldText, "\n"), strings.Split(newText, "\n"))
+	sb := strings.Builder{}
+	for _, chunk := range chunks {
+		for _, line := range chunk.Deleted {
+			sb.WriteString("-" + line + "\n")
+		}
+		for _, line := range chunk.Added {
+			sb.WriteString("+" + line + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,81 @@
+package preview
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func TestDiffFiles(t *testing.T) {
+	oldRemap := "# DO NOT EDIT - Generated at 1\nmap http://a/ http://o/\nmap http://b/ http://o/\n"
+	newRemap := "# DO NOT EDIT - Generated at 2\nmap http://a/ http://o/\nmap http://c/ http://o/\n"
+	sameRecords := "CONFIG proxy.config.foo INT 1\n"
+	oldKey := "old key"
+
+	applied := []tc.ServerConfigFile{
+		{Name: "remap.config", Path: "/etc/trafficserver", LineComment: "#", Hash: tc.ServerConfigFileHash(oldRemap, "#"), Text: &oldRemap},
+		{Name: "records.config", Path: "/etc/trafficserver", LineComment: "#", Hash: tc.ServerConfigFileHash("# header\n"+sameRecords, "#")},
+		{Name: "cache.config", Path: "/etc/trafficserver", LineComment: "#", Hash: "abc"},
+		{Name: "a.key", Path: "/etc/trafficserver/ssl", Hash: tc.ServerConfigFileHash(oldKey, ""), Text: &oldKey},
+	}
+	generated := []cfgfile.ATSConfigFile{
+		{Name: "remap.config", Path: "/etc/trafficserver", LineComment: "#", Text: newRemap},
+		{Name: "records.config", Path: "/etc/trafficserver", LineComment: "#", Text: "# other header\r\n" + sameRecords},
+		{Name: "a.key", Path: "/etc/trafficserver/ssl", Text: "new key", Secure: true},
+		{Name: "plugin.config", Path: "/etc/trafficserver", LineComment: "#", Text: "foo.so\n"},
+	}
+
+	previews := diffFiles(generated, applied)
+	if len(previews) != 4 {
+		t.Fatalf("diffFiles expected: 4 files, actual: %+v", previews)
+	}
+
+	expected := []struct {
+		name   string
+		status tc.ConfigFilePreviewStatus
+		diff   *string
+	}{
+		{"cache.config", tc.ConfigFilePreviewRemoved, nil},
+		{"plugin.config", tc.ConfigFilePreviewAdded, nil},
+		{"remap.config", tc.ConfigFilePreviewChanged, func() *string { s := "-map http://b/ http://o/\n+map http://c/ http://o/\n"; return &s }()},
+		{"a.key", tc.ConfigFilePreviewChanged, nil},
+	}
+	for i, exp := range expected {
+		actual := previews[i]
+		if actual.Name != exp.name || actual.Status != exp.status {
+			t.Errorf("diffFiles file %d expected: %s %s, actual: %s %s", i, exp.name, exp.status, actual.Name, actual.Status)
+		}
+		if (exp.diff == nil) != (actual.Diff == nil) || (exp.diff != nil && *exp.diff != *actual.Diff) {
+			t.Errorf("diffFiles file %s expected diff: %v, actual: %v", exp.name, exp.diff, actual.Diff)
+		}
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	if actual := lineDiff("a\nb\nc", "a\nb\nc"); actual != "" {
+		t.Errorf("lineDiff of equal text expected: empty, actual: %q", actual)
+	}
+	if expected, actual := "-b\n+d\n+e\n", lineDiff("a\nb\nc", "a\nd\ne\nc"); actual != expected {
+		t.Errorf("lineDiff expected: %q, actual: %q", expected, actual)
+	}
+}
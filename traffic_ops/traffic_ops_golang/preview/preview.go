@@ -0,0 +1,246 @@
+// Package preview provides a dry run of snapshotting and queuing updates on a
+// CDN, showing how its CRConfig and the configuration files of its cache
+// servers would change given the current Traffic Ops data.
+package preview
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+	"github.com/apache/trafficcontrol/lib/go-atscfg/cfgfile"
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/crconfig"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/server"
+)
+
+// TopologyQueryParam and CachegroupQueryParam limit the servers in a preview
+// to those in the cache groups of a Topology, or in a single cache group.
+const (
+	TopologyQueryParam   = "topology"
+	CachegroupQueryParam = "cachegroup"
+)
+
+// DefaultATSConfigDir is the ATS config directory used for generated files
+// without location Parameters, which is the default of t3c-apply.
+const DefaultATSConfigDir = "/opt/trafficserver/etc/trafficserver"
+
+const selectCDNQuery = `
+SELECT id, name, domain_name, dnssec_enabled, last_updated
+FROM cdn
+WHERE name = $1
+`
+
+const selectCachegroupExistsQuery = `SELECT EXISTS(SELECT 1 FROM cachegroup WHERE name = $1)`
+
+// Get is the handler for GET requests to /cdns/{{name}}/preview.
+func Get(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+	tx := inf.Tx.Tx
+
+	cdnName := inf.Params["name"]
+	cdn, ok, err := getCDN(tx, cdnName)
+	if err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("getting CDN '%s': %w", cdnName, err))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, tx, http.StatusNotFound, fmt.Errorf("CDN '%s' not found", cdnName), nil)
+		return
+	}
+
+	topologyName := inf.Params[TopologyQueryParam]
+	if topologyName != "" {
+		if ok, err := dbhelpers.TopologyExists(tx, topologyName); err != nil {
+			api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("checking topology '%s' existence: %w", topologyName, err))
+			return
+		} else if !ok {
+			api.HandleErr(w, r, tx, http.StatusBadRequest, fmt.Errorf("no such topology '%s'", topologyName), nil)
+			return
+		}
+	}
+	cachegroupName := inf.Params[CachegroupQueryParam]
+	if cachegroupName != "" {
+		exists := false
+		if err := tx.QueryRow(selectCachegroupExistsQuery, cachegroupName).Scan(&exists); err != nil {
+			api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("checking cache group '%s' existence: %w", cachegroupName, err))
+			return
+		} else if !exists {
+			api.HandleErr(w, r, tx, http.StatusBadRequest, fmt.Errorf("no such cache group '%s'", cachegroupName), nil)
+			return
+		}
+	}
+
+	preview := tc.CDNConfigPreview{CDNName: cdnName, Servers: []tc.ServerConfigPreview{}}
+
+	start := time.Now()
+	crcDiff, snapshotExists, err := diffCRConfig(inf, r, cdnName)
+	if err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("diffing CRConfig for CDN '%s': %w", cdnName, err))
+		return
+	}
+	preview.CRConfig = crcDiff
+	preview.SnapshotExists = snapshotExists
+	log.Infof("config preview: CRConfig diff for CDN '%s' took %v", cdnName, time.Since(start))
+
+	start = time.Now()
+	data, err := getConfigData(r.Context(), inf, cdn)
+	if err != nil {
+		api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("getting config data for CDN '%s': %w", cdnName, err))
+		return
+	}
+
+	cachegroups := map[string]struct{}(nil)
+	if cachegroupName != "" {
+		cachegroups = map[string]struct{}{cachegroupName: {}}
+	}
+	if topologyName != "" {
+		topologyCachegroups := map[string]struct{}{}
+		for _, topology := range data.Topologies {
+			if topology.Name != topologyName {
+				continue
+			}
+			for _, node := range topology.Nodes {
+				if cachegroups == nil {
+					topologyCachegroups[node.Cachegroup] = struct{}{}
+				} else if _, ok := cachegroups[node.Cachegroup]; ok {
+					topologyCachegroups[node.Cachegroup] = struct{}{}
+				}
+			}
+		}
+		cachegroups = topologyCachegroups
+	}
+
+	for _, sv := range data.Servers {
+		if sv.CDNID == nil || *sv.CDNID != cdn.ID || sv.HostName == nil || sv.ID == nil || !isCache(sv) {
+			continue
+		}
+		if cachegroups != nil {
+			if sv.Cachegroup == nil {
+				continue
+			}
+			if _, ok := cachegroups[*sv.Cachegroup]; !ok {
+				continue
+			}
+		}
+		serverPreview, err := previewServer(tx, data, sv)
+		if err != nil {
+			api.HandleErr(w, r, tx, http.StatusInternalServerError, nil, fmt.Errorf("previewing server '%s': %w", *sv.HostName, err))
+			return
+		}
+		if len(serverPreview.Files) == 0 && serverPreview.Error == "" {
+			preview.UnchangedServers++
+			continue
+		}
+		preview.Servers = append(preview.Servers, serverPreview)
+	}
+	sort.Slice(preview.Servers, func(i, j int) bool { return preview.Servers[i].HostName < preview.Servers[j].HostName })
+	log.Infof("config preview: server config diff for CDN '%s' took %v", cdnName, time.Since(start))
+
+	api.WriteResp(w, r, preview)
+}
+
+func getCDN(tx *sql.Tx, name string) (tc.CDN, bool, error) {
+	cdn := tc.CDN{}
+	if err := tx.QueryRow(selectCDNQuery, name).Scan(&cdn.ID, &cdn.Name, &cdn.DomainName, &cdn.DNSSECEnabled, &cdn.LastUpdated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cdn, false, nil
+		}
+		return cdn, false, err
+	}
+	return cdn, true, nil
+}
+
+// diffCRConfig returns the difference between the CDN's current snapshot and
+// the CRConfig which would be created by snapshotting it now, and whether the
+// CDN has a current snapshot.
+func diffCRConfig(inf *api.APIInfo, r *http.Request, cdnName string) (tc.CRConfigDiff, bool, error) {
+	snapshot, _, err := crconfig.GetSnapshot(inf.Tx.Tx, cdnName)
+	if err != nil {
+		return tc.CRConfigDiff{}, false, errors.New("getting snapshot: " + err.Error())
+	}
+	emulate := inf.Config.CRConfigEmulateOldPath || inf.Version.Major < 4
+	diff, err := crconfig.DiffSnapshot(inf.Tx.Tx, snapshot, cdnName, inf.User.UserName, r.Host, inf.Config.Version, inf.Config.CRConfigUseRequestHost, emulate)
+	return diff, snapshot != `{}`, err
+}
+
+// previewServer generates the configuration files of the given server, and
+// returns how they differ from the files it last reported applying. An error
+// generating the files is reported in the preview rather than returned.
+func previewServer(tx *sql.Tx, data *configData, sv atscfg.Server) (tc.ServerConfigPreview, error) {
+	preview := tc.ServerConfigPreview{
+		HostName:        *sv.HostName,
+		ID:              *sv.ID,
+		ConfigApplyTime: sv.ConfigApplyTime,
+		Files:           []tc.ConfigFilePreview{},
+	}
+	if sv.Cachegroup != nil {
+		preview.Cachegroup = *sv.Cachegroup
+	}
+
+	applied, err := server.GetConfigFiles(tx, *sv.ID)
+	if err != nil {
+		return preview, err
+	}
+	preview.Reported = len(applied) > 0
+
+	generated, err := generate(data, sv)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview, nil
+	}
+	preview.Files = diffFiles(generated, applied)
+	return preview, nil
+}
+
+// generate returns the configuration files of the given server, as generated
+// by t3c-generate with its default options.
+func generate(data *configData, sv atscfg.Server) ([]cfgfile.ATSConfigFile, error) {
+	serverParams, err := atscfg.LayerProfiles(sv.ProfileNames, data.ProfileParams)
+	if err != nil {
+		return nil, errors.New("layering profile parameters: " + err.Error())
+	}
+
+	toData := data.ConfigData
+	toData.Server = &sv
+	toData.ServerParams = serverParams
+	toData.MetaData.CacheHostName = *sv.HostName
+
+	cfg := cfgfile.Cfg{
+		Dir:           DefaultATSConfigDir,
+		UseStrategies: cfgfile.UseStrategiesFlagFalse,
+		ViaRelease:    true,
+		AppVersion:    "Traffic Ops preview",
+	}
+	return cfgfile.GetAllConfigs(&toData, cfg)
+}
@@ -0,0 +1,287 @@
+package rollout
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/util/monitorhlp"
+)
+
+const selectRunningRolloutIDsQuery = `
+SELECT id FROM rollout WHERE status = '` + string(tc.RolloutStatusRunning) + `' ORDER BY id
+`
+
+// selectRunningRolloutQuery selects a running Rollout without locking it, so
+// that Traffic Monitor can be polled for it outside of any transaction.
+const selectRunningRolloutQuery = selectRolloutsQuery + `
+WHERE r.id = $1 AND r.status = '` + string(tc.RolloutStatusRunning) + `'
+`
+
+// selectRunningRolloutForUpdateQuery locks a running Rollout, skipping it if
+// another Traffic Ops instance is already checking it.
+const selectRunningRolloutForUpdateQuery = selectRunningRolloutQuery + `
+FOR UPDATE OF r SKIP LOCKED
+`
+
+var checkerOnce = sync.Once{}
+
+// StartChecker starts checking running Rollouts every interval, proceeding to
+// their next wave or pausing them as their current wave's servers apply their
+// config and are polled by Traffic Monitor. If interval is not positive,
+// Rollouts are never checked, and so never proceed past their canary wave.
+//
+// The checker stops when ctx is done, and the returned channel is closed once
+// it has, so that any check in progress can finish before shutdown.
+func StartChecker(ctx context.Context, interval time.Duration, db *sql.DB, timeout time.Duration) <-chan struct{} {
+	done := make(chan struct{})
+	started := false
+	checkerOnce.Do(func() {
+		if interval <= 0 {
+			return
+		}
+		started = true
+		go func() {
+			defer close(done)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					checkRollouts(db, timeout)
+				}
+			}
+		}()
+	})
+	if !started {
+		close(done)
+	}
+	return done
+}
+
+func checkRollouts(db *sql.DB, timeout time.Duration) {
+	ids, err := getRunningRolloutIDs(db, timeout)
+	if err != nil {
+		log.Errorln("checking rollouts: " + err.Error())
+		return
+	}
+	// availability is fetched at most once per CDN per check, because
+	// Rollouts of the same CDN share the same Traffic Monitors.
+	availability := map[string]map[tc.CacheName]bool{}
+	for _, id := range ids {
+		if err := checkRollout(db, timeout, id, availability); err != nil {
+			log.Errorf("checking rollout %d: %s", id, err.Error())
+		}
+	}
+}
+
+func getRunningRolloutIDs(db *sql.DB, timeout time.Duration) ([]int, error) {
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	rows, err := db.QueryContext(dbCtx, selectRunningRolloutIDsQuery)
+	if err != nil {
+		return nil, errors.New("querying running rollouts: " + err.Error())
+	}
+	defer log.Close(rows, "closing running rollout rows")
+	ids := []int{}
+	for rows.Next() {
+		id := 0
+		if err := rows.Scan(&id); err != nil {
+			return nil, errors.New("scanning running rollouts: " + err.Error())
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating running rollout rows: " + err.Error())
+	}
+	return ids, nil
+}
+
+// checkRollout checks the running Rollout with the given ID, and pauses it,
+// starts its current wave's soak time, or proceeds to its next wave, as its
+// current wave requires.
+//
+// Traffic Monitor is polled before the Rollout is locked, so that no
+// transaction is held open while waiting on it.
+func checkRollout(db *sql.DB, timeout time.Duration, id int, availability map[string]map[tc.CacheName]bool) error {
+	cdn, err := getRunningRolloutCDN(db, timeout, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil // no longer running
+		}
+		return err
+	}
+	available, ok := availability[cdn]
+	if !ok {
+		if available, err = getAvailability(db, timeout, tc.CDNName(cdn)); err != nil {
+			log.Warnf("getting availability of CDN '%s' for rollout %d, not proceeding: %s", cdn, id, err.Error())
+		}
+		availability[cdn] = available
+	}
+
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	tx, err := db.BeginTx(dbCtx, nil)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	commit := false
+	defer func() {
+		if !commit {
+			if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+				log.Errorln("rolling back rollout check transaction: " + err.Error())
+			}
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			log.Errorln("committing rollout check transaction: " + err.Error())
+		}
+	}()
+
+	ro, err := scanRollout(tx.QueryRow(selectRunningRolloutForUpdateQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil // no longer running, or being checked by another Traffic Ops
+		}
+		return errors.New("querying rollout: " + err.Error())
+	}
+	servers, err := getRolloutServers(tx, id, &ro.CurrentWave)
+	if err != nil {
+		return err
+	}
+
+	msg := ""
+	switch action, reason := evaluateWave(servers, available, ro.WaveAppliedTime, time.Duration(ro.SoakTime)*time.Second, time.Now()); action {
+	case waveActionWait:
+		return nil
+	case waveActionPause:
+		if err := setStatus(tx, id, tc.RolloutStatusPaused, &reason); err != nil {
+			return err
+		}
+		msg = fmt.Sprintf("Rollout %d paused at wave %d: %s", id, ro.CurrentWave, reason)
+	case waveActionApplied:
+		if _, err := tx.Exec(updateRolloutWaveAppliedQuery, id); err != nil {
+			return fmt.Errorf("setting rollout wave applied time: %w", err)
+		}
+		log.Infof("rollout %d wave %d applied, soaking for %ds", id, ro.CurrentWave, ro.SoakTime)
+		commit = true
+		return nil
+	case waveActionProceed:
+		if ro.CurrentWave+1 >= ro.Waves {
+			if err := setStatus(tx, id, tc.RolloutStatusCompleted, nil); err != nil {
+				return err
+			}
+			msg = fmt.Sprintf("Rollout %d completed", id)
+		} else {
+			if err := queueWave(tx, id, ro.CurrentWave+1); err != nil {
+				return err
+			}
+			msg = fmt.Sprintf("Rollout %d wave %d queued", id, ro.CurrentWave+1)
+		}
+	}
+
+	if _, err := tx.Exec(rolloutChangeLogQuery, api.ApiChange, fmt.Sprintf("CDN: %s, ID: %d, ACTION: %s", ro.CDNName, ro.CDNID, msg), ro.Author); err != nil {
+		return fmt.Errorf("inserting change log: %w", err)
+	}
+	log.Infoln(msg)
+	commit = true
+	return nil
+}
+
+// getRunningRolloutCDN returns the name of the CDN of the running Rollout
+// with the given ID, or sql.ErrNoRows if it isn't running.
+func getRunningRolloutCDN(db *sql.DB, timeout time.Duration, id int) (string, error) {
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	ro, err := scanRollout(db.QueryRowContext(dbCtx, selectRunningRolloutQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+		return "", errors.New("querying rollout: " + err.Error())
+	}
+	return ro.CDNName, nil
+}
+
+// getAvailability returns the availability of each cache of the given CDN,
+// from the first of its online Traffic Monitors to respond. The database is
+// only used to look up the monitors, and Traffic Monitor is polled after that
+// transaction has ended.
+func getAvailability(db *sql.DB, timeout time.Duration, cdn tc.CDNName) (map[tc.CacheName]bool, error) {
+	monitors, client, err := getMonitors(db, timeout, cdn)
+	if err != nil {
+		return nil, err
+	}
+	errs := []error{}
+	for _, monitorFQDN := range monitors {
+		crStates, err := monitorhlp.GetCRStates(monitorFQDN, client)
+		if err != nil {
+			errs = append(errs, errors.New("getting CRStates from monitor '"+monitorFQDN+"': "+err.Error()))
+			continue
+		}
+		available := make(map[tc.CacheName]bool, len(crStates.Caches))
+		for cache, avail := range crStates.Caches {
+			available[cache] = avail.IsAvailable
+		}
+		return available, nil
+	}
+	return nil, util.JoinErrs(errs)
+}
+
+// getMonitors returns the FQDNs of the given CDN's online Traffic Monitors,
+// and the client with which to poll them.
+func getMonitors(db *sql.DB, timeout time.Duration, cdn tc.CDNName) ([]string, *http.Client, error) {
+	dbCtx, dbClose := context.WithTimeout(context.Background(), timeout)
+	defer dbClose()
+	tx, err := db.BeginTx(dbCtx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Errorln("rolling back rollout monitor transaction: " + err.Error())
+		}
+	}()
+	monitorURLs, err := monitorhlp.GetURLs(tx)
+	if err != nil {
+		return nil, nil, errors.New("getting monitors: " + err.Error())
+	}
+	monitors, ok := monitorURLs[cdn]
+	if !ok {
+		return nil, nil, errors.New("no online monitors")
+	}
+	client, err := monitorhlp.GetClient(tx)
+	if err != nil {
+		return nil, nil, errors.New("getting monitor client: " + err.Error())
+	}
+	return monitors, client, nil
+}
@@ -0,0 +1,483 @@
+// Package rollout implements staged rollouts of queued updates, which queue
+// updates to a CDN's cache servers in waves, starting with a canary wave, and
+// only proceed to the next wave once the last one has applied its config and
+// stayed healthy in Traffic Monitor.
+package rollout
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+
+	"github.com/lib/pq"
+)
+
+const selectRolloutsQuery = `
+SELECT r.id, r.cdn_id, c.name, r.topology, r.canary_cachegroups, r.canary_percent, r.wave_percent, r.soak_time,
+	r.status, r.status_message, r.current_wave, r.waves, r.wave_queued_time, r.wave_applied_time, r.author, r.last_updated
+FROM rollout AS r
+JOIN cdn AS c ON c.id = r.cdn_id
+`
+
+// selectActiveRolloutQuery selects the ID of the CDN's Rollout in progress,
+// if any.
+const selectActiveRolloutQuery = `
+SELECT id FROM rollout
+WHERE cdn_id = $1 AND status IN ('` + string(tc.RolloutStatusRunning) + `', '` + string(tc.RolloutStatusPaused) + `')
+`
+
+// selectCandidatesQuery selects the cache servers of a CDN which may be
+// included in a Rollout, optionally limited to the Cache Groups of a
+// Topology. Servers which aren't REPORTED or ONLINE are excluded, because
+// they may never apply their config, which would stall the Rollout.
+const selectCandidatesQuery = `
+SELECT s.id, s.host_name, cg.name
+FROM server AS s
+JOIN cachegroup AS cg ON cg.id = s.cachegroup
+JOIN type AS t ON t.id = s.type
+JOIN status AS st ON st.id = s.status
+WHERE s.cdn_id = $1
+AND (t.name LIKE '` + tc.EdgeTypePrefix + `%' OR t.name LIKE '` + tc.MidTypePrefix + `%')
+AND st.name IN ('` + string(tc.CacheStatusReported) + `', '` + string(tc.CacheStatusOnline) + `')
+AND ($2::text IS NULL OR cg.name IN (SELECT tcg.cachegroup FROM topology_cachegroup AS tcg WHERE tcg.topology = $2))
+`
+
+const selectRolloutServersQuery = `
+SELECT s.id, s.host_name, cg.name, rs.wave, rs.queued_time, s.config_apply_time, s.config_apply_failure_time
+FROM rollout_server AS rs
+JOIN server AS s ON s.id = rs.server_id
+JOIN cachegroup AS cg ON cg.id = s.cachegroup
+WHERE rs.rollout_id = $1
+AND ($2::integer IS NULL OR rs.wave = $2)
+ORDER BY rs.wave, s.host_name
+`
+
+const insertRolloutQuery = `
+INSERT INTO rollout (cdn_id, topology, canary_cachegroups, canary_percent, wave_percent, soak_time, waves, author)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id
+`
+
+const insertRolloutServerQuery = `
+INSERT INTO rollout_server (rollout_id, server_id, wave)
+VALUES ($1, $2, $3)
+`
+
+// queueWaveQuery queues updates for the servers of a wave, and records the
+// time they were queued, which is the update time t3c will report applying.
+const queueWaveQuery = `
+WITH queued AS (
+	UPDATE rollout_server
+	SET queued_time = now()
+	WHERE rollout_id = $1 AND wave = $2
+	RETURNING server_id
+)
+UPDATE server
+SET config_update_time = now()
+WHERE id IN (SELECT server_id FROM queued)
+`
+
+// dequeueRolloutQuery dequeues the updates of a Rollout's servers which were
+// queued but haven't been applied.
+const dequeueRolloutQuery = `
+UPDATE server AS s
+SET config_update_time = s.config_apply_time
+FROM rollout_server AS rs
+WHERE rs.server_id = s.id
+AND rs.rollout_id = $1
+AND rs.queued_time IS NOT NULL
+AND (s.config_apply_time IS NULL OR s.config_apply_time < rs.queued_time)
+`
+
+const updateRolloutWaveQuery = `
+UPDATE rollout
+SET current_wave = $2, wave_queued_time = now(), wave_applied_time = NULL, last_updated = now()
+WHERE id = $1
+`
+
+const updateRolloutStatusQuery = `
+UPDATE rollout
+SET status = $2, status_message = $3, last_updated = now()
+WHERE id = $1
+`
+
+const updateRolloutWaveAppliedQuery = `
+UPDATE rollout
+SET wave_applied_time = now(), last_updated = now()
+WHERE id = $1
+`
+
+// rolloutChangeLogQuery inserts a change log entry on behalf of the author of
+// a Rollout, for changes made by the Rollout checker rather than a request.
+const rolloutChangeLogQuery = `
+INSERT INTO log (level, message, tm_user)
+SELECT $1, $2, u.id
+FROM tm_user AS u
+WHERE u.username = $3
+`
+
+func scanRollout(row interface{ Scan(...interface{}) error }) (tc.Rollout, error) {
+	ro := tc.Rollout{}
+	err := row.Scan(&ro.ID, &ro.CDNID, &ro.CDNName, &ro.Topology, pq.Array(&ro.CanaryCacheGroups), &ro.CanaryPercent, &ro.WavePercent, &ro.SoakTime,
+		&ro.Status, &ro.StatusMessage, &ro.CurrentWave, &ro.Waves, &ro.WaveQueuedTime, &ro.WaveAppliedTime, &ro.Author, &ro.LastUpdated)
+	return ro, err
+}
+
+// GetRollouts returns all Rollouts, newest first.
+func GetRollouts(tx *sql.Tx) ([]tc.Rollout, error) {
+	rows, err := tx.Query(selectRolloutsQuery + `ORDER BY r.id DESC`)
+	if err != nil {
+		return nil, errors.New("querying rollouts: " + err.Error())
+	}
+	defer log.Close(rows, "closing rollout rows")
+
+	rollouts := []tc.Rollout{}
+	for rows.Next() {
+		ro, err := scanRollout(rows)
+		if err != nil {
+			return nil, errors.New("scanning rollouts: " + err.Error())
+		}
+		rollouts = append(rollouts, ro)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating rollout rows: " + err.Error())
+	}
+	return rollouts, nil
+}
+
+// GetRollout returns the Rollout with the given ID, including its servers. If
+// forUpdate, the Rollout's row is locked until the transaction ends. If no
+// such Rollout exists, false is returned.
+func GetRollout(tx *sql.Tx, id int, forUpdate bool) (tc.Rollout, bool, error) {
+	qry := selectRolloutsQuery + `WHERE r.id = $1`
+	if forUpdate {
+		qry += ` FOR UPDATE OF r`
+	}
+	ro, err := scanRollout(tx.QueryRow(qry, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ro, false, nil
+		}
+		return ro, false, errors.New("querying rollout: " + err.Error())
+	}
+	if ro.Servers, err = getRolloutServers(tx, ro.ID, nil); err != nil {
+		return ro, false, err
+	}
+	return ro, true, nil
+}
+
+// getRolloutServers returns the servers of the given Rollout, or only those of
+// the given wave if it isn't nil.
+func getRolloutServers(tx *sql.Tx, rolloutID int, wave *int) ([]tc.RolloutServer, error) {
+	rows, err := tx.Query(selectRolloutServersQuery, rolloutID, wave)
+	if err != nil {
+		return nil, errors.New("querying rollout servers: " + err.Error())
+	}
+	defer log.Close(rows, "closing rollout server rows")
+
+	servers := []tc.RolloutServer{}
+	for rows.Next() {
+		sv := tc.RolloutServer{}
+		applyTime := (*time.Time)(nil)
+		failTime := (*time.Time)(nil)
+		if err := rows.Scan(&sv.ServerID, &sv.HostName, &sv.CacheGroup, &sv.Wave, &sv.QueuedTime, &applyTime, &failTime); err != nil {
+			return nil, errors.New("scanning rollout servers: " + err.Error())
+		}
+		sv.Applied, sv.ApplyFailed = serverState(sv.QueuedTime, applyTime, failTime)
+		servers = append(servers, sv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating rollout server rows: " + err.Error())
+	}
+	return servers, nil
+}
+
+func getCandidates(tx *sql.Tx, cdnID int, topology *string) ([]candidate, error) {
+	rows, err := tx.Query(selectCandidatesQuery, cdnID, topology)
+	if err != nil {
+		return nil, errors.New("querying rollout servers: " + err.Error())
+	}
+	defer log.Close(rows, "closing rollout candidate rows")
+
+	candidates := []candidate{}
+	for rows.Next() {
+		c := candidate{}
+		if err := rows.Scan(&c.ID, &c.HostName, &c.CacheGroup); err != nil {
+			return nil, errors.New("scanning rollout servers: " + err.Error())
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.New("iterating rollout server rows: " + err.Error())
+	}
+	return candidates, nil
+}
+
+// CreateRollout creates a Rollout for the given request, and queues updates
+// for its canary wave. It returns the new Rollout's ID.
+func CreateRollout(tx *sql.Tx, req tc.RolloutRequest, author string) (int, error) {
+	candidates, err := getCandidates(tx, req.CDNID, req.Topology)
+	if err != nil {
+		return 0, err
+	}
+	if len(candidates) == 0 {
+		return 0, errors.New("no servers to roll out to")
+	}
+	waves, numWaves := assignWaves(candidates, req.CanaryCacheGroups, req.CanaryPercent, req.WavePercent)
+
+	id := 0
+	if err := tx.QueryRow(insertRolloutQuery, req.CDNID, req.Topology, pq.Array(req.CanaryCacheGroups), req.CanaryPercent, req.WavePercent, req.SoakTime, numWaves, author).Scan(&id); err != nil {
+		return 0, errors.New("inserting rollout: " + err.Error())
+	}
+	for serverID, wave := range waves {
+		if _, err := tx.Exec(insertRolloutServerQuery, id, serverID, wave); err != nil {
+			return 0, errors.New("inserting rollout server: " + err.Error())
+		}
+	}
+	if err := queueWave(tx, id, 0); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// queueWave queues updates for the servers of the given wave of a Rollout,
+// and makes it the Rollout's current wave.
+func queueWave(tx *sql.Tx, rolloutID int, wave int) error {
+	if _, err := tx.Exec(queueWaveQuery, rolloutID, wave); err != nil {
+		return fmt.Errorf("queueing rollout %d wave %d: %w", rolloutID, wave, err)
+	}
+	if _, err := tx.Exec(updateRolloutWaveQuery, rolloutID, wave); err != nil {
+		return fmt.Errorf("updating rollout %d wave: %w", rolloutID, err)
+	}
+	return nil
+}
+
+func setStatus(tx *sql.Tx, rolloutID int, status tc.RolloutStatus, msg *string) error {
+	if _, err := tx.Exec(updateRolloutStatusQuery, rolloutID, status, msg); err != nil {
+		return fmt.Errorf("setting rollout %d status: %w", rolloutID, err)
+	}
+	return nil
+}
+
+func validate(tx *sql.Tx, req tc.RolloutRequest) (string, error, error) {
+	errs := []error{}
+	cdnName, ok, err := dbhelpers.GetCDNNameFromID(tx, int64(req.CDNID))
+	if err != nil {
+		return "", nil, errors.New("getting CDN name from ID '" + strconv.Itoa(req.CDNID) + "': " + err.Error())
+	} else if !ok {
+		errs = append(errs, fmt.Errorf("no cdn exists with id %d", req.CDNID))
+	}
+	if req.Topology != nil {
+		if ok, err := dbhelpers.TopologyExists(tx, *req.Topology); err != nil {
+			return "", nil, fmt.Errorf("checking topology %s exists: %w", *req.Topology, err)
+		} else if !ok {
+			errs = append(errs, fmt.Errorf("no topology exists by the name of %s", *req.Topology))
+		}
+	}
+	if len(req.CanaryCacheGroups) == 0 && req.CanaryPercent <= 0 {
+		errs = append(errs, errors.New("at least one of canaryCacheGroups or canaryPercent must be given"))
+	}
+	if req.CanaryPercent < 0 || req.CanaryPercent > 100 {
+		errs = append(errs, errors.New("canaryPercent must be between 0 and 100"))
+	}
+	if req.WavePercent < 0 || req.WavePercent > 100 {
+		errs = append(errs, errors.New("wavePercent must be between 0 and 100"))
+	}
+	if req.SoakTime < 0 {
+		errs = append(errs, errors.New("soakTime must not be negative"))
+	}
+	if len(req.CanaryCacheGroups) > 0 {
+		existing := []string{}
+		if err := tx.QueryRow(`SELECT ARRAY(SELECT name FROM cachegroup WHERE name = ANY($1))`, pq.Array(req.CanaryCacheGroups)).Scan(pq.Array(&existing)); err != nil {
+			return "", nil, errors.New("checking canary cache groups exist: " + err.Error())
+		}
+		for _, cg := range req.CanaryCacheGroups {
+			if !util.ContainsStr(existing, cg) {
+				errs = append(errs, fmt.Errorf("no cache group exists by the name of %s", cg))
+			}
+		}
+	}
+	return string(cdnName), util.JoinErrs(errs), nil
+}
+
+// Get is the handler for GET requests to /rollouts.
+func Get(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, nil, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	rollouts, err := GetRollouts(inf.Tx.Tx)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	api.WriteResp(w, r, rollouts)
+}
+
+// GetByID is the handler for GET requests to /rollouts/{id}.
+func GetByID(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	ro, ok, err := GetRollout(inf.Tx.Tx, inf.IntParams["id"], false)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("no rollout exists with id %d", inf.IntParams["id"]), nil)
+		return
+	}
+	api.WriteResp(w, r, ro)
+}
+
+// Create is the handler for POST requests to /rollouts.
+func Create(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, nil, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	req := tc.RolloutRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, errors.New("malformed JSON: "+err.Error()), nil)
+		return
+	}
+	cdnName, userErr, sysErr := validate(inf.Tx.Tx, req)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, userErr, sysErr)
+		return
+	}
+	userErr, sysErr, statusCode := dbhelpers.CheckIfCurrentUserHasCdnLock(inf.Tx.Tx, cdnName, inf.User.UserName)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, statusCode, userErr, sysErr)
+		return
+	}
+	activeID := 0
+	if err := inf.Tx.Tx.QueryRow(selectActiveRolloutQuery, req.CDNID).Scan(&activeID); err == nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusConflict, fmt.Errorf("cdn %s already has rollout %d in progress, it must complete or be aborted first", cdnName, activeID), nil)
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("checking for a rollout in progress on cdn %s: %w", cdnName, err))
+		return
+	}
+
+	id, err := CreateRollout(inf.Tx.Tx, req, inf.User.UserName)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, errors.New("creating rollout: "+err.Error()))
+		return
+	}
+	ro, _, err := GetRollout(inf.Tx.Tx, id, false)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	api.CreateChangeLogRawTx(api.ApiChange, fmt.Sprintf("CDN: %s, ID: %d, ACTION: Created rollout %d of queued updates to %d servers in %d waves", cdnName, req.CDNID, id, len(ro.Servers), ro.Waves), inf.User, inf.Tx.Tx)
+	api.WriteRespAlertObj(w, r, tc.SuccessLevel, fmt.Sprintf("Rollout %d created, canary wave queued", id), ro)
+}
+
+// Pause is the handler for POST requests to /rollouts/{id}/pause.
+func Pause(w http.ResponseWriter, r *http.Request) {
+	changeStatus(w, r, tc.RolloutStatusPaused, []tc.RolloutStatus{tc.RolloutStatusRunning})
+}
+
+// Resume is the handler for POST requests to /rollouts/{id}/resume.
+func Resume(w http.ResponseWriter, r *http.Request) {
+	changeStatus(w, r, tc.RolloutStatusRunning, []tc.RolloutStatus{tc.RolloutStatusPaused})
+}
+
+// Abort is the handler for POST requests to /rollouts/{id}/abort.
+func Abort(w http.ResponseWriter, r *http.Request) {
+	changeStatus(w, r, tc.RolloutStatusAborted, []tc.RolloutStatus{tc.RolloutStatusRunning, tc.RolloutStatusPaused})
+}
+
+// changeStatus sets the status of the Rollout requested by r to the given
+// status, if it has one of the given current statuses.
+func changeStatus(w http.ResponseWriter, r *http.Request, status tc.RolloutStatus, from []tc.RolloutStatus) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"id"}, []string{"id"})
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	id := inf.IntParams["id"]
+	ro, ok, err := GetRollout(inf.Tx.Tx, id, true)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("no rollout exists with id %d", id), nil)
+		return
+	}
+	userErr, sysErr, statusCode := dbhelpers.CheckIfCurrentUserHasCdnLock(inf.Tx.Tx, ro.CDNName, inf.User.UserName)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, statusCode, userErr, sysErr)
+		return
+	}
+
+	allowed := false
+	for _, st := range from {
+		allowed = allowed || ro.Status == st
+	}
+	if !allowed {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusConflict, fmt.Errorf("rollout %d is %s, cannot set it to %s", id, ro.Status, status), nil)
+		return
+	}
+
+	if status == tc.RolloutStatusAborted {
+		if _, err := inf.Tx.Tx.Exec(dequeueRolloutQuery, id); err != nil {
+			api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("dequeueing rollout %d updates: %w", id, err))
+			return
+		}
+	}
+	if err := setStatus(inf.Tx.Tx, id, status, nil); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	if ro, _, err = GetRollout(inf.Tx.Tx, id, false); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+
+	api.CreateChangeLogRawTx(api.ApiChange, fmt.Sprintf("CDN: %s, ID: %d, ACTION: Rollout %d set to %s", ro.CDNName, ro.CDNID, id, status), inf.User, inf.Tx.Tx)
+	api.WriteRespAlertObj(w, r, tc.SuccessLevel, fmt.Sprintf("Rollout %d %s", id, status), ro)
+}
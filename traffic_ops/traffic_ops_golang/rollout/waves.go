@@ -0,0 +1,180 @@
+package rollout
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"sort"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+// candidate is a server which may be included in a Rollout.
+type candidate struct {
+	ID         int
+	HostName   string
+	CacheGroup string
+}
+
+// assignWaves returns the wave of each of the given servers, by ID, and the
+// number of waves.
+//
+// The canary wave, 0, is all servers in the given canary Cache Groups, plus
+// canaryPercent of all servers. Each later wave is wavePercent of all servers,
+// or all remaining servers if wavePercent is 0. Servers are picked across
+// Cache Groups in turn, so that no wave takes out a whole Cache Group unless
+// it must.
+func assignWaves(servers []candidate, canaryCacheGroups []string, canaryPercent int, wavePercent int) (map[int]int, int) {
+	waves := map[int]int{}
+	if len(servers) == 0 {
+		return waves, 0
+	}
+
+	canaryCGs := map[string]struct{}{}
+	for _, cg := range canaryCacheGroups {
+		canaryCGs[cg] = struct{}{}
+	}
+
+	rest := []candidate{}
+	for _, sv := range servers {
+		if _, ok := canaryCGs[sv.CacheGroup]; ok {
+			waves[sv.ID] = 0
+			continue
+		}
+		rest = append(rest, sv)
+	}
+	rest = interleaveCacheGroups(rest)
+
+	numCanaries := percentOf(len(servers), canaryPercent)
+	for numCanaries > 0 && len(rest) > 0 {
+		waves[rest[0].ID] = 0
+		rest = rest[1:]
+		numCanaries--
+	}
+
+	waveSize := len(rest)
+	if wavePercent > 0 {
+		waveSize = percentOf(len(servers), wavePercent)
+	}
+	numWaves := 1
+	for i, sv := range rest {
+		wave := 1 + i/waveSize
+		waves[sv.ID] = wave
+		numWaves = wave + 1
+	}
+	return waves, numWaves
+}
+
+// percentOf returns percent of n, rounded up.
+func percentOf(n int, percent int) int {
+	return (n*percent + 99) / 100
+}
+
+// interleaveCacheGroups returns the given servers ordered by taking one server
+// from each Cache Group in turn. Cache Groups, and servers within them, are
+// ordered by name, so the result is deterministic.
+func interleaveCacheGroups(servers []candidate) []candidate {
+	byCG := map[string][]candidate{}
+	cgs := []string{}
+	for _, sv := range servers {
+		if _, ok := byCG[sv.CacheGroup]; !ok {
+			cgs = append(cgs, sv.CacheGroup)
+		}
+		byCG[sv.CacheGroup] = append(byCG[sv.CacheGroup], sv)
+	}
+	sort.Strings(cgs)
+	for _, cg := range cgs {
+		cgServers := byCG[cg]
+		sort.Slice(cgServers, func(i, j int) bool { return cgServers[i].HostName < cgServers[j].HostName })
+	}
+
+	interleaved := make([]candidate, 0, len(servers))
+	for i := 0; len(interleaved) < len(servers); i++ {
+		for _, cg := range cgs {
+			if i < len(byCG[cg]) {
+				interleaved = append(interleaved, byCG[cg][i])
+			}
+		}
+	}
+	return interleaved
+}
+
+// waveAction is what to do with a running Rollout, given the state of its
+// current wave.
+type waveAction int
+
+const (
+	waveActionWait = waveAction(iota)
+	waveActionPause
+	// waveActionApplied is returned the first time all servers of the wave are
+	// seen to have applied their config, which starts its soak time.
+	waveActionApplied
+	waveActionProceed
+)
+
+// serverState returns whether a server whose Rollout wave was queued at the
+// given time has applied the queued config, or has failed to apply it and not
+// applied it since. The apply time reported by t3c is the update time it
+// applied, so it is equal to the queued time once the server has applied the
+// wave's config.
+func serverState(queued *time.Time, applyTime *time.Time, failTime *time.Time) (bool, bool) {
+	if queued == nil {
+		return false, false
+	}
+	if applyTime != nil && !applyTime.Before(*queued) {
+		return true, false
+	}
+	return false, failTime != nil && !failTime.Before(*queued)
+}
+
+// evaluateWave returns what to do with a running Rollout whose current wave is
+// the given servers, and if the Rollout should be paused, why.
+//
+// The available map is the availability of each cache in Traffic Monitor, or
+// nil if it couldn't be determined, in which case the Rollout won't proceed.
+// Only servers which have applied their config are checked for availability.
+// The waveApplied time is when all servers of the wave were first seen to have
+// applied their config, or nil if they haven't yet; the wave proceeds once
+// soak has passed since then.
+func evaluateWave(servers []tc.RolloutServer, available map[tc.CacheName]bool, waveApplied *time.Time, soak time.Duration, now time.Time) (waveAction, string) {
+	allApplied := true
+	for _, sv := range servers {
+		if sv.ApplyFailed {
+			return waveActionPause, "server " + sv.HostName + " failed to apply its config"
+		}
+		if !sv.Applied {
+			allApplied = false
+			continue
+		}
+		if avail, ok := available[tc.CacheName(sv.HostName)]; ok && !avail {
+			return waveActionPause, "server " + sv.HostName + " is unavailable in Traffic Monitor after applying its config"
+		}
+	}
+	if !allApplied || available == nil {
+		return waveActionWait, ""
+	}
+	if waveApplied == nil {
+		return waveActionApplied, ""
+	}
+	if now.Sub(*waveApplied) < soak {
+		return waveActionWait, ""
+	}
+	return waveActionProceed, ""
+}
@@ -0,0 +1,191 @@
+package rollout
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+)
+
+func TestAssignWaves(t *testing.T) {
+	servers := []candidate{
+		{ID: 1, HostName: "edge-a-1", CacheGroup: "cg-a"},
+		{ID: 2, HostName: "edge-a-2", CacheGroup: "cg-a"},
+		{ID: 3, HostName: "edge-a-3", CacheGroup: "cg-a"},
+		{ID: 4, HostName: "edge-b-1", CacheGroup: "cg-b"},
+		{ID: 5, HostName: "edge-b-2", CacheGroup: "cg-b"},
+		{ID: 6, HostName: "edge-c-1", CacheGroup: "cg-c"},
+		{ID: 7, HostName: "edge-c-2", CacheGroup: "cg-c"},
+		{ID: 8, HostName: "edge-c-3", CacheGroup: "cg-c"},
+		{ID: 9, HostName: "mid-1", CacheGroup: "cg-mid"},
+		{ID: 10, HostName: "mid-2", CacheGroup: "cg-mid"},
+	}
+
+	waves, numWaves := assignWaves(servers, []string{"cg-b"}, 10, 30)
+	expected := map[int]int{
+		4: 0, 5: 0, // canary cache group
+		1: 0, // 10% of 10 servers, first of the interleaved remainder
+		6: 1, 9: 1, 2: 1,
+		7: 2, 10: 2, 3: 2,
+		8: 3,
+	}
+	if numWaves != 4 {
+		t.Errorf("expected 4 waves, actual: %d", numWaves)
+	}
+	if len(waves) != len(expected) {
+		t.Fatalf("expected %d servers assigned, actual: %d", len(expected), len(waves))
+	}
+	for id, wave := range expected {
+		if waves[id] != wave {
+			t.Errorf("expected server %d in wave %d, actual: %d", id, wave, waves[id])
+		}
+	}
+
+	if waves, numWaves := assignWaves(servers, nil, 20, 0); numWaves != 2 {
+		t.Errorf("expected all remaining servers in one wave after the canaries, actual waves: %d", numWaves)
+	} else if waves[1] != 0 || waves[4] != 0 || waves[6] != 1 {
+		t.Errorf("expected canaries to be picked across cache groups, actual: %v", waves)
+	}
+
+	if _, numWaves := assignWaves(servers, nil, 100, 10); numWaves != 1 {
+		t.Errorf("expected only a canary wave when it includes every server, actual waves: %d", numWaves)
+	}
+
+	if waves, numWaves := assignWaves(nil, []string{"cg-a"}, 10, 10); numWaves != 0 || len(waves) != 0 {
+		t.Errorf("expected no waves for no servers, actual: %d waves %v", numWaves, waves)
+	}
+}
+
+func TestServerState(t *testing.T) {
+	queued := time.Date(2022, 5, 17, 10, 0, 0, 0, time.UTC)
+	before := queued.Add(-time.Hour)
+	after := queued.Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		queued    *time.Time
+		applyTime *time.Time
+		failTime  *time.Time
+		applied   bool
+		failed    bool
+	}{
+		{name: "not queued", queued: nil, applyTime: &after, failTime: &after},
+		{name: "pending", queued: &queued, applyTime: &before},
+		{name: "never applied", queued: &queued},
+		{name: "applied", queued: &queued, applyTime: &queued, applied: true},
+		{name: "old failure", queued: &queued, applyTime: &before, failTime: &before},
+		{name: "failed", queued: &queued, applyTime: &before, failTime: &after, failed: true},
+		{name: "applied after failing", queued: &queued, applyTime: &queued, failTime: &after, applied: true},
+	}
+	for _, test := range tests {
+		applied, failed := serverState(test.queued, test.applyTime, test.failTime)
+		if applied != test.applied || failed != test.failed {
+			t.Errorf("%s: expected applied %v failed %v, actual applied %v failed %v", test.name, test.applied, test.failed, applied, failed)
+		}
+	}
+}
+
+func TestEvaluateWave(t *testing.T) {
+	now := time.Now()
+	soak := 5 * time.Minute
+	appliedLongAgo := now.Add(-time.Hour)
+	appliedJustNow := now.Add(-time.Second)
+	applied := []tc.RolloutServer{
+		{HostName: "edge-1", Applied: true},
+		{HostName: "edge-2", Applied: true},
+	}
+	healthy := map[tc.CacheName]bool{"edge-1": true, "edge-2": true}
+
+	tests := []struct {
+		name        string
+		servers     []tc.RolloutServer
+		available   map[tc.CacheName]bool
+		waveApplied *time.Time
+		action      waveAction
+	}{
+		{
+			name:        "pending",
+			servers:     []tc.RolloutServer{{HostName: "edge-1", Applied: true}, {HostName: "edge-2"}},
+			available:   healthy,
+			waveApplied: nil,
+			action:      waveActionWait,
+		},
+		{
+			name:        "apply failed",
+			servers:     []tc.RolloutServer{{HostName: "edge-1", Applied: true}, {HostName: "edge-2", ApplyFailed: true}},
+			available:   healthy,
+			waveApplied: nil,
+			action:      waveActionPause,
+		},
+		{
+			name:        "unavailable",
+			servers:     applied,
+			available:   map[tc.CacheName]bool{"edge-1": true, "edge-2": false},
+			waveApplied: &appliedLongAgo,
+			action:      waveActionPause,
+		},
+		{
+			name:        "unknown availability",
+			servers:     applied,
+			available:   nil,
+			waveApplied: &appliedLongAgo,
+			action:      waveActionWait,
+		},
+		{
+			name:        "newly applied",
+			servers:     applied,
+			available:   healthy,
+			waveApplied: nil,
+			action:      waveActionApplied,
+		},
+		{
+			name:        "soaking",
+			servers:     applied,
+			available:   healthy,
+			waveApplied: &appliedJustNow,
+			action:      waveActionWait,
+		},
+		{
+			name:        "soaked",
+			servers:     applied,
+			available:   healthy,
+			waveApplied: &appliedLongAgo,
+			action:      waveActionProceed,
+		},
+		{
+			name:        "not monitored",
+			servers:     applied,
+			available:   map[tc.CacheName]bool{},
+			waveApplied: &appliedLongAgo,
+			action:      waveActionProceed,
+		},
+	}
+	for _, test := range tests {
+		action, reason := evaluateWave(test.servers, test.available, test.waveApplied, soak, now)
+		if action != test.action {
+			t.Errorf("%s: expected action %v, actual: %v", test.name, test.action, action)
+		}
+		if (action == waveActionPause) != (reason != "") {
+			t.Errorf("%s: expected a reason only when pausing, actual action %v reason '%s'", test.name, action, reason)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/api"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/dbhelpers"
+
+	"github.com/lib/pq"
+)
+
+const selectConfigFilesQuery = `
+SELECT
+	name,
+	path,
+	line_comment,
+	hash,
+	contents,
+	last_updated
+FROM server_config_file
+WHERE server = $1
+ORDER BY path, name
+`
+
+// deleteUnreportedConfigFilesQuery deletes the files of a server whose paths
+// are not in the given array of "path/name" strings.
+const deleteUnreportedConfigFilesQuery = `
+DELETE FROM server_config_file
+WHERE server = $1 AND NOT (path || '/' || name = ANY($2::text[]))
+`
+
+// upsertConfigFileQuery only rewrites a file which has changed since it was
+// last reported, or whose text wasn't reported before, so that servers
+// reporting the same files on every run don't rewrite them every time.
+const upsertConfigFileQuery = `
+INSERT INTO server_config_file (server, name, path, line_comment, hash, contents)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (server, path, name) DO UPDATE SET
+	line_comment = EXCLUDED.line_comment,
+	hash = EXCLUDED.hash,
+	contents = EXCLUDED.contents,
+	last_updated = now()
+WHERE server_config_file.hash <> EXCLUDED.hash
+	OR (server_config_file.contents IS NULL AND EXCLUDED.contents IS NOT NULL)
+`
+
+// GetConfigFilesHandler is the handler for GET requests to
+// /servers/{{host name}}/config_files, which returns the configuration files
+// the server last reported applying.
+func GetConfigFilesHandler(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"host_name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	hostName := inf.Params["host_name"]
+	serverID, ok, err := dbhelpers.GetServerIDFromName(hostName, inf.Tx.Tx)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("getting server id from name '%s': %w", hostName, err))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("server '%s' not found", hostName), nil)
+		return
+	}
+
+	files, err := GetConfigFiles(inf.Tx.Tx, serverID)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	api.WriteResp(w, r, files)
+}
+
+// PutConfigFilesHandler is the handler for PUT requests to
+// /servers/{{host name}}/config_files, which replaces the set of configuration
+// files the server last reported applying.
+func PutConfigFilesHandler(w http.ResponseWriter, r *http.Request) {
+	inf, userErr, sysErr, errCode := api.NewInfo(r, []string{"host_name"}, nil)
+	if userErr != nil || sysErr != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, errCode, userErr, sysErr)
+		return
+	}
+	defer inf.Close()
+
+	hostName := inf.Params["host_name"]
+	serverID, ok, err := dbhelpers.GetServerIDFromName(hostName, inf.Tx.Tx)
+	if err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, fmt.Errorf("getting server id from name '%s': %w", hostName, err))
+		return
+	} else if !ok {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusNotFound, fmt.Errorf("server '%s' not found", hostName), nil)
+		return
+	}
+
+	files := []tc.ServerConfigFile{}
+	if err := json.NewDecoder(r.Body).Decode(&files); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, fmt.Errorf("parsing request body: %w", err), nil)
+		return
+	}
+	if err := validateConfigFiles(files); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := replaceConfigFiles(inf.Tx.Tx, serverID, files); err != nil {
+		api.HandleErr(w, r, inf.Tx.Tx, http.StatusInternalServerError, nil, err)
+		return
+	}
+	api.WriteRespAlert(w, r, tc.SuccessLevel, fmt.Sprintf("successfully set %d config files for server %s", len(files), hostName))
+}
+
+// validateConfigFiles checks that the given reported files are well-formed and
+// unique, and sets the Hash of every file with a Text.
+func validateConfigFiles(files []tc.ServerConfigFile) error {
+	errs := []string{}
+	seen := map[string]struct{}{}
+	for i, file := range files {
+		if file.Name == "" {
+			errs = append(errs, fmt.Sprintf("file %d: name is required", i))
+			continue
+		}
+		key := file.Path + "/" + file.Name
+		if _, ok := seen[key]; ok {
+			errs = append(errs, fmt.Sprintf("file '%s' in '%s' is duplicated", file.Name, file.Path))
+		}
+		seen[key] = struct{}{}
+		if file.Text != nil {
+			files[i].Hash = tc.ServerConfigFileHash(*file.Text, file.LineComment)
+		} else if file.Hash == "" {
+			errs = append(errs, fmt.Sprintf("file '%s' in '%s': either text or hash is required", file.Name, file.Path))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// GetConfigFiles returns the configuration files the given server last
+// reported applying.
+func GetConfigFiles(tx *sql.Tx, serverID int) ([]tc.ServerConfigFile, error) {
+	rows, err := tx.Query(selectConfigFilesQuery, serverID)
+	if err != nil {
+		return nil, fmt.Errorf("querying server config files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []tc.ServerConfigFile{}
+	for rows.Next() {
+		file := tc.ServerConfigFile{}
+		if err := rows.Scan(&file.Name, &file.Path, &file.LineComment, &file.Hash, &file.Text, &file.LastUpdated); err != nil {
+			return nil, fmt.Errorf("scanning server config files: %w", err)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating over server config files: %w", err)
+	}
+	return files, nil
+}
+
+// replaceConfigFiles replaces the configuration files the given server last
+// reported applying, only writing the files which changed.
+func replaceConfigFiles(tx *sql.Tx, serverID int, files []tc.ServerConfigFile) error {
+	keys := make([]string, 0, len(files))
+	for _, file := range files {
+		keys = append(keys, file.Path+"/"+file.Name)
+	}
+	if _, err := tx.Exec(deleteUnreportedConfigFilesQuery, serverID, pq.Array(keys)); err != nil {
+		return fmt.Errorf("deleting server config files: %w", err)
+	}
+	for _, file := range files {
+		if _, err := tx.Exec(upsertConfigFileQuery, serverID, file.Name, file.Path, file.LineComment, file.Hash, file.Text); err != nil {
+			return fmt.Errorf("upserting server config file '%s': %w", file.Name, err)
+		}
+	}
+	return nil
+}
@@ -1081,6 +1081,20 @@ JOIN server_profile sp ON s.id = sp.server`
 	return returnable, serverCount, nil, nil, http.StatusOK, &maxTime
 }
 
+// GetServersV40 returns every server in Traffic Ops, as used by the ATS config
+// generators.
+func GetServersV40(tx *sqlx.Tx, user *auth.CurrentUser) ([]tc.ServerV40, error) {
+	servers, _, userErr, sysErr, _, _ := getServers(nil, map[string]string{}, tx, user, false, api.Version{Major: 4, Minor: 1})
+	if userErr != nil || sysErr != nil {
+		return nil, util.JoinErrs([]error{userErr, sysErr})
+	}
+	serversV40 := make([]tc.ServerV40, 0, len(servers))
+	for _, s := range servers {
+		serversV40 = append(serversV40, s.ServerV40)
+	}
+	return serversV40, nil
+}
+
 // getMidServers gets the mids used by the edges provided with an option to filter for a given cdn
 func getMidServers(edgeIDs []int, servers map[int]tc.ServerV41, dsID int, cdnID int, tx *sqlx.Tx, includeCapabilities bool) ([]int, error, error, int) {
 	if len(edgeIDs) == 0 {
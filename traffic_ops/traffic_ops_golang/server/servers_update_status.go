@@ -148,7 +148,8 @@ SELECT
 	s.config_update_time,
 	s.config_apply_time,
 	s.revalidate_update_time,
-	s.revalidate_apply_time
+	s.revalidate_apply_time,
+	s.config_apply_failure_time
 	FROM use_reval_pending,
 		 server s
 LEFT JOIN status ON s.status = status.id
@@ -157,7 +158,7 @@ LEFT JOIN type ON type.id = s.type
 LEFT JOIN parentservers ps ON ps.cachegroup = cg.parent_cachegroup_id
 	AND ps.cdn_id = s.cdn_id
 WHERE s.host_name = $5
-GROUP BY s.id, s.host_name, type.name, server_reval_pending, use_reval_pending.value, server_upd_pending, status.name, config_update_time, config_apply_time, revalidate_update_time, revalidate_apply_time
+GROUP BY s.id, s.host_name, type.name, server_reval_pending, use_reval_pending.value, server_upd_pending, status.name, config_update_time, config_apply_time, revalidate_update_time, revalidate_apply_time, config_apply_failure_time
 ORDER BY s.id
 `
 
@@ -173,7 +174,7 @@ ORDER BY s.id
 	for rows.Next() {
 		var us tc.ServerUpdateStatusV40
 		var serverType string
-		if err := rows.Scan(&us.HostId, &us.HostName, &serverType, &us.RevalPending, &us.UseRevalPending, &us.UpdatePending, &us.Status, &us.ParentPending, &us.ParentRevalPending, &us.ConfigUpdateTime, &us.ConfigApplyTime, &us.RevalidateUpdateTime, &us.RevalidateApplyTime); err != nil {
+		if err := rows.Scan(&us.HostId, &us.HostName, &serverType, &us.RevalPending, &us.UseRevalPending, &us.UpdatePending, &us.Status, &us.ParentPending, &us.ParentRevalPending, &us.ConfigUpdateTime, &us.ConfigApplyTime, &us.RevalidateUpdateTime, &us.RevalidateApplyTime, &us.ConfigApplyFailureTime); err != nil {
 			return nil, nil, fmt.Errorf("could not scan server update status: %w", err)
 		}
 		updateStatuses = append(updateStatuses, us)
@@ -251,6 +252,7 @@ type serverInfo struct {
 	configApplyTime  *time.Time
 	revalUpdateTime  *time.Time
 	revalApplyTime   *time.Time
+	configFailTime   *time.Time
 }
 
 const getUseRevalPendingQuery = `
@@ -271,7 +273,8 @@ const getServerInfoQuery = `
 		s.config_update_time,
 		s.config_apply_time,
 		s.revalidate_update_time,
-		s.revalidate_apply_time
+		s.revalidate_apply_time,
+		s.config_apply_failure_time
 	FROM server s
 	JOIN type t ON t.id = s.type
 	JOIN status st ON st.id = s.status
@@ -326,7 +329,7 @@ func getServerUpdateStatuses(db *sql.DB, timeout time.Duration) (map[string][]tc
 	defer log.Close(serverRows, "closing server rows")
 	for serverRows.Next() {
 		s := serverInfo{}
-		if err := serverRows.Scan(&s.id, &s.hostName, &s.typeName, &s.cdnId, &s.status, &s.cachegroup, &s.configUpdateTime, &s.configApplyTime, &s.revalUpdateTime, &s.revalApplyTime); err != nil {
+		if err := serverRows.Scan(&s.id, &s.hostName, &s.typeName, &s.cdnId, &s.status, &s.cachegroup, &s.configUpdateTime, &s.configApplyTime, &s.revalUpdateTime, &s.revalApplyTime, &s.configFailTime); err != nil {
 			return nil, fmt.Errorf("scanning servers: %w", err)
 		}
 		serversByID[s.id] = s
@@ -399,18 +402,19 @@ func getServerUpdateStatuses(db *sql.DB, timeout time.Duration) (map[string][]tc
 	serverUpdateStatuses := make(map[string][]tc.ServerUpdateStatusV40, len(serversByID))
 	for serverID, server := range serversByID {
 		updateStatus := tc.ServerUpdateStatusV40{
-			HostName:             server.hostName,
-			UpdatePending:        server.configUpdateTime.After(*server.configApplyTime),
-			RevalPending:         server.revalUpdateTime.After(*server.revalApplyTime),
-			UseRevalPending:      useRevalPending,
-			HostId:               serverID,
-			Status:               server.status,
-			ParentPending:        getParentPending(cacheGroupParents[server.cachegroup], updatePendingByCDNCachegroup[server.cdnId]),
-			ParentRevalPending:   getParentPending(cacheGroupParents[server.cachegroup], revalPendingByCDNCachegroup[server.cdnId]),
-			ConfigUpdateTime:     server.configUpdateTime,
-			ConfigApplyTime:      server.configApplyTime,
-			RevalidateUpdateTime: server.revalUpdateTime,
-			RevalidateApplyTime:  server.revalApplyTime,
+			HostName:               server.hostName,
+			UpdatePending:          server.configUpdateTime.After(*server.configApplyTime),
+			RevalPending:           server.revalUpdateTime.After(*server.revalApplyTime),
+			UseRevalPending:        useRevalPending,
+			HostId:                 serverID,
+			Status:                 server.status,
+			ParentPending:          getParentPending(cacheGroupParents[server.cachegroup], updatePendingByCDNCachegroup[server.cdnId]),
+			ParentRevalPending:     getParentPending(cacheGroupParents[server.cachegroup], revalPendingByCDNCachegroup[server.cdnId]),
+			ConfigUpdateTime:       server.configUpdateTime,
+			ConfigApplyTime:        server.configApplyTime,
+			RevalidateUpdateTime:   server.revalUpdateTime,
+			RevalidateApplyTime:    server.revalApplyTime,
+			ConfigApplyFailureTime: server.configFailTime,
 		}
 		serverUpdateStatuses[server.hostName] = append(serverUpdateStatuses[server.hostName], updateStatus)
 	}
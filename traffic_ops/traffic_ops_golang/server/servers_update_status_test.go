@@ -44,10 +44,10 @@ func TestGetServerUpdateStatus(t *testing.T) {
 	mock.ExpectBegin()
 	serverStatusRow := sqlmock.NewRows([]string{"id", "host_name", "type", "server_reval_pending", "use_reval_pending",
 		"server_upd_pending", "status", "parent_upd_pending", "parent_reval_pending",
-		"config_update_time", "config_apply_time", "revalidate_update_time", "revalidate_apply_time"})
+		"config_update_time", "config_apply_time", "revalidate_update_time", "revalidate_apply_time", "config_apply_failure_time"})
 	serverStatusRow.AddRow(1, "host_name_1", "EDGE", true, true,
 		true, "ONLINE", true, false,
-		time.Now(), time.Now(), time.Now(), time.Now())
+		time.Now(), time.Now(), time.Now(), time.Now(), nil)
 
 	mock.ExpectQuery("SELECT").WillReturnRows(serverStatusRow)
 	mock.ExpectCommit()
@@ -90,14 +90,14 @@ func TestGetServerUpdateStatuses(t *testing.T) {
 
 	serverInfoRows := sqlmock.NewRows([]string{"id", "host_name", "type", "cdn_id", "status",
 		"cachegroup", "config_update_time", "config_apply_time", "revalidate_update_time",
-		"revalidate_apply_time"})
+		"revalidate_apply_time", "config_apply_failure_time"})
 	tenSecAfter := time.UnixMilli(10000)
 	epoch := time.UnixMilli(0)
-	serverInfoRows.AddRow(1, "edge1", tc.CacheTypeEdge.String(), 1, tc.CacheStatusReported.String(), 1, tenSecAfter, tenSecAfter, tenSecAfter, tenSecAfter)
-	serverInfoRows.AddRow(2, "mid1", tc.CacheTypeMid.String(), 1, tc.CacheStatusReported.String(), 2, tenSecAfter, epoch, tenSecAfter, tenSecAfter)
-	serverInfoRows.AddRow(3, "edge2", tc.CacheTypeEdge.String(), 2, tc.CacheStatusReported.String(), 1, tenSecAfter, tenSecAfter, tenSecAfter, tenSecAfter)
-	serverInfoRows.AddRow(4, "mid2", tc.CacheTypeMid.String(), 2, tc.CacheStatusReported.String(), 2, tenSecAfter, tenSecAfter, tenSecAfter, tenSecAfter)
-	serverInfoRows.AddRow(5, "mid3", tc.CacheTypeMid.String(), 2, tc.CacheStatusReported.String(), 3, tenSecAfter, tenSecAfter, tenSecAfter, epoch)
+	serverInfoRows.AddRow(1, "edge1", tc.CacheTypeEdge.String(), 1, tc.CacheStatusReported.String(), 1, tenSecAfter, tenSecAfter, tenSecAfter, tenSecAfter, nil)
+	serverInfoRows.AddRow(2, "mid1", tc.CacheTypeMid.String(), 1, tc.CacheStatusReported.String(), 2, tenSecAfter, epoch, tenSecAfter, tenSecAfter, nil)
+	serverInfoRows.AddRow(3, "edge2", tc.CacheTypeEdge.String(), 2, tc.CacheStatusReported.String(), 1, tenSecAfter, tenSecAfter, tenSecAfter, tenSecAfter, nil)
+	serverInfoRows.AddRow(4, "mid2", tc.CacheTypeMid.String(), 2, tc.CacheStatusReported.String(), 2, tenSecAfter, tenSecAfter, tenSecAfter, tenSecAfter, nil)
+	serverInfoRows.AddRow(5, "mid3", tc.CacheTypeMid.String(), 2, tc.CacheStatusReported.String(), 3, tenSecAfter, tenSecAfter, tenSecAfter, epoch, nil)
 	mock.ExpectQuery("SELECT").WillReturnRows(serverInfoRows)
 
 	cachegroupRows := sqlmock.NewRows([]string{"id", "parent_cachegroup_id", "secondary_parent_cachegroup_id"})
@@ -145,6 +145,7 @@ type updateValues struct {
 	revalUpdateBool  *bool // Deprecated, prefer timestamps
 	configApplyTime  *time.Time
 	revalApplyTime   *time.Time
+	configFailTime   *time.Time
 }
 
 func parseQueryParams(params map[string]string) (*updateValues, error) {
@@ -155,10 +156,11 @@ func parseQueryParams(params map[string]string) (*updateValues, error) {
 	revalUpdatedBoolParam, hasRevalUpdatedBoolParam := params["reval_updated"] // Deprecated, but still required for backwards compatibility
 	configApplyTimeParam, hasConfigApplyTimeParam := params["config_apply_time"]
 	revalidateApplyTimeParam, hasRevalidateApplyTimeParam := params["revalidate_apply_time"]
+	configApplyFailureTimeParam, hasConfigApplyFailureTimeParam := params["config_apply_failure_time"]
 
-	if !hasConfigApplyTimeParam && !hasRevalidateApplyTimeParam &&
+	if !hasConfigApplyTimeParam && !hasRevalidateApplyTimeParam && !hasConfigApplyFailureTimeParam &&
 		!hasConfigUpdatedBoolParam && !hasRevalUpdatedBoolParam {
-		return nil, errors.New("must pass at least one of the following query parameters: 'config_apply_time', 'revalidate_apply_time' ,'updated', 'reval_updated'")
+		return nil, errors.New("must pass at least one of the following query parameters: 'config_apply_time', 'revalidate_apply_time', 'config_apply_failure_time', 'updated', 'reval_updated'")
 
 	}
 	// Prevent collision between booleans and timestamps
@@ -189,6 +191,14 @@ func parseQueryParams(params map[string]string) (*updateValues, error) {
 		paramValues.revalApplyTime = &revalApplyTime
 	}
 
+	if hasConfigApplyFailureTimeParam {
+		configFailTime, err := time.Parse(time.RFC3339Nano, configApplyFailureTimeParam)
+		if err != nil {
+			return nil, errors.New("query parameter 'config_apply_failure_time' must be valid RFC3339Nano format:" + err.Error())
+		}
+		paramValues.configFailTime = &configFailTime
+	}
+
 	// Booleans
 	if hasConfigUpdatedBoolParam {
 		updatedBool, err := strconv.ParseBool(configUpdatedBoolParam)
@@ -222,6 +232,12 @@ func setUpdateStatuses(tx *sql.Tx, serverID int64, values updateValues) error {
 		}
 	}
 
+	if values.configFailTime != nil {
+		if err := dbhelpers.SetApplyFailureForServerWithTime(tx, serverID, *values.configFailTime); err != nil {
+			return fmt.Errorf("setting config apply failure time: %w", err)
+		}
+	}
+
 	if values.configUpdateBool != nil {
 		if *values.configUpdateBool {
 			if err := dbhelpers.QueueUpdateForServer(tx, serverID); err != nil {
@@ -264,6 +280,9 @@ func responseMessage(idOrName string, values updateValues) string {
 	if values.revalApplyTime != nil {
 		respMsg += " revalidate_apply_time=" + (*values.revalApplyTime).Format(time.RFC3339Nano)
 	}
+	if values.configFailTime != nil {
+		respMsg += " config_apply_failure_time=" + (*values.configFailTime).Format(time.RFC3339Nano)
+	}
 
 	return respMsg
 }
@@ -301,8 +320,9 @@ func UpdateHandlerV4(w http.ResponseWriter, r *http.Request) {
 	_, hasRevalUpdatedBoolParam := inf.Params["reval_updated"]
 	_, hasConfigApplyTimeParam := inf.Params["config_apply_time"]
 	_, hasRevalidateApplyTimeParam := inf.Params["revalidate_apply_time"]
-	// Allow `apply_time` changes when the CDN is locked, but not `updated`
-	canIgnoreLock := (hasConfigApplyTimeParam || hasRevalidateApplyTimeParam) && !hasConfigUpdatedBoolParam && !hasRevalUpdatedBoolParam
+	_, hasConfigApplyFailureTimeParam := inf.Params["config_apply_failure_time"]
+	// Allow `apply_time` and `apply_failure_time` changes when the CDN is locked, but not `updated`
+	canIgnoreLock := (hasConfigApplyTimeParam || hasRevalidateApplyTimeParam || hasConfigApplyFailureTimeParam) && !hasConfigUpdatedBoolParam && !hasRevalUpdatedBoolParam
 	if !canIgnoreLock {
 		userDoesntHaveLockErr, sysErr, statusCode := dbhelpers.CheckIfCurrentUserHasCdnLock(inf.Tx.Tx, string(cdnName), inf.User.UserName)
 		if sysErr != nil || userDoesntHaveLockErr != nil {
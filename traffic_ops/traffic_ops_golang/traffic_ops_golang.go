@@ -20,6 +20,7 @@ package main
  */
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -38,8 +39,10 @@ import (
 	"github.com/apache/trafficcontrol/lib/go-log"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/about"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/auth"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/cdni"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/config"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/plugin"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/rollout"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/routing"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/server"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault"
@@ -148,6 +151,12 @@ func main() {
 
 	auth.InitUsersCache(time.Duration(cfg.UserCacheRefreshIntervalSec)*time.Second, db.DB, time.Duration(cfg.DBQueryTimeoutSeconds)*time.Second)
 	server.InitServerUpdateStatusCache(time.Duration(cfg.ServerUpdateStatusCacheRefreshIntervalSec)*time.Second, db.DB, time.Duration(cfg.DBQueryTimeoutSeconds)*time.Second)
+	// the server shuts down on SIGINT or SIGTERM, once the Rollout checker
+	// and CDNi delegation refresher have finished any work in progress
+	ctx, stop := signal.NotifyContext(context.Background(), unix.SIGINT, unix.SIGTERM)
+	defer stop()
+	rolloutCheckerDone := rollout.StartChecker(ctx, time.Duration(cfg.RolloutCheckIntervalSec)*time.Second, db.DB, time.Duration(cfg.DBQueryTimeoutSeconds)*time.Second)
+	delegationRefresherDone := cdni.StartDelegationRefresher(ctx, cdniDelegationRefreshInterval(cfg), db, time.Duration(cfg.DBQueryTimeoutSeconds)*time.Second, cfg.Cdni)
 
 	trafficVault := setupTrafficVault(*riakConfigFileName, &cfg)
 
@@ -229,7 +238,7 @@ func main() {
 			file.Close()
 		}
 		httpServer.Handler = mux
-		if err := httpServer.ListenAndServeTLS(cfg.CertPath, cfg.KeyPath); err != nil {
+		if err := httpServer.ListenAndServeTLS(cfg.CertPath, cfg.KeyPath); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Errorf("stopping server: %v\n", err)
 			os.Exit(1)
 		}
@@ -256,7 +265,26 @@ func main() {
 			routing.SetBackendConfig(backendConfig)
 		}
 	}
-	signalReloader(unix.SIGHUP, reloadProfilingAndBackendConfig)
+	signalReloader(ctx, unix.SIGHUP, reloadProfilingAndBackendConfig)
+
+	log.Infoln("shutting down")
+	shutdownCtx, shutdownClose := context.WithTimeout(context.Background(), time.Duration(cfg.WriteTimeout)*time.Second)
+	defer shutdownClose()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("shutting down server: %v", err)
+	}
+	<-rolloutCheckerDone
+	<-delegationRefresherDone
+}
+
+// cdniDelegationRefreshInterval returns the interval between refreshes of the
+// footprints of delegated Delivery Services, which is zero if CDNi isn't
+// configured.
+func cdniDelegationRefreshInterval(cfg config.Config) time.Duration {
+	if cfg.Cdni == nil {
+		return 0
+	}
+	return time.Duration(cfg.Cdni.DelegationRefreshIntervalSec) * time.Second
 }
 
 func setupTrafficVault(riakConfigFileName string, cfg *config.Config) trafficvault.TrafficVault {
@@ -409,12 +437,19 @@ func continuousProfile(profiling *bool, profilingDir *string, version string) {
 	}
 }
 
-func signalReloader(sig os.Signal, f func()) {
+// signalReloader calls f whenever sig is received, until ctx is done.
+func signalReloader(ctx context.Context, sig os.Signal, f func()) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, sig)
-	for range c {
-		log.Debugln("received SIGHUP")
-		f()
+	defer signal.Stop(c)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c:
+			log.Debugln("received SIGHUP")
+			f()
+		}
 	}
 }
 
@@ -0,0 +1,616 @@
+// Package hashicorpvault provides a TrafficVault implementation which stores
+// secrets directly in a HashiCorp Vault KV version 2 secrets engine.
+package hashicorpvault
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-tc/tovalidate"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/about"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/deliveryservice"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/hashicorpvault/kv"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	notImplementedErr = Error("this Traffic Vault functionality is not implemented for the hashicorp_vault backend")
+
+	hashiCorpVaultBackendName = "hashicorp_vault"
+
+	defaultTimeoutSec = 30
+	defaultPrefix     = "trafficvault"
+
+	// The secret sub-paths, beneath the configured prefix, of each kind of key.
+	SSLKeysPath        = "sslkeys"
+	DNSSECKeysPath     = "dnssec"
+	URLSigKeysPath     = "url_sig_keys"
+	URISigningKeysPath = "uri_signing_keys"
+
+	// The custom metadata keys stored on SSL key secrets, so that their
+	// ownership and expiration can be determined without reading the keys.
+	MetadataCDN        = "cdn"
+	MetadataProvider   = "provider"
+	MetadataExpiration = "expiration"
+	// MetadataVersionPrefix prefixes the custom metadata keys which map each
+	// Delivery Service SSL key version to the Vault secret version storing it.
+	MetadataVersionPrefix = "version_"
+)
+
+type Config struct {
+	Address    string `json:"address"`
+	Token      string `json:"token"`
+	RoleID     string `json:"role_id"`
+	SecretID   string `json:"secret_id"`
+	LoginPath  string `json:"login_path"`
+	Mount      string `json:"mount"`
+	Prefix     string `json:"prefix"`
+	TimeoutSec int    `json:"timeout_sec"`
+	Insecure   bool   `json:"insecure"`
+}
+
+type HashiCorpVault struct {
+	cfg    Config
+	client *kv.Client
+}
+
+// SecretKeys is the data of every secret stored by this backend. The keys are
+// stored as a single JSON value, rather than as separate Vault fields, so that
+// they round-trip exactly.
+type SecretKeys struct {
+	Keys json.RawMessage `json:"keys"`
+}
+
+// New returns a HashiCorpVault backend using the given client and path prefix.
+// It is intended for tools which manage Traffic Vault data directly.
+func New(client *kv.Client, prefix string) *HashiCorpVault {
+	return &HashiCorpVault{cfg: Config{Prefix: prefix, Address: client.Address()}, client: client}
+}
+
+func (h *HashiCorpVault) path(kind string, name string) string {
+	return h.cfg.Prefix + "/" + kind + "/" + name
+}
+
+func (h *HashiCorpVault) readKeys(ctx context.Context, path string, version int, keys interface{}) (bool, error) {
+	secret, ok, err := h.client.Read(ctx, path, version)
+	if err != nil || !ok {
+		return false, err
+	}
+	data := SecretKeys{}
+	if err := json.Unmarshal(secret.Data, &data); err != nil {
+		return false, fmt.Errorf("unmarshalling secret '%s': %w", path, err)
+	}
+	if err := json.Unmarshal(data.Keys, keys); err != nil {
+		return false, fmt.Errorf("unmarshalling secret '%s' keys: %w", path, err)
+	}
+	return true, nil
+}
+
+func (h *HashiCorpVault) writeKeys(ctx context.Context, path string, keys interface{}) (int, error) {
+	keysJSON, err := json.Marshal(keys)
+	if err != nil {
+		return 0, fmt.Errorf("marshalling secret '%s' keys: %w", path, err)
+	}
+	return h.client.Write(ctx, path, SecretKeys{Keys: keysJSON})
+}
+
+// list returns the names of the secrets of the given kind, excluding
+// directories.
+func (h *HashiCorpVault) list(ctx context.Context, kind string) ([]string, error) {
+	names, err := h.client.List(ctx, h.cfg.Prefix+"/"+kind)
+	if err != nil {
+		return nil, err
+	}
+	secrets := make([]string, 0, len(names))
+	for _, name := range names {
+		if !strings.HasSuffix(name, "/") {
+			secrets = append(secrets, name)
+		}
+	}
+	return secrets, nil
+}
+
+// ListKeys returns the sorted names - CDN names or Delivery Service XMLIDs -
+// of every secret of the given kind, which must be one of SSLKeysPath,
+// DNSSECKeysPath, URLSigKeysPath or URISigningKeysPath.
+func (h *HashiCorpVault) ListKeys(ctx context.Context, kind string) ([]string, error) {
+	names, err := h.list(ctx, kind)
+	if err != nil {
+		return nil, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetDeliveryServiceSSLKeyVersions returns every retained, undeleted version
+// of the SSL keys for the delivery service identified by the given xmlID, in
+// ascending order.
+func (h *HashiCorpVault) GetDeliveryServiceSSLKeyVersions(xmlID string, ctx context.Context) ([]int64, error) {
+	md, ok, err := h.client.ReadMetadata(ctx, h.path(SSLKeysPath, xmlID))
+	if err != nil {
+		return nil, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	versions := []int64{}
+	if !ok {
+		return versions, nil
+	}
+	for k := range md.CustomMetadata {
+		if !strings.HasPrefix(k, MetadataVersionPrefix) {
+			continue
+		}
+		version := strings.TrimPrefix(k, MetadataVersionPrefix)
+		if _, ok := sslKeyVaultVersion(md, version); !ok {
+			continue
+		}
+		if v, err := strconv.ParseInt(version, 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, nil
+}
+
+// sslKeyVaultVersion returns the Vault secret version storing the given
+// Delivery Service SSL key version, and whether it exists and has not been
+// deleted.
+func sslKeyVaultVersion(md kv.Metadata, version string) (int, bool) {
+	vaultVersion, err := strconv.Atoi(md.CustomMetadata[MetadataVersionPrefix+version])
+	if err != nil {
+		return 0, false
+	}
+	vm, ok := md.Version(vaultVersion)
+	if !ok || vm.Deleted() {
+		return 0, false
+	}
+	return vaultVersion, true
+}
+
+// GetDeliveryServiceSSLKeys retrieves the SSL keys of the given version for
+// the delivery service identified by the given xmlID. If version is empty,
+// the implementation should return the latest version.
+func (h *HashiCorpVault) GetDeliveryServiceSSLKeys(xmlID string, version string, tx *sql.Tx, ctx context.Context) (tc.DeliveryServiceSSLKeysV15, bool, error) {
+	path := h.path(SSLKeysPath, xmlID)
+	md, ok, err := h.client.ReadMetadata(ctx, path)
+	if err != nil {
+		return tc.DeliveryServiceSSLKeysV15{}, false, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	} else if !ok {
+		return tc.DeliveryServiceSSLKeysV15{}, false, nil
+	}
+	vaultVersion := 0
+	if version != "" {
+		if vaultVersion, ok = sslKeyVaultVersion(md, version); !ok {
+			return tc.DeliveryServiceSSLKeysV15{}, false, nil
+		}
+	}
+	sslKey := tc.DeliveryServiceSSLKeysV15{}
+	if ok, err := h.readKeys(ctx, path, vaultVersion, &sslKey); err != nil {
+		return tc.DeliveryServiceSSLKeysV15{}, false, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	} else if !ok {
+		return tc.DeliveryServiceSSLKeysV15{}, false, nil
+	}
+	if sslKey.Expiration.IsZero() {
+		if expiration, err := time.Parse(time.RFC3339, md.CustomMetadata[MetadataExpiration]); err == nil {
+			sslKey.Expiration = expiration
+		}
+	}
+	return sslKey, true, nil
+}
+
+// GetExpirationInformation returns the expiration information for all SSL Keys.
+func (h *HashiCorpVault) GetExpirationInformation(tx *sql.Tx, ctx context.Context, days int) ([]tc.SSLKeyExpirationInformation, error) {
+	fedMap := map[string]bool{}
+	fedRows, err := tx.Query("SELECT DISTINCT(ds.xml_id) FROM federation_deliveryservice AS fd JOIN deliveryservice AS ds ON ds.id = fd.deliveryservice")
+	if err != nil {
+		return []tc.SSLKeyExpirationInformation{}, err
+	}
+	defer log.Close(fedRows, "closing federated delivery services rows")
+	for fedRows.Next() {
+		var fedString string
+		if err = fedRows.Scan(&fedString); err != nil {
+			return []tc.SSLKeyExpirationInformation{}, err
+		}
+		fedMap[fedString] = true
+	}
+
+	inactiveList := map[string]bool{}
+	iaRows, err := tx.Query("SELECT xml_id FROM deliveryservice WHERE NOT active")
+	if err != nil {
+		return []tc.SSLKeyExpirationInformation{}, err
+	}
+	defer log.Close(iaRows, "closing inactive delivery services rows")
+	for iaRows.Next() {
+		var inactiveXmlId string
+		if err = iaRows.Scan(&inactiveXmlId); err != nil {
+			return []tc.SSLKeyExpirationInformation{}, err
+		}
+		inactiveList[inactiveXmlId] = true
+	}
+
+	xmlIDs, err := h.list(ctx, SSLKeysPath)
+	if err != nil {
+		return []tc.SSLKeyExpirationInformation{}, errors.New("Traffic Vault HashiCorp Vault: listing SSL keys: " + err.Error())
+	}
+	sort.Strings(xmlIDs)
+
+	cutoff := time.Now().AddDate(0, 0, days)
+	expirationInfos := []tc.SSLKeyExpirationInformation{}
+	for _, xmlID := range xmlIDs {
+		if inactiveList[xmlID] {
+			continue
+		}
+		md, ok, err := h.client.ReadMetadata(ctx, h.path(SSLKeysPath, xmlID))
+		if err != nil {
+			return []tc.SSLKeyExpirationInformation{}, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+		}
+		if !ok {
+			continue
+		}
+		if latest, ok := md.Version(md.CurrentVersion); !ok || latest.Deleted() {
+			continue
+		}
+		expiration, err := time.Parse(time.RFC3339, md.CustomMetadata[MetadataExpiration])
+		if err != nil {
+			log.Warnf("Traffic Vault HashiCorp Vault: SSL keys for delivery service '%s' have an invalid expiration '%s', skipping", xmlID, md.CustomMetadata[MetadataExpiration])
+			continue
+		}
+		if days != 0 && expiration.After(cutoff) {
+			continue
+		}
+		expirationInfos = append(expirationInfos, tc.SSLKeyExpirationInformation{
+			DeliveryService: xmlID,
+			CDN:             md.CustomMetadata[MetadataCDN],
+			Provider:        md.CustomMetadata[MetadataProvider],
+			Expiration:      expiration,
+			Federated:       fedMap[xmlID],
+		})
+	}
+	return expirationInfos, nil
+}
+
+// PutDeliveryServiceSSLKeys stores the given SSL keys for a delivery service.
+// Each call creates a new Vault version of the delivery service's secret, and
+// the custom metadata of the secret maps the keys' version to it.
+func (h *HashiCorpVault) PutDeliveryServiceSSLKeys(key tc.DeliveryServiceSSLKeys, tx *sql.Tx, ctx context.Context) error {
+	// the stored keys keep their encoding; only the copy used to find the
+	// expiration is decoded
+	cert := key.Certificate
+	if err := deliveryservice.Base64DecodeCertificate(&cert); err != nil {
+		return fmt.Errorf("decoding SSL keys, %w", err)
+	}
+	expiration, _, err := deliveryservice.ParseExpirationAndSansFromCert([]byte(cert.Crt), key.Hostname)
+	if err != nil {
+		return fmt.Errorf("parsing expiration from certificate: %w", err)
+	}
+	return h.putSSLKeys(ctx, key, expiration)
+}
+
+// CopyDeliveryServiceSSLKeys stores the given SSL keys for a delivery service
+// as they are, like PutDeliveryServiceSSLKeys, but doesn't require their
+// certificate to be valid. Keys whose certificate expiration can't be parsed
+// are stored without one. This is for copying existing keys, where one bad
+// certificate mustn't keep the rest from being copied.
+func (h *HashiCorpVault) CopyDeliveryServiceSSLKeys(key tc.DeliveryServiceSSLKeys, ctx context.Context) error {
+	expiration := time.Time{}
+	cert := key.Certificate
+	if err := deliveryservice.Base64DecodeCertificate(&cert); err != nil {
+		log.Warnf("Traffic Vault HashiCorp Vault: copying SSL keys for delivery service '%s' version %d without an expiration: decoding certificate: %s", key.DeliveryService, key.Version, err.Error())
+	} else if expiration, _, err = deliveryservice.ParseExpirationAndSansFromCert([]byte(cert.Crt), key.Hostname); err != nil {
+		log.Warnf("Traffic Vault HashiCorp Vault: copying SSL keys for delivery service '%s' version %d without an expiration: parsing certificate: %s", key.DeliveryService, key.Version, err.Error())
+		expiration = time.Time{}
+	}
+	return h.putSSLKeys(ctx, key, expiration)
+}
+
+// putSSLKeys writes the keys, and maps their version to the new Vault version
+// in the secret's custom metadata. A zero expiration removes the expiration
+// from the metadata.
+func (h *HashiCorpVault) putSSLKeys(ctx context.Context, key tc.DeliveryServiceSSLKeys, expiration time.Time) error {
+	path := h.path(SSLKeysPath, key.DeliveryService)
+	vaultVersion, err := h.writeKeys(ctx, path, key)
+	if err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+
+	md, _, err := h.client.ReadMetadata(ctx, path)
+	if err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	custom := map[string]string{}
+	for k, v := range md.CustomMetadata {
+		// drop mappings to versions Vault no longer retains
+		if strings.HasPrefix(k, MetadataVersionPrefix) {
+			if _, ok := sslKeyVaultVersion(md, strings.TrimPrefix(k, MetadataVersionPrefix)); !ok {
+				continue
+			}
+		}
+		custom[k] = v
+	}
+	custom[MetadataVersionPrefix+strconv.FormatInt(int64(key.Version), 10)] = strconv.Itoa(vaultVersion)
+	custom[MetadataCDN] = key.CDN
+	custom[MetadataProvider] = key.AuthType
+	if expiration.IsZero() {
+		delete(custom, MetadataExpiration)
+	} else {
+		custom[MetadataExpiration] = expiration.UTC().Format(time.RFC3339)
+	}
+	if err := h.client.WriteCustomMetadata(ctx, path, custom); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+// DeleteDeliveryServiceSSLKeys removes the SSL keys of the given version (or latest
+// if version is empty) for the delivery service identified by the given xmlID.
+func (h *HashiCorpVault) DeleteDeliveryServiceSSLKeys(xmlID string, version string, tx *sql.Tx, ctx context.Context) error {
+	path := h.path(SSLKeysPath, xmlID)
+	if version == "" {
+		if err := h.client.DeleteVersions(ctx, path); err != nil {
+			return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+		}
+		return nil
+	}
+	md, ok, err := h.client.ReadMetadata(ctx, path)
+	if err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	if !ok {
+		return nil
+	}
+	vaultVersion, ok := sslKeyVaultVersion(md, version)
+	if !ok {
+		return nil
+	}
+	if err := h.client.DeleteVersions(ctx, path, vaultVersion); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+// DeleteOldDeliveryServiceSSLKeys takes a set of existingXMLIDs as input and will remove
+// all SSL keys for delivery services in the CDN identified by the given cdnName that
+// do not contain an xmlID in the given set of existingXMLIDs. This method is called
+// during a snapshot operation in order to delete SSL keys for delivery services that
+// no longer exist.
+func (h *HashiCorpVault) DeleteOldDeliveryServiceSSLKeys(existingXMLIDs map[string]struct{}, cdnName string, tx *sql.Tx, ctx context.Context) error {
+	xmlIDs, err := h.list(ctx, SSLKeysPath)
+	if err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: listing SSL keys: " + err.Error())
+	}
+	for _, xmlID := range xmlIDs {
+		if _, ok := existingXMLIDs[xmlID]; ok {
+			continue
+		}
+		path := h.path(SSLKeysPath, xmlID)
+		md, ok, err := h.client.ReadMetadata(ctx, path)
+		if err != nil {
+			return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+		}
+		if !ok || md.CustomMetadata[MetadataCDN] != cdnName {
+			continue
+		}
+		if err := h.client.Destroy(ctx, path); err != nil {
+			return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// GetCDNSSLKeys retrieves all the SSL keys for delivery services in the CDN identified
+// by the given cdnName.
+func (h *HashiCorpVault) GetCDNSSLKeys(cdnName string, tx *sql.Tx, ctx context.Context) ([]tc.CDNSSLKey, error) {
+	keys := []tc.CDNSSLKey{}
+	xmlIDs, err := h.list(ctx, SSLKeysPath)
+	if err != nil {
+		return keys, errors.New("Traffic Vault HashiCorp Vault: listing SSL keys: " + err.Error())
+	}
+	sort.Strings(xmlIDs)
+	for _, xmlID := range xmlIDs {
+		path := h.path(SSLKeysPath, xmlID)
+		md, ok, err := h.client.ReadMetadata(ctx, path)
+		if err != nil {
+			return keys, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+		}
+		if !ok || md.CustomMetadata[MetadataCDN] != cdnName {
+			continue
+		}
+		key := tc.CDNSSLKey{}
+		if ok, err := h.readKeys(ctx, path, 0, &key); err != nil {
+			log.Errorf("Traffic Vault HashiCorp Vault: couldn't read SSL keys for delivery service '%s': %v", xmlID, err)
+			continue
+		} else if !ok {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (h *HashiCorpVault) GetDNSSECKeys(cdnName string, tx *sql.Tx, ctx context.Context) (tc.DNSSECKeysTrafficVault, bool, error) {
+	keys := tc.DNSSECKeysTrafficVault{}
+	ok, err := h.readKeys(ctx, h.path(DNSSECKeysPath, cdnName), 0, &keys)
+	if err != nil {
+		return tc.DNSSECKeysTrafficVault{}, false, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return keys, ok, nil
+}
+
+func (h *HashiCorpVault) PutDNSSECKeys(cdnName string, keys tc.DNSSECKeysTrafficVault, tx *sql.Tx, ctx context.Context) error {
+	if _, err := h.writeKeys(ctx, h.path(DNSSECKeysPath, cdnName), keys); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+func (h *HashiCorpVault) DeleteDNSSECKeys(cdnName string, tx *sql.Tx, ctx context.Context) error {
+	if err := h.client.Destroy(ctx, h.path(DNSSECKeysPath, cdnName)); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+func (h *HashiCorpVault) GetURLSigKeys(xmlID string, tx *sql.Tx, ctx context.Context) (tc.URLSigKeys, bool, error) {
+	keys := tc.URLSigKeys{}
+	ok, err := h.readKeys(ctx, h.path(URLSigKeysPath, xmlID), 0, &keys)
+	if err != nil {
+		return tc.URLSigKeys{}, false, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return keys, ok, nil
+}
+
+func (h *HashiCorpVault) PutURLSigKeys(xmlID string, keys tc.URLSigKeys, tx *sql.Tx, ctx context.Context) error {
+	if _, err := h.writeKeys(ctx, h.path(URLSigKeysPath, xmlID), keys); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+func (h *HashiCorpVault) DeleteURLSigKeys(xmlID string, tx *sql.Tx, ctx context.Context) error {
+	if err := h.client.Destroy(ctx, h.path(URLSigKeysPath, xmlID)); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+func (h *HashiCorpVault) GetURISigningKeys(xmlID string, tx *sql.Tx, ctx context.Context) ([]byte, bool, error) {
+	keys := json.RawMessage{}
+	ok, err := h.readKeys(ctx, h.path(URISigningKeysPath, xmlID), 0, &keys)
+	if err != nil {
+		return []byte{}, false, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return keys, ok, nil
+}
+
+func (h *HashiCorpVault) PutURISigningKeys(xmlID string, keysJson []byte, tx *sql.Tx, ctx context.Context) error {
+	if !json.Valid(keysJson) {
+		return errors.New("URI signing keys are not valid JSON")
+	}
+	if _, err := h.writeKeys(ctx, h.path(URISigningKeysPath, xmlID), json.RawMessage(keysJson)); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+func (h *HashiCorpVault) DeleteURISigningKeys(xmlID string, tx *sql.Tx, ctx context.Context) error {
+	if err := h.client.Destroy(ctx, h.path(URISigningKeysPath, xmlID)); err != nil {
+		return errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return nil
+}
+
+func (h *HashiCorpVault) Ping(tx *sql.Tx, ctx context.Context) (tc.TrafficVaultPing, error) {
+	if _, err := h.client.Health(ctx); err != nil {
+		return tc.TrafficVaultPing{}, errors.New("Traffic Vault HashiCorp Vault: " + err.Error())
+	}
+	return tc.TrafficVaultPing{Status: "OK", Server: h.cfg.Address}, nil
+}
+
+func (h *HashiCorpVault) GetBucketKey(bucket string, key string, tx *sql.Tx) ([]byte, bool, error) {
+	return nil, false, notImplementedErr
+}
+
+func init() {
+	trafficvault.AddBackend(hashiCorpVaultBackendName, hashiCorpVaultLoad)
+}
+
+func hashiCorpVaultLoad(b json.RawMessage) (trafficvault.TrafficVault, error) {
+	cfg := Config{}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, errors.New("unmarshalling HashiCorp Vault config: " + err.Error())
+	}
+	if err := validateConfig(cfg); err != nil {
+		return nil, errors.New("validating HashiCorp Vault config: " + err.Error())
+	}
+	if cfg.TimeoutSec == 0 {
+		cfg.TimeoutSec = defaultTimeoutSec
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = defaultPrefix
+	}
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+
+	client := kv.NewClient(kv.Config{
+		Address:   cfg.Address,
+		Mount:     cfg.Mount,
+		Token:     cfg.Token,
+		RoleID:    cfg.RoleID,
+		SecretID:  cfg.SecretID,
+		LoginPath: cfg.LoginPath,
+		Timeout:   time.Duration(cfg.TimeoutSec) * time.Second,
+		Insecure:  cfg.Insecure,
+		UserAgent: about.UserAgent(),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.TimeoutSec)*time.Second)
+	defer cancel()
+	// NOTE: not fatal since Traffic Vault not being available at startup shouldn't be fatal
+	if err := client.Login(ctx); err != nil {
+		log.Errorln("logging in to the Traffic Vault HashiCorp Vault: " + err.Error())
+	} else if _, err := client.Health(ctx); err != nil {
+		log.Errorln("checking the Traffic Vault HashiCorp Vault health: " + err.Error())
+	} else {
+		log.Infoln("successfully checked the Traffic Vault HashiCorp Vault health")
+	}
+
+	return &HashiCorpVault{cfg: cfg, client: client}, nil
+}
+
+func validateConfig(cfg Config) error {
+	errs := tovalidate.ToErrors(validation.Errors{
+		"address":     validation.Validate(cfg.Address, validation.Required, is.URL),
+		"timeout_sec": validation.Validate(cfg.TimeoutSec, validation.Min(0)),
+	})
+	if cfg.RoleID != "" || cfg.SecretID != "" {
+		if cfg.Token != "" {
+			errs = append(errs, errors.New("token and role_id/secret_id cannot both be set"))
+		}
+		appRoleErrs := tovalidate.ToErrors(validation.Errors{
+			"role_id":   validation.Validate(cfg.RoleID, validation.Required),
+			"secret_id": validation.Validate(cfg.SecretID, validation.Required),
+		})
+		errs = append(errs, appRoleErrs...)
+	} else if cfg.Token == "" {
+		errs = append(errs, errors.New("one of either token or role_id and secret_id is required"))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return util.JoinErrs(errs)
+}
@@ -0,0 +1,387 @@
+package hashicorpvault
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/hashicorpvault/kv"
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/hashicorpvault/kv/kvtest"
+
+	"gopkg.in/DATA-DOG/go-sqlmock.v1"
+)
+
+func newTestBackend(t *testing.T) (*HashiCorpVault, *kvtest.Vault) {
+	t.Helper()
+	vault := kvtest.NewVault()
+	cfg, err := json.Marshal(Config{Address: vault.URL, RoleID: kvtest.RoleID, SecretID: kvtest.SecretID})
+	if err != nil {
+		t.Fatalf("marshalling config: %v", err)
+	}
+	tv, err := hashiCorpVaultLoad(cfg)
+	if err != nil {
+		vault.Close()
+		t.Fatalf("loading backend: %v", err)
+	}
+	return tv.(*HashiCorpVault), vault
+}
+
+func makeSSLKeys(t *testing.T, xmlID string, cdn string, version int64, notAfter time.Time) tc.DeliveryServiceSSLKeys {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(version),
+		Subject:      pkix.Name{CommonName: xmlID + ".example.test"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return tc.DeliveryServiceSSLKeys{
+		AuthType:        tc.SelfSignedCertAuthType,
+		CDN:             cdn,
+		DeliveryService: xmlID,
+		Hostname:        xmlID + ".example.test",
+		Key:             xmlID,
+		Version:         util.JSONIntStr(version),
+		Certificate: tc.DeliveryServiceSSLKeysCertificate{
+			Crt: base64.StdEncoding.EncodeToString(crt),
+			Key: base64.StdEncoding.EncodeToString([]byte("not a real key")),
+			CSR: base64.StdEncoding.EncodeToString([]byte("not a real csr")),
+		},
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"token", Config{Address: "https://vault.test:8200", Token: "t"}, true},
+		{"approle", Config{Address: "https://vault.test:8200", RoleID: "r", SecretID: "s"}, true},
+		{"no auth", Config{Address: "https://vault.test:8200"}, false},
+		{"both auths", Config{Address: "https://vault.test:8200", Token: "t", RoleID: "r", SecretID: "s"}, false},
+		{"partial approle", Config{Address: "https://vault.test:8200", RoleID: "r"}, false},
+		{"no address", Config{Token: "t"}, false},
+	}
+	for _, test := range tests {
+		err := validateConfig(test.cfg)
+		if test.valid && err != nil {
+			t.Errorf("%s: expected valid config, got error: %v", test.name, err)
+		} else if !test.valid && err == nil {
+			t.Errorf("%s: expected invalid config, got no error", test.name)
+		}
+	}
+}
+
+func TestSSLKeyVersions(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	ctx := context.Background()
+
+	if _, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx); err != nil || ok {
+		t.Fatalf("expected no keys before any were stored, got ok: %t, err: %v", ok, err)
+	}
+
+	expiration := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	v1 := makeSSLKeys(t, "ds1", "cdn1", 1, expiration)
+	v2 := makeSSLKeys(t, "ds1", "cdn1", 2, expiration.Add(time.Hour))
+	for _, keys := range []tc.DeliveryServiceSSLKeys{v1, v2} {
+		if err := tv.PutDeliveryServiceSSLKeys(keys, nil, ctx); err != nil {
+			t.Fatalf("putting version %d: %v", keys.Version, err)
+		}
+	}
+
+	latest, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting latest keys: ok: %t, err: %v", ok, err)
+	}
+	if latest.Version != 2 || latest.Certificate != v2.Certificate {
+		t.Errorf("expected latest keys to be version 2, got version %d", latest.Version)
+	}
+	if !latest.Expiration.Equal(expiration.Add(time.Hour)) {
+		t.Errorf("expected expiration %v, got %v", expiration.Add(time.Hour), latest.Expiration)
+	}
+
+	old, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "1", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting version 1 keys: ok: %t, err: %v", ok, err)
+	}
+	if old.Version != 1 || old.Certificate != v1.Certificate {
+		t.Errorf("expected version 1 keys, got version %d", old.Version)
+	}
+
+	versions, err := tv.GetDeliveryServiceSSLKeyVersions("ds1", ctx)
+	if err != nil {
+		t.Fatalf("getting versions: %v", err)
+	}
+	if !reflect.DeepEqual(versions, []int64{1, 2}) {
+		t.Errorf("expected versions [1 2], got %v", versions)
+	}
+
+	if err := tv.DeleteDeliveryServiceSSLKeys("ds1", "1", nil, ctx); err != nil {
+		t.Fatalf("deleting version 1: %v", err)
+	}
+	if _, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "1", nil, ctx); err != nil || ok {
+		t.Errorf("expected version 1 to be deleted, got ok: %t, err: %v", ok, err)
+	}
+	if _, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx); err != nil || !ok {
+		t.Errorf("expected latest to remain after deleting version 1, got ok: %t, err: %v", ok, err)
+	}
+
+	if err := tv.DeleteDeliveryServiceSSLKeys("ds1", "", nil, ctx); err != nil {
+		t.Fatalf("deleting latest: %v", err)
+	}
+	if _, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx); err != nil || ok {
+		t.Errorf("expected latest to be deleted, got ok: %t, err: %v", ok, err)
+	}
+}
+
+func TestSSLKeyVersionsPruned(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	vault.MaxVersions = 2
+	ctx := context.Background()
+
+	expiration := time.Now().Add(24 * time.Hour)
+	for v := int64(1); v <= 3; v++ {
+		if err := tv.PutDeliveryServiceSSLKeys(makeSSLKeys(t, "ds1", "cdn1", v, expiration), nil, ctx); err != nil {
+			t.Fatalf("putting version %d: %v", v, err)
+		}
+	}
+	versions, err := tv.GetDeliveryServiceSSLKeyVersions("ds1", ctx)
+	if err != nil {
+		t.Fatalf("getting versions: %v", err)
+	}
+	if !reflect.DeepEqual(versions, []int64{2, 3}) {
+		t.Errorf("expected versions no longer retained by Vault to be dropped, got %v", versions)
+	}
+}
+
+func TestCopySSLKeys(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	ctx := context.Background()
+
+	expiration := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	valid := makeSSLKeys(t, "ds1", "cdn1", 1, expiration)
+	if err := tv.CopyDeliveryServiceSSLKeys(valid, ctx); err != nil {
+		t.Fatalf("copying valid keys: %v", err)
+	}
+	if keys, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx); err != nil || !ok || !keys.Expiration.Equal(expiration) {
+		t.Fatalf("expected copied keys with expiration %v, got ok: %t, expiration: %v, err: %v", expiration, ok, keys.Expiration, err)
+	}
+
+	invalid := makeSSLKeys(t, "ds1", "cdn1", 2, expiration)
+	invalid.Certificate.Crt = base64.StdEncoding.EncodeToString([]byte("not a real certificate"))
+	if err := tv.PutDeliveryServiceSSLKeys(invalid, nil, ctx); err == nil {
+		t.Error("expected putting keys with an invalid certificate to fail")
+	}
+	if err := tv.CopyDeliveryServiceSSLKeys(invalid, ctx); err != nil {
+		t.Fatalf("copying keys with an invalid certificate: %v", err)
+	}
+	keys, ok, err := tv.GetDeliveryServiceSSLKeys("ds1", "", nil, ctx)
+	if err != nil || !ok {
+		t.Fatalf("getting copied keys: ok: %t, err: %v", ok, err)
+	}
+	if keys.Version != 2 || keys.Certificate != invalid.Certificate {
+		t.Errorf("expected the keys with an invalid certificate to be copied as they are, got version %d certificate %+v", keys.Version, keys.Certificate)
+	}
+	if !keys.Expiration.IsZero() {
+		t.Errorf("expected the keys with an invalid certificate to have no expiration, got %v", keys.Expiration)
+	}
+}
+
+func TestCDNSSLKeys(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	ctx := context.Background()
+
+	expiration := time.Now().Add(24 * time.Hour)
+	for _, keys := range []tc.DeliveryServiceSSLKeys{
+		makeSSLKeys(t, "ds1", "cdn1", 1, expiration),
+		makeSSLKeys(t, "ds2", "cdn1", 1, expiration),
+		makeSSLKeys(t, "ds3", "cdn2", 1, expiration),
+	} {
+		if err := tv.PutDeliveryServiceSSLKeys(keys, nil, ctx); err != nil {
+			t.Fatalf("putting keys for %s: %v", keys.DeliveryService, err)
+		}
+	}
+
+	keys, err := tv.GetCDNSSLKeys("cdn1", nil, ctx)
+	if err != nil {
+		t.Fatalf("getting CDN SSL keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].DeliveryService != "ds1" || keys[1].DeliveryService != "ds2" {
+		t.Errorf("expected keys for ds1 and ds2, got %+v", keys)
+	}
+
+	if err := tv.DeleteOldDeliveryServiceSSLKeys(map[string]struct{}{"ds2": {}}, "cdn1", nil, ctx); err != nil {
+		t.Fatalf("deleting old keys: %v", err)
+	}
+	xmlIDs, err := tv.ListKeys(ctx, SSLKeysPath)
+	if err != nil {
+		t.Fatalf("listing keys: %v", err)
+	}
+	if !reflect.DeepEqual(xmlIDs, []string{"ds2", "ds3"}) {
+		t.Errorf("expected only ds1 to be deleted, got remaining keys %v", xmlIDs)
+	}
+}
+
+func TestGetExpirationInformation(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	ctx := context.Background()
+
+	soon := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	later := time.Now().AddDate(0, 0, 60)
+	for _, keys := range []tc.DeliveryServiceSSLKeys{
+		makeSSLKeys(t, "fed", "cdn1", 1, soon),
+		makeSSLKeys(t, "inactive", "cdn1", 1, soon),
+		makeSSLKeys(t, "later", "cdn1", 1, later),
+	} {
+		if err := tv.PutDeliveryServiceSSLKeys(keys, nil, ctx); err != nil {
+			t.Fatalf("putting keys for %s: %v", keys.DeliveryService, err)
+		}
+	}
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("opening mock database: %v", err)
+	}
+	defer mockDB.Close()
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"xml_id"}).AddRow("fed"))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"xml_id"}).AddRow("inactive"))
+	tx, err := mockDB.Begin()
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+
+	infos, err := tv.GetExpirationInformation(tx, ctx, 30)
+	if err != nil {
+		t.Fatalf("getting expiration information: %v", err)
+	}
+	expected := []tc.SSLKeyExpirationInformation{{
+		DeliveryService: "fed",
+		CDN:             "cdn1",
+		Provider:        tc.SelfSignedCertAuthType,
+		Expiration:      soon,
+		Federated:       true,
+	}}
+	if !reflect.DeepEqual(infos, expected) {
+		t.Errorf("expected %+v, got %+v", expected, infos)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet database expectations: %v", err)
+	}
+}
+
+func TestOtherKeys(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	ctx := context.Background()
+
+	dnssec := tc.DNSSECKeysTrafficVault{"cdn1": tc.DNSSECKeySetV11{
+		ZSK: []tc.DNSSECKeyV11{{InceptionDateUnix: 1, ExpirationDateUnix: 2, Name: "cdn1.", Status: tc.DNSSECKeyStatusNew}},
+	}}
+	if err := tv.PutDNSSECKeys("cdn1", dnssec, nil, ctx); err != nil {
+		t.Fatalf("putting DNSSEC keys: %v", err)
+	}
+	if actual, ok, err := tv.GetDNSSECKeys("cdn1", nil, ctx); err != nil || !ok || !reflect.DeepEqual(actual, dnssec) {
+		t.Errorf("expected DNSSEC keys %+v, got %+v, ok: %t, err: %v", dnssec, actual, ok, err)
+	}
+
+	urlSigKeys := tc.URLSigKeys{"key0": "foo", "key1": "bar"}
+	if err := tv.PutURLSigKeys("ds1", urlSigKeys, nil, ctx); err != nil {
+		t.Fatalf("putting URL sig keys: %v", err)
+	}
+	if actual, ok, err := tv.GetURLSigKeys("ds1", nil, ctx); err != nil || !ok || !reflect.DeepEqual(actual, urlSigKeys) {
+		t.Errorf("expected URL sig keys %+v, got %+v, ok: %t, err: %v", urlSigKeys, actual, ok, err)
+	}
+
+	uriSigningKeys := []byte(`{"issuer":{"keys":[]}}`)
+	if err := tv.PutURISigningKeys("ds1", uriSigningKeys, nil, ctx); err != nil {
+		t.Fatalf("putting URI signing keys: %v", err)
+	}
+	if actual, ok, err := tv.GetURISigningKeys("ds1", nil, ctx); err != nil || !ok || string(actual) != string(uriSigningKeys) {
+		t.Errorf("expected URI signing keys %s, got %s, ok: %t, err: %v", uriSigningKeys, actual, ok, err)
+	}
+	if err := tv.PutURISigningKeys("ds1", []byte("{"), nil, ctx); err == nil {
+		t.Error("expected an error putting invalid URI signing keys")
+	}
+
+	if err := tv.DeleteDNSSECKeys("cdn1", nil, ctx); err != nil {
+		t.Errorf("deleting DNSSEC keys: %v", err)
+	}
+	if err := tv.DeleteURLSigKeys("ds1", nil, ctx); err != nil {
+		t.Errorf("deleting URL sig keys: %v", err)
+	}
+	if err := tv.DeleteURISigningKeys("ds1", nil, ctx); err != nil {
+		t.Errorf("deleting URI signing keys: %v", err)
+	}
+	if paths := vault.Paths(); len(paths) != 0 {
+		t.Errorf("expected all secrets to be destroyed, got %v", paths)
+	}
+}
+
+func TestReauthentication(t *testing.T) {
+	tv, vault := newTestBackend(t)
+	defer vault.Close()
+	ctx := context.Background()
+
+	if err := tv.PutURLSigKeys("ds1", tc.URLSigKeys{"key0": "foo"}, nil, ctx); err != nil {
+		t.Fatalf("putting URL sig keys: %v", err)
+	}
+	vault.RevokeTokens()
+	if _, ok, err := tv.GetURLSigKeys("ds1", nil, ctx); err != nil || !ok {
+		t.Errorf("expected an expired token to be renewed by logging in again, got ok: %t, err: %v", ok, err)
+	}
+
+	if ping, err := tv.Ping(nil, ctx); err != nil || ping.Status != "OK" {
+		t.Errorf("expected ping status OK, got %+v, err: %v", ping, err)
+	}
+
+	unauthorized := New(kv.NewClient(kv.Config{Address: vault.URL, Token: "wrong"}), defaultPrefix)
+	if _, _, err := unauthorized.GetURLSigKeys("ds1", nil, ctx); err == nil {
+		t.Error("expected an error using an invalid token")
+	}
+}
@@ -0,0 +1,437 @@
+// Package kv provides a minimal client for the HashiCorp Vault KV version 2
+// secrets engine.
+package kv
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-log"
+	"github.com/apache/trafficcontrol/lib/go-rfc"
+)
+
+const (
+	// DefaultLoginPath is the default URI path of the AppRole login endpoint.
+	DefaultLoginPath = "/v1/auth/approle/login"
+	// DefaultMount is the default path at which the KV version 2 secrets
+	// engine is mounted.
+	DefaultMount = "secret"
+
+	defaultTimeout   = 30 * time.Second
+	defaultUserAgent = "TrafficOps"
+	vaultTokenHeader = "X-Vault-Token"
+	methodList       = "LIST"
+)
+
+// Config is the configuration of a Client.
+type Config struct {
+	// Address is the base URL of the Vault server, e.g. https://vault:8200.
+	Address string
+	// Mount is the path at which the KV version 2 secrets engine is mounted.
+	Mount string
+	// Token is a static Vault token. If RoleID is set, Token is ignored and a
+	// token is obtained by logging in with AppRole instead.
+	Token string
+	// RoleID and SecretID are the AppRole credentials used to log in.
+	RoleID   string
+	SecretID string
+	// LoginPath is the URI path of the AppRole login endpoint.
+	LoginPath string
+	Timeout   time.Duration
+	Insecure  bool
+	// UserAgent is the User-Agent of requests to Vault.
+	UserAgent string
+}
+
+// Client is a HashiCorp Vault KV version 2 client. It is safe for use by
+// multiple goroutines.
+type Client struct {
+	cfg        Config
+	httpClient *http.Client
+	token      string
+	m          *sync.RWMutex
+}
+
+// VersionMetadata is the metadata of a single version of a secret.
+type VersionMetadata struct {
+	Version      int       `json:"version"`
+	CreatedTime  time.Time `json:"created_time"`
+	DeletionTime string    `json:"deletion_time"`
+	Destroyed    bool      `json:"destroyed"`
+}
+
+// Deleted returns whether the version has been deleted or destroyed, i.e.
+// whether its data can no longer be read.
+func (v VersionMetadata) Deleted() bool {
+	return v.Destroyed || v.DeletionTime != ""
+}
+
+// Secret is a single version of a secret.
+type Secret struct {
+	Data     json.RawMessage `json:"data"`
+	Metadata VersionMetadata `json:"metadata"`
+}
+
+// Metadata is the metadata of a secret, shared by all of its versions.
+type Metadata struct {
+	CurrentVersion int                        `json:"current_version"`
+	OldestVersion  int                        `json:"oldest_version"`
+	CustomMetadata map[string]string          `json:"custom_metadata"`
+	Versions       map[string]VersionMetadata `json:"versions"`
+}
+
+// Version returns the metadata of the given version of the secret, and
+// whether it exists.
+func (m Metadata) Version(v int) (VersionMetadata, bool) {
+	vm, ok := m.Versions[strconv.Itoa(v)]
+	return vm, ok
+}
+
+// Health is the response of the Vault health endpoint.
+type Health struct {
+	Initialized bool   `json:"initialized"`
+	Sealed      bool   `json:"sealed"`
+	Standby     bool   `json:"standby"`
+	Version     string `json:"version"`
+	ClusterName string `json:"cluster_name"`
+}
+
+// NewClient creates a new Client. It does not log in; if AppRole credentials
+// are configured, the first request will do so.
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = DefaultMount
+	}
+	if cfg.LoginPath == "" {
+		cfg.LoginPath = DefaultLoginPath
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = defaultUserAgent
+	}
+	cfg.Mount = strings.Trim(cfg.Mount, "/")
+	return &Client{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+			Transport: &http.Transport{
+				TLSClientConfig:     &tls.Config{InsecureSkipVerify: cfg.Insecure, MinVersion: tls.VersionTLS12},
+				TLSHandshakeTimeout: 10 * time.Second,
+			},
+		},
+		token: cfg.Token,
+		m:     &sync.RWMutex{},
+	}
+}
+
+// Address returns the address of the Vault server.
+func (c *Client) Address() string {
+	return c.cfg.Address
+}
+
+type errResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// Login obtains a new token with the configured AppRole credentials. It is a
+// no-op if no AppRole is configured.
+func (c *Client) Login(ctx context.Context) error {
+	if c.cfg.RoleID == "" {
+		return nil
+	}
+	body, err := json.Marshal(map[string]string{"role_id": c.cfg.RoleID, "secret_id": c.cfg.SecretID})
+	if err != nil {
+		return errors.New("marshalling login request body: " + err.Error())
+	}
+	resp, err := c.do(ctx, http.MethodPost, c.cfg.LoginPath, nil, body, "")
+	if err != nil {
+		return errors.New("doing login request: " + err.Error())
+	}
+	defer log.Close(resp.Body, "closing HashiCorp Vault login response body")
+	if err := checkStatus(resp); err != nil {
+		return errors.New("logging in: " + err.Error())
+	}
+	loginResp := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return errors.New("decoding login response body: " + err.Error())
+	}
+	if loginResp.Auth.ClientToken == "" {
+		return errors.New("login response body contained empty auth.client_token")
+	}
+	c.m.Lock()
+	c.token = loginResp.Auth.ClientToken
+	c.m.Unlock()
+	log.Infof("successfully authenticated to HashiCorp Vault (addr = %s)", c.cfg.Address)
+	return nil
+}
+
+// Read reads the given version of the secret at the given path, relative to
+// the mount. If version is 0, the latest version is read. If the secret or
+// version doesn't exist or has been deleted, false is returned with a nil
+// error.
+func (c *Client) Read(ctx context.Context, path string, version int) (Secret, bool, error) {
+	query := url.Values{}
+	if version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	secret := Secret{}
+	found, err := c.request(ctx, http.MethodGet, c.path("data", path), query, nil, &struct {
+		Data *Secret `json:"data"`
+	}{Data: &secret})
+	if err != nil {
+		return Secret{}, false, fmt.Errorf("reading secret '%s': %w", path, err)
+	}
+	if !found || secret.Data == nil || string(secret.Data) == "null" {
+		return Secret{}, false, nil
+	}
+	return secret, true, nil
+}
+
+// Write writes the given data as a new version of the secret at the given
+// path, relative to the mount, and returns the new version number.
+func (c *Client) Write(ctx context.Context, path string, data interface{}) (int, error) {
+	body, err := json.Marshal(struct {
+		Data interface{} `json:"data"`
+	}{Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("marshalling secret '%s': %w", path, err)
+	}
+	vm := VersionMetadata{}
+	if _, err := c.request(ctx, http.MethodPost, c.path("data", path), nil, body, &struct {
+		Data *VersionMetadata `json:"data"`
+	}{Data: &vm}); err != nil {
+		return 0, fmt.Errorf("writing secret '%s': %w", path, err)
+	}
+	return vm.Version, nil
+}
+
+// ReadMetadata reads the metadata of the secret at the given path, relative
+// to the mount. If the secret doesn't exist, false is returned with a nil
+// error.
+func (c *Client) ReadMetadata(ctx context.Context, path string) (Metadata, bool, error) {
+	md := Metadata{}
+	found, err := c.request(ctx, http.MethodGet, c.path("metadata", path), nil, nil, &struct {
+		Data *Metadata `json:"data"`
+	}{Data: &md})
+	if err != nil {
+		return Metadata{}, false, fmt.Errorf("reading secret metadata '%s': %w", path, err)
+	}
+	return md, found, nil
+}
+
+// WriteCustomMetadata replaces the custom metadata of the secret at the given
+// path, relative to the mount.
+func (c *Client) WriteCustomMetadata(ctx context.Context, path string, custom map[string]string) error {
+	body, err := json.Marshal(map[string]interface{}{"custom_metadata": custom})
+	if err != nil {
+		return fmt.Errorf("marshalling secret metadata '%s': %w", path, err)
+	}
+	if _, err := c.request(ctx, http.MethodPost, c.path("metadata", path), nil, body, nil); err != nil {
+		return fmt.Errorf("writing secret metadata '%s': %w", path, err)
+	}
+	return nil
+}
+
+// DeleteVersions soft-deletes the given versions of the secret at the given
+// path, relative to the mount. If no versions are given, the latest version
+// is deleted.
+func (c *Client) DeleteVersions(ctx context.Context, path string, versions ...int) error {
+	if len(versions) == 0 {
+		if _, err := c.request(ctx, http.MethodDelete, c.path("data", path), nil, nil, nil); err != nil {
+			return fmt.Errorf("deleting secret '%s': %w", path, err)
+		}
+		return nil
+	}
+	body, err := json.Marshal(map[string][]int{"versions": versions})
+	if err != nil {
+		return fmt.Errorf("marshalling secret versions '%s': %w", path, err)
+	}
+	if _, err := c.request(ctx, http.MethodPost, c.path("delete", path), nil, body, nil); err != nil {
+		return fmt.Errorf("deleting secret versions '%s': %w", path, err)
+	}
+	return nil
+}
+
+// Destroy permanently removes every version and all metadata of the secret at
+// the given path, relative to the mount. It is not an error if the secret
+// doesn't exist.
+func (c *Client) Destroy(ctx context.Context, path string) error {
+	if _, err := c.request(ctx, http.MethodDelete, c.path("metadata", path), nil, nil, nil); err != nil {
+		return fmt.Errorf("destroying secret '%s': %w", path, err)
+	}
+	return nil
+}
+
+// List returns the names of the secrets and directories directly beneath the
+// given path, relative to the mount. Directory names end with a '/'. If the
+// path doesn't exist, an empty list is returned with a nil error.
+func (c *Client) List(ctx context.Context, path string) ([]string, error) {
+	keys := struct {
+		Keys []string `json:"keys"`
+	}{}
+	if _, err := c.request(ctx, methodList, c.path("metadata", path), nil, nil, &struct {
+		Data *struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}{Data: &keys}); err != nil {
+		return nil, fmt.Errorf("listing secrets '%s': %w", path, err)
+	}
+	return keys.Keys, nil
+}
+
+// Health returns the health of the Vault server. An error is returned if the
+// server is unreachable, uninitialized or sealed.
+func (c *Client) Health(ctx context.Context) (Health, error) {
+	// standbyok makes standby nodes return 200 instead of 429, since they can
+	// still serve reads by forwarding to the active node.
+	query := url.Values{"standbyok": []string{"true"}}
+	resp, err := c.do(ctx, http.MethodGet, "/v1/sys/health", query, nil, "")
+	if err != nil {
+		return Health{}, errors.New("doing health request: " + err.Error())
+	}
+	defer log.Close(resp.Body, "closing HashiCorp Vault health response body")
+	health := Health{}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return Health{}, fmt.Errorf("decoding health response body (status %s): %w", resp.Status, err)
+	}
+	if !health.Initialized {
+		return health, errors.New("vault is not initialized")
+	}
+	if health.Sealed {
+		return health, errors.New("vault is sealed")
+	}
+	return health, nil
+}
+
+// Get gets the given URI path, which unlike the paths of secrets is not
+// relative to the mount, and decodes the response body into respObj. If the
+// path doesn't exist, false is returned with a nil error.
+func (c *Client) Get(ctx context.Context, path string, respObj interface{}) (bool, error) {
+	return c.request(ctx, http.MethodGet, path, nil, nil, respObj)
+}
+
+func (c *Client) path(kind string, path string) string {
+	return "/v1/" + c.cfg.Mount + "/" + kind + "/" + strings.TrimPrefix(path, "/")
+}
+
+// request performs the given request, logging in again and retrying once if
+// the token was rejected, and decodes the response body into respObj if it
+// is not nil. It returns false with a nil error if the response was a 404 Not
+// Found.
+func (c *Client) request(ctx context.Context, method string, path string, query url.Values, body []byte, respObj interface{}) (bool, error) {
+	c.m.RLock()
+	token := c.token
+	c.m.RUnlock()
+	if token == "" && c.cfg.RoleID != "" {
+		if err := c.Login(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.m.RLock()
+		token = c.token
+		c.m.RUnlock()
+		resp, err := c.do(ctx, method, path, query, body, token)
+		if err != nil {
+			return false, err
+		}
+		if resp.StatusCode == http.StatusForbidden && attempt == 0 && c.cfg.RoleID != "" {
+			log.Close(resp.Body, "closing HashiCorp Vault response body")
+			if err := c.Login(ctx); err != nil {
+				return false, err
+			}
+			continue
+		}
+		defer log.Close(resp.Body, "closing HashiCorp Vault response body")
+		if resp.StatusCode == http.StatusNotFound {
+			// A 404 with errors is a real error, e.g. a bad mount; without,
+			// it simply means the secret doesn't exist.
+			errResp := errResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(&errResp); err == nil && len(errResp.Errors) > 0 {
+				return false, fmt.Errorf("%s %s returned status %s, errors: %s", method, path, resp.Status, strings.Join(errResp.Errors, ", "))
+			}
+			return false, nil
+		}
+		if err := checkStatus(resp); err != nil {
+			return false, fmt.Errorf("%s %s %w", method, path, err)
+		}
+		if respObj == nil || resp.StatusCode == http.StatusNoContent {
+			return true, nil
+		}
+		if err := json.NewDecoder(resp.Body).Decode(respObj); err != nil && err != io.EOF {
+			return false, fmt.Errorf("decoding %s %s response body: %w", method, path, err)
+		}
+		return true, nil
+	}
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body []byte, token string) (*http.Response, error) {
+	reqURL := strings.TrimSuffix(c.cfg.Address, "/") + "/" + strings.TrimPrefix(path, "/")
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bodyReader)
+	if err != nil {
+		return nil, errors.New("creating http request: " + err.Error())
+	}
+	if body != nil {
+		req.Header.Set(rfc.ContentType, rfc.ApplicationJSON)
+	}
+	req.Header.Set(rfc.UserAgent, c.cfg.UserAgent)
+	if token != "" {
+		req.Header.Set(vaultTokenHeader, token)
+	}
+	return c.httpClient.Do(req)
+}
+
+func checkStatus(resp *http.Response) error {
+	if 200 <= resp.StatusCode && resp.StatusCode <= 299 {
+		return nil
+	}
+	errResp := errResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || len(errResp.Errors) == 0 {
+		return fmt.Errorf("returned status %s", resp.Status)
+	}
+	return fmt.Errorf("returned status %s, errors: %s", resp.Status, strings.Join(errResp.Errors, ", "))
+}
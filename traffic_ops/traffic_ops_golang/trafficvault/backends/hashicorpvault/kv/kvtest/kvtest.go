@@ -0,0 +1,315 @@
+// Package kvtest provides an in-memory stand-in for the subset of the
+// HashiCorp Vault HTTP API used by package kv, for use in tests.
+package kvtest
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Token is the static token accepted by the fake Vault.
+	Token = "kvtest-token"
+	// RoleID and SecretID are the AppRole credentials accepted by the fake
+	// Vault, which issue LoginToken.
+	RoleID     = "kvtest-role-id"
+	SecretID   = "kvtest-secret-id"
+	LoginToken = "kvtest-login-token"
+	// Mount is the path at which the fake KV version 2 engine is mounted.
+	Mount = "secret"
+)
+
+type version struct {
+	data      json.RawMessage
+	created   time.Time
+	deleted   time.Time
+	destroyed bool
+}
+
+type secret struct {
+	versions []*version // versions[i] is version i+1
+	custom   map[string]string
+}
+
+// Vault is a fake HashiCorp Vault server with a single KV version 2 mount.
+type Vault struct {
+	*httptest.Server
+	// MaxVersions is the number of versions retained per secret; older
+	// versions are removed on write. 0 means unlimited.
+	MaxVersions int
+
+	m       sync.Mutex
+	secrets map[string]*secret
+	tokens  map[string]struct{}
+}
+
+// NewVault starts and returns a new fake Vault. Callers should Close it when
+// done.
+func NewVault() *Vault {
+	v := &Vault{
+		secrets: map[string]*secret{},
+		tokens:  map[string]struct{}{Token: {}},
+	}
+	v.Server = httptest.NewServer(http.HandlerFunc(v.serveHTTP))
+	return v
+}
+
+// RevokeTokens invalidates every token issued by AppRole login, so the next
+// request using one is rejected.
+func (v *Vault) RevokeTokens() {
+	v.m.Lock()
+	defer v.m.Unlock()
+	delete(v.tokens, LoginToken)
+}
+
+// Paths returns the sorted paths, relative to the mount, of every secret.
+func (v *Vault) Paths() []string {
+	v.m.Lock()
+	defer v.m.Unlock()
+	paths := make([]string, 0, len(v.secrets))
+	for path := range v.secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(obj)
+}
+
+func writeErr(w http.ResponseWriter, code int, msg string) {
+	errs := []string{}
+	if msg != "" {
+		errs = append(errs, msg)
+	}
+	writeJSON(w, code, map[string][]string{"errors": errs})
+}
+
+func (v *Vault) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	v.m.Lock()
+	defer v.m.Unlock()
+
+	switch {
+	case r.URL.Path == "/v1/sys/health":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"initialized": true, "sealed": false, "standby": false, "version": "1.11.0"})
+		return
+	case r.URL.Path == "/v1/auth/approle/login" && r.Method == http.MethodPost:
+		creds := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil || creds["role_id"] != RoleID || creds["secret_id"] != SecretID {
+			writeErr(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		v.tokens[LoginToken] = struct{}{}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"auth": map[string]string{"client_token": LoginToken}})
+		return
+	}
+
+	if _, ok := v.tokens[r.Header.Get("X-Vault-Token")]; !ok {
+		writeErr(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v1/"+Mount+"/"), "/", 2)
+	if !strings.HasPrefix(r.URL.Path, "/v1/"+Mount+"/") || len(parts) != 2 {
+		writeErr(w, http.StatusNotFound, "no handler for route '"+r.URL.Path+"'")
+		return
+	}
+	kind, path := parts[0], parts[1]
+
+	switch kind + " " + r.Method {
+	case "data GET":
+		v.readData(w, r, path)
+	case "data POST", "data PUT":
+		v.writeData(w, r, path)
+	case "data DELETE":
+		if s, ok := v.secrets[path]; ok && len(s.versions) > 0 {
+			s.versions[len(s.versions)-1].deleted = time.Now()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "delete POST":
+		req := struct {
+			Versions []int `json:"versions"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if s, ok := v.secrets[path]; ok {
+			for _, n := range req.Versions {
+				if n > 0 && n <= len(s.versions) && s.versions[n-1] != nil {
+					s.versions[n-1].deleted = time.Now()
+				}
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "metadata GET":
+		if r.URL.Query().Get("list") == "true" {
+			v.list(w, path)
+			return
+		}
+		v.readMetadata(w, path)
+	case "metadata LIST":
+		v.list(w, path)
+	case "metadata POST", "metadata PUT":
+		req := struct {
+			Custom map[string]string `json:"custom_metadata"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s, ok := v.secrets[path]
+		if !ok {
+			s = &secret{}
+			v.secrets[path] = s
+		}
+		s.custom = req.Custom
+		w.WriteHeader(http.StatusNoContent)
+	case "metadata DELETE":
+		delete(v.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeErr(w, http.StatusMethodNotAllowed, "unsupported operation")
+	}
+}
+
+func (v *Vault) readData(w http.ResponseWriter, r *http.Request, path string) {
+	s, ok := v.secrets[path]
+	if !ok || len(s.versions) == 0 {
+		writeErr(w, http.StatusNotFound, "")
+		return
+	}
+	n := len(s.versions)
+	if q := r.URL.Query().Get("version"); q != "" {
+		var err error
+		if n, err = strconv.Atoi(q); err != nil || n < 1 || n > len(s.versions) {
+			writeErr(w, http.StatusNotFound, "")
+			return
+		}
+	}
+	ver := s.versions[n-1]
+	if ver == nil {
+		writeErr(w, http.StatusNotFound, "")
+		return
+	}
+	if ver.destroyed || !ver.deleted.IsZero() {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"data": map[string]interface{}{"data": nil, "metadata": versionMetadata(n, ver)}})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"data": ver.data, "metadata": versionMetadata(n, ver)}})
+}
+
+func (v *Vault) writeData(w http.ResponseWriter, r *http.Request, path string) {
+	req := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Data) == 0 || req.Data[0] != '{' {
+		writeErr(w, http.StatusBadRequest, "data must be an object")
+		return
+	}
+	s, ok := v.secrets[path]
+	if !ok {
+		s = &secret{}
+		v.secrets[path] = s
+	}
+	ver := &version{data: req.Data, created: time.Now()}
+	s.versions = append(s.versions, ver)
+	if v.MaxVersions > 0 {
+		// like Vault, old versions are removed, but numbering is unaffected
+		for i := 0; i < len(s.versions)-v.MaxVersions; i++ {
+			s.versions[i] = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": versionMetadata(len(s.versions), ver)})
+}
+
+func (v *Vault) readMetadata(w http.ResponseWriter, path string) {
+	s, ok := v.secrets[path]
+	if !ok {
+		writeErr(w, http.StatusNotFound, "")
+		return
+	}
+	versions := map[string]interface{}{}
+	oldest := 0
+	for i, ver := range s.versions {
+		if ver == nil {
+			continue
+		}
+		if oldest == 0 {
+			oldest = i + 1
+		}
+		versions[strconv.Itoa(i+1)] = versionMetadata(i+1, ver)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+		"current_version": len(s.versions),
+		"oldest_version":  oldest,
+		"custom_metadata": s.custom,
+		"versions":        versions,
+	}})
+}
+
+func (v *Vault) list(w http.ResponseWriter, path string) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	keySet := map[string]struct{}{}
+	for p := range v.secrets {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(p, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			rest = rest[:i+1]
+		}
+		keySet[rest] = struct{}{}
+	}
+	if len(keySet) == 0 {
+		writeErr(w, http.StatusNotFound, "")
+		return
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string][]string{"keys": keys}})
+}
+
+func versionMetadata(n int, ver *version) map[string]interface{} {
+	deletionTime := ""
+	if !ver.deleted.IsZero() {
+		deletionTime = ver.deleted.Format(time.RFC3339Nano)
+	}
+	return map[string]interface{}{
+		"version":       n,
+		"created_time":  ver.created.Format(time.RFC3339Nano),
+		"deletion_time": deletionTime,
+		"destroyed":     ver.destroyed,
+	}
+}
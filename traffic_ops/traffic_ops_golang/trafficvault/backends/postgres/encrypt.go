@@ -26,6 +26,7 @@ import (
 	"io/ioutil"
 	"time"
 
+	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/about"
 	"github.com/apache/trafficcontrol/traffic_ops/traffic_ops_golang/trafficvault/backends/postgres/hashicorpvault"
 )
 
@@ -48,6 +49,7 @@ func readKey(cfg Config) ([]byte, error) {
 			cfg.HashiCorpVault.SecretPath,
 			time.Duration(cfg.HashiCorpVault.TimeoutSec)*time.Second,
 			cfg.HashiCorpVault.Insecure,
+			about.UserAgent(),
 		)
 		if err := hashiVault.Login(); err != nil {
 			return nil, errors.New("failed to login to HashiCorp Vault: " + err.Error())
@@ -77,3 +77,17 @@ func (to *Session) SetUpdateServerStatusTimes(serverName string, configApplyTime
 	reqInf, err := to.post(path, opts, nil, &alerts)
 	return alerts, reqInf, err
 }
+
+// SetServerConfigApplyFailureTime records that the server identified by
+// 'serverName' failed to apply its config at the given time.
+func (to *Session) SetServerConfigApplyFailureTime(serverName string, failureTime time.Time, opts RequestOptions) (tc.Alerts, toclientlib.ReqInf, error) {
+	if opts.QueryParameters == nil {
+		opts.QueryParameters = url.Values{}
+	}
+	opts.QueryParameters.Set("config_apply_failure_time", failureTime.Format(time.RFC3339Nano))
+
+	var alerts tc.Alerts
+	path := `/servers/` + url.PathEscape(serverName) + `/update`
+	reqInf, err := to.post(path, opts, nil, &alerts)
+	return alerts, reqInf, err
+}
@@ -76,3 +76,14 @@ func (to *Session) QueueUpdatesForCDN(cdnID int, queueUpdate bool, opts RequestO
 	reqInf, err := to.post(path, opts, req, &resp)
 	return resp, reqInf, err
 }
+
+// GetCDNConfigPreview retrieves a dry run of snapshotting and queuing updates
+// on the CDN with the given name: how its CRConfig and the configuration files
+// of its cache servers would change. The "topology" and "cachegroup" query
+// parameters may be used to limit the servers included.
+func (to *Session) GetCDNConfigPreview(name string, opts RequestOptions) (tc.CDNConfigPreviewResponse, toclientlib.ReqInf, error) {
+	route := fmt.Sprintf("%s/%s/preview", apiCDNs, url.PathEscape(name))
+	var data tc.CDNConfigPreviewResponse
+	reqInf, err := to.get(route, opts, &data)
+	return data, reqInf, err
+}
@@ -27,6 +27,7 @@ const (
 	apiCDNsNameDNSSECKeys        = "/cdns/name/%s/dnsseckeys"
 	apiCDNsDNSSECRefresh         = "/cdns/dnsseckeys/refresh"
 	apiCDNsDNSSECKeysKSKGenerate = "/cdns/%s/dnsseckeys/ksk/generate"
+	apiCDNsDNSSECKeysRollover    = "/cdns/%s/dnsseckeys/rollover"
 )
 
 // GenerateCDNDNSSECKeys generates DNSSEC keys for the given CDN.
@@ -66,3 +67,37 @@ func (to *Session) GenerateCDNDNSSECKSK(name string, req tc.CDNGenerateKSKReq, o
 	reqInf, err := to.post(route, opts, req, &resp)
 	return resp, reqInf, err
 }
+
+// GetCDNDNSSECRollover gets the DNSSEC algorithm rollover of the given CDN.
+func (to *Session) GetCDNDNSSECRollover(name string, opts RequestOptions) (tc.CDNDNSSECRolloverResponse, toclientlib.ReqInf, error) {
+	route := fmt.Sprintf(apiCDNsDNSSECKeysRollover, url.PathEscape(name))
+	var resp tc.CDNDNSSECRolloverResponse
+	reqInf, err := to.get(route, opts, &resp)
+	return resp, reqInf, err
+}
+
+// StartCDNDNSSECRollover starts a rollover of the given CDN's DNSSEC keys to
+// another algorithm.
+func (to *Session) StartCDNDNSSECRollover(name string, req tc.CDNDNSSECRolloverReq, opts RequestOptions) (tc.CDNDNSSECRolloverResponse, toclientlib.ReqInf, error) {
+	route := fmt.Sprintf(apiCDNsDNSSECKeysRollover, url.PathEscape(name))
+	var resp tc.CDNDNSSECRolloverResponse
+	reqInf, err := to.post(route, opts, req, &resp)
+	return resp, reqInf, err
+}
+
+// UpdateCDNDNSSECRollover advances the given CDN's DNSSEC algorithm rollover
+// to its next stage.
+func (to *Session) UpdateCDNDNSSECRollover(name string, req tc.CDNDNSSECRolloverUpdateReq, opts RequestOptions) (tc.CDNDNSSECRolloverResponse, toclientlib.ReqInf, error) {
+	route := fmt.Sprintf(apiCDNsDNSSECKeysRollover, url.PathEscape(name))
+	var resp tc.CDNDNSSECRolloverResponse
+	reqInf, err := to.put(route, opts, req, &resp)
+	return resp, reqInf, err
+}
+
+// CancelCDNDNSSECRollover cancels the given CDN's DNSSEC algorithm rollover.
+func (to *Session) CancelCDNDNSSECRollover(name string, opts RequestOptions) (tc.Alerts, toclientlib.ReqInf, error) {
+	route := fmt.Sprintf(apiCDNsDNSSECKeysRollover, url.PathEscape(name))
+	var resp tc.Alerts
+	reqInf, err := to.del(route, opts, &resp)
+	return resp, reqInf, err
+}
@@ -0,0 +1,55 @@
+package client
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"fmt"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/toclientlib"
+)
+
+// apiDeliveryServiceCDNiDelegation is the API version-relative path to the
+// /deliveryservices/{{ID}}/cdni/delegation API endpoint.
+const apiDeliveryServiceCDNiDelegation = apiDeliveryServices + "/%d/cdni/delegation"
+
+// GetDeliveryServiceCDNiDelegation returns the delegation of the Delivery
+// Service with the given ID to a CDNi downstream CDN.
+func (to *Session) GetDeliveryServiceCDNiDelegation(dsID int, opts RequestOptions) (tc.CDNiDelegationResponse, toclientlib.ReqInf, error) {
+	var resp tc.CDNiDelegationResponse
+	reqInf, err := to.get(fmt.Sprintf(apiDeliveryServiceCDNiDelegation, dsID), opts, &resp)
+	return resp, reqInf, err
+}
+
+// DelegateDeliveryService delegates the Delivery Service with the given ID to
+// a CDNi downstream CDN, pushing its configuration to the dCDN.
+func (to *Session) DelegateDeliveryService(dsID int, req tc.CDNiDelegationRequest, opts RequestOptions) (tc.CDNiDelegationResponse, toclientlib.ReqInf, error) {
+	var resp tc.CDNiDelegationResponse
+	reqInf, err := to.put(fmt.Sprintf(apiDeliveryServiceCDNiDelegation, dsID), opts, req, &resp)
+	return resp, reqInf, err
+}
+
+// DeleteDeliveryServiceCDNiDelegation removes the delegation of the Delivery
+// Service with the given ID to a CDNi downstream CDN.
+func (to *Session) DeleteDeliveryServiceCDNiDelegation(dsID int, opts RequestOptions) (tc.Alerts, toclientlib.ReqInf, error) {
+	var alerts tc.Alerts
+	reqInf, err := to.del(fmt.Sprintf(apiDeliveryServiceCDNiDelegation, dsID), opts, &alerts)
+	return alerts, reqInf, err
+}
@@ -18,6 +18,7 @@ package client
 import (
 	"encoding/json"
 	"errors"
+	"strconv"
 
 	"github.com/apache/trafficcontrol/lib/go-tc"
 	"github.com/apache/trafficcontrol/traffic_ops/toclientlib"
@@ -59,3 +60,39 @@ func (to *Session) GetCRConfigNew(cdn string, opts RequestOptions) (tc.SnapshotR
 	reqInf, err := to.get(uri, opts, &resp)
 	return resp, reqInf, err
 }
+
+// GetCRConfigDiff returns the difference between the current Snapshot for the
+// given CDN and the *new* Snapshot which would be created by snapshotting it.
+func (to *Session) GetCRConfigDiff(cdn string, opts RequestOptions) (tc.CRConfigDiffResponse, toclientlib.ReqInf, error) {
+	uri := `/cdns/` + cdn + `/snapshot/diff`
+	var resp tc.CRConfigDiffResponse
+	reqInf, err := to.get(uri, opts, &resp)
+	return resp, reqInf, err
+}
+
+// GetSnapshotHistory returns the retained Snapshots for the given CDN, newest
+// first, without their contents.
+func (to *Session) GetSnapshotHistory(cdn string, opts RequestOptions) (tc.SnapshotHistoryResponse, toclientlib.ReqInf, error) {
+	uri := `/cdns/` + cdn + `/snapshot/history`
+	var resp tc.SnapshotHistoryResponse
+	reqInf, err := to.get(uri, opts, &resp)
+	return resp, reqInf, err
+}
+
+// GetSnapshotHistoryEntry returns the retained Snapshot for the given CDN with
+// the given history ID, including its contents.
+func (to *Session) GetSnapshotHistoryEntry(cdn string, id int, opts RequestOptions) (tc.SnapshotHistoryEntryResponse, toclientlib.ReqInf, error) {
+	uri := `/cdns/` + cdn + `/snapshot/history/` + strconv.Itoa(id)
+	var resp tc.SnapshotHistoryEntryResponse
+	reqInf, err := to.get(uri, opts, &resp)
+	return resp, reqInf, err
+}
+
+// RollbackSnapshot re-publishes the retained Snapshot for the given CDN with the
+// given history ID as its current Snapshot.
+func (to *Session) RollbackSnapshot(cdn string, id int, opts RequestOptions) (tc.SnapshotHistoryEntryResponse, toclientlib.ReqInf, error) {
+	uri := `/cdns/` + cdn + `/snapshot/history/` + strconv.Itoa(id) + `/rollback`
+	var resp tc.SnapshotHistoryEntryResponse
+	reqInf, err := to.post(uri, opts, nil, &resp)
+	return resp, reqInf, err
+}
@@ -0,0 +1,76 @@
+package client
+
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+import (
+	"strconv"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/traffic_ops/toclientlib"
+)
+
+// apiRollouts is the API version-relative path to the /rollouts API endpoint.
+const apiRollouts = "/rollouts"
+
+// GetRollouts returns all staged rollouts of queued updates, newest first,
+// without their servers.
+func (to *Session) GetRollouts(opts RequestOptions) (tc.RolloutsResponse, toclientlib.ReqInf, error) {
+	var resp tc.RolloutsResponse
+	reqInf, err := to.get(apiRollouts, opts, &resp)
+	return resp, reqInf, err
+}
+
+// GetRollout returns the staged rollout with the given ID, including its
+// servers.
+func (to *Session) GetRollout(id int, opts RequestOptions) (tc.RolloutResponse, toclientlib.ReqInf, error) {
+	var resp tc.RolloutResponse
+	reqInf, err := to.get(apiRollouts+"/"+strconv.Itoa(id), opts, &resp)
+	return resp, reqInf, err
+}
+
+// CreateRollout creates a staged rollout of queued updates, which queues
+// updates for its canary wave immediately.
+func (to *Session) CreateRollout(req tc.RolloutRequest, opts RequestOptions) (tc.RolloutResponse, toclientlib.ReqInf, error) {
+	var resp tc.RolloutResponse
+	reqInf, err := to.post(apiRollouts, opts, req, &resp)
+	return resp, reqInf, err
+}
+
+// PauseRollout pauses the running staged rollout with the given ID.
+func (to *Session) PauseRollout(id int, opts RequestOptions) (tc.RolloutResponse, toclientlib.ReqInf, error) {
+	return to.rolloutAction(id, "pause", opts)
+}
+
+// ResumeRollout resumes the paused staged rollout with the given ID.
+func (to *Session) ResumeRollout(id int, opts RequestOptions) (tc.RolloutResponse, toclientlib.ReqInf, error) {
+	return to.rolloutAction(id, "resume", opts)
+}
+
+// AbortRollout aborts the staged rollout with the given ID, dequeueing the
+// updates of its servers which haven't applied them.
+func (to *Session) AbortRollout(id int, opts RequestOptions) (tc.RolloutResponse, toclientlib.ReqInf, error) {
+	return to.rolloutAction(id, "abort", opts)
+}
+
+func (to *Session) rolloutAction(id int, action string, opts RequestOptions) (tc.RolloutResponse, toclientlib.ReqInf, error) {
+	var resp tc.RolloutResponse
+	reqInf, err := to.post(apiRollouts+"/"+strconv.Itoa(id)+"/"+action, opts, nil, &resp)
+	return resp, reqInf, err
+}
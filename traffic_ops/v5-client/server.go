@@ -168,3 +168,21 @@ func (to *Session) GetServerUpdateStatus(hostName string, opts RequestOptions) (
 	reqInf, err := to.get(path, opts, &data)
 	return data, reqInf, err
 }
+
+// GetServerConfigFiles retrieves the configuration files which the Server with
+// the given (short) hostname last reported applying.
+func (to *Session) GetServerConfigFiles(hostName string, opts RequestOptions) (tc.ServerConfigFilesResponse, toclientlib.ReqInf, error) {
+	path := apiServers + `/` + url.PathEscape(hostName) + `/config_files`
+	var data tc.ServerConfigFilesResponse
+	reqInf, err := to.get(path, opts, &data)
+	return data, reqInf, err
+}
+
+// SetServerConfigFiles replaces the configuration files which the Server with
+// the given (short) hostname reports having applied.
+func (to *Session) SetServerConfigFiles(hostName string, files []tc.ServerConfigFile, opts RequestOptions) (tc.Alerts, toclientlib.ReqInf, error) {
+	path := apiServers + `/` + url.PathEscape(hostName) + `/config_files`
+	var alerts tc.Alerts
+	reqInf, err := to.put(path, opts, files, &alerts)
+	return alerts, reqInf, err
+}
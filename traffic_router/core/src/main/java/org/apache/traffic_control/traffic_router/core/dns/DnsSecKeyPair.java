@@ -50,6 +50,8 @@ public interface DnsSecKeyPair {
 
 	boolean isKeyCached(long maxTTL);
 
+	boolean isPublished();
+
 	boolean isOlder(DnsSecKeyPair other);
 
 	boolean isNewer(DnsSecKeyPair other);
@@ -21,6 +21,10 @@ import org.apache.traffic_control.traffic_router.secure.BindPrivateKey;
 import com.fasterxml.jackson.databind.JsonNode;
 import org.apache.logging.log4j.LogManager;
 import org.apache.logging.log4j.Logger;
+import org.bouncycastle.asn1.edec.EdECObjectIdentifiers;
+import org.bouncycastle.asn1.x509.AlgorithmIdentifier;
+import org.bouncycastle.asn1.x509.SubjectPublicKeyInfo;
+import org.bouncycastle.jce.provider.BouncyCastleProvider;
 import org.xbill.DNS.DNSKEYRecord;
 import org.xbill.DNS.DNSSEC;
 import org.xbill.DNS.Master;
@@ -31,8 +35,12 @@ import org.xbill.DNS.Type;
 import java.io.ByteArrayInputStream;
 import java.io.IOException;
 import java.io.InputStream;
+import java.security.GeneralSecurityException;
+import java.security.KeyFactory;
 import java.security.PrivateKey;
+import java.security.Provider;
 import java.security.PublicKey;
+import java.security.spec.X509EncodedKeySpec;
 import java.util.Base64.Decoder;
 import java.util.Calendar;
 import java.util.Date;
@@ -41,11 +49,18 @@ import static java.util.Base64.getMimeDecoder;
 
 public class DnsSecKeyPairImpl implements DnsSecKeyPair {
 	private static final Logger LOGGER = LogManager.getLogger(DnsSecKeyPairImpl.class);
+	// dnsjava has no constant for DNSSEC algorithm 15 (ED25519), and the JDK has no Ed25519 keys before Java 15
+	private static final int ED25519 = 15;
+	private static final Provider ED25519_PROVIDER = new BouncyCastleProvider();
+	// the status Traffic Ops gives the keys of the old algorithm of a DNSSEC algorithm rollover once their DNSKEY records are removed,
+	// while they still sign zones (RFC 6781 section 4.1.4)
+	private static final String UNPUBLISHED_STATUS = "unpublished";
 	private long ttl;
 	private Date inception;
 	private Date effective;
 	private Date expiration;
 	private String name;
+	private String status;
 	private DNSKEYRecord dnskeyRecord;
 	private PrivateKey privateKey;
 
@@ -55,6 +70,7 @@ public class DnsSecKeyPairImpl implements DnsSecKeyPair {
 		this.expiration = new Date(1000L * JsonUtils.getLong(keyPair, "expirationDate"));
 		this.ttl = JsonUtils.optLong(keyPair, "ttl", defaultTTL);
 		this.name = JsonUtils.getString(keyPair, "name").toLowerCase();
+		this.status = JsonUtils.optString(keyPair, "status", "");
 
 		final Decoder mimeDecoder = getMimeDecoder();
 		try {
@@ -149,6 +165,11 @@ public class DnsSecKeyPairImpl implements DnsSecKeyPair {
 		return getExpiration().after(new Date(System.currentTimeMillis() - (maxTTL * 1000)));
 	}
 
+	@Override
+	public boolean isPublished() {
+		return !UNPUBLISHED_STATUS.equals(status);
+	}
+
 	@Override
 	public boolean isOlder(final DnsSecKeyPair other) {
 		return getEffective().before(other.getEffective());
@@ -172,8 +193,14 @@ public class DnsSecKeyPairImpl implements DnsSecKeyPair {
 	@Override
 	public PublicKey getPublic() {
 		try {
+			if (dnskeyRecord.getAlgorithm() == ED25519) {
+				// the public key of an Ed25519 DNSKEY record is the raw 32 byte key (RFC 8080), which dnsjava can't decode
+				final SubjectPublicKeyInfo publicKeyInfo = new SubjectPublicKeyInfo(new AlgorithmIdentifier(EdECObjectIdentifiers.id_Ed25519), dnskeyRecord.getKey());
+				return KeyFactory.getInstance("Ed25519", ED25519_PROVIDER).generatePublic(new X509EncodedKeySpec(publicKeyInfo.getEncoded()));
+			}
+
 			return dnskeyRecord.getPublicKey();
-		} catch (DNSSEC.DNSSECException e) {
+		} catch (DNSSEC.DNSSECException | GeneralSecurityException | IOException e) {
 			LOGGER.error("Failed to extract public key from DNSKEY record for " + name + " : " + e.getMessage(), e);
 		}
 		return null;
@@ -199,6 +226,8 @@ public class DnsSecKeyPairImpl implements DnsSecKeyPair {
 			return false;
 		} else if (getTTL() != okp.getTTL()) {
 			return false;
+		} else if (isPublished() != okp.isPublished()) {
+			return false;
 		}
 
 		return true;
@@ -217,6 +246,10 @@ public class DnsSecKeyPairImpl implements DnsSecKeyPair {
 		sb.append("\" expiration=\"");
 		sb.append(getExpiration()).append('"');
 
+		if (!isPublished()) {
+			sb.append(" unpublished");
+		}
+
 		return sb.toString();
 	}
 }
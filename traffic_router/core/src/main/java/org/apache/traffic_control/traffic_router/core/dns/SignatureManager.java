@@ -320,9 +320,11 @@ public final class SignatureManager {
 	private List<DnsSecKeyPair> getZoneSigningKeyPair(final Name name, final boolean wantKsk, final long maxTTL) throws IOException, NoSuchAlgorithmException {
 		/*
 		 * This method returns a list, but we will identify the correct key with which to sign the zone.
-		 * We select one key (we call this method twice, for zsk and ksks respectively)
+		 * We select one key per algorithm (we call this method twice, for zsk and ksks respectively)
 		 * to follow the pre-publish key roll methodology described in RFC 6781.
 		 * https://tools.ietf.org/html/rfc6781#section-4.1.1.1
+		 * During an algorithm rollover there are keys of two algorithms, and the zone is signed with both.
+		 * https://tools.ietf.org/html/rfc6781#section-4.1.4
 		 */
 
 		return getKeyPairs(name, wantKsk, true, maxTTL);
@@ -339,7 +341,8 @@ public final class SignatureManager {
 	@SuppressWarnings({"PMD.CyclomaticComplexity", "PMD.NPathComplexity"})
 	private List<DnsSecKeyPair> getKeyPairs(final Name name, final boolean wantKsk, final boolean wantSigningKey, final long maxTTL) throws IOException, NoSuchAlgorithmException {
 		final List<DnsSecKeyPair> keyPairs = keyMap.get(name.toString().toLowerCase());
-		DnsSecKeyPair signingKey = null;
+		// one signing key per algorithm, so that zones are signed with every algorithm during an algorithm rollover (RFC 6781 section 4.1.4)
+		final Map<Integer, DnsSecKeyPair> signingKeys = new HashMap<>();
 
 		if (keyPairs == null) {
 			return null;
@@ -355,6 +358,10 @@ public final class SignatureManager {
 				if ((isKsk && !wantKsk) || (!isKsk && wantKsk)) {
 					LOGGER.debug("Skipping key: wantKsk = " + wantKsk + "; key: " + kpw.toString());
 					continue;
+				} else if (!wantSigningKey && !kpw.isPublished()) {
+					// the DNSKEY records of a retired algorithm are removed before its signatures (RFC 6781 section 4.1.4)
+					LOGGER.debug("Skipping unpublished key: " + kpw.toString());
+					continue;
 				} else if (!wantSigningKey && (isExpiredKeyAllowed() || kpw.isKeyCached(maxTTL))) {
 					LOGGER.debug("key selected: " + kpw.toString());
 					keys.add(kpw);
@@ -368,16 +375,17 @@ public final class SignatureManager {
 					}
 
 					// Locate the key with the earliest valid effective date accounting for expiration
-					if ((isKsk && wantKsk) || (!isKsk && !wantKsk)) {
-						if (signingKey == null) {
-							signingKey = kpw;
-						} else if (signingKey.isExpired() && !kpw.isExpired()) {
-							signingKey = kpw;
-						} else if (signingKey.isExpired() && kpw.isNewer(signingKey)) {
-							signingKey = kpw; // if we have an expired key, try to find the most recent
-						} else if (!signingKey.isExpired() && !kpw.isExpired() && kpw.isOlder(signingKey)) {
-							signingKey = kpw; // otherwise use the oldest valid/non-expired key
-						}
+					final int algorithm = kpw.getDNSKEYRecord().getAlgorithm();
+					final DnsSecKeyPair signingKey = signingKeys.get(algorithm);
+
+					if (signingKey == null) {
+						signingKeys.put(algorithm, kpw);
+					} else if (signingKey.isExpired() && !kpw.isExpired()) {
+						signingKeys.put(algorithm, kpw);
+					} else if (signingKey.isExpired() && kpw.isNewer(signingKey)) {
+						signingKeys.put(algorithm, kpw); // if we have an expired key, try to find the most recent
+					} else if (!signingKey.isExpired() && !kpw.isExpired() && kpw.isOlder(signingKey)) {
+						signingKeys.put(algorithm, kpw); // otherwise use the oldest valid/non-expired key
 					}
 				}
 			} else {
@@ -385,16 +393,26 @@ public final class SignatureManager {
 			}
 		}
 
-		if (wantSigningKey && signingKey != null) {
-			if (signingKey.isExpired()) {
-				LOGGER.warn("Using expired signing key: " + signingKey.toString());
-			} else {
-				LOGGER.debug("Signing key selected: " + signingKey.toString());
-			}
+		if (wantSigningKey && !signingKeys.isEmpty()) {
+			// an algorithm with only expired keys is being retired, so stop signing with it if another algorithm has a valid key
+			final boolean haveValidKey = signingKeys.values().stream().anyMatch(signingKey -> !signingKey.isExpired());
 
 			keys.clear(); // in case we have something in here for some reason (shouldn't happen)
-			keys.add(signingKey);
-		} else if (wantSigningKey && signingKey == null) {
+
+			for (final DnsSecKeyPair signingKey : signingKeys.values()) {
+				if (signingKey.isExpired()) {
+					if (haveValidKey) {
+						LOGGER.debug("Skipping expired signing key of a retired algorithm: " + signingKey.toString());
+						continue;
+					}
+					LOGGER.warn("Using expired signing key: " + signingKey.toString());
+				} else {
+					LOGGER.debug("Signing key selected: " + signingKey.toString());
+				}
+
+				keys.add(signingKey);
+			}
+		} else if (wantSigningKey) {
 			LOGGER.fatal("Unable to find signing key for " + name);
 		}
 
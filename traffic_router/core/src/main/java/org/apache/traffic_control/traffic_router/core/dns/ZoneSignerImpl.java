@@ -17,6 +17,7 @@ package org.apache.traffic_control.traffic_router.core.dns;
 
 import org.apache.logging.log4j.LogManager;
 import org.apache.logging.log4j.Logger;
+import org.bouncycastle.jce.provider.BouncyCastleProvider;
 import org.xbill.DNS.DNSKEYRecord;
 import org.xbill.DNS.DNSSEC;
 import org.xbill.DNS.DSRecord;
@@ -31,6 +32,8 @@ import org.xbill.DNS.Type;
 import java.io.IOException;
 import java.security.GeneralSecurityException;
 import java.security.PrivateKey;
+import java.security.Provider;
+import java.security.Signature;
 import java.util.ArrayList;
 import java.util.Collections;
 import java.util.Date;
@@ -49,6 +52,9 @@ import static org.xbill.DNS.DClass.IN;
 
 public class ZoneSignerImpl implements ZoneSigner {
 	private final static Logger LOGGER = LogManager.getLogger(ZoneSignerImpl.class);
+	// dnsjava has no constant for DNSSEC algorithm 15 (ED25519), and the JDK has no Ed25519 signatures before Java 15
+	private final static int ED25519 = 15;
+	private final static Provider ED25519_PROVIDER = new BouncyCastleProvider();
 
 	private Stream<Record> toRRStream(final RRset rrSet) {
 		final Iterable<Record> iterable = () -> rrSet.rrs(false);
@@ -60,10 +66,27 @@ public class ZoneSignerImpl implements ZoneSigner {
 		return StreamSupport.stream(iterable.spliterator(), false);
 	}
 
+	// dnsjava can't sign with Ed25519 keys, so sign the RRSIG data it digests ourselves (RFC 8080)
+	private RRSIGRecord signEd25519(final RRset rrset, final DNSKEYRecord dnskeyRecord, final PrivateKey privateKey, final Date inception, final Date expiration) throws GeneralSecurityException {
+		final RRSIGRecord unsigned = new RRSIGRecord(rrset.getName(), rrset.getDClass(), rrset.getTTL(), rrset.getType(),
+			dnskeyRecord.getAlgorithm(), rrset.getTTL(), expiration, inception, dnskeyRecord.getFootprint(), dnskeyRecord.getName(), null);
+
+		final Signature signer = Signature.getInstance("Ed25519", ED25519_PROVIDER);
+		signer.initSign(privateKey);
+		signer.update(DNSSEC.digestRRset(unsigned, rrset));
+
+		return new RRSIGRecord(rrset.getName(), rrset.getDClass(), rrset.getTTL(), rrset.getType(),
+			dnskeyRecord.getAlgorithm(), rrset.getTTL(), expiration, inception, dnskeyRecord.getFootprint(), dnskeyRecord.getName(), signer.sign());
+	}
+
 	private RRSIGRecord sign(final RRset rrset, final DNSKEYRecord dnskeyRecord, final PrivateKey privateKey, final Date inception, final Date expiration) {
 		try {
+			if (dnskeyRecord.getAlgorithm() == ED25519) {
+				return signEd25519(rrset, dnskeyRecord, privateKey, inception, expiration);
+			}
+
 			return DNSSEC.sign(rrset, dnskeyRecord, privateKey, inception, expiration);
-		} catch (DNSSEC.DNSSECException e) {
+		} catch (DNSSEC.DNSSECException | GeneralSecurityException e) {
 			final String message = String.format("Failed to sign Resource Record Set for %s %d %d %d : %s",
 					dnskeyRecord.getName(), dnskeyRecord.getDClass(), dnskeyRecord.getType(), dnskeyRecord.getTTL(), e.getMessage());
 			LOGGER.error(message, e);
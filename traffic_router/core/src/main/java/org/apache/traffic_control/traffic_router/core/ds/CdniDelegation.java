@@ -0,0 +1,118 @@
+/*
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package org.apache.traffic_control.traffic_router.core.ds;
+
+import com.fasterxml.jackson.databind.JsonNode;
+import org.apache.logging.log4j.LogManager;
+import org.apache.logging.log4j.Logger;
+import org.apache.traffic_control.traffic_router.core.loc.NetworkNode;
+import org.apache.traffic_control.traffic_router.core.loc.NetworkNodeException;
+import org.apache.traffic_control.traffic_router.core.util.JsonUtils;
+import org.apache.traffic_control.traffic_router.core.util.JsonUtilsException;
+
+import java.util.HashSet;
+import java.util.Locale;
+import java.util.Objects;
+import java.util.Set;
+
+/**
+ * The delegation of a Delivery Service to a CDNi downstream CDN (dCDN). Clients in the footprints the dCDN
+ * advertised are redirected to the host by which the dCDN delivers the Delivery Service's content.
+ * Only IPv4 CIDR, IPv6 CIDR and country code footprints are matched.
+ */
+public class CdniDelegation {
+	private static final Logger LOGGER = LogManager.getLogger(CdniDelegation.class);
+	private static final String FOOTPRINT_LOC = "cdni";
+
+	private final String dcdn;
+	private final String host;
+	private final NetworkNode.SuperNode networks;
+	private final Set<String> countryCodes = new HashSet<>();
+
+	public CdniDelegation(final JsonNode delegationJo) throws JsonUtilsException, NetworkNodeException {
+		this.dcdn = JsonUtils.getString(delegationJo, "dcdn");
+		this.host = JsonUtils.getString(delegationJo, "host");
+		this.networks = new NetworkNode.SuperNode();
+
+		final JsonNode footprints = delegationJo.get("footprints");
+		if (footprints == null || !footprints.isArray()) {
+			return;
+		}
+
+		for (final JsonNode footprint : footprints) {
+			final String type = JsonUtils.getString(footprint, "footprint-type");
+			final JsonNode values = footprint.get("footprint-value");
+			if (values == null || !values.isArray()) {
+				continue;
+			}
+
+			switch (type) {
+			case "ipv4cidr":
+				for (final JsonNode value : values) {
+					networks.add(new NetworkNode(value.asText(), FOOTPRINT_LOC));
+				}
+				break;
+			case "ipv6cidr":
+				for (final JsonNode value : values) {
+					networks.add6(new NetworkNode(value.asText(), FOOTPRINT_LOC));
+				}
+				break;
+			case "countrycode":
+				for (final JsonNode value : values) {
+					countryCodes.add(value.asText().toUpperCase(Locale.ROOT));
+				}
+				break;
+			default:
+				LOGGER.warn("CDNi delegation to dCDN '" + dcdn + "' has unsupported footprint type '" + type + "'. Disregarding.");
+			}
+		}
+	}
+
+	public String getDcdn() {
+		return dcdn;
+	}
+
+	public String getHost() {
+		return host;
+	}
+
+	/**
+	 * Checks whether an address is in the IPv4 or IPv6 CIDR footprints.
+	 * @param address The client's IP address.
+	 * @return Whether the address is in a CIDR footprint.
+	 */
+	public boolean containsAddress(final String address) {
+		try {
+			final NetworkNode nn = networks.getNetwork(address);
+			return nn != null && Objects.equals(nn.getLoc(), FOOTPRINT_LOC);
+		} catch (NetworkNodeException e) {
+			LOGGER.warn("CDNi delegation to dCDN '" + dcdn + "': invalid client address '" + address + "'", e);
+			return false;
+		}
+	}
+
+	public boolean hasCountryCodes() {
+		return !countryCodes.isEmpty();
+	}
+
+	/**
+	 * Checks whether a country is in the country code footprints.
+	 * @param countryCode The client's ISO 3166-1 alpha-2 country code.
+	 * @return Whether the country is in a country code footprint.
+	 */
+	public boolean containsCountry(final String countryCode) {
+		return countryCode != null && countryCodes.contains(countryCode.toUpperCase(Locale.ROOT));
+	}
+}
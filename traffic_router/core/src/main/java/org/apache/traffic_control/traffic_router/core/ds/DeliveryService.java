@@ -48,6 +48,7 @@ import org.apache.traffic_control.traffic_router.core.edge.InetRecord;
 import org.apache.traffic_control.traffic_router.core.edge.Location;
 import org.apache.traffic_control.traffic_router.core.edge.Cache.DeliveryServiceReference;
 import org.apache.traffic_control.traffic_router.core.edge.CacheLocation;
+import org.apache.traffic_control.traffic_router.core.loc.NetworkNodeException;
 import org.apache.traffic_control.traffic_router.geolocation.Geolocation;
 import org.apache.traffic_control.traffic_router.core.request.DNSRequest;
 import org.apache.traffic_control.traffic_router.core.request.HTTPRequest;
@@ -85,6 +86,7 @@ public class DeliveryService {
 	private final Pattern wildcardPattern = Pattern.compile("^\\(\\.\\*\\\\\\.\\|\\^\\)|^\\.\\*\\\\\\.|\\\\\\.\\.\\*");
 	@JsonIgnore
 	private final JsonNode bypassDestination;
+	private final CdniDelegation cdniDelegation;
 	@JsonIgnore
 	private final JsonNode soa;
 	@JsonIgnore
@@ -137,6 +139,7 @@ public class DeliveryService {
 		this.geoRedirectFile = this.geoRedirectUrl;
 		this.staticDnsEntries = dsJo.get("staticDnsEntries");
 		this.bypassDestination = dsJo.get("bypassDestination");
+		this.cdniDelegation = initCdniDelegation(dsJo);
 		this.routingName = JsonUtils.getString(dsJo, "routingName").toLowerCase();
 		this.domain = getDomainFromJson(dsJo.get("domains"));
 		this.tld = this.domain != null
@@ -219,6 +222,19 @@ public class DeliveryService {
 		}
 	}
 
+	private CdniDelegation initCdniDelegation(final JsonNode dsJo) {
+		final JsonNode delegationJo = dsJo.get("cdniDelegation");
+		if (delegationJo == null) {
+			return null;
+		}
+		try {
+			return new CdniDelegation(delegationJo);
+		} catch (JsonUtilsException | NetworkNodeException e) {
+			LOGGER.error("Delivery Service '" + id + "' has a malformed cdniDelegation. Disregarding.", e);
+			return null;
+		}
+	}
+
 	private void initTopology(final JsonNode dsJo) {
 		if (dsJo.has("topology")) {
 			this.topology = JsonUtils.optString(dsJo, "topology");
@@ -339,6 +355,17 @@ public class DeliveryService {
 	}
 	private static final String REGEX_PERIOD = "\\.";
 
+	/**
+	 * Creates the URL to which a client is redirected when it's in the footprints of the CDNi downstream CDN
+	 * the Delivery Service is delegated to.
+	 * @param request The client's HTTP request.
+	 * @return The URL of the requested content at the downstream CDN.
+	 */
+	public URL getCdniDelegationUrl(final HTTPRequest request) throws MalformedURLException {
+		final int port = useSecure(request) ? STANDARD_HTTPS_PORT : STANDARD_HTTP_PORT;
+		return new URL(createURIString(request, cdniDelegation.getHost(), port, null));
+	}
+
 	private boolean useSecure(final HTTPRequest request) {
 		if (request.isSecure()) {
 			return acceptHttps && isSslReady();
@@ -635,6 +662,11 @@ public class DeliveryService {
 		return getProp("maxDnsIpsForLocation",0);
 	}
 
+	@JsonIgnore
+	public CdniDelegation getCdniDelegation() {
+		return cdniDelegation;
+	}
+
 	@JsonIgnore
 	public JsonNode getStaticDnsEntries() {
 		return staticDnsEntries;
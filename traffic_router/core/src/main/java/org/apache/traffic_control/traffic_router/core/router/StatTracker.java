@@ -196,7 +196,7 @@ public class StatTracker {
 		public enum ResultDetails {
 			NO_DETAILS, DS_NOT_FOUND, DS_TLS_MISMATCH, DS_NO_BYPASS, DS_BYPASS, DS_CZ_ONLY, DS_CLIENT_GEO_UNSUPPORTED, GEO_NO_CACHE_FOUND,
 			REGIONAL_GEO_NO_RULE, REGIONAL_GEO_ALTERNATE_WITHOUT_CACHE, REGIONAL_GEO_ALTERNATE_WITH_CACHE, DS_CZ_BACKUP_CG,
-			DS_INVALID_ROUTING_NAME, LOCALIZED_DNS
+			DS_INVALID_ROUTING_NAME, LOCALIZED_DNS, DS_CDNI_DELEGATED
 		}
 
 		public enum ResultCode {
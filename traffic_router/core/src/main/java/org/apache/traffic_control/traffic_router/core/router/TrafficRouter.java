@@ -18,6 +18,7 @@ package org.apache.traffic_control.traffic_router.core.router;
 import org.apache.traffic_control.traffic_router.configuration.ConfigurationListener;
 import org.apache.traffic_control.traffic_router.core.dns.DNSAccessRecord;
 import org.apache.traffic_control.traffic_router.core.dns.ZoneManager;
+import org.apache.traffic_control.traffic_router.core.ds.CdniDelegation;
 import org.apache.traffic_control.traffic_router.core.ds.DeliveryService;
 import org.apache.traffic_control.traffic_router.core.ds.Steering;
 import org.apache.traffic_control.traffic_router.core.ds.SteeringGeolocationComparator;
@@ -1215,6 +1216,13 @@ public class TrafficRouter {
 
 		routeResult.setDeliveryService(deliveryService);
 
+		if (isCdniDelegated(request, deliveryService, track)) {
+			track.setResult(ResultType.DS_REDIRECT);
+			track.setResultDetails(ResultDetails.DS_CDNI_DELEGATED);
+			routeResult.setUrl(deliveryService.getCdniDelegationUrl(request));
+			return routeResult;
+		}
+
 		final List<Cache> caches = selectCaches(request, deliveryService, track);
 		if (caches == null || caches.isEmpty()) {
 			if (track.getResult() == ResultType.GEO_REDIRECT) {
@@ -1253,6 +1261,31 @@ public class TrafficRouter {
 		return routeResult;
 	}
 
+	/**
+	 * Checks whether a client is in the footprints of the CDNi downstream CDN a Delivery Service is delegated to.
+	 * @param request The client HTTP request.
+	 * @param ds The Delivery Service being served.
+	 * @return Whether the client should be redirected to the downstream CDN.
+	 * @throws GeolocationException if the client's country is needed, and geo-locating the client fails
+	 */
+	private boolean isCdniDelegated(final HTTPRequest request, final DeliveryService ds, final Track track) throws GeolocationException {
+		final CdniDelegation delegation = ds.getCdniDelegation();
+		if (delegation == null) {
+			return false;
+		}
+
+		if (delegation.containsAddress(request.getClientIP())) {
+			return true;
+		}
+
+		if (!delegation.hasCountryCodes()) {
+			return false;
+		}
+
+		final Geolocation clientGeolocation = getClientGeolocation(request.getClientIP(), track, ds);
+		return clientGeolocation != null && delegation.containsCountry(clientGeolocation.getCountryCode());
+	}
+
 	/**
 	 * Gets all the possible steering results for a request to a Delivery Service.
 	 * @param request The client HTTP request.
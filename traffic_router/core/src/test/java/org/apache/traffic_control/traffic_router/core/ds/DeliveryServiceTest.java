@@ -84,4 +84,33 @@ public class DeliveryServiceTest {
 
         assertThat(Whitebox.getInternalState(deliveryService, "requiredCapabilities"), containsInAnyOrder("all-read", "all-write", "cdn-read"));
     }
+
+    @Test
+    public void itRedirectsCdniDelegatedClientsToTheDownstreamCdn() throws Exception {
+        final ObjectMapper mapper = new ObjectMapper();
+        final JsonNode json = mapper.readTree("{\"routingName\":\"edge\",\"coverageZoneOnly\":false,\"cdniDelegation\":{\"dcdn\":\"dcdn1\",\"host\":\"video.dcdn.example.com\"," +
+                "\"footprints\":[{\"footprint-type\":\"ipv4cidr\",\"footprint-value\":[\"192.0.2.0/24\"]},{\"footprint-type\":\"ipv6cidr\",\"footprint-value\":[\"2001:db8::/32\"]}," +
+                "{\"footprint-type\":\"countrycode\",\"footprint-value\":[\"us\"]},{\"footprint-type\":\"asn\",\"footprint-value\":[\"as64496\"]}]}}");
+        final DeliveryService deliveryService = new DeliveryService("delegated", json);
+
+        final CdniDelegation delegation = deliveryService.getCdniDelegation();
+        assertThat(delegation.getDcdn(), equalTo("dcdn1"));
+        assertThat(delegation.containsAddress("192.0.2.10"), equalTo(true));
+        assertThat(delegation.containsAddress("198.51.100.10"), equalTo(false));
+        assertThat(delegation.containsAddress("2001:db8::1"), equalTo(true));
+        assertThat(delegation.containsAddress("2001:db9::1"), equalTo(false));
+        assertThat(delegation.containsCountry("US"), equalTo(true));
+        assertThat(delegation.containsCountry("CA"), equalTo(false));
+
+        final HTTPRequest request = new HTTPRequest();
+        request.setUri("/path/to/asset.m3u8");
+        request.setQueryString("foo=bar");
+        assertThat(deliveryService.getCdniDelegationUrl(request).toString(), equalTo("http://video.dcdn.example.com/path/to/asset.m3u8?foo=bar"));
+    }
+
+    @Test
+    public void itHandlesLackOfCdniDelegationInJSON() throws Exception {
+        final JsonNode json = (new ObjectMapper()).readTree("{\"routingName\":\"edge\",\"coverageZoneOnly\":false}");
+        assertThat(new DeliveryService("test", json).getCdniDelegation(), equalTo(null));
+    }
 }
@@ -17,10 +17,23 @@ package org.apache.traffic_control.traffic_router.secure;
 
 import org.apache.logging.log4j.LogManager;
 import org.apache.logging.log4j.Logger;
+import org.bouncycastle.asn1.DEROctetString;
+import org.bouncycastle.asn1.edec.EdECObjectIdentifiers;
+import org.bouncycastle.asn1.pkcs.PrivateKeyInfo;
+import org.bouncycastle.asn1.x509.AlgorithmIdentifier;
+import org.bouncycastle.jce.provider.BouncyCastleProvider;
 
+import java.io.IOException;
 import java.math.BigInteger;
+import java.security.AlgorithmParameters;
+import java.security.GeneralSecurityException;
 import java.security.KeyFactory;
 import java.security.PrivateKey;
+import java.security.Provider;
+import java.security.spec.ECGenParameterSpec;
+import java.security.spec.ECParameterSpec;
+import java.security.spec.ECPrivateKeySpec;
+import java.security.spec.PKCS8EncodedKeySpec;
 import java.security.spec.RSAPrivateCrtKeySpec;
 import java.util.Arrays;
 import java.util.HashMap;
@@ -32,6 +45,14 @@ import static java.util.Base64.getDecoder;
 public class BindPrivateKey {
 	private static final Logger LOGGER = LogManager.getLogger(BindPrivateKey.class);
 
+	// DNSSEC algorithm numbers, https://www.iana.org/assignments/dns-sec-alg-numbers/dns-sec-alg-numbers.xhtml
+	private static final int ECDSAP256SHA256 = 13;
+	private static final int ECDSAP384SHA384 = 14;
+	private static final int ED25519 = 15;
+
+	// the JDK has no Ed25519 keys before Java 15
+	private static final Provider ED25519_PROVIDER = new BouncyCastleProvider();
+
 	private BigInteger decodeBigInt(final String s) {
 		return new BigInteger(1, getDecoder().decode(s.getBytes()));
 	}
@@ -55,7 +76,77 @@ public class BindPrivateKey {
 		return bigIntegerMap;
 	}
 
+	private Map<String, String> decodeFields(final String s) {
+		final Map<String, String> fields = new HashMap<>();
+
+		for (final String line : s.split("\n")) {
+			final String[] tokens = line.split(": ", 2);
+
+			if (tokens.length == 2) {
+				fields.put(tokens[0].trim(), tokens[1].trim());
+			}
+		}
+
+		return fields;
+	}
+
+	private int decodeAlgorithm(final Map<String, String> fields) {
+		final String algorithm = fields.get("Algorithm");
+
+		if (algorithm == null) {
+			return 0;
+		}
+
+		try {
+			// e.g. "13 (ECDSAP256SHA256)"
+			return Integer.parseInt(algorithm.split(" ")[0]);
+		} catch (NumberFormatException e) {
+			LOGGER.warn("Failed to parse Bind Private Key algorithm '" + algorithm + "'");
+		}
+
+		return 0;
+	}
+
+	private PrivateKey decodeECDSA(final String curve, final String privateKey) throws GeneralSecurityException {
+		final AlgorithmParameters parameters = AlgorithmParameters.getInstance("EC");
+		parameters.init(new ECGenParameterSpec(curve));
+		final ECParameterSpec parameterSpec = parameters.getParameterSpec(ECParameterSpec.class);
+
+		return KeyFactory.getInstance("EC").generatePrivate(new ECPrivateKeySpec(decodeBigInt(privateKey), parameterSpec));
+	}
+
+	private PrivateKey decodeEd25519(final String privateKey) throws GeneralSecurityException, IOException {
+		// the BIND private key of an Ed25519 key is its 32 byte seed (RFC 8080)
+		final PrivateKeyInfo privateKeyInfo = new PrivateKeyInfo(new AlgorithmIdentifier(EdECObjectIdentifiers.id_Ed25519),
+			new DEROctetString(getDecoder().decode(privateKey.getBytes())));
+
+		return KeyFactory.getInstance("Ed25519", ED25519_PROVIDER).generatePrivate(new PKCS8EncodedKeySpec(privateKeyInfo.getEncoded()));
+	}
+
 	public PrivateKey decode(final String data) {
+		final Map<String, String> fields = decodeFields(data);
+		final int algorithm = decodeAlgorithm(fields);
+
+		if (algorithm == ECDSAP256SHA256 || algorithm == ECDSAP384SHA384) {
+			try {
+				return decodeECDSA(algorithm == ECDSAP256SHA256 ? "secp256r1" : "secp384r1", fields.get("PrivateKey"));
+			} catch (Exception e) {
+				LOGGER.error("Failed to decode Bind Private Key data: " + e.getMessage(), e);
+			}
+
+			return null;
+		}
+
+		if (algorithm == ED25519) {
+			try {
+				return decodeEd25519(fields.get("PrivateKey"));
+			} catch (Exception e) {
+				LOGGER.error("Failed to decode Bind Private Key data: " + e.getMessage(), e);
+			}
+
+			return null;
+		}
+
 		final Map<String, BigInteger> map = decodeBigIntegers(data);
 		final BigInteger modulus = map.get("Modulus");
 		final BigInteger publicExponent = map.get("PublicExponent");
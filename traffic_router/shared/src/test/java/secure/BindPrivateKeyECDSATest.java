@@ -0,0 +1,86 @@
+/*
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secure;
+
+import org.apache.traffic_control.traffic_router.secure.BindPrivateKey;
+import org.bouncycastle.jce.provider.BouncyCastleProvider;
+import org.bouncycastle.util.encoders.Hex;
+import org.junit.Test;
+
+import java.math.BigInteger;
+import java.security.KeyPair;
+import java.security.KeyPairGenerator;
+import java.security.PrivateKey;
+import java.security.Signature;
+import java.security.interfaces.ECPrivateKey;
+import java.security.spec.ECGenParameterSpec;
+
+import static java.util.Base64.getEncoder;
+import static org.hamcrest.MatcherAssert.assertThat;
+import static org.hamcrest.Matchers.equalTo;
+import static org.hamcrest.Matchers.instanceOf;
+import static org.hamcrest.Matchers.notNullValue;
+
+public class BindPrivateKeyECDSATest {
+	String encode(BigInteger bigInteger) {
+		return new String(getEncoder().encode(bigInteger.toByteArray()));
+	}
+
+	private void assertDecodes(final String curve, final String algorithm) throws Exception {
+		KeyPairGenerator keyPairGenerator = KeyPairGenerator.getInstance("EC");
+		keyPairGenerator.initialize(new ECGenParameterSpec(curve));
+		KeyPair keyPair = keyPairGenerator.generateKeyPair();
+
+		ECPrivateKey ecPrivateKey = (ECPrivateKey) keyPair.getPrivate();
+
+		String privateKeyString = "Private-key-format: v1.3\n" +
+			"Algorithm: " + algorithm + "\n" +
+			"PrivateKey: " + encode(ecPrivateKey.getS()) + "\n";
+
+		PrivateKey key = new BindPrivateKey().decode(privateKeyString);
+		assertThat(key, instanceOf(ECPrivateKey.class));
+		assertThat(((ECPrivateKey) key).getS(), equalTo(ecPrivateKey.getS()));
+		assertThat(((ECPrivateKey) key).getParams().getOrder(), equalTo(ecPrivateKey.getParams().getOrder()));
+	}
+
+	@Test
+	public void itDecodesECDSAP256SHA256PrivateKeyString() throws Exception {
+		assertDecodes("secp256r1", "13 (ECDSAP256SHA256)");
+	}
+
+	@Test
+	public void itDecodesECDSAP384SHA384PrivateKeyString() throws Exception {
+		assertDecodes("secp384r1", "14 (ECDSAP384SHA384)");
+	}
+
+	@Test
+	public void itDecodesED25519PrivateKeyString() throws Exception {
+		// test vector 1 of RFC 8032 section 7.1
+		final byte[] seed = Hex.decode("9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60");
+		final byte[] signature = Hex.decode("e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b");
+
+		String privateKeyString = "Private-key-format: v1.3\n" +
+			"Algorithm: 15 (ED25519)\n" +
+			"PrivateKey: " + new String(getEncoder().encode(seed)) + "\n";
+
+		PrivateKey key = new BindPrivateKey().decode(privateKeyString);
+		assertThat(key, notNullValue());
+
+		Signature signer = Signature.getInstance("Ed25519", new BouncyCastleProvider());
+		signer.initSign(key);
+		assertThat(signer.sign(), equalTo(signature));
+	}
+}
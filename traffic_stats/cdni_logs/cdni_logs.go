@@ -0,0 +1,489 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+// cdni_logs makes CDNi logging files (RFC 7937) from the CDNi access logs of
+// cache servers, whose format t3c generates in logging.yaml. Each run reads
+// the log lines written since the last run, and writes a logging file of the
+// records of each Delivery Service with Logs Enabled into a directory named by
+// its XMLID - which Traffic Ops serves to uCDNs from its cdni.log_dir.
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/apache/trafficcontrol/lib/go-atscfg"
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	client "github.com/apache/trafficcontrol/traffic_ops/v5-client"
+
+	"github.com/google/uuid"
+)
+
+const (
+	UserAgent = "cdni-logs"
+
+	// LogFileExtension is the file name extension of CDNi logging files.
+	LogFileExtension = ".log"
+
+	// RecordType is the CDNi logging record type of the records made.
+	RecordType = "cdni_http_request_v1"
+
+	// crlf ends the lines of CDNi logging files.
+	crlf = "\r\n"
+)
+
+// Fields are the CDNi logging fields of the records made, in order.
+var Fields = []string{
+	"date",
+	"time",
+	"time-taken",
+	"c-groupid",
+	"s-ip",
+	"s-hostname",
+	"cs-method",
+	"u-uri",
+	"protocol",
+	"sc-status",
+	"sc-total-bytes",
+	"sc-entity-bytes",
+	"cs(User-Agent)",
+	"cs(Referer)",
+	"s-cached",
+}
+
+// State holds the offsets in the CDNi access logs up to which lines have been
+// read, by path.
+type State struct {
+	Offsets map[string]LogOffset `json:"offsets"`
+}
+
+// LogOffset is the offset in a CDNi access log up to which lines have been
+// read, with the inode of the log file, which identifies it across rotations.
+type LogOffset struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+// dsMatcher matches request hosts to the Delivery Service whose host regular
+// expression they match.
+type dsMatcher struct {
+	regexes []dsRegex
+	hosts   map[string]string
+}
+
+type dsRegex struct {
+	xmlID string
+	re    *regexp.Regexp
+}
+
+func main() {
+	toURL := flag.String("to-url", os.Getenv("TO_URL"), "Traffic Ops URL; defaults to the TO_URL environment variable")
+	toUser := flag.String("to-user", os.Getenv("TO_USER"), "Traffic Ops user; defaults to the TO_USER environment variable")
+	toPass := flag.String("to-pass", os.Getenv("TO_PASSWORD"), "Traffic Ops password; defaults to the TO_PASSWORD environment variable")
+	insecure := flag.Bool("insecure", false, "Whether to skip verifying the Traffic Ops certificate")
+	timeout := flag.Duration("timeout", 30*time.Second, "Timeout of Traffic Ops requests")
+	cdn := flag.String("cdn", "", "Name of the CDN of the Delivery Services whose records to make")
+	origin := flag.String("origin", "", "The claimed origin of the logging files, i.e. the dcdn_id of Traffic Ops")
+	input := flag.String("input", "", "Glob of the CDNi access log files to read")
+	output := flag.String("output", "", "Directory to write the CDNi logging files into")
+	statePath := flag.String("state", "", "File of the offsets read up to in the access logs; defaults to .state.json in the output directory")
+	flag.Parse()
+
+	if *toURL == "" || *toUser == "" || *toPass == "" || *cdn == "" || *origin == "" || *input == "" || *output == "" {
+		fmt.Fprintln(os.Stderr, "the Traffic Ops URL, user and password, -cdn, -origin, -input and -output are required")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *statePath == "" {
+		*statePath = filepath.Join(*output, ".state.json")
+	}
+
+	if err := run(*toURL, *toUser, *toPass, *insecure, *timeout, *cdn, *origin, *input, *output, *statePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error making CDNi logging files: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(toURL, toUser, toPass string, insecure bool, timeout time.Duration, cdn, origin, input, output, statePath string) error {
+	start := time.Now()
+	state, err := loadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	toClient, _, err := client.LoginWithAgent(toURL, toUser, toPass, insecure, UserAgent, false, timeout)
+	if err != nil {
+		return fmt.Errorf("logging in to Traffic Ops: %w", err)
+	}
+	resp, _, err := toClient.GetDeliveryServices(client.RequestOptions{})
+	if err != nil {
+		return fmt.Errorf("getting delivery services: %w", err)
+	}
+	matcher, err := newDSMatcher(resp.Response, cdn)
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(input)
+	if err != nil {
+		return fmt.Errorf("matching input files: %w", err)
+	}
+	sort.Strings(paths)
+
+	records := map[string][]string{}
+	skipped := 0
+	state, err = readLogs(paths, state, func(line string) {
+		xmlID, record, err := makeRecord(line, matcher)
+		if err != nil {
+			skipped++
+			return
+		}
+		if xmlID != "" {
+			records[xmlID] = append(records[xmlID], record)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %d malformed lines\n", skipped)
+	}
+
+	hostName, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("getting host name: %w", err)
+	}
+	name := start.UTC().Format("20060102T150405Z") + "_" + hostName + LogFileExtension
+	for xmlID, dsRecords := range records {
+		if err := writeLogFile(filepath.Join(output, xmlID), name, origin, dsRecords); err != nil {
+			return fmt.Errorf("writing log file of delivery service %s: %w", xmlID, err)
+		}
+		fmt.Printf("Wrote %d records of %s\n", len(dsRecords), xmlID)
+	}
+
+	return saveState(statePath, state)
+}
+
+// newDSMatcher returns a matcher of the active Delivery Services of the CDN
+// with Logs Enabled.
+func newDSMatcher(dses []tc.DeliveryServiceV4, cdn string) (*dsMatcher, error) {
+	matcher := &dsMatcher{hosts: map[string]string{}}
+	for _, ds := range dses {
+		if ds.XMLID == nil || ds.CDNName == nil || *ds.CDNName != cdn || ds.Active == nil || !*ds.Active || ds.LogsEnabled == nil || !*ds.LogsEnabled || ds.MatchList == nil {
+			continue
+		}
+		for _, match := range *ds.MatchList {
+			if match.Type != tc.DSMatchTypeHostRegex {
+				continue
+			}
+			re, err := regexp.Compile(`^(?:` + match.Pattern + `)$`)
+			if err != nil {
+				return nil, fmt.Errorf("compiling host regex '%s' of delivery service %s: %w", match.Pattern, *ds.XMLID, err)
+			}
+			matcher.regexes = append(matcher.regexes, dsRegex{xmlID: *ds.XMLID, re: re})
+		}
+	}
+	return matcher, nil
+}
+
+// match returns the XMLID of the Delivery Service of the host, or the empty
+// string if the host is of no Delivery Service the matcher matches.
+func (m *dsMatcher) match(host string) string {
+	host = strings.ToLower(host)
+	if xmlID, ok := m.hosts[host]; ok {
+		return xmlID
+	}
+	xmlID := ""
+	for _, regex := range m.regexes {
+		if regex.re.MatchString(host) {
+			xmlID = regex.xmlID
+			break
+		}
+	}
+	m.hosts[host] = xmlID
+	return xmlID
+}
+
+// readLogs calls f with each complete line of the log files written since the
+// last state, and returns the new state.
+//
+// Offsets are found by inode rather than path, so that a rotated log is read
+// to its end under its new name - if it matches the input glob - and its
+// replacement is read from its start.
+func readLogs(paths []string, last State, f func(string)) (State, error) {
+	offsets := map[uint64]int64{}
+	for _, offset := range last.Offsets {
+		offsets[offset.Inode] = offset.Offset
+	}
+
+	state := State{Offsets: map[string]LogOffset{}}
+	for _, path := range paths {
+		offset, n, err := readLog(path, offsets, f)
+		if err != nil {
+			return State{}, err
+		}
+		state.Offsets[path] = offset
+		fmt.Printf("Read %d lines of %s\n", n, path)
+	}
+	return state, nil
+}
+
+// readLog calls f with each complete line of the log file written after the
+// offset of its inode in offsets, and returns the inode and offset of the end
+// of the last complete line, and the number of lines read. A log file of an
+// inode not in offsets is read from its start, as is one smaller than its
+// offset, which has been truncated.
+func readLog(path string, offsets map[uint64]int64, f func(string)) (LogOffset, int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return LogOffset{}, 0, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return LogOffset{}, 0, fmt.Errorf("getting info of %s: %w", path, err)
+	}
+	inode := fileInode(info)
+	offset := offsets[inode]
+	if info.Size() < offset {
+		offset = 0
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return LogOffset{}, 0, fmt.Errorf("seeking %s: %w", path, err)
+	}
+
+	reader := bufio.NewReader(file)
+	n := 0
+	for {
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			// a partial line is still being written, and is read next time
+			return LogOffset{Inode: inode, Offset: offset}, n, nil
+		} else if err != nil {
+			return LogOffset{}, 0, fmt.Errorf("reading %s: %w", path, err)
+		}
+		offset += int64(len(line))
+		n++
+		f(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+// fileInode returns the inode of the file, or 0 if its info has none.
+func fileInode(info fs.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Ino)
+	}
+	return 0
+}
+
+// makeRecord returns the XMLID of the Delivery Service of the CDNi access log
+// line, and the CDNi logging record of it. The XMLID is empty if the request
+// was for no Delivery Service the matcher matches.
+func makeRecord(line string, matcher *dsMatcher) (string, string, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != atscfg.CDNiLogFieldCount {
+		return "", "", fmt.Errorf("expected %d fields, got %d", atscfg.CDNiLogFieldCount, len(fields))
+	}
+
+	xmlID := matcher.match(fields[atscfg.CDNiLogFieldHost])
+	if xmlID == "" {
+		return "", "", nil
+	}
+
+	start, err := parseTimestamp(fields[atscfg.CDNiLogFieldTimestamp])
+	if err != nil {
+		return "", "", err
+	}
+	timeTakenMS, err := strconv.ParseInt(fields[atscfg.CDNiLogFieldTimeTakenMS], 10, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("parsing time taken: %w", err)
+	}
+	headerBytes, err := parseBytes(fields[atscfg.CDNiLogFieldHeaderBytes])
+	if err != nil {
+		return "", "", err
+	}
+	contentBytes, err := parseBytes(fields[atscfg.CDNiLogFieldContentBytes])
+	if err != nil {
+		return "", "", err
+	}
+
+	// records are of the time processing the request completed
+	end := start.Add(time.Duration(timeTakenMS) * time.Millisecond).UTC()
+	protocol := fields[atscfg.CDNiLogFieldProtocol]
+	if !strings.HasPrefix(protocol, "HTTP/") {
+		protocol = "HTTP/" + protocol
+	}
+	cached := "0"
+	if strings.Contains(fields[atscfg.CDNiLogFieldCacheResult], "HIT") {
+		cached = "1"
+	}
+
+	record := []string{
+		end.Format("2006-01-02"),
+		end.Format("15:04:05.000"),
+		strconv.FormatFloat(float64(timeTakenMS)/1000, 'f', 3, 64),
+		clientGroupID(fields[atscfg.CDNiLogFieldClientIP]),
+		fields[atscfg.CDNiLogFieldServerIP],
+		fields[atscfg.CDNiLogFieldServerHostName],
+		fields[atscfg.CDNiLogFieldMethod],
+		fields[atscfg.CDNiLogFieldURL],
+		protocol,
+		fields[atscfg.CDNiLogFieldStatus],
+		strconv.FormatInt(headerBytes+contentBytes, 10),
+		strconv.FormatInt(contentBytes, 10),
+		quote(fields[atscfg.CDNiLogFieldUserAgent]),
+		quote(fields[atscfg.CDNiLogFieldReferer]),
+		cached,
+	}
+	return xmlID, strings.Join(record, "\t"), nil
+}
+
+// parseTimestamp parses an ATS timestamp in seconds since the epoch, with a
+// fraction of milliseconds.
+func parseTimestamp(s string) (time.Time, error) {
+	secStr, msStr, _ := strings.Cut(s, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing timestamp: %w", err)
+	}
+	ms := int64(0)
+	if msStr != "" {
+		if ms, err = strconv.ParseInt(msStr, 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("parsing timestamp: %w", err)
+		}
+	}
+	return time.Unix(sec, ms*int64(time.Millisecond)), nil
+}
+
+// parseBytes parses a byte count ATS logs, which is "-" if there's none.
+func parseBytes(s string) (int64, error) {
+	if s == "-" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing byte count: %w", err)
+	}
+	return n, nil
+}
+
+// clientGroupID returns the group of clients of the address, its /24 IPv4 or
+// /48 IPv6 prefix, so that records do not identify individual clients.
+func clientGroupID(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "-"
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// quote returns the header value as a CDNi logging quoted string. ATS logs
+// missing headers as "-".
+func quote(s string) string {
+	if s == "-" {
+		s = ""
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// writeLogFile writes the CDNi logging file of the records into the
+// directory. It's written to a hidden file first, so that a partially
+// written file is never served.
+func writeLogFile(dir string, name string, origin string, records []string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+	tmpPath := filepath.Join(dir, "."+name)
+	if err := os.WriteFile(tmpPath, []byte(makeLogFile(origin, uuid.New(), records)), 0644); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("renaming file: %w", err)
+	}
+	return nil
+}
+
+// makeLogFile returns the text of the CDNi logging file of the records. The
+// integrity hash directive ends it, an MD5 digest of all the lines before it.
+func makeLogFile(origin string, id uuid.UUID, records []string) string {
+	b := strings.Builder{}
+	b.WriteString("#version:\tcdni/1.0" + crlf)
+	b.WriteString("#UUID:\turn:uuid:" + id.String() + crlf)
+	b.WriteString("#claimed-origin:\t" + origin + crlf)
+	b.WriteString("#record-type:\t" + RecordType + crlf)
+	b.WriteString("#fields:\t" + strings.Join(Fields, "\t") + crlf)
+	for _, record := range records {
+		b.WriteString(record + crlf)
+	}
+	hash := md5.Sum([]byte(b.String()))
+	b.WriteString("#integrity-hash:\t" + hex.EncodeToString(hash[:]) + crlf)
+	return b.String()
+}
+
+func loadState(path string) (State, error) {
+	state := State{Offsets: map[string]LogOffset{}}
+	bts, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return State{}, fmt.Errorf("reading state: %w", err)
+	}
+	if err := json.Unmarshal(bts, &state); err != nil {
+		return State{}, fmt.Errorf("decoding state: %w", err)
+	}
+	if state.Offsets == nil {
+		state.Offsets = map[string]LogOffset{}
+	}
+	return state, nil
+}
+
+func saveState(path string, state State) error {
+	bts, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encoding state: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, bts, 0644); err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("renaming state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,310 @@
+/*
+Licensed to the Apache Software Foundation (ASF) under one
+or more contributor license agreements.  See the NOTICE file
+distributed with this work for additional information
+regarding copyright ownership.  The ASF licenses this file
+to you under the Apache License, Version 2.0 (the
+"License"); you may not use this file except in compliance
+with the License.  You may obtain a copy of the License at
+
+  http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing,
+software distributed under the License is distributed on an
+"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+KIND, either express or implied.  See the License for the
+specific language governing permissions and limitations
+under the License.
+*/
+
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apache/trafficcontrol/lib/go-tc"
+	"github.com/apache/trafficcontrol/lib/go-util"
+
+	"github.com/google/uuid"
+)
+
+func makeDS(xmlID string, cdn string, logsEnabled bool, patterns ...string) tc.DeliveryServiceV4 {
+	ds := tc.DeliveryServiceV4{}
+	ds.XMLID = util.StrPtr(xmlID)
+	ds.CDNName = util.StrPtr(cdn)
+	ds.Active = util.BoolPtr(true)
+	ds.LogsEnabled = util.BoolPtr(logsEnabled)
+	matchList := []tc.DeliveryServiceMatch{{Type: tc.DSMatchTypePathRegex, Pattern: ".*"}}
+	for _, pattern := range patterns {
+		matchList = append(matchList, tc.DeliveryServiceMatch{Type: tc.DSMatchTypeHostRegex, Pattern: pattern})
+	}
+	ds.MatchList = &matchList
+	return ds
+}
+
+func makeMatcher(t *testing.T) *dsMatcher {
+	matcher, err := newDSMatcher([]tc.DeliveryServiceV4{
+		makeDS("cdni-ucdn1-video", "cdn1", true, `video\.ucdn1\.example`),
+		makeDS("ds2", "cdn1", true, `.*\.ds2\..*`),
+		makeDS("nologs", "cdn1", false, `nologs\.example`),
+		makeDS("othercdn", "cdn2", true, `othercdn\.example`),
+	}, "cdn1")
+	if err != nil {
+		t.Fatalf("newDSMatcher: %v", err)
+	}
+	return matcher
+}
+
+func TestDSMatcher(t *testing.T) {
+	matcher := makeMatcher(t)
+	for host, expected := range map[string]string{
+		"video.ucdn1.example":       "cdni-ucdn1-video",
+		"VIDEO.ucdn1.example":       "cdni-ucdn1-video",
+		"video.ucdn1.example.evil":  "",
+		"edge.ds2.cdn1.example.com": "ds2",
+		"nologs.example":            "",
+		"othercdn.example":          "",
+	} {
+		if actual := matcher.match(host); actual != expected {
+			t.Errorf("match(%q): expected '%s', actual: '%s'", host, expected, actual)
+		}
+	}
+
+	if _, err := newDSMatcher([]tc.DeliveryServiceV4{makeDS("bad", "cdn1", true, `(`)}, "cdn1"); err == nil {
+		t.Error("expected an error for an invalid host regex, actual: nil")
+	}
+}
+
+func TestMakeRecord(t *testing.T) {
+	matcher := makeMatcher(t)
+	line := strings.Join([]string{
+		"1652346000.250",
+		"1750",
+		"198.51.100.23",
+		"192.0.2.10",
+		"edge1",
+		"GET",
+		"video.ucdn1.example",
+		"http://video.ucdn1.example/movie.mp4",
+		"1.1",
+		"200",
+		"300",
+		"6729891",
+		"TCP_MEM_HIT",
+		`Mozilla/5.0 "quoted"`,
+		"-",
+	}, "\t")
+
+	xmlID, record, err := makeRecord(line, matcher)
+	if err != nil {
+		t.Fatalf("makeRecord: %v", err)
+	}
+	if xmlID != "cdni-ucdn1-video" {
+		t.Errorf("expected XMLID 'cdni-ucdn1-video', actual: '%s'", xmlID)
+	}
+	expected := strings.Join([]string{
+		"2022-05-12",
+		"09:00:02.000",
+		"1.750",
+		"198.51.100.0/24",
+		"192.0.2.10",
+		"edge1",
+		"GET",
+		"http://video.ucdn1.example/movie.mp4",
+		"HTTP/1.1",
+		"200",
+		"6730191",
+		"6729891",
+		`"Mozilla/5.0 \"quoted\""`,
+		`""`,
+		"1",
+	}, "\t")
+	if record != expected {
+		t.Errorf("expected record:\n%s\nactual:\n%s", expected, record)
+	}
+
+	line = strings.Replace(line, "video.ucdn1.example\t", "unknown.example\t", 1)
+	if xmlID, _, err := makeRecord(line, matcher); err != nil || xmlID != "" {
+		t.Errorf("expected no XMLID or error for an unknown host, actual: '%s', %v", xmlID, err)
+	}
+
+	for _, bad := range []string{
+		"too\tfew\tfields",
+		strings.Replace(line, "unknown.example", "video.ucdn1.example", 1) + "\textra",
+		strings.Replace(strings.Replace(line, "unknown.example", "video.ucdn1.example", 1), "1750", "x", 1),
+	} {
+		if _, _, err := makeRecord(bad, matcher); err == nil {
+			t.Errorf("expected an error for line '%s', actual: nil", bad)
+		}
+	}
+}
+
+func TestClientGroupID(t *testing.T) {
+	for addr, expected := range map[string]string{
+		"198.51.100.23":       "198.51.100.0/24",
+		"2001:db8:1:2::1":     "2001:db8:1::/48",
+		"::ffff:198.51.100.1": "198.51.100.0/24",
+		"-":                   "-",
+	} {
+		if actual := clientGroupID(addr); actual != expected {
+			t.Errorf("clientGroupID(%q): expected '%s', actual: '%s'", addr, expected, actual)
+		}
+	}
+}
+
+func TestMakeLogFile(t *testing.T) {
+	id := uuid.MustParse("53bd0b22-1e5c-4cbe-8c3d-5a5f8c1e4c2b")
+	text := makeLogFile("dcdn1", id, []string{"record1", "record2"})
+
+	lines := strings.Split(strings.TrimSuffix(text, crlf), crlf)
+	if len(lines) != 8 {
+		t.Fatalf("expected 8 lines, actual: %q", lines)
+	}
+	for i, expected := range []string{
+		"#version:\tcdni/1.0",
+		"#UUID:\turn:uuid:53bd0b22-1e5c-4cbe-8c3d-5a5f8c1e4c2b",
+		"#claimed-origin:\tdcdn1",
+		"#record-type:\tcdni_http_request_v1",
+		"#fields:\t" + strings.Join(Fields, "\t"),
+		"record1",
+		"record2",
+	} {
+		if lines[i] != expected {
+			t.Errorf("expected line %d to be '%s', actual: '%s'", i, expected, lines[i])
+		}
+	}
+	hashed := text[:strings.LastIndex(text, "#integrity-hash:")]
+	hash := md5.Sum([]byte(hashed))
+	if expected := "#integrity-hash:\t" + hex.EncodeToString(hash[:]); lines[7] != expected {
+		t.Errorf("expected line 7 to be '%s', actual: '%s'", expected, lines[7])
+	}
+	if len(strings.Split(lines[4], "\t"))-1 != len(Fields) {
+		t.Errorf("expected %d fields, actual: '%s'", len(Fields), lines[4])
+	}
+}
+
+func TestReadLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cdni.log")
+	if err := os.WriteFile(path, []byte("line1\nline2\npartial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(offset LogOffset) (LogOffset, []string) {
+		lines := []string{}
+		offset, n, err := readLog(path, map[uint64]int64{offset.Inode: offset.Offset}, func(line string) { lines = append(lines, line) })
+		if err != nil {
+			t.Fatalf("readLog: %v", err)
+		}
+		if n != len(lines) {
+			t.Errorf("expected %d lines read, actual: %d", len(lines), n)
+		}
+		return offset, lines
+	}
+
+	offset, lines := read(LogOffset{})
+	if offset.Offset != 12 || strings.Join(lines, ",") != "line1,line2" {
+		t.Errorf("expected lines 'line1,line2' to offset 12, actual: %q to %d", lines, offset.Offset)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("3\n")
+	file.Close()
+	offset, lines = read(offset)
+	if offset.Offset != 21 || strings.Join(lines, ",") != "partial3" {
+		t.Errorf("expected line 'partial3' to offset 21, actual: %q to %d", lines, offset.Offset)
+	}
+
+	// truncated
+	if err := os.WriteFile(path, []byte("new\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	offset, lines = read(offset)
+	if offset.Offset != 4 || strings.Join(lines, ",") != "new" {
+		t.Errorf("expected line 'new' of the truncated log to offset 4, actual: %q to %d", lines, offset.Offset)
+	}
+}
+
+func TestReadLogsRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cdni.log")
+	rotatedPath := path + ".1"
+	if err := os.WriteFile(path, []byte("line1\nline2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(last State) (State, []string) {
+		paths, err := filepath.Glob(path + "*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := []string{}
+		state, err := readLogs(paths, last, func(line string) { lines = append(lines, line) })
+		if err != nil {
+			t.Fatalf("readLogs: %v", err)
+		}
+		return state, lines
+	}
+
+	state, lines := read(State{})
+	if strings.Join(lines, ",") != "line1,line2" {
+		t.Errorf("expected lines 'line1,line2', actual: %q", lines)
+	}
+
+	// lines written before the rotation are read from the rotated log, and
+	// the new log is read from its start, though it's already longer than the
+	// offset of the old one
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("line3\n")
+	file.Close()
+	if err := os.Rename(path, rotatedPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("line4\nline5\nline6\nline7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state, lines = read(state)
+	sort.Strings(lines)
+	if strings.Join(lines, ",") != "line3,line4,line5,line6,line7" {
+		t.Errorf("expected lines 'line3,line4,line5,line6,line7' after rotation, actual: %q", lines)
+	}
+	if state.Offsets[path].Inode == state.Offsets[rotatedPath].Inode {
+		t.Errorf("expected the rotated and new logs to have different inodes, actual: %+v", state.Offsets)
+	}
+
+	if _, lines = read(state); len(lines) != 0 {
+		t.Errorf("expected no lines without new writes, actual: %q", lines)
+	}
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := loadState(path)
+	if err != nil {
+		t.Fatalf("loading missing state: %v", err)
+	}
+	if len(state.Offsets) != 0 {
+		t.Errorf("expected no offsets, actual: %v", state.Offsets)
+	}
+	if err := saveState(path, State{Offsets: map[string]LogOffset{"cdni.log": {Inode: 7, Offset: 42}}}); err != nil {
+		t.Fatalf("saveState: %v", err)
+	}
+	if state, err = loadState(path); err != nil {
+		t.Fatalf("loadState: %v", err)
+	}
+	if offset := state.Offsets["cdni.log"]; offset.Inode != 7 || offset.Offset != 42 {
+		t.Errorf("expected inode 7 offset 42, actual: %v", state.Offsets)
+	}
+}